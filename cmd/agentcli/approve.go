@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// toolApprover gates -approve-tools calls on a y/N answer read from the
+// terminal or from -approve-file, or on an edit of the call's arguments with
+// -editor. Prompts are serialized and the input is opened once, on the first
+// call that needs approval.
+type toolApprover struct {
+	all    bool
+	names  map[string]bool
+	path   string
+	editor bool
+	stderr io.Writer
+
+	mu      sync.Mutex
+	in      *bufio.Reader
+	openErr error
+}
+
+// newToolApprover returns nil when -approve-tools is unset.
+func newToolApprover(cfg cliConfig, stderr io.Writer) *toolApprover {
+	if len(cfg.approveTools) == 0 {
+		return nil
+	}
+	a := &toolApprover{names: make(map[string]bool), path: cfg.approveFile, editor: cfg.editor, stderr: stderr}
+	for _, n := range cfg.approveTools {
+		if n == "all" {
+			a.all = true
+		}
+		a.names[n] = true
+	}
+	return a
+}
+
+func (a *toolApprover) requires(name string) bool {
+	return a != nil && (a.all || a.names[name])
+}
+
+// approve prints the proposed call to stderr and reports whether the answer
+// was y or yes. No usable input, EOF, or any other answer denies the call.
+// The returned call carries the arguments to run with, which differ from
+// tc's only when they were edited under -editor.
+func (a *toolApprover) approve(tc oai.ToolCall) (oai.ToolCall, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	args := json.RawMessage(strings.TrimSpace(tc.Function.Arguments))
+	if len(args) == 0 || !json.Valid(args) {
+		args = json.RawMessage("{}")
+	}
+	if a.editor {
+		return a.edit(tc, args)
+	}
+	proposal, err := json.Marshal(struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}{tc.Function.Name, args})
+	if err != nil {
+		proposal = []byte(tc.Function.Name)
+	}
+	safeFprintf(a.stderr, "approve tool call? %s [y/N]: ", proposal)
+	if a.in == nil && a.openErr == nil {
+		a.in, a.openErr = a.open()
+	}
+	if a.openErr != nil {
+		safeFprintf(a.stderr, "\nWARN: denying %s: %v\n", tc.Function.Name, a.openErr)
+		return tc, false
+	}
+	line, err := a.in.ReadString('\n')
+	if err != nil && line == "" {
+		safeFprintf(a.stderr, "\nWARN: denying %s: no answer (%v)\n", tc.Function.Name, err)
+		return tc, false
+	}
+	if a.path != "" {
+		// Echo file answers so the log shows the decision
+		safeFprintln(a.stderr, strings.TrimSpace(line))
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return tc, true
+	}
+	return tc, false
+}
+
+// edit hands the call's arguments to the user's editor. Saving them runs the
+// call, with the edited arguments when they changed; clearing the file or
+// leaving invalid JSON denies it.
+func (a *toolApprover) edit(tc oai.ToolCall, args json.RawMessage) (oai.ToolCall, bool) {
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, args, "", "  "); err != nil {
+		pretty.Reset()
+		pretty.Write(args)
+	}
+	safeFprintf(a.stderr, "approve tool call? %s: waiting for the editor\n", tc.Function.Name)
+	edited, err := editInEditor(tc.Function.Name+".json", pretty.String(), []string{
+		fmt.Sprintf("Approve the %s tool call %s with the arguments above.", tc.Function.Name, tc.ID),
+		"Save to run it; edit the JSON to change the arguments.",
+		"Delete everything to deny the call. Lines starting with '#' are ignored.",
+	})
+	switch {
+	case errors.Is(err, errEditorEmpty):
+		safeFprintf(a.stderr, "denied %s\n", tc.Function.Name)
+		return tc, false
+	case err != nil:
+		safeFprintf(a.stderr, "WARN: denying %s: %v\n", tc.Function.Name, err)
+		return tc, false
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, []byte(edited)); err != nil {
+		safeFprintf(a.stderr, "WARN: denying %s: edited arguments are not valid JSON: %v\n", tc.Function.Name, err)
+		return tc, false
+	}
+	var orig bytes.Buffer
+	_ = json.Compact(&orig, args) //nolint:errcheck // args is valid JSON
+	if compact.String() != orig.String() {
+		safeFprintf(a.stderr, "approved %s with edited arguments %s\n", tc.Function.Name, compact.String())
+		tc.Function.Arguments = compact.String()
+		return tc, true
+	}
+	safeFprintf(a.stderr, "approved %s\n", tc.Function.Name)
+	return tc, true
+}
+
+// open returns -approve-file (which may be a FIFO fed by another process) or
+// stdin when it is a terminal.
+func (a *toolApprover) open() (*bufio.Reader, error) {
+	if a.path != "" {
+		f, err := os.Open(a.path)
+		if err != nil {
+			return nil, fmt.Errorf("open -approve-file: %w", err)
+		}
+		return bufio.NewReader(f), nil
+	}
+	fi, err := os.Stdin.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return nil, fmt.Errorf("stdin is not a terminal; pass -approve-file")
+	}
+	return bufio.NewReader(os.Stdin), nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// twoPingServer asks for two ping calls, then answers with the tool results
+// joined by "|".
+func twoPingServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	return fakeChatServer(t, nil, callTools(func(req oai.ChatCompletionsRequest) string {
+		var results []string
+		for _, m := range req.Messages {
+			if m.Role == oai.RoleTool {
+				results = append(results, m.ToolCallID+"="+m.Content)
+			}
+		}
+		return strings.Join(results, "|")
+	}, toolCall("c1", "ping", `{"n":1}`), toolCall("c2", "ping", `{"n":2}`)))
+}
+
+func TestCLIMain_ApproveTools_FromFile(t *testing.T) {
+	toolsPath := writeEchoOKTool(t)
+	srv := twoPingServer(t)
+	answers := filepath.Join(t.TempDir(), "answers")
+	if err := os.WriteFile(answers, []byte("y\nno\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-prompt", "q", "-tools", toolsPath, "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-approve-tools", "ping", "-approve-file", answers}, &out, &errb)
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	// Tool results arrive in completion order
+	got := strings.TrimSpace(out.String())
+	if !strings.Contains(got, `c1={"ok":true}`) || !strings.Contains(got, `c2={"error":"tool call denied by the user"}`) {
+		t.Fatalf("stdout=%q", got)
+	}
+	if !strings.Contains(errb.String(), `approve tool call? {"name":"ping","arguments":{"n":2}} [y/N]: no`) {
+		t.Fatalf("proposal not printed: %s", errb.String())
+	}
+}
+
+func TestCLIMain_ApproveTools_NoInputDenies(t *testing.T) {
+	toolsPath := writeEchoOKTool(t)
+	srv := twoPingServer(t)
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-prompt", "q", "-tools", toolsPath, "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-approve-tools", "all", "-approve-file", filepath.Join(t.TempDir(), "missing")}, &out, &errb)
+	if code != 0 || strings.Count(out.String(), "denied by the user") != 2 || !strings.Contains(errb.String(), "WARN: denying ping") {
+		t.Fatalf("exit=%d stdout=%s stderr=%s", code, out.String(), errb.String())
+	}
+}
+
+func TestParseFlags_ApproveFileRequiresApproveTools(t *testing.T) {
+	var out, errb bytes.Buffer
+	if code := cliMain([]string{"-prompt", "q", "-approve-file", "x"}, &out, &errb); code != 2 || !strings.Contains(errb.String(), "-approve-file requires -approve-tools") {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+}
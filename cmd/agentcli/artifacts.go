@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hyperifyio/goagent/internal/artifacts"
+)
+
+// artifactsRoot is GOAGENT_ARTIFACTS_DIR, or <repo>/.goagent/artifacts when
+// unset.
+func artifactsRoot() string {
+	if v := strings.TrimSpace(os.Getenv("GOAGENT_ARTIFACTS_DIR")); v != "" {
+		return v
+	}
+	return filepath.Join(findRepoRoot(), ".goagent", "artifacts")
+}
+
+// storeToolArtifact replaces a tool output of the form {"artifact":{...}}
+// with a reference to the file copied into the artifact store, so the
+// transcript never carries the content. A bad artifact fails the call.
+func storeToolArtifact(out []byte, runErr error) ([]byte, error) {
+	if runErr != nil {
+		return out, runErr
+	}
+	rewritten, _, err := artifacts.Store{Dir: artifactsRoot()}.StoreOutput(bytes.TrimSpace(out))
+	if err != nil {
+		return nil, err
+	}
+	return rewritten, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+func TestRunAgent_ToolArtifactIsStoredByReference(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell tool fixture requires a POSIX shell")
+	}
+	store := t.TempDir()
+	t.Setenv("GOAGENT_ARTIFACTS_DIR", store)
+	dir := t.TempDir()
+	blob := filepath.Join(dir, "blob.bin")
+	payload := strings.Repeat("SECRET-BYTES", 1000)
+	script := filepath.Join(dir, "ping.sh")
+	body := "#!/bin/sh\ncat >/dev/null\nprintf '%s' '" + payload + "' > " + blob + "\necho '{\"artifact\":{\"path\":\"" + blob + "\"}}'\n"
+	if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+		t.Fatalf("write tool: %v", err)
+	}
+	man := `{"tools":[{"name":"ping","schema":{"type":"object"},"command":["` + script + `"]}]}`
+	toolsPath := filepath.Join(dir, "tools.json")
+	if err := os.WriteFile(toolsPath, []byte(man), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+
+	var toolResult string
+	step := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req oai.ChatCompletionsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		step++
+		reply := "done"
+		if step == 1 {
+			reply = "```tool\n{\"name\":\"ping\",\"arguments\":{}}\n```"
+		} else {
+			toolResult = req.Messages[len(req.Messages)-1].Content
+		}
+		_ = json.NewEncoder(w).Encode(oai.ChatCompletionsResponse{Choices: []oai.ChatCompletionsResponseChoice{{ //nolint:errcheck
+			Message: oai.Message{Role: oai.RoleAssistant, Content: reply},
+		}}})
+	}))
+	defer srv.Close()
+
+	var out, errb bytes.Buffer
+	if code := runAgent(textProtocolConfig(toolsPath, srv.URL), &out, &errb); code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if strings.Contains(toolResult, "SECRET-BYTES") || !strings.Contains(toolResult, `"ref":"sha256:`) || !strings.Contains(toolResult, `"bytes":12000`) {
+		t.Fatalf("tool result=%q", toolResult)
+	}
+	matches, _ := filepath.Glob(filepath.Join(store, "*", "*"))
+	if len(matches) != 1 {
+		t.Fatalf("store holds %v", matches)
+	}
+	if stored, err := os.ReadFile(matches[0]); err != nil || string(stored) != payload {
+		t.Fatalf("stored copy differs: %v", err)
+	}
+}
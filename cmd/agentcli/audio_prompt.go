@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// maxAudioPromptBytes matches the upload limit of the OpenAI transcription
+// endpoint.
+const maxAudioPromptBytes = 25 << 20
+
+// readAudioPrompt loads the -audio-prompt file.
+func readAudioPrompt(path string) ([]byte, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("-audio-prompt: %w", err)
+	}
+	if !fi.Mode().IsRegular() {
+		return nil, fmt.Errorf("-audio-prompt %s: not a regular file", path)
+	}
+	if fi.Size() > maxAudioPromptBytes {
+		return nil, fmt.Errorf("-audio-prompt %s: file is %d bytes; the limit is %d", path, fi.Size(), maxAudioPromptBytes)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("-audio-prompt: %w", err)
+	}
+	return data, nil
+}
+
+// transcribeAudioPrompt sends audio to the transcription endpoint with the
+// -audio-* connection and retry settings and returns the text.
+func transcribeAudioPrompt(ctx context.Context, cfg cliConfig, audio []byte) (string, error) {
+	client := oai.NewAudioClient(cfg.audioBaseURL, cfg.audioAPIKey, cfg.audioHTTPTimeout, retryPolicyFor(cfg, cfg.audioHTTPRetries, cfg.audioHTTPBackoff))
+	text, err := client.Transcribe(oai.WithAuditStage(ctx, "audio"), oai.TranscriptionRequest{
+		Model:    cfg.audioModel,
+		Filename: filepath.Base(cfg.audioPrompt),
+		Audio:    audio,
+		Language: strings.TrimSpace(cfg.audioLanguage),
+	})
+	if err != nil {
+		return "", fmt.Errorf("-audio-prompt transcription failed: %w", err)
+	}
+	if text == "" {
+		return "", fmt.Errorf("-audio-prompt transcription of %s is empty", cfg.audioPrompt)
+	}
+	return text, nil
+}
+
+// joinAudioPrompt appends the transcript to the typed prompt, if any.
+func joinAudioPrompt(prompt, transcript string) string {
+	if strings.TrimSpace(prompt) == "" {
+		return transcript
+	}
+	return prompt + "\n\n" + transcript
+}
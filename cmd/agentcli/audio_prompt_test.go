@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+func TestCLIMain_AudioPromptTranscribes(t *testing.T) {
+	chdirTemp(t)
+	audio := filepath.Join(t.TempDir(), "memo.wav")
+	if err := os.WriteFile(audio, []byte("RIFF...."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var userPrompt string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/audio/transcriptions":
+			if r.FormValue("model") != "stt" {
+				t.Errorf("model=%q", r.FormValue("model"))
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, `{"text":"buy milk"}`) //nolint:errcheck
+		default:
+			var req oai.ChatCompletionsRequest
+			_ = json.NewDecoder(r.Body).Decode(&req) //nolint:errcheck
+			userPrompt = req.Messages[len(req.Messages)-1].Content
+			_ = json.NewEncoder(w).Encode(oai.ChatCompletionsResponse{Choices: []oai.ChatCompletionsResponseChoice{{Message: oai.Message{Role: oai.RoleAssistant, Content: "ok"}}}}) //nolint:errcheck
+		}
+	}))
+	defer srv.Close()
+
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-prompt", "Summarize:", "-audio-prompt", audio, "-audio-model", "stt", "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-verbose"}, &out, &errb)
+	if code != 0 || userPrompt != "Summarize:\n\nbuy milk" || !strings.Contains(errb.String(), "info: transcribed "+audio) {
+		t.Fatalf("exit=%d prompt=%q stderr=%s", code, userPrompt, errb.String())
+	}
+	// The transcript alone is a prompt
+	if code := cliMain([]string{"-audio-prompt", audio, "-audio-model", "stt", "-base-url", srv.URL, "-model", "m", "-prep-enabled=false"}, &out, &errb); code != 0 || userPrompt != "buy milk" {
+		t.Fatalf("exit=%d prompt=%q", code, userPrompt)
+	}
+	errb.Reset()
+	if code := cliMain([]string{"-audio-prompt", filepath.Join(t.TempDir(), "none.wav"), "-base-url", srv.URL}, &out, &errb); code != 2 || !strings.Contains(errb.String(), "error: -audio-prompt") {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+}
+
+func TestPrintConfig_AudioInheritsHTTPKnobs(t *testing.T) {
+	t.Setenv("OAI_AUDIO_HTTP_RETRIES", "4")
+	var out, errb bytes.Buffer
+	if code := cliMain([]string{"-prompt", "p", "-print-config", "-base-url", "http://main.example/v1", "-http-timeout", "7s", "-audio-base-url", "http://stt.example/v1"}, &out, &errb); code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	var payload struct {
+		Audio map[string]any `json:"audio"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &payload); err != nil {
+		t.Fatal(err)
+	}
+	a := payload.Audio
+	if a["baseURL"] != "http://stt.example/v1" || a["baseURLSource"] != "flag" || a["httpTimeout"] != "7s" || a["httpTimeoutSource"] != "inherit" || a["httpRetries"] != float64(4) || a["httpRetriesSource"] != "env" || a["model"] != "whisper-1" {
+		t.Fatalf("audio=%v", a)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// loadBatch reads and validates every item up front so a bad line fails
+// before any model call. Blank lines are skipped.
+func loadBatch(path string, topP float64) ([]runRequest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read -batch: %w", err)
+	}
+	defer f.Close() //nolint:errcheck
+	var items []runRequest
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), maxRunRequestBytes)
+	for n := 1; sc.Scan(); n++ {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		item, err := decodeRunRequest(line, topP)
+		if err != nil {
+			return nil, fmt.Errorf("-batch line %d: %v", n, err)
+		}
+		item.line = n
+		items = append(items, item)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read -batch: %w", err)
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("-batch has no items")
+	}
+	return items, nil
+}
+
+// runBatch runs every -batch item as its own agent run, at most
+// -batch-concurrency at a time, and writes one result per line in input
+// order. Items share the HTTP clients, so connections and the retry breaker
+// carry across items, and the pre-stage cache on disk. It exits 0 when every
+// item succeeded and 1 otherwise.
+func runBatch(cfg cliConfig, stdout, stderr io.Writer) int {
+	items, err := loadBatch(cfg.batchPath, cfg.topP)
+	if err != nil {
+		safeFprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+	out := stdout
+	if cfg.batchOut != "" {
+		f, err := os.Create(cfg.batchOut)
+		if err != nil {
+			safeFprintf(stderr, "error: -batch-out: %v\n", err)
+			return 1
+		}
+		defer f.Close() //nolint:errcheck
+		out = f
+	}
+
+	// Items that can edit the workspace take its lock one at a time
+	concurrency := cfg.batchConcurrency
+	if concurrency > 1 && editsWorkspace(cfg) {
+		safeFprintln(stderr, "info: -tools can edit the workspace; running -batch items one at a time (use -read-only to run them concurrently)")
+		concurrency = 1
+	}
+	cfg = shareAcrossRuns(cfg, stderr)
+
+	ctx, stop := agentSignalContext()
+	defer stop()
+	type done struct {
+		index  int
+		result runResult
+	}
+	results := make(chan done)
+	sem := make(chan struct{}, concurrency)
+	started := 0
+	go func() {
+		for i, item := range items {
+			sem <- struct{}{}
+			if ctx.Err() != nil {
+				<-sem
+				break
+			}
+			started++
+			go func(i int, item runRequest) {
+				defer func() { <-sem }()
+				results <- done{i, runInProcess(cfg, item, nil)}
+			}(i, item)
+		}
+		// Wait for the running items, then signal the collector
+		for range cap(sem) {
+			sem <- struct{}{}
+		}
+		close(results)
+	}()
+
+	// Write results in input order as they become available
+	pending := map[int]runResult{}
+	next, failed, writeErr := 0, 0, error(nil)
+	for d := range results {
+		r := d.result
+		safeFprintf(stderr, "batch: line=%d model=%s exit=%d steps=%d tokens=%d latency=%dms\n", r.Line, r.Model, r.ExitCode, r.Steps, r.TotalTokens, r.LatencyMS)
+		if r.ExitCode != 0 {
+			failed++
+		}
+		pending[d.index] = r
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			b, _ := json.Marshal(r) //nolint:errcheck
+			if _, err := out.Write(append(b, '\n')); err != nil && writeErr == nil {
+				writeErr = err
+			}
+		}
+	}
+	switch {
+	case writeErr != nil:
+		safeFprintf(stderr, "error: -batch-out: %v\n", writeErr)
+		return 1
+	case ctx.Err() != nil:
+		safeFprintf(stderr, "info: interrupted; %d of %d -batch items not started\n", len(items)-started, len(items))
+		return exitInterrupted
+	case failed > 0:
+		safeFprintf(stderr, "error: %d of %d -batch items failed\n", failed, len(items))
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+func writeBatchFile(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "items.jsonl")
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadBatch_Validation(t *testing.T) {
+	items, err := loadBatch(writeBatchFile(t, "{\"id\":1,\"prompt\":\"a\"}\n\n{\"prompt\":\"b\",\"model\":\"m2\",\"max_steps\":2}\n"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].line != 1 || items[1].line != 3 || string(items[0].ID) != "1" || items[1].Model != "m2" {
+		t.Fatalf("items=%+v", items)
+	}
+	for body, want := range map[string]string{
+		"{\"prompt\":\"a\"}\n{\"id\":\"x\"}\n":               "line 2: prompt is required",
+		"{\"prompt\":\"a\",\"extra\":1}\n":                   `line 1: json: unknown field "extra"`,
+		"not json\n":                                         "line 1:",
+		"{\"prompt\":\"a\",\"max_steps\":-1}\n":              "max_steps must be >= 0",
+		"\n\n":                                               "no items",
+		"{\"prompt\":\"a\",\"temperature\":0.1}\n":           "conflicts with -top-p",
+		"{\"prompt\":\"a\"}\n{\"prompt\":\"b\",\"tools\":1}": "line 2:",
+	} {
+		if _, err := loadBatch(writeBatchFile(t, body), 0.9); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: err=%v want %q", body, err, want)
+		}
+	}
+}
+
+// batchServer answers each request with its model and last user prompt, and
+// fails prompts that say "fail".
+func batchServer(t *testing.T, calls *atomic.Int32) *httptest.Server {
+	t.Helper()
+	return fakeChatServer(t, calls, func(req oai.ChatCompletionsRequest) (oai.ChatCompletionsResponse, error) {
+		prompt := req.Messages[len(req.Messages)-1].Content
+		if prompt == "fail" {
+			return oai.ChatCompletionsResponse{}, errors.New("boom")
+		}
+		// Finish out of order so results must be reordered
+		if prompt == "slow" {
+			time.Sleep(50 * time.Millisecond)
+		}
+		resp, err := answer(finalMessage(req.Model + ":" + prompt))
+		resp.Usage = &oai.Usage{PromptTokens: 3, CompletionTokens: 1, TotalTokens: 4}
+		return resp, err
+	})
+}
+
+func TestCLI_Batch(t *testing.T) {
+	var calls atomic.Int32
+	srv := batchServer(t, &calls)
+	defer srv.Close()
+	in := writeBatchFile(t, `{"id":"a","prompt":"slow"}
+{"id":2,"prompt":"quick","model":"other"}
+{"prompt":"fail"}
+`)
+	outPath := filepath.Join(t.TempDir(), "results.jsonl")
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-batch", in, "-batch-out", outPath, "-batch-concurrency", "3", "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-http-retries", "0"}, &out, &errb)
+	if code != 1 || !strings.Contains(errb.String(), "1 of 3 -batch items failed") {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if out.Len() != 0 || strings.Count(errb.String(), "batch: line=") != 3 || calls.Load() != 3 {
+		t.Fatalf("stdout=%q calls=%d stderr=%s", out.String(), calls.Load(), errb.String())
+	}
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("results=%s", data)
+	}
+	var got []runResult
+	for _, l := range lines {
+		var r runResult
+		if err := json.Unmarshal([]byte(l), &r); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, r)
+	}
+	if got[0].Line != 1 || string(got[0].ID) != `"a"` || got[0].Output != "m:slow" || got[0].ExitCode != 0 || got[0].TotalTokens != 4 || got[0].Steps != 1 {
+		t.Errorf("item 1: %+v", got[0])
+	}
+	if got[1].Line != 2 || string(got[1].ID) != "2" || got[1].Model != "other" || got[1].Output != "other:quick" {
+		t.Errorf("item 2: %+v", got[1])
+	}
+	if got[2].Line != 3 || got[2].ID != nil || got[2].ExitCode != exitHTTPFailure || got[2].Reason != "http" || !strings.Contains(got[2].Error, "chat call failed") {
+		t.Errorf("item 3: %+v", got[2])
+	}
+}
+
+func TestCLI_BatchFlagConflicts(t *testing.T) {
+	in := writeBatchFile(t, `{"prompt":"x"}`)
+	for args, want := range map[string][]string{
+		"-batch cannot be combined":         {"-batch", in, "-prompt", "p"},
+		"-output-file, -save-messages":      {"-batch", in, "-output-file", "x.txt"},
+		"-batch-concurrency must be >= 1":   {"-batch", in, "-batch-concurrency", "0"},
+		"-batch-out requires -batch":        {"-prompt", "p", "-batch-out", "x.jsonl"},
+		"-batch line 1: prompt is required": {"-batch", writeBatchFile(t, `{"prompt":""}`)},
+	} {
+		var out, errb bytes.Buffer
+		if code := cliMain(want, &out, &errb); code != 2 || !strings.Contains(errb.String(), args) {
+			t.Errorf("%v: exit=%d stderr=%s", want, code, errb.String())
+		}
+	}
+}
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/clock"
+	"github.com/hyperifyio/goagent/internal/oai"
+	"github.com/hyperifyio/goagent/internal/tools"
+)
+
+// benchTask is one task file (*.json) in a -suite directory.
+type benchTask struct {
+	Name           string   `json:"name,omitempty"`
+	Prompt         string   `json:"prompt"`
+	System         string   `json:"system,omitempty"`
+	ExpectContains []string `json:"expect_contains,omitempty"`
+	ExpectRegex    string   `json:"expect_regex,omitempty"`
+	MaxSteps       int      `json:"max_steps,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// check reports whether the final output satisfies the task expectations.
+func (t benchTask) check(out string) bool {
+	return checkExpectations(out, t.ExpectContains, t.re) == nil
+}
+
+// checkExpectations reports the first expect_contains string missing from
+// out, or an expect_regex mismatch.
+func checkExpectations(out string, contains []string, re *regexp.Regexp) error {
+	for _, want := range contains {
+		if !strings.Contains(out, want) {
+			return fmt.Errorf("expect_contains %q not found", want)
+		}
+	}
+	if re != nil && !re.MatchString(out) {
+		return fmt.Errorf("expect_regex %q does not match", re.String())
+	}
+	return nil
+}
+
+// benchResult is the outcome of one task for one model/strategy pair.
+type benchResult struct {
+	Model            string  `json:"model"`
+	Strategy         string  `json:"strategy"`
+	Task             string  `json:"task"`
+	Passed           bool    `json:"passed"`
+	ExitCode         int     `json:"exit_code"`
+	Steps            int     `json:"steps"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	LatencyMS        int64   `json:"latency_ms"`
+	CostUSD          float64 `json:"cost_usd"`
+	Error            string  `json:"error,omitempty"`
+}
+
+// benchSummary aggregates results per model/strategy pair.
+type benchSummary struct {
+	Model        string  `json:"model"`
+	Strategy     string  `json:"strategy"`
+	Tasks        int     `json:"tasks"`
+	Passed       int     `json:"passed"`
+	SuccessRate  float64 `json:"success_rate"`
+	AvgSteps     float64 `json:"avg_steps"`
+	TotalTokens  int     `json:"total_tokens"`
+	AvgLatencyMS int64   `json:"avg_latency_ms"`
+	CostUSD      float64 `json:"cost_usd"`
+	Priced       bool    `json:"priced"`
+}
+
+// benchPrice is the USD price per million prompt and completion tokens.
+type benchPrice struct{ in, out float64 }
+
+// benchFlagNames are consumed by bench itself; all other flags configure the
+// agent runs exactly as they would for a single invocation.
+var benchFlagNames = map[string]bool{"suite": true, "models": true, "strategies": true, "price": true, "report": true, "report-format": true}
+
+// runBench implements `agentcli bench -suite <dir>`: it runs every task in
+// the suite for each model and strategy and writes a comparison report.
+func runBench(args []string, stdout, stderr io.Writer) int {
+	benchArgs, agentArgs := splitFlagArgs(args, benchFlagNames)
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	var prices stringSliceFlag
+	suiteDir := fs.String("suite", "", "")
+	models := fs.String("models", "", "")
+	strategies := fs.String("strategies", "", "")
+	reportPath := fs.String("report", "", "")
+	format := fs.String("report-format", "markdown", "")
+	fs.Var(&prices, "price", "")
+	if err := fs.Parse(benchArgs); err != nil {
+		safeFprintf(stderr, "error: bench: %v\n", err)
+		return 2
+	}
+	if strings.TrimSpace(*suiteDir) == "" {
+		safeFprintln(stderr, "error: bench requires -suite <dir>")
+		return 2
+	}
+	if *format != "markdown" && *format != "json" {
+		safeFprintf(stderr, "error: invalid -report-format %q (allowed: markdown, json)\n", *format)
+		return 2
+	}
+	priceTable, err := parseBenchPrices(prices)
+	if err != nil {
+		safeFprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+	tasks, err := loadBenchSuite(*suiteDir)
+	if err != nil {
+		safeFprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+
+	// Resolve shared agent settings; prompts come from the suite
+	origArgs := os.Args
+	os.Args = append([]string{origArgs[0], "-prompt", "bench"}, agentArgs...)
+	cfg, code := parseFlags()
+	os.Args = origArgs
+	if code != 0 {
+		safeFprintln(stderr, cfg.parseError)
+		return code
+	}
+	if cfg.deterministic {
+		clock.SetDeterministic(int64(cfg.seed))
+	}
+	stopMetrics, ok := startMetricsServer(cfg, stderr)
+	if !ok {
+		return 2
+	}
+	defer stopMetrics()
+	defer startTracing(stderr)()
+	modelList := splitCSV(*models, cfg.model)
+	strategyList := splitCSV(*strategies, cfg.strategy)
+	for i, s := range strategyList {
+		s = strings.ToLower(s)
+		strategyList[i] = s
+		if s != strategyNative && s != strategyReAct && s != strategyPlan {
+			safeFprintf(stderr, "error: invalid -strategies entry %q (allowed: native, react, plan)\n", s)
+			return 2
+		}
+	}
+	if err := oai.ConfigureSharedTransport(transportOptionsFor(cfg)); err != nil {
+		safeFprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+	tools.SetKillGrace(cfg.toolGrace)
+	tools.SetWorkspace(cfg.workspace, cfg.workspaceRootDirs)
+
+	var results []benchResult
+	for _, model := range modelList {
+		for _, strategy := range strategyList {
+			for _, task := range tasks {
+				r := runBenchTask(cfg, model, strategy, task, priceTable)
+				safeFprintf(stderr, "bench: model=%s strategy=%s task=%s passed=%t steps=%d tokens=%d latency=%dms\n",
+					r.Model, r.Strategy, r.Task, r.Passed, r.Steps, r.TotalTokens, r.LatencyMS)
+				results = append(results, r)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	summaries := summarizeBench(results, priceTable)
+	if *format == "json" {
+		b, _ := json.MarshalIndent(map[string]any{"summary": summaries, "results": results}, "", "  ") //nolint:errcheck
+		buf.Write(b)
+		buf.WriteByte('\n')
+	} else {
+		writeBenchMarkdown(&buf, summaries)
+	}
+	if p := strings.TrimSpace(*reportPath); p != "" {
+		if err := os.WriteFile(p, buf.Bytes(), 0o644); err != nil {
+			safeFprintf(stderr, "error: write bench report: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	_, _ = stdout.Write(buf.Bytes()) //nolint:errcheck
+	return 0
+}
+
+// runBenchTask runs one task in-process through runAgent and scores it.
+func runBenchTask(base cliConfig, model, strategy string, task benchTask, prices map[string]benchPrice) benchResult {
+	cfg := base
+	cfg.model, cfg.strategy = model, strategy
+	cfg.prompt, cfg.promptFile = task.Prompt, ""
+	if strings.TrimSpace(task.System) != "" {
+		cfg.systemPrompt, cfg.systemFile = task.System, ""
+	}
+	if task.MaxSteps > 0 {
+		cfg.maxSteps = task.MaxSteps
+	}
+	cfg.verbose = false
+	var usage runUsage
+	cfg.usageSink = &usage
+
+	var out, errb bytes.Buffer
+	start := time.Now()
+	code := runAgent(cfg, &out, &errb)
+	r := benchResult{
+		Model:            model,
+		Strategy:         strategy,
+		Task:             task.Name,
+		ExitCode:         code,
+		Steps:            usage.calls,
+		PromptTokens:     usage.promptTokens,
+		CompletionTokens: usage.completionTokens,
+		TotalTokens:      usage.totalTokens,
+		LatencyMS:        time.Since(start).Milliseconds(),
+	}
+	r.Passed = code == 0 && task.check(out.String())
+	if code != 0 {
+		r.Error = lastLine(errb.String())
+	}
+	if p, ok := prices[model]; ok {
+		r.CostUSD = (float64(r.PromptTokens)*p.in + float64(r.CompletionTokens)*p.out) / 1e6
+	}
+	return r
+}
+
+// loadBenchSuite reads every *.json task file in dir in name order.
+func loadBenchSuite(dir string) ([]benchTask, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("bench suite %s has no *.json task files", dir)
+	}
+	sort.Strings(paths)
+	tasks := make([]benchTask, 0, len(paths))
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("read bench task: %w", err)
+		}
+		var t benchTask
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&t); err != nil {
+			return nil, fmt.Errorf("bench task %s: %v", filepath.Base(p), err)
+		}
+		if strings.TrimSpace(t.Prompt) == "" {
+			return nil, fmt.Errorf("bench task %s: prompt is required", filepath.Base(p))
+		}
+		if strings.TrimSpace(t.Name) == "" {
+			t.Name = strings.TrimSuffix(filepath.Base(p), ".json")
+		}
+		if t.ExpectRegex != "" {
+			if t.re, err = regexp.Compile(t.ExpectRegex); err != nil {
+				return nil, fmt.Errorf("bench task %s: expect_regex: %v", filepath.Base(p), err)
+			}
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, nil
+}
+
+// parseBenchPrices parses repeatable -price model=in/out values (USD per
+// million prompt/completion tokens).
+func parseBenchPrices(vals []string) (map[string]benchPrice, error) {
+	out := make(map[string]benchPrice, len(vals))
+	for _, v := range vals {
+		model, rates, ok := strings.Cut(v, "=")
+		in, outRate, ok2 := strings.Cut(rates, "/")
+		if !ok || !ok2 || strings.TrimSpace(model) == "" {
+			return nil, fmt.Errorf("invalid -price %q (want model=in/out)", v)
+		}
+		pin, err1 := strconv.ParseFloat(strings.TrimSpace(in), 64)
+		pout, err2 := strconv.ParseFloat(strings.TrimSpace(outRate), 64)
+		if err := errors.Join(err1, err2); err != nil || pin < 0 || pout < 0 {
+			return nil, fmt.Errorf("invalid -price %q (want model=in/out)", v)
+		}
+		out[strings.TrimSpace(model)] = benchPrice{in: pin, out: pout}
+	}
+	return out, nil
+}
+
+func summarizeBench(results []benchResult, prices map[string]benchPrice) []benchSummary {
+	var out []benchSummary
+	index := map[string]int{}
+	for _, r := range results {
+		key := r.Model + "\x00" + r.Strategy
+		i, ok := index[key]
+		if !ok {
+			_, priced := prices[r.Model]
+			out = append(out, benchSummary{Model: r.Model, Strategy: r.Strategy, Priced: priced})
+			i = len(out) - 1
+			index[key] = i
+		}
+		s := &out[i]
+		s.Tasks++
+		if r.Passed {
+			s.Passed++
+		}
+		s.AvgSteps += float64(r.Steps)
+		s.TotalTokens += r.TotalTokens
+		s.AvgLatencyMS += r.LatencyMS
+		s.CostUSD += r.CostUSD
+	}
+	for i := range out {
+		n := out[i].Tasks
+		out[i].SuccessRate = float64(out[i].Passed) / float64(n)
+		out[i].AvgSteps /= float64(n)
+		out[i].AvgLatencyMS /= int64(n)
+	}
+	return out
+}
+
+func writeBenchMarkdown(w io.Writer, summaries []benchSummary) {
+	safeFprintln(w, "| model | strategy | passed | success | avg steps | tokens | avg latency | cost (USD) |")
+	safeFprintln(w, "|---|---|---|---|---|---|---|---|")
+	for _, s := range summaries {
+		cost := "-"
+		if s.Priced {
+			cost = fmt.Sprintf("%.4f", s.CostUSD)
+		}
+		safeFprintf(w, "| %s | %s | %d/%d | %.0f%% | %.1f | %d | %dms | %s |\n",
+			s.Model, s.Strategy, s.Passed, s.Tasks, s.SuccessRate*100, s.AvgSteps, s.TotalTokens, s.AvgLatencyMS, cost)
+	}
+}
+
+// splitFlagArgs separates a subcommand's own flags (names) from agent flags.
+// Both "-name v" and "-name=v" forms are recognized.
+func splitFlagArgs(args []string, names map[string]bool) (own, agent []string) {
+	for i := 0; i < len(args); i++ {
+		name := strings.TrimLeft(args[i], "-")
+		hasValue := strings.Contains(name, "=")
+		name, _, _ = strings.Cut(name, "=")
+		if !strings.HasPrefix(args[i], "-") || !names[name] {
+			agent = append(agent, args[i])
+			continue
+		}
+		own = append(own, args[i])
+		if !hasValue && i+1 < len(args) {
+			i++
+			own = append(own, args[i])
+		}
+	}
+	return own, agent
+}
+
+// splitCSV returns the trimmed, non-empty entries of s, or def when s is blank.
+func splitCSV(s, def string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	if len(out) == 0 {
+		return []string{def}
+	}
+	return out
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+func writeBenchSuite(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"a_math.json":  `{"prompt":"2+2?","expect_contains":["4"]}`,
+		"b_greet.json": `{"name":"greet","prompt":"say hi","expect_regex":"(?i)^hello"}`,
+		"notes.txt":    `ignored`,
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadBenchSuite(t *testing.T) {
+	tasks, err := loadBenchSuite(writeBenchSuite(t))
+	if err != nil || len(tasks) != 2 || tasks[0].Name != "a_math" || tasks[1].Name != "greet" {
+		t.Fatalf("tasks=%+v err=%v", tasks, err)
+	}
+	if !tasks[0].check("it is 4") || tasks[0].check("five") || !tasks[1].check("Hello!") || tasks[1].check("oh hello") {
+		t.Fatalf("expectation checks wrong")
+	}
+	bad := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bad, "x.json"), []byte(`{"prompt":"p","expect":"4"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadBenchSuite(bad); err == nil {
+		t.Fatalf("unknown task field must be rejected")
+	}
+	if _, err := loadBenchSuite(t.TempDir()); err == nil {
+		t.Fatalf("empty suite must be rejected")
+	}
+}
+
+func TestSplitBenchArgsAndPrices(t *testing.T) {
+	bench, agent := splitFlagArgs([]string{"-suite", "d", "-model", "m", "--models=a,b", "-price", "a=1/2", "-max-steps", "3"}, benchFlagNames)
+	if strings.Join(bench, " ") != "-suite d --models=a,b -price a=1/2" || strings.Join(agent, " ") != "-model m -max-steps 3" {
+		t.Fatalf("bench=%v agent=%v", bench, agent)
+	}
+	prices, err := parseBenchPrices([]string{"a=1.5/2"})
+	if err != nil || prices["a"] != (benchPrice{in: 1.5, out: 2}) {
+		t.Fatalf("prices=%v err=%v", prices, err)
+	}
+	for _, v := range []string{"a=1", "=1/2", "a=x/2", "a=-1/2"} {
+		if _, err := parseBenchPrices([]string{v}); err == nil {
+			t.Errorf("%q: expected error", v)
+		}
+	}
+}
+
+func TestCLIMain_Bench_ComparesModels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req oai.ChatCompletionsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		// The "good" model answers both tasks; the "bad" one answers neither
+		reply := "no idea"
+		if req.Model == "good" {
+			reply = "4"
+			if strings.Contains(req.Messages[len(req.Messages)-1].Content, "hi") {
+				reply = "Hello there"
+			}
+		}
+		_ = json.NewEncoder(w).Encode(oai.ChatCompletionsResponse{ //nolint:errcheck
+			Choices: []oai.ChatCompletionsResponseChoice{{Message: oai.Message{Role: oai.RoleAssistant, Channel: "final", Content: reply}}},
+			Usage:   &oai.Usage{PromptTokens: 100, CompletionTokens: 10, TotalTokens: 110},
+		})
+	}))
+	defer srv.Close()
+
+	suite := writeBenchSuite(t)
+	report := filepath.Join(t.TempDir(), "report.json")
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"bench", "-suite", suite, "-models", "good,bad", "-price", "good=1000/1000",
+		"-report", report, "-report-format", "json", "-base-url", srv.URL, "-prep-enabled=false"}, &out, &errb)
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	data, err := os.ReadFile(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Summary []benchSummary `json:"summary"`
+		Results []benchResult  `json:"results"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("report: %v\n%s", err, data)
+	}
+	if len(got.Results) != 4 || len(got.Summary) != 2 {
+		t.Fatalf("report=%s", data)
+	}
+	good, bad := got.Summary[0], got.Summary[1]
+	if good.Model != "good" || good.Passed != 2 || good.SuccessRate != 1 || good.AvgSteps != 1 || good.TotalTokens != 220 || !good.Priced || good.CostUSD != 0.22 {
+		t.Fatalf("good summary=%+v", good)
+	}
+	if bad.Passed != 0 || bad.Priced {
+		t.Fatalf("bad summary=%+v", bad)
+	}
+	if !strings.Contains(errb.String(), "bench: model=bad strategy=native task=greet passed=false") {
+		t.Fatalf("progress missing: %s", errb.String())
+	}
+
+	out.Reset()
+	errb.Reset()
+	if code := cliMain([]string{"bench", "-suite", suite, "-base-url", srv.URL, "-model", "good", "-prep-enabled=false"}, &out, &errb); code != 0 {
+		t.Fatalf("markdown run exit=%d stderr=%s", code, errb.String())
+	}
+	if !strings.Contains(out.String(), "| good | native | 2/2 | 100% | 1.0 | 220 |") {
+		t.Fatalf("markdown report=%s", out.String())
+	}
+}
+
+func TestCLIMain_Bench_Misuse(t *testing.T) {
+	var out, errb bytes.Buffer
+	if code := cliMain([]string{"bench"}, &out, &errb); code != 2 || !strings.Contains(errb.String(), "-suite") {
+		t.Fatalf("missing suite: exit=%d stderr=%s", code, errb.String())
+	}
+	errb.Reset()
+	if code := cliMain([]string{"bench", "-suite", writeBenchSuite(t), "-strategies", "tree"}, &out, &errb); code != 2 {
+		t.Fatalf("bad strategy: exit=%d stderr=%s", code, errb.String())
+	}
+}
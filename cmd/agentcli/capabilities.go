@@ -1,11 +1,13 @@
 package main
 
 import (
-    "encoding/json"
     "fmt"
     "io"
     "os"
     "sort"
+    "strings"
+
+    "github.com/hyperifyio/goagent/internal/tools"
 )
 
 // printCapabilities prints a human-readable summary of enabled tools based on the
@@ -14,14 +16,6 @@ import (
 // - When a manifest is present, lists tools sorted by name with description.
 // - For img_create, an explicit warning is appended.
 func printCapabilities(cfg cliConfig, stdout io.Writer, _ io.Writer) int {
-    type toolEntry struct {
-        Name        string `json:"name"`
-        Description string `json:"description"`
-    }
-    type manifest struct {
-        Tools []toolEntry `json:"tools"`
-    }
-
     // Header to make intent clear in CLI output
     _, _ = io.WriteString(stdout, "Capabilities (enabled tools):\n")
 
@@ -30,29 +24,37 @@ func printCapabilities(cfg cliConfig, stdout io.Writer, _ io.Writer) int {
         return 0
     }
 
-    // Read and parse manifest
-    data, err := os.ReadFile(cfg.toolsPath)
-    if err != nil {
-        // Fall back to minimal notice; keep CLI resilient
-        _, _ = io.WriteString(stdout, "No tools enabled\n")
-        return 0
-    }
-    var m manifest
-    if err := json.Unmarshal(data, &m); err != nil {
+    // Load the manifest with its includes and namespaces; keep CLI resilient
+    // and fall back to a minimal notice when it does not load
+    registry, _, err := tools.LoadManifest(cfg.toolsPath)
+    if err != nil || len(registry) == 0 {
         _, _ = io.WriteString(stdout, "No tools enabled\n")
         return 0
     }
 
-    if len(m.Tools) == 0 {
-        _, _ = io.WriteString(stdout, "No tools enabled\n")
-        return 0
+    // Sort tools by name for deterministic output; aliases point at a tool
+    // listed under its canonical name
+    names := make([]string, 0, len(registry))
+    aliases := make(map[string][]string)
+    for name, spec := range registry {
+        if name != spec.Name {
+            aliases[spec.Name] = append(aliases[spec.Name], name)
+            continue
+        }
+        names = append(names, name)
     }
+    sort.Strings(names)
 
-    // Sort tools by name for deterministic output
-    sort.Slice(m.Tools, func(i, j int) bool { return m.Tools[i].Name < m.Tools[j].Name })
-
-    for _, t := range m.Tools {
+    for _, name := range names {
+        t := registry[name]
         line := fmt.Sprintf("- %s: %s", t.Name, t.Description)
+        if as := aliases[name]; len(as) > 0 {
+            sort.Strings(as)
+            line += fmt.Sprintf(" (aliases: %s)", strings.Join(as, ", "))
+        }
+        if t.SupportsDryRun {
+            line += " [dry-run]"
+        }
         if t.Name == "img_create" {
             line += " [WARNING: makes outbound network calls and can save files]"
         }
@@ -63,3 +63,19 @@ func TestPrintCapabilities_WithManifest(t *testing.T) {
 		t.Fatalf("img_create warning missing or incorrect: %q", got)
 	}
 }
+
+func TestPrintCapabilities_NamespacesAndAliases(t *testing.T) {
+	dir := t.TempDir()
+	toolsPath := filepath.Join(dir, "tools.json")
+	data := `{"namespace":"core","tools":[{"name":"fs_read_file","description":"read","command":["/bin/true"]}],"aliases":{"read":"fs_read_file"}}`
+	if err := os.WriteFile(toolsPath, []byte(data), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	var outBuf, errBuf bytes.Buffer
+	if code := printCapabilities(cliConfig{toolsPath: toolsPath, capabilities: true}, &outBuf, &errBuf); code != 0 {
+		t.Fatalf("exit %d", code)
+	}
+	if got := outBuf.String(); !strings.Contains(got, "- core.fs_read_file: read (aliases: read)\n") || strings.Contains(got, "- read:") {
+		t.Fatalf("unexpected stdout: %q", got)
+	}
+}
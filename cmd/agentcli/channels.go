@@ -1,11 +1,30 @@
 package main
 
-import "strings"
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
 
-// resolveChannelRoute returns the destination for a given assistant channel.
-// Defaults: final→stdout; non-final (critic/confidence)→stderr. Unknown/empty
-// channels default to final behavior. When an override is provided via
-// -channel-route, it takes precedence.
+// fileRoutePrefix marks a -channel-route destination that appends to a file.
+const fileRoutePrefix = "file:"
+
+// channelRouteOther is the -channel-route name for channels without a rule
+// of their own other than final, critic, and confidence.
+const channelRouteOther = "*"
+
+// reChannelName bounds custom channel names to a safe, printable set.
+var reChannelName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// resolveChannelRoute returns the destination for a given assistant channel:
+// stdout, stderr, omit, or file:<path>. Defaults: final→stdout; any other
+// channel→stderr. Empty channels count as final. A -channel-route rule for
+// the channel takes precedence, then the "*" rule for channels other than
+// final, critic, and confidence.
 func resolveChannelRoute(cfg cliConfig, channel string, nonFinal bool) string {
 	ch := strings.TrimSpace(channel)
 	if ch == "" {
@@ -15,6 +34,9 @@ func resolveChannelRoute(cfg cliConfig, channel string, nonFinal bool) string {
 		if dest, ok := cfg.channelRoutes[ch]; ok {
 			return dest
 		}
+		if dest, ok := cfg.channelRoutes[channelRouteOther]; ok && !builtinChannel(ch) {
+			return dest
+		}
 	}
 	if ch == "final" {
 		return "stdout"
@@ -22,3 +44,122 @@ func resolveChannelRoute(cfg cliConfig, channel string, nonFinal bool) string {
 	// Default non-final route
 	return "stderr"
 }
+
+// hasFileRoute reports whether any -channel-route rule writes to a file.
+func hasFileRoute(cfg cliConfig) bool {
+	for _, dest := range cfg.channelRoutes {
+		if strings.HasPrefix(dest, fileRoutePrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func builtinChannel(ch string) bool {
+	return ch == "final" || ch == "critic" || ch == "confidence"
+}
+
+// parseChannelRoute parses one -channel-route value: name=dest or
+// name=>dest, where dest is stdout, stderr, omit, or file:<path>.
+func parseChannelRoute(pair string) (name, dest string, err error) {
+	name, dest, ok := strings.Cut(strings.TrimSpace(pair), "=")
+	name, dest = strings.TrimSpace(name), strings.TrimSpace(strings.TrimPrefix(dest, ">"))
+	if !ok || name == "" || dest == "" {
+		return "", "", fmt.Errorf("invalid -channel-route value %q (expected name=stdout|stderr|omit|file:<path>)", pair)
+	}
+	if name != channelRouteOther && !reChannelName.MatchString(name) {
+		return "", "", fmt.Errorf("invalid -channel-route channel %q (letters, digits, '.', '_', '-'; or * for all other channels)", name)
+	}
+	switch {
+	case dest == "stdout" || dest == "stderr" || dest == "omit":
+	case strings.HasPrefix(dest, fileRoutePrefix) && strings.TrimSpace(strings.TrimPrefix(dest, fileRoutePrefix)) != "":
+		dest = fileRoutePrefix + filepath.Clean(strings.TrimSpace(strings.TrimPrefix(dest, fileRoutePrefix)))
+	default:
+		return "", "", fmt.Errorf("invalid -channel-route destination %q (allowed: stdout, stderr, omit, file:<path>)", dest)
+	}
+	return name, dest, nil
+}
+
+// emitChannel delivers text on channel per its route. Non-final channels
+// reach stdout or stderr only under -verbose; file routes are written
+// either way, so custom channels can be captured from quiet runs.
+func emitChannel(cfg cliConfig, channel string, nonFinal bool, text string, stdout, stderr io.Writer) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	dest := resolveChannelRoute(cfg, channel, nonFinal)
+	if path, ok := strings.CutPrefix(dest, fileRoutePrefix); ok {
+		cfg.channelSinks.write(path, text, stderr)
+		return
+	}
+	if nonFinal && !cfg.verbose {
+		return
+	}
+	switch dest {
+	case "stdout":
+		safeFprintln(stdout, text)
+	case "stderr":
+		safeFprintln(stderr, text)
+	}
+}
+
+// channelSinks holds the files -channel-route file: rules append to; each
+// file is opened once per process and shared by every run that routes to it.
+type channelSinks struct {
+	mu    sync.Mutex
+	files map[string]*os.File
+	// failed remembers paths that could not be opened so the warning is
+	// printed once
+	failed map[string]bool
+}
+
+func newChannelSinks() *channelSinks {
+	return &channelSinks{files: map[string]*os.File{}, failed: map[string]bool{}}
+}
+
+// write appends text and a newline to path; a nil receiver drops the text.
+func (s *channelSinks) write(path, text string, stderr io.Writer) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f := s.files[path]
+	if f == nil {
+		if s.failed[path] {
+			return
+		}
+		var err error
+		if dir := filepath.Dir(path); dir != "." {
+			if err = os.MkdirAll(dir, 0o755); err != nil {
+				s.failed[path] = true
+				safeFprintf(stderr, "WARN: -channel-route: %v\n", err)
+				return
+			}
+		}
+		f, err = os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			s.failed[path] = true
+			safeFprintf(stderr, "WARN: -channel-route: %v\n", err)
+			return
+		}
+		s.files[path] = f
+	}
+	if _, err := f.WriteString(text + "\n"); err != nil {
+		safeFprintf(stderr, "WARN: -channel-route: write %s: %v\n", path, err)
+	}
+}
+
+// close closes every open sink.
+func (s *channelSinks) close() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for p, f := range s.files {
+		_ = f.Close() //nolint:errcheck
+		delete(s.files, p)
+	}
+}
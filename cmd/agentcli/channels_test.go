@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+func TestParseChannelRoute(t *testing.T) {
+	for pair, want := range map[string][2]string{
+		"critic=stdout":                 {"critic", "stdout"},
+		"analysis=>file:logs/a.log":     {"analysis", "file:logs/a.log"},
+		" commentary = omit ":           {"commentary", "omit"},
+		"*=omit":                        {"*", "omit"},
+		"x.y_z-1=file:./out//trace.txt": {"x.y_z-1", "file:out/trace.txt"},
+	} {
+		name, dest, err := parseChannelRoute(pair)
+		if err != nil || name != want[0] || dest != want[1] {
+			t.Fatalf("%q: got %q %q %v", pair, name, dest, err)
+		}
+	}
+	for _, bad := range []string{"critic", "=stdout", "critic=", "critic=pipe", "critic=file:", "bad name=stdout", "a/b=stdout"} {
+		if _, _, err := parseChannelRoute(bad); err == nil {
+			t.Fatalf("%q: want error", bad)
+		}
+	}
+}
+
+func TestResolveChannelRoute_WildcardSkipsBuiltins(t *testing.T) {
+	cfg := cliConfig{channelRoutes: map[string]string{"*": "omit", "analysis": "file:a.log"}}
+	for ch, want := range map[string]string{
+		"analysis":   "file:a.log",
+		"commentary": "omit",
+		"critic":     "stderr",
+		"confidence": "stderr",
+		"":           "stdout",
+		"final":      "stdout",
+	} {
+		if got := resolveChannelRoute(cfg, ch, ch != "" && ch != "final"); got != want {
+			t.Fatalf("%q: got %q want %q", ch, got, want)
+		}
+	}
+}
+
+func TestChannelRoute_FileSinkCapturesCustomChannelWithoutVerbose(t *testing.T) {
+	step := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		step++
+		msg := oai.Message{Role: oai.RoleAssistant, Channel: "analysis", Content: "thinking " + string(rune('0'+step))}
+		if step == 3 {
+			msg = oai.Message{Role: oai.RoleAssistant, Channel: "final", Content: "answer"}
+		}
+		_ = json.NewEncoder(w).Encode(oai.ChatCompletionsResponse{Choices: []oai.ChatCompletionsResponseChoice{{Message: msg}}}) //nolint:errcheck
+	}))
+	defer srv.Close()
+	log := filepath.Join(t.TempDir(), "logs", "analysis.log")
+
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-prompt", "q", "-base-url", srv.URL, "-model", "m", "-prep-enabled=false",
+		"-channel-route", "analysis=>file:" + log, "-channel-route", "*=omit"}, &out, &errb)
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if out.String() != "answer\n" {
+		t.Fatalf("stdout=%q", out.String())
+	}
+	got, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "thinking 1\nthinking 2\n" {
+		t.Fatalf("log=%q", got)
+	}
+}
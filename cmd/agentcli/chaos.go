@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// chaosRates holds the -chaos injection probabilities, each in [0,1].
+type chaosRates struct {
+	timeout  float64 // HTTP attempt fails as a client timeout
+	http500  float64 // HTTP attempt is answered with a synthetic 500
+	toolFail float64 // tool call fails without running
+}
+
+func (r chaosRates) enabled() bool {
+	return r.timeout > 0 || r.http500 > 0 || r.toolFail > 0
+}
+
+// parseChaos parses -chaos "timeout=0.1,http500=0.05,tool-fail=0.1".
+func parseChaos(raw string) (chaosRates, error) {
+	var r chaosRates
+	fields := map[string]*float64{"timeout": &r.timeout, "http500": &r.http500, "tool-fail": &r.toolFail}
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(part, "=")
+		key = strings.TrimSpace(key)
+		dst, known := fields[key]
+		if !ok || !known {
+			names := make([]string, 0, len(fields))
+			for n := range fields {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			return r, fmt.Errorf("invalid -chaos entry %q; want NAME=PROBABILITY with NAME one of %s", part, strings.Join(names, ", "))
+		}
+		p, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		if err != nil || p < 0 || p > 1 {
+			return r, fmt.Errorf("invalid -chaos probability for %s: %q (want 0..1)", key, strings.TrimSpace(val))
+		}
+		*dst = p
+	}
+	return r, nil
+}
+
+// chaosTimeout is the injected HTTP fault. It reports Timeout so the client
+// retries it like a real one.
+type chaosTimeout struct{}
+
+func (chaosTimeout) Error() string   { return "chaos: injected timeout" }
+func (chaosTimeout) Timeout() bool   { return true }
+func (chaosTimeout) Temporary() bool { return true }
+
+// chaosInjector draws -chaos faults from a generator seeded with -seed, so a
+// run with the same seed and the same sequence of calls fails the same way.
+type chaosInjector struct {
+	rates  chaosRates
+	stderr io.Writer
+	mu     sync.Mutex
+	rng    *rand.Rand
+}
+
+// newChaosInjector returns nil when -chaos is off.
+func newChaosInjector(cfg cliConfig, stderr io.Writer) *chaosInjector {
+	if !cfg.chaos.enabled() {
+		return nil
+	}
+	return &chaosInjector{rates: cfg.chaos, stderr: stderr, rng: rand.New(rand.NewSource(int64(cfg.seed)))}
+}
+
+func (c *chaosInjector) roll(p float64) bool {
+	if c == nil || p <= 0 {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rng.Float64() < p
+}
+
+// install adds the HTTP faults to client.
+func (c *chaosInjector) install(client *oai.Client) {
+	if c == nil || (c.rates.timeout <= 0 && c.rates.http500 <= 0) {
+		return
+	}
+	client.OnRequest(func(req *http.Request) error {
+		if c.roll(c.rates.timeout) {
+			safeFprintf(c.stderr, "chaos: injected timeout for POST %s\n", req.URL.Path)
+			return chaosTimeout{}
+		}
+		if c.roll(c.rates.http500) {
+			safeFprintf(c.stderr, "chaos: injected HTTP 500 for POST %s\n", req.URL.Path)
+			body := `{"error":{"message":"chaos: injected server error"}}`
+			return &oai.HookResponse{Response: &http.Response{
+				StatusCode: http.StatusInternalServerError,
+				Status:     "500 Internal Server Error",
+				Header:     http.Header{"Content-Type": []string{"application/json"}},
+				Body:       io.NopCloser(strings.NewReader(body)),
+			}}
+		}
+		return nil
+	})
+}
+
+// toolFault reports whether this tool call should fail without running.
+func (c *chaosInjector) toolFault(name string) error {
+	if c == nil || !c.roll(c.rates.toolFail) {
+		return nil
+	}
+	safeFprintf(c.stderr, "chaos: injected failure for tool %s\n", name)
+	return fmt.Errorf("chaos: injected tool failure")
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseChaos(t *testing.T) {
+	r, err := parseChaos(" timeout=0.1, http500=0.05,tool-fail=1 ")
+	if err != nil || r.timeout != 0.1 || r.http500 != 0.05 || r.toolFail != 1 {
+		t.Fatalf("rates=%+v err=%v", r, err)
+	}
+	if r, err := parseChaos(""); err != nil || r.enabled() {
+		t.Fatalf("empty: rates=%+v err=%v", r, err)
+	}
+	for _, bad := range []string{"latency=0.1", "timeout", "timeout=2", "http500=-0.1", "tool-fail=x"} {
+		if _, err := parseChaos(bad); err == nil {
+			t.Fatalf("%q: want error", bad)
+		}
+	}
+}
+
+func TestChaosInjector_SeededSequenceRepeats(t *testing.T) {
+	draw := func(seed int) []bool {
+		c := newChaosInjector(cliConfig{chaos: chaosRates{toolFail: 0.5}, seed: seed}, &bytes.Buffer{})
+		var out []bool
+		for i := 0; i < 32; i++ {
+			out = append(out, c.toolFault("t") != nil)
+		}
+		return out
+	}
+	a, b, other := draw(7), draw(7), draw(8)
+	same := true
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("seed 7 differs at draw %d", i)
+		}
+		same = same && a[i] == other[i]
+	}
+	if same {
+		t.Fatalf("seeds 7 and 8 produced the same sequence")
+	}
+	if newChaosInjector(cliConfig{}, nil) != nil {
+		t.Fatalf("injector without -chaos")
+	}
+}
+
+func TestCLIMain_Chaos_ToolFail(t *testing.T) {
+	toolsPath := writeEchoOKTool(t)
+	srv := twoPingServer(t)
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-prompt", "p", "-tools", toolsPath, "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-no-lock", "-chaos", "tool-fail=1"}, &out, &errb)
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if got := out.String(); strings.Count(got, "chaos: injected tool failure") != 2 || strings.Contains(got, "ok") {
+		t.Fatalf("stdout=%q", got)
+	}
+	if !strings.Contains(errb.String(), "chaos: injected failure for tool ping") {
+		t.Fatalf("stderr=%s", errb.String())
+	}
+}
+
+func TestCLIMain_Chaos_HTTP500IsRetried(t *testing.T) {
+	var calls atomic.Int32
+	final := finalAnswerServer(t)
+	defer final.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		final.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-prompt", "p", "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-chaos", "http500=1", "-http-retries", "2", "-http-retry-backoff", "1ms"}, &out, &errb)
+	if code != exitHTTPFailure || calls.Load() != 0 {
+		t.Fatalf("exit=%d server calls=%d stderr=%s", code, calls.Load(), errb.String())
+	}
+	if n := strings.Count(errb.String(), "chaos: injected HTTP 500"); n != 3 {
+		t.Fatalf("want 3 injected attempts, got %d: %s", n, errb.String())
+	}
+
+	out.Reset()
+	errb.Reset()
+	code = cliMain([]string{"-prompt", "p", "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-chaos", "http500=0"}, &out, &errb)
+	if code != 0 || strings.TrimSpace(out.String()) != "done" || calls.Load() != 1 {
+		t.Fatalf("exit=%d stdout=%q calls=%d", code, out.String(), calls.Load())
+	}
+}
+
+func TestParseFlags_ChaosInvalid(t *testing.T) {
+	var out, errb bytes.Buffer
+	if code := cliMain([]string{"-prompt", "p", "-chaos", "latency=0.5"}, &out, &errb); code != 2 || !strings.Contains(errb.String(), "invalid -chaos entry") {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+}
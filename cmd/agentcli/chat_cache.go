@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// chatCache implements -chat-cache: complete chat replies stored on disk
+// under GOAGENT_CACHE_DIR/chat, keyed by the exact request (model, messages,
+// sampling, tools, and the remaining request fields), so a repeated run
+// replays them without calling the API. A nil *chatCache is disabled.
+type chatCache struct {
+	store   prepCache
+	verbose bool
+	stderr  io.Writer
+}
+
+// newChatCache returns nil unless -chat-cache is set. Its size is capped by
+// GOAGENT_CHAT_CACHE_MAX_BYTES (default 64 MiB).
+func newChatCache(cfg cliConfig, stderr io.Writer) *chatCache {
+	if !cfg.chatCache {
+		return nil
+	}
+	store := prepCache{dir: filepath.Join(cacheRoot(), "chat"), ttl: cfg.chatCacheTTL, maxBytes: cacheMaxBytes("GOAGENT_CHAT_CACHE_MAX_BYTES")}
+	return &chatCache{store: store, verbose: cfg.verbose, stderr: stderr}
+}
+
+// chatCacheKey hashes the request as sent; streaming does not change the reply.
+func chatCacheKey(req oai.ChatCompletionsRequest) (string, bool) {
+	req.Stream = false
+	b, err := json.Marshal(req)
+	if err != nil {
+		return "", false
+	}
+	return sha256SumHex(b), true
+}
+
+// get returns the cached reply to req as a one-choice response.
+func (c *chatCache) get(req oai.ChatCompletionsRequest, step int) (oai.ChatCompletionsResponse, bool) {
+	if c == nil {
+		return oai.ChatCompletionsResponse{}, false
+	}
+	key, ok := chatCacheKey(req)
+	if !ok {
+		return oai.ChatCompletionsResponse{}, false
+	}
+	msgs, ok := c.store.get(key)
+	ok = ok && len(msgs) == 1
+	_ = c.store.withLock(func() error { return c.store.count(ok) }) //nolint:errcheck // stats are best-effort
+	if !ok {
+		return oai.ChatCompletionsResponse{}, false
+	}
+	if c.verbose {
+		safeFprintf(c.stderr, "info: chat cache hit step=%d\n", step)
+	}
+	msg := msgs[0]
+	return oai.ChatCompletionsResponse{Model: req.Model, Choices: []oai.ChatCompletionsResponseChoice{{
+		Message:      msg,
+		FinishReason: oai.NormalizeFinishReason("", msg),
+	}}}, true
+}
+
+// put stores a complete reply; truncated or filtered ones are not replayed.
+// Write failures only warn.
+func (c *chatCache) put(req oai.ChatCompletionsRequest, msg oai.Message, finishReason string) {
+	if c == nil {
+		return
+	}
+	switch oai.NormalizeFinishReason(finishReason, msg) {
+	case "stop", "tool_calls", "function_call":
+	default:
+		return
+	}
+	key, ok := chatCacheKey(req)
+	if !ok {
+		return
+	}
+	if err := c.store.put(key, []oai.Message{msg}); err != nil {
+		safeFprintf(c.stderr, "WARN: chat cache write failed: %v\n", err)
+	}
+}
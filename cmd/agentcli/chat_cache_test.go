@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// countingPingServer asks for one ping call, then answers with its result,
+// counting the requests.
+func countingPingServer(t *testing.T, calls *atomic.Int32) *httptest.Server {
+	t.Helper()
+	return fakeChatServer(t, calls, callTools(func(req oai.ChatCompletionsRequest) string {
+		return "c1=" + req.Messages[len(req.Messages)-1].Content
+	}, toolCall("c1", "ping", `{}`)))
+}
+
+func TestCLIMain_ChatCache_ReplaysRun(t *testing.T) {
+	t.Setenv("GOAGENT_CACHE_DIR", t.TempDir())
+	toolsPath := writeEchoOKTool(t)
+	var calls atomic.Int32
+	srv := countingPingServer(t, &calls)
+	args := []string{"-prompt", "q", "-tools", toolsPath, "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-chat-cache", "-verbose"}
+
+	var first, errb bytes.Buffer
+	if code := cliMain(args, &first, &errb); code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if calls.Load() != 2 || strings.Contains(errb.String(), "chat cache hit") {
+		t.Fatalf("first run calls=%d stderr=%s", calls.Load(), errb.String())
+	}
+
+	var second bytes.Buffer
+	errb.Reset()
+	if code := cliMain(args, &second, &errb); code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if calls.Load() != 2 || second.String() != first.String() || !strings.Contains(first.String(), `c1={"ok":true}`) {
+		t.Fatalf("replay calls=%d stdout=%q want %q", calls.Load(), second.String(), first.String())
+	}
+	if !strings.Contains(errb.String(), "info: chat cache hit step=1") || !strings.Contains(errb.String(), "info: chat cache hit step=2") {
+		t.Fatalf("stderr=%s", errb.String())
+	}
+
+	// Any change to the request misses
+	if code := cliMain(append(args, "-temp", "0.5"), &second, &errb); code != 0 || calls.Load() != 4 {
+		t.Fatalf("changed request: exit=%d calls=%d", code, calls.Load())
+	}
+	// Without the flag the cache is not consulted
+	if code := cliMain(args[:len(args)-2], &second, &errb); code != 0 || calls.Load() != 6 {
+		t.Fatalf("disabled: exit=%d calls=%d", code, calls.Load())
+	}
+}
+
+func TestChatCache_SkipsIncompleteRepliesAndExpires(t *testing.T) {
+	t.Setenv("GOAGENT_CACHE_DIR", t.TempDir())
+	var errb bytes.Buffer
+	c := newChatCache(cliConfig{chatCache: true, chatCacheTTL: -1}, &errb)
+	req := oai.ChatCompletionsRequest{Model: "m", Messages: []oai.Message{{Role: oai.RoleUser, Content: "q"}}}
+	msg := oai.Message{Role: oai.RoleAssistant, Content: "partial"}
+	c.put(req, msg, "length")
+	c.put(req, msg, "content_filter")
+	if _, ok := c.get(req, 1); ok {
+		t.Fatalf("incomplete reply must not be cached")
+	}
+
+	c.put(req, msg, "")
+	streamed := req
+	streamed.Stream = true
+	got, ok := c.get(streamed, 1)
+	if !ok || got.Choices[0].Message.Content != "partial" || got.Choices[0].FinishReason != "stop" {
+		t.Fatalf("got %+v %v", got, ok)
+	}
+	b, err := json.Marshal(got)
+	if err != nil || !strings.Contains(string(b), `"model":"m"`) {
+		t.Fatalf("response=%s %v", b, err)
+	}
+
+	c.store.ttl = 1
+	if _, ok := c.get(req, 1); ok {
+		t.Fatalf("expired reply must miss")
+	}
+	if newChatCache(cliConfig{}, &errb) != nil {
+		t.Fatalf("cache must be off by default")
+	}
+}
+
+func TestParseFlags_ChatCache(t *testing.T) {
+	orig := os.Args
+	defer func() { os.Args = orig }()
+	t.Setenv("AGENTCLI_CHAT_CACHE", "true")
+	t.Setenv("AGENTCLI_CHAT_CACHE_TTL", "2h")
+	os.Args = []string{"agentcli.test", "-prompt", "p"}
+	if cfg, code := parseFlags(); code != 0 || !cfg.chatCache || cfg.chatCacheTTL != 2*time.Hour {
+		t.Fatalf("env: code=%d cache=%v ttl=%v", code, cfg.chatCache, cfg.chatCacheTTL)
+	}
+	os.Args = []string{"agentcli.test", "-prompt", "p", "-chat-cache=false", "-chat-cache-ttl", "5m"}
+	if cfg, _ := parseFlags(); cfg.chatCache || cfg.chatCacheTTL != 5*time.Minute {
+		t.Fatalf("flags must win: cache=%v ttl=%v", cfg.chatCache, cfg.chatCacheTTL)
+	}
+	var out, errb bytes.Buffer
+	if code := cliMain([]string{"-prompt", "p", "-chat-cache-ttl", "-1s"}, &out, &errb); code != 2 || !strings.Contains(errb.String(), "-chat-cache-ttl must be >= 0") {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+}
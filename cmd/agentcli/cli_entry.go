@@ -4,6 +4,10 @@ import (
 	"io"
 	"os"
 	"strings"
+
+	"github.com/hyperifyio/goagent/internal/clock"
+	"github.com/hyperifyio/goagent/internal/oai"
+	"github.com/hyperifyio/goagent/internal/tools"
 )
 
 func main() {
@@ -12,8 +16,9 @@ func main() {
 
 // cliMain is a testable entrypoint for the CLI. It accepts argv (excluding program name)
 // and writers for stdout/stderr, returns the intended process exit code, and performs
-// no global side effects beyond temporarily setting os.Args for flag parsing.
-func cliMain(args []string, stdout io.Writer, stderr io.Writer) int {
+// no global side effects beyond temporarily setting os.Args for flag parsing and
+// installing the shared HTTP transport configured by the transport flags.
+func cliMain(args []string, stdout io.Writer, stderr io.Writer) (code int) {
 	// Handle help flags prior to any parsing/validation or side effects
 	if helpRequested(args) {
 		printUsage(stdout)
@@ -24,13 +29,37 @@ func cliMain(args []string, stdout io.Writer, stderr io.Writer) int {
 		printVersion(stdout)
 		return 0
 	}
+	if len(args) > 0 && args[0] == "bench" {
+		return runBench(args[1:], stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "serve" {
+		return runServe(args[1:], stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "fuzz-tools" {
+		return runFuzzTools(args[1:], stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "state" {
+		return runState(args[1:], stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "snapshot" {
+		return runSnapshot(args[1:], stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "completion" {
+		return runCompletion(args[1:], stdout, stderr)
+	}
 
 	// Temporarily set os.Args so parseFlags() (which reads os.Args) sees our args
 	origArgs := os.Args
 	os.Args = append([]string{origArgs[0]}, args...)
 	defer func() { os.Args = origArgs }()
 
+	// -error-json reports the last "error: " line once everything else is printed
+	errLines := &errorLineWriter{w: stderr}
+	stderr = errLines
 	cfg, exitOn := parseFlags()
+	if cfg.errorJSON {
+		defer func() { writeErrorJSON(errLines.w, code, errLines.last) }()
+	}
 	if exitOn != 0 {
 		if strings.TrimSpace(cfg.parseError) != "" {
 			safeFprintln(stderr, cfg.parseError)
@@ -41,6 +70,12 @@ func cliMain(args []string, stdout io.Writer, stderr io.Writer) int {
 		printUsage(stderr)
 		return exitOn
 	}
+	// A replay without -seed reuses the recorded one so requests match
+	cfg = adoptRecordedSeed(cfg)
+	// Reproducible runs: freeze time and seed randomness before anything logs
+	if cfg.deterministic {
+		clock.SetDeterministic(int64(cfg.seed))
+	}
 	// Global dry-run: print intended state actions and exit without executing network calls or writing state
 	if cfg.dryRun {
 		return printStateDryRunPlan(cfg, stdout, stderr)
@@ -48,11 +83,47 @@ func cliMain(args []string, stdout io.Writer, stderr io.Writer) int {
 	if cfg.printConfig {
 		return printResolvedConfig(cfg, stdout)
 	}
+	if len(cfg.diffMessages) == 2 {
+		return runDiffMessages(cfg, stdout, stderr)
+	}
 	if cfg.capabilities {
 		return printCapabilities(cfg, stdout, stderr)
 	}
+	if cfg.prepCacheStats {
+		return printPrepCacheStats(stdout, stderr)
+	}
+	if stateInspection(cfg) {
+		return runStateInspection(cfg, stdout, stderr)
+	}
+	// Install the tuned transport before any client is created
+	if err := oai.ConfigureSharedTransport(transportOptionsFor(cfg)); err != nil {
+		safeFprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+	tools.SetKillGrace(cfg.toolGrace)
+	tools.SetWorkspace(cfg.workspace, cfg.workspaceRootDirs)
+	stopRecording, ok := startRecording(cfg, stderr)
+	if !ok {
+		return 2
+	}
+	defer stopRecording()
+	stopMetrics, ok := startMetricsServer(cfg, stderr)
+	if !ok {
+		return 2
+	}
+	defer stopMetrics()
+	defer startTracing(stderr)()
 	if cfg.prepDryRun {
 		return runPrepDryRun(cfg, stdout, stderr)
 	}
+	if cfg.batchPath != "" {
+		return runBatch(cfg, stdout, stderr)
+	}
+	if cfg.scriptPath != "" {
+		return runScript(cfg, stdout, stderr)
+	}
+	if cfg.outputFormat == outputJSON {
+		return runJSONOutput(cfg, stdout)
+	}
 	return runAgent(cfg, stdout, stderr)
 }
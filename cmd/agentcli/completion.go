@@ -0,0 +1,449 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const completionUsage = "error: usage: agentcli completion bash|zsh|fish|powershell"
+
+// completionFlag is one flag as documented in the help text.
+type completionFlag struct {
+	name    string
+	arg     string // value placeholder ("string", "int", "dir", ...); empty for booleans
+	desc    string
+	values  []string // fixed choices from "...: a|b|c" in the description
+	dynamic bool     // values come from `agentcli completion __values`
+	files   bool     // the value is a path
+}
+
+// completionSection groups the flags of the main command (sub "") or of one
+// subcommand.
+type completionSection struct {
+	sub   string
+	flags []completionFlag
+}
+
+// dynamicCompletions are flags whose values are read at completion time:
+// profile names from the config file and tool names from the manifest.
+var dynamicCompletions = map[string]bool{"profile": true, "approve-tools": true}
+
+var (
+	usageFlagRe    = regexp.MustCompile(`^  -([a-z0-9][a-z0-9-]*)(?: (\S+))?$`)
+	usageSectionRe = regexp.MustCompile(`\(agentcli ([a-z-]+)`)
+	usageChoiceRe  = regexp.MustCompile(`: ([a-z0-9.]+(?:\|[a-z0-9.]+)+)(?:[\s;,)]|$)`)
+	usageFlagRefRe = regexp.MustCompile(`-[a-z][a-z0-9-]*`)
+	usagePathRe    = regexp.MustCompile(`\b(?:file|path|dir|directory)s?\b`)
+)
+
+// completionSections reads the flags from printUsage, which the help snapshot
+// test keeps in step with the flags parseFlags registers, so completions
+// cover every documented flag without a second list to maintain.
+func completionSections() []completionSection {
+	var b strings.Builder
+	printUsage(&b)
+	var sections []completionSection
+	var cur *completionSection
+	lines := strings.Split(b.String(), "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "Flags ("):
+			sections = append(sections, completionSection{})
+			cur = &sections[len(sections)-1]
+			continue
+		case strings.HasSuffix(line, "):") && strings.Contains(line, " flags ("):
+			m := usageSectionRe.FindStringSubmatch(line)
+			if m == nil {
+				cur = nil
+				continue
+			}
+			sections = append(sections, completionSection{sub: m[1]})
+			cur = &sections[len(sections)-1]
+			continue
+		case line == "":
+			cur = nil
+			continue
+		}
+		m := usageFlagRe.FindStringSubmatch(line)
+		if cur == nil || m == nil {
+			continue
+		}
+		f := completionFlag{name: m[1], arg: m[2]}
+		if i+1 < len(lines) && strings.HasPrefix(lines[i+1], "    ") {
+			f.desc = strings.TrimSpace(lines[i+1])
+		}
+		if f.arg != "" {
+			f.dynamic = dynamicCompletions[f.name]
+			if c := usageChoiceRe.FindStringSubmatch(f.desc); c != nil && !f.dynamic {
+				f.values = strings.Split(c[1], "|")
+			}
+			// Mentions of other flags such as -vars-file say nothing about this value
+			prose := usageFlagRefRe.ReplaceAllString(strings.ToLower(f.desc), "")
+			f.files = !f.dynamic && f.values == nil && (f.arg == "dir" || f.arg == "file" || strings.HasSuffix(f.name, "-file") || usagePathRe.MatchString(prose))
+		}
+		cur.flags = append(cur.flags, f)
+	}
+	return sections
+}
+
+// completionSubcommands lists the subcommands in help order.
+func completionSubcommands(sections []completionSection) []string {
+	subs := []string{}
+	for _, s := range sections {
+		if s.sub != "" {
+			subs = append(subs, s.sub)
+		}
+	}
+	return append(subs, "completion")
+}
+
+// flagsFor returns the flags offered after sub: its own and the main ones.
+func flagsFor(sections []completionSection, sub string) []completionFlag {
+	var out []completionFlag
+	seen := map[string]bool{}
+	for _, want := range []string{sub, ""} {
+		for _, s := range sections {
+			if s.sub != want {
+				continue
+			}
+			for _, f := range s.flags {
+				if !seen[f.name] {
+					seen[f.name] = true
+					out = append(out, f)
+				}
+			}
+		}
+		if sub == "" {
+			break
+		}
+	}
+	return out
+}
+
+// allFlags returns every distinct flag across sections.
+func allFlags(sections []completionSection) []completionFlag {
+	var out []completionFlag
+	seen := map[string]bool{}
+	for _, s := range sections {
+		for _, f := range s.flags {
+			if !seen[f.name] {
+				seen[f.name] = true
+				out = append(out, f)
+			}
+		}
+	}
+	return out
+}
+
+// runCompletion implements `agentcli completion SHELL`, printing a script
+// to source, and the hidden `agentcli completion __values FLAG CUR WORDS...`
+// that the scripts call for dynamic values.
+func runCompletion(args []string, stdout, stderr io.Writer) int {
+	if len(args) >= 1 && args[0] == "__values" {
+		if len(args) < 3 {
+			return 2
+		}
+		for _, v := range completionValues(args[1], args[2], args[3:]) {
+			safeFprintln(stdout, v)
+		}
+		return 0
+	}
+	if len(args) != 1 {
+		safeFprintln(stderr, completionUsage)
+		return 2
+	}
+	sections := completionSections()
+	var script string
+	switch args[0] {
+	case "bash":
+		script = bashCompletion(sections)
+	case "zsh":
+		script = zshCompletion(sections)
+	case "fish":
+		script = fishCompletion(sections)
+	case "powershell":
+		script = powershellCompletion(sections)
+	default:
+		safeFprintln(stderr, completionUsage)
+		return 2
+	}
+	safeFprintf(stdout, "%s", script)
+	return 0
+}
+
+// completionValues lists candidates for a dynamic flag given the word being
+// completed and the command line so far. Errors yield no candidates.
+func completionValues(flagName, cur string, words []string) []string {
+	arg := func(name string) string {
+		for i := len(words) - 2; i >= 0; i-- {
+			if words[i] == "-"+name || words[i] == "--"+name {
+				return words[i+1]
+			}
+			if v, ok := strings.CutPrefix(words[i+1], "-"+name+"="); ok {
+				return v
+			}
+		}
+		return ""
+	}
+	configPath := findConfigFile(nonEmptyOr(arg("config"), os.Getenv("AGENTCLI_CONFIG")))
+	switch flagName {
+	case "profile":
+		if configPath == "" {
+			return nil
+		}
+		_, profiles, err := readConfigFile(configPath)
+		if err != nil {
+			return nil
+		}
+		names := make([]string, 0, len(profiles))
+		for name := range profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names
+	case "approve-tools":
+		manifest := arg("tools")
+		if manifest == "" && configPath != "" {
+			if top, _, err := readConfigFile(configPath); err == nil {
+				manifest, _ = top["tools"].(string)
+			}
+		}
+		names := append([]string{"all"}, manifestToolNames(manifest)...)
+		// Complete the last entry of a comma-separated list
+		if i := strings.LastIndexByte(cur, ','); i >= 0 {
+			for j := range names {
+				names[j] = cur[:i+1] + names[j]
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+// manifestToolNames reads tool names from a tools.json without validating
+// the commands, so completion works before the tools are built.
+func manifestToolNames(path string) []string {
+	if strings.TrimSpace(path) == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var man struct {
+		Tools []struct {
+			Name string `json:"name"`
+		} `json:"tools"`
+	}
+	if json.Unmarshal(data, &man) != nil {
+		return nil
+	}
+	var names []string
+	for _, t := range man.Tools {
+		if t.Name != "" {
+			names = append(names, t.Name)
+		}
+	}
+	return names
+}
+
+func flagWords(flags []completionFlag) string {
+	words := make([]string, len(flags))
+	for i, f := range flags {
+		words[i] = "-" + f.name
+	}
+	return strings.Join(words, " ")
+}
+
+// valueCases groups value-taking flags by how their value completes, as
+// "-a|-b" patterns: dynamic, files, free text, and one entry per choice list.
+func valueCases(flags []completionFlag) (dynamic, files, free string, choices [][2]string) {
+	var dyn, fil, fre []string
+	for _, f := range flags {
+		switch {
+		case f.arg == "":
+		case f.dynamic:
+			dyn = append(dyn, "-"+f.name)
+		case f.values != nil:
+			choices = append(choices, [2]string{"-" + f.name, strings.Join(f.values, " ")})
+		case f.files:
+			fil = append(fil, "-"+f.name)
+		default:
+			fre = append(fre, "-"+f.name)
+		}
+	}
+	return strings.Join(dyn, "|"), strings.Join(fil, "|"), strings.Join(fre, "|"), choices
+}
+
+func bashCompletion(sections []completionSection) string {
+	var b strings.Builder
+	dyn, files, free, choices := valueCases(allFlags(sections))
+	b.WriteString("# bash completion for agentcli\n# Load with: source <(agentcli completion bash)\n")
+	b.WriteString("_agentcli() {\n")
+	b.WriteString("    local cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\" sub=\"${COMP_WORDS[1]}\"\n")
+	b.WriteString("    case \"$prev\" in\n")
+	caseArm(&b, dyn, "COMPREPLY=($(compgen -W \"$(\"${COMP_WORDS[0]}\" completion __values \"${prev#-}\" \"$cur\" \"${COMP_WORDS[@]:0:COMP_CWORD}\" 2>/dev/null)\" -- \"$cur\"))")
+	for _, c := range choices {
+		caseArm(&b, c[0], "COMPREPLY=($(compgen -W \""+c[1]+"\" -- \"$cur\"))")
+	}
+	caseArm(&b, files, "COMPREPLY=($(compgen -f -- \"$cur\"))")
+	caseArm(&b, free, "")
+	b.WriteString("    esac\n")
+	fmt.Fprintf(&b, "    if [[ $COMP_CWORD -eq 1 && $cur != -* ]]; then\n        COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n        return\n    fi\n", strings.Join(completionSubcommands(sections), " "))
+	b.WriteString("    if [[ $cur == -* ]]; then\n        local flags\n        case \"$sub\" in\n")
+	for _, s := range sections {
+		if s.sub != "" {
+			fmt.Fprintf(&b, "            %s) flags=\"%s\" ;;\n", s.sub, flagWords(flagsFor(sections, s.sub)))
+		}
+	}
+	fmt.Fprintf(&b, "            *) flags=\"%s\" ;;\n", flagWords(flagsFor(sections, "")))
+	b.WriteString("        esac\n        COMPREPLY=($(compgen -W \"$flags\" -- \"$cur\"))\n        return\n    fi\n")
+	b.WriteString("    COMPREPLY=($(compgen -f -- \"$cur\"))\n}\n")
+	b.WriteString("complete -o default -F _agentcli agentcli\n")
+	return b.String()
+}
+
+// caseArm writes a bash/zsh case arm that runs action and returns; an empty
+// pattern writes nothing.
+func caseArm(b *strings.Builder, pattern, action string) {
+	if pattern == "" {
+		return
+	}
+	fmt.Fprintf(b, "        %s)\n", pattern)
+	if action != "" {
+		fmt.Fprintf(b, "            %s\n", action)
+	}
+	b.WriteString("            return ;;\n")
+}
+
+// zshQuote quotes s for a single-quoted zsh word.
+func zshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func zshCompletion(sections []completionSection) string {
+	var b strings.Builder
+	dyn, files, free, choices := valueCases(allFlags(sections))
+	b.WriteString("#compdef agentcli\n# zsh completion for agentcli\n# Load with: source <(agentcli completion zsh)\n")
+	b.WriteString("_agentcli() {\n")
+	b.WriteString("    local cur=$PREFIX prev=${words[CURRENT-1]} sub=${words[2]}\n")
+	b.WriteString("    case $prev in\n")
+	caseArm(&b, dyn, "compadd -Q -- ${(f)\"$(${words[1]} completion __values ${prev#-} \"$cur\" ${words[1,CURRENT-1]} 2>/dev/null)\"}")
+	for _, c := range choices {
+		caseArm(&b, c[0], "compadd -- "+c[1])
+	}
+	caseArm(&b, files, "_files")
+	caseArm(&b, free, "")
+	b.WriteString("    esac\n")
+	fmt.Fprintf(&b, "    if (( CURRENT == 2 )) && [[ $cur != -* ]]; then\n        compadd -- %s\n        return\n    fi\n", strings.Join(completionSubcommands(sections), " "))
+	b.WriteString("    if [[ $cur == -* ]]; then\n        local -a flags\n        case $sub in\n")
+	writeZshFlags := func(pattern string, flags []completionFlag) {
+		fmt.Fprintf(&b, "            %s)\n                flags=(\n", pattern)
+		for _, f := range flags {
+			fmt.Fprintf(&b, "                    %s\n", zshQuote("-"+f.name+":"+f.desc))
+		}
+		b.WriteString("                ) ;;\n")
+	}
+	for _, s := range sections {
+		if s.sub != "" {
+			writeZshFlags(s.sub, flagsFor(sections, s.sub))
+		}
+	}
+	writeZshFlags("*", flagsFor(sections, ""))
+	b.WriteString("        esac\n        _describe -t flags 'agentcli flag' flags\n        return\n    fi\n")
+	b.WriteString("    _files\n}\n")
+	b.WriteString("compdef _agentcli agentcli\n")
+	return b.String()
+}
+
+// fishQuote quotes s for a single-quoted fish word.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+func fishCompletion(sections []completionSection) string {
+	var b strings.Builder
+	subs := completionSubcommands(sections)
+	b.WriteString("# fish completion for agentcli\n# Load with: agentcli completion fish | source\n")
+	b.WriteString("function __agentcli_values\n    set -l words (commandline -opc)\n    $words[1] completion __values $argv[1] (commandline -ct) $words\nend\n")
+	fmt.Fprintf(&b, "complete -c agentcli -n __fish_use_subcommand -f -a %s\n", fishQuote(strings.Join(subs, " ")))
+	for _, s := range sections {
+		cond := ""
+		if s.sub != "" {
+			cond = " -n " + fishQuote("__fish_seen_subcommand_from "+s.sub)
+		}
+		for _, f := range s.flags {
+			fmt.Fprintf(&b, "complete -c agentcli%s -o %s -d %s", cond, f.name, fishQuote(f.desc))
+			switch {
+			case f.arg == "":
+			case f.dynamic:
+				fmt.Fprintf(&b, " -x -a %s", fishQuote("(__agentcli_values "+f.name+")"))
+			case f.values != nil:
+				fmt.Fprintf(&b, " -x -a %s", fishQuote(strings.Join(f.values, " ")))
+			case f.files:
+				b.WriteString(" -r -F")
+			default:
+				b.WriteString(" -x")
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// psQuote quotes s for a single-quoted PowerShell string.
+func psQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func psList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, s := range items {
+		quoted[i] = psQuote(s)
+	}
+	return "@(" + strings.Join(quoted, ", ") + ")"
+}
+
+func powershellCompletion(sections []completionSection) string {
+	var b strings.Builder
+	b.WriteString("# PowerShell completion for agentcli\n# Load with: agentcli completion powershell | Out-String | Invoke-Expression\n")
+	b.WriteString("Register-ArgumentCompleter -Native -CommandName 'agentcli', 'agentcli.exe' -ScriptBlock {\n")
+	b.WriteString("    param($wordToComplete, $commandAst, $cursorPosition)\n")
+	b.WriteString("    $words = @($commandAst.CommandElements | ForEach-Object { $_.ToString() })\n")
+	b.WriteString("    if ($wordToComplete -ne '' -and $words.Count -gt 0) { $words = @($words[0..($words.Count - 2)]) }\n")
+	b.WriteString("    $prev = if ($words.Count -gt 0) { $words[-1] } else { '' }\n")
+	b.WriteString("    $sub = if ($words.Count -gt 1) { $words[1] } else { '' }\n")
+	b.WriteString("    $values = $null\n")
+	b.WriteString("    switch -exact ($prev) {\n")
+	for _, f := range allFlags(sections) {
+		switch {
+		case f.arg == "":
+		case f.dynamic:
+			fmt.Fprintf(&b, "        %s { $values = @(& $words[0] completion __values %s $wordToComplete @words 2>$null) }\n", psQuote("-"+f.name), f.name)
+		case f.values != nil:
+			fmt.Fprintf(&b, "        %s { $values = %s }\n", psQuote("-"+f.name), psList(f.values))
+		default:
+			// Paths and free text: leave it to the default completion
+			fmt.Fprintf(&b, "        %s { return }\n", psQuote("-"+f.name))
+		}
+	}
+	b.WriteString("    }\n")
+	b.WriteString("    if ($null -eq $values) {\n")
+	fmt.Fprintf(&b, "        if ($words.Count -le 1 -and -not $wordToComplete.StartsWith('-')) { $values = %s }\n", psList(completionSubcommands(sections)))
+	b.WriteString("        elseif ($wordToComplete.StartsWith('-')) {\n            $values = switch ($sub) {\n")
+	for _, s := range sections {
+		if s.sub != "" {
+			fmt.Fprintf(&b, "                %s { %s }\n", psQuote(s.sub), psList(strings.Fields(flagWords(flagsFor(sections, s.sub)))))
+		}
+	}
+	fmt.Fprintf(&b, "                default { %s }\n", psList(strings.Fields(flagWords(flagsFor(sections, "")))))
+	b.WriteString("            }\n        }\n        else { return }\n    }\n")
+	b.WriteString("    $values | Where-Object { $_ -like \"$wordToComplete*\" } | ForEach-Object {\n")
+	b.WriteString("        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)\n    }\n}\n")
+	return b.String()
+}
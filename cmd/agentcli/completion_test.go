@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompletionSections_CoverHelp(t *testing.T) {
+	sections := completionSections()
+	if len(sections) == 0 || sections[0].sub != "" {
+		t.Fatalf("sections=%+v", sections)
+	}
+	byName := map[string]completionFlag{}
+	for _, f := range allFlags(sections) {
+		byName[f.name] = f
+	}
+	for _, name := range []string{"prompt", "tools", "config", "profile", "approve-tools", "strategy", "vars-file", "debug"} {
+		if _, ok := byName[name]; !ok {
+			t.Errorf("missing -%s", name)
+		}
+	}
+	if f := byName["profile"]; !f.dynamic || f.files {
+		t.Errorf("-profile: %+v", f)
+	}
+	if f := byName["approve-tools"]; !f.dynamic {
+		t.Errorf("-approve-tools: %+v", f)
+	}
+	if f := byName["strategy"]; !reflect.DeepEqual(f.values, []string{"native", "react", "plan"}) {
+		t.Errorf("-strategy values: %v", f.values)
+	}
+	for _, name := range []string{"tools", "config", "vars-file", "state-dir"} {
+		if !byName[name].files {
+			t.Errorf("-%s should complete paths", name)
+		}
+	}
+	if f := byName["prep-profile"]; f.files {
+		t.Errorf("-prep-profile should not complete paths")
+	}
+	subs := completionSubcommands(sections)
+	for _, want := range []string{"bench", "state", "completion"} {
+		if !strings.Contains(" "+strings.Join(subs, " ")+" ", " "+want+" ") {
+			t.Errorf("subcommands %v missing %s", subs, want)
+		}
+	}
+}
+
+func TestCompletion_ScriptsListAllFlags(t *testing.T) {
+	flags := allFlags(completionSections())
+	for _, shell := range []string{"bash", "zsh", "fish", "powershell"} {
+		var out, errb bytes.Buffer
+		if code := runCompletion([]string{shell}, &out, &errb); code != 0 {
+			t.Fatalf("%s: exit=%d stderr=%s", shell, code, errb.String())
+		}
+		script := out.String()
+		for _, f := range flags {
+			word := "-" + f.name
+			if shell == "fish" {
+				word = "-o " + f.name + " "
+			}
+			if !strings.Contains(script, word) {
+				t.Errorf("%s: missing %s", shell, f.name)
+			}
+		}
+		if !strings.Contains(script, "completion __values") {
+			t.Errorf("%s: no dynamic value lookup", shell)
+		}
+	}
+
+	var out, errb bytes.Buffer
+	if code := runCompletion([]string{"tcsh"}, &out, &errb); code != 2 || !strings.Contains(errb.String(), "usage") {
+		t.Fatalf("unknown shell: exit=%d stderr=%s", code, errb.String())
+	}
+	errb.Reset()
+	if code := cliMain([]string{"completion"}, &out, &errb); code != 2 {
+		t.Fatalf("no shell: exit=%d", code)
+	}
+}
+
+func TestCompletionValues(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("AGENTCLI_CONFIG", "")
+	if err := os.WriteFile(filepath.Join(dir, ".goagent.yaml"), []byte("tools: tools.json\nprofiles:\n  slow:\n    model: a\n  fast:\n    model: b\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "tools.json"), []byte(`{"tools":[{"name":"fs_read_file"},{"name":"get_time"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "other.json"), []byte(`{"tools":[{"name":"exec"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := completionValues("profile", "", []string{"agentcli", "-profile"}); !reflect.DeepEqual(got, []string{"fast", "slow"}) {
+		t.Fatalf("profiles=%v", got)
+	}
+	if got := completionValues("profile", "", []string{"agentcli", "-config", "missing.yaml", "-profile"}); got != nil {
+		t.Fatalf("missing config: %v", got)
+	}
+	if got := completionValues("approve-tools", "", []string{"agentcli", "-approve-tools"}); !reflect.DeepEqual(got, []string{"all", "fs_read_file", "get_time"}) {
+		t.Fatalf("config manifest: %v", got)
+	}
+	got := completionValues("approve-tools", "get_time,", []string{"agentcli", "-tools=other.json", "-approve-tools"})
+	if !reflect.DeepEqual(got, []string{"get_time,all", "get_time,exec"}) {
+		t.Fatalf("list entry: %v", got)
+	}
+}
+
+func TestBashCompletion_Runs(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	var script bytes.Buffer
+	if code := runCompletion([]string{"bash"}, &script, &bytes.Buffer{}); code != 0 {
+		t.Fatal("bash script failed")
+	}
+	// Stand in for the binary so the dynamic lookup is observable
+	driver := script.String() + `
+agentcli() { [[ $1 == completion && $2 == __values ]] && printf '%s\n' "p-$3" "q-$3"; }
+run() { COMP_WORDS=("$@"); COMP_CWORD=$(($# - 1)); COMPREPLY=(); _agentcli; echo "${COMPREPLY[*]}"; }
+run agentcli -profile ""
+run agentcli -strat
+run agentcli -strategy r
+run agentcli ben
+run agentcli bench -sui
+`
+	out, err := exec.Command("bash", "-c", driver).CombinedOutput()
+	if err != nil {
+		t.Fatalf("bash: %v\n%s", err, out)
+	}
+	want := []string{"p-profile q-profile", "-strategy", "react", "bench", "-suite"}
+	if got := strings.Split(strings.TrimSpace(string(out)), "\n"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q want %q", got, want)
+	}
+}
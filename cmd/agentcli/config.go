@@ -1,6 +1,10 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
 	"time"
 
 	"github.com/hyperifyio/goagent/internal/oai"
@@ -12,11 +16,27 @@ type cliConfig struct {
 	// Role inputs: developer and files
 	developerPrompts []string
 	developerFiles   []string
+	// Images sent with the user prompt (-image-attach): files or URLs
+	imageAttach []string
 	systemFile       string
 	promptFile       string
+	systemSet        bool // -system or -system-file was given
+	// -prompt-template: Go text/template rendering the role prompts, with
+	// variables from -var, AGENTCLI_VAR_*, and -vars-file
+	promptTemplate string
+	templateVars   []string
+	varsFile       string
+	templateStrict bool // missing variables are errors instead of empty
 	// Pre-stage specific system message inputs
 	prepSystem      string
 	prepSystemFile  string
+	// Pre-stage prompt overrides (-prep-prompt, -prep-prompt-file, or
+	// OAI_PREP_PROMPT), and the prompt resolved from them with its source
+	// ("override" | "default"); resolved once by runAgent
+	prepPrompts      []string
+	prepPromptFiles  []string
+	prepPromptSource string
+	prepPromptText   string
 	toolsPath       string
 	systemPrompt    string
 	baseURL         string
@@ -27,8 +47,20 @@ type cliConfig struct {
 	httpTimeout     time.Duration // resolved HTTP timeout (final value after env/flags/global)
 	prepHTTPTimeout time.Duration // resolved pre-stage HTTP timeout (inherits from http-timeout)
 	toolTimeout     time.Duration // resolved per-tool timeout (final value after flags/global)
+	toolGrace       time.Duration // SIGTERM to SIGKILL grace for a timed-out or canceled tool
 	httpRetries     int           // number of retries for HTTP
 	httpBackoff     time.Duration // base backoff between retries
+	// Shared HTTP transport tuning (see oai.TransportOptions)
+	httpMaxIdleConns int
+	httpKeepAlive    time.Duration
+	tlsMinVersion    string
+	caBundlePath     string
+	// Chat response size cap and request compression (see oai.Limits)
+	httpMaxResponseBytes int
+	httpGzip             bool
+	// Circuit breaker: consecutive 429/5xx before failing fast (0 disables), and open duration
+	httpBreakerThreshold int
+	httpBreakerCooldown  time.Duration
 	temperature     float64
 	topP            float64
 	prepTopP        float64
@@ -41,13 +73,27 @@ type cliConfig struct {
 	verbose     bool
 	quiet       bool
 	// Pre-stage cache controls
-	prepCacheBust bool // when true, bypass pre-stage cache for this run
+	prepCacheBust  bool // when true, bypass pre-stage cache for this run
+	prepCacheStats bool // when true, print pre-stage cache hit/miss/size and exit
+	// -state-remote: S3-compatible store -state-dir is pulled from and pushed to
+	stateRemote string
+	// State inspection and branching under -state-dir; each prints and exits
+	stateList bool   // -state-list: list saved snapshots
+	stateShow string // -state-show: print the snapshot with this SHA-256 prefix
+	stateFork string // -state-fork: copy the latest snapshot into this scope
 	// Pre-stage master switch
 	prepEnabled bool // when false, completely skip pre-stage
 	// Tracks whether -prep-enabled was explicitly provided by the user
 	prepEnabledSet bool
 	capabilities   bool
 	printConfig    bool
+	// Project config file (-config, or .goagent.yaml in the working
+	// directory) and the -profile preset selected within it
+	configPath    string
+	profile       string
+	configApplied []string // flag names set from the config file
+	modelSource   string   // "flag" | "env" | "config" | "default"
+	baseURLSource string   // "flag" | "env" | "config" | "default"
 	// Dry-run planning for state persistence actions
 	dryRun bool
 	// State persistence
@@ -104,25 +150,199 @@ type cliConfig struct {
 	imageStyle                 string // natural|vivid
 	imageResponseFormat        string // url|b64_json
 	imageTransparentBackground bool
+	// Audio transcription (-audio-prompt): the file's transcript becomes (or
+	// is appended to) the user prompt
+	audioPrompt            string
+	audioBaseURL           string
+	audioAPIKey            string
+	audioModel             string
+	audioLanguage          string
+	audioBaseURLSource     string // "flag" | "env" | "inherit"
+	audioAPIKeySource      string // "flag" | "env|env:OPENAI_API_KEY" | "inherit|empty"
+	audioHTTPTimeout       time.Duration
+	audioHTTPRetries       int
+	audioHTTPBackoff       time.Duration
+	audioHTTPTimeoutSource string // "flag" | "env" | "inherit"
+	audioHTTPRetriesSource string // "flag" | "env" | "inherit"
+	audioHTTPBackoffSource string // "flag" | "env" | "inherit"
 	// Image prompt (optional). Not exposed via flags yet; populated when loading
 	// from a saved messages file that contains an auxiliary "image_prompt" field.
 	imagePrompt string
 	// Message viewing modes
 	prepDryRun    bool // When true, run pre-stage only, print refined messages to stdout, and exit
 	printMessages bool // When true, pretty-print final merged messages to stderr before main call
+	printPlan     bool // When true, print the pre-stage plan to stderr and again after each plan.update
+	// -diff-messages: OLD,NEW compares two saved files and exits; a single FILE
+	// is compared with this run's merged messages
+	diffMessages []string
 	// Streaming control
 	streamFinal bool // When true, request SSE streaming and print only assistant{channel:"final"} progressively
 	// Save/load refined messages
 	saveMessagesPath string // When set, write the final merged Harmony messages to this JSON path and continue
 	loadMessagesPath string // When set, bypass pre-stage and prompt; load messages JSON verbatim (validator-checked)
+	// Final answer destination: atomic write to a file instead of stdout
+	outputFile   string
+	outputAppend bool // Append to outputFile instead of replacing it
+	ifEmptyFail  bool // Exit 1 without writing when the final answer is empty
+	// Exit-code gating on the final answer: -succeed-if and -fail-if patterns
+	// and their compiled forms
+	succeedIf   string
+	failIf      string
+	succeedIfRe *regexp.Regexp
+	failIfRe    *regexp.Regexp
+	// Print a final {exitCode, reason, message} JSON line to stderr on failure
+	errorJSON bool
+	// Result format on stdout: text (the final answer) or json (one object
+	// describing the run, with all human text suppressed)
+	outputFormat string
+	// Regression gate against a saved transcript: its path, tool-call edits
+	// allowed, minimum final-answer similarity, and whether arguments count
+	goldenPath            string
+	goldenCallTolerance   int
+	goldenFinalSimilarity float64
+	goldenIgnoreArgs      bool
+	// Append the finished transcript to this file as OpenAI fine-tuning JSONL
+	exportJSONL string
 	// Custom channel routing: map specific assistant channels to stdout|stderr|omit
 	channelRoutes map[string]string
+	// channelSinks holds the files -channel-route file: rules write to
+	channelSinks *channelSinks
 	// Raw repeatable flag values for -channel-route parsing (e.g., "critic=stdout")
 	channelRoutePairs []string
+	// Tool schema simplification for small models: "auto" | "always" | "never"
+	schemaSimplify string
+	// Capability tier threshold for -schema-simplify auto: small|medium|large
+	schemaMinTier string
+	// Tool-calling wire protocol: "native" | "functions" | "text"
+	toolProtocol string
+	// Provider-specific fields merged into each main-loop chat request
+	extraBody map[string]json.RawMessage
+	// Chat template name passed through to servers that accept it per request
+	chatTemplate string
+	// -tools-topk advertises only the toolsTopK tools whose descriptions
+	// best match each step (0 advertises all), ranked with embedModel
+	toolsTopK  int
+	embedModel string
+	// -workspace is the default root of the fs_* tools and -workspace-root
+	// the named roots ("name=dir") they may address as "name:path";
+	// workspaceRootDirs holds the validated named roots
+	workspace         string
+	workspaceRoots    []string
+	workspaceRootDirs map[string]string
+	// -prompt-cache marks the stable prompt prefix (system and developer
+	// messages, tool schemas) with cache_control for providers that cache
+	// explicitly
+	promptCache bool
+	// Agent loop strategy: "native" | "react" | "plan"
+	strategy string
+	// validateMessages is -validate-messages: strict rejects an invalid
+	// message sequence, repair fixes it and reports each change
+	validateMessages string
+	// Optional reviewer model that critiques the candidate final answer, and
+	// how many critique-and-revise rounds it may request
+	reviewModel  string
+	reviewRounds int
 	// parseError carries a human-readable parse error for early exit situations
 	parseError string
 	// initMessages allows tests to inject a custom starting transcript to
 	// exercise pre-flight validation paths (e.g., stray tool message). When
 	// empty, the default [system,user] seed is used.
 	initMessages []oai.Message
+	// Reproducible runs: frozen clock and seeded randomness
+	deterministic bool
+	seed          int
+	seedSource    string // "flag" | "env" | "recording" | "default"
+	// sendSeed puts seed on chat requests; set when the seed does not come
+	// from the default, or under -deterministic
+	sendSeed bool
+	// Replay complete chat replies cached for identical requests
+	chatCache    bool
+	chatCacheTTL time.Duration
+	// Register model capabilities from the provider's /models listing,
+	// cached for modelsCacheTTL
+	discoverModels bool
+	modelsCacheTTL time.Duration
+	// Collapse file reads made outdated by a later write (see pruneStaleReads)
+	pruneStaleReads bool
+	// Refuse calls to tools that modify the workspace (see tools.MutatesWorkspace)
+	readOnly bool
+	// Shell command run around every tool call (see tools.CommandHook)
+	toolHookCmd string
+	// Skip the workspace lock normally held while mutating tools are enabled
+	noLock bool
+	// Address serving Prometheus metrics at /metrics; empty disables
+	metricsListen string
+	// Capture every API exchange and tool run to recordDir, or answer them
+	// from the recording in replayDir without network or tool processes
+	recordDir string
+	replayDir string
+	// -model-escalate tiers; cfg.model follows them step by step (nil keeps
+	// -model throughout)
+	modelEscalate modelEscalation
+	// -providers routing table; chat calls fail over down its list (nil uses
+	// -base-url alone)
+	providersPath string
+	providers     *oai.ProviderTable
+	// -provider: "openai" speaks the chat completions API to -base-url;
+	// other providers send the same requests through providerTransport,
+	// which adapts them to the provider's own API
+	provider          string
+	providerTransport http.RoundTripper
+	// Nesting levels the built-in agent.run tool may still spawn; 0 disables it
+	subagentDepth int
+	// The context a run follows instead of SIGINT/SIGTERM: the parent's
+	// tool-call context for nested agent.run children and the call's context
+	// for gRPC runs. toolAllowlist holds the tool names the run may use (nil
+	// keeps all). tokenBudget is the total token budget from -token-budget or
+	// agent.run's max_tokens (0 is unlimited)
+	parentCtx     context.Context
+	toolAllowlist []string
+	tokenBudget   int
+	// Human approval for side-effecting tools: names (or "all") from
+	// -approve-tools, the optional answer source, and the runtime gate
+	approveTools []string
+	approveFile  string
+	approver     *toolApprover
+	// Hand approvals and -strategy plan plans to $VISUAL/$EDITOR instead of
+	// a y/N prompt
+	editor bool
+	// Fault injection from -chaos, and the seeded injector shared with
+	// nested subagents
+	chaos    chaosRates
+	injector *chaosInjector
+	// Live terminal dashboard, and the optional USD price per million prompt
+	// and completion tokens for its cost meter
+	tui      bool
+	tuiPrice *benchPrice
+	// Structured run events (see runEvent); set by -tui
+	events eventSink
+	// Set while the unanswered calls of a loaded transcript run; only then
+	// do mutating calls reuse results recorded in the -state-dir call ledger
+	resuming bool
+	// Pre-stage plan tracked by the built-in plan.update tool; nil when the
+	// transcript carries no plan
+	plan *runPlan
+	// -tasks checklist file, and the checklist tracked by the built-in
+	// task.complete tool
+	tasksPath string
+	checklist *checklist
+	// Per-step prompt composition report printed after the run: "" | "table" | "json"
+	contextReport string
+	// Scripted multi-turn run: path to a JSON file of user turns
+	scriptPath string
+	// Batch run: JSONL file of prompts, result destination ("" is stdout),
+	// and how many items run at once
+	batchPath        string
+	batchOut         string
+	batchConcurrency int
+	// Set by -batch so every item reuses one main and one pre-stage client
+	// (connections, retry breaker); nil builds per-run clients
+	chatClient *oai.Client
+	prepClient *oai.Client
+	// transcriptSink, when set, receives the transcript including the final
+	// answer on success (used by -script to carry it into the next turn)
+	transcriptSink *[]oai.Message
+	// usageSink, when set, receives the run's token accounting on return
+	// (used by the bench subcommand and -batch).
+	usageSink *runUsage
 }
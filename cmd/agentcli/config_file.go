@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configFileNames are looked up in the working directory when neither
+// -config nor AGENTCLI_CONFIG names a file.
+var configFileNames = []string{".goagent.yaml", ".goagent.yml", ".goagent.json"}
+
+// configFlagEnv lists environment variables that override a config entry
+// but are not named as "(env X" in the flag's usage text.
+var configFlagEnv = map[string][]string{
+	"api-key":  {"OPENAI_API_KEY"},
+	"base-url": {"OAI_BASE_URL"},
+	"model":    {"OAI_MODEL"},
+	"temp":     {"LLM_TEMPERATURE"},
+}
+
+var usageEnvRe = regexp.MustCompile(`\(env ([A-Z][A-Z0-9_]*)`)
+
+// configFile is a parsed project config: flag values by flag name, with the
+// selected profile merged over the top-level entries.
+type configFile struct {
+	path   string
+	values map[string][]string
+}
+
+// findConfigFile returns explicit when set, else the first configFileNames
+// entry present in the working directory, else "".
+func findConfigFile(explicit string) string {
+	if p := strings.TrimSpace(explicit); p != "" {
+		return p
+	}
+	for _, name := range configFileNames {
+		if st, err := os.Stat(name); err == nil && st.Mode().IsRegular() {
+			return name
+		}
+	}
+	return ""
+}
+
+// loadConfigFile reads a YAML (or, by .json extension, JSON) mapping of flag
+// names to values. Entries under profiles.<profile> override the top level.
+func loadConfigFile(path, profile string, fs *flag.FlagSet) (configFile, error) {
+	top, profiles, err := readConfigFile(path)
+	if err != nil {
+		return configFile{}, err
+	}
+	values, err := configValues(top, fs)
+	if err != nil {
+		return configFile{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if profile = strings.TrimSpace(profile); profile != "" {
+		preset, ok := profiles[profile].(map[string]any)
+		if !ok {
+			names := make([]string, 0, len(profiles))
+			for name := range profiles {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return configFile{}, fmt.Errorf("%s: profile %q not found (available: %s)", path, profile, nonEmptyOr(strings.Join(names, ", "), "none"))
+		}
+		overrides, err := configValues(preset, fs)
+		if err != nil {
+			return configFile{}, fmt.Errorf("%s: profile %q: %w", path, profile, err)
+		}
+		for name, v := range overrides {
+			values[name] = v
+		}
+	}
+	return configFile{path: path, values: values}, nil
+}
+
+// readConfigFile parses a config file into its top-level entries and its
+// profiles section.
+func readConfigFile(path string) (map[string]any, map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var root any
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &root)
+	} else {
+		err = yaml.Unmarshal(data, &root)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if root == nil {
+		// An empty file sets nothing
+		root = map[string]any{}
+	}
+	top, ok := root.(map[string]any)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s: want a mapping of flag names to values", path)
+	}
+	profiles, _ := top["profiles"].(map[string]any)
+	if top["profiles"] != nil && profiles == nil {
+		return nil, nil, fmt.Errorf("%s: profiles must map names to flag settings", path)
+	}
+	delete(top, "profiles")
+	return top, profiles, nil
+}
+
+// configValues converts config entries to flag.Set arguments. Keys are flag
+// names (a leading "-" is allowed); sequences set repeatable flags once per
+// item; null entries are skipped.
+func configValues(m map[string]any, fs *flag.FlagSet) (map[string][]string, error) {
+	out := make(map[string][]string, len(m))
+	for key, raw := range m {
+		name := strings.TrimLeft(key, "-")
+		switch {
+		case name == "config" || name == "profile" || name == "profiles":
+			return nil, fmt.Errorf("%q cannot be set in a config file", key)
+		case fs.Lookup(name) == nil:
+			return nil, fmt.Errorf("unknown flag %q", key)
+		}
+		items, isList := raw.([]any)
+		if !isList {
+			if raw == nil {
+				continue
+			}
+			items = []any{raw}
+		}
+		vals := make([]string, 0, len(items))
+		for _, item := range items {
+			s, err := configScalar(item)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			vals = append(vals, s)
+		}
+		out[name] = vals
+	}
+	return out, nil
+}
+
+func configScalar(v any) (string, error) {
+	switch x := v.(type) {
+	case string:
+		return x, nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	case int:
+		return strconv.Itoa(x), nil
+	case int64:
+		return strconv.FormatInt(x, 10), nil
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64), nil
+	}
+	return "", fmt.Errorf("want a string, number, boolean, or list of them")
+}
+
+// applyConfigFile sets flags from file that were not given on the command
+// line and whose environment variable is unset, giving the precedence
+// flag > env > config > default. It returns the names it set.
+func applyConfigFile(fs *flag.FlagSet, file configFile) ([]string, error) {
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	names := make([]string, 0, len(file.values))
+	for name := range file.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var applied []string
+	for _, name := range names {
+		if explicit[name] || envOverridesConfig(fs.Lookup(name)) {
+			continue
+		}
+		for _, v := range file.values[name] {
+			if err := fs.Set(name, v); err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", file.path, name, err)
+			}
+		}
+		applied = append(applied, name)
+	}
+	return applied, nil
+}
+
+// envOverridesConfig reports whether an environment variable backing f is
+// set. Flags document their variable as "(env NAME" in their usage text.
+func envOverridesConfig(f *flag.Flag) bool {
+	envs := append([]string{}, configFlagEnv[f.Name]...)
+	if m := usageEnvRe.FindStringSubmatch(f.Usage); m != nil {
+		envs = append(envs, m[1])
+	}
+	for _, env := range envs {
+		if strings.TrimSpace(os.Getenv(env)) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// sourceOf reports "config" for a value resolved as "flag" that the config
+// file supplied; other sources pass through.
+func (c cliConfig) sourceOf(name, resolved string) string {
+	if resolved == "flag" {
+		for _, applied := range c.configApplied {
+			if applied == name {
+				return "config"
+			}
+		}
+	}
+	return resolved
+}
+
+// flagSource reports where a plain flag's value came from; env is the
+// variable read as its default.
+func flagSource(cfg cliConfig, name, env string) string {
+	if src := cfg.sourceOf(name, "flag"); src == "config" {
+		return src
+	}
+	explicit := false
+	flag.CommandLine.Visit(func(f *flag.Flag) { explicit = explicit || f.Name == name })
+	switch {
+	case explicit:
+		return "flag"
+	case strings.TrimSpace(os.Getenv(env)) != "":
+		return "env"
+	}
+	return "default"
+}
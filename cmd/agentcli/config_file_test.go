@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testConfigYAML = `# project defaults
+model: cfg-model
+base-url: http://config.example/v1
+http-timeout: 42s
+max-steps: 3
+channel-route:
+  - critic=stdout
+profiles:
+  fast:
+    model: fast-model
+    prep-enabled: false
+`
+
+// printConfigFrom runs -print-config in dir and decodes the JSON.
+func printConfigFrom(t *testing.T, dir string, args ...string) (map[string]any, string, int) {
+	t.Helper()
+	t.Chdir(dir)
+	var out, errb bytes.Buffer
+	code := cliMain(append([]string{"-print-config"}, args...), &out, &errb)
+	var got map[string]any
+	if code == 0 {
+		if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+			t.Fatalf("decode: %v\n%s", err, out.String())
+		}
+	}
+	return got, errb.String(), code
+}
+
+func writeProjectConfig(t *testing.T, name, body string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestConfigFile_DefaultsAndSources(t *testing.T) {
+	t.Setenv("OAI_MODEL", "")
+	t.Setenv("OAI_BASE_URL", "")
+	t.Setenv("OAI_HTTP_TIMEOUT", "")
+	dir := writeProjectConfig(t, ".goagent.yaml", testConfigYAML)
+
+	got, stderr, code := printConfigFrom(t, dir)
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, stderr)
+	}
+	if got["model"] != "cfg-model" || got["modelSource"] != "config" || got["baseURL"] != "http://config.example/v1" || got["baseURLSource"] != "config" {
+		t.Fatalf("model/baseURL: %v", got)
+	}
+	if got["httpTimeout"] != "42s" || got["httpTimeoutSource"] != "config" {
+		t.Fatalf("httpTimeout=%v source=%v", got["httpTimeout"], got["httpTimeoutSource"])
+	}
+	want := map[string]any{"path": ".goagent.yaml", "profile": "", "flags": []any{"base-url", "channel-route", "http-timeout", "max-steps", "model"}}
+	if !reflect.DeepEqual(got["config"], want) {
+		t.Fatalf("config block: %#v", got["config"])
+	}
+
+	// flag > env > config
+	t.Setenv("OAI_HTTP_TIMEOUT", "7s")
+	got, _, _ = printConfigFrom(t, dir, "-model", "cli-model")
+	if got["model"] != "cli-model" || got["modelSource"] != "flag" || got["httpTimeout"] != "7s" || got["httpTimeoutSource"] != "env" {
+		t.Fatalf("precedence: model=%v/%v timeout=%v/%v", got["model"], got["modelSource"], got["httpTimeout"], got["httpTimeoutSource"])
+	}
+}
+
+func TestConfigFile_Profile(t *testing.T) {
+	t.Setenv("OAI_MODEL", "")
+	dir := writeProjectConfig(t, ".goagent.yaml", testConfigYAML)
+
+	got, stderr, code := printConfigFrom(t, dir, "-profile", "fast")
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, stderr)
+	}
+	if got["model"] != "fast-model" || got["prep"].(map[string]any)["enabled"] != false {
+		t.Fatalf("profile not applied: %v", got)
+	}
+	if got["config"].(map[string]any)["profile"] != "fast" {
+		t.Fatalf("config block: %v", got["config"])
+	}
+
+	if _, stderr, code := printConfigFrom(t, dir, "-profile", "slow"); code != 2 || !strings.Contains(stderr, `profile "slow" not found (available: fast)`) {
+		t.Fatalf("unknown profile: exit=%d stderr=%s", code, stderr)
+	}
+	if _, stderr, code := printConfigFrom(t, t.TempDir(), "-profile", "fast"); code != 2 || !strings.Contains(stderr, "-profile needs a config file") {
+		t.Fatalf("no file: exit=%d stderr=%s", code, stderr)
+	}
+}
+
+func TestConfigFile_ExplicitJSONAndErrors(t *testing.T) {
+	t.Setenv("OAI_MODEL", "")
+	dir := writeProjectConfig(t, "agent.json", `{"model": "json-model", "max-steps": 5}`)
+	got, stderr, code := printConfigFrom(t, dir, "-config", "agent.json")
+	if code != 0 || got["model"] != "json-model" {
+		t.Fatalf("exit=%d model=%v stderr=%s", code, got["model"], stderr)
+	}
+
+	for body, want := range map[string]string{
+		"no-such-flag: 1\n":     `unknown flag "no-such-flag"`,
+		"max-steps: many\n":     "max-steps",
+		"profile: fast\n":       "cannot be set in a config file",
+		"model:\n  nested: x\n": "want a string, number, boolean",
+		"- just\n- a list\n":    "want a mapping",
+		"profiles:\n  - fast\n": "profiles must map names",
+		"model: [unclosed\n":    "yaml:",
+	} {
+		dir := writeProjectConfig(t, ".goagent.yaml", body)
+		if _, stderr, code := printConfigFrom(t, dir); code != 2 || !strings.HasPrefix(stderr, "error: config: ") || !strings.Contains(stderr, want) {
+			t.Errorf("%q: exit=%d stderr=%s", body, code, stderr)
+		}
+	}
+	if _, stderr, code := printConfigFrom(t, t.TempDir(), "-config", "missing.yaml"); code != 2 || !strings.Contains(stderr, "missing.yaml") {
+		t.Fatalf("missing file: exit=%d stderr=%s", code, stderr)
+	}
+}
+
+func TestReadConfigFile_BlockScalarsAndAnchors(t *testing.T) {
+	dir := writeProjectConfig(t, ".goagent.yaml", `system: |
+  Line one.
+  Line two.
+prep-system: >-
+  folded
+  text
+model: &m shared-model
+profiles:
+  fast:
+    model: *m
+`)
+	top, profiles, err := readConfigFile(filepath.Join(dir, ".goagent.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if top["system"] != "Line one.\nLine two.\n" || top["prep-system"] != "folded text" {
+		t.Fatalf("block scalars: %#v", top)
+	}
+	if profiles["fast"].(map[string]any)["model"] != "shared-model" {
+		t.Fatalf("alias: %#v", profiles)
+	}
+	empty := writeProjectConfig(t, ".goagent.yaml", "# nothing yet\n")
+	if top, _, err := readConfigFile(filepath.Join(empty, ".goagent.yaml")); err != nil || len(top) != 0 {
+		t.Fatalf("empty file: %v %v", top, err)
+	}
+}
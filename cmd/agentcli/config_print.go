@@ -44,22 +44,25 @@ func printResolvedConfig(cfg cliConfig, stdout io.Writer) int {
 	// Build a minimal, stable JSON payload
 	payload := map[string]any{
 		"model":                 cfg.model,
+		"modelSource":           nonEmptyOr(cfg.modelSource, "default"),
 		"baseURL":               cfg.baseURL,
+		"baseURLSource":         nonEmptyOr(cfg.baseURLSource, "default"),
 		"httpTimeout":           cfg.httpTimeout.String(),
-		"httpTimeoutSource":     cfg.httpTimeoutSource,
+		"httpTimeoutSource":     cfg.sourceOf("http-timeout", cfg.httpTimeoutSource),
 		"prepHTTPTimeout":       cfg.prepHTTPTimeout.String(),
-		"prepHTTPTimeoutSource": cfg.prepHTTPTimeoutSource,
+		"prepHTTPTimeoutSource": cfg.sourceOf("prep-http-timeout", cfg.prepHTTPTimeoutSource),
 		"toolTimeout":           cfg.toolTimeout.String(),
-		"toolTimeoutSource":     cfg.toolTimeoutSource,
+		"toolTimeoutSource":     cfg.sourceOf("tool-timeout", cfg.toolTimeoutSource),
+		"toolTimeoutGrace":      cfg.toolGrace.String(),
 		"timeout":               cfg.timeout.String(),
-		"timeoutSource":         cfg.globalTimeoutSource,
+		"timeoutSource":         cfg.sourceOf("timeout", cfg.globalTimeoutSource),
 	}
 
 	// Resolve prep-specific view for printing
-	prepModel, prepModelSource := cfg.prepModel, cfg.prepModelSource
-	prepBase, prepBaseSource := cfg.prepBaseURL, cfg.prepBaseURLSource
+	prepModel, prepModelSource := cfg.prepModel, cfg.sourceOf("prep-model", cfg.prepModelSource)
+	prepBase, prepBaseSource := cfg.prepBaseURL, cfg.sourceOf("prep-base-url", cfg.prepBaseURLSource)
 	var apiKeyPresent bool
-	apiKeySource := cfg.prepAPIKeySource
+	apiKeySource := cfg.sourceOf("prep-api-key", cfg.prepAPIKeySource)
 	if strings.TrimSpace(cfg.prepAPIKey) != "" {
 		apiKeyPresent = true
 	} else {
@@ -70,13 +73,13 @@ func printResolvedConfig(cfg cliConfig, stdout io.Writer) int {
 	var prepTempStr, prepTempSource, prepTopPStr, prepTopPSource string
 	if cfg.prepTopP > 0 {
 		prepTopPStr = strconv.FormatFloat(cfg.prepTopP, 'f', -1, 64)
-		prepTopPSource = cfg.prepTopPSource
+		prepTopPSource = cfg.sourceOf("prep-top-p", cfg.prepTopPSource)
 		prepTempStr = "(omitted)"
 		prepTempSource = "omitted:one-knob"
 	} else if cfg.prepTemperatureSource == "flag" || cfg.prepTemperatureSource == "env" {
 		if oai.SupportsTemperature(prepModel) {
 			prepTempStr = strconv.FormatFloat(cfg.prepTemperature, 'f', -1, 64)
-			prepTempSource = cfg.prepTemperatureSource
+			prepTempSource = cfg.sourceOf("prep-temp", cfg.prepTemperatureSource)
 			prepTopPStr = "(omitted)"
 			prepTopPSource = "inherit"
 		} else {
@@ -88,7 +91,7 @@ func printResolvedConfig(cfg cliConfig, stdout io.Writer) int {
 	} else {
 		if oai.SupportsTemperature(prepModel) {
 			prepTempStr = strconv.FormatFloat(cfg.temperature, 'f', -1, 64)
-			prepTempSource = cfg.temperatureSource
+			prepTempSource = cfg.sourceOf("temp", cfg.temperatureSource)
 			prepTopPStr = "(omitted)"
 			prepTopPSource = "inherit"
 		} else {
@@ -99,6 +102,12 @@ func printResolvedConfig(cfg cliConfig, stdout io.Writer) int {
 		}
 	}
 
+	// Pre-stage prompt source without reading -prep-prompt-file contents
+	prepPromptSource, _ := oai.ResolvePrepPrompt(cfg.prepPrompts, "")
+	if len(cfg.prepPrompts) == 0 && len(cfg.prepPromptFiles) > 0 {
+		prepPromptSource = "override"
+	}
+
 	// Pre-stage block
 	payload["prep"] = map[string]any{
 		"enabled":                cfg.prepEnabled,
@@ -109,11 +118,12 @@ func printResolvedConfig(cfg cliConfig, stdout io.Writer) int {
 		"apiKeyPresent":          apiKeyPresent,
 		"apiKeySource":           apiKeySource,
 		"httpTimeout":            cfg.prepHTTPTimeout.String(),
-		"httpTimeoutSource":      cfg.prepHTTPTimeoutSource,
+		"httpTimeoutSource":      cfg.sourceOf("prep-http-timeout", cfg.prepHTTPTimeoutSource),
 		"httpRetries":            cfg.prepHTTPRetries,
-		"httpRetriesSource":      cfg.prepHTTPRetriesSource,
+		"httpRetriesSource":      cfg.sourceOf("prep-http-retries", cfg.prepHTTPRetriesSource),
 		"httpRetryBackoff":       cfg.prepHTTPBackoff.String(),
-		"httpRetryBackoffSource": cfg.prepHTTPBackoffSource,
+		"httpRetryBackoffSource": cfg.sourceOf("prep-http-retry-backoff", cfg.prepHTTPBackoffSource),
+		"promptSource":           prepPromptSource,
 		"sampling": map[string]any{
 			"temperature":       prepTempStr,
 			"temperatureSource": prepTempSource,
@@ -126,16 +136,16 @@ func printResolvedConfig(cfg cliConfig, stdout io.Writer) int {
 		img, baseSrc, keySrc := oai.ResolveImageConfig(cfg.imageBaseURL, cfg.imageAPIKey, cfg.baseURL, cfg.apiKey)
 		payload["image"] = map[string]any{
 			"baseURL":                img.BaseURL,
-			"baseURLSource":          baseSrc,
+			"baseURLSource":          cfg.sourceOf("image-base-url", baseSrc),
 			"apiKey":                 oai.MaskAPIKeyLast4(img.APIKey),
-			"apiKeySource":           keySrc,
+			"apiKeySource":           cfg.sourceOf("image-api-key", keySrc),
 			"model":                  cfg.imageModel,
 			"httpTimeout":            cfg.imageHTTPTimeout.String(),
-			"httpTimeoutSource":      nonEmptyOr(cfg.imageHTTPTimeoutSource, "inherit"),
+			"httpTimeoutSource":      cfg.sourceOf("image-http-timeout", nonEmptyOr(cfg.imageHTTPTimeoutSource, "inherit")),
 			"httpRetries":            cfg.imageHTTPRetries,
-			"httpRetriesSource":      nonEmptyOr(cfg.imageHTTPRetriesSource, "inherit"),
+			"httpRetriesSource":      cfg.sourceOf("image-http-retries", nonEmptyOr(cfg.imageHTTPRetriesSource, "inherit")),
 			"httpRetryBackoff":       cfg.imageHTTPBackoff.String(),
-			"httpRetryBackoffSource": nonEmptyOr(cfg.imageHTTPBackoffSource, "inherit"),
+			"httpRetryBackoffSource": cfg.sourceOf("image-http-retry-backoff", nonEmptyOr(cfg.imageHTTPBackoffSource, "inherit")),
 			"n":                      cfg.imageN,
 			"size":                   cfg.imageSize,
 			"quality":                cfg.imageQuality,
@@ -144,6 +154,31 @@ func printResolvedConfig(cfg cliConfig, stdout io.Writer) int {
 			"transparent_background": cfg.imageTransparentBackground,
 		}
 	}
+	// Audio block with redacted API key
+	payload["audio"] = map[string]any{
+		"baseURL":                cfg.audioBaseURL,
+		"baseURLSource":          cfg.sourceOf("audio-base-url", nonEmptyOr(cfg.audioBaseURLSource, "inherit")),
+		"apiKey":                 oai.MaskAPIKeyLast4(cfg.audioAPIKey),
+		"apiKeySource":           cfg.sourceOf("audio-api-key", nonEmptyOr(cfg.audioAPIKeySource, "inherit")),
+		"model":                  cfg.audioModel,
+		"language":               cfg.audioLanguage,
+		"httpTimeout":            cfg.audioHTTPTimeout.String(),
+		"httpTimeoutSource":      cfg.sourceOf("audio-http-timeout", nonEmptyOr(cfg.audioHTTPTimeoutSource, "inherit")),
+		"httpRetries":            cfg.audioHTTPRetries,
+		"httpRetriesSource":      cfg.sourceOf("audio-http-retries", nonEmptyOr(cfg.audioHTTPRetriesSource, "inherit")),
+		"httpRetryBackoff":       cfg.audioHTTPBackoff.String(),
+		"httpRetryBackoffSource": cfg.sourceOf("audio-http-retry-backoff", nonEmptyOr(cfg.audioHTTPBackoffSource, "inherit")),
+	}
+	// Config file and the flags it supplied
+	configFlags := cfg.configApplied
+	if configFlags == nil {
+		configFlags = []string{}
+	}
+	payload["config"] = map[string]any{
+		"path":    cfg.configPath,
+		"profile": cfg.profile,
+		"flags":   configFlags,
+	}
 
 	data, err := json.MarshalIndent(payload, "", "  ")
 	if err != nil {
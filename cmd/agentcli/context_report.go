@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// contextStep is the estimated prompt composition of one step's request.
+type contextStep struct {
+	Step    int            `json:"step"`
+	Total   int            `json:"total"`
+	Sources map[string]int `json:"sources"`
+}
+
+// contextReport collects -context-report data: per step, estimated tokens by
+// source (system, developer, user, assistant, prep, tool_schemas, and
+// tool:<name> per tool's outputs).
+type contextReport struct {
+	format string
+	tok    oai.Tokenizer
+	seed   map[string]int
+	prep   []bool
+	steps  []contextStep
+}
+
+// newContextReport returns nil when -context-report is off. Tokens are
+// counted with model's tokenizer. seed is the transcript before the
+// pre-stage, used to tell injected messages apart.
+func newContextReport(format, model string, seed []oai.Message) *contextReport {
+	if format == "" {
+		return nil
+	}
+	r := &contextReport{format: format, tok: oai.TokenizerForModel(model), seed: make(map[string]int, len(seed))}
+	for _, m := range seed {
+		r.seed[m.Role+"\x00"+m.Content]++
+	}
+	return r
+}
+
+// observe records the composition of a step's request messages (after
+// hygiene, before any strategy rewrite) and advertised tools. A length retry
+// replaces the step's earlier attempt.
+func (r *contextReport) observe(step int, messages []oai.Message, advertised []oai.Tool) {
+	if r == nil {
+		return
+	}
+	if r.prep == nil {
+		// The first request carries the pre-stage output; anything not in the
+		// seed was injected by it
+		remaining := r.seed
+		r.prep = make([]bool, len(messages))
+		for i, m := range messages {
+			key := m.Role + "\x00" + m.Content
+			if remaining[key] > 0 {
+				remaining[key]--
+				continue
+			}
+			r.prep[i] = true
+		}
+	}
+	cs := contextStep{Step: step, Sources: map[string]int{}}
+	for i, m := range messages {
+		n := oai.CountTokensWith(r.tok, []oai.Message{m})
+		switch {
+		case i < len(r.prep) && r.prep[i]:
+			cs.Sources["prep"] += n
+		case m.Role == oai.RoleTool:
+			cs.Sources["tool:"+nonEmptyOr(m.Name, "unknown")] += n
+		default:
+			cs.Sources[m.Role] += n
+		}
+		cs.Total += n
+	}
+	if len(advertised) > 0 {
+		if b, err := json.Marshal(advertised); err == nil {
+			n := r.tok.CountTokens(string(b))
+			cs.Sources["tool_schemas"] = n
+			cs.Total += n
+		}
+	}
+	if k := len(r.steps); k > 0 && r.steps[k-1].Step == step {
+		r.steps[k-1] = cs
+		return
+	}
+	r.steps = append(r.steps, cs)
+}
+
+// print writes the report to w as an aligned table or as JSON.
+func (r *contextReport) print(w io.Writer) {
+	if r == nil || len(r.steps) == 0 {
+		return
+	}
+	if r.format == "json" {
+		b, err := json.Marshal(map[string]any{"context_report": r.steps})
+		if err == nil {
+			safeFprintln(w, string(b))
+		}
+		return
+	}
+	cols := []string{oai.RoleSystem, oai.RoleDeveloper, oai.RoleUser, oai.RoleAssistant, "prep", "tool_schemas"}
+	var toolCols []string
+	seen := map[string]bool{}
+	for _, s := range r.steps {
+		for k := range s.Sources {
+			if strings.HasPrefix(k, "tool:") && !seen[k] {
+				seen[k] = true
+				toolCols = append(toolCols, k)
+			}
+		}
+	}
+	sort.Strings(toolCols)
+	cols = append(cols, toolCols...)
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "step\t%s\ttotal\t\n", strings.Join(cols, "\t"))
+	for _, s := range r.steps {
+		fmt.Fprintf(tw, "%d\t", s.Step)
+		for _, c := range cols {
+			fmt.Fprintf(tw, "%d\t", s.Sources[c])
+		}
+		fmt.Fprintf(tw, "%d\t\n", s.Total)
+	}
+	_ = tw.Flush() //nolint:errcheck // writes to a strings.Builder
+	safeFprintln(w, "context report (estimated prompt tokens per step):")
+	safeFprintf(w, "%s", sb.String())
+}
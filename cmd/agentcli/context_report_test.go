@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+func TestContextReport_AttributesSources(t *testing.T) {
+	seed := []oai.Message{{Role: oai.RoleSystem, Content: "sys"}, {Role: oai.RoleUser, Content: "question"}}
+	r := newContextReport("json", "", seed)
+	// The pre-stage rewrote the system prompt and added a developer note
+	step1 := []oai.Message{{Role: oai.RoleSystem, Content: "refined sys"}, {Role: oai.RoleDeveloper, Content: "be brief"}, {Role: oai.RoleUser, Content: "question"}}
+	r.observe(1, step1, nil)
+	step2 := append(step1,
+		oai.Message{Role: oai.RoleAssistant, ToolCalls: []oai.ToolCall{{ID: "1", Type: "function", Function: oai.ToolCallFunction{Name: "ping", Arguments: "{}"}}}},
+		oai.Message{Role: oai.RoleTool, Name: "ping", ToolCallID: "1", Content: strings.Repeat("x", 400)})
+	r.observe(2, step2, []oai.Tool{{Type: "function", Function: oai.ToolFunction{Name: "ping"}}})
+	r.observe(2, step2, nil) // length retry replaces step 2
+
+	if len(r.steps) != 2 {
+		t.Fatalf("steps=%d", len(r.steps))
+	}
+	s1 := r.steps[0].Sources
+	if s1["prep"] == 0 || s1[oai.RoleSystem] != 0 || s1[oai.RoleDeveloper] != 0 || s1[oai.RoleUser] == 0 {
+		t.Fatalf("step 1 sources=%v", s1)
+	}
+	s2 := r.steps[1]
+	if s2.Sources["tool:ping"] < 100 || s2.Sources["tool_schemas"] != 0 {
+		t.Fatalf("step 2 sources=%v", s2.Sources)
+	}
+	sum := 0
+	for _, n := range s2.Sources {
+		sum += n
+	}
+	if sum != s2.Total {
+		t.Fatalf("total=%d sum=%d", s2.Total, sum)
+	}
+}
+
+func TestCLIMain_ContextReport_JSON(t *testing.T) {
+	toolsPath := writeEchoOKTool(t)
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		msg := oai.Message{Role: oai.RoleAssistant, Channel: "final", Content: "done"}
+		if atomic.AddInt32(&calls, 1) == 1 {
+			msg = oai.Message{Role: oai.RoleAssistant, ToolCalls: []oai.ToolCall{{ID: "c1", Type: "function", Function: oai.ToolCallFunction{Name: "ping", Arguments: "{}"}}}}
+		}
+		_ = json.NewEncoder(w).Encode(oai.ChatCompletionsResponse{Choices: []oai.ChatCompletionsResponseChoice{{Message: msg}}}) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-prompt", "q", "-tools", toolsPath, "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-context-report", "json"}, &out, &errb)
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	var rep struct {
+		Steps []contextStep `json:"context_report"`
+	}
+	line := lastLine(errb.String())
+	if err := json.Unmarshal([]byte(line), &rep); err != nil {
+		t.Fatalf("report %q: %v", line, err)
+	}
+	if len(rep.Steps) != 2 {
+		t.Fatalf("steps=%+v", rep.Steps)
+	}
+	if rep.Steps[0].Sources["tool_schemas"] == 0 || rep.Steps[0].Sources["tool:ping"] != 0 || rep.Steps[1].Sources["tool:ping"] == 0 {
+		t.Fatalf("sources=%+v", rep.Steps)
+	}
+}
+
+func TestCLIMain_ContextReport_Table(t *testing.T) {
+	srv := finalAnswerServer(t)
+	defer srv.Close()
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-prompt", "q", "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-context-report", "table"}, &out, &errb)
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if !strings.Contains(errb.String(), "context report") || !strings.Contains(errb.String(), "tool_schemas") {
+		t.Fatalf("stderr=%s", errb.String())
+	}
+	if code := cliMain([]string{"-prompt", "q", "-context-report", "xml"}, &out, &errb); code != 2 {
+		t.Fatalf("invalid format exit=%d", code)
+	}
+}
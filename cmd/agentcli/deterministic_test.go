@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/clock"
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+func TestCLIMain_Deterministic_ReproducesRuns(t *testing.T) {
+	defer clock.Reset()
+	t.Setenv("AGENTCLI_DETERMINISTIC", "")
+	t.Setenv("AGENTCLI_SEED", "")
+	toolsPath := writeEchoOKTool(t)
+	var keys []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req oai.ChatCompletionsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		msg := oai.Message{Role: oai.RoleAssistant, Channel: "final", Content: "done"}
+		if req.Messages[len(req.Messages)-1].Role == oai.RoleUser {
+			msg = oai.Message{Role: oai.RoleAssistant, ToolCalls: []oai.ToolCall{{ID: "c1", Type: "function", Function: oai.ToolCallFunction{Name: "ping", Arguments: "{}"}}}}
+		}
+		_ = json.NewEncoder(w).Encode(oai.ChatCompletionsResponse{Choices: []oai.ChatCompletionsResponseChoice{{Message: msg}}}) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	run := func(extra ...string) string {
+		t.Helper()
+		args := append([]string{"-prompt", "p", "-tools", toolsPath, "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-debug"}, extra...)
+		var out, errb bytes.Buffer
+		if code := cliMain(args, &out, &errb); code != 0 {
+			t.Fatalf("exit=%d stderr=%s", code, errb.String())
+		}
+		return out.String() + errb.String()
+	}
+
+	first := run("-deterministic", "-seed", "7")
+	firstKeys := append([]string(nil), keys...)
+	keys = nil
+	second := run("-deterministic", "-seed", "7")
+	if first != second {
+		t.Fatalf("deterministic runs differ:\n%s\n---\n%s", first, second)
+	}
+	if len(firstKeys) != 2 || firstKeys[0] != keys[0] || firstKeys[1] != keys[1] {
+		t.Fatalf("idempotency keys not reproducible: %v vs %v", firstKeys, keys)
+	}
+	if !clock.Now().Equal(clock.DeterministicEpoch) {
+		t.Fatalf("clock not frozen: %s", clock.Now())
+	}
+
+	clock.Reset()
+	keys = nil
+	run("-seed", "7")
+	if keys[0] == firstKeys[0] {
+		t.Fatalf("non-deterministic run reused the seeded idempotency key")
+	}
+}
+
+func TestDeterministicFlag_EnvAndDefaults(t *testing.T) {
+	orig := os.Args
+	defer func() { os.Args = orig }()
+	t.Setenv("AGENTCLI_DETERMINISTIC", "true")
+	t.Setenv("AGENTCLI_SEED", "9")
+	os.Args = []string{"agentcli.test", "-prompt", "p"}
+	cfg, code := parseFlags()
+	if code != 0 || !cfg.deterministic || cfg.seed != 9 {
+		t.Fatalf("env: code=%d deterministic=%v seed=%d", code, cfg.deterministic, cfg.seed)
+	}
+	os.Args = []string{"agentcli.test", "-prompt", "p", "-deterministic=false", "-seed", "3"}
+	cfg, _ = parseFlags()
+	if cfg.deterministic || cfg.seed != 3 {
+		t.Fatalf("flags must win: deterministic=%v seed=%d", cfg.deterministic, cfg.seed)
+	}
+	t.Setenv("AGENTCLI_DETERMINISTIC", "")
+	t.Setenv("AGENTCLI_SEED", "")
+	os.Args = []string{"agentcli.test", "-prompt", "p"}
+	if cfg, _ = parseFlags(); cfg.deterministic || cfg.seed != 1 {
+		t.Fatalf("defaults: deterministic=%v seed=%d", cfg.deterministic, cfg.seed)
+	}
+}
+
+func TestCLIMain_SeedSentAndReusedOnReplay(t *testing.T) {
+	defer clock.Reset()
+	t.Setenv("AGENTCLI_DETERMINISTIC", "")
+	t.Setenv("AGENTCLI_SEED", "")
+	var seeds []*int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req oai.ChatCompletionsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		seeds = append(seeds, req.Seed)
+		_ = json.NewEncoder(w).Encode(oai.ChatCompletionsResponse{SystemFingerprint: "fp_abc", Choices: []oai.ChatCompletionsResponseChoice{{Message: oai.Message{Role: oai.RoleAssistant, Channel: "final", Content: "done"}}}}) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	run := func(extra ...string) string {
+		t.Helper()
+		args := append([]string{"-prompt", "p", "-base-url", srv.URL, "-model", "m", "-prep-enabled=false"}, extra...)
+		var out, errb bytes.Buffer
+		if code := cliMain(args, &out, &errb); code != 0 {
+			t.Fatalf("exit=%d stderr=%s", code, errb.String())
+		}
+		return errb.String()
+	}
+
+	run()
+	if len(seeds) != 1 || seeds[0] != nil {
+		t.Fatalf("seed sent without -seed: %v", seeds)
+	}
+	dir := t.TempDir()
+	if stderr := run("-seed", "7", "-record", dir, "-debug"); !strings.Contains(stderr, `"system_fingerprint": "fp_abc"`) {
+		t.Fatalf("debug output lacks the fingerprint:\n%s", stderr)
+	}
+	if len(seeds) != 2 || seeds[1] == nil || *seeds[1] != 7 {
+		t.Fatalf("seed not sent: %v", seeds)
+	}
+	// Replay without -seed answers from the recording, which needs seed 7
+	run("-replay", dir)
+	if len(seeds) != 2 {
+		t.Fatalf("replay reached the server")
+	}
+	var out, errb bytes.Buffer
+	if code := cliMain([]string{"-prompt", "p", "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-replay", dir, "-seed", "8"}, &out, &errb); code == 0 || !strings.Contains(errb.String(), "diverged") {
+		t.Fatalf("replay with another seed: exit=%d stderr=%s", code, errb.String())
+	}
+}
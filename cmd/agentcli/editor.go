@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// errEditorEmpty reports that the user cleared the file, which declines the
+// handoff the way an empty message aborts git commit.
+var errEditorEmpty = errors.New("empty edit")
+
+// editorCommand returns $VISUAL, then $EDITOR, then vi.
+func editorCommand() string {
+	for _, k := range []string{"VISUAL", "EDITOR"} {
+		if v := strings.TrimSpace(os.Getenv(k)); v != "" {
+			return v
+		}
+	}
+	return "vi"
+}
+
+// editInEditor writes content followed by help as '#' comment lines to a
+// temp file named after name, opens it in the user's editor, and returns the
+// edited text with comment lines dropped and surrounding blank space trimmed.
+// The editor runs through sh so values such as "code --wait" work, and is
+// attached to the controlling terminal when there is one, so it works while
+// stdout is piped.
+func editInEditor(name, content string, help []string) (string, error) {
+	f, err := os.CreateTemp("", "agentcli-*-"+name)
+	if err != nil {
+		return "", fmt.Errorf("create edit file: %w", err)
+	}
+	path := f.Name()
+	defer func() { _ = os.Remove(path) }() //nolint:errcheck // best-effort cleanup
+
+	var b strings.Builder
+	b.WriteString(strings.TrimRight(content, "\n"))
+	b.WriteString("\n\n")
+	for _, ln := range help {
+		b.WriteString("# " + ln + "\n")
+	}
+	_, werr := f.WriteString(b.String())
+	if cerr := f.Close(); werr == nil {
+		werr = cerr
+	}
+	if werr != nil {
+		return "", fmt.Errorf("write edit file: %w", werr)
+	}
+
+	editor := editorCommand()
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", path)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stderr, os.Stderr
+	if tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0); err == nil {
+		defer func() { _ = tty.Close() }() //nolint:errcheck // best-effort close
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = tty, tty, tty
+	}
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %q failed: %w", editor, err)
+	}
+	edited, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read edit file: %w", err)
+	}
+	var kept []string
+	for _, ln := range strings.Split(string(edited), "\n") {
+		if !strings.HasPrefix(ln, "#") {
+			kept = append(kept, strings.TrimRight(ln, "\r"))
+		}
+	}
+	out := strings.TrimSpace(strings.Join(kept, "\n"))
+	if out == "" {
+		return "", errEditorEmpty
+	}
+	return out, nil
+}
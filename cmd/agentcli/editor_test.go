@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// useEditor points $EDITOR at a shell script with the given body; the file
+// to edit is "$1".
+func useEditor(t *testing.T, body string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "editor.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", path)
+}
+
+func TestEditInEditor(t *testing.T) {
+	seen := filepath.Join(t.TempDir(), "seen")
+	useEditor(t, `cp "$1" `+seen+`; printf '# note\n\n  edited  \n#tail\n' > "$1"`)
+	got, err := editInEditor("x.txt", "original\n", []string{"help line"})
+	if err != nil || got != "edited" {
+		t.Fatalf("got %q err %v", got, err)
+	}
+	if b, _ := os.ReadFile(seen); string(b) != "original\n\n# help line\n" {
+		t.Fatalf("editor saw %q", b)
+	}
+
+	useEditor(t, `printf '# only comments\n' > "$1"`)
+	if _, err := editInEditor("x.txt", "a", nil); !errors.Is(err, errEditorEmpty) {
+		t.Fatalf("want errEditorEmpty, got %v", err)
+	}
+	useEditor(t, "exit 3")
+	if _, err := editInEditor("x.txt", "a", nil); err == nil || !strings.Contains(err.Error(), "failed") {
+		t.Fatalf("want editor failure, got %v", err)
+	}
+}
+
+func TestCLIMain_Editor_ApprovalEditsAndDenies(t *testing.T) {
+	toolsPath := writeEchoOKTool(t)
+	srv := twoPingServer(t)
+	// Clear the first call to deny it; rewrite the second
+	useEditor(t, `if grep -q '"n": 1' "$1"; then : > "$1"; else printf '{"n": 20}\n' > "$1"; fi`)
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-prompt", "q", "-tools", toolsPath, "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-approve-tools", "ping", "-editor"}, &out, &errb)
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	got := out.String()
+	if !strings.Contains(got, `c1={"error":"tool call denied by the user"}`) || !strings.Contains(got, `c2={"ok":true}`) {
+		t.Fatalf("stdout=%q", got)
+	}
+	if !strings.Contains(errb.String(), "denied ping") || !strings.Contains(errb.String(), `approved ping with edited arguments {"n":20}`) {
+		t.Fatalf("stderr=%s", errb.String())
+	}
+}
+
+func TestApprover_EditorKeepsUnchangedArguments(t *testing.T) {
+	useEditor(t, "true")
+	var errb bytes.Buffer
+	a := newToolApprover(cliConfig{approveTools: []string{"all"}, editor: true}, &errb)
+	tc := oai.ToolCall{ID: "c", Function: oai.ToolCallFunction{Name: "ping", Arguments: `{ "b":[1, 2] }`}}
+	got, ok := a.approve(tc)
+	if !ok || got.Function.Arguments != tc.Function.Arguments || !strings.Contains(errb.String(), "approved ping\n") {
+		t.Fatalf("ok=%v args=%q stderr=%s", ok, got.Function.Arguments, errb.String())
+	}
+	useEditor(t, `printf '{"b":' > "$1"`)
+	if _, ok := a.approve(tc); ok || !strings.Contains(errb.String(), "not valid JSON") {
+		t.Fatalf("invalid edit must deny: %s", errb.String())
+	}
+}
+
+func TestRunAgent_Editor_ReviewsPlan(t *testing.T) {
+	toolsPath := writeEchoOKTool(t)
+	marker := filepath.Join(t.TempDir(), "rejected")
+	// Reject the first plan, then replace the second with a single task
+	useEditor(t, `if [ ! -e `+marker+` ]; then touch `+marker+`; : > "$1"; else printf '{"plan":[{"id":1,"task":"Only this"}]}\n' > "$1"; fi`)
+	var seen []oai.ChatCompletionsRequest
+	plan := `{"plan":[{"id":1,"task":"Check host"},{"id":2,"task":"Summarize"}]}`
+	srv := planServer(t, []string{plan, plan, "TASK DONE: ok", "answer"}, &seen)
+	defer srv.Close()
+
+	cfg := textProtocolConfig(toolsPath, srv.URL)
+	cfg.toolProtocol = oai.ToolProtocolNative
+	cfg.strategy = strategyPlan
+	cfg.editor = true
+	cfg.maxSteps = 6
+	var out, errb bytes.Buffer
+	if code := runAgent(cfg, &out, &errb); code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if out.String() != "answer\n" {
+		t.Fatalf("stdout=%q", out.String())
+	}
+	if last := seen[1].Messages[len(seen[1].Messages)-1]; !strings.HasPrefix(last.Content, "The user rejected your plan") {
+		t.Fatalf("rejection not sent: %q", last.Content)
+	}
+	if last := seen[2].Messages[len(seen[2].Messages)-1]; !strings.HasPrefix(last.Content, "Plan accepted with changes from the user") {
+		t.Fatalf("acceptance=%q", last.Content)
+	}
+	if sys := seen[2].Messages[0].Content; !strings.Contains(sys, "Current task 1: Only this") || strings.Contains(sys, "Summarize") {
+		t.Fatalf("edited plan not used: %q", sys)
+	}
+}
+
+func TestParseFlags_Editor(t *testing.T) {
+	for args, want := range map[string]string{
+		"-editor": "-editor requires -approve-tools or -strategy plan",
+		"-editor -approve-tools all -approve-file x": "-editor and -approve-file are mutually exclusive",
+		"-editor -strategy plan -tui":                "-editor and -tui are mutually exclusive",
+	} {
+		var out, errb bytes.Buffer
+		if code := cliMain(append([]string{"-prompt", "p"}, strings.Fields(args)...), &out, &errb); code != 2 || !strings.Contains(errb.String(), want) {
+			t.Fatalf("%s: exit=%d stderr=%s", args, code, errb.String())
+		}
+	}
+}
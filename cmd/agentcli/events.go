@@ -0,0 +1,30 @@
+package main
+
+// runEvent is one entry in the structured event stream a run publishes while
+// it works. The -tui dashboard consumes it; Kind selects which fields are set.
+type runEvent struct {
+	Kind     string // eventStep | eventUsage | eventToolStart | eventToolEnd
+	Step     int    // eventStep: 1-based step number
+	MaxSteps int    // eventStep: the step cap
+	Usage    runUsage
+	Tool     string // eventTool*: tool name
+	CallID   string // eventTool*: tool call id
+	Failed   bool   // eventToolEnd: the tool returned an error result
+}
+
+const (
+	eventStep      = "step"
+	eventUsage     = "usage"
+	eventToolStart = "tool_start"
+	eventToolEnd   = "tool_end"
+)
+
+// eventSink receives run events. It may be called from concurrent tool
+// goroutines. A nil sink drops events.
+type eventSink func(runEvent)
+
+func (s eventSink) emit(ev runEvent) {
+	if s != nil {
+		s(ev)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/hyperifyio/goagent/internal/redact"
+)
+
+// Exit codes for failures a wrapping script may want to branch on. 1 stays
+// the catch-all for other operational errors and 2 covers misuse and
+// configuration errors; 3 and 4 are the verdict codes and 130 an interrupt.
+const (
+	exitHTTPFailure    = 5  // chat or transcription call failed after retries
+	exitToolFailure    = 6  // tools manifest unusable or a configured tool missing
+	exitStepCap        = 7  // -max-steps reached without a final answer
+	exitBudgetExceeded = 8  // -token-budget spent before a final answer
+	exitValidation     = 9  // message sequence failed validation
+	exitWorkspaceLock  = 10 // another run holds a workspace lock
+	exitStateRemote    = 11 // -state-remote could not be pulled
+)
+
+// exitReasons names each exit code in -error-json output.
+var exitReasons = map[int]string{
+	1:                   "error",
+	2:                   "config",
+	exitFailIfMatched:   "fail_if",
+	exitSucceedIfMissed: "assertion",
+	exitHTTPFailure:     "http",
+	exitToolFailure:     "tool",
+	exitStepCap:         "step_cap",
+	exitBudgetExceeded:  "budget",
+	exitValidation:      "validation",
+	exitWorkspaceLock:   "locked",
+	exitStateRemote:     "state_remote",
+	exitInterrupted:     "interrupted",
+}
+
+// exitSummaries describe codes whose runs print no "error: " line.
+var exitSummaries = map[int]string{
+	exitFailIfMatched:   "final answer matched -fail-if",
+	exitSucceedIfMissed: "final answer failed its assertions",
+	exitInterrupted:     "interrupted",
+}
+
+// errorLineWriter passes writes through and remembers the last complete
+// "error: " line, the message -error-json reports.
+type errorLineWriter struct {
+	w       io.Writer
+	partial []byte
+	last    string
+}
+
+func (e *errorLineWriter) Write(p []byte) (int, error) {
+	e.partial = append(e.partial, p...)
+	for {
+		i := bytes.IndexByte(e.partial, '\n')
+		if i < 0 {
+			break
+		}
+		if msg, ok := strings.CutPrefix(string(e.partial[:i]), "error: "); ok {
+			e.last = strings.TrimSpace(msg)
+		}
+		e.partial = e.partial[i+1:]
+	}
+	return e.w.Write(p)
+}
+
+// writeErrorJSON prints the -error-json object for a failed run as the last
+// line on stderr: {"exitCode":N,"reason":"...","message":"..."}.
+func writeErrorJSON(w io.Writer, code int, message string) {
+	if code == 0 {
+		return
+	}
+	reason, ok := exitReasons[code]
+	if !ok {
+		reason = "error"
+	}
+	if message == "" {
+		message = nonEmptyOr(exitSummaries[code], reason)
+	}
+	b, err := json.Marshal(struct {
+		ExitCode int    `json:"exitCode"`
+		Reason   string `json:"reason"`
+		Message  string `json:"message"`
+	}{code, reason, redact.String(message)})
+	if err != nil {
+		return
+	}
+	safeFprintln(w, string(b))
+}
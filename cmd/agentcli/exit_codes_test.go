@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+func TestErrorLineWriter_KeepsLastErrorLine(t *testing.T) {
+	var b bytes.Buffer
+	w := &errorLineWriter{w: &b}
+	for _, chunk := range []string{"WARN: x\nerror: fir", "st\n", "info: y\n", "error: second  \n", "error: partial"} {
+		if _, err := io.WriteString(w, chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if w.last != "second" {
+		t.Fatalf("last=%q", w.last)
+	}
+	if !strings.HasSuffix(b.String(), "error: partial") {
+		t.Fatalf("passthrough: %q", b.String())
+	}
+}
+
+// errorJSONLine decodes the final stderr line written by -error-json.
+func errorJSONLine(t *testing.T, stderr string) map[string]any {
+	t.Helper()
+	var got map[string]any
+	if err := json.Unmarshal([]byte(lastLine(stderr)), &got); err != nil {
+		t.Fatalf("last stderr line is not JSON: %v\n%s", err, stderr)
+	}
+	return got
+}
+
+func TestCLI_ErrorJSON(t *testing.T) {
+	// Replies with usage and no content, so the run never finishes on its own
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)                         //nolint:errcheck
+		_ = json.NewEncoder(w).Encode(oai.ChatCompletionsResponse{ //nolint:errcheck
+			Choices: []oai.ChatCompletionsResponseChoice{{Message: oai.Message{Role: oai.RoleAssistant}}},
+			Usage:   &oai.Usage{PromptTokens: 8, CompletionTokens: 2, TotalTokens: 10},
+		})
+	}))
+	defer srv.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	base := []string{"-prompt", "p", "-model", "m", "-prep-enabled=false", "-error-json", "-http-retries", "0"}
+
+	cases := []struct {
+		name    string
+		args    []string
+		code    int
+		reason  string
+		message string
+	}{
+		{"step cap", []string{"-base-url", srv.URL, "-max-steps", "2"}, exitStepCap, "step_cap", "reached maximum steps (2)"},
+		{"budget", []string{"-base-url", srv.URL, "-max-steps", "5", "-token-budget", "15"}, exitBudgetExceeded, "budget", "token budget exhausted (20 of 15 tokens used)"},
+		{"http", []string{"-base-url", failing.URL}, exitHTTPFailure, "http", "chat call failed"},
+		{"tool", []string{"-base-url", srv.URL, "-tools", "missing.json"}, exitToolFailure, "tool", "failed to load tools manifest"},
+		{"config", []string{"-token-budget", "-1"}, 2, "config", "-token-budget must be >= 0"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out, errb bytes.Buffer
+			code := cliMain(append(append([]string{}, base...), tc.args...), &out, &errb)
+			if code != tc.code {
+				t.Fatalf("exit=%d want %d stderr=%s", code, tc.code, errb.String())
+			}
+			got := errorJSONLine(t, errb.String())
+			if got["exitCode"] != float64(tc.code) || got["reason"] != tc.reason || !strings.Contains(got["message"].(string), tc.message) {
+				t.Fatalf("error JSON=%v", got)
+			}
+		})
+	}
+}
+
+func TestCLI_ErrorJSON_SilentOnSuccess(t *testing.T) {
+	srv := finalAnswerServer(t)
+	defer srv.Close()
+	var out, errb bytes.Buffer
+	if code := cliMain([]string{"-prompt", "p", "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-error-json"}, &out, &errb); code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if strings.Contains(errb.String(), "exitCode") {
+		t.Fatalf("unexpected error JSON: %s", errb.String())
+	}
+}
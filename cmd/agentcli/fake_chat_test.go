@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// chatReply answers one decoded chat request; an error is served as a 400
+// with its text.
+type chatReply func(req oai.ChatCompletionsRequest) (oai.ChatCompletionsResponse, error)
+
+// fakeChatServer serves chat completions from reply. calls, when not nil,
+// counts the requests. The server is closed when the test ends.
+func fakeChatServer(t *testing.T, calls *atomic.Int32, reply chatReply) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls != nil {
+			calls.Add(1)
+		}
+		var req oai.ChatCompletionsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		resp, err := reply(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(resp) //nolint:errcheck
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// answer is a response holding the single message msg.
+func answer(msg oai.Message) (oai.ChatCompletionsResponse, error) {
+	return oai.ChatCompletionsResponse{Choices: []oai.ChatCompletionsResponseChoice{{Message: msg}}}, nil
+}
+
+// finalMessage is an assistant message on the final channel.
+func finalMessage(content string) oai.Message {
+	return oai.Message{Role: oai.RoleAssistant, Channel: "final", Content: content}
+}
+
+// callTools asks for calls until the request ends with tool results, then
+// answers with final(req) on the final channel.
+func callTools(final func(req oai.ChatCompletionsRequest) string, calls ...oai.ToolCall) chatReply {
+	return func(req oai.ChatCompletionsRequest) (oai.ChatCompletionsResponse, error) {
+		if req.Messages[len(req.Messages)-1].Role == oai.RoleTool {
+			return answer(finalMessage(final(req)))
+		}
+		return answer(oai.Message{Role: oai.RoleAssistant, ToolCalls: calls})
+	}
+}
+
+// toolCall is a function call of name with JSON args.
+func toolCall(id, name, args string) oai.ToolCall {
+	return oai.ToolCall{ID: id, Type: "function", Function: oai.ToolCallFunction{Name: name, Arguments: args}}
+}
@@ -6,21 +6,41 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/hyperifyio/goagent/internal/oai"
 )
 
-// writeFileAtomic writes data to path atomically by writing to a temp file
-// in the same directory and then renaming it over the destination. Parent
-// directories are created if missing.
+// writeFileAtomic writes data to path atomically by writing to a uniquely
+// named temp file in the same directory and then renaming it over the
+// destination, so readers and concurrent writers never see a partial file.
+// Parent directories are created if missing.
 func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return err
 	}
-	tmp := path + ".tmp"
-	if err := os.WriteFile(tmp, data, perm); err != nil {
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
 		return err
 	}
-	return os.Rename(tmp, path)
+	tmp := f.Name()
+	_, werr := f.Write(data)
+	if werr == nil {
+		werr = f.Sync()
+	}
+	if cerr := f.Close(); werr == nil {
+		werr = cerr
+	}
+	if werr == nil {
+		werr = os.Chmod(tmp, perm)
+	}
+	if werr == nil {
+		werr = os.Rename(tmp, path)
+	}
+	if werr != nil {
+		_ = os.Remove(tmp) //nolint:errcheck // best-effort cleanup of the temp file
+	}
+	return werr
 }
 
 // resolveMaybeFile returns the effective content from either an inline string
@@ -58,3 +78,22 @@ func resolveDeveloperMessages(inlines []string, files []string) ([]string, error
 	out = append(out, inlines...)
 	return out, nil
 }
+
+// resolvePrepPrompt selects the pre-stage prompt with oai.ResolvePrepPrompt.
+// Files are read only when no -prep-prompt strings are set, since those win.
+func resolvePrepPrompt(cfg cliConfig) (source string, text string, err error) {
+	var joined string
+	if len(cfg.prepPrompts) == 0 && len(cfg.prepPromptFiles) > 0 {
+		parts := make([]string, 0, len(cfg.prepPromptFiles))
+		for _, f := range cfg.prepPromptFiles {
+			s, err := resolveMaybeFile("", f)
+			if err != nil {
+				return "", "", err
+			}
+			parts = append(parts, s)
+		}
+		joined = oai.JoinPrompts(parts)
+	}
+	source, text = oai.ResolvePrepPrompt(cfg.prepPrompts, joined)
+	return source, text, nil
+}
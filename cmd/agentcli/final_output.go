@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// writeFinalOutput delivers the final assistant content to -output-file and
+// returns the exit code. The file is replaced atomically, keeping the mode
+// of a file it replaces; with -append the content is added with one
+// O_APPEND write, so concurrent appenders do not lose each other's output.
+// With -if-empty-fail an empty answer exits 1 and leaves the file untouched.
+func writeFinalOutput(cfg cliConfig, content string, stderr io.Writer) int {
+	content = strings.TrimSpace(content)
+	if content == "" && cfg.ifEmptyFail {
+		safeFprintln(stderr, "error: final assistant content is empty (-if-empty-fail)")
+		return 1
+	}
+	data := []byte(content)
+	if content != "" {
+		data = append(data, '\n')
+	}
+	if cfg.outputAppend {
+		if err := appendFile(cfg.outputFile, data); err != nil {
+			safeFprintf(stderr, "error: append -output-file: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	perm := os.FileMode(0o644)
+	if info, err := os.Stat(cfg.outputFile); err == nil {
+		perm = info.Mode().Perm()
+	}
+	if err := writeFileAtomic(cfg.outputFile, data, perm); err != nil {
+		safeFprintf(stderr, "error: write -output-file: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// appendFile adds data to the end of path, creating it (and its directory)
+// when missing.
+func appendFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	_, werr := f.Write(data)
+	return errors.Join(werr, f.Close())
+}
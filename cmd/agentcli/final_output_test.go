@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+func TestCLIMain_OutputFile_ReplacesAndAppends(t *testing.T) {
+	srv := finalAnswerServer(t)
+	defer srv.Close()
+	path := filepath.Join(t.TempDir(), "out", "answer.txt")
+	run := func(extra ...string) (int, string, string) {
+		var out, errb bytes.Buffer
+		args := append([]string{"-prompt", "p", "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-output-file", path}, extra...)
+		code := cliMain(args, &out, &errb)
+		return code, out.String(), errb.String()
+	}
+
+	if code, out, errb := run(); code != 0 || out != "" {
+		t.Fatalf("exit=%d stdout=%q stderr=%s", code, out, errb)
+	}
+	if code, _, errb := run("-append"); code != 0 {
+		t.Fatalf("append: exit=%d stderr=%s", code, errb)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "done\ndone\n" { //nolint:errcheck
+		t.Fatalf("file=%q", b)
+	}
+	if code, _, _ := run(); code != 0 {
+		t.Fatal("replace run failed")
+	}
+	if b, _ := os.ReadFile(path); string(b) != "done\n" { //nolint:errcheck
+		t.Fatalf("replace should truncate: %q", b)
+	}
+	if tmps, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "*.tmp")); len(tmps) != 0 { //nolint:errcheck
+		t.Fatalf("temp files left behind: %v", tmps)
+	}
+}
+
+func TestCLIMain_OutputFile_StreamedEmptyAnswerFails(t *testing.T) {
+	events := []string{`{"choices":[{"index":0,"delta":{"role":"assistant","channel":"final","content":"streamed "}}]}`, `{"choices":[{"index":0,"delta":{"channel":"final","content":"answer"}}]}`}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body) //nolint:errcheck
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, e := range events {
+			_, _ = w.Write([]byte("data: " + e + "\n\n")) //nolint:errcheck
+		}
+		_, _ = w.Write([]byte("data: [DONE]\n\n")) //nolint:errcheck
+	}))
+	defer srv.Close()
+	path := filepath.Join(t.TempDir(), "answer.txt")
+	args := []string{"-prompt", "p", "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-stream-final", "-output-file", path, "-if-empty-fail"}
+
+	var out, errb bytes.Buffer
+	if code := cliMain(args, &out, &errb); code != 0 || out.String() != "" {
+		t.Fatalf("exit=%d stdout=%q stderr=%s", code, out.String(), errb.String())
+	}
+	if b, _ := os.ReadFile(path); string(b) != "streamed answer\n" { //nolint:errcheck
+		t.Fatalf("file=%q", b)
+	}
+
+	events = nil // the next stream carries no content
+	out.Reset()
+	errb.Reset()
+	if code := cliMain(args, &out, &errb); code != 1 || !strings.Contains(errb.String(), "-if-empty-fail") {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if b, _ := os.ReadFile(path); string(b) != "streamed answer\n" { //nolint:errcheck
+		t.Fatalf("file must be untouched: %q", b)
+	}
+}
+
+func TestCLIMain_OutputFile_NoFinalLeavesFileAlone(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)                                                                                                                        //nolint:errcheck
+		_ = json.NewEncoder(w).Encode(oai.ChatCompletionsResponse{Choices: []oai.ChatCompletionsResponseChoice{{Message: oai.Message{Role: oai.RoleAssistant}}}}) //nolint:errcheck
+	}))
+	defer srv.Close()
+	path := filepath.Join(t.TempDir(), "answer.txt")
+	var out, errb bytes.Buffer
+	if code := cliMain([]string{"-prompt", "p", "-base-url", srv.URL, "-prep-enabled=false", "-max-steps", "1", "-output-file", path}, &out, &errb); code != exitStepCap {
+		t.Fatalf("exit=%d", code)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("no final answer must not create the file: %v", err)
+	}
+}
+
+func TestParseFlags_OutputModifiersRequireOutputFile(t *testing.T) {
+	for _, flagName := range []string{"-append", "-if-empty-fail"} {
+		var out, errb bytes.Buffer
+		if code := cliMain([]string{"-prompt", "p", flagName}, &out, &errb); code != 2 || !strings.Contains(errb.String(), "require -output-file") {
+			t.Fatalf("%s: exit=%d stderr=%s", flagName, code, errb.String())
+		}
+	}
+}
+
+func TestWriteFinalOutput_ConcurrentAppendsAndMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "answer.txt")
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	const n = 20
+	done := make(chan int, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			done <- writeFinalOutput(cliConfig{outputFile: path, outputAppend: true}, "line", io.Discard)
+		}()
+	}
+	for i := 0; i < n; i++ {
+		if code := <-done; code != 0 {
+			t.Fatalf("append exit=%d", code)
+		}
+	}
+	if b, _ := os.ReadFile(path); strings.Count(string(b), "line\n") != n { //nolint:errcheck
+		t.Fatalf("appends lost: %q", b)
+	}
+	if code := writeFinalOutput(cliConfig{outputFile: path}, "replaced", io.Discard); code != 0 {
+		t.Fatalf("replace exit=%d", code)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("mode not kept: %v %v", info.Mode(), err)
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"crypto/tls"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
@@ -11,6 +13,8 @@ import (
 	"time"
 
 	"github.com/hyperifyio/goagent/internal/oai"
+	"github.com/hyperifyio/goagent/internal/redact"
+	"github.com/hyperifyio/goagent/internal/tools"
 )
 
 func getEnv(key, def string) string {
@@ -54,25 +58,70 @@ func parseFlags() (cliConfig, int) {
 	// -developer is repeatable; collect via custom sliceVar
 	flag.Var((*stringSliceFlag)(&cfg.developerPrompts), "developer", "Developer message (repeatable)")
 	flag.Var((*stringSliceFlag)(&cfg.developerFiles), "developer-file", "Path to file containing developer message (repeatable; '-' for STDIN)")
+	flag.Var((*stringSliceFlag)(&cfg.imageAttach), "image-attach", "Image file or http(s) URL to send with the user prompt for multimodal models (repeatable)")
 	flag.StringVar(&cfg.systemFile, "system-file", "", "Path to file containing system prompt ('-' for STDIN; mutually exclusive with -system)")
+	flag.StringVar(&cfg.promptTemplate, "prompt-template", "", "Go text/template file rendering the prompts: {{define \"system\"}}, {{define \"developer\"}}, and {{define \"user\"}} blocks, or a plain body as the user prompt")
+	flag.Var((*stringSliceFlag)(&cfg.templateVars), "var", "Template variable key=value for -prompt-template (repeatable; overrides env AGENTCLI_VAR_<key> and -vars-file)")
+	flag.StringVar(&cfg.varsFile, "vars-file", "", "JSON object of template variables for -prompt-template")
+	flag.BoolVar(&cfg.templateStrict, "template-strict", false, "Fail when -prompt-template references a variable that is not set (default renders it empty)")
 	flag.StringVar(&cfg.promptFile, "prompt-file", "", "Path to file containing user prompt ('-' for STDIN; mutually exclusive with -prompt)")
 	// Pre-stage system message (optional). Precedence: flag > env > empty. Mutually exclusive with -prep-system-file
 	flag.StringVar(&cfg.prepSystem, "prep-system", "", "Pre-stage system message (env OAI_PREP_SYSTEM; mutually exclusive with -prep-system-file)")
 	flag.StringVar(&cfg.prepSystemFile, "prep-system-file", "", "Path to file containing pre-stage system message ('-' for STDIN; env OAI_PREP_SYSTEM_FILE; mutually exclusive with -prep-system)")
+	// Pre-stage prompt overrides (repeatable). Precedence: -prep-prompt > -prep-prompt-file > env > embedded default
+	flag.Var((*stringSliceFlag)(&cfg.prepPrompts), "prep-prompt", "Pre-stage prompt replacing the embedded default (repeatable; env OAI_PREP_PROMPT)")
+	flag.Var((*stringSliceFlag)(&cfg.prepPromptFiles), "prep-prompt-file", "Path to file containing the pre-stage prompt (repeatable; '-' for STDIN; ignored when -prep-prompt is set)")
 	flag.StringVar(&cfg.toolsPath, "tools", "", "Path to tools.json (optional)")
+	var toolsTopKSet bool
+	flag.CommandLine.Var(&intFlexFlag{dst: &cfg.toolsTopK, set: &toolsTopKSet}, "tools-topk", "Advertise only the N tools whose descriptions best match each step, plus tools already called; 0 advertises all (env AGENTCLI_TOOLS_TOPK)")
+	flag.StringVar(&cfg.embedModel, "embed-model", getEnv("OAI_EMBED_MODEL", defaultEmbedModel), "Embedding model -tools-topk ranks tools with (env OAI_EMBED_MODEL)")
+	flag.StringVar(&cfg.workspace, "workspace", getEnv("AGENTCLI_WORKSPACE", ""), "Default root directory of the fs_* tools; empty uses the working directory (env AGENTCLI_WORKSPACE)")
+	flag.Var((*stringSliceFlag)(&cfg.workspaceRoots), "workspace-root", "Named root name=dir the fs_* tools may address as name:path (repeatable)")
+	flag.StringVar(&cfg.schemaSimplify, "schema-simplify", getEnv("OAI_SCHEMA_SIMPLIFY", "auto"), "Flatten tool schemas for small models: auto|always|never (env OAI_SCHEMA_SIMPLIFY; default auto)")
+	flag.StringVar(&cfg.toolProtocol, "tool-protocol", getEnv("OAI_TOOL_PROTOCOL", oai.ToolProtocolNative), "Tool-calling protocol: native|functions|text (env OAI_TOOL_PROTOCOL; default native)")
+	extraBodyRaw := ""
+	flag.StringVar(&extraBodyRaw, "extra-body", getEnv("OAI_EXTRA_BODY", ""), "JSON object of provider-specific fields merged into each chat request, e.g. vLLM guided_json or use_beam_search (env OAI_EXTRA_BODY)")
+	flag.StringVar(&cfg.chatTemplate, "chat-template", getEnv("OAI_CHAT_TEMPLATE", ""), "Chat template name sent as chat_template for llama.cpp/vLLM-style servers (env OAI_CHAT_TEMPLATE)")
+	var promptCacheSet bool
+	flag.CommandLine.Var(&boolFlexFlag{dst: &cfg.promptCache, set: &promptCacheSet}, "prompt-cache", "Mark system and developer messages and tool schemas with cache_control for providers with explicit prompt caching (env AGENTCLI_PROMPT_CACHE)")
+	flag.StringVar(&cfg.validateMessages, "validate-messages", getEnv("AGENTCLI_VALIDATE_MESSAGES", validateStrict), "Message sequence check before each request: strict|repair (env AGENTCLI_VALIDATE_MESSAGES; default strict)")
+	flag.StringVar(&cfg.strategy, "strategy", getEnv("AGENTCLI_STRATEGY", strategyNative), "Agent loop strategy: native|react|plan (env AGENTCLI_STRATEGY; default native)")
+	approveToolsRaw := ""
+	flag.StringVar(&approveToolsRaw, "approve-tools", "", "Comma-separated tool names, or all, that need a y/N approval before each call")
+	flag.StringVar(&cfg.approveFile, "approve-file", "", "Read approval answers from this file or FIFO instead of the terminal")
+	flag.StringVar(&cfg.toolHookCmd, "tool-hook-cmd", "", "Shell command run before and after every tool call with the call JSON on stdin; it may rewrite arguments or output, or block the call")
+	flag.BoolVar(&cfg.editor, "editor", false, "Open $VISUAL or $EDITOR to approve or edit gated tool calls and -strategy plan plans")
+	flag.StringVar(&cfg.goldenPath, "golden", "", "Compare the finished run's tool calls and final answer with this saved transcript; exit 4 with a JSON diff on mismatch")
+	flag.IntVar(&cfg.goldenCallTolerance, "golden-call-tolerance", 0, "Tool-call edits (missing, extra, or changed calls) allowed against -golden")
+	flag.Float64Var(&cfg.goldenFinalSimilarity, "golden-final-similarity", 1, "Minimum word-level similarity (0..1) of the final answer to -golden's")
+	flag.BoolVar(&cfg.goldenIgnoreArgs, "golden-ignore-args", false, "Compare only tool names, not arguments, against -golden")
+	flag.IntVar(&cfg.subagentDepth, "subagent-depth", 0, "Offer the built-in agent.run tool, letting the model spawn nested agents up to this depth (0 disables)")
+	flag.StringVar(&cfg.reviewModel, "review-model", getEnv("OAI_REVIEW_MODEL", ""), "Model that critiques the candidate final answer before it is printed (env OAI_REVIEW_MODEL)")
+	var reviewRoundsSet bool
+	flag.CommandLine.Var(&intFlexFlag{dst: &cfg.reviewRounds, set: &reviewRoundsSet}, "review-rounds", "Maximum critique-and-revise rounds with -review-model (env OAI_REVIEW_ROUNDS; default 1)")
+	flag.StringVar(&cfg.schemaMinTier, "schema-min-tier", getEnv("OAI_SCHEMA_MIN_TIER", "medium"), "With -schema-simplify auto, simplify when the model tier is below this: small|medium|large (env OAI_SCHEMA_MIN_TIER; default medium)")
 	// State directory (CLI > env > empty). When set, create if missing with 0700.
 	flag.StringVar(&cfg.stateDir, "state-dir", getEnv("AGENTCLI_STATE_DIR", ""), "Directory to persist and restore execution state across runs (env AGENTCLI_STATE_DIR)")
 	// Optional state scope (CLI > env > computed default)
+	flag.StringVar(&cfg.stateRemote, "state-remote", getEnv("AGENTCLI_STATE_REMOTE", ""), "Share -state-dir through an object store: s3://bucket/prefix or gs://bucket/prefix (env AGENTCLI_STATE_REMOTE)")
 	flag.StringVar(&cfg.stateScope, "state-scope", getEnv("AGENTCLI_STATE_SCOPE", ""), "Optional scope key to partition saved state (env AGENTCLI_STATE_SCOPE); when empty, a default hash of model|base_url|toolset is used")
 	// Refinement flags
 	flag.BoolVar(&cfg.stateRefine, "state-refine", false, "Refine the loaded state bundle using -state-refine-text or -state-refine-file (requires -state-dir)")
 	flag.StringVar(&cfg.stateRefineText, "state-refine-text", "", "Refinement input text to apply to the loaded state bundle (ignored when -state-refine-file is set; requires -state-dir)")
 	flag.StringVar(&cfg.stateRefineFile, "state-refine-file", "", "Path to file containing refinement input (wins over -state-refine-text; requires -state-dir)")
+	// Snapshot inspection and branching
+	flag.BoolVar(&cfg.stateList, "state-list", false, "List the snapshots in -state-dir and exit")
+	flag.StringVar(&cfg.stateShow, "state-show", "", "Print the snapshot in -state-dir whose SHA-256 starts with this prefix (or latest) and exit")
+	flag.StringVar(&cfg.stateFork, "state-fork", "", "Copy the latest snapshot in -state-dir into this new scope and exit")
 	flag.StringVar(&cfg.systemPrompt, "system", defaultSystem, "System prompt")
 	flag.StringVar(&cfg.baseURL, "base-url", defaultBase, "OpenAI-compatible base URL")
 	flag.StringVar(&cfg.apiKey, "api-key", defaultKey, "API key if required (env OAI_API_KEY; falls back to OPENAI_API_KEY)")
 	flag.StringVar(&cfg.model, "model", defaultModel, "Model ID")
+	flag.StringVar(&cfg.provider, "provider", getEnv("AGENTCLI_PROVIDER", providerOpenAI), "Chat API: openai (OpenAI-compatible -base-url), bedrock (Amazon Bedrock Converse, signed with AWS credentials from the environment), or gemini (Google Gemini generateContent) (env AGENTCLI_PROVIDER)")
+	var modelEscalateRaw string
+	flag.StringVar(&modelEscalateRaw, "model-escalate", getEnv("AGENTCLI_MODEL_ESCALATE", ""), "Per-step model routing, e.g. small:2,large: the first 2 steps use small, later steps and steps after a failed tool call move up a tier; overrides -model (env AGENTCLI_MODEL_ESCALATE)")
 	flag.IntVar(&cfg.maxSteps, "max-steps", 8, "Maximum reasoning/tool steps")
+	flag.IntVar(&cfg.tokenBudget, "token-budget", 0, "Stop with exit 8 once the run has used this many total tokens (0 is unlimited)")
 	// Deprecated global timeout retained as a fallback if the split timeouts are not provided
 	// Accept plain seconds (e.g., 300 => 300s) in addition to Go duration strings.
 	cfg.timeout = 30 * time.Second
@@ -87,6 +136,8 @@ func parseFlags() (cliConfig, int) {
 	flag.Var(durationFlexFlag{dst: &cfg.httpTimeout, set: &httpSet}, "http-timeout", "HTTP timeout for chat completions (env OAI_HTTP_TIMEOUT; falls back to -timeout if unset)")
 	flag.Var(durationFlexFlag{dst: &cfg.prepHTTPTimeout, set: &prepHTTPSet}, "prep-http-timeout", "HTTP timeout for pre-stage (env OAI_PREP_HTTP_TIMEOUT; falls back to -http-timeout if unset)")
 	flag.Var(durationFlexFlag{dst: &cfg.toolTimeout, set: &toolSet}, "tool-timeout", "Per-tool timeout (falls back to -timeout if unset)")
+	cfg.toolGrace = tools.DefaultKillGrace
+	flag.Var(durationFlexFlag{dst: &cfg.toolGrace}, "tool-timeout-grace", "After a tool times out or is canceled, wait this long following SIGTERM before SIGKILL (default 2s)")
 	// Use a flexible float flag to detect whether -temp was explicitly set
 	var tempSet bool
 	var _ flag.Value = (*float64FlexFlag)(nil)
@@ -130,26 +181,80 @@ func parseFlags() (cliConfig, int) {
 		f := durationFlexFlag{dst: &cfg.httpBackoff, set: &httpBackoffSet}
 		flag.CommandLine.Var(f, "http-retry-backoff", "Base backoff between HTTP retry attempts (exponential) (env OAI_HTTP_RETRY_BACKOFF; default 500ms)")
 	})()
+	var httpMaxIdleConnsSet, httpKeepAliveSet bool
+	cfg.httpMaxIdleConns = -1 // sentinel to detect unset
+	flag.CommandLine.Var(&intFlexFlag{dst: &cfg.httpMaxIdleConns, set: &httpMaxIdleConnsSet}, "http-max-idle-conns", "Max pooled idle connections, total and per host, shared by all API clients (env OAI_HTTP_MAX_IDLE_CONNS; default 100)")
+	flag.CommandLine.Var(durationFlexFlag{dst: &cfg.httpKeepAlive, set: &httpKeepAliveSet}, "http-keepalive", "TCP keep-alive period for API connections; 0 disables connection reuse (env OAI_HTTP_KEEPALIVE; default 30s)")
+	flag.StringVar(&cfg.tlsMinVersion, "tls-min-version", getEnv("OAI_TLS_MIN_VERSION", "1.2"), "Minimum TLS version for API connections: 1.2|1.3 (env OAI_TLS_MIN_VERSION; default 1.2)")
+	flag.StringVar(&cfg.caBundlePath, "ca-bundle", getEnv("OAI_CA_BUNDLE", ""), "PEM file with extra CA certificates to trust for API connections (env OAI_CA_BUNDLE)")
+	var httpMaxResponseSet bool
+	flag.CommandLine.Var(&intFlexFlag{dst: &cfg.httpMaxResponseBytes, set: &httpMaxResponseSet}, "http-max-response-bytes", "Max bytes read from one chat response or stream after decompression (env OAI_HTTP_MAX_RESPONSE_BYTES; default 33554432)")
+	flag.BoolVar(&cfg.httpGzip, "http-gzip", false, "Gzip-compress chat request bodies (Content-Encoding: gzip); only for servers that accept it")
+	var seedSet bool
+	flag.BoolVar(&cfg.deterministic, "deterministic", false, "Freeze the clock and seed all randomness so repeated runs produce identical transcripts and audit logs (env AGENTCLI_DETERMINISTIC)")
+	flag.CommandLine.Var(&intFlexFlag{dst: &cfg.seed, set: &seedSet}, "seed", "Sampling seed sent with chat requests, also used by -deterministic and -chaos (env AGENTCLI_SEED; default 1, sent only when set or with -deterministic)")
+	var chatCacheTTLSet bool
+	flag.BoolVar(&cfg.chatCache, "chat-cache", false, "Replay complete chat replies cached on disk for identical requests instead of calling the API (env AGENTCLI_CHAT_CACHE)")
+	flag.CommandLine.Var(durationFlexFlag{dst: &cfg.chatCacheTTL, set: &chatCacheTTLSet}, "chat-cache-ttl", "How long cached chat replies are replayed; 0 keeps them until evicted (env AGENTCLI_CHAT_CACHE_TTL; default 24h)")
+	var modelsCacheTTLSet bool
+	flag.BoolVar(&cfg.discoverModels, "discover-models", false, "Query the provider's /models endpoint before the first call for context window, tool, and temperature support (env AGENTCLI_DISCOVER_MODELS)")
+	flag.CommandLine.Var(durationFlexFlag{dst: &cfg.modelsCacheTTL, set: &modelsCacheTTLSet}, "models-cache-ttl", "How long a /models listing is reused from the cache; 0 never expires it (env AGENTCLI_MODELS_CACHE_TTL; default 24h)")
+	var chaosRaw string
+	flag.StringVar(&chaosRaw, "chaos", getEnv("AGENTCLI_CHAOS", ""), "Inject faults to test retry and tool policies: comma-separated timeout=P,http500=P,tool-fail=P with P in 0..1, drawn from -seed (env AGENTCLI_CHAOS)")
+	flag.BoolVar(&cfg.pruneStaleReads, "prune-stale-reads", true, "Replace earlier fs_read_file/fs_read_lines results with a stale marker once a later tool call modifies the file (off under -debug)")
+	flag.BoolVar(&cfg.readOnly, "read-only", false, "Refuse calls to tools that modify the workspace (manifest \"mutates\": true, or bundled writers such as fs_write_file, fs_apply_patch, fs_rm, fs_move, exec); tools with \"supportsDryRun\": true run as dry runs instead")
+	flag.BoolVar(&cfg.noLock, "no-lock", false, "Do not take the workspace lock (.goagent/run.lock) that serializes runs with mutating tools")
+	flag.StringVar(&cfg.recordDir, "record", "", "Record every HTTP exchange and tool run to this directory for -replay")
+	flag.StringVar(&cfg.replayDir, "replay", "", "Run offline: answer HTTP requests and tool runs from a -record directory")
+	flag.StringVar(&cfg.providersPath, "providers", getEnv("AGENTCLI_PROVIDERS", ""), "JSON or YAML routing table of base URLs and models to fail over between when the current one keeps failing (env AGENTCLI_PROVIDERS)")
+	flag.StringVar(&cfg.metricsListen, "metrics-listen", getEnv("AGENTCLI_METRICS_LISTEN", ""), "Serve Prometheus metrics at /metrics on this address, e.g. :9090 (env AGENTCLI_METRICS_LISTEN)")
+	var httpBreakerThresholdSet, httpBreakerCooldownSet bool
+	cfg.httpBreakerThreshold = -1 // sentinel to detect unset
+	flag.CommandLine.Var(&intFlexFlag{dst: &cfg.httpBreakerThreshold, set: &httpBreakerThresholdSet}, "http-breaker-threshold", "Consecutive 429/5xx responses from one base URL before failing fast; 0 disables (env OAI_HTTP_BREAKER_THRESHOLD; default 5)")
+	flag.CommandLine.Var(durationFlexFlag{dst: &cfg.httpBreakerCooldown, set: &httpBreakerCooldownSet}, "http-breaker-cooldown", "How long the circuit breaker stays open (env OAI_HTTP_BREAKER_COOLDOWN; default 30s)")
 	flag.BoolVar(&cfg.debug, "debug", false, "Dump request/response JSON to stderr")
-	flag.BoolVar(&cfg.verbose, "verbose", false, "Also print non-final assistant channels (critic/confidence) to stderr")
+	flag.BoolVar(&cfg.verbose, "verbose", false, "Also print non-final assistant channels (critic/confidence) and a final usage summary to stderr")
 	flag.BoolVar(&cfg.quiet, "quiet", false, "Suppress non-final output; print only final text to stdout")
 	flag.BoolVar(&cfg.prepToolsAllowExternal, "prep-tools-allow-external", false, "Allow pre-stage to execute external tools from -tools; when false, pre-stage is limited to built-in read-only tools")
 	flag.StringVar(&cfg.prepToolsPath, "prep-tools", "", "Path to pre-stage tools.json (optional; used only with -prep-tools-allow-external)")
 	flag.BoolVar(&cfg.prepCacheBust, "prep-cache-bust", false, "Skip pre-stage cache and force recompute")
+	flag.BoolVar(&cfg.prepCacheStats, "prep-cache-stats", false, "Print pre-stage cache hits, misses, and size as JSON and exit")
 	// Enabled by default; user can disable to skip pre-stage entirely. Track if explicitly set.
 	cfg.prepEnabled = true
 	flag.CommandLine.Var(&boolFlexFlag{dst: &cfg.prepEnabled, set: &cfg.prepEnabledSet}, "prep-enabled", "Enable pre-stage processing (default true; when false, skip pre-stage and proceed directly to main call)")
 	// Message viewing flags
 	flag.BoolVar(&cfg.prepDryRun, "prep-dry-run", false, "Run pre-stage only, print refined Harmony messages to stdout, and exit 0")
 	flag.BoolVar(&cfg.printMessages, "print-messages", false, "Pretty-print the final merged message array to stderr before the main call")
+	flag.BoolVar(&cfg.printPlan, "print-plan", false, "Print the pre-stage plan to stderr before the main call and after each plan.update")
+	var diffMessagesRaw string
+	flag.StringVar(&diffMessagesRaw, "diff-messages", "", "Compare saved messages: OLD,NEW prints a structural diff and exits (0 same, 1 different); FILE diffs against this run's merged messages on stderr")
 	flag.BoolVar(&cfg.streamFinal, "stream-final", false, "If server supports streaming, stream only assistant{channel:\"final\"} to stdout; buffer other channels for -verbose")
-	// Custom channel routing (repeatable): -channel-route name=stdout|stderr|omit
-	flag.Var((*stringSliceFlag)(&cfg.channelRoutePairs), "channel-route", "Route assistant channels (final|critic|confidence) to stdout|stderr|omit; repeatable, e.g., -channel-route critic=stdout")
+	// Custom channel routing (repeatable): -channel-route name=stdout|stderr|omit|file:<path>
+	flag.Var((*stringSliceFlag)(&cfg.channelRoutePairs), "channel-route", "Route an assistant channel (final, critic, confidence, any custom name, or * for other custom channels) to stdout|stderr|omit|file:<path>; repeatable, e.g., -channel-route critic=stdout or -channel-route analysis=>file:analysis.log")
 	// Save/load refined messages
 	flag.StringVar(&cfg.saveMessagesPath, "save-messages", "", "Write the final merged Harmony messages to the given JSON file and continue")
+	flag.StringVar(&cfg.outputFile, "output-file", "", "Write the final assistant content to this file atomically (temp file + rename) instead of stdout")
+	flag.BoolVar(&cfg.outputAppend, "append", false, "With -output-file, append to the file instead of replacing it")
+	flag.StringVar(&cfg.succeedIf, "succeed-if", "", "Exit 4 unless the final answer matches this regular expression")
+	flag.StringVar(&cfg.failIf, "fail-if", "", "Exit 3 when the final answer matches this regular expression")
+	flag.StringVar(&cfg.outputFormat, "output", getEnv("AGENTCLI_OUTPUT", outputText), "Result format on stdout: text|json; json suppresses all human text and prints one object {final, steps, usage, tool_calls, exit_reason} (env AGENTCLI_OUTPUT; default text)")
+	flag.BoolVar(&cfg.errorJSON, "error-json", false, "On failure, print a final JSON line {exitCode, reason, message} to stderr")
+	flag.StringVar(&cfg.exportJSONL, "export-jsonl", "", "Append the finished transcript to this file as one OpenAI fine-tuning JSONL record")
+	flag.BoolVar(&cfg.ifEmptyFail, "if-empty-fail", false, "With -output-file, exit 1 and leave the file untouched when the final content is empty")
+	flag.StringVar(&cfg.contextReport, "context-report", "", "After the run, print estimated prompt tokens per step by source to stderr: table|json")
+	var tuiPriceRaw string
+	flag.BoolVar(&cfg.tui, "tui", false, "Show a live dashboard on stderr (step, tool activity, token and cost meters, output); output is printed when the run ends")
+	flag.StringVar(&tuiPriceRaw, "tui-price", "", "USD per million prompt/completion tokens for the -tui cost meter, e.g. 1.25/10")
+	flag.StringVar(&cfg.scriptPath, "script", "", "Run the user turns in this JSON file in order over one transcript (replaces -prompt)")
+	flag.StringVar(&cfg.tasksPath, "tasks", "", "Checklist file (.md list or .json array) to work through; progress is tracked with the built-in task.complete tool and reported at the end")
+	flag.StringVar(&cfg.batchPath, "batch", "", "Run every prompt in this JSONL file as its own agent run (replaces -prompt)")
+	flag.StringVar(&cfg.batchOut, "batch-out", "", "Write one JSON result per -batch item to this file (default stdout)")
+	flag.IntVar(&cfg.batchConcurrency, "batch-concurrency", 4, "How many -batch items run at once")
 	flag.StringVar(&cfg.loadMessagesPath, "load-messages", "", "Bypass pre-stage and prompt; load Harmony messages from the given JSON file (validator-checked)")
 	flag.BoolVar(&cfg.capabilities, "capabilities", false, "Print enabled tools and exit")
 	flag.BoolVar(&cfg.printConfig, "print-config", false, "Print resolved config and exit")
+	flag.StringVar(&cfg.configPath, "config", getEnv("AGENTCLI_CONFIG", ""), "YAML or JSON file of flag defaults (env AGENTCLI_CONFIG; default .goagent.yaml in the working directory when present)")
+	flag.StringVar(&cfg.profile, "profile", getEnv("AGENTCLI_PROFILE", ""), "Named preset from the config file's profiles section (env AGENTCLI_PROFILE)")
 	// Global dry-run for state persistence planning (no disk writes)
 	flag.BoolVar(&cfg.dryRun, "dry-run", false, "Print intended state actions (restore/refine/save) and exit without writing state")
 	// Image API flags
@@ -172,6 +277,19 @@ func parseFlags() (cliConfig, int) {
 	cfg.imageHTTPBackoff = 0
 	flag.Var(durationFlexFlag{dst: &cfg.imageHTTPBackoff, set: &imageHTTPBackoffSet}, "image-http-retry-backoff", "Image HTTP retry backoff (env OAI_IMAGE_HTTP_RETRY_BACKOFF; inherits -http-retry-backoff if unset)")
 	// Image parameter pass-through flags (precedence: flag > env > default)
+	// Audio transcription of -audio-prompt; connection and HTTP knobs
+	// inherit from the main call like the image ones
+	flag.StringVar(&cfg.audioPrompt, "audio-prompt", "", "Audio file to transcribe into the user prompt via /audio/transcriptions (appended to -prompt when both are set)")
+	flag.StringVar(&cfg.audioBaseURL, "audio-base-url", "", "Audio API base URL (env OAI_AUDIO_BASE_URL; inherits -base-url if unset)")
+	flag.StringVar(&cfg.audioAPIKey, "audio-api-key", "", "Audio API key (env OAI_AUDIO_API_KEY; inherits -api-key if unset; falls back to OPENAI_API_KEY)")
+	flag.StringVar(&cfg.audioModel, "audio-model", getEnv("OAI_AUDIO_MODEL", "whisper-1"), "Transcription model ID (env OAI_AUDIO_MODEL; default whisper-1)")
+	flag.StringVar(&cfg.audioLanguage, "audio-language", getEnv("OAI_AUDIO_LANGUAGE", ""), "Spoken language hint as ISO-639-1, e.g. en (env OAI_AUDIO_LANGUAGE)")
+	var audioHTTPTimeoutSet, audioHTTPRetriesSet, audioHTTPBackoffSet bool
+	cfg.audioHTTPRetries = -1 // sentinel for unset
+	flag.Var(durationFlexFlag{dst: &cfg.audioHTTPTimeout, set: &audioHTTPTimeoutSet}, "audio-http-timeout", "Audio HTTP timeout (env OAI_AUDIO_HTTP_TIMEOUT; inherits -http-timeout if unset)")
+	flag.Var(&intFlexFlag{dst: &cfg.audioHTTPRetries, set: &audioHTTPRetriesSet}, "audio-http-retries", "Audio HTTP retries (env OAI_AUDIO_HTTP_RETRIES; inherits -http-retries if unset)")
+	flag.Var(durationFlexFlag{dst: &cfg.audioHTTPBackoff, set: &audioHTTPBackoffSet}, "audio-http-retry-backoff", "Audio HTTP retry backoff (env OAI_AUDIO_HTTP_RETRY_BACKOFF; inherits -http-retry-backoff if unset)")
+
 	// -image-n
 	cfg.imageN = -1 // sentinel for unset
 	var imageNSet bool
@@ -187,6 +305,29 @@ func parseFlags() (cliConfig, int) {
 	// -image-transparent-background
 	flag.CommandLine.Var(&boolFlexFlag{dst: &cfg.imageTransparentBackground}, "image-transparent-background", "Request transparent background when supported (env OAI_IMAGE_TRANSPARENT_BACKGROUND; default false)")
 	ignoreError(flag.CommandLine.Parse(os.Args[1:]))
+
+	// Project config file fills flags that neither the command line nor the
+	// environment set: flag > env > config > default
+	if path := findConfigFile(cfg.configPath); path != "" {
+		file, err := loadConfigFile(path, cfg.profile, flag.CommandLine)
+		if err == nil {
+			cfg.configApplied, err = applyConfigFile(flag.CommandLine, file)
+		}
+		if err != nil {
+			cfg.parseError = "error: config: " + err.Error()
+			return cfg, 2
+		}
+		cfg.configPath = path
+	} else if strings.TrimSpace(cfg.profile) != "" {
+		cfg.parseError = "error: -profile needs a config file (-config or .goagent.yaml)"
+		return cfg, 2
+	}
+	cfg.modelSource = flagSource(cfg, "model", "OAI_MODEL")
+	cfg.promptCache, _ = oai.ResolveBool(promptCacheSet, cfg.promptCache, os.Getenv("AGENTCLI_PROMPT_CACHE"), nil, false)
+	cfg.baseURLSource = flagSource(cfg, "base-url", "OAI_BASE_URL")
+	if code := resolveProvider(&cfg); code != 0 {
+		return cfg, code
+	}
 	if strings.TrimSpace(prepProfileRaw) != "" {
 		cfg.prepProfile = oai.PromptProfile(strings.TrimSpace(prepProfileRaw))
 	}
@@ -202,8 +343,13 @@ func parseFlags() (cliConfig, int) {
 			cfg.prepSystemFile = v
 		}
 	}
+	if len(cfg.prepPrompts) == 0 && len(cfg.prepPromptFiles) == 0 {
+		if v := strings.TrimSpace(os.Getenv("OAI_PREP_PROMPT")); v != "" {
+			cfg.prepPrompts = []string{v}
+		}
+	}
 
-	// Resolve temperature precedence: flag > env (LLM_TEMPERATURE) > config file (not implemented) > default 1.0
+	// Resolve temperature precedence: flag > env (LLM_TEMPERATURE) > config file > default 1.0
 	if tempSet {
 		cfg.temperatureSource = "flag"
 	} else {
@@ -289,6 +435,10 @@ func parseFlags() (cliConfig, int) {
 			cfg.toolTimeout = 30 * time.Second
 		}
 	}
+	if cfg.toolGrace <= 0 {
+		cfg.parseError = "error: -tool-timeout-grace must be > 0"
+		return cfg, 2
+	}
 
 	// Resolve global HTTP retry knobs using centralized helpers
 	// http-retries: flag > env > default(2)
@@ -302,6 +452,180 @@ func parseFlags() (cliConfig, int) {
 		cfg.httpBackoff = resolved
 	}
 
+	// Transport knobs: flag > env > default
+	{
+		resolved, _ := oai.ResolveInt(httpMaxIdleConnsSet, cfg.httpMaxIdleConns, os.Getenv("OAI_HTTP_MAX_IDLE_CONNS"), nil, 100)
+		cfg.httpMaxIdleConns = resolved
+		keepAlive, _ := oai.ResolveDuration(httpKeepAliveSet, cfg.httpKeepAlive, os.Getenv("OAI_HTTP_KEEPALIVE"), nil, 30*time.Second)
+		cfg.httpKeepAlive = keepAlive
+		maxResponse, _ := oai.ResolveInt(httpMaxResponseSet, cfg.httpMaxResponseBytes, os.Getenv("OAI_HTTP_MAX_RESPONSE_BYTES"), nil, oai.DefaultMaxResponseBytes)
+		cfg.httpMaxResponseBytes = maxResponse
+	}
+	if cfg.httpMaxResponseBytes <= 0 {
+		cfg.parseError = "error: -http-max-response-bytes must be > 0"
+		return cfg, 2
+	}
+	cfg.toolsTopK, _ = oai.ResolveInt(toolsTopKSet, cfg.toolsTopK, os.Getenv("AGENTCLI_TOOLS_TOPK"), nil, 0)
+	if cfg.toolsTopK < 0 {
+		cfg.parseError = "error: -tools-topk must be >= 0"
+		return cfg, 2
+	}
+	if err := resolveWorkspaceRoots(&cfg); err != nil {
+		cfg.parseError = "error: " + err.Error()
+		return cfg, 2
+	}
+	if err := redact.Check(); err != nil {
+		cfg.parseError = "error: " + err.Error()
+		return cfg, 2
+	}
+	if cfg.httpMaxIdleConns < 0 {
+		cfg.parseError = "error: -http-max-idle-conns must be >= 0"
+		return cfg, 2
+	}
+	if v, ok := oai.ParseTLSVersion(cfg.tlsMinVersion); !ok || v < tls.VersionTLS12 {
+		cfg.parseError = fmt.Sprintf("error: invalid -tls-min-version %q (allowed: 1.2, 1.3)", cfg.tlsMinVersion)
+		return cfg, 2
+	}
+
+	// Deterministic mode: flag > env > default
+	deterministicSet := false
+	flag.CommandLine.Visit(func(f *flag.Flag) { deterministicSet = deterministicSet || f.Name == "deterministic" })
+	if !deterministicSet {
+		if b, err := strconv.ParseBool(strings.TrimSpace(os.Getenv("AGENTCLI_DETERMINISTIC"))); err == nil {
+			cfg.deterministic = b
+		}
+	}
+	cfg.seed, cfg.seedSource = oai.ResolveInt(seedSet, cfg.seed, os.Getenv("AGENTCLI_SEED"), nil, 1)
+	cfg.sendSeed = cfg.seedSource != "default" || cfg.deterministic
+
+	flag.CommandLine.Visit(func(f *flag.Flag) { cfg.systemSet = cfg.systemSet || f.Name == "system" || f.Name == "system-file" })
+	for _, pair := range cfg.templateVars {
+		if _, _, err := parseTemplateVar(pair); err != nil {
+			cfg.parseError = "error: " + err.Error()
+			return cfg, 2
+		}
+	}
+
+	// Chat cache: flag > env > default
+	chatCacheSet := false
+	flag.CommandLine.Visit(func(f *flag.Flag) { chatCacheSet = chatCacheSet || f.Name == "chat-cache" })
+	if !chatCacheSet {
+		if b, err := strconv.ParseBool(strings.TrimSpace(os.Getenv("AGENTCLI_CHAT_CACHE"))); err == nil {
+			cfg.chatCache = b
+		}
+	}
+	cfg.chatCacheTTL, _ = oai.ResolveDuration(chatCacheTTLSet, cfg.chatCacheTTL, os.Getenv("AGENTCLI_CHAT_CACHE_TTL"), nil, 24*time.Hour)
+	if cfg.chatCacheTTL < 0 {
+		cfg.parseError = "error: -chat-cache-ttl must be >= 0"
+		return cfg, 2
+	}
+
+	// Model discovery: flag > env > default
+	discoverModelsSet := false
+	flag.CommandLine.Visit(func(f *flag.Flag) { discoverModelsSet = discoverModelsSet || f.Name == "discover-models" })
+	if !discoverModelsSet {
+		if b, err := strconv.ParseBool(strings.TrimSpace(os.Getenv("AGENTCLI_DISCOVER_MODELS"))); err == nil {
+			cfg.discoverModels = b
+		}
+	}
+	cfg.modelsCacheTTL, _ = oai.ResolveDuration(modelsCacheTTLSet, cfg.modelsCacheTTL, os.Getenv("AGENTCLI_MODELS_CACHE_TTL"), nil, 24*time.Hour)
+	if cfg.modelsCacheTTL < 0 {
+		cfg.parseError = "error: -models-cache-ttl must be >= 0"
+		return cfg, 2
+	}
+
+	if cfg.recordDir != "" && cfg.replayDir != "" {
+		cfg.parseError = "error: -record and -replay are mutually exclusive"
+		return cfg, 2
+	}
+	// A recording must hold every exchange, and a replay must answer from it
+	if cfg.recordDir != "" || cfg.replayDir != "" {
+		cfg.prepCacheBust = true
+		cfg.chatCache = false
+	}
+
+	if p := strings.TrimSpace(cfg.providersPath); p != "" {
+		table, err := oai.LoadProviderTable(p)
+		if err != nil {
+			cfg.parseError = "error: -providers: " + err.Error()
+			return cfg, 2
+		}
+		cfg.providers = &table
+	}
+
+	chaos, chaosErr := parseChaos(chaosRaw)
+	if chaosErr != nil {
+		cfg.parseError = "error: " + chaosErr.Error()
+		return cfg, 2
+	}
+	cfg.chaos = chaos
+
+	if strings.Trim(approveToolsRaw, ", ") != "" {
+		cfg.approveTools = splitCSV(approveToolsRaw, "")
+	}
+	cfg.approveFile = strings.TrimSpace(cfg.approveFile)
+	if cfg.approveFile != "" && cfg.approveTools == nil {
+		cfg.parseError = "error: -approve-file requires -approve-tools"
+		return cfg, 2
+	}
+	extraBody, extraBodyErr := parseExtraBody(extraBodyRaw)
+	if extraBodyErr != nil {
+		cfg.parseError = "error: " + extraBodyErr.Error()
+		return cfg, 2
+	}
+	cfg.extraBody = extraBody
+	cfg.goldenPath = strings.TrimSpace(cfg.goldenPath)
+	if cfg.goldenPath == "" && (cfg.goldenCallTolerance != 0 || cfg.goldenFinalSimilarity != 1 || cfg.goldenIgnoreArgs) {
+		cfg.parseError = "error: -golden-call-tolerance, -golden-final-similarity, and -golden-ignore-args require -golden"
+		return cfg, 2
+	}
+	if cfg.goldenCallTolerance < 0 {
+		cfg.parseError = "error: -golden-call-tolerance must be >= 0"
+		return cfg, 2
+	}
+	if cfg.goldenFinalSimilarity < 0 || cfg.goldenFinalSimilarity > 1 {
+		cfg.parseError = "error: -golden-final-similarity must be between 0 and 1"
+		return cfg, 2
+	}
+	if cfg.subagentDepth < 0 {
+		cfg.parseError = "error: -subagent-depth must be >= 0"
+		return cfg, 2
+	}
+	if cfg.tokenBudget < 0 {
+		cfg.parseError = "error: -token-budget must be >= 0"
+		return cfg, 2
+	}
+
+	// Review loop: flag > env > default
+	cfg.reviewModel = strings.TrimSpace(cfg.reviewModel)
+	cfg.reviewRounds, _ = oai.ResolveInt(reviewRoundsSet, cfg.reviewRounds, os.Getenv("OAI_REVIEW_ROUNDS"), nil, 1)
+	if cfg.reviewRounds < 0 {
+		cfg.parseError = "error: -review-rounds must be >= 0"
+		return cfg, 2
+	}
+
+	// Circuit breaker knobs: flag > env > default
+	{
+		resolved, _ := oai.ResolveInt(httpBreakerThresholdSet, cfg.httpBreakerThreshold, os.Getenv("OAI_HTTP_BREAKER_THRESHOLD"), nil, 5)
+		cfg.httpBreakerThreshold = resolved
+		cooldown, _ := oai.ResolveDuration(httpBreakerCooldownSet, cfg.httpBreakerCooldown, os.Getenv("OAI_HTTP_BREAKER_COOLDOWN"), nil, 30*time.Second)
+		cfg.httpBreakerCooldown = cooldown
+	}
+	if cfg.httpBreakerThreshold < 0 {
+		cfg.parseError = "error: -http-breaker-threshold must be >= 0"
+		return cfg, 2
+	}
+
+	if strings.TrimSpace(modelEscalateRaw) != "" {
+		tiers, err := parseModelEscalation(modelEscalateRaw)
+		if err != nil {
+			cfg.parseError = "error: " + err.Error()
+			return cfg, 2
+		}
+		cfg.modelEscalate = tiers
+		cfg.model = tiers[0].model
+	}
+
 	// Resolve prep overrides precedence: flag > env OAI_PREP_* > inherit main-call
 	// Model
 	if strings.TrimSpace(cfg.prepModel) != "" {
@@ -415,6 +739,24 @@ func parseFlags() (cliConfig, int) {
 		}
 	}
 
+	// Audio connection and HTTP knobs: same precedence and inheritance as image
+	if audio, baseSrc, keySrc := oai.ResolveAudioConfig(cfg.audioBaseURL, cfg.audioAPIKey, cfg.baseURL, cfg.apiKey); true {
+		cfg.audioBaseURL, cfg.audioAPIKey = audio.BaseURL, audio.APIKey
+		cfg.audioBaseURLSource, cfg.audioAPIKeySource = baseSrc, keySrc
+	}
+	{
+		inheritTimeout, inheritRetries, inheritBackoff := cfg.httpTimeout, cfg.httpRetries, cfg.httpBackoff
+		cfg.audioHTTPTimeout, cfg.audioHTTPTimeoutSource = oai.ResolveDuration(audioHTTPTimeoutSet, cfg.audioHTTPTimeout, os.Getenv("OAI_AUDIO_HTTP_TIMEOUT"), &inheritTimeout, cfg.httpTimeout)
+		if cfg.audioHTTPTimeout <= 0 {
+			cfg.audioHTTPTimeout = cfg.httpTimeout
+		}
+		cfg.audioHTTPRetries, cfg.audioHTTPRetriesSource = oai.ResolveInt(audioHTTPRetriesSet, cfg.audioHTTPRetries, os.Getenv("OAI_AUDIO_HTTP_RETRIES"), &inheritRetries, cfg.httpRetries)
+		if cfg.audioHTTPRetries < 0 {
+			cfg.audioHTTPRetries = cfg.httpRetries
+		}
+		cfg.audioHTTPBackoff, cfg.audioHTTPBackoffSource = oai.ResolveDuration(audioHTTPBackoffSet, cfg.audioHTTPBackoff, os.Getenv("OAI_AUDIO_HTTP_RETRY_BACKOFF"), &inheritBackoff, cfg.httpBackoff)
+	}
+
 	// Resolve image parameter pass-throughs with precedence: flag > env > default
 	if !imageNSet {
 		if v := strings.TrimSpace(os.Getenv("OAI_IMAGE_N")); v != "" {
@@ -509,9 +851,17 @@ func parseFlags() (cliConfig, int) {
 		cfg.parseError = "error: -prompt and -prompt-file are mutually exclusive"
 		return cfg, 2
 	}
-	if !cfg.capabilities && !cfg.printConfig {
+	if strings.TrimSpace(diffMessagesRaw) != "" {
+		paths, err := parseDiffMessages(diffMessagesRaw)
+		if err != nil {
+			cfg.parseError = "error: " + err.Error()
+			return cfg, 2
+		}
+		cfg.diffMessages = paths
+	}
+	if !cfg.capabilities && !cfg.printConfig && !cfg.prepCacheStats && len(cfg.diffMessages) != 2 && !stateInspection(cfg) {
 		// Resolve effective prompt presence considering -prompt-file
-		if strings.TrimSpace(cfg.loadMessagesPath) == "" && strings.TrimSpace(cfg.prompt) == "" && strings.TrimSpace(cfg.promptFile) == "" {
+		if strings.TrimSpace(cfg.loadMessagesPath) == "" && strings.TrimSpace(cfg.scriptPath) == "" && strings.TrimSpace(cfg.batchPath) == "" && strings.TrimSpace(cfg.prompt) == "" && strings.TrimSpace(cfg.promptFile) == "" && strings.TrimSpace(cfg.audioPrompt) == "" && strings.TrimSpace(cfg.promptTemplate) == "" {
 			return cfg, 2
 		}
 	}
@@ -519,46 +869,160 @@ func parseFlags() (cliConfig, int) {
 	if len(cfg.channelRoutePairs) > 0 {
 		cfg.channelRoutes = make(map[string]string)
 		for _, pair := range cfg.channelRoutePairs {
-			p := strings.TrimSpace(pair)
-			if p == "" {
+			if strings.TrimSpace(pair) == "" {
 				continue
 			}
-			eq := strings.IndexByte(p, '=')
-			if eq <= 0 || eq >= len(p)-1 {
-				cfg.parseError = "error: invalid -channel-route value (expected name=stdout|stderr|omit)"
-				return cfg, 2
-			}
-			name := strings.TrimSpace(p[:eq])
-			dest := strings.TrimSpace(p[eq+1:])
-			switch name {
-			case "final", "critic", "confidence":
-				// ok
-			default:
-				cfg.parseError = fmt.Sprintf("error: invalid -channel-route channel %q (allowed: final, critic, confidence)", name)
-				return cfg, 2
-			}
-			switch dest {
-			case "stdout", "stderr", "omit":
-				// ok
-			default:
-				cfg.parseError = fmt.Sprintf("error: invalid -channel-route destination %q (allowed: stdout, stderr, omit)", dest)
+			name, dest, err := parseChannelRoute(pair)
+			if err != nil {
+				cfg.parseError = "error: " + err.Error()
 				return cfg, 2
 			}
 			cfg.channelRoutes[name] = dest
 		}
 	}
 
+	// Validate schema simplification knobs
+	switch strings.ToLower(strings.TrimSpace(cfg.schemaSimplify)) {
+	case "auto", "always", "never":
+		cfg.schemaSimplify = strings.ToLower(strings.TrimSpace(cfg.schemaSimplify))
+	default:
+		cfg.parseError = fmt.Sprintf("error: invalid -schema-simplify %q (allowed: auto, always, never)", cfg.schemaSimplify)
+		return cfg, 2
+	}
+	if _, ok := oai.ParseModelTier(cfg.schemaMinTier); !ok {
+		cfg.parseError = fmt.Sprintf("error: invalid -schema-min-tier %q (allowed: small, medium, large)", cfg.schemaMinTier)
+		return cfg, 2
+	}
+
+	switch strings.ToLower(strings.TrimSpace(cfg.toolProtocol)) {
+	case oai.ToolProtocolNative, oai.ToolProtocolFunctions, oai.ToolProtocolText:
+		cfg.toolProtocol = strings.ToLower(strings.TrimSpace(cfg.toolProtocol))
+	default:
+		cfg.parseError = fmt.Sprintf("error: invalid -tool-protocol %q (allowed: native, functions, text)", cfg.toolProtocol)
+		return cfg, 2
+	}
+
+	switch strings.ToLower(strings.TrimSpace(cfg.validateMessages)) {
+	case validateStrict, validateRepair:
+		cfg.validateMessages = strings.ToLower(strings.TrimSpace(cfg.validateMessages))
+	default:
+		cfg.parseError = fmt.Sprintf("error: invalid -validate-messages %q (allowed: strict, repair)", cfg.validateMessages)
+		return cfg, 2
+	}
+
+	switch strings.ToLower(strings.TrimSpace(cfg.strategy)) {
+	case strategyNative, strategyReAct, strategyPlan:
+		cfg.strategy = strings.ToLower(strings.TrimSpace(cfg.strategy))
+	default:
+		cfg.parseError = fmt.Sprintf("error: invalid -strategy %q (allowed: native, react, plan)", cfg.strategy)
+		return cfg, 2
+	}
+
+	if cfg.editor {
+		switch {
+		case cfg.approveTools == nil && cfg.strategy != strategyPlan:
+			cfg.parseError = "error: -editor requires -approve-tools or -strategy plan"
+			return cfg, 2
+		case cfg.approveFile != "":
+			cfg.parseError = "error: -editor and -approve-file are mutually exclusive"
+			return cfg, 2
+		case cfg.tui:
+			cfg.parseError = "error: -editor and -tui are mutually exclusive"
+			return cfg, 2
+		}
+	}
+
 	// Conflict checks for save/load flags
 	if strings.TrimSpace(cfg.saveMessagesPath) != "" && strings.TrimSpace(cfg.loadMessagesPath) != "" {
 		cfg.parseError = "error: -save-messages and -load-messages are mutually exclusive"
 		return cfg, 2
 	}
+	// -append and -if-empty-fail only qualify -output-file
+	cfg.outputFile = strings.TrimSpace(cfg.outputFile)
+	cfg.exportJSONL = strings.TrimSpace(cfg.exportJSONL)
+	if cfg.outputFile == "" && (cfg.outputAppend || cfg.ifEmptyFail) {
+		cfg.parseError = "error: -append and -if-empty-fail require -output-file"
+		return cfg, 2
+	}
+	{
+		var err error
+		if cfg.succeedIfRe, err = compileVerdictPattern(cfg.succeedIf); err != nil {
+			cfg.parseError = fmt.Sprintf("error: invalid -succeed-if: %v", err)
+			return cfg, 2
+		}
+		if cfg.failIfRe, err = compileVerdictPattern(cfg.failIf); err != nil {
+			cfg.parseError = fmt.Sprintf("error: invalid -fail-if: %v", err)
+			return cfg, 2
+		}
+	}
+	switch cfg.contextReport = strings.ToLower(strings.TrimSpace(cfg.contextReport)); cfg.contextReport {
+	case "", "table", "json":
+	default:
+		cfg.parseError = fmt.Sprintf("error: invalid -context-report %q (allowed: table, json)", cfg.contextReport)
+		return cfg, 2
+	}
+	switch cfg.outputFormat = strings.ToLower(strings.TrimSpace(cfg.outputFormat)); cfg.outputFormat {
+	case outputText:
+	case outputJSON:
+		if cfg.tui || cfg.editor || strings.TrimSpace(cfg.batchPath) != "" || strings.TrimSpace(cfg.scriptPath) != "" {
+			cfg.parseError = "error: -output json cannot be combined with -tui, -editor, -batch, or -script"
+			return cfg, 2
+		}
+		// The y/N prompt would land in the captured stderr and the run
+		// would wait on an answer nobody was asked for
+		if cfg.approveTools != nil && cfg.approveFile == "" {
+			cfg.parseError = "error: -output json with -approve-tools requires -approve-file"
+			return cfg, 2
+		}
+	default:
+		cfg.parseError = fmt.Sprintf("error: invalid -output %q (allowed: text, json)", cfg.outputFormat)
+		return cfg, 2
+	}
+	if strings.TrimSpace(tuiPriceRaw) != "" {
+		if !cfg.tui {
+			cfg.parseError = "error: -tui-price requires -tui"
+			return cfg, 2
+		}
+		p, perr := parseTUIPrice(tuiPriceRaw)
+		if perr != nil {
+			cfg.parseError = "error: " + perr.Error()
+			return cfg, 2
+		}
+		cfg.tuiPrice = &p
+	}
+	cfg.scriptPath = strings.TrimSpace(cfg.scriptPath)
+	if cfg.scriptPath != "" && (strings.TrimSpace(cfg.prompt) != "" || strings.TrimSpace(cfg.promptFile) != "" || strings.TrimSpace(cfg.audioPrompt) != "" || strings.TrimSpace(cfg.loadMessagesPath) != "") {
+		cfg.parseError = "error: -script cannot be combined with -prompt, -prompt-file, -audio-prompt, or -load-messages"
+		return cfg, 2
+	}
+	cfg.batchPath = strings.TrimSpace(cfg.batchPath)
+	if cfg.batchPath != "" {
+		if cfg.scriptPath != "" || strings.TrimSpace(cfg.prompt) != "" || strings.TrimSpace(cfg.promptFile) != "" || strings.TrimSpace(cfg.promptTemplate) != "" || strings.TrimSpace(cfg.audioPrompt) != "" || strings.TrimSpace(cfg.loadMessagesPath) != "" {
+			cfg.parseError = "error: -batch cannot be combined with -prompt, -prompt-file, -prompt-template, -audio-prompt, -load-messages, or -script"
+			return cfg, 2
+		}
+		if cfg.outputFile != "" || strings.TrimSpace(cfg.saveMessagesPath) != "" || strings.TrimSpace(cfg.goldenPath) != "" || cfg.tui {
+			cfg.parseError = "error: -batch writes its results to -batch-out; -output-file, -save-messages, -golden, and -tui do not apply"
+			return cfg, 2
+		}
+		if cfg.batchConcurrency < 1 {
+			cfg.parseError = "error: -batch-concurrency must be >= 1"
+			return cfg, 2
+		}
+	} else if strings.TrimSpace(cfg.batchOut) != "" {
+		cfg.parseError = "error: -batch-out requires -batch"
+		return cfg, 2
+	}
 	if strings.TrimSpace(cfg.loadMessagesPath) != "" {
 		// Loading messages conflicts with providing -prompt or -prompt-file
 		if strings.TrimSpace(cfg.prompt) != "" || strings.TrimSpace(cfg.promptFile) != "" {
 			cfg.parseError = "error: -load-messages cannot be combined with -prompt or -prompt-file"
 			return cfg, 2
 		}
+		if strings.TrimSpace(cfg.audioPrompt) != "" {
+			cfg.parseError = "error: -load-messages cannot be combined with -audio-prompt"
+			return cfg, 2
+		}
 	}
 	// Prep top_p source labeling for config dump
 	if cfg.prepTopP > 0 {
@@ -600,5 +1064,38 @@ func parseFlags() (cliConfig, int) {
 			return cfg, 2
 		}
 	}
+	if r := strings.TrimSpace(cfg.stateRemote); r != "" {
+		if strings.TrimSpace(cfg.stateDir) == "" {
+			cfg.parseError = "error: -state-remote requires -state-dir to be set; it is the local copy"
+			return cfg, 2
+		}
+		if !strings.HasPrefix(r, "s3://") && !strings.HasPrefix(r, "gs://") {
+			cfg.parseError = fmt.Sprintf("error: invalid -state-remote %q; want s3://bucket/prefix or gs://bucket/prefix", r)
+			return cfg, 2
+		}
+		cfg.stateRemote = r
+	}
+	if stateInspection(cfg) && strings.TrimSpace(cfg.stateDir) == "" {
+		cfg.parseError = "error: -state-list, -state-show, and -state-fork require -state-dir to be set"
+		return cfg, 2
+	}
 	return cfg, 0
 }
+
+// parseExtraBody decodes -extra-body. It must be a JSON object whose keys are
+// not fields agentcli already sets (model, messages, temperature, ...).
+func parseExtraBody(raw string) (map[string]json.RawMessage, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(raw), &body); err != nil || body == nil {
+		return nil, fmt.Errorf("-extra-body must be a JSON object")
+	}
+	for k := range body {
+		if oai.IsRequestField(k) {
+			return nil, fmt.Errorf("-extra-body key %q is set by agentcli; use its flag instead", k)
+		}
+	}
+	return body, nil
+}
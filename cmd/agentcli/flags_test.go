@@ -8,6 +8,9 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/gemini"
 )
 
 // TestParseFlags_SystemAndSystemFile_MutuallyExclusive ensures providing both
@@ -491,3 +494,117 @@ func TestImageHTTPKnobsPrecedence(t *testing.T) {
 			}
 		})
 }
+
+// TestSchemaSimplify_FlagValidationAndAutoTier covers parsing of -schema-simplify
+// and -schema-min-tier and the auto decision based on model tier.
+func TestSchemaSimplify_FlagValidationAndAutoTier(t *testing.T) {
+	orig := os.Args
+	defer func() { os.Args = orig }()
+	t.Setenv("OAI_SCHEMA_SIMPLIFY", "")
+	t.Setenv("OAI_SCHEMA_MIN_TIER", "")
+
+	os.Args = []string{"agentcli.test", "-prompt", "p", "-schema-simplify", "sometimes"}
+	if _, code := parseFlags(); code != 2 {
+		t.Fatalf("invalid -schema-simplify: exit=%d want 2", code)
+	}
+	os.Args = []string{"agentcli.test", "-prompt", "p", "-schema-min-tier", "huge"}
+	if _, code := parseFlags(); code != 2 {
+		t.Fatalf("invalid -schema-min-tier: exit=%d want 2", code)
+	}
+
+	os.Args = []string{"agentcli.test", "-prompt", "p", "-model", "llama-3.1-8b"}
+	cfg, code := parseFlags()
+	if code != 0 || cfg.schemaSimplify != "auto" || cfg.schemaMinTier != "medium" {
+		t.Fatalf("defaults: code=%d simplify=%q tier=%q", code, cfg.schemaSimplify, cfg.schemaMinTier)
+	}
+	if !shouldSimplifySchemas(cfg) {
+		t.Fatalf("8b model below medium tier should be simplified")
+	}
+	cfg.model = "gpt-5"
+	if shouldSimplifySchemas(cfg) {
+		t.Fatalf("large model should not be simplified in auto mode")
+	}
+	cfg.schemaSimplify = "always"
+	if !shouldSimplifySchemas(cfg) {
+		t.Fatalf("always should force simplification")
+	}
+}
+
+func TestParseFlags_ExtraBody(t *testing.T) {
+	orig := os.Args
+	defer func() { os.Args = orig }()
+	os.Args = []string{"agentcli.test", "-prompt", "p", "-extra-body", `{"guided_json":{"type":"object"},"best_of":2}`}
+	cfg, code := parseFlags()
+	if code != 0 || string(cfg.extraBody["best_of"]) != "2" || string(cfg.extraBody["guided_json"]) != `{"type":"object"}` {
+		t.Fatalf("code=%d extraBody=%v err=%q", code, cfg.extraBody, cfg.parseError)
+	}
+	for _, bad := range []string{`[1]`, `{"temperature":1}`, `not json`} {
+		os.Args = []string{"agentcli.test", "-prompt", "p", "-extra-body", bad}
+		if cfg, code := parseFlags(); code != 2 || !strings.Contains(cfg.parseError, "-extra-body") {
+			t.Fatalf("%s: code=%d err=%q", bad, code, cfg.parseError)
+		}
+	}
+}
+
+func TestParseFlags_ToolTimeoutGrace(t *testing.T) {
+	origArgs := os.Args
+	defer func() { os.Args = origArgs }()
+	os.Args = []string{"agentcli.test", "-prompt", "p", "-tool-timeout-grace", "5"}
+	cfg, code := parseFlags()
+	if code != 0 || cfg.toolGrace != 5*time.Second {
+		t.Fatalf("code=%d grace=%s", code, cfg.toolGrace)
+	}
+	os.Args = []string{"agentcli.test", "-prompt", "p", "-tool-timeout-grace", "0"}
+	cfg, code = parseFlags()
+	if code != 2 || !strings.Contains(cfg.parseError, "-tool-timeout-grace must be > 0") {
+		t.Fatalf("code=%d err=%q", code, cfg.parseError)
+	}
+}
+
+func TestParseFlags_InvalidRedactPattern(t *testing.T) {
+	origArgs := os.Args
+	defer func() { os.Args = origArgs }()
+	os.Args = []string{"agentcli.test", "-prompt", "p"}
+	t.Setenv("GOAGENT_REDACT", "ok-\\d+\nbad(")
+	cfg, code := parseFlags()
+	if code != 2 || !strings.Contains(cfg.parseError, `GOAGENT_REDACT: invalid pattern "bad("`) {
+		t.Fatalf("code=%d err=%q", code, cfg.parseError)
+	}
+}
+
+func TestParseFlags_Provider(t *testing.T) {
+	origArgs := os.Args
+	defer func() { os.Args = origArgs }()
+	t.Setenv("OAI_BASE_URL", "")
+	t.Setenv("AWS_REGION", "eu-west-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	os.Args = []string{"agentcli.test", "-prompt", "p", "-provider", "bedrock"}
+	cfg, code := parseFlags()
+	if code != 2 || !strings.Contains(cfg.parseError, "AWS_ACCESS_KEY_ID") {
+		t.Fatalf("code=%d err=%q", code, cfg.parseError)
+	}
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	cfg, code = parseFlags()
+	if code != 0 || cfg.providerTransport == nil || cfg.baseURL != "https://bedrock-runtime.eu-west-1.amazonaws.com" {
+		t.Fatalf("code=%d base=%q err=%q", code, cfg.baseURL, cfg.parseError)
+	}
+
+	os.Args = []string{"agentcli.test", "-prompt", "p", "-provider", "gemini", "-api-key", ""}
+	t.Setenv("OAI_API_KEY", "")
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("GEMINI_API_KEY", "")
+	t.Setenv("GOOGLE_API_KEY", "g-key")
+	cfg, code = parseFlags()
+	if code != 0 || cfg.providerTransport == nil || cfg.baseURL != gemini.DefaultBaseURL {
+		t.Fatalf("code=%d base=%q err=%q", code, cfg.baseURL, cfg.parseError)
+	}
+
+	os.Args = []string{"agentcli.test", "-prompt", "p", "-provider", "azure"}
+	cfg, code = parseFlags()
+	if code != 2 || !strings.Contains(cfg.parseError, `invalid -provider "azure"`) {
+		t.Fatalf("code=%d err=%q", code, cfg.parseError)
+	}
+}
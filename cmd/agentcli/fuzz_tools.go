@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/tools"
+)
+
+// runFuzzTools implements `agentcli fuzz-tools -tools <manifest>`: each tool
+// is run with schema-conformant random arguments and must answer with valid
+// JSON (or a JSON error) within its timeout. Exits 1 when any tool fails.
+func runFuzzTools(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("fuzz-tools", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	var only stringSliceFlag
+	toolsPath := fs.String("tools", "", "")
+	iterations := fs.Int("iterations", 25, "")
+	seed := fs.Int64("seed", 0, "")
+	toolTimeout := fs.Duration("tool-timeout", 30*time.Second, "")
+	workDir := fs.String("workdir", "", "")
+	fs.Var(&only, "tool", "")
+	if err := fs.Parse(args); err != nil {
+		safeFprintf(stderr, "error: fuzz-tools: %v\n", err)
+		return 2
+	}
+	if strings.TrimSpace(*toolsPath) == "" {
+		safeFprintln(stderr, "error: fuzz-tools requires -tools <manifest>")
+		return 2
+	}
+	if *iterations < 1 {
+		safeFprintln(stderr, "error: -iterations must be >= 1")
+		return 2
+	}
+	registry, _, err := tools.LoadManifest(*toolsPath)
+	if err != nil {
+		safeFprintf(stderr, "error: failed to load tools manifest: %v\n", err)
+		return 2
+	}
+	names := make([]string, 0, len(registry))
+	for name, spec := range registry {
+		// Aliases run their canonical tool, which is fuzzed under its own name
+		if name != spec.Name {
+			continue
+		}
+		if len(only) == 0 || containsString(only, name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		safeFprintln(stderr, "error: no tools selected")
+		return 2
+	}
+	sort.Strings(names)
+
+	// Tools run in a scratch directory by default so file-writing tools
+	// cannot touch the caller's tree with random arguments.
+	dir := strings.TrimSpace(*workDir)
+	if dir == "" {
+		tmp, err := os.MkdirTemp("", "agentcli-fuzz-")
+		if err != nil {
+			safeFprintf(stderr, "error: create fuzz workdir: %v\n", err)
+			return 1
+		}
+		defer func() { _ = os.RemoveAll(tmp) }() //nolint:errcheck
+		dir = tmp
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	safeFprintf(stdout, "fuzz-tools: seed=%d iterations=%d workdir=%s\n", *seed, *iterations, dir)
+
+	ctx, stop := agentSignalContext()
+	defer stop()
+	r := rand.New(rand.NewSource(*seed))
+	failed := 0
+	for _, name := range names {
+		spec := registry[name]
+		spec.WorkDir = dir
+		rep := tools.FuzzTool(ctx, spec, *iterations, r, *toolTimeout)
+		status := "PASS"
+		if len(rep.Failures) > 0 {
+			status = "FAIL"
+			failed++
+		}
+		safeFprintf(stdout, "%s %s %d/%d (controlled errors: %d)\n", status, name, rep.Passed, rep.Iterations, rep.ControlledErrors)
+		for _, f := range rep.Failures {
+			safeFprintf(stdout, "  iteration %d: %s; args=%s\n", f.Iteration, f.Problem, f.Args)
+		}
+	}
+	if ctx.Err() != nil {
+		return exitInterrupted
+	}
+	if failed > 0 {
+		safeFprintf(stdout, "fuzz-tools: %d of %d tools failed (rerun with -seed %d to reproduce)\n", failed, len(names), *seed)
+		return 1
+	}
+	return 0
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCLIMain_FuzzTools(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell tool fixtures require a POSIX shell")
+	}
+	dir := t.TempDir()
+	scripts := map[string]string{
+		"good.sh": "#!/bin/sh\ncat >/dev/null\necho '{\"ok\":true}'\n",
+		"bad.sh":  "#!/bin/sh\ncat >/dev/null\necho 'panic: nil map' >&2\nexit 2\n",
+	}
+	for name, body := range scripts {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	schema := map[string]any{"type": "object", "properties": map[string]any{"q": map[string]any{"type": "string"}}}
+	man, _ := json.Marshal(map[string]any{"tools": []map[string]any{ //nolint:errcheck
+		{"name": "good", "schema": schema, "command": []string{filepath.Join(dir, "good.sh")}},
+		{"name": "bad", "schema": schema, "command": []string{filepath.Join(dir, "bad.sh")}},
+	}})
+	manifest := filepath.Join(dir, "tools.json")
+	if err := os.WriteFile(manifest, man, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out, errb bytes.Buffer
+	if code := cliMain([]string{"fuzz-tools", "-tools", manifest, "-iterations", "3", "-seed", "5", "-tool", "good"}, &out, &errb); code != 0 {
+		t.Fatalf("good only: exit=%d out=%s err=%s", code, out.String(), errb.String())
+	}
+	if !strings.Contains(out.String(), "seed=5") || !strings.Contains(out.String(), "PASS good 3/3") {
+		t.Fatalf("stdout=%s", out.String())
+	}
+
+	out.Reset()
+	if code := cliMain([]string{"fuzz-tools", "-tools", manifest, "-iterations", "2", "-seed", "5"}, &out, &errb); code != 1 {
+		t.Fatalf("exit=%d want 1; out=%s", code, out.String())
+	}
+	if !strings.Contains(out.String(), "FAIL bad 0/2") || !strings.Contains(out.String(), "panic: nil map") || !strings.Contains(out.String(), "-seed 5") {
+		t.Fatalf("stdout=%s", out.String())
+	}
+
+	if code := cliMain([]string{"fuzz-tools"}, &out, &errb); code != 2 {
+		t.Fatalf("missing -tools: exit=%d", code)
+	}
+}
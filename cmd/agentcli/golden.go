@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// goldenTranscript is a -golden reference run: its tool-call sequence and
+// final answer, compared against the run's own when it finishes.
+type goldenTranscript struct {
+	path          string
+	calls         []string
+	final         string
+	callTolerance int
+	minSimilarity float64
+	ignoreArgs    bool
+}
+
+// goldenEdit is one entry of a -golden structured diff.
+type goldenEdit struct {
+	Op     string `json:"op"` // missing | extra | changed
+	Index  int    `json:"index"`
+	Golden string `json:"golden,omitempty"`
+	Got    string `json:"got,omitempty"`
+}
+
+// goldenReport is printed to stderr as JSON when a run deviates from -golden.
+type goldenReport struct {
+	Golden    string `json:"golden"`
+	ToolCalls struct {
+		Distance  int          `json:"distance"`
+		Tolerance int          `json:"tolerance"`
+		Diff      []goldenEdit `json:"diff,omitempty"`
+	} `json:"tool_calls"`
+	Final struct {
+		Similarity    float64 `json:"similarity"`
+		MinSimilarity float64 `json:"min_similarity"`
+		Golden        string  `json:"golden,omitempty"`
+		Got           string  `json:"got,omitempty"`
+	} `json:"final"`
+}
+
+// loadGolden reads a -golden transcript in any format -load-messages accepts.
+// It returns nil when -golden is unset.
+func loadGolden(cfg cliConfig) (*goldenTranscript, error) {
+	if cfg.goldenPath == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(cfg.goldenPath)
+	if err != nil {
+		return nil, fmt.Errorf("read -golden: %w", err)
+	}
+	msgs, _, err := parseSavedMessages(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse -golden: %w", err)
+	}
+	g := &goldenTranscript{path: cfg.goldenPath, callTolerance: cfg.goldenCallTolerance, minSimilarity: cfg.goldenFinalSimilarity, ignoreArgs: cfg.goldenIgnoreArgs}
+	g.calls = g.callSequence(msgs)
+	final, ok := finalAnswerOf(msgs)
+	if !ok {
+		return nil, fmt.Errorf("-golden %s has no final assistant answer", cfg.goldenPath)
+	}
+	g.final = final
+	return g, nil
+}
+
+// check compares a finished run with the golden transcript and returns the
+// exit code: 0 within tolerance, otherwise exitSucceedIfMissed after printing
+// a structured diff.
+func (g *goldenTranscript) check(transcript []oai.Message, final string, stderr io.Writer) int {
+	if g == nil {
+		return 0
+	}
+	var rep goldenReport
+	rep.Golden = g.path
+	got := g.callSequence(transcript)
+	rep.ToolCalls.Diff = editScript(g.calls, got)
+	rep.ToolCalls.Distance = len(rep.ToolCalls.Diff)
+	rep.ToolCalls.Tolerance = g.callTolerance
+	gw, fw := strings.Fields(g.final), strings.Fields(final)
+	rep.Final.Similarity = 1
+	if n := max(len(gw), len(fw)); n > 0 {
+		rep.Final.Similarity = 1 - float64(len(editScript(gw, fw)))/float64(n)
+	}
+	rep.Final.MinSimilarity = g.minSimilarity
+	callsOK := rep.ToolCalls.Distance <= g.callTolerance
+	finalOK := rep.Final.Similarity >= g.minSimilarity
+	if callsOK && finalOK {
+		return 0
+	}
+	if callsOK {
+		rep.ToolCalls.Diff = nil
+	}
+	if !finalOK {
+		rep.Final.Golden = strings.TrimSpace(g.final)
+		rep.Final.Got = strings.TrimSpace(final)
+	}
+	safeFprintf(stderr, "error: run does not match -golden %s\n", g.path)
+	if b, err := json.MarshalIndent(map[string]goldenReport{"golden_diff": rep}, "", "  "); err == nil {
+		safeFprintln(stderr, string(b))
+	}
+	return exitSucceedIfMissed
+}
+
+// callSequence lists a transcript's tool calls in order as "name args" with
+// arguments in compact form, or just the name under -golden-ignore-args.
+func (g *goldenTranscript) callSequence(msgs []oai.Message) []string {
+	var out []string
+	for _, m := range msgs {
+		if m.Role != oai.RoleAssistant {
+			continue
+		}
+		for _, tc := range m.ToolCalls {
+			s := tc.Function.Name
+			if !g.ignoreArgs {
+				var buf bytes.Buffer
+				args := strings.TrimSpace(tc.Function.Arguments)
+				if json.Compact(&buf, []byte(args)) == nil {
+					args = buf.String()
+				}
+				if args != "" && args != "{}" {
+					s += " " + args
+				}
+			}
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// finalAnswerOf returns the content of the last assistant message without
+// tool calls.
+func finalAnswerOf(msgs []oai.Message) (string, bool) {
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if m := msgs[i]; m.Role == oai.RoleAssistant && len(m.ToolCalls) == 0 {
+			return m.Content, true
+		}
+	}
+	return "", false
+}
+
+// editScript returns a minimal list of edits (Levenshtein, unit costs) that
+// turns want into got. Indexes refer to got for extra and changed entries and
+// to want for missing ones.
+func editScript(want, got []string) []goldenEdit {
+	n, m := len(want), len(got)
+	d := make([][]int, n+1)
+	for i := range d {
+		d[i] = make([]int, m+1)
+		d[i][0] = i
+	}
+	for j := 0; j <= m; j++ {
+		d[0][j] = j
+	}
+	for i := 1; i <= n; i++ {
+		for j := 1; j <= m; j++ {
+			sub := d[i-1][j-1]
+			if want[i-1] != got[j-1] {
+				sub++
+			}
+			d[i][j] = min(sub, d[i-1][j]+1, d[i][j-1]+1)
+		}
+	}
+	var edits []goldenEdit
+	for i, j := n, m; i > 0 || j > 0; {
+		switch {
+		case i > 0 && j > 0 && want[i-1] == got[j-1] && d[i][j] == d[i-1][j-1]:
+			i, j = i-1, j-1
+		case i > 0 && j > 0 && d[i][j] == d[i-1][j-1]+1:
+			edits = append(edits, goldenEdit{Op: "changed", Index: j - 1, Golden: want[i-1], Got: got[j-1]})
+			i, j = i-1, j-1
+		case i > 0 && d[i][j] == d[i-1][j]+1:
+			edits = append(edits, goldenEdit{Op: "missing", Index: i - 1, Golden: want[i-1]})
+			i--
+		default:
+			edits = append(edits, goldenEdit{Op: "extra", Index: j - 1, Got: got[j-1]})
+			j--
+		}
+	}
+	for l, r := 0, len(edits)-1; l < r; l, r = l+1, r-1 {
+		edits[l], edits[r] = edits[r], edits[l]
+	}
+	return edits
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+func TestEditScript(t *testing.T) {
+	got := editScript([]string{"a", "b", "c"}, []string{"a", "x", "c", "d"})
+	want := []goldenEdit{{Op: "changed", Index: 1, Golden: "b", Got: "x"}, {Op: "extra", Index: 3, Got: "d"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v", got)
+	}
+	if got := editScript([]string{"a", "b"}, []string{"b"}); len(got) != 1 || got[0].Op != "missing" || got[0].Golden != "a" {
+		t.Fatalf("got %+v", got)
+	}
+}
+
+// pingOnceServer calls ping with host "a", then answers "done today".
+func pingOnceServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	return fakeChatServer(t, nil, callTools(func(oai.ChatCompletionsRequest) string { return "done today" }, toolCall("c1", "ping", `{ "host": "a" }`)))
+}
+
+func writeGolden(t *testing.T, host, final string) string {
+	t.Helper()
+	msgs := []oai.Message{
+		{Role: oai.RoleUser, Content: "q"},
+		{Role: oai.RoleAssistant, ToolCalls: []oai.ToolCall{{ID: "g1", Type: "function", Function: oai.ToolCallFunction{Name: "ping", Arguments: `{"host":"` + host + `"}`}}}},
+		{Role: oai.RoleTool, ToolCallID: "g1", Name: "ping", Content: `{"ok":true}`},
+		{Role: oai.RoleAssistant, Channel: "final", Content: final},
+	}
+	b, err := json.Marshal(map[string]any{"messages": msgs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "golden.json")
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCLIMain_Golden(t *testing.T) {
+	toolsPath := writeEchoOKTool(t)
+	srv := pingOnceServer(t)
+	run := func(golden string, extra ...string) (int, string) {
+		var out, errb bytes.Buffer
+		args := append([]string{"-prompt", "q", "-tools", toolsPath, "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-golden", golden}, extra...)
+		return cliMain(args, &out, &errb), errb.String()
+	}
+
+	if code, errs := run(writeGolden(t, "a", "done  today")); code != 0 {
+		t.Fatalf("matching golden: exit=%d stderr=%s", code, errs)
+	}
+
+	code, errs := run(writeGolden(t, "b", "done today"))
+	if code != exitSucceedIfMissed {
+		t.Fatalf("changed args: exit=%d stderr=%s", code, errs)
+	}
+	var rep struct {
+		Diff goldenReport `json:"golden_diff"`
+	}
+	if err := json.Unmarshal([]byte(errs[strings.Index(errs, "{"):]), &rep); err != nil {
+		t.Fatalf("diff JSON: %v\n%s", err, errs)
+	}
+	if d := rep.Diff.ToolCalls; d.Distance != 1 || len(d.Diff) != 1 || d.Diff[0].Op != "changed" || d.Diff[0].Got != `ping {"host":"a"}` {
+		t.Fatalf("tool call diff=%+v", d)
+	}
+	if rep.Diff.Final.Got != "" {
+		t.Fatalf("matching final answer included in diff: %+v", rep.Diff.Final)
+	}
+
+	if code, errs := run(writeGolden(t, "b", "done today"), "-golden-ignore-args"); code != 0 {
+		t.Fatalf("-golden-ignore-args: exit=%d stderr=%s", code, errs)
+	}
+	if code, errs := run(writeGolden(t, "a", "done yesterday"), "-golden-final-similarity", "0.5"); code != 0 {
+		t.Fatalf("similar final: exit=%d stderr=%s", code, errs)
+	}
+	if code, errs := run(writeGolden(t, "a", "done yesterday")); code != exitSucceedIfMissed || !strings.Contains(errs, `"similarity": 0.5`) {
+		t.Fatalf("different final: exit=%d stderr=%s", code, errs)
+	}
+}
+
+func TestParseFlags_GoldenTolerancesNeedGolden(t *testing.T) {
+	orig := os.Args
+	defer func() { os.Args = orig }()
+	os.Args = []string{"agentcli.test", "-prompt", "p", "-golden-ignore-args"}
+	if cfg, code := parseFlags(); code != 2 || !strings.Contains(cfg.parseError, "require -golden") {
+		t.Fatalf("code=%d err=%q", code, cfg.parseError)
+	}
+	os.Args = []string{"agentcli.test", "-prompt", "p", "-golden", "g.json", "-golden-final-similarity", "1.5"}
+	if cfg, code := parseFlags(); code != 2 || !strings.Contains(cfg.parseError, "between 0 and 1") {
+		t.Fatalf("code=%d err=%q", code, cfg.parseError)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"crypto/tls"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// retryJitterFraction spreads retries from concurrent agents hitting the
+// same rate limit so they do not retry in lockstep.
+const retryJitterFraction = 0.2
+
+// retryPolicyFor builds the chat retry policy shared by the pre-stage and
+// main clients: jittered exponential backoff plus the per-base-URL breaker.
+func retryPolicyFor(cfg cliConfig, retries int, backoff time.Duration) oai.RetryPolicy {
+	cooldown := cfg.httpBreakerCooldown
+	if cooldown <= 0 {
+		cooldown = 30 * time.Second
+	}
+	return oai.RetryPolicy{
+		MaxRetries:       retries,
+		Backoff:          backoff,
+		JitterFraction:   retryJitterFraction,
+		BreakerThreshold: cfg.httpBreakerThreshold,
+		BreakerCooldown:  cooldown,
+	}
+}
+
+// limitsFor maps the response cap and -http-gzip onto the chat clients.
+func limitsFor(cfg cliConfig) oai.Limits {
+	return oai.Limits{MaxResponseBytes: int64(cfg.httpMaxResponseBytes), GzipRequests: cfg.httpGzip}
+}
+
+// transportOptionsFor maps transport flags onto the shared oai transport.
+func transportOptionsFor(cfg cliConfig) oai.TransportOptions {
+	opts := oai.DefaultTransportOptions()
+	opts.MaxIdleConns = cfg.httpMaxIdleConns
+	opts.KeepAlive = cfg.httpKeepAlive
+	opts.CABundlePath = cfg.caBundlePath
+	if v, ok := oai.ParseTLSVersion(cfg.tlsMinVersion); ok {
+		opts.TLSMinVersion = v
+	} else {
+		opts.TLSMinVersion = tls.VersionTLS12
+	}
+	return opts
+}
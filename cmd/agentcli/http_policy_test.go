@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestTransportFlags_ResolutionAndValidation(t *testing.T) {
+	orig := os.Args
+	defer func() { os.Args = orig }()
+	for _, k := range []string{"OAI_HTTP_MAX_IDLE_CONNS", "OAI_HTTP_KEEPALIVE", "OAI_TLS_MIN_VERSION", "OAI_CA_BUNDLE"} {
+		t.Setenv(k, "")
+	}
+
+	os.Args = []string{"agentcli.test", "-prompt", "p"}
+	cfg, code := parseFlags()
+	if code != 0 || cfg.httpMaxIdleConns != 100 || cfg.httpKeepAlive != 30*time.Second || cfg.tlsMinVersion != "1.2" {
+		t.Fatalf("defaults: code=%d idle=%d keepalive=%s tls=%q", code, cfg.httpMaxIdleConns, cfg.httpKeepAlive, cfg.tlsMinVersion)
+	}
+
+	t.Setenv("OAI_HTTP_MAX_IDLE_CONNS", "8")
+	os.Args = []string{"agentcli.test", "-prompt", "p", "-http-keepalive", "0", "-tls-min-version", "1.3", "-ca-bundle", "/etc/ca.pem"}
+	cfg, code = parseFlags()
+	if code != 0 {
+		t.Fatalf("exit=%d", code)
+	}
+	opts := transportOptionsFor(cfg)
+	if opts.MaxIdleConns != 8 || opts.KeepAlive != 0 || opts.TLSMinVersion != tls.VersionTLS13 || opts.CABundlePath != "/etc/ca.pem" {
+		t.Fatalf("unexpected transport options: %+v", opts)
+	}
+
+	os.Args = []string{"agentcli.test", "-prompt", "p", "-tls-min-version", "1.0"}
+	if _, code := parseFlags(); code != 2 {
+		t.Fatalf("TLS 1.0 must be rejected: exit=%d", code)
+	}
+}
+
+func TestCliMain_InvalidCABundleExits2(t *testing.T) {
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-prompt", "p", "-ca-bundle", "/nonexistent/ca.pem"}, &out, &errb)
+	if code != 2 {
+		t.Fatalf("exit=%d want 2; stderr=%s", code, errb.String())
+	}
+}
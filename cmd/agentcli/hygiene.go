@@ -1,6 +1,11 @@
 package main
 
 import (
+	"encoding/json"
+	"path"
+	"path/filepath"
+	"strings"
+
 	"github.com/hyperifyio/goagent/internal/oai"
 )
 
@@ -26,3 +31,157 @@ func applyTranscriptHygiene(in []oai.Message, debug bool) []oai.Message {
 	}
 	return out
 }
+
+// staleReadTools are the bundled tools whose result is a file's content.
+var staleReadTools = map[string]bool{"fs_read_file": true, "fs_read_lines": true}
+
+// pruneStaleReads collapses file reads that a later tool call has made
+// outdated: once fs_write_file, fs_append_file, fs_edit_range,
+// fs_apply_patch, fs_rm, fs_move, or text_replace succeeds on a path, the
+// content of earlier fs_read_file/fs_read_lines results for that path (or
+// any path under it) is replaced with a compact JSON marker so only the
+// latest authoritative view stays in context. Calls issued in the same assistant
+// turn as the write run concurrently and are left alone. Tool messages
+// keep their tool_call_id so the transcript stays valid.
+func pruneStaleReads(in []oai.Message) []oai.Message {
+	type call struct {
+		turn int
+		name string
+		args string
+	}
+	calls := make(map[string]call)
+	results := make(map[string]string)
+	failed := make(map[string]bool)
+	for i, m := range in {
+		switch m.Role {
+		case oai.RoleAssistant:
+			for _, tc := range m.ToolCalls {
+				calls[tc.ID] = call{turn: i, name: tc.Function.Name, args: tc.Function.Arguments}
+			}
+		case oai.RoleTool:
+			results[m.ToolCallID] = m.Content
+			failed[m.ToolCallID] = isToolError(m.Content)
+		}
+	}
+	// The last turn that successfully modified each path
+	modified := make(map[string]int)
+	for id, c := range calls {
+		if failed[id] {
+			continue
+		}
+		for _, p := range modifiedPaths(c.name, c.args, results[id]) {
+			if t, ok := modified[p]; !ok || c.turn > t {
+				modified[p] = c.turn
+			}
+		}
+	}
+	if len(modified) == 0 {
+		return in
+	}
+	var out []oai.Message
+	for i, m := range in {
+		if m.Role != oai.RoleTool {
+			continue
+		}
+		c, ok := calls[m.ToolCallID]
+		if !ok || !staleReadTools[c.name] {
+			continue
+		}
+		var args struct {
+			Path string `json:"path"`
+		}
+		if json.Unmarshal([]byte(c.args), &args) != nil || strings.TrimSpace(args.Path) == "" {
+			continue
+		}
+		read := cleanToolPath(args.Path)
+		for p, t := range modified {
+			if t > c.turn && (read == p || strings.HasPrefix(read, p+"/")) {
+				if out == nil {
+					out = append([]oai.Message(nil), in...)
+				}
+				b, _ := json.Marshal(map[string]any{"stale": true, "reason": "file-modified-later", "path": args.Path}) //nolint:errcheck
+				out[i].Content = string(b)
+				break
+			}
+		}
+	}
+	if out == nil {
+		return in
+	}
+	return out
+}
+
+// modifiedPaths returns the cleaned paths a bundled file tool call changes.
+// text_replace picks its files by glob, so they are read from its result.
+func modifiedPaths(name, argsJSON, result string) []string {
+	var args struct {
+		Path        string `json:"path"`
+		From        string `json:"from"`
+		To          string `json:"to"`
+		UnifiedDiff string `json:"unifiedDiff"`
+		DryRun      bool   `json:"dryRun"`
+	}
+	if json.Unmarshal([]byte(argsJSON), &args) != nil {
+		return nil
+	}
+	var raw []string
+	switch name {
+	case "fs_write_file", "fs_append_file", "fs_edit_range", "fs_rm":
+		raw = []string{args.Path}
+	case "fs_move":
+		raw = []string{args.From, args.To}
+	case "text_replace":
+		var res struct {
+			Files []struct {
+				Path string `json:"path"`
+			} `json:"files"`
+			DryRun bool `json:"dryRun"`
+		}
+		if args.DryRun || json.Unmarshal([]byte(result), &res) != nil || res.DryRun {
+			return nil
+		}
+		// Paths in a named root keep their "root:" prefix, as reads of them do
+		for _, f := range res.Files {
+			raw = append(raw, f.Path)
+		}
+	case "fs_apply_patch":
+		if args.DryRun {
+			return nil
+		}
+		for _, line := range strings.Split(args.UnifiedDiff, "\n") {
+			if !strings.HasPrefix(line, "--- ") && !strings.HasPrefix(line, "+++ ") {
+				continue
+			}
+			p := strings.TrimSpace(line[4:])
+			if tab := strings.IndexByte(p, '\t'); tab >= 0 {
+				p = p[:tab]
+			}
+			if p == "/dev/null" {
+				continue
+			}
+			if strings.HasPrefix(p, "a/") || strings.HasPrefix(p, "b/") {
+				p = p[2:]
+			}
+			raw = append(raw, p)
+		}
+	}
+	var out []string
+	for _, p := range raw {
+		if strings.TrimSpace(p) != "" {
+			out = append(out, cleanToolPath(p))
+		}
+	}
+	return out
+}
+
+func cleanToolPath(p string) string {
+	return path.Clean(filepath.ToSlash(strings.TrimSpace(p)))
+}
+
+// isToolError reports whether a tool message carries an {"error":...} result.
+func isToolError(content string) bool {
+	var v struct {
+		Error json.RawMessage `json:"error"`
+	}
+	return json.Unmarshal([]byte(content), &v) == nil && len(v.Error) > 0
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+func callTurn(id, name, args string) oai.Message {
+	return oai.Message{Role: oai.RoleAssistant, ToolCalls: []oai.ToolCall{{ID: id, Type: "function", Function: oai.ToolCallFunction{Name: name, Arguments: args}}}}
+}
+
+func callResult(id, name, content string) oai.Message {
+	return oai.Message{Role: oai.RoleTool, Name: name, ToolCallID: id, Content: content}
+}
+
+func TestPruneStaleReads(t *testing.T) {
+	in := []oai.Message{
+		{Role: oai.RoleUser, Content: "edit"},
+		callTurn("r1", "fs_read_file", `{"path":"./src/a.go"}`),
+		callResult("r1", "fs_read_file", `{"contentBase64":"b2xk"}`),
+		callTurn("r2", "fs_read_lines", `{"path":"src/b.go","startLine":1,"endLine":2}`),
+		callResult("r2", "fs_read_lines", `{"content":"b"}`),
+		callTurn("r3", "fs_read_file", `{"path":"src/c.go"}`),
+		callResult("r3", "fs_read_file", `{"contentBase64":"Yw=="}`),
+		callTurn("w1", "fs_write_file", `{"path":"src/a.go","contentBase64":"bmV3"}`),
+		callResult("w1", "fs_write_file", `{"bytesWritten":3}`),
+		// A failed write leaves b.go's read authoritative
+		callTurn("w2", "fs_edit_range", `{"path":"src/b.go"}`),
+		callResult("w2", "fs_edit_range", `{"error":"sha mismatch"}`),
+		callTurn("p1", "fs_apply_patch", "{\"unifiedDiff\":\"--- a/src/c.go\\n+++ b/src/c.go\\n@@ -1 +1 @@\\n-c\\n+d\\n\"}"),
+		callResult("p1", "fs_apply_patch", `{"filesChanged":1}`),
+		callTurn("r4", "fs_read_file", `{"path":"src/a.go"}`),
+		callResult("r4", "fs_read_file", `{"contentBase64":"bmV3"}`),
+	}
+	out := pruneStaleReads(in)
+	if got := out[2].Content; got != `{"path":"./src/a.go","reason":"file-modified-later","stale":true}` {
+		t.Fatalf("a.go read not collapsed: %s", got)
+	}
+	if out[4].Content != `{"content":"b"}` {
+		t.Fatalf("read kept after failed write was collapsed: %s", out[4].Content)
+	}
+	if !strings.Contains(out[6].Content, `"stale":true`) {
+		t.Fatalf("c.go read not collapsed after patch: %s", out[6].Content)
+	}
+	if out[14].Content != `{"contentBase64":"bmV3"}` || out[14].ToolCallID != "r4" {
+		t.Fatalf("latest read changed: %+v", out[14])
+	}
+	if in[2].Content != `{"contentBase64":"b2xk"}` {
+		t.Fatal("input transcript was modified")
+	}
+}
+
+func TestPruneStaleReads_SameTurnAndDirectories(t *testing.T) {
+	in := []oai.Message{
+		{Role: oai.RoleAssistant, ToolCalls: []oai.ToolCall{
+			{ID: "r1", Type: "function", Function: oai.ToolCallFunction{Name: "fs_read_file", Arguments: `{"path":"x.txt"}`}},
+			{ID: "w1", Type: "function", Function: oai.ToolCallFunction{Name: "fs_write_file", Arguments: `{"path":"x.txt"}`}},
+		}},
+		callResult("r1", "fs_read_file", "old"),
+		callResult("w1", "fs_write_file", "{}"),
+		callTurn("r2", "fs_read_file", `{"path":"dir/sub/y.txt"}`),
+		callResult("r2", "fs_read_file", "y"),
+		callTurn("m1", "fs_move", `{"from":"dir","to":"dir2"}`),
+		callResult("m1", "fs_move", `{"moved":true}`),
+	}
+	out := pruneStaleReads(in)
+	if out[1].Content != "old" {
+		t.Fatalf("same-turn read collapsed: %s", out[1].Content)
+	}
+	if !strings.Contains(out[4].Content, `"stale":true`) {
+		t.Fatalf("read under moved directory kept: %s", out[4].Content)
+	}
+}
+
+func TestPruneStaleReads_TextReplace(t *testing.T) {
+	in := []oai.Message{
+		callTurn("r1", "fs_read_file", `{"path":"a.go"}`),
+		callResult("r1", "fs_read_file", "a"),
+		callTurn("r2", "fs_read_file", `{"path":"api:b.go"}`),
+		callResult("r2", "fs_read_file", "b"),
+		callTurn("r3", "fs_read_file", `{"path":"c.go"}`),
+		callResult("r3", "fs_read_file", "c"),
+		callTurn("d1", "text_replace", `{"pattern":"x","replacement":"y","dryRun":true}`),
+		callResult("d1", "text_replace", `{"files":[{"path":"c.go","replacements":1,"lines":[1]}],"totalReplacements":1,"filesChanged":1,"dryRun":true}`),
+		callTurn("t1", "text_replace", `{"pattern":"x","replacement":"y"}`),
+		callResult("t1", "text_replace", `{"files":[{"path":"a.go","replacements":1,"lines":[1]}],"totalReplacements":1,"filesChanged":1}`),
+		callTurn("t2", "text_replace", `{"pattern":"x","replacement":"y","root":"api"}`),
+		callResult("t2", "text_replace", `{"files":[{"path":"api:b.go","replacements":2,"lines":[1,3]}],"totalReplacements":2,"filesChanged":1}`),
+	}
+	out := pruneStaleReads(in)
+	if !strings.Contains(out[1].Content, `"stale":true`) {
+		t.Fatalf("a.go read kept after text_replace: %s", out[1].Content)
+	}
+	if !strings.Contains(out[3].Content, `"stale":true`) {
+		t.Fatalf("api:b.go read kept after text_replace in root api: %s", out[3].Content)
+	}
+	if out[5].Content != "c" {
+		t.Fatalf("read collapsed by a dry run: %s", out[5].Content)
+	}
+}
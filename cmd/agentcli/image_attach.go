@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// maxImageAttachBytes caps one -image-attach file; providers reject larger
+// inline images anyway.
+const maxImageAttachBytes = 20 << 20
+
+// loadImageAttachments turns -image-attach values into image content parts.
+// http(s) and data: URLs are passed through for the provider to fetch;
+// anything else is a local file sent inline as a base64 data URL.
+func loadImageAttachments(refs []string) ([]oai.ContentPart, error) {
+	var parts []oai.ContentPart
+	for _, ref := range refs {
+		ref = strings.TrimSpace(ref)
+		if ref == "" {
+			continue
+		}
+		lower := strings.ToLower(ref)
+		if strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "data:image/") {
+			parts = append(parts, oai.ImagePart(ref))
+			continue
+		}
+		url, err := imageDataURL(ref)
+		if err != nil {
+			return nil, fmt.Errorf("-image-attach %s: %w", ref, err)
+		}
+		parts = append(parts, oai.ImagePart(url))
+	}
+	return parts, nil
+}
+
+func imageDataURL(path string) (string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !fi.Mode().IsRegular() {
+		return "", fmt.Errorf("not a regular file")
+	}
+	if fi.Size() > maxImageAttachBytes {
+		return "", fmt.Errorf("file is %d bytes; the limit is %d", fi.Size(), maxImageAttachBytes)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	// Trust the content over the extension, except for SVG, which the
+	// sniffer reports as text
+	sniffed := http.DetectContentType(data)
+	mediaType := sniffed
+	if strings.HasPrefix(sniffed, "text/") && mime.TypeByExtension(strings.ToLower(filepath.Ext(path))) == "image/svg+xml" {
+		mediaType = "image/svg+xml"
+	}
+	if !strings.HasPrefix(mediaType, "image/") {
+		return "", fmt.Errorf("not an image (detected %s)", sniffed)
+	}
+	return "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
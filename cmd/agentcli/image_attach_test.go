@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestLoadImageAttachments(t *testing.T) {
+	dir := t.TempDir()
+	png := filepath.Join(dir, "shot.png")
+	svg := filepath.Join(dir, "diagram.svg")
+	txt := filepath.Join(dir, "notes.png")
+	for path, data := range map[string][]byte{png: pngHeader, svg: []byte(`<svg xmlns="http://www.w3.org/2000/svg"/>`), txt: []byte("plain text")} {
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	parts, err := loadImageAttachments([]string{png, "https://example.com/a.jpg", svg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 3 || parts[0].Type != "image_url" ||
+		!strings.HasPrefix(parts[0].ImageURL.URL, "data:image/png;base64,") ||
+		parts[1].ImageURL.URL != "https://example.com/a.jpg" ||
+		!strings.HasPrefix(parts[2].ImageURL.URL, "data:image/svg+xml;base64,") {
+		t.Fatalf("parts=%+v", parts)
+	}
+	for _, bad := range []string{txt, filepath.Join(dir, "missing.png"), dir} {
+		if _, err := loadImageAttachments([]string{bad}); err == nil || !strings.Contains(err.Error(), "-image-attach") {
+			t.Errorf("%s: err=%v", bad, err)
+		}
+	}
+}
+
+func TestCLIMain_ImageAttachSendsContentParts(t *testing.T) {
+	img := filepath.Join(t.TempDir(), "shot.png")
+	if err := os.WriteFile(img, pngHeader, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)                                                                                                                                                  //nolint:errcheck
+		_ = json.NewEncoder(w).Encode(oai.ChatCompletionsResponse{Choices: []oai.ChatCompletionsResponseChoice{{Message: oai.Message{Role: oai.RoleAssistant, Content: "a chart"}}}}) //nolint:errcheck
+	}))
+	defer srv.Close()
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-prompt", "describe", "-image-attach", img, "-image-attach", "https://example.com/b.png", "-base-url", srv.URL, "-model", "m", "-prep-enabled=false"}, &out, &errb)
+	if code != 0 || strings.TrimSpace(out.String()) != "a chart" {
+		t.Fatalf("exit=%d stdout=%q stderr=%s", code, out.String(), errb.String())
+	}
+	var req struct {
+		Messages []struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		t.Fatal(err)
+	}
+	user := string(req.Messages[len(req.Messages)-1].Content)
+	if !strings.HasPrefix(user, `[{"type":"text","text":"describe"},{"type":"image_url","image_url":{"url":"data:image/png;base64,`) || !strings.HasSuffix(user, `{"type":"image_url","image_url":{"url":"https://example.com/b.png"}}]`) {
+		t.Fatalf("user content=%s", user)
+	}
+
+	errb.Reset()
+	if code := cliMain([]string{"-prompt", "p", "-image-attach", filepath.Join(t.TempDir(), "none.png"), "-base-url", srv.URL, "-prep-enabled=false"}, &out, &errb); code != 2 || !strings.Contains(errb.String(), "error: -image-attach") {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+}
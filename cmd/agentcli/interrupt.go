@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/clock"
+	"github.com/hyperifyio/goagent/internal/oai"
+	"github.com/hyperifyio/goagent/internal/state"
+)
+
+// exitInterrupted is returned when SIGINT/SIGTERM stops a run (128+SIGINT).
+const exitInterrupted = 130
+
+// agentSignalContext returns the run context canceled on SIGINT/SIGTERM.
+// Tests replace it to simulate an interrupt.
+var agentSignalContext = func() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// handleInterrupt reports a canceled run, snapshots the transcript to
+// -state-dir when set, and returns exitInterrupted.
+func handleInterrupt(cfg cliConfig, messages []oai.Message, step int, stderr io.Writer) int {
+	safeFprintf(stderr, "info: interrupted at step %d; canceled in-flight request and tools\n", step+1)
+	if strings.TrimSpace(cfg.stateDir) != "" {
+		if err := state.SaveStateBundle(cfg.stateDir, interruptedBundle(cfg, messages, step)); err != nil {
+			safeFprintf(stderr, "WARN: failed to save state: %v\n", err)
+		} else {
+			safeFprintf(stderr, "info: saved state to %s\n", cfg.stateDir)
+			if cfg.plan != nil {
+				cfg.plan.saved = true
+			}
+		}
+	}
+	return exitInterrupted
+}
+
+// interruptedBundle captures the prompts and transcript of a canceled run.
+func interruptedBundle(cfg cliConfig, messages []oai.Message, step int) *state.StateBundle {
+	return runBundle(cfg, messages, map[string]any{"interrupted": true, "step": step + 1})
+}
+
+// runBundle captures the prompts, transcript, and pre-stage plan of a run.
+// details becomes the bundle context, with the transcript under "messages".
+func runBundle(cfg cliConfig, messages []oai.Message, details map[string]any) *state.StateBundle {
+	prompts := map[string]string{}
+	for _, m := range messages {
+		if (m.Role == oai.RoleSystem || m.Role == oai.RoleUser) && prompts[m.Role] == "" {
+			prompts[m.Role] = m.Content
+		}
+	}
+	// Round-trip through JSON so the state sanitizer sees plain maps
+	var transcript []any
+	if b, err := json.Marshal(messages); err == nil {
+		_ = json.Unmarshal(b, &transcript) //nolint:errcheck
+	}
+	details["messages"] = transcript
+	toolsetHash := computeToolsetHash(strings.TrimSpace(cfg.toolsPath))
+	scope := strings.TrimSpace(cfg.stateScope)
+	if scope == "" {
+		scope = computeDefaultStateScope(cfg.model, cfg.baseURL, toolsetHash)
+	}
+	return &state.StateBundle{
+		Version:     "1",
+		CreatedAt:   clock.Now().UTC().Format(time.RFC3339),
+		ToolVersion: version,
+		ModelID:     cfg.model,
+		BaseURL:     cfg.baseURL,
+		ToolsetHash: toolsetHash,
+		ScopeKey:    scope,
+		Prompts:     prompts,
+		Context:     details,
+		SourceHash:  state.ComputeSourceHash(cfg.model, cfg.baseURL, toolsetHash, scope),
+		Plan:        cfg.plan.snapshot(),
+	}
+}
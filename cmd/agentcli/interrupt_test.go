@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// interruptAfter makes runAgent's signal context fire after d.
+func interruptAfter(t *testing.T, d time.Duration) {
+	t.Helper()
+	orig := agentSignalContext
+	agentSignalContext = func() (context.Context, context.CancelFunc) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(d, cancel)
+		return ctx, cancel
+	}
+	t.Cleanup(func() { agentSignalContext = orig })
+}
+
+func TestRunAgent_Interrupt_CancelsHTTPAndSavesState(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body) //nolint:errcheck
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+		}
+	}))
+	defer srv.Close()
+	interruptAfter(t, 200*time.Millisecond)
+
+	dir := t.TempDir()
+	cfg := cliConfig{prompt: "hang", systemPrompt: "sys", baseURL: srv.URL, model: "m", maxSteps: 2,
+		httpTimeout: 10 * time.Second, httpRetries: 3, prepEnabledSet: true, stateDir: dir, stateScope: "s"}
+	var out, errb bytes.Buffer
+	start := time.Now()
+	code := runAgent(cfg, &out, &errb)
+	if code != exitInterrupted {
+		t.Fatalf("exit=%d want %d stderr=%s", code, exitInterrupted, errb.String())
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Fatalf("interrupt took %s; retries should stop on cancel", d)
+	}
+	if !strings.Contains(errb.String(), "interrupted at step 1") {
+		t.Fatalf("stderr=%s", errb.String())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "latest.json")); err != nil {
+		t.Fatalf("state not saved: %v", err)
+	}
+}
+
+func TestRunAgent_Interrupt_StopsRunningTool(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell tool fixture requires a POSIX shell")
+	}
+	dir := t.TempDir()
+	script := filepath.Join(dir, "slow.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nexec sleep 30\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	man, _ := json.Marshal(map[string]any{"tools": []map[string]any{{"name": "slow", "schema": map[string]any{"type": "object"}, "command": []string{script}}}}) //nolint:errcheck
+	manifest := filepath.Join(dir, "tools.json")
+	if err := os.WriteFile(manifest, man, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(oai.ChatCompletionsResponse{Choices: []oai.ChatCompletionsResponseChoice{{ //nolint:errcheck
+			Message: oai.Message{Role: oai.RoleAssistant, ToolCalls: []oai.ToolCall{{ID: "c1", Type: "function", Function: oai.ToolCallFunction{Name: "slow", Arguments: "{}"}}}},
+		}}})
+	}))
+	defer srv.Close()
+	interruptAfter(t, 300*time.Millisecond)
+
+	cfg := cliConfig{prompt: "p", systemPrompt: "sys", toolsPath: manifest, baseURL: srv.URL, model: "m", maxSteps: 3,
+		httpTimeout: 10 * time.Second, toolTimeout: 30 * time.Second, prepEnabledSet: true}
+	var out, errb bytes.Buffer
+	start := time.Now()
+	if code := runAgent(cfg, &out, &errb); code != exitInterrupted {
+		t.Fatalf("exit=%d want %d stderr=%s", code, exitInterrupted, errb.String())
+	}
+	if d := time.Since(start); d > 10*time.Second {
+		t.Fatalf("tool was not stopped on interrupt (took %s)", d)
+	}
+}
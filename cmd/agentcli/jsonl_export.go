@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+	"github.com/hyperifyio/goagent/internal/redact"
+)
+
+// fineTuneMessage is one message in the OpenAI chat fine-tuning format.
+// Weight 0 marks assistant turns that should not be trained on.
+type fineTuneMessage struct {
+	Role       string         `json:"role"`
+	Content    string         `json:"content,omitempty"`
+	Name       string         `json:"name,omitempty"`
+	ToolCallID string         `json:"tool_call_id,omitempty"`
+	ToolCalls  []oai.ToolCall `json:"tool_calls,omitempty"`
+	Weight     *int           `json:"weight,omitempty"`
+}
+
+// fineTuneRecord is one JSONL line: a whole conversation plus the tools that
+// were advertised for it.
+type fineTuneRecord struct {
+	Messages []fineTuneMessage `json:"messages"`
+	Tools    []oai.Tool        `json:"tools,omitempty"`
+}
+
+// toFineTuneRecord converts a transcript to the fine-tuning format. Developer
+// messages become system messages, channels are dropped, and assistant turns
+// on a non-final channel (critic, confidence) get weight 0.
+func toFineTuneRecord(messages []oai.Message, tools []oai.Tool) fineTuneRecord {
+	rec := fineTuneRecord{Tools: tools}
+	for _, m := range messages {
+		fm := fineTuneMessage{Role: m.Role, Content: m.Content, Name: m.Name, ToolCallID: m.ToolCallID, ToolCalls: m.ToolCalls}
+		switch m.Role {
+		case oai.RoleDeveloper:
+			fm.Role = oai.RoleSystem
+		case oai.RoleAssistant:
+			if ch := strings.TrimSpace(m.Channel); ch != "" && ch != "final" {
+				zero := 0
+				fm.Weight = &zero
+			}
+		}
+		rec.Messages = append(rec.Messages, fm)
+	}
+	return rec
+}
+
+// exportFinalTranscript appends the finished transcript to -export-jsonl as
+// one redacted fine-tuning record. It returns the exit code for the run.
+func exportFinalTranscript(cfg cliConfig, messages []oai.Message, tools []oai.Tool, stderr io.Writer) int {
+	if cfg.exportJSONL == "" {
+		return 0
+	}
+	b, err := json.Marshal(toFineTuneRecord(messages, tools))
+	if err != nil {
+		safeFprintf(stderr, "error: encode -export-jsonl record: %v\n", err)
+		return 1
+	}
+	line := append(redact.Bytes(b), '\n')
+	if err := os.MkdirAll(filepath.Dir(cfg.exportJSONL), 0o755); err != nil {
+		safeFprintf(stderr, "error: write -export-jsonl: %v\n", err)
+		return 1
+	}
+	// A single O_APPEND write keeps concurrent exporters from interleaving lines
+	f, err := os.OpenFile(cfg.exportJSONL, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		safeFprintf(stderr, "error: write -export-jsonl: %v\n", err)
+		return 1
+	}
+	_, werr := f.Write(line)
+	if cerr := f.Close(); werr == nil {
+		werr = cerr
+	}
+	if werr != nil {
+		safeFprintf(stderr, "error: write -export-jsonl: %v\n", werr)
+		return 1
+	}
+	return 0
+}
+
+// parseFineTuneJSONL reads fine-tuning JSONL and returns the messages of the
+// last record, so a file built up by repeated -export-jsonl runs loads the
+// most recent conversation. Weights and tool definitions are ignored.
+func parseFineTuneJSONL(data []byte) ([]oai.Message, error) {
+	var last []byte
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	n := 0
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		n++
+		if !json.Valid(line) {
+			return nil, fmt.Errorf("JSONL line %d is not valid JSON", n)
+		}
+		last = append(last[:0], line...)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if last == nil {
+		return nil, fmt.Errorf("JSONL file has no records")
+	}
+	var rec struct {
+		Messages []oai.Message `json:"messages"`
+	}
+	if err := json.Unmarshal(last, &rec); err != nil {
+		return nil, fmt.Errorf("JSONL record %d: %w", n, err)
+	}
+	if len(rec.Messages) == 0 {
+		return nil, fmt.Errorf("JSONL record %d has no messages", n)
+	}
+	return rec.Messages, nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"io"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// -validate-messages modes.
+const (
+	validateStrict = "strict"
+	validateRepair = "repair"
+)
+
+// repairMessages applies -validate-messages=repair: it returns messages
+// fixed by oai.RepairMessageSequence and prints one warning per change.
+// In strict mode messages are returned as they are.
+func repairMessages(cfg cliConfig, messages []oai.Message, stderr io.Writer) []oai.Message {
+	if cfg.validateMessages != validateRepair {
+		return messages
+	}
+	out, actions := oai.RepairMessageSequence(messages)
+	for _, a := range actions {
+		safeFprintf(stderr, "warning: repaired message sequence: %s\n", a)
+	}
+	return out
+}
+
+// reportSequenceProblems follows a strict validation error with every
+// problem in messages, so one run shows all of them.
+func reportSequenceProblems(messages []oai.Message, stderr io.Writer) {
+	_, actions := oai.RepairMessageSequence(messages)
+	if len(actions) == 0 {
+		return
+	}
+	safeFprintf(stderr, "%d problems (-validate-messages=repair would fix them):\n", len(actions))
+	for _, a := range actions {
+		safeFprintf(stderr, "  - %s\n", a)
+	}
+}
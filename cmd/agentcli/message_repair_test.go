@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// brokenTranscript has an orphaned tool result and a call without a result.
+const brokenTranscript = `[
+ {"role":"system","content":"s"},
+ {"role":"tool","tool_call_id":"ghost","content":"stray"},
+ {"role":"user","content":"q"},
+ {"role":"assistant","tool_calls":[{"id":"c1","type":"function","function":{"name":"ping","arguments":"{}"}}]},
+ {"role":"user","content":"go on"}
+]`
+
+func TestValidateMessages_RepairFixesLoadedTranscript(t *testing.T) {
+	var sent []oai.Message
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req oai.ChatCompletionsRequest
+		_ = json.NewDecoder(r.Body).Decode(&req) //nolint:errcheck
+		sent = req.Messages
+		_ = json.NewEncoder(w).Encode(oai.ChatCompletionsResponse{Choices: []oai.ChatCompletionsResponseChoice{{FinishReason: "stop", Message: oai.Message{Role: oai.RoleAssistant, Content: "ok"}}}}) //nolint:errcheck
+	}))
+	defer srv.Close()
+	path := filepath.Join(t.TempDir(), "msgs.json")
+	if err := os.WriteFile(path, []byte(brokenTranscript), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out, errb bytes.Buffer
+	args := []string{"-load-messages", path, "-base-url", srv.URL, "-model", "m", "-prep-enabled=false"}
+	if code := cliMain(args, &out, &errb); code != exitValidation {
+		t.Fatalf("strict: exit=%d stderr=%s", code, errb.String())
+	}
+	if !strings.Contains(errb.String(), "2 problems") || !strings.Contains(errb.String(), `"ghost"`) {
+		t.Fatalf("strict diagnostics: %s", errb.String())
+	}
+
+	out.Reset()
+	errb.Reset()
+	if code := cliMain(append(args, "-validate-messages", "repair"), &out, &errb); code != 0 {
+		t.Fatalf("repair: exit=%d stderr=%s", code, errb.String())
+	}
+	if strings.Count(errb.String(), "warning: repaired message sequence:") != 2 {
+		t.Fatalf("repair warnings: %s", errb.String())
+	}
+	roles := []string{}
+	for _, m := range sent {
+		roles = append(roles, m.Role)
+	}
+	if strings.Join(roles, ",") != "system,user,assistant,tool,user" || sent[3].ToolCallID != "c1" {
+		t.Fatalf("sent roles=%v", roles)
+	}
+}
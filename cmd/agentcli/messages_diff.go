@@ -0,0 +1,265 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+	"github.com/hyperifyio/goagent/internal/redact"
+)
+
+// msgEdit is one step of a transcript alignment: a message kept, removed
+// from the old side, added on the new side, or changed in place.
+type msgEdit struct {
+	kind byte // ' ', '-', '+', '~'
+	a, b int  // indexes into the old and new transcripts; -1 when absent
+}
+
+// alignMessages aligns two transcripts on identical messages (longest common
+// subsequence). Between anchors, removed and added messages of the same role
+// pair up in order as changes, which is how pre-stage merging usually shows:
+// a rewritten system or developer message, a tool call with new arguments.
+func alignMessages(a, b []oai.Message) []msgEdit {
+	keys := func(ms []oai.Message) []string {
+		out := make([]string, len(ms))
+		for i, m := range ms {
+			data, _ := json.Marshal(m) //nolint:errcheck // Message always marshals
+			out[i] = string(data)
+		}
+		return out
+	}
+	ka, kb := keys(a), keys(b)
+	// lcs[i][j] is the common length of ka[i:] and kb[j:]
+	lcs := make([][]int, len(ka)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(kb)+1)
+	}
+	for i := len(ka) - 1; i >= 0; i-- {
+		for j := len(kb) - 1; j >= 0; j-- {
+			if ka[i] == kb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var edits []msgEdit
+	var removed, added []int
+	flush := func() {
+		for len(removed) > 0 || len(added) > 0 {
+			switch {
+			case len(removed) > 0 && len(added) > 0 && a[removed[0]].Role == b[added[0]].Role:
+				edits = append(edits, msgEdit{'~', removed[0], added[0]})
+				removed, added = removed[1:], added[1:]
+			case len(removed) > 0 && (len(added) == 0 || !hasRole(b, added, a[removed[0]].Role)):
+				edits = append(edits, msgEdit{'-', removed[0], -1})
+				removed = removed[1:]
+			default:
+				edits = append(edits, msgEdit{'+', -1, added[0]})
+				added = added[1:]
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(ka) || j < len(kb) {
+		switch {
+		case i < len(ka) && j < len(kb) && ka[i] == kb[j]:
+			flush()
+			edits = append(edits, msgEdit{' ', i, j})
+			i++
+			j++
+		case j < len(kb) && (i == len(ka) || lcs[i][j+1] >= lcs[i+1][j]):
+			added = append(added, j)
+			j++
+		default:
+			removed = append(removed, i)
+			i++
+		}
+	}
+	flush()
+	return edits
+}
+
+// hasRole reports whether any message at idx in ms has role.
+func hasRole(ms []oai.Message, idx []int, role string) bool {
+	for _, i := range idx {
+		if ms[i].Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// writeMessagesDiff prints a structural diff of two transcripts and reports
+// whether they differ. Content is redacted and shortened.
+func writeMessagesDiff(w io.Writer, nameA, nameB string, a, b []oai.Message) bool {
+	safeFprintf(w, "--- %s (%d messages)\n+++ %s (%d messages)\n", nameA, len(a), nameB, len(b))
+	counts := map[byte]int{}
+	for _, e := range alignMessages(a, b) {
+		counts[e.kind]++
+		switch e.kind {
+		case ' ':
+			safeFprintf(w, "  [%d] %s\n", e.a, describeMessage(a[e.a]))
+		case '-':
+			safeFprintf(w, "- [%d] %s\n", e.a, describeMessage(a[e.a]))
+			writeMessageBody(w, "-", a[e.a])
+		case '+':
+			safeFprintf(w, "+ [%d] %s\n", e.b, describeMessage(b[e.b]))
+			writeMessageBody(w, "+", b[e.b])
+		case '~':
+			safeFprintf(w, "~ [%d -> %d] %s\n", e.a, e.b, a[e.a].Role)
+			for _, line := range messageChanges(a[e.a], b[e.b]) {
+				safeFprintf(w, "    %s\n", line)
+			}
+		}
+	}
+	safeFprintf(w, "summary: %d added, %d removed, %d changed, %d unchanged\n", counts['+'], counts['-'], counts['~'], counts[' '])
+	return counts['+']+counts['-']+counts['~'] > 0
+}
+
+// describeMessage is the one-line header of a message: role, channel, and
+// tool identifiers.
+func describeMessage(m oai.Message) string {
+	var b strings.Builder
+	b.WriteString(m.Role)
+	if m.Channel != "" {
+		fmt.Fprintf(&b, " channel=%s", m.Channel)
+	}
+	if m.Name != "" {
+		fmt.Fprintf(&b, " name=%s", m.Name)
+	}
+	if m.ToolCallID != "" {
+		fmt.Fprintf(&b, " tool_call_id=%s", m.ToolCallID)
+	}
+	for _, tc := range m.ToolCalls {
+		fmt.Fprintf(&b, " call=%s(%s)", tc.Function.Name, tc.ID)
+	}
+	if len(m.Parts) > 0 {
+		fmt.Fprintf(&b, " parts=%d", len(m.Parts))
+	}
+	return b.String()
+}
+
+func writeMessageBody(w io.Writer, sign string, m oai.Message) {
+	if m.Content != "" {
+		safeFprintf(w, "%s     %s\n", sign, diffSnippet(m.Content))
+	}
+}
+
+// messageChanges lists what differs between two messages of the same role.
+func messageChanges(x, y oai.Message) []string {
+	var out []string
+	field := func(name, a, b string) {
+		if a != b {
+			out = append(out, fmt.Sprintf("%s %q -> %q", name, a, b))
+		}
+	}
+	field("channel", x.Channel, y.Channel)
+	field("name", x.Name, y.Name)
+	field("tool_call_id", x.ToolCallID, y.ToolCallID)
+	if x.Content != y.Content {
+		out = append(out, "content changed:", "- "+diffSnippet(x.Content), "+ "+diffSnippet(y.Content))
+	}
+	out = append(out, toolCallChanges(x.ToolCalls, y.ToolCalls)...)
+	if !jsonEqual(x.FunctionCall, y.FunctionCall) {
+		out = append(out, "function_call changed")
+	}
+	if !jsonEqual(x.Parts, y.Parts) {
+		out = append(out, fmt.Sprintf("parts changed (%d -> %d)", len(x.Parts), len(y.Parts)))
+	}
+	return out
+}
+
+// toolCallChanges matches calls by ID (by position when IDs are missing).
+func toolCallChanges(x, y []oai.ToolCall) []string {
+	key := func(i int, tc oai.ToolCall) string {
+		if tc.ID != "" {
+			return tc.ID
+		}
+		return "#" + strconv.Itoa(i)
+	}
+	old := map[string]oai.ToolCall{}
+	for i, tc := range x {
+		old[key(i, tc)] = tc
+	}
+	var out []string
+	seen := map[string]bool{}
+	for i, tc := range y {
+		k := key(i, tc)
+		seen[k] = true
+		prev, ok := old[k]
+		switch {
+		case !ok:
+			out = append(out, fmt.Sprintf("+ tool call %s(%s) %s", tc.Function.Name, k, diffSnippet(tc.Function.Arguments)))
+		case prev.Function.Name != tc.Function.Name:
+			out = append(out, fmt.Sprintf("~ tool call %s: %s -> %s", k, prev.Function.Name, tc.Function.Name))
+		case prev.Function.Arguments != tc.Function.Arguments:
+			out = append(out, fmt.Sprintf("~ tool call %s(%s) arguments %s -> %s", tc.Function.Name, k, diffSnippet(prev.Function.Arguments), diffSnippet(tc.Function.Arguments)))
+		}
+	}
+	for i, tc := range x {
+		if k := key(i, tc); !seen[k] {
+			out = append(out, fmt.Sprintf("- tool call %s(%s)", tc.Function.Name, k))
+		}
+	}
+	return out
+}
+
+func jsonEqual(a, b any) bool {
+	ja, _ := json.Marshal(a) //nolint:errcheck // plain data
+	jb, _ := json.Marshal(b) //nolint:errcheck // plain data
+	return string(ja) == string(jb)
+}
+
+func diffSnippet(s string) string {
+	return strconv.Quote(truncateRunes(redact.String(oneLine(s)), 160))
+}
+
+// parseDiffMessages splits the -diff-messages value into one or two paths.
+func parseDiffMessages(raw string) ([]string, error) {
+	var paths []string
+	for _, p := range strings.Split(raw, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	if len(paths) == 0 || len(paths) > 2 {
+		return nil, fmt.Errorf("-diff-messages takes FILE or OLD,NEW; got %q", raw)
+	}
+	return paths, nil
+}
+
+func loadMessagesFile(path string) ([]oai.Message, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("-diff-messages: %w", err)
+	}
+	msgs, _, err := parseSavedMessages(data)
+	if err != nil {
+		return nil, fmt.Errorf("-diff-messages %s: %w", path, err)
+	}
+	return msgs, nil
+}
+
+// runDiffMessages compares two saved transcripts without running the agent.
+// Like diff(1) it exits 0 when they match and 1 when they differ.
+func runDiffMessages(cfg cliConfig, stdout, stderr io.Writer) int {
+	a, err := loadMessagesFile(cfg.diffMessages[0])
+	if err != nil {
+		safeFprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+	b, err := loadMessagesFile(cfg.diffMessages[1])
+	if err != nil {
+		safeFprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+	if writeMessagesDiff(stdout, cfg.diffMessages[0], cfg.diffMessages[1], a, b) {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+func TestWriteMessagesDiff(t *testing.T) {
+	old := []oai.Message{
+		{Role: oai.RoleSystem, Content: "sys"},
+		{Role: oai.RoleDeveloper, Content: "be brief"},
+		{Role: oai.RoleUser, Content: "hi"},
+		{Role: oai.RoleAssistant, ToolCalls: []oai.ToolCall{{ID: "c1", Type: "function", Function: oai.ToolCallFunction{Name: "ls", Arguments: `{"dir":"."}`}}}},
+		{Role: oai.RoleTool, ToolCallID: "c1", Content: "a.txt"},
+	}
+	cur := []oai.Message{
+		{Role: oai.RoleSystem, Content: "sys"},
+		{Role: oai.RoleDeveloper, Content: "be very brief"},
+		{Role: oai.RoleUser, Content: "hi"},
+		{Role: oai.RoleAssistant, Channel: "commentary", ToolCalls: []oai.ToolCall{
+			{ID: "c1", Type: "function", Function: oai.ToolCallFunction{Name: "ls", Arguments: `{"dir":"src"}`}},
+			{ID: "c2", Type: "function", Function: oai.ToolCallFunction{Name: "cat", Arguments: `{}`}},
+		}},
+		{Role: oai.RoleTool, ToolCallID: "c1", Content: "a.txt"},
+		{Role: oai.RoleAssistant, Channel: "final", Content: "done"},
+	}
+	var out bytes.Buffer
+	if !writeMessagesDiff(&out, "old.json", "new.json", old, cur) {
+		t.Fatal("expected a difference")
+	}
+	got := out.String()
+	for _, want := range []string{
+		"--- old.json (5 messages)\n+++ new.json (6 messages)\n",
+		"  [0] system\n",
+		"~ [1 -> 1] developer\n    content changed:\n    - \"be brief\"\n    + \"be very brief\"\n",
+		`channel "" -> "commentary"`,
+		`~ tool call ls(c1) arguments "{\"dir\":\".\"}" -> "{\"dir\":\"src\"}"`,
+		"+ tool call cat(c2)",
+		"  [4] tool tool_call_id=c1\n",
+		"+ [5] assistant channel=final\n+     \"done\"\n",
+		"summary: 1 added, 0 removed, 2 changed, 3 unchanged\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+
+	out.Reset()
+	if writeMessagesDiff(&out, "a", "b", old, old) || !strings.HasSuffix(out.String(), "summary: 0 added, 0 removed, 0 changed, 5 unchanged\n") {
+		t.Fatalf("identical transcripts: %s", out.String())
+	}
+}
+
+func TestAlignMessages_RoleMismatchIsAddRemove(t *testing.T) {
+	a := []oai.Message{{Role: oai.RoleUser, Content: "q"}, {Role: oai.RoleDeveloper, Content: "x"}}
+	b := []oai.Message{{Role: oai.RoleUser, Content: "q"}, {Role: oai.RoleSystem, Content: "x"}}
+	edits := alignMessages(a, b)
+	if len(edits) != 3 || edits[1].kind != '-' || edits[2].kind != '+' {
+		t.Fatalf("edits=%+v", edits)
+	}
+}
+
+func TestParseDiffMessages(t *testing.T) {
+	if got, err := parseDiffMessages(" a.json , b.json "); err != nil || len(got) != 2 || got[1] != "b.json" {
+		t.Fatalf("got=%v err=%v", got, err)
+	}
+	for _, bad := range []string{",", "a,b,c"} {
+		if _, err := parseDiffMessages(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func writeMessagesJSON(t *testing.T, dir, name string, msgs []oai.Message) string {
+	t.Helper()
+	b, err := json.Marshal(buildMessagesWrapper(msgs, "", "", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestCLI_DiffMessagesTwoFiles(t *testing.T) {
+	dir := t.TempDir()
+	a := writeMessagesJSON(t, dir, "a.json", []oai.Message{{Role: oai.RoleUser, Content: "one"}})
+	b := writeMessagesJSON(t, dir, "b.json", []oai.Message{{Role: oai.RoleUser, Content: "two"}})
+
+	var out, errb bytes.Buffer
+	if code := cliMain([]string{"-diff-messages", a + "," + b}, &out, &errb); code != 1 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if !strings.Contains(out.String(), "summary: 0 added, 0 removed, 1 changed, 0 unchanged") {
+		t.Fatalf("stdout=%s", out.String())
+	}
+	out.Reset()
+	if code := cliMain([]string{"-diff-messages", a + "," + a}, &out, &errb); code != 0 {
+		t.Fatalf("identical: exit=%d stdout=%s", code, out.String())
+	}
+	errb.Reset()
+	if code := cliMain([]string{"-diff-messages", a + "," + filepath.Join(dir, "missing.json")}, &out, &errb); code != 2 || !strings.Contains(errb.String(), "error: -diff-messages") {
+		t.Fatalf("missing: exit=%d stderr=%s", code, errb.String())
+	}
+}
+
+func TestCLI_DiffMessagesAgainstRun(t *testing.T) {
+	srv := finalAnswerServer(t)
+	defer srv.Close()
+	saved := writeMessagesJSON(t, t.TempDir(), "saved.json", []oai.Message{
+		{Role: oai.RoleSystem, Content: "other system"},
+		{Role: oai.RoleUser, Content: "q"},
+	})
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-prompt", "q", "-system", "sys", "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-diff-messages", saved}, &out, &errb)
+	if code != 0 || strings.TrimSpace(out.String()) != "done" {
+		t.Fatalf("exit=%d stdout=%s stderr=%s", code, out.String(), errb.String())
+	}
+	if !strings.Contains(errb.String(), "+++ current run (2 messages)") || !strings.Contains(errb.String(), "~ [0 -> 0] system") || !strings.Contains(errb.String(), "  [1] user") {
+		t.Fatalf("stderr=%s", errb.String())
+	}
+}
@@ -5,13 +5,19 @@ import (
 	"strings"
 
 	"github.com/hyperifyio/goagent/internal/oai"
+	"github.com/hyperifyio/goagent/internal/redact"
 )
 
-// parseSavedMessages accepts either a JSON array of oai.Message (legacy format)
-// or a JSON object {"messages":[...], "image_prompt":"..."} and returns
-// the parsed messages and optional image prompt.
+// parseSavedMessages accepts either a JSON array of oai.Message (legacy format),
+// a JSON object {"messages":[...], "image_prompt":"..."}, or OpenAI
+// fine-tuning JSONL as written by -export-jsonl (the last record is used), and
+// returns the parsed messages and optional image prompt.
 func parseSavedMessages(data []byte) ([]oai.Message, string, error) {
 	trimmed := strings.TrimSpace(string(data))
+	if strings.HasPrefix(trimmed, "{") && !json.Valid([]byte(trimmed)) {
+		msgs, err := parseFineTuneJSONL([]byte(trimmed))
+		return msgs, "", err
+	}
 	if strings.HasPrefix(trimmed, "[") {
 		var msgs []oai.Message
 		if err := json.Unmarshal([]byte(trimmed), &msgs); err != nil {
@@ -30,11 +36,12 @@ func parseSavedMessages(data []byte) ([]oai.Message, string, error) {
 }
 
 // buildMessagesWrapper constructs the saved/printed JSON wrapper including
-// the Harmony messages, optional image prompt, and pre-stage metadata.
-func buildMessagesWrapper(messages []oai.Message, imagePrompt string) any {
-	// Pre-stage prompt resolver is not available on this branch; record a
-	// deterministic placeholder so downstream consumers can rely on shape.
-	src, text := "default", ""
+// the Harmony messages, optional image prompt, and pre-stage metadata: the
+// source of the pre-stage prompt ("override" or "default") and its size.
+func buildMessagesWrapper(messages []oai.Message, imagePrompt, prepSource, prepPrompt string) any {
+	if prepSource == "" {
+		prepSource = "default"
+	}
 	type prestageMeta struct {
 		Source string `json:"source"`
 		Bytes  int    `json:"bytes"`
@@ -46,7 +53,7 @@ func buildMessagesWrapper(messages []oai.Message, imagePrompt string) any {
 	}
 	w := wrapper{
 		Messages: messages,
-		Prestage: prestageMeta{Source: src, Bytes: len([]byte(text))},
+		Prestage: prestageMeta{Source: prepSource, Bytes: len(prepPrompt)},
 	}
 	if strings.TrimSpace(imagePrompt) != "" {
 		w.ImagePrompt = strings.TrimSpace(imagePrompt)
@@ -56,11 +63,11 @@ func buildMessagesWrapper(messages []oai.Message, imagePrompt string) any {
 
 // writeSavedMessages writes the wrapper JSON with messages, optional image_prompt,
 // and pre-stage metadata.
-func writeSavedMessages(path string, messages []oai.Message, imagePrompt string) error {
-	wrapper := buildMessagesWrapper(messages, strings.TrimSpace(imagePrompt))
+func writeSavedMessages(path string, messages []oai.Message, imagePrompt, prepSource, prepPrompt string) error {
+	wrapper := buildMessagesWrapper(messages, strings.TrimSpace(imagePrompt), prepSource, prepPrompt)
 	b, err := json.MarshalIndent(wrapper, "", "  ")
 	if err != nil {
 		return err
 	}
-	return writeFileAtomic(path, b, 0o644)
+	return writeFileAtomic(path, redact.Bytes(b), 0o644)
 }
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+	"github.com/hyperifyio/goagent/internal/tools"
+)
+
+func TestWriteSavedMessages_RedactsSecrets(t *testing.T) {
+	t.Setenv("OAI_API_KEY", "my-local-key-42")
+	t.Setenv("GOAGENT_REDACT", "")
+	path := filepath.Join(t.TempDir(), "msgs.json")
+	msgs := []oai.Message{
+		{Role: oai.RoleSystem, Content: "sys"},
+		{Role: oai.RoleUser, Content: "use key my-local-key-42 and sk-abcdefghijklmnopqrst"},
+	}
+	if err := writeSavedMessages(path, msgs, "", "", ""); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	s := string(data)
+	if strings.Contains(s, "my-local-key-42") || strings.Contains(s, "sk-abcdefghijklmnopqrst") {
+		t.Fatalf("secret leaked into saved messages: %s", s)
+	}
+	if !strings.Contains(s, "[REDACTED:env]") || !strings.Contains(s, "[REDACTED:api_key]") {
+		t.Fatalf("missing redaction markers: %s", s)
+	}
+	if _, _, err := parseSavedMessages(data); err != nil {
+		t.Fatalf("redacted file must still load: %v", err)
+	}
+}
+
+func TestSanitizeToolContent_RedactsSecrets(t *testing.T) {
+	t.Setenv("GOAGENT_REDACT", `internal-\d+`)
+	got := sanitizeToolContent([]byte(`{"token":"ghp_`+strings.Repeat("x", 36)+`","id":"internal-7"}`), nil)
+	if got != `{"token":"[REDACTED:github_token]","id":"[REDACTED:custom]"}` {
+		t.Fatalf("unexpected content: %s", got)
+	}
+}
+
+func TestSanitizeToolContent_TimeoutNamesLimit(t *testing.T) {
+	got := sanitizeToolContent(nil, &tools.TimeoutError{Timeout: 1500 * time.Millisecond})
+	if got != `{"error":"tool timed out","timeout_ms":1500}` {
+		t.Fatalf("unexpected content: %s", got)
+	}
+}
+
+func TestSanitizeToolContent_RateLimitedSaysWhenToRetry(t *testing.T) {
+	got := sanitizeToolContent(nil, &tools.RateLimitError{RetryAfter: 2500 * time.Millisecond})
+	if got != `{"error":"rate_limited","retry_after_ms":2500}` {
+		t.Fatalf("unexpected content: %s", got)
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"io"
+
+	"github.com/hyperifyio/goagent/internal/metrics"
+)
+
+// startMetricsServer serves /metrics on cfg.metricsListen for the lifetime of
+// the run. It returns a stop function and false when the address cannot be bound.
+func startMetricsServer(cfg cliConfig, stderr io.Writer) (func(), bool) {
+	if cfg.metricsListen == "" {
+		return func() {}, true
+	}
+	srv, err := metrics.Listen(cfg.metricsListen)
+	if err != nil {
+		safeFprintf(stderr, "error: -metrics-listen: %v\n", err)
+		return nil, false
+	}
+	return func() { _ = srv.Close() }, true //nolint:errcheck // best-effort shutdown
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/metrics"
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close() //nolint:errcheck
+	return addr
+}
+
+func TestCLIMain_MetricsListen_ServesDuringRun(t *testing.T) {
+	toolsPath := writeEchoOKTool(t)
+	addr := freeAddr(t)
+	toolRuns := metrics.ToolDuration.Count("ping", "ok")
+	var scraped string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req oai.ChatCompletionsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		resp := oai.ChatCompletionsResponse{Usage: &oai.Usage{PromptTokens: 11, CompletionTokens: 3, TotalTokens: 14}}
+		msg := oai.Message{Role: oai.RoleAssistant, ToolCalls: []oai.ToolCall{{ID: "c1", Type: "function", Function: oai.ToolCallFunction{Name: "ping", Arguments: "{}"}}}}
+		if req.Messages[len(req.Messages)-1].Role == oai.RoleTool {
+			// Scrape mid-run, after the tool finished
+			mr, err := http.Get("http://" + addr + "/metrics")
+			if err != nil {
+				t.Errorf("scrape: %v", err)
+			} else {
+				b, _ := io.ReadAll(mr.Body) //nolint:errcheck
+				_ = mr.Body.Close()         //nolint:errcheck
+				scraped = string(b)
+			}
+			msg = oai.Message{Role: oai.RoleAssistant, Channel: "final", Content: "done"}
+		}
+		resp.Choices = []oai.ChatCompletionsResponseChoice{{Message: msg}}
+		_ = json.NewEncoder(w).Encode(resp) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-prompt", "p", "-tools", toolsPath, "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-metrics-listen", addr}, &out, &errb)
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	for _, want := range []string{`goagent_tool_duration_seconds_count{tool="ping",outcome="ok"}`, `goagent_tokens_total{kind="prompt"}`, "goagent_http_request_duration_seconds_bucket"} {
+		if !strings.Contains(scraped, want) {
+			t.Fatalf("scrape missing %q:\n%s", want, scraped)
+		}
+	}
+	if got := metrics.ToolDuration.Count("ping", "ok"); got != toolRuns+1 {
+		t.Fatalf("tool runs=%d want %d", got, toolRuns+1)
+	}
+	// The endpoint is gone once the run returns
+	if _, err := http.Get("http://" + addr + "/metrics"); err == nil {
+		t.Fatal("metrics endpoint still serving after the run")
+	}
+}
+
+func TestCLIMain_MetricsListen_BindErrorIsMisuse(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer func() { _ = ln.Close() }() //nolint:errcheck
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-prompt", "p", "-base-url", "http://127.0.0.1:1", "-prep-enabled=false", "-metrics-listen", ln.Addr().String()}, &out, &errb)
+	if code != 2 || !strings.Contains(errb.String(), "-metrics-listen") {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+}
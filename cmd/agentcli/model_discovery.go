@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/clock"
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// modelsCacheFile is the -discover-models cache, GOAGENT_CACHE_DIR/models.json:
+// the last /models listing of each base URL.
+type modelsCacheFile struct {
+	Endpoints map[string]modelsCacheEntry `json:"endpoints"`
+}
+
+type modelsCacheEntry struct {
+	FetchedAt time.Time       `json:"fetched_at"`
+	Models    []oai.ModelInfo `json:"models"`
+}
+
+func modelsCachePath() string { return filepath.Join(cacheRoot(), "models.json") }
+
+// discoverModels implements -discover-models: it registers the capabilities
+// the base URL's /models endpoint reports, from the cache while younger than
+// -models-cache-ttl. Discovery is best-effort; a failure only warns and the
+// built-in rules stay in effect. -record and -replay bypass the cache so
+// the listing is part of the recording.
+func discoverModels(ctx context.Context, cfg cliConfig, client *oai.Client, stderr io.Writer) {
+	if !cfg.discoverModels {
+		return
+	}
+	key := strings.TrimRight(cfg.baseURL, "/")
+	useCache := cfg.recordDir == "" && cfg.replayDir == ""
+	var cache modelsCacheFile
+	if useCache {
+		cache = readModelsCache()
+		if e, ok := cache.Endpoints[key]; ok && (cfg.modelsCacheTTL == 0 || clock.Since(e.FetchedAt) < cfg.modelsCacheTTL) {
+			oai.RegisterModels(e.Models)
+			if cfg.verbose {
+				safeFprintf(stderr, "info: models cache hit for %s (%d models)\n", key, len(e.Models))
+			}
+			return
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, cfg.httpTimeout)
+	defer cancel()
+	models, err := client.ListModels(ctx)
+	if err != nil {
+		safeFprintf(stderr, "WARN: -discover-models: %v; using built-in model capabilities\n", err)
+		return
+	}
+	oai.RegisterModels(models)
+	if cfg.verbose {
+		safeFprintf(stderr, "info: discovered %d models at %s\n", len(models), key)
+	}
+	if !useCache {
+		return
+	}
+	cache.Endpoints[key] = modelsCacheEntry{FetchedAt: clock.Now().UTC(), Models: models}
+	if err := writeModelsCache(cache); err != nil {
+		safeFprintf(stderr, "WARN: -discover-models: write cache: %v\n", err)
+	}
+}
+
+// readModelsCache returns the cache, or an empty one when it is missing or
+// unreadable.
+func readModelsCache() modelsCacheFile {
+	var cache modelsCacheFile
+	if data, err := os.ReadFile(modelsCachePath()); err == nil {
+		_ = json.Unmarshal(data, &cache) //nolint:errcheck // a corrupt cache is refetched
+	}
+	if cache.Endpoints == nil {
+		cache.Endpoints = map[string]modelsCacheEntry{}
+	}
+	return cache
+}
+
+// writeModelsCache replaces the cache file atomically.
+func writeModelsCache(cache modelsCacheFile) error {
+	path := modelsCachePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "models-*.json")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()           //nolint:errcheck // already failing
+		_ = os.Remove(tmp.Name()) //nolint:errcheck // best-effort cleanup
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name()) //nolint:errcheck // best-effort cleanup
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// warnToolSupport notes on stderr when the discovered listing says the model
+// takes no tool definitions but tools are configured.
+func warnToolSupport(cfg cliConfig, oaiTools []oai.Tool, stderr io.Writer) {
+	if len(oaiTools) > 0 && cfg.toolProtocol != oai.ToolProtocolText && !oai.SupportsTools(cfg.model) {
+		safeFprintf(stderr, "WARN: model %s does not list tool support; consider -tool-protocol text\n", cfg.model)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+func TestCLIMain_DiscoverModels(t *testing.T) {
+	defer oai.ResetModels()
+	t.Setenv("GOAGENT_CACHE_DIR", t.TempDir())
+	t.Setenv("AGENTCLI_DISCOVER_MODELS", "")
+	t.Setenv("AGENTCLI_MODELS_CACHE_TTL", "")
+	toolsPath := writeEchoOKTool(t)
+	listings := 0
+	var temps []bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/models" {
+			listings++
+			_, _ = w.Write([]byte(`{"data":[{"id":"m","context_length":2048,"supported_parameters":["max_tokens"]}]}`)) //nolint:errcheck
+			return
+		}
+		var req oai.ChatCompletionsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		temps = append(temps, req.Temperature != nil)
+		_ = json.NewEncoder(w).Encode(oai.ChatCompletionsResponse{Choices: []oai.ChatCompletionsResponseChoice{{Message: oai.Message{Role: oai.RoleAssistant, Channel: "final", Content: "done"}}}}) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	run := func(extra ...string) string {
+		t.Helper()
+		args := append([]string{"-prompt", "p", "-tools", toolsPath, "-base-url", srv.URL, "-model", "m", "-prep-enabled=false"}, extra...)
+		var out, errb bytes.Buffer
+		if code := cliMain(args, &out, &errb); code != 0 {
+			t.Fatalf("exit=%d stderr=%s", code, errb.String())
+		}
+		return errb.String()
+	}
+
+	run()
+	if listings != 0 || !temps[0] {
+		t.Fatalf("discovery ran without the flag: listings=%d temps=%v", listings, temps)
+	}
+	stderr := run("-discover-models")
+	if listings != 1 || temps[1] {
+		t.Fatalf("listing not applied: listings=%d temps=%v", listings, temps)
+	}
+	if !strings.Contains(stderr, "does not list tool support") {
+		t.Fatalf("missing tool support warning:\n%s", stderr)
+	}
+	if oai.ContextWindowForModel("m") != 2048 {
+		t.Fatalf("window=%d", oai.ContextWindowForModel("m"))
+	}
+	// The second run reads the cache; a zero TTL never expires it
+	oai.ResetModels()
+	run("-discover-models", "-models-cache-ttl", "0")
+	if listings != 1 || temps[2] {
+		t.Fatalf("cache not used: listings=%d temps=%v", listings, temps)
+	}
+	run("-discover-models", "-models-cache-ttl", "1ns")
+	if listings != 2 {
+		t.Fatalf("expired cache reused: listings=%d", listings)
+	}
+}
+
+func TestCLIMain_DiscoverModelsFailureWarns(t *testing.T) {
+	defer oai.ResetModels()
+	t.Setenv("GOAGENT_CACHE_DIR", t.TempDir())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/models" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(oai.ChatCompletionsResponse{Choices: []oai.ChatCompletionsResponseChoice{{Message: oai.Message{Role: oai.RoleAssistant, Channel: "final", Content: "done"}}}}) //nolint:errcheck
+	}))
+	defer srv.Close()
+	var out, errb bytes.Buffer
+	if code := cliMain([]string{"-prompt", "p", "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-discover-models"}, &out, &errb); code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if !strings.Contains(errb.String(), "WARN: -discover-models:") || strings.TrimSpace(out.String()) != "done" {
+		t.Fatalf("stdout=%q stderr=%s", out.String(), errb.String())
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// modelTier is one -model-escalate entry: model serves steps steps before
+// the next tier takes over; the last tier has no limit.
+type modelTier struct {
+	model string
+	steps int
+}
+
+// modelEscalation is the parsed -model-escalate routing rule, cheapest first.
+type modelEscalation []modelTier
+
+// parseModelEscalation parses "small:2,medium:3,large": every tier but the
+// last names its step count after the final colon, so model IDs that contain
+// colons (e.g. "llama3:8b:2") still work. The last tier is taken verbatim.
+func parseModelEscalation(raw string) (modelEscalation, error) {
+	var tiers modelEscalation
+	parts := strings.Split(raw, ",")
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, fmt.Errorf("invalid -model-escalate %q: empty entry", raw)
+		}
+		if i == len(parts)-1 {
+			tiers = append(tiers, modelTier{model: part})
+			break
+		}
+		cut := strings.LastIndex(part, ":")
+		n, err := strconv.Atoi(strings.TrimSpace(part[cut+1:]))
+		if cut <= 0 || err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid -model-escalate entry %q; want MODEL:STEPS with STEPS >= 1 (only the last model has no step count)", part)
+		}
+		tiers = append(tiers, modelTier{model: strings.TrimSpace(part[:cut]), steps: n})
+	}
+	return tiers, nil
+}
+
+// modelFor returns the model for step (1-based). A step that follows a failed
+// tool call is served one tier up.
+func (e modelEscalation) modelFor(step int, afterToolError bool) string {
+	level, used := 0, 0
+	for level < len(e)-1 && step > used+e[level].steps {
+		used += e[level].steps
+		level++
+	}
+	if afterToolError && level < len(e)-1 {
+		level++
+	}
+	return e[level].model
+}
+
+// lastToolsFailed reports whether any result of the latest batch of tool
+// calls is an error.
+func lastToolsFailed(messages []oai.Message) bool {
+	for i := len(messages) - 1; i >= 0 && messages[i].Role == oai.RoleTool; i-- {
+		if isToolError(messages[i].Content) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+func TestParseModelEscalation(t *testing.T) {
+	tiers, err := parseModelEscalation("small:2, llama3:8b:3 ,large:v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := modelEscalation{{model: "small", steps: 2}, {model: "llama3:8b", steps: 3}, {model: "large:v2"}}
+	if !reflect.DeepEqual(tiers, want) {
+		t.Fatalf("got %+v", tiers)
+	}
+	for _, bad := range []string{"small,large", "small:0,large", ":2,large", "small:2,", "small:x,large"} {
+		if _, err := parseModelEscalation(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestModelEscalation_ModelFor(t *testing.T) {
+	e := modelEscalation{{model: "s", steps: 2}, {model: "m", steps: 1}, {model: "l"}}
+	for _, tc := range []struct {
+		step      int
+		toolError bool
+		want      string
+	}{{1, false, "s"}, {2, false, "s"}, {3, false, "m"}, {4, false, "l"}, {9, false, "l"}, {1, true, "m"}, {3, true, "l"}, {5, true, "l"}} {
+		if got := e.modelFor(tc.step, tc.toolError); got != tc.want {
+			t.Errorf("step %d toolError=%v: got %s want %s", tc.step, tc.toolError, got, tc.want)
+		}
+	}
+}
+
+// modelRecordingServer asks for one call of tool, then answers, recording
+// the model of each request.
+func modelRecordingServer(t *testing.T, tool string, models *[]string) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	reply := callTools(func(oai.ChatCompletionsRequest) string { return "done" }, toolCall("c1", tool, `{}`))
+	return fakeChatServer(t, nil, func(req oai.ChatCompletionsRequest) (oai.ChatCompletionsResponse, error) {
+		mu.Lock()
+		*models = append(*models, req.Model)
+		mu.Unlock()
+		return reply(req)
+	})
+}
+
+func TestCLIMain_ModelEscalate(t *testing.T) {
+	toolsPath := writeEchoOKTool(t)
+	for _, tc := range []struct {
+		name, tool, rule string
+		want             []string
+	}{
+		{"by step", "ping", "small:1,large", []string{"small", "large"}},
+		{"tool ok", "ping", "small:5,large", []string{"small", "small"}},
+		{"tool error", "missing", "small:5,large", []string{"small", "large"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var models []string
+			srv := modelRecordingServer(t, tc.tool, &models)
+			var out, errb bytes.Buffer
+			code := cliMain([]string{"-prompt", "q", "-tools", toolsPath, "-base-url", srv.URL, "-model", "ignored", "-prep-enabled=false", "-model-escalate", tc.rule, "-verbose"}, &out, &errb)
+			if code != 0 || !reflect.DeepEqual(models, tc.want) {
+				t.Fatalf("exit=%d models=%v want %v stderr=%s", code, models, tc.want, errb.String())
+			}
+			if escalated := tc.want[1] == "large"; escalated != strings.Contains(errb.String(), "info: step 2 escalates model small -> large") {
+				t.Fatalf("stderr=%s", errb.String())
+			}
+		})
+	}
+	var out, errb bytes.Buffer
+	if code := cliMain([]string{"-prompt", "q", "-model-escalate", "small,large"}, &out, &errb); code != 2 || !strings.Contains(errb.String(), "want MODEL:STEPS") {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/clock"
+	"github.com/hyperifyio/goagent/internal/oai"
+	"github.com/hyperifyio/goagent/internal/redact"
+)
+
+// -output formats.
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// exitReasonCompleted is the -output json exit_reason of a successful run.
+const exitReasonCompleted = "completed"
+
+// jsonToolCall is one tool call in the -output json result.
+type jsonToolCall struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Failed     bool   `json:"failed"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// jsonUsage is the token accounting in the -output json result.
+type jsonUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+	CachedTokens     int `json:"cached_tokens"`
+}
+
+// jsonResult is the single object -output json prints on stdout.
+type jsonResult struct {
+	Final      string         `json:"final"`
+	Steps      int            `json:"steps"`
+	Usage      jsonUsage      `json:"usage"`
+	ToolCalls  []jsonToolCall `json:"tool_calls"`
+	ExitReason string         `json:"exit_reason"`
+	ExitCode   int            `json:"exit_code"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// jsonCollector records the steps and tool calls of a -output json run from
+// its events; tool events arrive from concurrent goroutines.
+type jsonCollector struct {
+	mu      sync.Mutex
+	steps   int
+	calls   []jsonToolCall
+	started map[string]time.Time
+}
+
+func (c *jsonCollector) handle(ev runEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch ev.Kind {
+	case eventStep:
+		c.steps = max(c.steps, ev.Step)
+	case eventToolStart:
+		if c.started == nil {
+			c.started = map[string]time.Time{}
+		}
+		c.started[ev.CallID] = clock.Now()
+		c.calls = append(c.calls, jsonToolCall{ID: ev.CallID, Name: ev.Tool})
+	case eventToolEnd:
+		for i := len(c.calls) - 1; i >= 0; i-- {
+			if c.calls[i].ID == ev.CallID {
+				c.calls[i].Failed = ev.Failed
+				if t, ok := c.started[ev.CallID]; ok {
+					c.calls[i].DurationMS = clock.Since(t).Milliseconds()
+				}
+				break
+			}
+		}
+	}
+}
+
+// runJSONOutput runs the agent with its human output captured and prints
+// one JSON object describing the run on stdout instead.
+func runJSONOutput(cfg cliConfig, stdout io.Writer) int {
+	collector := &jsonCollector{}
+	cfg.events = collector.handle
+	var usage runUsage
+	cfg.usageSink = &usage
+	var transcript []oai.Message
+	cfg.transcriptSink = &transcript
+
+	var out, errb bytes.Buffer
+	errLines := &errorLineWriter{w: &errb}
+	code := runAgent(cfg, &out, errLines)
+
+	collector.mu.Lock()
+	res := jsonResult{
+		Final:      strings.TrimSpace(out.String()),
+		Steps:      collector.steps,
+		Usage:      jsonUsage{usage.promptTokens, usage.completionTokens, usage.totalTokens, usage.cachedTokens},
+		ToolCalls:  append([]jsonToolCall{}, collector.calls...),
+		ExitReason: exitReasonCompleted,
+		ExitCode:   code,
+	}
+	collector.mu.Unlock()
+	// The transcript holds the answer even when -output-file took it
+	if n := len(transcript); n > 0 && transcript[n-1].Role == oai.RoleAssistant {
+		res.Final = strings.TrimSpace(transcript[n-1].Content)
+	}
+	if code != 0 {
+		res.ExitReason = nonEmptyOr(exitReasons[code], "error")
+		res.Error = redact.String(nonEmptyOr(errLines.last, nonEmptyOr(exitSummaries[code], lastLine(errb.String()))))
+	}
+	b, err := json.Marshal(res)
+	if err != nil {
+		return 1
+	}
+	safeFprintln(stdout, string(b))
+	return code
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/clock"
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+func TestCLI_OutputJSON(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)                         //nolint:errcheck
+		_ = json.NewEncoder(w).Encode(oai.ChatCompletionsResponse{ //nolint:errcheck
+			Choices: []oai.ChatCompletionsResponseChoice{{Message: oai.Message{Role: oai.RoleAssistant, Content: "the answer"}}},
+			Usage:   &oai.Usage{PromptTokens: 8, CompletionTokens: 2, TotalTokens: 10},
+		})
+	}))
+	defer srv.Close()
+	empty := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)                         //nolint:errcheck
+		_ = json.NewEncoder(w).Encode(oai.ChatCompletionsResponse{ //nolint:errcheck
+			Choices: []oai.ChatCompletionsResponseChoice{{Message: oai.Message{Role: oai.RoleAssistant}}},
+		})
+	}))
+	defer empty.Close()
+	base := []string{"-prompt", "p", "-model", "m", "-prep-enabled=false", "-output", "json", "-verbose"}
+
+	cases := []struct {
+		name   string
+		args   []string
+		code   int
+		final  string
+		steps  int
+		reason string
+	}{
+		{"completed", []string{"-base-url", srv.URL}, 0, "the answer", 1, exitReasonCompleted},
+		{"step cap", []string{"-base-url", empty.URL, "-max-steps", "2"}, exitStepCap, "", 2, "step_cap"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out, errb bytes.Buffer
+			code := cliMain(append(append([]string{}, base...), tc.args...), &out, &errb)
+			if code != tc.code {
+				t.Fatalf("exit=%d want %d stderr=%s", code, tc.code, errb.String())
+			}
+			if errb.Len() != 0 {
+				t.Fatalf("human text leaked to stderr: %q", errb.String())
+			}
+			if strings.Count(strings.TrimSpace(out.String()), "\n") != 0 {
+				t.Fatalf("want exactly one line on stdout, got %q", out.String())
+			}
+			var got jsonResult
+			if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+				t.Fatalf("stdout is not JSON: %v\n%s", err, out.String())
+			}
+			if got.Final != tc.final || got.Steps != tc.steps || got.ExitReason != tc.reason || got.ExitCode != tc.code {
+				t.Fatalf("result=%+v", got)
+			}
+			if tc.code == 0 && (got.Usage.TotalTokens != 10 || got.Error != "") {
+				t.Fatalf("result=%+v", got)
+			}
+			if tc.code != 0 && !strings.Contains(got.Error, "reached maximum steps") {
+				t.Fatalf("error=%q", got.Error)
+			}
+			if got.ToolCalls == nil {
+				t.Fatalf("tool_calls must be an array, got %s", out.String())
+			}
+		})
+	}
+}
+
+func TestCLI_OutputJSON_InvalidFormat(t *testing.T) {
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-prompt", "p", "-output", "yaml"}, &out, &errb)
+	if code != 2 || !strings.Contains(errb.String(), `invalid -output "yaml"`) {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	errb.Reset()
+	code = cliMain([]string{"-prompt", "p", "-output", "json", "-tui"}, &out, &errb)
+	if code != 2 || !strings.Contains(errb.String(), "-output json cannot be combined") {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	errb.Reset()
+	code = cliMain([]string{"-prompt", "p", "-output", "json", "-approve-tools", "all"}, &out, &errb)
+	if code != 2 || !strings.Contains(errb.String(), "-approve-tools requires -approve-file") {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+}
+
+func TestJSONCollector_RecordsToolCalls(t *testing.T) {
+	var c jsonCollector
+	c.handle(runEvent{Kind: eventStep, Step: 1})
+	c.handle(runEvent{Kind: eventToolStart, Tool: "a", CallID: "1"})
+	c.handle(runEvent{Kind: eventToolStart, Tool: "b", CallID: "2"})
+	c.handle(runEvent{Kind: eventToolEnd, Tool: "b", CallID: "2", Failed: true})
+	c.handle(runEvent{Kind: eventToolEnd, Tool: "a", CallID: "1"})
+	c.handle(runEvent{Kind: eventStep, Step: 2})
+	if c.steps != 2 || len(c.calls) != 2 {
+		t.Fatalf("steps=%d calls=%+v", c.steps, c.calls)
+	}
+	if c.calls[0].Name != "a" || c.calls[0].Failed || c.calls[1].Name != "b" || !c.calls[1].Failed {
+		t.Fatalf("calls=%+v", c.calls)
+	}
+}
+
+func TestJSONCollector_DurationFollowsClock(t *testing.T) {
+	clock.SetDeterministic(0)
+	t.Cleanup(clock.Reset)
+	var c jsonCollector
+	c.handle(runEvent{Kind: eventToolStart, Tool: "a", CallID: "1"})
+	time.Sleep(5 * time.Millisecond)
+	c.handle(runEvent{Kind: eventToolEnd, Tool: "a", CallID: "1"})
+	if c.calls[0].DurationMS != 0 {
+		t.Fatalf("duration_ms=%d under a fixed clock", c.calls[0].DurationMS)
+	}
+}
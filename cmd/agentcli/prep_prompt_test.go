@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// prepRecorder answers every request with a final "done" and records them;
+// the first request is the pre-stage.
+func prepRecorder(t *testing.T, seen *[]oai.ChatCompletionsRequest) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req oai.ChatCompletionsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		*seen = append(*seen, req)
+		_ = json.NewEncoder(w).Encode(oai.ChatCompletionsResponse{Choices: []oai.ChatCompletionsResponseChoice{{ //nolint:errcheck
+			Message: oai.Message{Role: oai.RoleAssistant, Channel: "final", Content: "done"},
+		}}})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// chdirTemp keeps the pre-stage cache out of the repository.
+func chdirTemp(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	old, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(old) }) //nolint:errcheck
+	return dir
+}
+
+func prestageMeta(t *testing.T, path string) (string, int) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var w struct {
+		Prestage struct {
+			Source string `json:"source"`
+			Bytes  int    `json:"bytes"`
+		} `json:"prestage"`
+	}
+	if err := json.Unmarshal(data, &w); err != nil {
+		t.Fatal(err)
+	}
+	return w.Prestage.Source, w.Prestage.Bytes
+}
+
+func TestCLIMain_PrepPromptOverride(t *testing.T) {
+	dir := chdirTemp(t)
+	var seen []oai.ChatCompletionsRequest
+	srv := prepRecorder(t, &seen)
+	saved := filepath.Join(dir, "msgs.json")
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-prompt", "q", "-base-url", srv.URL, "-model", "m", "-prep-system", "PS", "-prep-prompt", "first\n", "-prep-prompt", "second", "-prep-prompt-file", "ignored-missing", "-save-messages", saved}, &out, &errb)
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	prep := seen[0].Messages
+	if len(prep) < 2 || prep[0].Content != "PS" || prep[1].Role != oai.RoleDeveloper || prep[1].Content != "first\n\nsecond" {
+		t.Fatalf("pre-stage messages=%+v", prep)
+	}
+	if src, n := prestageMeta(t, saved); src != "override" || n != len("first\n\nsecond") {
+		t.Fatalf("prestage meta=%s/%d", src, n)
+	}
+}
+
+func TestCLIMain_PrepPromptFileAndDefault(t *testing.T) {
+	dir := chdirTemp(t)
+	var seen []oai.ChatCompletionsRequest
+	srv := prepRecorder(t, &seen)
+	file := filepath.Join(dir, "prep.md")
+	if err := os.WriteFile(file, []byte("from file\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	saved := filepath.Join(dir, "msgs.json")
+	var out, errb bytes.Buffer
+	if code := cliMain([]string{"-prompt", "q", "-base-url", srv.URL, "-model", "m", "-prep-prompt-file", file, "-save-messages", saved}, &out, &errb); code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if got := seen[0].Messages[0]; got.Role != oai.RoleDeveloper || got.Content != "from file" {
+		t.Fatalf("first pre-stage message=%+v", got)
+	}
+
+	seen = nil
+	if code := cliMain([]string{"-prompt", "q", "-base-url", srv.URL, "-model", "m", "-save-messages", saved}, &out, &errb); code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if got := seen[0].Messages[0].Content; got != strings.TrimSpace(oai.DefaultPrepPrompt()) {
+		t.Fatalf("default prompt not sent: %.60q", got)
+	}
+	if src, n := prestageMeta(t, saved); src != "default" || n != len(oai.DefaultPrepPrompt()) {
+		t.Fatalf("prestage meta=%s/%d", src, n)
+	}
+
+	if code := cliMain([]string{"-prompt", "q", "-base-url", srv.URL, "-model", "m", "-prep-prompt-file", filepath.Join(dir, "missing")}, &out, &errb); code != 2 || !strings.Contains(errb.String(), "missing") {
+		t.Fatalf("unreadable file: exit=%d stderr=%s", code, errb.String())
+	}
+}
+
+func TestPrintConfig_PrepPromptSource(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		env  string
+		want string
+	}{
+		{nil, "", "default"},
+		{nil, "from env", "override"},
+		{[]string{"-prep-prompt-file", "x.md"}, "", "override"},
+	} {
+		t.Setenv("OAI_PREP_PROMPT", tc.env)
+		var out, errb bytes.Buffer
+		if code := cliMain(append([]string{"-print-config"}, tc.args...), &out, &errb); code != 0 {
+			t.Fatalf("exit=%d stderr=%s", code, errb.String())
+		}
+		var cfg struct {
+			Prep struct {
+				PromptSource string `json:"promptSource"`
+			} `json:"prep"`
+		}
+		if err := json.Unmarshal(out.Bytes(), &cfg); err != nil {
+			t.Fatalf("decode: %v: %s", err, out.String())
+		}
+		if cfg.Prep.PromptSource != tc.want {
+			t.Fatalf("%v env=%q: promptSource=%q want %q", tc.args, tc.env, cfg.Prep.PromptSource, tc.want)
+		}
+	}
+}
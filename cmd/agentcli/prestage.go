@@ -8,14 +8,15 @@ import (
     "io"
     "io/fs"
     "os"
-    "os/exec"
     "path/filepath"
     "runtime"
     "sort"
     "strings"
+    "time"
 
     "github.com/hyperifyio/goagent/internal/oai"
     "github.com/hyperifyio/goagent/internal/oai/prestage"
+    "github.com/hyperifyio/goagent/internal/redact"
     "github.com/hyperifyio/goagent/internal/tools"
 )
 
@@ -28,12 +29,12 @@ func dumpJSONIfDebug(w io.Writer, label string, v any, debug bool) {
 	if err != nil {
 		return
 	}
-	safeFprintf(w, "\n--- %s ---\n%s\n", label, string(b))
+	safeFprintf(w, "\n--- %s ---\n%s\n", label, string(redact.Bytes(b)))
 }
 
 // runPreStage performs the preparatory chat call and optional tool execution.
 // nolint:gocyclo // The flow covers caching, validation, tool policy, and is thoroughly unit/integration tested.
-func runPreStage(cfg cliConfig, messages []oai.Message, stderr io.Writer) ([]oai.Message, error) {
+func runPreStage(parent context.Context, cfg cliConfig, messages []oai.Message, stderr io.Writer) ([]oai.Message, error) {
 	// Resolve pre-stage overrides with robust fallbacks so tests that construct cfg directly still work
 	prepModel := func() string {
 		if v := strings.TrimSpace(cfg.prepModel); v != "" {
@@ -44,38 +45,7 @@ func runPreStage(cfg cliConfig, messages []oai.Message, stderr io.Writer) ([]oai
 		}
 		return cfg.model
 	}()
-	prepBaseURL := func() string {
-		if v := strings.TrimSpace(cfg.prepBaseURL); v != "" {
-			return v
-		}
-		if v := strings.TrimSpace(os.Getenv("OAI_PREP_BASE_URL")); v != "" {
-			return v
-		}
-		return cfg.baseURL
-	}()
-	prepAPIKey := func() string {
-		if v := strings.TrimSpace(cfg.prepAPIKey); v != "" {
-			return v
-		}
-		if v := strings.TrimSpace(os.Getenv("OAI_PREP_API_KEY")); v != "" {
-			return v
-		}
-		if v := strings.TrimSpace(os.Getenv("OAI_API_KEY")); v != "" {
-			return v
-		}
-		if v := strings.TrimSpace(os.Getenv("OPENAI_API_KEY")); v != "" {
-			return v
-		}
-		return cfg.apiKey
-	}()
-	retries := cfg.prepHTTPRetries
-	if retries <= 0 {
-		retries = cfg.httpRetries
-	}
-	backoff := cfg.prepHTTPBackoff
-	if backoff == 0 {
-		backoff = cfg.httpBackoff
-	}
+	prepBaseURL, _, _, _ := prepConnection(cfg)
 
 	// Compute pre-stage sampling effective knobs for cache key
 	var (
@@ -126,9 +96,21 @@ func runPreStage(cfg cliConfig, messages []oai.Message, stderr io.Writer) ([]oai
 		return "manifest:" + sum
 	}()
 
+	// The pre-stage prompt is normally resolved by runAgent; tests that build
+	// cfg directly get it resolved here
+	prepPrompt := cfg.prepPromptText
+	if cfg.prepPromptSource == "" {
+		_, text, err := resolvePrepPrompt(cfg)
+		if err != nil {
+			safeFprintf(stderr, "error: prep prompt read failed: %v\n", err)
+			return nil, err
+		}
+		prepPrompt = text
+	}
+
 	// Attempt cache read unless bust requested
 	if !cfg.prepCacheBust {
-		if out, ok := tryReadPrepCache(prepModel, prepBaseURL, effectiveTemp, effectiveTopP, cfg.httpRetries, cfg.httpBackoff, toolSpec, messages); ok {
+		if out, ok := tryReadPrepCache(prepModel, prepBaseURL, effectiveTemp, effectiveTopP, cfg.httpRetries, cfg.httpBackoff, toolSpec, prepPrompt, messages); ok {
 			return out, nil
 		}
 	}
@@ -153,6 +135,10 @@ func runPreStage(cfg cliConfig, messages []oai.Message, stderr io.Writer) ([]oai
 			prepMessages = append(prepMessages, oai.Message{Role: oai.RoleSystem, Content: s})
 		}
 	}
+	// The pre-stage prompt follows as a developer message
+	if s := strings.TrimSpace(prepPrompt); s != "" {
+		prepMessages = append(prepMessages, oai.Message{Role: oai.RoleDeveloper, Content: s})
+	}
 	prepMessages = append(prepMessages, applyTranscriptHygiene(normalizedIn, cfg.debug)...)
 	req := oai.ChatCompletionsRequest{
 		Model:    prepModel,
@@ -168,11 +154,15 @@ func runPreStage(cfg cliConfig, messages []oai.Message, stderr io.Writer) ([]oai
 	} else if effectiveTemp != nil {
 		req.Temperature = effectiveTemp
 	}
-	// Create a dedicated client honoring pre-stage timeout and normal retry policy
-	httpClient := oai.NewClientWithRetry(prepBaseURL, prepAPIKey, cfg.prepHTTPTimeout, oai.RetryPolicy{MaxRetries: retries, Backoff: backoff})
+	req.Seed = requestSeed(cfg)
+	// Use a dedicated client honoring pre-stage timeout and normal retry policy
+	httpClient := cfg.prepClient
+	if httpClient == nil {
+		httpClient = newPrepClient(cfg)
+	}
 	dumpJSONIfDebug(stderr, "prep.request", req, cfg.debug)
 	// Tag context with audit stage so HTTP audit lines include stage: "prep"
-	ctx, cancel := context.WithTimeout(oai.WithAuditStage(context.Background(), "prep"), cfg.prepHTTPTimeout)
+	ctx, cancel := context.WithTimeout(oai.WithAuditStage(parent, "prep"), cfg.prepHTTPTimeout)
 	defer cancel()
 	resp, err := httpClient.CreateChatCompletion(ctx, req)
 	if err != nil {
@@ -209,7 +199,7 @@ func runPreStage(cfg cliConfig, messages []oai.Message, stderr io.Writer) ([]oai
 	// If there are no tool calls, return merged messages
 	if len(resp.Choices) == 0 || len(resp.Choices[0].Message.ToolCalls) == 0 {
 		// Cache the merged transcript for consistency
-		if err := writePrepCache(prepModel, prepBaseURL, effectiveTemp, effectiveTopP, cfg.httpRetries, cfg.httpBackoff, toolSpec, normalizedIn, merged); err != nil {
+		if err := writePrepCache(prepModel, prepBaseURL, effectiveTemp, effectiveTopP, cfg.httpRetries, cfg.httpBackoff, toolSpec, prepPrompt, normalizedIn, merged); err != nil {
 			_ = err // best-effort cache write; ignore error
 		}
 		return merged, nil
@@ -228,7 +218,7 @@ func runPreStage(cfg cliConfig, messages []oai.Message, stderr io.Writer) ([]oai
 		// Ignore -tools and execute only built-in read-only adapters
 		out = appendPreStageBuiltinToolOutputs(out, assistantMsg, cfg)
 		// Write cache
-		if err := writePrepCache(prepModel, prepBaseURL, effectiveTemp, effectiveTopP, cfg.httpRetries, cfg.httpBackoff, toolSpec, normalizedIn, out); err != nil {
+		if err := writePrepCache(prepModel, prepBaseURL, effectiveTemp, effectiveTopP, cfg.httpRetries, cfg.httpBackoff, toolSpec, prepPrompt, normalizedIn, out); err != nil {
 			_ = err // best-effort cache write; ignore error
 		}
 		return out, nil
@@ -250,22 +240,73 @@ func runPreStage(cfg cliConfig, messages []oai.Message, stderr io.Writer) ([]oai
 		return nil, lerr
 	}
 	for name, spec := range registry {
-		if len(spec.Command) == 0 {
+		if tools.ProgramPath(spec) == "" {
 			safeFprintf(stderr, "error: configured tool %q has no command\n", name)
 			return nil, fmt.Errorf("tool %s has no command", name)
 		}
-		if _, lookErr := exec.LookPath(spec.Command[0]); lookErr != nil {
-			safeFprintf(stderr, "error: configured tool %q is unavailable: %v (program %q)\n", name, lookErr, spec.Command[0])
+		if lookErr := tools.CheckProgram(spec); lookErr != nil {
+			safeFprintf(stderr, "error: configured tool %q is unavailable: %v (program %q)\n", name, lookErr, tools.ProgramPath(spec))
 			return nil, lookErr
 		}
 	}
-	out = appendToolCallOutputs(out, assistantMsg, registry, cfg)
-	if err := writePrepCache(prepModel, prepBaseURL, effectiveTemp, effectiveTopP, cfg.httpRetries, cfg.httpBackoff, toolSpec, normalizedIn, out); err != nil {
+	out = appendToolCallOutputs(parent, out, assistantMsg, registry, cfg)
+	if err := writePrepCache(prepModel, prepBaseURL, effectiveTemp, effectiveTopP, cfg.httpRetries, cfg.httpBackoff, toolSpec, prepPrompt, normalizedIn, out); err != nil {
 		_ = err // best-effort cache write; ignore error
 	}
 	return out, nil
 }
 
+// prepConnection resolves the pre-stage endpoint, key, and retry policy,
+// falling back to the main settings so tests that construct cfg directly
+// still work.
+func prepConnection(cfg cliConfig) (baseURL, apiKey string, retries int, backoff time.Duration) {
+	baseURL = func() string {
+		if v := strings.TrimSpace(cfg.prepBaseURL); v != "" {
+			return v
+		}
+		if v := strings.TrimSpace(os.Getenv("OAI_PREP_BASE_URL")); v != "" {
+			return v
+		}
+		return cfg.baseURL
+	}()
+	apiKey = func() string {
+		if v := strings.TrimSpace(cfg.prepAPIKey); v != "" {
+			return v
+		}
+		if v := strings.TrimSpace(os.Getenv("OAI_PREP_API_KEY")); v != "" {
+			return v
+		}
+		if v := strings.TrimSpace(os.Getenv("OAI_API_KEY")); v != "" {
+			return v
+		}
+		if v := strings.TrimSpace(os.Getenv("OPENAI_API_KEY")); v != "" {
+			return v
+		}
+		return cfg.apiKey
+	}()
+	retries = cfg.prepHTTPRetries
+	if retries <= 0 {
+		retries = cfg.httpRetries
+	}
+	backoff = cfg.prepHTTPBackoff
+	if backoff == 0 {
+		backoff = cfg.httpBackoff
+	}
+	return baseURL, apiKey, retries, backoff
+}
+
+// newPrepClient builds the pre-stage HTTP client.
+func newPrepClient(cfg cliConfig) *oai.Client {
+	baseURL, apiKey, retries, backoff := prepConnection(cfg)
+	client := oai.NewClientWithRetry(baseURL, apiKey, cfg.prepHTTPTimeout, retryPolicyFor(cfg, retries, backoff)).WithLimits(limitsFor(cfg))
+	// A -prep-base-url of its own is an OpenAI-compatible server
+	if baseURL == cfg.baseURL {
+		installProvider(cfg, client)
+	}
+	cfg.injector.install(client)
+	return client
+}
+
 // appendPreStageBuiltinToolOutputs executes built-in read-only pre-stage tools.
 // For now this is a no-op placeholder to keep behavior deterministic without external tools.
 func appendPreStageBuiltinToolOutputs(messages []oai.Message, assistantMsg oai.Message, _ cliConfig) []oai.Message {
@@ -440,14 +481,35 @@ func sanitizeToolContent(stdout []byte, runErr error) string {
 		if trimmed == "" {
 			return "{}"
 		}
-		// Ensure it is one line to keep prompts compact
-		return oneLine(trimmed)
+		// Ensure it is one line to keep prompts compact; mask secrets the tool echoed
+		return redact.String(oneLine(trimmed))
 	}
-	// On error, return {"error":"..."}
+	// On error, return {"error":"..."}; a timeout also names its limit and a
+	// rate-limited call says when to retry
 	msg := runErr.Error()
 	if errors.Is(runErr, context.DeadlineExceeded) {
 		msg = "tool timed out"
 	}
+	var limited *tools.RateLimitError
+	if errors.As(runErr, &limited) {
+		b, mErr := json.Marshal(struct {
+			Error        string `json:"error"`
+			RetryAfterMS int64  `json:"retry_after_ms"`
+		}{msg, limited.RetryAfter.Milliseconds()})
+		if mErr == nil {
+			return string(b)
+		}
+	}
+	var timeout *tools.TimeoutError
+	if errors.As(runErr, &timeout) {
+		b, mErr := json.Marshal(struct {
+			Error     string `json:"error"`
+			TimeoutMS int64  `json:"timeout_ms"`
+		}{msg, timeout.Timeout.Milliseconds()})
+		if mErr == nil {
+			return string(b)
+		}
+	}
 	// Truncate to avoid bloat
 	const maxLen = 1000
 	if len(msg) > maxLen {
@@ -459,5 +521,5 @@ func sanitizeToolContent(stdout []byte, runErr error) string {
 		// Fallback to a minimal JSON on marshal error
 		return "{\"error\":\"internal error\"}"
 	}
-	return oneLine(string(b))
+	return redact.String(oneLine(string(b)))
 }
@@ -2,64 +2,289 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/hyperifyio/goagent/internal/clock"
 	"github.com/hyperifyio/goagent/internal/oai"
 )
 
-// tryReadPrepCache attempts to load cached pre-stage output messages.
-func tryReadPrepCache(model, base string, temp *float64, topP *float64, retries int, backoff time.Duration, toolSpec string, inMessages []oai.Message) ([]oai.Message, bool) {
-	key := computePrepCacheKey(model, base, temp, topP, retries, backoff, toolSpec, inMessages)
-	dir := filepath.Join(findRepoRoot(), ".goagent", "cache", "prep")
-	path := filepath.Join(dir, key+".json")
-	// TTL check based on file mtime
+// tryReadPrepCache attempts to load cached pre-stage output messages and
+// records the hit or miss in the cache stats.
+func tryReadPrepCache(model, base string, temp *float64, topP *float64, retries int, backoff time.Duration, toolSpec, prompt string, inMessages []oai.Message) ([]oai.Message, bool) {
+	key := computePrepCacheKey(model, base, temp, topP, retries, backoff, toolSpec, prompt, inMessages)
+	c := openPrepCache()
+	messages, ok := c.get(key)
+	_ = c.withLock(func() error { return c.count(ok) }) //nolint:errcheck // stats are best-effort
+	return messages, ok
+}
+
+// writePrepCache writes outMessages as JSON under the computed cache key.
+func writePrepCache(model, base string, temp *float64, topP *float64, retries int, backoff time.Duration, toolSpec, prompt string, inMessages, outMessages []oai.Message) error {
+	key := computePrepCacheKey(model, base, temp, topP, retries, backoff, toolSpec, prompt, inMessages)
+	return openPrepCache().put(key, outMessages)
+}
+
+const (
+	// defaultPrepCacheMaxBytes bounds the cache when GOAGENT_PREP_CACHE_MAX_BYTES is unset
+	defaultPrepCacheMaxBytes = 64 << 20
+	prepCacheLockName        = ".lock"
+	prepCacheStatsName       = ".stats.json"
+	// A lock older than this is left over from a crashed run and is taken over
+	prepCacheLockStale = 10 * time.Second
+	prepCacheLockWait  = 2 * time.Second
+)
+
+// prepCache is the on-disk pre-stage cache: one JSON entry per key, evicted
+// least recently used first once the directory exceeds maxBytes. Entries are
+// written atomically; writes, eviction, and stats updates are serialized
+// across processes by a lock file, so concurrent runs can share a directory.
+// -chat-cache keeps its replies in the same form under chat/.
+type prepCache struct {
+	dir      string
+	ttl      time.Duration
+	maxBytes int64 // <= 0 is unbounded
+}
+
+// prepCacheEntry is the stored form. Created drives the TTL; the file mtime
+// is refreshed on every hit and drives LRU eviction.
+type prepCacheEntry struct {
+	Created  time.Time     `json:"created"`
+	Messages []oai.Message `json:"messages"`
+}
+
+// prepCacheStats is printed by -prep-cache-stats. Hits and misses persist in
+// the cache directory across runs.
+type prepCacheStats struct {
+	Dir      string `json:"dir"`
+	Entries  int    `json:"entries"`
+	Bytes    int64  `json:"bytes"`
+	MaxBytes int64  `json:"maxBytes"`
+	Hits     int64  `json:"hits"`
+	Misses   int64  `json:"misses"`
+}
+
+// openPrepCache resolves the cache directory and limits from the environment:
+// GOAGENT_CACHE_DIR (shared root; entries go under prep/, default
+// <repo>/.goagent/cache), GOAGENT_PREP_CACHE_TTL, and
+// GOAGENT_PREP_CACHE_MAX_BYTES.
+func openPrepCache() prepCache {
+	return prepCache{dir: filepath.Join(cacheRoot(), "prep"), ttl: prepCacheTTL(), maxBytes: cacheMaxBytes("GOAGENT_PREP_CACHE_MAX_BYTES")}
+}
+
+// cacheRoot is GOAGENT_CACHE_DIR, or <repo>/.goagent/cache when unset.
+func cacheRoot() string {
+	if v := strings.TrimSpace(os.Getenv("GOAGENT_CACHE_DIR")); v != "" {
+		return v
+	}
+	return filepath.Join(findRepoRoot(), ".goagent", "cache")
+}
+
+func (c prepCache) path(key string) string { return filepath.Join(c.dir, key+".json") }
+
+// get returns a live entry and marks it recently used. Entries written as a
+// bare message array by older versions are read with their mtime as the
+// creation time.
+func (c prepCache) get(key string) ([]oai.Message, bool) {
+	path := c.path(key)
 	fi, err := os.Stat(path)
 	if err != nil {
 		return nil, false
 	}
-	ttl := prepCacheTTL()
-	if ttl > 0 {
-		if fi.ModTime().Add(ttl).Before(time.Now()) {
-			return nil, false
-		}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var entry prepCacheEntry
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "[") {
+		entry.Created = fi.ModTime()
+		err = json.Unmarshal(data, &entry.Messages)
+	} else {
+		err = json.Unmarshal(data, &entry)
 	}
-	data, rerr := os.ReadFile(path)
-	if rerr != nil {
+	if err != nil {
 		return nil, false
 	}
-	var messages []oai.Message
-	if jerr := json.Unmarshal(data, &messages); jerr != nil {
+	if c.ttl > 0 && entry.Created.Add(c.ttl).Before(clock.Now()) {
 		return nil, false
 	}
-	return messages, true
+	now := time.Now()
+	_ = os.Chtimes(path, now, now) //nolint:errcheck // recency only affects eviction order
+	return entry.Messages, true
 }
 
-// writePrepCache writes outMessages as JSON under the computed cache key.
-func writePrepCache(model, base string, temp *float64, topP *float64, retries int, backoff time.Duration, toolSpec string, inMessages, outMessages []oai.Message) error {
-	key := computePrepCacheKey(model, base, temp, topP, retries, backoff, toolSpec, inMessages)
-	dir := filepath.Join(findRepoRoot(), ".goagent", "cache", "prep")
-	if err := os.MkdirAll(dir, 0o755); err != nil {
+// put stores messages under key and evicts old entries to fit maxBytes.
+func (c prepCache) put(key string, messages []oai.Message) error {
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(prepCacheEntry{Created: clock.Now(), Messages: messages})
+	if err != nil {
+		return err
+	}
+	return c.withLock(func() error {
+		if err := writeFileAtomic(c.path(key), data, 0o644); err != nil {
+			return err
+		}
+		return c.evict(key + ".json")
+	})
+}
+
+type prepCacheFile struct {
+	name string
+	size int64
+	used time.Time
+}
+
+// entries lists cache entries, oldest use first.
+func (c prepCache) entries() ([]prepCacheFile, error) {
+	des, err := os.ReadDir(c.dir)
+	if err != nil {
+		return nil, err
+	}
+	var out []prepCacheFile
+	for _, de := range des {
+		name := de.Name()
+		if de.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != ".json" {
+			continue
+		}
+		fi, err := de.Info()
+		if err != nil {
+			continue
+		}
+		out = append(out, prepCacheFile{name: name, size: fi.Size(), used: fi.ModTime()})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].used.Before(out[j].used) })
+	return out, nil
+}
+
+// evict removes least recently used entries until the total fits maxBytes.
+// keep, the entry just written, is never removed. Callers hold the lock.
+func (c prepCache) evict(keep string) error {
+	if c.maxBytes <= 0 {
+		return nil
+	}
+	files, err := c.entries()
+	if err != nil {
 		return err
 	}
-	path := filepath.Join(dir, key+".json")
-	data, err := json.Marshal(outMessages)
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+	for _, f := range files {
+		if total <= c.maxBytes {
+			break
+		}
+		if f.name == keep {
+			continue
+		}
+		if err := os.Remove(filepath.Join(c.dir, f.name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		total -= f.size
+	}
+	return nil
+}
+
+// count adds one hit or miss to the persisted stats. Callers hold the lock.
+func (c prepCache) count(hit bool) error {
+	var st prepCacheStats
+	path := filepath.Join(c.dir, prepCacheStatsName)
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &st) //nolint:errcheck // a corrupt stats file restarts from zero
+	}
+	if hit {
+		st.Hits++
+	} else {
+		st.Misses++
+	}
+	data, err := json.Marshal(struct {
+		Hits   int64 `json:"hits"`
+		Misses int64 `json:"misses"`
+	}{st.Hits, st.Misses})
 	if err != nil {
 		return err
 	}
-	// Atomic write: write to temp then rename
-	tmp := path + ".tmp"
-	if werr := os.WriteFile(tmp, data, 0o644); werr != nil {
-		return werr
+	return writeFileAtomic(path, data, 0o644)
+}
+
+// stats reports the entry count and size on disk with the persisted counters.
+func (c prepCache) stats() (prepCacheStats, error) {
+	st := prepCacheStats{Dir: c.dir, MaxBytes: c.maxBytes}
+	if data, err := os.ReadFile(filepath.Join(c.dir, prepCacheStatsName)); err == nil {
+		if err := json.Unmarshal(data, &st); err != nil {
+			return st, fmt.Errorf("read cache stats: %w", err)
+		}
+		st.Dir, st.MaxBytes = c.dir, c.maxBytes
+	}
+	files, err := c.entries()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return st, err
 	}
-	return os.Rename(tmp, path)
+	for _, f := range files {
+		st.Entries++
+		st.Bytes += f.size
+	}
+	return st, nil
+}
+
+// withLock runs fn while holding the cache directory's lock file. The lock
+// is a file created exclusively, like the workspace lock; one older than
+// prepCacheLockStale is taken over. Wall-clock time is used so a frozen
+// -deterministic clock cannot stall or break the wait.
+func (c prepCache) withLock(fn func() error) error {
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return err
+	}
+	path := filepath.Join(c.dir, prepCacheLockName)
+	deadline := time.Now().Add(prepCacheLockWait)
+	for {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err == nil {
+			_ = f.Close()                          //nolint:errcheck // the file's existence is the lock
+			defer func() { _ = os.Remove(path) }() //nolint:errcheck // a leftover lock goes stale
+			return fn()
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("lock cache: %w", err)
+		}
+		if fi, serr := os.Stat(path); serr == nil && time.Since(fi.ModTime()) > prepCacheLockStale {
+			_ = os.Remove(path) //nolint:errcheck // racing removers are harmless
+			continue
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("cache %s is locked by another run", c.dir)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// printPrepCacheStats implements -prep-cache-stats.
+func printPrepCacheStats(stdout, stderr io.Writer) int {
+	st, err := openPrepCache().stats()
+	if err != nil {
+		safeFprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	b, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		safeFprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	safeFprintln(stdout, string(b))
+	return 0
 }
 
 // computePrepCacheKey builds a deterministic key covering inputs and config.
-func computePrepCacheKey(model, base string, temp *float64, topP *float64, retries int, backoff time.Duration, toolSpec string, inMessages []oai.Message) string {
+func computePrepCacheKey(model, base string, temp *float64, topP *float64, retries int, backoff time.Duration, toolSpec, prompt string, inMessages []oai.Message) string {
 	// Build a stable map for hashing
 	type hashPayload struct {
 		Model    string        `json:"model"`
@@ -69,6 +294,7 @@ func computePrepCacheKey(model, base string, temp *float64, topP *float64, retri
 		Retries  int           `json:"retries"`
 		Backoff  string        `json:"backoff"`
 		ToolSpec string        `json:"tool_spec"`
+		Prompt   string        `json:"prep_prompt"`
 		Messages []oai.Message `json:"messages"`
 	}
 	payload := hashPayload{
@@ -79,6 +305,7 @@ func computePrepCacheKey(model, base string, temp *float64, topP *float64, retri
 		Retries:  retries,
 		Backoff:  backoff.String(),
 		ToolSpec: toolSpec,
+		Prompt:   prompt,
 		Messages: normalizeMessagesForHash(inMessages),
 	}
 	b, err := json.Marshal(payload)
@@ -100,6 +327,17 @@ func normalizeMessagesForHash(in []oai.Message) []oai.Message {
 	return out
 }
 
+// cacheMaxBytes returns a cache size cap; default 64 MiB, override via the
+// named environment variable (0 disables the cap).
+func cacheMaxBytes(env string) int64 {
+	if v := strings.TrimSpace(os.Getenv(env)); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return defaultPrepCacheMaxBytes
+}
+
 // prepCacheTTL returns the TTL for prep cache; default 10 minutes, override via GOAGENT_PREP_CACHE_TTL.
 func prepCacheTTL() time.Duration {
 	if v := strings.TrimSpace(os.Getenv("GOAGENT_PREP_CACHE_TTL")); v != "" {
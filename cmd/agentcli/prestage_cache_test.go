@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+func testPrepCache(t *testing.T, maxBytes string) prepCache {
+	t.Helper()
+	root := t.TempDir()
+	t.Setenv("GOAGENT_CACHE_DIR", root)
+	t.Setenv("GOAGENT_PREP_CACHE_MAX_BYTES", maxBytes)
+	t.Setenv("GOAGENT_PREP_CACHE_TTL", "")
+	c := openPrepCache()
+	if c.dir != filepath.Join(root, "prep") {
+		t.Fatalf("dir=%s", c.dir)
+	}
+	return c
+}
+
+func cacheMsgs(s string) []oai.Message {
+	return []oai.Message{{Role: oai.RoleSystem, Content: s}}
+}
+
+// setUsed backdates an entry's last use.
+func setUsed(t *testing.T, c prepCache, key string, ago time.Duration) {
+	t.Helper()
+	at := time.Now().Add(-ago)
+	if err := os.Chtimes(c.path(key), at, at); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPrepCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := testPrepCache(t, "0")
+	for _, k := range []string{"a", "b", "c"} {
+		if err := c.put(k, cacheMsgs(k)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fi, err := os.Stat(c.path("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	setUsed(t, c, "a", 3*time.Hour)
+	setUsed(t, c, "b", 2*time.Hour)
+	setUsed(t, c, "c", time.Hour)
+	// Reading a makes b the least recently used
+	if got, ok := c.get("a"); !ok || got[0].Content != "a" {
+		t.Fatalf("get a=%v %v", got, ok)
+	}
+
+	c.maxBytes = 3 * fi.Size()
+	if err := c.put("d", cacheMsgs("d")); err != nil {
+		t.Fatal(err)
+	}
+	for k, want := range map[string]bool{"a": true, "b": false, "c": true, "d": true} {
+		if _, err := os.Stat(c.path(k)); (err == nil) != want {
+			t.Fatalf("entry %s present=%v want %v", k, err == nil, want)
+		}
+	}
+	c.maxBytes = 1
+	if err := c.put("e", cacheMsgs("e")); err != nil {
+		t.Fatal(err)
+	}
+	if st, err := c.stats(); err != nil || st.Entries != 1 {
+		t.Fatalf("the new entry must survive alone: %+v %v", st, err)
+	}
+}
+
+func TestPrepCache_TTLAndLegacyEntries(t *testing.T) {
+	c := testPrepCache(t, "")
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(c.path("legacy"), []byte(`[{"role":"system","content":"old"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := c.get("legacy"); !ok || got[0].Content != "old" {
+		t.Fatalf("legacy entry: %v %v", got, ok)
+	}
+	stale, err := json.Marshal(prepCacheEntry{Created: time.Now().Add(-time.Hour), Messages: cacheMsgs("x")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(c.path("stale"), stale, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.get("stale"); ok {
+		t.Fatalf("entry older than the TTL must miss")
+	}
+	// Recent use does not extend the TTL
+	setUsed(t, c, "stale", 0)
+	if _, ok := c.get("stale"); ok {
+		t.Fatalf("TTL must follow creation, not use")
+	}
+}
+
+func TestPrepCache_ConcurrentWritersAndStats(t *testing.T) {
+	c := testPrepCache(t, "")
+	// A lock left by a crashed run is taken over
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	lock := filepath.Join(c.dir, prepCacheLockName)
+	if err := os.WriteFile(lock, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Minute)
+	if err := os.Chtimes(lock, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := "k" + strconv.Itoa(i%4)
+			if err := c.put(key, cacheMsgs(key)); err != nil {
+				t.Error(err)
+			}
+			_, ok := c.get(key)
+			if err := c.withLock(func() error { return c.count(ok) }); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	var out, errb bytes.Buffer
+	if code := cliMain([]string{"-prep-cache-stats"}, &out, &errb); code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	var st prepCacheStats
+	if err := json.Unmarshal(out.Bytes(), &st); err != nil {
+		t.Fatalf("decode %q: %v", out.String(), err)
+	}
+	if st.Dir != c.dir || st.Entries != 4 || st.Hits != 8 || st.Misses != 0 || st.Bytes == 0 || st.MaxBytes != defaultPrepCacheMaxBytes {
+		t.Fatalf("stats=%+v", st)
+	}
+	for i := 0; i < 4; i++ {
+		if got, ok := c.get("k" + strconv.Itoa(i)); !ok || len(got) != 1 {
+			t.Fatalf("entry k%d corrupt: %v", i, got)
+		}
+	}
+	if _, err := os.Stat(lock); !os.IsNotExist(err) {
+		t.Fatalf("lock not released: %v", err)
+	}
+}
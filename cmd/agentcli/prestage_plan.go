@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+	"github.com/hyperifyio/goagent/internal/oai/prestage"
+	"github.com/hyperifyio/goagent/internal/state"
+	"github.com/hyperifyio/goagent/internal/tools"
+)
+
+// planUpdateTool is the built-in tool the main loop uses to report progress
+// on a plan from the pre-stage.
+const planUpdateTool = "plan.update"
+
+const planUpdateSchema = `{"type":"object","properties":{` +
+	`"step":{"type":"integer","minimum":1,"description":"Step number from the plan"},` +
+	`"status":{"type":"string","enum":["pending","in_progress","done","failed"]},` +
+	`"note":{"type":"string","description":"Optional short result or reason"}},` +
+	`"required":["step","status"],"additionalProperties":false}`
+
+// planUpdateArgs is the plan.update argument object.
+type planUpdateArgs struct {
+	Step   int              `json:"step"`
+	Status state.TaskStatus `json:"status"`
+	Note   string           `json:"note"`
+}
+
+// runPlan holds the pre-stage plan and each step's progress. plan.update
+// calls from one turn run concurrently, so access is locked.
+type runPlan struct {
+	mu     sync.Mutex
+	tasks  []state.Task
+	print  bool
+	stderr io.Writer
+	// saved is set once an interrupted run stored the plan with its transcript
+	saved bool
+}
+
+// newRunPlan returns the plan carried by messages, or nil when there is none.
+func newRunPlan(cfg cliConfig, messages []oai.Message, stderr io.Writer) *runPlan {
+	steps := prestage.PlanFromMessages(messages)
+	if len(steps) == 0 {
+		return nil
+	}
+	p := &runPlan{print: cfg.printPlan, stderr: stderr}
+	for i, s := range steps {
+		p.tasks = append(p.tasks, state.Task{ID: i + 1, Title: s, Status: state.TaskPending})
+	}
+	return p
+}
+
+// addTool registers plan.update; a -tools entry with the same name conflicts.
+func (p *runPlan) addTool(registry map[string]tools.ToolSpec, oaiTools []oai.Tool) (map[string]tools.ToolSpec, []oai.Tool, error) {
+	if _, exists := registry[planUpdateTool]; exists {
+		return nil, nil, fmt.Errorf("tool %q in -tools conflicts with the built-in plan tool", planUpdateTool)
+	}
+	if registry == nil {
+		registry = make(map[string]tools.ToolSpec)
+	}
+	spec := tools.ToolSpec{
+		Name:        planUpdateTool,
+		Description: "Mark a step of the plan as in_progress, done, failed, or pending, with an optional note. Returns the updated plan.",
+		Schema:      json.RawMessage(planUpdateSchema),
+	}
+	registry[planUpdateTool] = spec
+	oaiTools = append(oaiTools, oai.Tool{Type: "function", Function: oai.ToolFunction{Name: spec.Name, Description: spec.Description, Parameters: spec.Schema}})
+	return registry, oaiTools, nil
+}
+
+// update applies a plan.update call and returns the plan as the tool result.
+func (p *runPlan) update(argsJSON []byte) ([]byte, error) {
+	var args planUpdateArgs
+	if err := json.Unmarshal(argsJSON, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %v", err)
+	}
+	switch args.Status {
+	case state.TaskPending, state.TaskInProgress, state.TaskDone, state.TaskFailed:
+	default:
+		return nil, fmt.Errorf("status must be one of pending, in_progress, done, failed")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if args.Step < 1 || args.Step > len(p.tasks) {
+		return nil, fmt.Errorf("step must be between 1 and %d", len(p.tasks))
+	}
+	t := &p.tasks[args.Step-1]
+	t.Status = args.Status
+	t.Result = strings.Join(strings.Fields(args.Note), " ")
+	if p.print {
+		safeFprintln(p.stderr, p.renderLocked())
+	}
+	remaining := 0
+	for _, t := range p.tasks {
+		if t.Status != state.TaskDone && t.Status != state.TaskFailed {
+			remaining++
+		}
+	}
+	return json.Marshal(map[string]any{"plan": p.tasks, "remaining": remaining})
+}
+
+// report prints the plan under -print-plan.
+func (p *runPlan) report() {
+	if p == nil || !p.print {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	safeFprintln(p.stderr, p.renderLocked())
+}
+
+func (p *runPlan) renderLocked() string {
+	return "plan:\n" + (&state.TaskBoard{Tasks: p.tasks}).Render()
+}
+
+// snapshot returns a copy of the steps; nil receivers return nil.
+func (p *runPlan) snapshot() []state.Task {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]state.Task(nil), p.tasks...)
+}
+
+// persist saves the plan with the transcript as a state bundle under
+// -state-dir when the run ends. An interrupted run already saved it.
+func (p *runPlan) persist(cfg cliConfig, messages []oai.Message, stderr io.Writer) {
+	if p == nil || p.saved || strings.TrimSpace(cfg.stateDir) == "" {
+		return
+	}
+	if err := state.SaveStateBundle(cfg.stateDir, runBundle(cfg, messages, map[string]any{})); err != nil {
+		safeFprintf(stderr, "WARN: failed to save plan: %v\n", err)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+	"github.com/hyperifyio/goagent/internal/state"
+)
+
+func TestCLIMain_PrestagePlan_UpdatedAndSaved(t *testing.T) {
+	t.Setenv("GOAGENT_CACHE_DIR", t.TempDir())
+	var mainReqs []oai.ChatCompletionsRequest
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req oai.ChatCompletionsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		calls++
+		var msg oai.Message
+		switch last := req.Messages[len(req.Messages)-1]; {
+		case calls == 1:
+			// Pre-stage
+			msg = oai.Message{Role: oai.RoleAssistant, Content: `[{"plan":["Read the file",{"title":"Summarize it"}]}]`}
+		case last.Role == oai.RoleTool:
+			mainReqs = append(mainReqs, req)
+			msg = oai.Message{Role: oai.RoleAssistant, Channel: "final", Content: last.Content}
+		default:
+			mainReqs = append(mainReqs, req)
+			msg = oai.Message{Role: oai.RoleAssistant, ToolCalls: []oai.ToolCall{{ID: "c1", Type: "function", Function: oai.ToolCallFunction{Name: planUpdateTool, Arguments: `{"step":1,"status":"done","note":"read 3 lines"}`}}}}
+		}
+		_ = json.NewEncoder(w).Encode(oai.ChatCompletionsResponse{Choices: []oai.ChatCompletionsResponseChoice{{Message: msg}}}) //nolint:errcheck
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-prompt", "p", "-base-url", srv.URL, "-model", "m", "-print-plan", "-state-dir", dir}, &out, &errb)
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if len(mainReqs) == 0 || len(mainReqs[0].Tools) != 1 || mainReqs[0].Tools[0].Function.Name != planUpdateTool {
+		t.Fatalf("main loop must be offered plan.update: %+v", mainReqs)
+	}
+	if !strings.Contains(errb.String(), "[ ] 1. Read the file\n[ ] 2. Summarize it") ||
+		!strings.Contains(errb.String(), "[x] 1. Read the file — read 3 lines\n[ ] 2. Summarize it") {
+		t.Fatalf("stderr should show the plan before and after the update:\n%s", errb.String())
+	}
+	if !strings.Contains(out.String(), `"remaining":1`) {
+		t.Fatalf("tool result: %s", out.String())
+	}
+	b, err := state.LoadLatestStateBundle(dir)
+	if err != nil {
+		t.Fatalf("load bundle: %v", err)
+	}
+	if len(b.Plan) != 2 || b.Plan[0].Status != state.TaskDone || b.Plan[1].Status != state.TaskPending || b.Plan[0].Result != "read 3 lines" {
+		t.Fatalf("saved plan: %+v", b.Plan)
+	}
+}
+
+func TestRunPlan_UpdateRejectsBadArguments(t *testing.T) {
+	p := newRunPlan(cliConfig{}, []oai.Message{{Role: oai.RoleDeveloper, Content: "x"}}, nil)
+	if p != nil {
+		t.Fatalf("no plan message should give no plan")
+	}
+	p = &runPlan{tasks: []state.Task{{ID: 1, Title: "a", Status: state.TaskPending}}}
+	for _, args := range []string{`{"step":2,"status":"done"}`, `{"step":1,"status":"finished"}`, `not json`} {
+		if _, err := p.update([]byte(args)); err == nil {
+			t.Fatalf("%s: expected error", args)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// templateVarEnvPrefix marks environment variables that become template
+// variables: AGENTCLI_VAR_repo=x sets {{.repo}}.
+const templateVarEnvPrefix = "AGENTCLI_VAR_"
+
+// renderedPrompts holds the roles a -prompt-template filled in. Empty fields
+// leave the matching flags in charge.
+type renderedPrompts struct {
+	system    string
+	developer string
+	user      string
+}
+
+// parseTemplateVar splits a -var KEY=VALUE pair.
+func parseTemplateVar(pair string) (string, string, error) {
+	eq := strings.IndexByte(pair, '=')
+	if eq <= 0 || strings.TrimSpace(pair[:eq]) == "" {
+		return "", "", fmt.Errorf("invalid -var %q (expected key=value)", pair)
+	}
+	return strings.TrimSpace(pair[:eq]), pair[eq+1:], nil
+}
+
+// loadTemplateVars merges template variables from, in increasing precedence,
+// the -vars-file JSON object, AGENTCLI_VAR_* environment variables, and -var
+// pairs. Non-string JSON values are kept as their JSON text.
+func loadTemplateVars(varsFile string, environ []string, pairs []string) (map[string]string, error) {
+	vars := map[string]string{}
+	if f := strings.TrimSpace(varsFile); f != "" {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("-vars-file: %w", err)
+		}
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return nil, fmt.Errorf("-vars-file %s: want a JSON object: %w", f, err)
+		}
+		for k, raw := range obj {
+			var s string
+			if json.Unmarshal(raw, &s) == nil {
+				vars[k] = s
+			} else {
+				vars[k] = string(raw)
+			}
+		}
+	}
+	for _, kv := range environ {
+		if name, value, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(name, templateVarEnvPrefix) && len(name) > len(templateVarEnvPrefix) {
+			vars[strings.TrimPrefix(name, templateVarEnvPrefix)] = value
+		}
+	}
+	for _, pair := range pairs {
+		k, v, err := parseTemplateVar(pair)
+		if err != nil {
+			return nil, err
+		}
+		vars[k] = v
+	}
+	return vars, nil
+}
+
+// renderPromptTemplate executes a Go text/template file. Named blocks
+// {{define "system"}}, {{define "developer"}}, and {{define "user"}} render
+// those roles; without a "user" block the text outside the blocks is the user
+// prompt. A missing variable renders empty, or fails when strict is set.
+func renderPromptTemplate(path string, vars map[string]string, strict bool) (renderedPrompts, error) {
+	src, err := resolveMaybeFile("", path)
+	if err != nil {
+		return renderedPrompts{}, fmt.Errorf("-prompt-template: %w", err)
+	}
+	missing := "missingkey=zero"
+	if strict {
+		missing = "missingkey=error"
+	}
+	tmpl, err := template.New(filepath.Base(path)).Option(missing).Parse(src)
+	if err != nil {
+		return renderedPrompts{}, fmt.Errorf("-prompt-template: %w", err)
+	}
+	render := func(t *template.Template) (string, error) {
+		if t == nil {
+			return "", nil
+		}
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, vars); err != nil {
+			return "", fmt.Errorf("-prompt-template: %w", err)
+		}
+		return strings.TrimSpace(buf.String()), nil
+	}
+	var out renderedPrompts
+	if out.system, err = render(tmpl.Lookup("system")); err != nil {
+		return renderedPrompts{}, err
+	}
+	if out.developer, err = render(tmpl.Lookup("developer")); err != nil {
+		return renderedPrompts{}, err
+	}
+	user := tmpl.Lookup("user")
+	if user == nil {
+		user = tmpl
+	}
+	if out.user, err = render(user); err != nil {
+		return renderedPrompts{}, err
+	}
+	return out, nil
+}
+
+// applyPromptTemplate renders cfg.promptTemplate and returns the effective
+// system, user, and developer prompts. A role the template renders may not
+// also be given by flag.
+func applyPromptTemplate(cfg cliConfig, sys, prm string, devs []string) (string, string, []string, error) {
+	vars, err := loadTemplateVars(cfg.varsFile, os.Environ(), cfg.templateVars)
+	if err != nil {
+		return "", "", nil, err
+	}
+	out, err := renderPromptTemplate(cfg.promptTemplate, vars, cfg.templateStrict)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if out.system != "" {
+		if cfg.systemSet {
+			return "", "", nil, fmt.Errorf("-prompt-template renders a system prompt; drop -system/-system-file")
+		}
+		sys = out.system
+	}
+	if out.user != "" {
+		if strings.TrimSpace(cfg.prompt) != "" || strings.TrimSpace(cfg.promptFile) != "" {
+			return "", "", nil, fmt.Errorf("-prompt-template renders a user prompt; drop -prompt/-prompt-file")
+		}
+		prm = out.user
+	}
+	if out.developer != "" {
+		devs = append(devs, out.developer)
+	}
+	if strings.TrimSpace(prm) == "" && strings.TrimSpace(cfg.audioPrompt) == "" {
+		return "", "", nil, fmt.Errorf("-prompt-template rendered no user prompt and no -prompt was given")
+	}
+	return sys, prm, devs, nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+func TestLoadTemplateVars_Precedence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "vars.json")
+	if err := os.WriteFile(file, []byte(`{"a":"file","b":"file","c":"file","n":3,"list":["x"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	vars, err := loadTemplateVars(file, []string{"AGENTCLI_VAR_b=env", "AGENTCLI_VAR_c=env", "AGENTCLI_VAR_=skip", "OTHER=x"}, []string{"c=flag=1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"a": "file", "b": "env", "c": "flag=1", "n": "3", "list": `["x"]`}
+	if len(vars) != len(want) {
+		t.Fatalf("vars=%v", vars)
+	}
+	for k, v := range want {
+		if vars[k] != v {
+			t.Errorf("%s=%q want %q", k, vars[k], v)
+		}
+	}
+	if _, err := loadTemplateVars("", nil, []string{"novalue"}); err == nil {
+		t.Fatal("expected error for pair without '='")
+	}
+}
+
+func TestRenderPromptTemplate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "p.tmpl")
+	src := `{{define "system"}}You review {{.lang}} code.{{end}}
+{{define "developer"}}Cite {{.style}} lines.{{end}}
+Review {{.repo}}{{.missing}}.
+`
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]string{"lang": "Go", "repo": "goagent", "style": "file:line"}
+	out, err := renderPromptTemplate(path, vars, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.system != "You review Go code." || out.developer != "Cite file:line lines." || out.user != "Review goagent." {
+		t.Fatalf("out=%+v", out)
+	}
+	if _, err := renderPromptTemplate(path, vars, true); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Fatalf("strict: err=%v", err)
+	}
+
+	// An explicit user block wins over the body
+	if err := os.WriteFile(path, []byte(`{{define "user"}}hi {{.who}}{{end}}ignored`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := renderPromptTemplate(path, map[string]string{"who": "there"}, true); err != nil || out.user != "hi there" || out.system != "" {
+		t.Fatalf("user block: out=%+v err=%v", out, err)
+	}
+	if err := os.WriteFile(path, []byte(`{{.x`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := renderPromptTemplate(path, nil, false); err == nil || !strings.Contains(err.Error(), "-prompt-template") {
+		t.Fatalf("parse error: %v", err)
+	}
+}
+
+func TestApplyPromptTemplate_Conflicts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "p.tmpl")
+	if err := os.WriteFile(path, []byte(`{{define "system"}}sys{{end}}user text`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := cliConfig{promptTemplate: path}
+	if _, _, _, err := applyPromptTemplate(cliConfig{promptTemplate: path, systemSet: true}, "s", "", nil); err == nil || !strings.Contains(err.Error(), "-system") {
+		t.Fatalf("system conflict: %v", err)
+	}
+	if _, _, _, err := applyPromptTemplate(cliConfig{promptTemplate: path, prompt: "p"}, "s", "p", nil); err == nil || !strings.Contains(err.Error(), "-prompt") {
+		t.Fatalf("prompt conflict: %v", err)
+	}
+	sys, prm, devs, err := applyPromptTemplate(cfg, "default", "", []string{"dev"})
+	if err != nil || sys != "sys" || prm != "user text" || len(devs) != 1 {
+		t.Fatalf("sys=%q prm=%q devs=%v err=%v", sys, prm, devs, err)
+	}
+
+	// A system-only template still needs a user prompt from somewhere
+	if err := os.WriteFile(path, []byte(`{{define "system"}}sys{{end}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := applyPromptTemplate(cfg, "default", "", nil); err == nil || !strings.Contains(err.Error(), "no user prompt") {
+		t.Fatalf("no user prompt: %v", err)
+	}
+	if _, prm, _, err := applyPromptTemplate(cliConfig{promptTemplate: path, prompt: "p"}, "default", "p", nil); err != nil || prm != "p" {
+		t.Fatalf("flag prompt: prm=%q err=%v", prm, err)
+	}
+}
+
+func TestCLI_PromptTemplate(t *testing.T) {
+	var got oai.ChatCompletionsRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)                                                                                                                                                              //nolint:errcheck
+		_ = json.Unmarshal(body, &got)                                                                                                                                                             //nolint:errcheck
+		_ = json.NewEncoder(w).Encode(oai.ChatCompletionsResponse{Choices: []oai.ChatCompletionsResponseChoice{{Message: oai.Message{Role: oai.RoleAssistant, Channel: "final", Content: "ok"}}}}) //nolint:errcheck
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+	tmpl := filepath.Join(dir, "p.tmpl")
+	if err := os.WriteFile(tmpl, []byte(`{{define "developer"}}Audience: {{.audience}}{{end}}Explain {{.topic}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AGENTCLI_VAR_audience", "beginners")
+
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-prompt-template", tmpl, "-var", "topic=channels", "-base-url", srv.URL, "-model", "m", "-prep-enabled=false"}, &out, &errb)
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if len(got.Messages) != 3 || got.Messages[1].Content != "Audience: beginners" || got.Messages[2].Content != "Explain channels" {
+		t.Fatalf("messages=%+v", got.Messages)
+	}
+
+	errb.Reset()
+	if code := cliMain([]string{"-prompt-template", tmpl, "-template-strict", "-base-url", srv.URL, "-model", "m", "-prep-enabled=false"}, &out, &errb); code != 2 || !strings.Contains(errb.String(), "topic") {
+		t.Fatalf("strict: exit=%d stderr=%s", code, errb.String())
+	}
+	errb.Reset()
+	if code := cliMain([]string{"-prompt-template", tmpl, "-var", "bad"}, &out, &errb); code != 2 || !strings.Contains(errb.String(), "invalid -var") {
+		t.Fatalf("bad var: exit=%d stderr=%s", code, errb.String())
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/hyperifyio/goagent/internal/bedrock"
+	"github.com/hyperifyio/goagent/internal/gemini"
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// -provider values.
+const (
+	providerOpenAI  = "openai"
+	providerBedrock = "bedrock"
+	providerGemini  = "gemini"
+)
+
+// resolveProvider validates -provider and builds its transport. Bedrock
+// needs AWS credentials up front and Gemini an API key; unless -base-url was
+// given, either targets the provider's own endpoint. It returns a non-zero
+// exit code with cfg.parseError set.
+func resolveProvider(cfg *cliConfig) int {
+	cfg.provider = strings.ToLower(strings.TrimSpace(cfg.provider))
+	var endpoint string
+	var err error
+	switch cfg.provider {
+	case "", providerOpenAI:
+		cfg.provider = providerOpenAI
+		return 0
+	case providerBedrock:
+		region := bedrock.RegionFromEnv()
+		endpoint = bedrock.Endpoint(region)
+		cfg.providerTransport, err = bedrock.NewTransport(region, nil)
+	case providerGemini:
+		// -api-key defaults from the OpenAI variables; only an explicit
+		// value goes to Google, never an exported OpenAI secret
+		key := ""
+		if src := flagSource(*cfg, "api-key", "OAI_API_KEY"); src == "flag" || src == "config" {
+			key = cfg.apiKey
+		}
+		for _, k := range []string{"GEMINI_API_KEY", "GOOGLE_API_KEY"} {
+			if strings.TrimSpace(key) == "" {
+				key = os.Getenv(k)
+			}
+		}
+		endpoint = gemini.DefaultBaseURL
+		cfg.providerTransport, err = gemini.NewTransport(key, nil)
+	default:
+		cfg.parseError = fmt.Sprintf("error: invalid -provider %q (want openai, bedrock, or gemini)", cfg.provider)
+		return 2
+	}
+	if err != nil {
+		cfg.providerTransport = nil
+		cfg.parseError = fmt.Sprintf("error: -provider %s: %v", cfg.provider, err)
+		return 2
+	}
+	if strings.TrimSpace(cfg.providersPath) != "" {
+		cfg.parseError = fmt.Sprintf("error: -providers cannot be combined with -provider %s", cfg.provider)
+		return 2
+	}
+	if cfg.baseURLSource == "default" {
+		cfg.baseURL = endpoint
+	}
+	return 0
+}
+
+// installProvider sends client through the -provider adapter, if any.
+func installProvider(cfg cliConfig, client *oai.Client) {
+	tr := cfg.providerTransport
+	// Bedrock reads the Converse reply itself, within the same limit
+	if b, ok := tr.(*bedrock.Transport); ok {
+		tr = b.WithLimits(limitsFor(cfg))
+	}
+	if tr != nil {
+		client.WithTransport(tr)
+	}
+}
+
+// installProviders routes client through the -providers table, if any, and
+// warns on stderr at each failover. Only the main loop is routed: the
+// pre-stage keeps its own -prep-base-url.
+func installProviders(cfg cliConfig, client *oai.Client, stderr io.Writer) {
+	if cfg.providers == nil {
+		return
+	}
+	client.WithProviders(*cfg.providers, cfg.apiKey, func(ev oai.FailoverEvent) {
+		safeFprintf(stderr, "WARN: provider %s failed (%v); failing over to %s\n", ev.From.Name, ev.Err, ev.To.Name)
+	})
+}
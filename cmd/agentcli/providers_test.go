@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCLIMain_ProvidersFailOver(t *testing.T) {
+	dir := chdirTemp(t)
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	up := finalAnswerServer(t)
+	defer up.Close()
+	table := filepath.Join(dir, "providers.yaml")
+	body := "providers:\n  - name: primary\n    baseURL: " + down.URL + "\n  - name: backup\n    baseURL: " + up.URL + "\n"
+	if err := os.WriteFile(table, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-prompt", "q", "-model", "m", "-prep-enabled=false", "-http-retries", "0", "-base-url", "http://unused.invalid", "-providers", table}, &out, &errb)
+	if code != 0 || strings.TrimSpace(out.String()) != "done" {
+		t.Fatalf("exit=%d stdout=%q stderr=%s", code, out.String(), errb.String())
+	}
+	if !strings.Contains(errb.String(), "WARN: provider primary failed") || !strings.Contains(errb.String(), "failing over to backup") {
+		t.Fatalf("stderr=%s", errb.String())
+	}
+}
+
+func TestParseFlags_ProvidersInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "providers.json")
+	if err := os.WriteFile(path, []byte(`{"providers":[]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var out, errb bytes.Buffer
+	if code := cliMain([]string{"-prompt", "p", "-providers", path}, &out, &errb); code != 2 || !strings.Contains(errb.String(), "error: -providers:") {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+}
+
+func TestCLIMain_GeminiKeyIgnoresOpenAIDefault(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get("x-goog-api-key"))
+		mu.Unlock()
+		_, _ = io.WriteString(w, `{"candidates":[{"content":{"role":"model","parts":[{"text":"ok"}]},"finishReason":"STOP"}]}`) //nolint:errcheck
+	}))
+	defer srv.Close()
+	t.Setenv("OAI_API_KEY", "")
+	t.Setenv("OPENAI_API_KEY", "sk-openai-secret")
+	t.Setenv("GEMINI_API_KEY", "g-key")
+	t.Setenv("GOOGLE_API_KEY", "")
+	base := []string{"-prompt", "q", "-model", "m", "-prep-enabled=false", "-provider", "gemini", "-base-url", srv.URL}
+
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{nil, "g-key"},
+		{[]string{"-api-key", "explicit-key"}, "explicit-key"},
+	} {
+		keys = nil
+		var out, errb bytes.Buffer
+		if code := cliMain(append(append([]string{}, base...), tc.args...), &out, &errb); code != 0 {
+			t.Fatalf("%v: exit=%d stderr=%s", tc.args, code, errb.String())
+		}
+		mu.Lock()
+		got := strings.Join(keys, ",")
+		mu.Unlock()
+		if got != tc.want {
+			t.Fatalf("%v: x-goog-api-key=%q, want %q", tc.args, got, tc.want)
+		}
+	}
+}
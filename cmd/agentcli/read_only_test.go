@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCLIMain_ReadOnly_RefusesMutatingTools(t *testing.T) {
+	toolsPath := writeEchoOKTool(t)
+	b, err := os.ReadFile(toolsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(toolsPath, bytes.Replace(b, []byte(`"name":"ping"`), []byte(`"mutating":true,"name":"ping"`), 1), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	t.Chdir(root)
+	// A live lock holder would block a run that can mutate the workspace
+	lockPath := holdLock(t, root, 4242)
+	srv := twoPingServer(t)
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-prompt", "q", "-tools", toolsPath, "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-read-only", "-approve-tools", "ping"}, &out, &errb)
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	refusal := `{"error":"tool ping is disabled in read-only mode; use a tool that does not modify the workspace"}`
+	got := strings.TrimSpace(out.String())
+	if !strings.Contains(got, "c1="+refusal) || !strings.Contains(got, "c2="+refusal) {
+		t.Fatalf("stdout=%q", got)
+	}
+	if strings.Contains(errb.String(), "approve tool call?") {
+		t.Fatalf("refused call was sent for approval: %s", errb.String())
+	}
+	if _, err := os.Stat(lockPath); err != nil {
+		t.Fatalf("read-only run touched the workspace lock: %v", err)
+	}
+}
+
+func TestCLIMain_ReadOnly_AllowsReadOnlyTools(t *testing.T) {
+	toolsPath := writeEchoOKTool(t)
+	srv := twoPingServer(t)
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-prompt", "q", "-tools", toolsPath, "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-read-only"}, &out, &errb)
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if got := strings.TrimSpace(out.String()); strings.Count(got, `{"ok":true}`) != 2 {
+		t.Fatalf("stdout=%q", got)
+	}
+}
+
+func TestCLIMain_ReadOnly_RunsDryRunCapableToolsAsDryRuns(t *testing.T) {
+	toolsPath := writeEchoOKTool(t)
+	// The tool echoes its arguments back so the rewrite is visible
+	if err := os.WriteFile(filepath.Join(filepath.Dir(toolsPath), "ping.sh"), []byte("#!/bin/sh\ncat\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(toolsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(toolsPath, bytes.Replace(b, []byte(`"name":"ping"`), []byte(`"mutating":true,"supportsDryRun":true,"name":"ping"`), 1), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	srv := twoPingServer(t)
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-prompt", "q", "-tools", toolsPath, "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-read-only"}, &out, &errb)
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	got := strings.TrimSpace(out.String())
+	if !strings.Contains(got, `c1={"dryRun":true,"n":1}`) || !strings.Contains(got, `c2={"dryRun":true,"n":2}`) {
+		t.Fatalf("stdout=%q", got)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+	"github.com/hyperifyio/goagent/internal/recording"
+	"github.com/hyperifyio/goagent/internal/tools"
+)
+
+// startRecording routes every API client and tool run through a -record or
+// -replay session for the lifetime of the run. It returns a stop function
+// and false when the directory cannot be used.
+func startRecording(cfg cliConfig, stderr io.Writer) (func(), bool) {
+	var session *recording.Session
+	var err error
+	switch {
+	case cfg.recordDir != "":
+		session, err = recording.Record(cfg.recordDir)
+	case cfg.replayDir != "":
+		session, err = recording.Replay(cfg.replayDir)
+	default:
+		return func() {}, true
+	}
+	if err != nil {
+		safeFprintf(stderr, "error: %v\n", err)
+		return nil, false
+	}
+	restoreTransport := oai.InterceptSharedTransport(session.Transport)
+	restoreTools := tools.SetInterceptor(session.RunTool)
+	return func() {
+		restoreTools()
+		restoreTransport()
+		if err := session.Close(); err != nil {
+			safeFprintf(stderr, "WARN: -record: %v\n", err)
+		}
+	}, true
+}
+
+// adoptRecordedSeed gives a -replay run without an explicit seed the seed of
+// the recorded chat requests, so its requests match the recording.
+func adoptRecordedSeed(cfg cliConfig) cliConfig {
+	if cfg.replayDir == "" || cfg.seedSource == "flag" || cfg.seedSource == "env" {
+		return cfg
+	}
+	if seed, ok := recordedSeed(cfg.replayDir); ok {
+		cfg.seed, cfg.seedSource, cfg.sendSeed = int(seed), "recording", true
+	}
+	return cfg
+}
+
+// recordedSeed returns the seed of the first recorded chat request that
+// carries one.
+func recordedSeed(dir string) (int64, bool) {
+	f, err := os.Open(filepath.Join(dir, recording.FileName))
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close() //nolint:errcheck // read-only
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 64<<20)
+	for sc.Scan() {
+		var e recording.Entry
+		if json.Unmarshal(sc.Bytes(), &e) != nil || e.Kind != "http" || !strings.HasSuffix(e.Path, "/chat/completions") {
+			continue
+		}
+		var body struct {
+			Seed *int64 `json:"seed"`
+		}
+		if json.Unmarshal([]byte(e.RequestBody), &body) == nil && body.Seed != nil {
+			return *body.Seed, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/recording"
+)
+
+func TestCLIMain_RecordThenReplayOffline(t *testing.T) {
+	dir := chdirTemp(t)
+	toolsPath := writeEchoOKTool(t)
+	srv := countingPingServer(t, nil)
+	rec := filepath.Join(dir, "rec")
+	args := []string{"-prompt", "q", "-tools", toolsPath, "-model", "m", "-prep-enabled=false"}
+
+	var recorded, errb bytes.Buffer
+	if code := cliMain(append(args, "-base-url", srv.URL, "-record", rec), &recorded, &errb); code != 0 {
+		t.Fatalf("record exit=%d stderr=%s", code, errb.String())
+	}
+	data, err := os.ReadFile(filepath.Join(rec, recording.FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), `"kind":"http"`); n != 2 || strings.Count(string(data), `"kind":"tool"`) != 1 {
+		t.Fatalf("recording=%s", data)
+	}
+
+	// Offline: the server is gone and the tool would now answer differently
+	srv.Close()
+	script := filepath.Join(filepath.Dir(toolsPath), "ping.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho '{\"ok\":false}'\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	var replayed bytes.Buffer
+	errb.Reset()
+	if code := cliMain(append(args, "-base-url", srv.URL, "-replay", rec), &replayed, &errb); code != 0 {
+		t.Fatalf("replay exit=%d stderr=%s", code, errb.String())
+	}
+	if replayed.String() != recorded.String() || !strings.Contains(recorded.String(), `c1={"ok":true}`) {
+		t.Fatalf("replayed %q, recorded %q", replayed.String(), recorded.String())
+	}
+
+	// A different prompt diverges from the recording
+	errb.Reset()
+	changed := append([]string{"-prompt", "other"}, args[2:]...)
+	if code := cliMain(append(changed, "-replay", rec), &replayed, &errb); code != exitHTTPFailure || !strings.Contains(errb.String(), "diverged from the recording") {
+		t.Fatalf("divergent replay: exit=%d stderr=%s", code, errb.String())
+	}
+}
+
+func TestCLIMain_RecordReplayFlags(t *testing.T) {
+	var out, errb bytes.Buffer
+	if code := cliMain([]string{"-prompt", "p", "-record", "a", "-replay", "b"}, &out, &errb); code != 2 || !strings.Contains(errb.String(), "mutually exclusive") {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	errb.Reset()
+	if code := cliMain([]string{"-prompt", "p", "-replay", t.TempDir()}, &out, &errb); code != 2 || !strings.Contains(errb.String(), "read recording") {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// reviewApproved is the reply that accepts a candidate answer unchanged.
+const reviewApproved = "APPROVED"
+
+const reviewSystemPrompt = "You review answers written by another assistant. Check the candidate answer against the original request for correctness, completeness, and whether it follows the request's instructions. If it is acceptable, reply with exactly " + reviewApproved + ". Otherwise reply with a concise list of the concrete problems to fix; do not rewrite the answer yourself."
+
+// reviewer runs the -review-model critique loop: each candidate final answer
+// is checked against the original prompt, and a rejected answer is sent back
+// to the main model for revision until -review-rounds is used up.
+type reviewer struct {
+	cfg        cliConfig
+	client     *oai.Client
+	prompt     string
+	roundsLeft int
+}
+
+// newReviewer returns nil when -review-model is unset or -review-rounds is 0.
+func newReviewer(cfg cliConfig, client *oai.Client, prompt string) *reviewer {
+	if cfg.reviewModel == "" || cfg.reviewRounds <= 0 {
+		return nil
+	}
+	return &reviewer{cfg: cfg, client: client, prompt: prompt, roundsLeft: cfg.reviewRounds}
+}
+
+// review critiques candidate. When the reviewer asks for changes it returns
+// the turns that request a revision and true; the caller appends them and
+// continues the loop. Reviewer failures keep the candidate with a warning.
+func (r *reviewer) review(ctx context.Context, candidate oai.Message, stdout, stderr io.Writer) ([]oai.Message, bool) {
+	if r == nil || r.roundsLeft <= 0 {
+		return nil, false
+	}
+	r.roundsLeft--
+	critique, err := r.critique(ctx, candidate.Content)
+	if err != nil {
+		safeFprintf(stderr, "WARN: review failed; keeping the answer: %v\n", err)
+		return nil, false
+	}
+	if strings.HasPrefix(strings.ToUpper(critique), reviewApproved) {
+		return nil, false
+	}
+	r.report(critique, stdout, stderr)
+	next := "A reviewer found problems with your answer:\n\n" + critique + "\n\nRevise your answer to address them and reply with the complete final answer."
+	return []oai.Message{candidate, {Role: oai.RoleUser, Content: next}}, true
+}
+
+func (r *reviewer) critique(parent context.Context, candidate string) (string, error) {
+	req := oai.ChatCompletionsRequest{
+		Model: r.cfg.reviewModel,
+		Messages: []oai.Message{
+			{Role: oai.RoleSystem, Content: reviewSystemPrompt},
+			{Role: oai.RoleUser, Content: "Original request:\n\n" + r.prompt + "\n\nCandidate answer:\n\n" + strings.TrimSpace(candidate)},
+		},
+	}
+	if oai.SupportsTemperature(r.cfg.reviewModel) {
+		temp := 0.0
+		req.Temperature = &temp
+	}
+	ctx, cancel := context.WithTimeout(oai.WithAuditStage(parent, "review"), r.cfg.httpTimeout)
+	defer cancel()
+	resp, err := r.client.CreateChatCompletion(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("reviewer returned no choices")
+	}
+	critique := strings.TrimSpace(resp.Choices[0].Message.Content)
+	if critique == "" {
+		return "", fmt.Errorf("reviewer returned an empty reply")
+	}
+	return critique, nil
+}
+
+// report delivers the critique on the critic channel: printed under
+// -verbose, or written to a file route.
+func (r *reviewer) report(critique string, stdout, stderr io.Writer) {
+	emitChannel(r.cfg, "critic", true, critique, stdout, stderr)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// reviewServer answers the main model with draft N on its Nth call and the
+// reviewer with the next queued verdict.
+func reviewServer(t *testing.T, verdicts ...string) (*httptest.Server, *[]oai.ChatCompletionsRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var reqs []oai.ChatCompletionsRequest
+	drafts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req oai.ChatCompletionsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		mu.Lock()
+		reqs = append(reqs, req)
+		var msg oai.Message
+		if req.Model == "critic-m" {
+			msg = oai.Message{Role: oai.RoleAssistant, Content: verdicts[0]}
+			verdicts = verdicts[1:]
+		} else {
+			drafts++
+			msg = oai.Message{Role: oai.RoleAssistant, Channel: "final", Content: "draft " + string(rune('0'+drafts))}
+		}
+		mu.Unlock()
+		_ = json.NewEncoder(w).Encode(oai.ChatCompletionsResponse{Choices: []oai.ChatCompletionsResponseChoice{{Message: msg}}}) //nolint:errcheck
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &reqs
+}
+
+func TestCLIMain_Review_RevisesUntilApproved(t *testing.T) {
+	srv, reqs := reviewServer(t, "Missing units.", "approved")
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-prompt", "how far?", "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-review-model", "critic-m", "-review-rounds", "3", "-verbose"}, &out, &errb)
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if got := strings.TrimSpace(out.String()); got != "draft 2" {
+		t.Fatalf("stdout=%q", got)
+	}
+	if !strings.Contains(errb.String(), "Missing units.") {
+		t.Fatalf("critique not on stderr under -verbose: %s", errb.String())
+	}
+	if len(*reqs) != 4 {
+		t.Fatalf("want draft, review, revision, review; got %d requests", len(*reqs))
+	}
+	review := (*reqs)[1].Messages[1].Content
+	if !strings.Contains(review, "how far?") || !strings.Contains(review, "draft 1") {
+		t.Fatalf("reviewer request lacks prompt or candidate: %q", review)
+	}
+	revision := (*reqs)[2].Messages
+	if last := revision[len(revision)-1]; last.Role != oai.RoleUser || !strings.Contains(last.Content, "Missing units.") {
+		t.Fatalf("revision request lacks critique: %+v", last)
+	}
+}
+
+func TestCLIMain_Review_StopsAfterRounds(t *testing.T) {
+	srv, reqs := reviewServer(t, "Wrong.")
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-prompt", "q", "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-review-model", "critic-m"}, &out, &errb)
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if got := strings.TrimSpace(out.String()); got != "draft 2" || len(*reqs) != 3 {
+		t.Fatalf("stdout=%q requests=%d", got, len(*reqs))
+	}
+	if strings.Contains(errb.String(), "Wrong.") {
+		t.Fatalf("critique printed without -verbose: %s", errb.String())
+	}
+}
+
+func TestParseFlags_ReviewRounds(t *testing.T) {
+	orig := os.Args
+	defer func() { os.Args = orig }()
+	t.Setenv("OAI_REVIEW_MODEL", "env-critic")
+	t.Setenv("OAI_REVIEW_ROUNDS", "2")
+	os.Args = []string{"agentcli.test", "-prompt", "p"}
+	cfg, code := parseFlags()
+	if code != 0 || cfg.reviewModel != "env-critic" || cfg.reviewRounds != 2 {
+		t.Fatalf("env: code=%d cfg=%q/%d", code, cfg.reviewModel, cfg.reviewRounds)
+	}
+	os.Args = []string{"agentcli.test", "-prompt", "p", "-review-rounds", "-1"}
+	if cfg, code := parseFlags(); code != 2 || !strings.Contains(cfg.parseError, "-review-rounds") {
+		t.Fatalf("negative rounds: code=%d err=%q", code, cfg.parseError)
+	}
+}
@@ -6,12 +6,13 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"strings"
 	"time"
 
 	"github.com/hyperifyio/goagent/internal/oai"
+	"github.com/hyperifyio/goagent/internal/redact"
 	"github.com/hyperifyio/goagent/internal/tools"
+	"github.com/hyperifyio/goagent/internal/tracing"
 )
 
 // runAgent executes the non-interactive agent loop and returns a process exit code.
@@ -45,6 +46,20 @@ func runAgent(cfg cliConfig, stdout io.Writer, stderr io.Writer) int {
 			cfg.toolTimeout = 30 * time.Second
 		}
 	}
+	// -channel-route file: rules append to files held open for the run
+	if cfg.channelSinks == nil && hasFileRoute(cfg) {
+		cfg.channelSinks = newChannelSinks()
+		defer cfg.channelSinks.close()
+	}
+	// -tui: draw a live dashboard from the run's events; the run's own output
+	// is captured while it is open and replayed when it closes
+	if cfg.tui && cfg.events == nil {
+		if dash := newDashboard(cfg, stderr); dash != nil {
+			defer dash.close(stdout)
+			cfg.events = dash.handle
+			stdout, stderr = dash.stdout(), dash.stderr()
+		}
+	}
 	// Load tools manifest if provided
 	var (
 		toolRegistry map[string]tools.ToolSpec
@@ -55,23 +70,99 @@ func runAgent(cfg cliConfig, stdout io.Writer, stderr io.Writer) int {
 		toolRegistry, oaiTools, err = tools.LoadManifest(cfg.toolsPath)
 		if err != nil {
 			safeFprintf(stderr, "error: failed to load tools manifest: %v\n", err)
-			return 1
+			return exitToolFailure
+		}
+		// Advertise model-targeted description variants when the manifest declares them
+		oaiTools = tools.ApplyModelDescriptions(oaiTools, toolRegistry, cfg.model)
+		// Flatten complex schemas when the target model is below the configured tier
+		if shouldSimplifySchemas(cfg) {
+			oaiTools = tools.SimplifyToolSchemas(oaiTools)
 		}
 		// Validate each configured tool is available on this system before proceeding
 		for name, spec := range toolRegistry {
-			if len(spec.Command) == 0 {
+			if tools.ProgramPath(spec) == "" {
 				safeFprintf(stderr, "error: configured tool %q has no command\n", name)
-				return 1
+				return exitToolFailure
 			}
-			if _, lookErr := exec.LookPath(spec.Command[0]); lookErr != nil {
-				safeFprintf(stderr, "error: configured tool %q is unavailable: %v (program %q)\n", name, lookErr, spec.Command[0])
-				return 1
+			if lookErr := tools.CheckProgram(spec); lookErr != nil {
+				safeFprintf(stderr, "error: configured tool %q is unavailable: %v (program %q)\n", name, lookErr, tools.ProgramPath(spec))
+				return exitToolFailure
 			}
 		}
 	}
 
-	// Configure HTTP client with retry policy
-	httpClient := oai.NewClientWithRetry(cfg.baseURL, cfg.apiKey, cfg.httpTimeout, oai.RetryPolicy{MaxRetries: cfg.httpRetries, Backoff: cfg.httpBackoff})
+	// Built-in subagent tool, then the subset a subagent or script turn may use
+	if toolRegistry, oaiTools, err = addAgentRunTool(cfg, toolRegistry, oaiTools); err != nil {
+		safeFprintf(stderr, "error: %v\n", err)
+		return exitToolFailure
+	}
+	if cfg.toolAllowlist != nil {
+		if toolRegistry, oaiTools, err = filterTools(toolRegistry, oaiTools, cfg.toolAllowlist); err != nil {
+			safeFprintf(stderr, "error: %v\n", err)
+			return exitToolFailure
+		}
+	}
+
+	// Gate -approve-tools calls on a human answer; nested subagents share it
+	if cfg.approver == nil {
+		cfg.approver = newToolApprover(cfg, stderr)
+	}
+	// -chaos draws from one seeded generator, shared with nested subagents
+	if cfg.injector == nil {
+		cfg.injector = newChaosInjector(cfg, stderr)
+	}
+
+	// Serialize runs that can edit the workspace so file edits and caches
+	// are never interleaved between concurrent invocations; -read-only runs
+	// cannot edit it
+	if !cfg.noLock && !cfg.readOnly && tools.AnyMutates(toolRegistry) {
+		lock, lockErr := acquireWorkspaceLocks(workspaceLockRoots(cfg))
+		if lockErr != nil {
+			safeFprintf(stderr, "error: %v\n", lockErr)
+			return exitWorkspaceLock
+		}
+		defer lock.release()
+	}
+
+	// Ctrl-C/SIGTERM cancels the in-flight HTTP call and running tools
+	var ctx context.Context
+	var stopSignals context.CancelFunc
+	if cfg.parentCtx != nil {
+		// A nested agent.run child follows its parent's tool call instead,
+		// and a gRPC run its call
+		ctx, stopSignals = context.WithCancel(cfg.parentCtx)
+	} else {
+		ctx, stopSignals = agentSignalContext()
+	}
+	defer stopSignals()
+	// Trace the whole run; pre-stage and steps nest under it
+	ctx, runSpan := tracing.Start(ctx, "agent.run", tracing.String("gen_ai.request.model", cfg.model), tracing.String("goagent.strategy", cfg.strategy))
+	defer runSpan.End(nil)
+
+	// -state-remote: start from the shared state and share this run's; the
+	// push is deferred first so it runs after every local save
+	pushState, err := pullStateRemote(ctx, cfg, stderr)
+	if err != nil {
+		safeFprintf(stderr, "error: -state-remote: %v\n", err)
+		return exitStateRemote
+	}
+	defer pushState()
+
+	// Configure HTTP client with retry policy; -batch items share one
+	httpClient := cfg.chatClient
+	if httpClient == nil {
+		httpClient = newChatClient(cfg, stderr)
+	}
+	var usage runUsage
+	if cfg.verbose {
+		defer func() { printUsageSummary(stderr, usage, httpClient.Stats()) }()
+	}
+	if cfg.usageSink != nil {
+		defer func() { *cfg.usageSink = usage }()
+	}
+	// -discover-models: learn the model's window and parameter support first
+	discoverModels(ctx, cfg, httpClient, stderr)
+	warnToolSupport(cfg, oaiTools, stderr)
 
 	var messages []oai.Message
 	if strings.TrimSpace(cfg.loadMessagesPath) != "" {
@@ -90,9 +181,11 @@ func runAgent(cfg cliConfig, stdout io.Writer, stderr io.Writer) int {
 		if strings.TrimSpace(cfg.imagePrompt) == "" && strings.TrimSpace(imgPrompt) != "" {
 			cfg.imagePrompt = strings.TrimSpace(imgPrompt)
 		}
+		messages = repairMessages(cfg, messages, stderr)
 		if err := oai.ValidateMessageSequence(messages); err != nil {
 			safeFprintf(stderr, "error: invalid loaded message sequence: %v\n", err)
-			return 2
+			reportSequenceProblems(messages, stderr)
+			return exitValidation
 		}
 	} else if len(cfg.initMessages) > 0 {
 		// Use injected messages (tests only)
@@ -114,6 +207,29 @@ func runAgent(cfg cliConfig, stdout io.Writer, stderr io.Writer) int {
 			safeFprintf(stderr, "error: %v\n", devErr)
 			return 2
 		}
+		if strings.TrimSpace(cfg.promptTemplate) != "" {
+			var tmplErr error
+			if sys, prm, devs, tmplErr = applyPromptTemplate(cfg, sys, prm, devs); tmplErr != nil {
+				safeFprintf(stderr, "error: %v\n", tmplErr)
+				return 2
+			}
+		}
+		if strings.TrimSpace(cfg.audioPrompt) != "" {
+			audio, err := readAudioPrompt(cfg.audioPrompt)
+			if err != nil {
+				safeFprintf(stderr, "error: %v\n", err)
+				return 2
+			}
+			transcript, err := transcribeAudioPrompt(ctx, cfg, audio)
+			if err != nil {
+				safeFprintf(stderr, "error: %v\n", err)
+				return exitHTTPFailure
+			}
+			if cfg.verbose {
+				safeFprintf(stderr, "info: transcribed %s (%d chars)\n", cfg.audioPrompt, len(transcript))
+			}
+			prm = joinAudioPrompt(prm, transcript)
+		}
 		// Build messages honoring precedence
 		var seed []oai.Message
 		seed = append(seed, oai.Message{Role: oai.RoleSystem, Content: sys})
@@ -122,10 +238,50 @@ func runAgent(cfg cliConfig, stdout io.Writer, stderr io.Writer) int {
 				seed = append(seed, oai.Message{Role: oai.RoleDeveloper, Content: s})
 			}
 		}
-		seed = append(seed, oai.Message{Role: oai.RoleUser, Content: prm})
+		images, imgErr := loadImageAttachments(cfg.imageAttach)
+		if imgErr != nil {
+			safeFprintf(stderr, "error: %v\n", imgErr)
+			return 2
+		}
+		seed = append(seed, oai.Message{Role: oai.RoleUser, Content: prm, Parts: images})
 		messages = seed
 	}
 
+	// -golden: load the reference run before spending any requests
+	golden, goldenErr := loadGolden(cfg)
+	if goldenErr != nil {
+		safeFprintf(stderr, "error: %v\n", goldenErr)
+		return 2
+	}
+
+	// -tasks: the checklist goes in as a developer message ahead of the
+	// pre-stage, and task.complete tracks it for the final report
+	if strings.TrimSpace(cfg.tasksPath) != "" && cfg.checklist == nil {
+		if cfg.checklist, err = loadChecklist(strings.TrimSpace(cfg.tasksPath)); err != nil {
+			safeFprintf(stderr, "error: %v\n", err)
+			return 2
+		}
+	}
+	if cfg.checklist != nil {
+		if toolRegistry, oaiTools, err = cfg.checklist.addTool(toolRegistry, oaiTools); err != nil {
+			safeFprintf(stderr, "error: %v\n", err)
+			return exitToolFailure
+		}
+		messages = cfg.checklist.inject(messages)
+		defer cfg.checklist.report(stderr)
+	}
+
+	// Resolve the pre-stage prompt once: the pre-stage sends it and saved
+	// messages record its source
+	if cfg.prepPromptSource == "" {
+		src, text, err := resolvePrepPrompt(cfg)
+		if err != nil {
+			safeFprintf(stderr, "error: %v\n", err)
+			return 2
+		}
+		cfg.prepPromptSource, cfg.prepPromptText = src, text
+	}
+
 	// Loop with per-request timeouts so multi-step tool calls have full budget each time.
 	warnedOneKnob := false
 	// Enforce a hard ceiling of 15 steps regardless of the provided value.
@@ -133,6 +289,9 @@ func runAgent(cfg cliConfig, stdout io.Writer, stderr io.Writer) int {
 	if effectiveMaxSteps > 15 {
 		effectiveMaxSteps = 15
 	}
+	// -context-report compares requests against the pre-stage seed
+	ctxReport := newContextReport(cfg.contextReport, cfg.model, messages)
+	defer ctxReport.print(stderr)
 	// Pre-stage: perform a preparatory chat call and append any pre-stage tool outputs
 	// to the transcript before entering the main loop. Behavior is additive only.
 	// nolint below: ignore returned error intentionally to fail-open on pre-stage
@@ -142,7 +301,9 @@ func runAgent(cfg cliConfig, stdout io.Writer, stderr io.Writer) int {
 			return nil
 		}
 		// Execute pre-stage and update messages if any tool outputs were produced
-		out, err := runPreStage(cfg, messages, stderr)
+		prepCtx, prepSpan := tracing.Start(ctx, "prestage")
+		out, err := runPreStage(prepCtx, cfg, messages, stderr)
+		prepSpan.End(err)
 		if err != nil {
 			// Fail-open: log one concise WARN and proceed with original messages
 			safeFprintf(stderr, "WARN: pre-stage failed; skipping (reason: %s)\n", oneLine(err.Error()))
@@ -152,79 +313,139 @@ func runAgent(cfg cliConfig, stdout io.Writer, stderr io.Writer) int {
 		return nil
 	}()
 
+	// A plan from the pre-stage gets the built-in plan.update tool for
+	// progress and is saved with -state-dir when the run ends
+	if cfg.plan = newRunPlan(cfg, messages, stderr); cfg.plan != nil {
+		if toolRegistry, oaiTools, err = cfg.plan.addTool(toolRegistry, oaiTools); err != nil {
+			safeFprintf(stderr, "error: %v\n", err)
+			return exitToolFailure
+		}
+		cfg.plan.report()
+		defer func() { cfg.plan.persist(cfg, messages, stderr) }()
+	}
+
 	// Optional: pretty-print the final merged messages prior to the main call
 	if cfg.printMessages {
 		// Print a wrapper that includes metadata but omits any sensitive keys
-		if b, err := json.MarshalIndent(buildMessagesWrapper(messages, strings.TrimSpace(cfg.imagePrompt)), "", "  "); err == nil {
-			safeFprintln(stderr, string(b))
+		if b, err := json.MarshalIndent(buildMessagesWrapper(messages, strings.TrimSpace(cfg.imagePrompt), cfg.prepPromptSource, cfg.prepPromptText), "", "  "); err == nil {
+			safeFprintln(stderr, string(redact.Bytes(b)))
 		}
 	}
 
+	// Optional: diff a saved transcript against the merged messages
+	if len(cfg.diffMessages) == 1 {
+		saved, err := loadMessagesFile(cfg.diffMessages[0])
+		if err != nil {
+			safeFprintf(stderr, "error: %v\n", err)
+			return 2
+		}
+		writeMessagesDiff(stderr, cfg.diffMessages[0], "current run", saved, messages)
+	}
+
 	// Optional: save the final merged messages to a JSON file before main call
 	if strings.TrimSpace(cfg.saveMessagesPath) != "" {
-		if err := writeSavedMessages(strings.TrimSpace(cfg.saveMessagesPath), messages, strings.TrimSpace(cfg.imagePrompt)); err != nil {
+		if err := writeSavedMessages(strings.TrimSpace(cfg.saveMessagesPath), messages, strings.TrimSpace(cfg.imagePrompt), cfg.prepPromptSource, cfg.prepPromptText); err != nil {
 			safeFprintf(stderr, "error: write save-messages file: %v\n", err)
 			return 2
 		}
 	}
 
+	// A loaded transcript that stopped between a tool request and its
+	// results finishes those calls before asking the model again
+	if strings.TrimSpace(cfg.loadMessagesPath) != "" {
+		messages = resumePendingToolCalls(ctx, messages, toolRegistry, cfg, stderr)
+	}
+
+	// Plan-and-execute keeps its task board across steps
+	var planner *planRunner
+	if cfg.strategy == strategyPlan {
+		planner = newPlanRunner(cfg, firstUserContent(messages), stderr)
+	}
+	// -review-model critiques each candidate final answer before it is printed
+	critic := newReviewer(cfg, httpClient, firstUserContent(messages))
+
+	// With -state-dir, record each step so `agentcli state replay` can rebuild its request
+	recorder := newRunRecorder(cfg, oaiTools)
+	defer func() { recorder.save(messages, stderr) }()
+	// -chat-cache replays replies to requests identical to earlier ones
+	chats := newChatCache(cfg, stderr)
+	// -tools-topk advertises only the tools relevant to each step
+	retriever := newToolRetriever(cfg, httpClient, stderr)
+
 	var step int
+	var stepSpan *tracing.Span
+	defer func() { stepSpan.End(nil) }()
 	for step = 0; step < effectiveMaxSteps; step++ {
+		// Each step is a span; chat requests and tool runs below nest under it
+		stepSpan.End(nil)
+		var stepCtx context.Context
+		stepCtx, stepSpan = tracing.Start(ctx, "step", tracing.Int("goagent.step", step+1))
+		ctx := stepCtx
+		cfg.events.emit(runEvent{Kind: eventStep, Step: step + 1, MaxSteps: effectiveMaxSteps})
+		// -model-escalate: pick this step's model by step number and the
+		// outcome of the previous step's tool calls
+		if len(cfg.modelEscalate) > 0 {
+			if m := cfg.modelEscalate.modelFor(step+1, lastToolsFailed(messages)); m != cfg.model {
+				if cfg.verbose {
+					safeFprintf(stderr, "info: step %d escalates model %s -> %s\n", step+1, cfg.model, m)
+				}
+				cfg.model = m
+			}
+		}
+		if cfg.tokenBudget > 0 && usage.totalTokens >= cfg.tokenBudget {
+			safeFprintf(stderr, "error: token budget exhausted (%d of %d tokens used)\n", usage.totalTokens, cfg.tokenBudget)
+			return exitBudgetExceeded
+		}
 		// completionCap governs optional MaxTokens on the request. It defaults to 0
 		// (omitted) and will be adjusted by length backoff logic.
 		completionCap := 0
 		retriedForLength := false
+		stepTools := retriever.toolsFor(ctx, step+1, messages, oaiTools)
 
 		// Perform at most one in-step retry when finish_reason=="length".
 		for {
-			// Apply transcript hygiene before sending to the API when -debug is off
-			hygienic := applyTranscriptHygiene(messages, cfg.debug)
-			req := oai.ChatCompletionsRequest{
-				Model:    cfg.model,
-				Messages: hygienic,
+			if ctx.Err() != nil {
+				return handleInterrupt(cfg, messages, step, stderr)
 			}
-			// One-knob rule: if -top-p is set, set top_p and omit temperature; warn once.
-			if cfg.topP > 0 {
-				// Set top_p in the request payload
-				topP := cfg.topP
-				req.TopP = &topP
-				if !warnedOneKnob {
-					safeFprintln(stderr, "warning: -top-p is set; omitting temperature per one-knob rule")
-					warnedOneKnob = true
-				}
-			} else {
-				// Include temperature only when supported by the target model.
-				if oai.SupportsTemperature(cfg.model) {
-					req.Temperature = &cfg.temperature
-				}
-			}
-			if len(oaiTools) > 0 {
-				req.Tools = oaiTools
-				req.ToolChoice = "auto"
-			}
-
-			// Include MaxTokens only when a positive completionCap is set.
-			if completionCap > 0 {
-				req.MaxTokens = completionCap
+			messages = repairMessages(cfg, messages, stderr)
+			req := buildStepRequest(cfg, messages, stepTools, completionCap)
+			// One-knob rule: if -top-p is set, temperature is omitted; warn once.
+			if cfg.topP > 0 && !warnedOneKnob {
+				safeFprintln(stderr, "warning: -top-p is set; omitting temperature per one-knob rule")
+				warnedOneKnob = true
 			}
 
 			// Pre-flight validate message sequence to avoid API 400s for stray tool messages
 			if err := oai.ValidateMessageSequence(req.Messages); err != nil {
 				safeFprintf(stderr, "error: %v\n", err)
-				return 1
+				reportSequenceProblems(req.Messages, stderr)
+				return exitValidation
 			}
 
+			ctxReport.observe(step+1, req.Messages, req.Tools)
+			// Translate tools and tool turns for the strategy and tool protocol
+			req = applyStrategyRequest(cfg, req, planner)
+			recorder.record(step+1, len(messages), completionCap)
+
 			// Request debug dump (no human-readable output precedes requests)
 			dumpJSONIfDebug(stderr, fmt.Sprintf("chat.request step=%d", step+1), req, cfg.debug)
+			cached, cacheHit := chats.get(req, step+1)
 
 			// Per-call context
-			callCtx, cancel := context.WithTimeout(context.Background(), cfg.httpTimeout)
-			// Attempt streaming first when enabled; on unsupported, fall back
-			if cfg.streamFinal {
+			callCtx, cancel := context.WithTimeout(ctx, cfg.httpTimeout)
+			// Attempt streaming first when enabled; on unsupported, fall back.
+			// The text tool protocol needs the whole reply to find tool blocks, and
+			// a reviewed answer must not reach stdout before the critique.
+			// A cached reply is handled like a non-streamed one.
+			if cfg.streamFinal && cfg.toolProtocol != oai.ToolProtocolText && cfg.strategy == strategyNative && critic == nil && !cacheHit {
 				var streamedFinal strings.Builder
 				type buffered struct{ channel, content string }
 				var bufferedNonFinal []buffered
+				var acc oai.StreamAccumulator
 				streamErr := httpClient.StreamChat(callCtx, req, func(chunk oai.StreamChunk) error {
+					if err := acc.Add(chunk); err != nil {
+						return err
+					}
 					// Accumulate only final channel content to stdout progressively; buffer others
 					for _, ch := range chunk.Choices {
 						delta := ch.Delta
@@ -232,7 +453,10 @@ func runAgent(cfg cliConfig, stdout io.Writer, stderr io.Writer) int {
 							continue
 						}
 						if strings.TrimSpace(delta.Channel) == "final" || strings.TrimSpace(delta.Channel) == "" {
-							safeFprintf(stdout, "%s", delta.Content)
+							// With -output-file the answer is written once complete
+							if cfg.outputFile == "" {
+								safeFprintf(stdout, "%s", delta.Content)
+							}
 							streamedFinal.WriteString(delta.Content)
 						} else {
 							bufferedNonFinal = append(bufferedNonFinal, buffered{channel: strings.TrimSpace(delta.Channel), content: delta.Content})
@@ -242,57 +466,103 @@ func runAgent(cfg cliConfig, stdout io.Writer, stderr io.Writer) int {
 				})
 				cancel()
 				if streamErr == nil {
+					if fp := acc.SystemFingerprint(); cfg.debug && fp != "" {
+						safeFprintf(stderr, "\n--- chat.stream step=%d ---\nsystem_fingerprint=%s\n", step+1, fp)
+					}
+					usage.add(acc.Usage())
+					chats.put(req, acc.Message(), acc.FinishReason())
+					cfg.events.emit(runEvent{Kind: eventUsage, Usage: usage})
+					// Streamed tool calls: run them and continue with another turn
+					if msg := acc.Message(); len(msg.ToolCalls) > 0 && len(toolRegistry) > 0 {
+						if streamedFinal.Len() > 0 && cfg.outputFile == "" {
+							safeFprintln(stdout, "")
+						}
+						messages = append(messages, msg)
+						messages = appendToolCallOutputs(ctx, messages, msg, toolRegistry, cfg)
+						break
+					}
 					// Stream finished successfully. Emit newline to finalize stdout.
-					safeFprintln(stdout, "")
-					if cfg.verbose {
-						for _, b := range bufferedNonFinal {
-							route := resolveChannelRoute(cfg, b.channel, true /*nonFinal*/)
-							switch route {
-							case "stdout":
-								safeFprintln(stdout, strings.TrimSpace(b.content))
-							case "stderr":
-								safeFprintln(stderr, strings.TrimSpace(b.content))
-							case "omit":
-								// skip
-							}
+					code := 0
+					if cfg.outputFile != "" {
+						code = writeFinalOutput(cfg, streamedFinal.String(), stderr)
+					} else {
+						safeFprintln(stdout, "")
+					}
+					recorder.finish(append(messages, acc.Message()))
+					if cfg.transcriptSink != nil {
+						*cfg.transcriptSink = append(messages, acc.Message())
+					}
+					if code == 0 {
+						code = exportFinalTranscript(cfg, append(messages, acc.Message()), oaiTools, stderr)
+					}
+					if code == 0 {
+						code = finalVerdict(cfg, streamedFinal.String(), stderr)
+					}
+					if code == 0 {
+						code = golden.check(append(messages, acc.Message()), streamedFinal.String(), stderr)
+					}
+					// Deltas of one channel in a row make up one message
+					for i := 0; i < len(bufferedNonFinal); {
+						ch := bufferedNonFinal[i].channel
+						var text strings.Builder
+						for ; i < len(bufferedNonFinal) && bufferedNonFinal[i].channel == ch; i++ {
+							text.WriteString(bufferedNonFinal[i].content)
 						}
+						emitChannel(cfg, ch, true /*nonFinal*/, text.String(), stdout, stderr)
 					}
-					return 0
+					return code
 				}
 				// If not supported, fall through to non-streaming; otherwise treat as error
+				if ctx.Err() != nil {
+					return handleInterrupt(cfg, messages, step, stderr)
+				}
 				if !strings.Contains(strings.ToLower(streamErr.Error()), "does not support streaming") {
 					src := cfg.httpTimeoutSource
 					if src == "" {
 						src = "default"
 					}
 					safeFprintf(stderr, "error: chat call failed: %v (http-timeout source=%s)\n", streamErr, src)
-					return 1
+					return exitHTTPFailure
 				}
 				// Reset context for fallback after streaming attempt
-				callCtx, cancel = context.WithTimeout(context.Background(), cfg.httpTimeout)
+				callCtx, cancel = context.WithTimeout(ctx, cfg.httpTimeout)
 			} else {
 				cancel()
 				// Reset context for non-streaming path when streaming disabled
-				callCtx, cancel = context.WithTimeout(context.Background(), cfg.httpTimeout)
+				callCtx, cancel = context.WithTimeout(ctx, cfg.httpTimeout)
 			}
 
 			// Fallback: non-streaming request
-			resp, err := httpClient.CreateChatCompletion(callCtx, req)
+			resp := cached
+			var err error
+			if !cacheHit {
+				resp, err = httpClient.CreateChatCompletion(callCtx, req)
+			}
 			cancel()
 			if err != nil {
+				if ctx.Err() != nil {
+					return handleInterrupt(cfg, messages, step, stderr)
+				}
 				src := cfg.httpTimeoutSource
 				if src == "" {
 					src = "default"
 				}
 				safeFprintf(stderr, "error: chat call failed: %v (http-timeout source=%s)\n", err, src)
-				return 1
+				return exitHTTPFailure
+			}
+			if !cacheHit {
+				usage.add(resp.Usage)
+				cfg.events.emit(runEvent{Kind: eventUsage, Usage: usage})
 			}
 			if len(resp.Choices) == 0 {
 				safeFprintln(stderr, "error: chat response has no choices")
-				return 1
+				return exitHTTPFailure
 			}
 
 			choice := resp.Choices[0]
+			if !cacheHit {
+				chats.put(req, choice.Message, choice.FinishReason)
+			}
 
 			// Length backoff: one-time in-step retry doubling the completion cap (min 256)
 			if strings.TrimSpace(choice.FinishReason) == "length" && !retriedForLength {
@@ -310,8 +580,8 @@ func runAgent(cfg cliConfig, stdout io.Writer, stderr io.Writer) int {
 				}
 				// Clamp to remaining context window before resending
 				window := oai.ContextWindowForModel(cfg.model)
-				estimated := oai.EstimateTokens(messages)
-				completionCap = oai.ClampCompletionCap(messages, completionCap, window)
+				estimated := oai.CountTokens(cfg.model, messages)
+				completionCap = oai.ClampCompletionCap(cfg.model, messages, completionCap, window)
 				// Emit audit entry describing the backoff decision
 				oai.LogLengthBackoff(cfg.model, prev, completionCap, window, estimated)
 				retriedForLength = true
@@ -320,19 +590,39 @@ func runAgent(cfg cliConfig, stdout io.Writer, stderr io.Writer) int {
 			}
 
 			msg := choice.Message
-			// Under -verbose, if the assistant returns a non-final channel, print immediately respecting routing.
-			if cfg.verbose && msg.Role == oai.RoleAssistant {
-				ch := strings.TrimSpace(msg.Channel)
-				if ch != "final" && strings.TrimSpace(msg.Content) != "" {
-					route := resolveChannelRoute(cfg, ch, true /*nonFinal*/)
-					switch route {
-					case "stdout":
-						safeFprintln(stdout, strings.TrimSpace(msg.Content))
-					case "stderr":
-						safeFprintln(stderr, strings.TrimSpace(msg.Content))
-					case "omit":
-						// skip
-					}
+			if cfg.strategy == strategyReAct {
+				// ReAct: run the requested action as a text Observation turn, or
+				// continue below with the extracted Final Answer
+				final, turns, done := handleReActReply(ctx, msg, toolRegistry, cfg, step)
+				if !done {
+					messages = append(messages, turns...)
+					break
+				}
+				msg = final
+			} else {
+				var protoErr error
+				msg, protoErr = adoptProtocolToolCalls(cfg.toolProtocol, msg, toolRegistry, step)
+				if protoErr != nil {
+					// Keep the rejected reply and ask the model to correct it
+					msg.ToolCalls = nil
+					messages = append(messages, msg, oai.Message{Role: oai.RoleUser, Content: fmt.Sprintf("Your tool call was rejected: %v. Reply with valid ```tool blocks or with the final answer.", protoErr)})
+					break
+				}
+			}
+			// Plan-and-execute: record the plan or the current task's outcome;
+			// the task board is printed instead of the raw reply
+			if planner != nil && len(msg.ToolCalls) == 0 && strings.TrimSpace(msg.Content) != "" {
+				if turns, handled := planner.handleReply(msg, stdout, stderr); handled {
+					dumpJSONIfDebug(stderr, fmt.Sprintf("chat.response step=%d", step+1), resp, cfg.debug)
+					messages = append(messages, turns...)
+					break
+				}
+			}
+			// A non-final channel is delivered immediately per its route:
+			// printed under -verbose, written to file routes always
+			if msg.Role == oai.RoleAssistant {
+				if ch := strings.TrimSpace(msg.Channel); ch != "final" && ch != "" {
+					emitChannel(cfg, ch, true /*nonFinal*/, msg.Content, stdout, stderr)
 				}
 			}
 
@@ -342,7 +632,7 @@ func runAgent(cfg cliConfig, stdout io.Writer, stderr io.Writer) int {
 			// corresponding tool messages and continue the loop for the next turn.
 			if len(msg.ToolCalls) > 0 && len(toolRegistry) > 0 {
 				messages = append(messages, msg)
-				messages = appendToolCallOutputs(messages, msg, toolRegistry, cfg)
+				messages = appendToolCallOutputs(ctx, messages, msg, toolRegistry, cfg)
 				// Continue outer loop for another assistant response using appended tool outputs
 				break
 			}
@@ -352,19 +642,35 @@ func runAgent(cfg cliConfig, stdout io.Writer, stderr io.Writer) int {
 				// Respect channel-aware printing: only print channel=="final" to stdout by default.
 				ch := strings.TrimSpace(msg.Channel)
 				if ch == "final" || ch == "" {
-					// Determine destination per routing; default final->stdout
-					dest := resolveChannelRoute(cfg, "final", false /*nonFinal*/)
-					switch dest {
-					case "stdout":
-						safeFprintln(stdout, strings.TrimSpace(msg.Content))
-					case "stderr":
-						safeFprintln(stderr, strings.TrimSpace(msg.Content))
-					case "omit":
-						// do not print
+					if turns, revise := critic.review(ctx, msg, stdout, stderr); revise {
+						dumpJSONIfDebug(stderr, fmt.Sprintf("chat.response step=%d", step+1), resp, cfg.debug)
+						messages = append(messages, turns...)
+						break
+					}
+					// Determine destination per routing; default final->stdout.
+					// -output-file takes the answer instead of any stream.
+					code := 0
+					if cfg.outputFile != "" {
+						code = writeFinalOutput(cfg, msg.Content, stderr)
+					} else {
+						emitChannel(cfg, "final", false /*nonFinal*/, msg.Content, stdout, stderr)
 					}
 					// Dump debug response JSON after human-readable output, then exit
 					dumpJSONIfDebug(stderr, fmt.Sprintf("chat.response step=%d", step+1), resp, cfg.debug)
-					return 0
+					recorder.finish(append(messages, msg))
+					if cfg.transcriptSink != nil {
+						*cfg.transcriptSink = append(messages, msg)
+					}
+					if code == 0 {
+						code = exportFinalTranscript(cfg, append(messages, msg), oaiTools, stderr)
+					}
+					if code == 0 {
+						code = finalVerdict(cfg, msg.Content, stderr)
+					}
+					if code == 0 {
+						code = golden.check(append(messages, msg), msg.Content, stderr)
+					}
+					return code
 				} else {
 					// Non-final assistant message with content: do not print to stdout by default.
 					// (already printed above under -verbose)
@@ -385,9 +691,19 @@ func runAgent(cfg cliConfig, stdout io.Writer, stderr io.Writer) int {
 	// If we reach here, the loop ended without printing final content.
 	// Distinguish between generic termination and hitting the step cap.
 	if step >= effectiveMaxSteps {
-		safeFprintln(stderr, fmt.Sprintf("info: reached maximum steps (%d); needs human review", effectiveMaxSteps))
-	} else {
-		safeFprintln(stderr, "error: run ended without final assistant content")
+		safeFprintln(stderr, fmt.Sprintf("error: reached maximum steps (%d); needs human review", effectiveMaxSteps))
+		return exitStepCap
 	}
+	safeFprintln(stderr, "error: run ended without final assistant content")
 	return 1
 }
+
+// newChatClient builds the main-loop HTTP client with the retry policy,
+// -provider adapter, -chaos hooks, and -providers failover.
+func newChatClient(cfg cliConfig, stderr io.Writer) *oai.Client {
+	client := oai.NewClientWithRetry(cfg.baseURL, cfg.apiKey, cfg.httpTimeout, retryPolicyFor(cfg, cfg.httpRetries, cfg.httpBackoff)).WithLimits(limitsFor(cfg))
+	installProvider(cfg, client)
+	cfg.injector.install(client)
+	installProviders(cfg, client, stderr)
+	return client
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/tools"
+)
+
+// maxRunRequestBytes bounds one -batch line or `agentcli serve` request body.
+const maxRunRequestBytes = 4 << 20
+
+// runRequest is one prompt run in-process, as a -batch line or the body of
+// POST /runs. Only prompt is required; the other fields override the
+// command-line settings for that run.
+type runRequest struct {
+	ID          json.RawMessage `json:"id,omitempty"`
+	Prompt      string          `json:"prompt"`
+	System      string          `json:"system,omitempty"`
+	Developer   []string        `json:"developer,omitempty"`
+	Model       string          `json:"model,omitempty"`
+	Temperature *float64        `json:"temperature,omitempty"`
+	MaxSteps    int             `json:"max_steps,omitempty"`
+	Tools       *[]string       `json:"tools,omitempty"`
+
+	line int // -batch line number
+}
+
+// runResult is the outcome of one runRequest.
+type runResult struct {
+	Line             int             `json:"line,omitempty"`
+	ID               json.RawMessage `json:"id,omitempty"`
+	Model            string          `json:"model"`
+	ExitCode         int             `json:"exit_code"`
+	Reason           string          `json:"reason,omitempty"`
+	Output           string          `json:"output"`
+	Error            string          `json:"error,omitempty"`
+	Steps            int             `json:"steps"`
+	PromptTokens     int             `json:"prompt_tokens"`
+	CompletionTokens int             `json:"completion_tokens"`
+	TotalTokens      int             `json:"total_tokens"`
+	LatencyMS        int64           `json:"latency_ms"`
+}
+
+// decodeRunRequest parses and validates one request; topP is the -top-p the
+// run would inherit.
+func decodeRunRequest(data []byte, topP float64) (runRequest, error) {
+	var req runRequest
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		return runRequest{}, err
+	}
+	if err := req.validate(topP); err != nil {
+		return runRequest{}, err
+	}
+	return req, nil
+}
+
+// validate checks the request against the run settings it would inherit.
+func (req runRequest) validate(topP float64) error {
+	switch {
+	case strings.TrimSpace(req.Prompt) == "":
+		return fmt.Errorf("prompt is required")
+	case req.MaxSteps < 0:
+		return fmt.Errorf("max_steps must be >= 0")
+	case req.Temperature != nil && topP > 0:
+		return fmt.Errorf("temperature conflicts with -top-p")
+	}
+	return nil
+}
+
+// apply returns base with the request's prompt and overrides.
+func (req runRequest) apply(base cliConfig) cliConfig {
+	cfg := base
+	cfg.prompt, cfg.promptFile = req.Prompt, ""
+	if strings.TrimSpace(req.System) != "" {
+		cfg.systemPrompt, cfg.systemFile = req.System, ""
+	}
+	if req.Developer != nil {
+		cfg.developerPrompts, cfg.developerFiles = req.Developer, nil
+	}
+	if strings.TrimSpace(req.Model) != "" {
+		cfg.model = strings.TrimSpace(req.Model)
+		cfg.modelEscalate = nil
+	}
+	if req.Temperature != nil {
+		cfg.temperature = *req.Temperature
+	}
+	if req.MaxSteps > 0 {
+		cfg.maxSteps = req.MaxSteps
+	}
+	if req.Tools != nil {
+		cfg.toolAllowlist = *req.Tools
+	}
+	return cfg
+}
+
+// runInProcess runs req through runAgent with its output captured, passing
+// the run's events to events when set.
+func runInProcess(base cliConfig, req runRequest, events eventSink) runResult {
+	cfg := req.apply(base)
+	cfg.verbose = false
+	cfg.events = events
+	var usage runUsage
+	cfg.usageSink = &usage
+
+	var out, errb bytes.Buffer
+	errLines := &errorLineWriter{w: &errb}
+	start := time.Now()
+	code := runAgent(cfg, &out, errLines)
+	r := runResult{
+		Line:             req.line,
+		ID:               req.ID,
+		Model:            cfg.model,
+		ExitCode:         code,
+		Output:           strings.TrimSpace(out.String()),
+		Steps:            usage.calls,
+		PromptTokens:     usage.promptTokens,
+		CompletionTokens: usage.completionTokens,
+		TotalTokens:      usage.totalTokens,
+		LatencyMS:        time.Since(start).Milliseconds(),
+	}
+	if code != 0 {
+		r.Reason = nonEmptyOr(exitReasons[code], "error")
+		r.Error = nonEmptyOr(errLines.last, lastLine(errb.String()))
+	}
+	return r
+}
+
+// shareAcrossRuns returns cfg set up so the in-process runs of a -batch or
+// serve session share approvals, -chaos, and the HTTP clients (connections
+// and the retry breaker).
+func shareAcrossRuns(cfg cliConfig, stderr io.Writer) cliConfig {
+	if cfg.approver == nil {
+		cfg.approver = newToolApprover(cfg, stderr)
+	}
+	if cfg.injector == nil {
+		cfg.injector = newChaosInjector(cfg, stderr)
+	}
+	cfg.chatClient = newChatClient(cfg, stderr)
+	cfg.prepClient = newPrepClient(cfg)
+	return cfg
+}
+
+// editsWorkspace reports whether runs may edit the workspace (a -tools entry
+// mutates and -read-only is off), so they take its lock and must not overlap.
+func editsWorkspace(cfg cliConfig) bool {
+	if cfg.readOnly || strings.TrimSpace(cfg.toolsPath) == "" {
+		return false
+	}
+	registry, _, err := tools.LoadManifest(cfg.toolsPath)
+	return err == nil && tools.AnyMutates(registry)
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// shouldSimplifySchemas reports whether advertised tool schemas should be
+// flattened for the main model. "always"/"never" force the decision; "auto"
+// (and unset, for tests that construct cfg directly) compares the model's
+// estimated tier against -schema-min-tier (default medium).
+func shouldSimplifySchemas(cfg cliConfig) bool {
+	switch strings.ToLower(strings.TrimSpace(cfg.schemaSimplify)) {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	minTier, ok := oai.ParseModelTier(cfg.schemaMinTier)
+	if !ok {
+		minTier = oai.TierMedium
+	}
+	return oai.ModelTierFor(cfg.model) < minTier
+}
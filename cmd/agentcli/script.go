@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// scriptTurn is one user turn in a -script file.
+type scriptTurn struct {
+	Prompt         string    `json:"prompt"`
+	Tools          *[]string `json:"tools,omitempty"`
+	ExpectContains []string  `json:"expect_contains,omitempty"`
+	ExpectRegex    string    `json:"expect_regex,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// loadScript reads {"turns":[...]} and validates every turn up front so a
+// bad assertion fails before any model call.
+func loadScript(path string) ([]scriptTurn, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read -script: %w", err)
+	}
+	var script struct {
+		Turns []scriptTurn `json:"turns"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&script); err != nil {
+		return nil, fmt.Errorf("parse -script: %v", err)
+	}
+	if len(script.Turns) == 0 {
+		return nil, fmt.Errorf("-script has no turns")
+	}
+	for i := range script.Turns {
+		t := &script.Turns[i]
+		if strings.TrimSpace(t.Prompt) == "" {
+			return nil, fmt.Errorf("-script turn %d: prompt is required", i+1)
+		}
+		if t.ExpectRegex != "" {
+			if t.re, err = regexp.Compile(t.ExpectRegex); err != nil {
+				return nil, fmt.Errorf("-script turn %d: expect_regex: %v", i+1, err)
+			}
+		}
+	}
+	return script.Turns, nil
+}
+
+// runScript runs each scripted user turn as its own agent loop over one
+// growing transcript. Every turn's answer is printed as usual and checked
+// against its assertions; the first failing turn stops the script. Output
+// files, -export-jsonl, and -succeed-if/-fail-if apply to the last turn only,
+// which sees the whole conversation.
+func runScript(cfg cliConfig, stdout, stderr io.Writer) int {
+	turns, err := loadScript(cfg.scriptPath)
+	if err != nil {
+		safeFprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+	// One -tasks checklist spans every turn
+	if strings.TrimSpace(cfg.tasksPath) != "" {
+		if cfg.checklist, err = loadChecklist(strings.TrimSpace(cfg.tasksPath)); err != nil {
+			safeFprintf(stderr, "error: %v\n", err)
+			return 2
+		}
+	}
+	var transcript []oai.Message
+	for i, turn := range turns {
+		tc := cfg
+		if i == 0 {
+			tc.prompt = turn.Prompt
+		} else {
+			// Later turns continue the transcript; the pre-stage ran once already
+			tc.initMessages = append(append([]oai.Message(nil), transcript...), oai.Message{Role: oai.RoleUser, Content: turn.Prompt})
+			tc.saveMessagesPath = ""
+		}
+		if turn.Tools != nil {
+			tc.toolAllowlist = *turn.Tools
+		}
+		if i < len(turns)-1 {
+			tc.outputFile, tc.outputAppend, tc.ifEmptyFail = "", false, false
+			tc.exportJSONL = ""
+			tc.succeedIfRe, tc.failIfRe = nil, nil
+			tc.goldenPath = ""
+		}
+		tc.transcriptSink = &transcript
+		if code := runAgent(tc, stdout, stderr); code != 0 {
+			safeFprintf(stderr, "error: -script stopped at turn %d of %d\n", i+1, len(turns))
+			return code
+		}
+		answer := ""
+		if n := len(transcript); n > 0 {
+			answer = strings.TrimSpace(transcript[n-1].Content)
+		}
+		if err := checkExpectations(answer, turn.ExpectContains, turn.re); err != nil {
+			safeFprintf(stderr, "error: -script turn %d: %v\n", i+1, err)
+			return exitSucceedIfMissed
+		}
+	}
+	return 0
+}
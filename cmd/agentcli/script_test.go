@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+func writeScript(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "script.json")
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// echoTurnServer answers every request with "re: <last user prompt>" and
+// records the requests.
+func echoTurnServer(t *testing.T) (*httptest.Server, *[]oai.ChatCompletionsRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var reqs []oai.ChatCompletionsRequest
+	srv := fakeChatServer(t, nil, func(req oai.ChatCompletionsRequest) (oai.ChatCompletionsResponse, error) {
+		mu.Lock()
+		reqs = append(reqs, req)
+		mu.Unlock()
+		return answer(finalMessage("re: " + req.Messages[len(req.Messages)-1].Content))
+	})
+	return srv, &reqs
+}
+
+func TestCLIMain_Script_RunsTurnsInOneTranscript(t *testing.T) {
+	toolsPath := writeEchoOKTool(t)
+	srv, reqs := echoTurnServer(t)
+	script := writeScript(t, `{"turns":[
+		{"prompt":"one","expect_contains":["re: one"]},
+		{"prompt":"two","tools":[],"expect_regex":"^re: two$"}
+	]}`)
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-script", script, "-tools", toolsPath, "-base-url", srv.URL, "-model", "m", "-prep-enabled=false"}, &out, &errb)
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if out.String() != "re: one\nre: two\n" {
+		t.Fatalf("stdout=%q", out.String())
+	}
+	if len(*reqs) != 2 {
+		t.Fatalf("requests=%d", len(*reqs))
+	}
+	first, second := (*reqs)[0], (*reqs)[1]
+	if len(first.Tools) != 1 || len(second.Tools) != 0 {
+		t.Fatalf("per-turn tools: first=%d second=%d", len(first.Tools), len(second.Tools))
+	}
+	var roles []string
+	for _, m := range second.Messages {
+		roles = append(roles, m.Role+":"+m.Content)
+	}
+	if got := strings.Join(roles[1:], ","); got != "user:one,assistant:re: one,user:two" {
+		t.Fatalf("second turn transcript: %s", got)
+	}
+}
+
+func TestCLIMain_Script_AssertionFailureStops(t *testing.T) {
+	srv, reqs := echoTurnServer(t)
+	script := writeScript(t, `{"turns":[{"prompt":"one","expect_contains":["PASS"]},{"prompt":"two"}]}`)
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-script", script, "-base-url", srv.URL, "-model", "m", "-prep-enabled=false"}, &out, &errb)
+	if code != exitSucceedIfMissed || !strings.Contains(errb.String(), `turn 1: expect_contains "PASS" not found`) || len(*reqs) != 1 {
+		t.Fatalf("exit=%d requests=%d stderr=%s", code, len(*reqs), errb.String())
+	}
+}
+
+func TestCLIMain_Script_Misuse(t *testing.T) {
+	cases := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"with prompt", []string{"-script", "s.json", "-prompt", "p"}, "-script cannot be combined"},
+		{"no turns", []string{"-script", writeScript(t, `{"turns":[]}`)}, "-script has no turns"},
+		{"unknown field", []string{"-script", writeScript(t, `{"turns":[{"prompt":"p","expect":"x"}]}`)}, "unknown field"},
+		{"bad regex", []string{"-script", writeScript(t, `{"turns":[{"prompt":"p","expect_regex":"("}]}`)}, "turn 1: expect_regex"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out, errb bytes.Buffer
+			if code := cliMain(append(tc.args, "-prep-enabled=false"), &out, &errb); code != 2 || !strings.Contains(errb.String(), tc.want) {
+				t.Fatalf("exit=%d stderr=%s", code, errb.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"flag"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/clock"
+	"google.golang.org/grpc"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+	"github.com/hyperifyio/goagent/internal/server"
+	"github.com/hyperifyio/goagent/internal/state"
+	"github.com/hyperifyio/goagent/internal/tools"
+)
+
+// serveFlagNames are consumed by serve itself; all other flags configure
+// every run the server starts.
+var serveFlagNames = map[string]bool{"listen": true, "grpc-listen": true, "token": true, "max-runs": true}
+
+// maxServeRuns bounds how many runs the server remembers; the oldest
+// finished runs are forgotten first.
+const maxServeRuns = 1000
+
+// Run states reported by GET /runs/{id}.
+const (
+	serveRunning   = "running"
+	serveSucceeded = "succeeded"
+	serveFailed    = "failed"
+)
+
+// serveEvent is one server-sent event of a run. Every event carries the
+// step it happened in.
+type serveEvent struct {
+	kind             string
+	Step             int    `json:"step"`
+	MaxSteps         int    `json:"max_steps,omitempty"`
+	Tool             string `json:"tool,omitempty"`
+	CallID           string `json:"call_id,omitempty"`
+	Failed           bool   `json:"failed,omitempty"`
+	PromptTokens     int    `json:"prompt_tokens,omitempty"`
+	CompletionTokens int    `json:"completion_tokens,omitempty"`
+	TotalTokens      int    `json:"total_tokens,omitempty"`
+}
+
+// serveRun is one run started by POST /runs.
+type serveRun struct {
+	id      string
+	created time.Time
+
+	mu      sync.Mutex
+	step    int
+	events  []serveEvent
+	changed chan struct{} // closed and replaced whenever events or result change
+	result  *runResult
+}
+
+// serveRunStatus is the JSON body of POST /runs and GET /runs/{id}.
+type serveRunStatus struct {
+	ID      string     `json:"id"`
+	Status  string     `json:"status"`
+	Created string     `json:"created"`
+	Step    int        `json:"step"`
+	Result  *runResult `json:"result,omitempty"`
+}
+
+func (r *serveRun) record(ev runEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if ev.Kind == eventStep {
+		r.step = ev.Step
+	}
+	e := serveEvent{kind: ev.Kind, Step: r.step, Tool: ev.Tool, CallID: ev.CallID, Failed: ev.Failed}
+	switch ev.Kind {
+	case eventStep:
+		e.MaxSteps = ev.MaxSteps
+	case eventUsage:
+		e.PromptTokens, e.CompletionTokens, e.TotalTokens = ev.Usage.promptTokens, ev.Usage.completionTokens, ev.Usage.totalTokens
+	}
+	r.events = append(r.events, e)
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+func (r *serveRun) finish(res runResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.result = &res
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+func (r *serveRun) status() serveRunStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	st := serveRunStatus{ID: r.id, Status: serveRunning, Created: r.created.UTC().Format(time.RFC3339), Step: r.step, Result: r.result}
+	if r.result != nil {
+		st.Status = serveSucceeded
+		if r.result.ExitCode != 0 {
+			st.Status = serveFailed
+		}
+	}
+	return st
+}
+
+// runServer implements the agent HTTP API over in-process runs of base.
+type runServer struct {
+	base  cliConfig
+	token string
+	slots chan struct{}
+	// wg tracks runs still working so shutdown can wait for them
+	wg sync.WaitGroup
+
+	mu    sync.Mutex
+	runs  map[string]*serveRun
+	order []string
+}
+
+func newRunServer(base cliConfig, token string, maxRuns int) *runServer {
+	return &runServer{base: base, token: token, slots: make(chan struct{}, maxRuns), runs: map[string]*serveRun{}}
+}
+
+func (s *runServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /runs", s.createRun)
+	mux.HandleFunc("GET /runs/{id}", s.getRun)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+s.token)) != 1 {
+			writeServeError(w, http.StatusUnauthorized, "missing or wrong bearer token")
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// createRun starts a run and answers 202 with its status; 429 when
+// -max-runs runs are already working.
+func (s *runServer) createRun(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRunRequestBytes))
+	if err != nil {
+		writeServeError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
+	req, err := decodeRunRequest(body, s.base.topP)
+	if err != nil {
+		writeServeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		writeServeError(w, http.StatusTooManyRequests, "all run slots are busy; retry later")
+		return
+	}
+	run := s.add()
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() { <-s.slots }()
+		run.finish(runInProcess(s.base, req, run.record))
+	}()
+	w.Header().Set("Location", "/runs/"+run.id)
+	writeServeJSON(w, http.StatusAccepted, run.status())
+}
+
+// getRun answers with the run's status, or streams its events as
+// server-sent events when the client accepts text/event-stream.
+func (s *runServer) getRun(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	run := s.runs[r.PathValue("id")]
+	s.mu.Unlock()
+	if run == nil {
+		writeServeError(w, http.StatusNotFound, "no such run")
+		return
+	}
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		writeServeJSON(w, http.StatusOK, run.status())
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeServeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	// Replay the events so far, then follow the run until it finishes
+	for sent := 0; ; {
+		run.mu.Lock()
+		events := append([]serveEvent(nil), run.events[sent:]...)
+		finished := run.result != nil
+		changed := run.changed
+		run.mu.Unlock()
+		for _, ev := range events {
+			writeSSE(w, ev.kind, ev)
+		}
+		sent += len(events)
+		if finished {
+			writeSSE(w, "done", run.status())
+			flusher.Flush()
+			return
+		}
+		flusher.Flush()
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// add registers a new run, forgetting the oldest finished runs beyond
+// maxServeRuns.
+func (s *runServer) add() *serveRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := state.NewRunID()
+	for s.runs[id] != nil {
+		id = state.NewRunID()
+	}
+	run := &serveRun{id: id, created: clock.Now(), changed: make(chan struct{})}
+	s.runs[id] = run
+	s.order = append(s.order, id)
+	for i := 0; len(s.runs) > maxServeRuns && i < len(s.order); {
+		old := s.runs[s.order[i]]
+		if old.status().Status == serveRunning {
+			i++
+			continue
+		}
+		delete(s.runs, s.order[i])
+		s.order = append(s.order[:i], s.order[i+1:]...)
+	}
+	return run
+}
+
+func writeServeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v) //nolint:errcheck
+}
+
+func writeServeError(w http.ResponseWriter, code int, msg string) {
+	writeServeJSON(w, code, map[string]string{"error": msg})
+}
+
+func writeSSE(w io.Writer, event string, v any) {
+	b, _ := json.Marshal(v) //nolint:errcheck
+	safeFprintf(w, "event: %s\ndata: %s\n\n", event, b)
+}
+
+// runServe implements `agentcli serve`: an HTTP API, and with -grpc-listen
+// the gRPC AgentService, that start agent runs in-process with the other
+// flags as their configuration, until SIGINT or SIGTERM. Both share the
+// -max-runs slots. Runs still working at shutdown are canceled like an
+// interrupted run.
+func runServe(args []string, stdout, stderr io.Writer) int {
+	serveArgs, agentArgs := splitFlagArgs(args, serveFlagNames)
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	listen := fs.String("listen", "127.0.0.1:8080", "")
+	grpcListen := fs.String("grpc-listen", "", "")
+	token := fs.String("token", os.Getenv("AGENTCLI_SERVE_TOKEN"), "")
+	maxRuns := fs.Int("max-runs", 4, "")
+	if err := fs.Parse(serveArgs); err != nil {
+		safeFprintf(stderr, "error: serve: %v\n", err)
+		return 2
+	}
+	if *maxRuns < 1 {
+		safeFprintln(stderr, "error: -max-runs must be >= 1")
+		return 2
+	}
+
+	// Resolve shared run settings; prompts come from the requests
+	origArgs := os.Args
+	os.Args = append([]string{origArgs[0], "-prompt", "serve"}, agentArgs...)
+	cfg, code := parseFlags()
+	os.Args = origArgs
+	if code != 0 {
+		safeFprintln(stderr, cfg.parseError)
+		return code
+	}
+	if cfg.batchPath != "" || cfg.scriptPath != "" || cfg.outputFile != "" || strings.TrimSpace(cfg.saveMessagesPath) != "" || cfg.goldenPath != "" || cfg.tui {
+		safeFprintln(stderr, "error: serve takes prompts from POST /runs; -batch, -script, -output-file, -save-messages, -golden, and -tui do not apply")
+		return 2
+	}
+	if cfg.deterministic {
+		clock.SetDeterministic(int64(cfg.seed))
+	}
+	if err := oai.ConfigureSharedTransport(transportOptionsFor(cfg)); err != nil {
+		safeFprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+	tools.SetKillGrace(cfg.toolGrace)
+	tools.SetWorkspace(cfg.workspace, cfg.workspaceRootDirs)
+	stopMetrics, ok := startMetricsServer(cfg, stderr)
+	if !ok {
+		return 2
+	}
+	defer stopMetrics()
+	defer startTracing(stderr)()
+
+	if *maxRuns > 1 && editsWorkspace(cfg) {
+		safeFprintln(stderr, "info: -tools can edit the workspace; running one run at a time (use -read-only to run them concurrently)")
+		*maxRuns = 1
+	}
+	srv := newRunServer(shareAcrossRuns(cfg, stderr), strings.TrimSpace(*token), *maxRuns)
+
+	ln, ok := serveListen(*listen, "-listen", srv.token, stderr)
+	if !ok {
+		return 2
+	}
+	safeFprintf(stdout, "serving agent API on http://%s\n", ln.Addr())
+	errc := make(chan error, 2)
+	var grpcSrv *grpc.Server
+	if strings.TrimSpace(*grpcListen) != "" {
+		gln, ok := serveListen(*grpcListen, "-grpc-listen", srv.token, stderr)
+		if !ok {
+			_ = ln.Close() //nolint:errcheck
+			return 2
+		}
+		grpcSrv = server.NewGRPC(server.New(grpcRunner{base: srv.base}, srv.slots, srv.token))
+		safeFprintf(stdout, "serving gRPC AgentService on %s\n", gln.Addr())
+		go func() { errc <- grpcSrv.Serve(gln) }()
+	}
+
+	ctx, stop := agentSignalContext()
+	defer stop()
+	httpSrv := &http.Server{Handler: srv.handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() { errc <- httpSrv.Serve(ln) }()
+	code = 0
+	select {
+	case err := <-errc:
+		safeFprintf(stderr, "error: serve: %v\n", err)
+		code = 1
+	case <-ctx.Done():
+		safeFprintln(stderr, "info: shutting down")
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = httpSrv.Shutdown(shutdownCtx) //nolint:errcheck // best-effort; runs are waited for below
+	if grpcSrv != nil {
+		// Stop cancels the calls, and with them their runs
+		grpcSrv.Stop()
+	}
+	srv.wg.Wait()
+	return code
+}
+
+// serveListen binds addr for flag, warning when it is reachable from other
+// hosts without a token.
+func serveListen(addr, flag, token string, stderr io.Writer) (net.Listener, bool) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		safeFprintf(stderr, "error: %s: %v\n", flag, err)
+		return nil, false
+	}
+	if host, _, _ := net.SplitHostPort(ln.Addr().String()); token == "" && !net.ParseIP(host).IsLoopback() {
+		safeFprintf(stderr, "WARN: serving on %s without -token; anyone who can reach it can run the agent and its tools\n", ln.Addr())
+	}
+	return ln, true
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+
+	"github.com/hyperifyio/goagent/internal/server/agentpb"
+)
+
+// grpcRunner runs the AgentService requests of `agentcli serve -grpc-listen`
+// in-process with the serve settings, like POST /runs.
+type grpcRunner struct {
+	base cliConfig
+}
+
+func (g grpcRunner) Check(pb *agentpb.RunRequest) error {
+	return runRequestFromProto(pb).validate(g.base.topP)
+}
+
+func (g grpcRunner) Run(ctx context.Context, pb *agentpb.RunRequest, events func(*agentpb.RunEvent)) *agentpb.RunResult {
+	cfg := g.base
+	cfg.parentCtx = ctx
+	r := runInProcess(cfg, runRequestFromProto(pb), func(ev runEvent) { events(runEventToProto(ev)) })
+	return &agentpb.RunResult{
+		Model:            r.Model,
+		ExitCode:         int32(r.ExitCode),
+		Reason:           r.Reason,
+		Error:            r.Error,
+		Output:           r.Output,
+		Steps:            int32(r.Steps),
+		PromptTokens:     int64(r.PromptTokens),
+		CompletionTokens: int64(r.CompletionTokens),
+		TotalTokens:      int64(r.TotalTokens),
+		LatencyMs:        r.LatencyMS,
+	}
+}
+
+func runRequestFromProto(pb *agentpb.RunRequest) runRequest {
+	req := runRequest{
+		Prompt:      pb.GetPrompt(),
+		System:      pb.GetSystem(),
+		Developer:   pb.GetDeveloper(),
+		Model:       pb.GetModel(),
+		Temperature: pb.Temperature,
+		MaxSteps:    int(pb.GetMaxSteps()),
+	}
+	if pb.GetTools() != nil {
+		names := append([]string{}, pb.GetTools().GetNames()...)
+		req.Tools = &names
+	}
+	return req
+}
+
+var protoEventKinds = map[string]agentpb.EventKind{
+	eventStep:      agentpb.EventKind_EVENT_KIND_STEP,
+	eventUsage:     agentpb.EventKind_EVENT_KIND_USAGE,
+	eventToolStart: agentpb.EventKind_EVENT_KIND_TOOL_START,
+	eventToolEnd:   agentpb.EventKind_EVENT_KIND_TOOL_END,
+}
+
+func runEventToProto(ev runEvent) *agentpb.RunEvent {
+	return &agentpb.RunEvent{
+		Kind:             protoEventKinds[ev.Kind],
+		Step:             int32(ev.Step),
+		MaxSteps:         int32(ev.MaxSteps),
+		Tool:             ev.Tool,
+		CallId:           ev.CallID,
+		Failed:           ev.Failed,
+		PromptTokens:     int64(ev.Usage.promptTokens),
+		CompletionTokens: int64(ev.Usage.completionTokens),
+		TotalTokens:      int64(ev.Usage.totalTokens),
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/server/agentpb"
+)
+
+func newTestRunServer(t *testing.T, token string, maxRuns int) *httptest.Server {
+	t.Helper()
+	var calls atomic.Int32
+	chat := batchServer(t, &calls)
+	t.Cleanup(chat.Close)
+	base := cliConfig{baseURL: chat.URL, model: "m", maxSteps: 4, prepEnabled: false, httpTimeout: 5 * time.Second, prepHTTPTimeout: 5 * time.Second}
+	srv := httptest.NewServer(newRunServer(shareAcrossRuns(base, io.Discard), token, maxRuns).handler())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func postRun(t *testing.T, url, body, token string) (*http.Response, serveRunStatus) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url+"/runs", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	var st serveRunStatus
+	_ = json.NewDecoder(resp.Body).Decode(&st) //nolint:errcheck
+	return resp, st
+}
+
+// waitRun polls GET /runs/{id} until the run is no longer running.
+func waitRun(t *testing.T, url, id string) serveRunStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		r, err := http.Get(url + "/runs/" + id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var st serveRunStatus
+		_ = json.NewDecoder(r.Body).Decode(&st) //nolint:errcheck
+		_ = r.Body.Close()                      //nolint:errcheck
+		if st.Status != serveRunning {
+			return st
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("run %s still running", id)
+	return serveRunStatus{}
+}
+
+func TestServe_RunAndPoll(t *testing.T) {
+	srv := newTestRunServer(t, "", 2)
+	resp, st := postRun(t, srv.URL, `{"id":"q1","prompt":"hello","model":"other"}`, "")
+	if resp.StatusCode != http.StatusAccepted || st.ID == "" || st.Status != serveRunning || resp.Header.Get("Location") != "/runs/"+st.ID {
+		t.Fatalf("status=%d body=%+v", resp.StatusCode, st)
+	}
+	st = waitRun(t, srv.URL, st.ID)
+	if st.Status != serveSucceeded || st.Result == nil || st.Result.Output != "other:hello" || string(st.Result.ID) != `"q1"` || st.Result.TotalTokens != 4 || st.Step != 1 {
+		t.Fatalf("final status: %+v result=%+v", st, st.Result)
+	}
+
+	_, st = postRun(t, srv.URL, `{"prompt":"fail"}`, "")
+	st = waitRun(t, srv.URL, st.ID)
+	if st.Status != serveFailed || st.Result.ExitCode != exitHTTPFailure || st.Result.Reason != "http" {
+		t.Fatalf("failed run: %+v result=%+v", st, st.Result)
+	}
+}
+
+func TestServe_StreamsEvents(t *testing.T) {
+	srv := newTestRunServer(t, "", 1)
+	_, st := postRun(t, srv.URL, `{"prompt":"slow"}`, "")
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/runs/"+st.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content-type=%q", ct)
+	}
+	var events []string
+	var done serveRunStatus
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		line := sc.Text()
+		if name, ok := strings.CutPrefix(line, "event: "); ok {
+			events = append(events, name)
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok && events[len(events)-1] == "done" {
+			if err := json.Unmarshal([]byte(data), &done); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if strings.Join(events, ",") != "step,usage,done" {
+		t.Fatalf("events=%v", events)
+	}
+	if done.Status != serveSucceeded || done.Result == nil || done.Result.Output != "m:slow" {
+		t.Fatalf("done=%+v", done)
+	}
+}
+
+func TestServe_Errors(t *testing.T) {
+	srv := newTestRunServer(t, "s3cret", 1)
+	if resp, _ := postRun(t, srv.URL, `{"prompt":"x"}`, ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("no token: status=%d", resp.StatusCode)
+	}
+	if resp, _ := postRun(t, srv.URL, `{"prompt":"x"}`, "wrong"); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("wrong token: status=%d", resp.StatusCode)
+	}
+	for _, body := range []string{`{"prompt":""}`, `{"prompt":"x","extra":1}`, `not json`} {
+		if resp, _ := postRun(t, srv.URL, body, "s3cret"); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: status=%d", body, resp.StatusCode)
+		}
+	}
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/runs/nope", nil) //nolint:errcheck
+	req.Header.Set("Authorization", "Bearer s3cret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown run: status=%d", resp.StatusCode)
+	}
+	// The only slot is taken by the slow run
+	if resp, _ := postRun(t, srv.URL, `{"prompt":"slow"}`, "s3cret"); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("slow: status=%d", resp.StatusCode)
+	}
+	if resp, _ := postRun(t, srv.URL, `{"prompt":"x"}`, "s3cret"); resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("busy: status=%d", resp.StatusCode)
+	}
+}
+
+func TestGRPCRunner(t *testing.T) {
+	var calls atomic.Int32
+	chat := batchServer(t, &calls)
+	defer chat.Close()
+	runner := grpcRunner{base: shareAcrossRuns(cliConfig{baseURL: chat.URL, model: "m", maxSteps: 4, httpTimeout: 5 * time.Second}, io.Discard)}
+	req := &agentpb.RunRequest{Prompt: "hi", Model: "other", Tools: &agentpb.ToolList{}}
+	if err := runner.Check(req); err != nil {
+		t.Fatal(err)
+	}
+	var kinds []agentpb.EventKind
+	res := runner.Run(context.Background(), req, func(ev *agentpb.RunEvent) { kinds = append(kinds, ev.GetKind()) })
+	if res.GetOutput() != "other:hi" || res.GetExitCode() != 0 || res.GetTotalTokens() != 4 || res.GetSteps() != 1 {
+		t.Fatalf("result=%v", res)
+	}
+	if len(kinds) != 2 || kinds[0] != agentpb.EventKind_EVENT_KIND_STEP || kinds[1] != agentpb.EventKind_EVENT_KIND_USAGE {
+		t.Fatalf("events=%v", kinds)
+	}
+	if err := runner.Check(&agentpb.RunRequest{Prompt: "hi", MaxSteps: -1}); err == nil {
+		t.Fatal("negative max_steps accepted")
+	}
+
+	// A canceled call interrupts the run
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if res := runner.Run(ctx, req, func(*agentpb.RunEvent) {}); res.GetExitCode() != exitInterrupted {
+		t.Fatalf("canceled: %v", res)
+	}
+}
+
+func TestCLI_ServeFlagErrors(t *testing.T) {
+	for want, args := range map[string][]string{
+		"-max-runs must be >= 1":        {"serve", "-max-runs", "0"},
+		"serve takes prompts from POST": {"serve", "-output-file", "x.txt"},
+	} {
+		var out, errb strings.Builder
+		if code := cliMain(args, &out, &errb); code != 2 || !strings.Contains(errb.String(), want) {
+			t.Errorf("%v: exit=%d stderr=%s", args, code, errb.String())
+		}
+	}
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const snapshotUsage = "error: usage: agentcli snapshot create -out <file> [-repo dir] [-state-dir dir] | agentcli snapshot restore <file> [-repo dir] [-state-dir dir] [-force]"
+
+// snapshotManifest describes a workspace snapshot. The archive also holds
+// worktree.patch (git diff --binary HEAD), files/<path> for untracked files,
+// and state/<path> for the contents of -state-dir.
+type snapshotManifest struct {
+	Version   string    `json:"version"`
+	Created   time.Time `json:"created"`
+	Head      string    `json:"head"`
+	Branch    string    `json:"branch,omitempty"`
+	Untracked []string  `json:"untracked"`
+	State     []string  `json:"state"`
+}
+
+// snapshotSummary is printed to stdout by both subcommands.
+type snapshotSummary struct {
+	Snapshot   string `json:"snapshot"`
+	Head       string `json:"head"`
+	PatchBytes int    `json:"patchBytes"`
+	Untracked  int    `json:"untracked"`
+	StateFiles int    `json:"stateFiles"`
+}
+
+// runSnapshot implements `agentcli snapshot create|restore`: it captures the
+// tracked-file changes against HEAD, the untracked files, and the state
+// directory as one tar.gz, and puts them back on a clean checkout of the
+// same commit.
+func runSnapshot(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || (args[0] != "create" && args[0] != "restore") {
+		safeFprintln(stderr, snapshotUsage)
+		return 2
+	}
+	set := flag.NewFlagSet("snapshot "+args[0], flag.ContinueOnError)
+	set.SetOutput(io.Discard)
+	repo := set.String("repo", ".", "")
+	stateDir := set.String("state-dir", getEnv("AGENTCLI_STATE_DIR", ""), "")
+	out := set.String("out", "", "")
+	force := set.Bool("force", false, "")
+	// Accept the snapshot path before or after the flags
+	var files []string
+	rest := args[1:]
+	for {
+		if err := set.Parse(rest); err != nil {
+			safeFprintf(stderr, "error: snapshot %s: %v\n", args[0], err)
+			return 2
+		}
+		if set.NArg() == 0 {
+			break
+		}
+		files = append(files, set.Arg(0))
+		rest = set.Args()[1:]
+	}
+	create := args[0] == "create"
+	if create && (len(files) != 0 || strings.TrimSpace(*out) == "" || *force) || !create && (len(files) != 1 || *out != "") {
+		safeFprintln(stderr, snapshotUsage)
+		return 2
+	}
+	root, err := gitOutput(*repo, nil, "rev-parse", "--show-toplevel")
+	if err != nil {
+		safeFprintf(stderr, "error: %s is not a git work tree: %v\n", *repo, err)
+		return 1
+	}
+	root = strings.TrimSpace(root)
+	var sum snapshotSummary
+	if create {
+		sum, err = createSnapshot(root, *stateDir, *out)
+	} else {
+		sum, err = restoreSnapshot(root, *stateDir, files[0], *force, stderr)
+	}
+	if err != nil {
+		safeFprintf(stderr, "error: snapshot %s: %v\n", args[0], err)
+		return 1
+	}
+	data, err := json.Marshal(sum)
+	if err != nil {
+		safeFprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	safeFprintln(stdout, string(data))
+	return 0
+}
+
+func createSnapshot(root, stateDir, out string) (snapshotSummary, error) {
+	head, err := gitOutput(root, nil, "rev-parse", "--verify", "HEAD")
+	if err != nil {
+		return snapshotSummary{}, fmt.Errorf("the repository needs a commit to anchor the snapshot: %w", err)
+	}
+	m := snapshotManifest{Version: "1", Created: time.Now().UTC(), Head: strings.TrimSpace(head), Untracked: []string{}, State: []string{}}
+	if branch, err := gitOutput(root, nil, "symbolic-ref", "--short", "-q", "HEAD"); err == nil {
+		m.Branch = strings.TrimSpace(branch)
+	}
+	patch, err := gitOutput(root, nil, "diff", "--binary", "HEAD")
+	if err != nil {
+		return snapshotSummary{}, err
+	}
+	listed, err := gitOutput(root, nil, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return snapshotSummary{}, err
+	}
+
+	// Keep the snapshot itself and the state directory out of files/
+	skip := map[string]bool{}
+	for _, p := range []string{out, stateDir} {
+		if p == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(p); err == nil {
+			skip[abs] = true
+		}
+	}
+	skipped := func(abs string) bool {
+		for p := range skip {
+			if abs == p || strings.HasPrefix(abs, p+string(filepath.Separator)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	if err := tarAddBytes(tw, "worktree.patch", []byte(patch)); err != nil {
+		return snapshotSummary{}, err
+	}
+	for _, rel := range strings.Split(strings.TrimRight(listed, "\x00"), "\x00") {
+		if rel == "" {
+			continue
+		}
+		abs := filepath.Join(root, filepath.FromSlash(rel))
+		if skipped(abs) {
+			continue
+		}
+		added, err := tarAddPath(tw, "files/"+rel, abs)
+		if err != nil {
+			return snapshotSummary{}, err
+		}
+		if added {
+			m.Untracked = append(m.Untracked, rel)
+		}
+	}
+	if stateDir != "" {
+		err := filepath.WalkDir(stateDir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(stateDir, p)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			added, err := tarAddPath(tw, "state/"+rel, p)
+			if added {
+				m.State = append(m.State, rel)
+			}
+			return err
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return snapshotSummary{}, fmt.Errorf("read state dir: %w", err)
+		}
+	}
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return snapshotSummary{}, err
+	}
+	if err := tarAddBytes(tw, "manifest.json", manifest); err != nil {
+		return snapshotSummary{}, err
+	}
+	if err := tw.Close(); err != nil {
+		return snapshotSummary{}, err
+	}
+	if err := zw.Close(); err != nil {
+		return snapshotSummary{}, err
+	}
+	// Transcripts may hold sensitive content; keep the archive private
+	if err := writeFileAtomic(out, buf.Bytes(), 0o600); err != nil {
+		return snapshotSummary{}, fmt.Errorf("write %s: %w", out, err)
+	}
+	return snapshotSummary{Snapshot: out, Head: m.Head, PatchBytes: len(patch), Untracked: len(m.Untracked), StateFiles: len(m.State)}, nil
+}
+
+func restoreSnapshot(root, stateDir, file string, force bool, stderr io.Writer) (snapshotSummary, error) {
+	m, patch, entries, err := readSnapshot(file)
+	if err != nil {
+		return snapshotSummary{}, err
+	}
+	head, err := gitOutput(root, nil, "rev-parse", "--verify", "HEAD")
+	if err != nil || strings.TrimSpace(head) != m.Head {
+		return snapshotSummary{}, fmt.Errorf("snapshot was taken at %s; run git checkout %s first", m.Head, m.Head)
+	}
+	status, err := gitOutput(root, nil, "status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return snapshotSummary{}, err
+	}
+	if strings.TrimSpace(status) != "" {
+		if !force {
+			return snapshotSummary{}, errors.New("tracked files have uncommitted changes; commit or stash them, or pass -force to discard them")
+		}
+		if _, err := gitOutput(root, nil, "reset", "--hard", "-q"); err != nil {
+			return snapshotSummary{}, err
+		}
+	}
+	if len(patch) > 0 {
+		if _, err := gitOutput(root, patch, "apply", "--binary", "--whitespace=nowarn"); err != nil {
+			return snapshotSummary{}, fmt.Errorf("apply worktree.patch: %w", err)
+		}
+	}
+	// Write symlinks last so no archived file lands behind an archived link
+	sort.SliceStable(entries, func(i, j int) bool { return !entries[i].isLink && entries[j].isLink })
+	var untracked, stateFiles int
+	for _, e := range entries {
+		var dest string
+		switch {
+		case strings.HasPrefix(e.name, "files/"):
+			dest = filepath.Join(root, filepath.FromSlash(strings.TrimPrefix(e.name, "files/")))
+			untracked++
+		case strings.HasPrefix(e.name, "state/"):
+			if stateDir == "" {
+				continue
+			}
+			dest = filepath.Join(stateDir, filepath.FromSlash(strings.TrimPrefix(e.name, "state/")))
+			stateFiles++
+		default:
+			continue
+		}
+		if err := e.write(dest, strings.HasPrefix(e.name, "state/")); err != nil {
+			return snapshotSummary{}, err
+		}
+	}
+	if stateDir == "" && len(m.State) > 0 {
+		safeFprintf(stderr, "WARN: snapshot holds %d state files; pass -state-dir to restore them\n", len(m.State))
+	}
+	return snapshotSummary{Snapshot: file, Head: m.Head, PatchBytes: len(patch), Untracked: untracked, StateFiles: stateFiles}, nil
+}
+
+// snapshotEntry is a file or symlink read from a snapshot archive.
+type snapshotEntry struct {
+	name   string
+	mode   os.FileMode
+	link   string
+	data   []byte
+	isLink bool
+}
+
+// write creates the entry at dest, replacing what is there. State files are
+// kept private like the state directory itself.
+func (e snapshotEntry) write(dest string, private bool) error {
+	dirMode, mode := os.FileMode(0o755), e.mode.Perm()
+	if private {
+		dirMode, mode = 0o700, 0o600
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), dirMode); err != nil {
+		return err
+	}
+	if e.isLink {
+		if err := os.Remove(dest); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return os.Symlink(e.link, dest)
+	}
+	return writeFileAtomic(dest, e.data, mode)
+}
+
+func readSnapshot(file string) (snapshotManifest, []byte, []snapshotEntry, error) {
+	var m snapshotManifest
+	f, err := os.Open(file)
+	if err != nil {
+		return m, nil, nil, err
+	}
+	defer func() { _ = f.Close() }() //nolint:errcheck // read-only
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return m, nil, nil, fmt.Errorf("%s is not a snapshot: %w", file, err)
+	}
+	tr := tar.NewReader(zr)
+	var patch []byte
+	var entries []snapshotEntry
+	var sawManifest bool
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return m, nil, nil, fmt.Errorf("read %s: %w", file, err)
+		}
+		name := hdr.Name
+		if name == "" || path.IsAbs(name) || path.Clean(name) != name || name == ".." || strings.HasPrefix(name, "../") || strings.Contains(name, `\`) {
+			return m, nil, nil, fmt.Errorf("snapshot entry %q escapes the workspace", name)
+		}
+		e := snapshotEntry{name: name, mode: hdr.FileInfo().Mode()}
+		switch hdr.Typeflag {
+		case tar.TypeReg:
+			if e.data, err = io.ReadAll(tr); err != nil {
+				return m, nil, nil, fmt.Errorf("read %s: %w", name, err)
+			}
+		case tar.TypeSymlink:
+			e.isLink, e.link = true, hdr.Linkname
+		default:
+			return m, nil, nil, fmt.Errorf("snapshot entry %q has unsupported type %q", name, hdr.Typeflag)
+		}
+		switch {
+		case name == "manifest.json":
+			if err := json.Unmarshal(e.data, &m); err != nil {
+				return m, nil, nil, fmt.Errorf("manifest.json: %w", err)
+			}
+			sawManifest = true
+		case name == "worktree.patch":
+			patch = e.data
+		default:
+			entries = append(entries, e)
+		}
+	}
+	if !sawManifest || m.Head == "" {
+		return m, nil, nil, fmt.Errorf("%s has no manifest", file)
+	}
+	return m, patch, entries, nil
+}
+
+func tarAddBytes(tw *tar.Writer, name string, data []byte) error {
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// tarAddPath adds a regular file or symlink; it reports false for other
+// types, which git does not track either.
+func tarAddPath(tw *tar.Writer, name, p string) (bool, error) {
+	fi, err := os.Lstat(p)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case fi.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(p)
+		if err != nil {
+			return false, err
+		}
+		return true, tw.WriteHeader(&tar.Header{Name: name, Linkname: target, Mode: 0o777, Typeflag: tar.TypeSymlink})
+	case fi.Mode().IsRegular():
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return false, err
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: int64(fi.Mode().Perm()), Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+			return false, err
+		}
+		_, err = tw.Write(data)
+		return true, err
+	}
+	return false, nil
+}
+
+// gitOutput runs git in dir with optional stdin and returns stdout; stderr
+// becomes part of the error.
+func gitOutput(dir string, stdin []byte, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var out, errb bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &errb
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(errb.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return out.String(), nil
+}
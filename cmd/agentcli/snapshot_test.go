@@ -0,0 +1,176 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// gitRepo creates a repository with one committed file, a.txt.
+func gitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.txt"), "one\n")
+	writeFile(t, filepath.Join(dir, ".gitignore"), "*.log\n")
+	git(t, dir, "init", "-q")
+	git(t, dir, "add", ".")
+	git(t, dir, "commit", "-q", "-m", "init")
+	return dir
+}
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@example.com", "-C", dir}, args...)...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, out)
+	}
+	return string(out)
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestSnapshot_CreateAndRestore(t *testing.T) {
+	repo := gitRepo(t)
+	stateDir := filepath.Join(t.TempDir(), "state")
+	writeFile(t, filepath.Join(stateDir, "runs", "r1.json"), `{"run":1}`)
+	writeFile(t, filepath.Join(repo, "a.txt"), "two\n")
+	writeFile(t, filepath.Join(repo, "sub", "new.txt"), "fresh\n")
+	writeFile(t, filepath.Join(repo, "debug.log"), "ignored\n")
+	if err := os.Symlink("new.txt", filepath.Join(repo, "sub", "link")); err != nil {
+		t.Fatal(err)
+	}
+	snap := filepath.Join(t.TempDir(), "exp.tar.gz")
+
+	var out, errb bytes.Buffer
+	if code := cliMain([]string{"snapshot", "create", "-repo", repo, "-state-dir", stateDir, "-out", snap}, &out, &errb); code != 0 {
+		t.Fatalf("create exit=%d stderr=%s", code, errb.String())
+	}
+	var sum snapshotSummary
+	if err := json.Unmarshal(out.Bytes(), &sum); err != nil {
+		t.Fatalf("decode %q: %v", out.String(), err)
+	}
+	if sum.Untracked != 2 || sum.StateFiles != 1 || sum.PatchBytes == 0 || sum.Head != strings.TrimSpace(git(t, repo, "rev-parse", "HEAD")) {
+		t.Fatalf("summary=%+v", sum)
+	}
+	if fi, err := os.Stat(snap); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Fatalf("archive mode: %v %v", fi, err)
+	}
+
+	// Roll the experiment back, then restore it elsewhere
+	git(t, repo, "checkout", "--", "a.txt")
+	if err := os.RemoveAll(filepath.Join(repo, "sub")); err != nil {
+		t.Fatal(err)
+	}
+	restored := filepath.Join(t.TempDir(), "restored")
+	out.Reset()
+	if code := cliMain([]string{"snapshot", "restore", snap, "-repo", repo, "-state-dir", restored}, &out, &errb); code != 0 {
+		t.Fatalf("restore exit=%d stderr=%s", code, errb.String())
+	}
+	if got := readFile(t, filepath.Join(repo, "a.txt")); got != "two\n" {
+		t.Fatalf("a.txt=%q", got)
+	}
+	if got := readFile(t, filepath.Join(repo, "sub", "link")); got != "fresh\n" {
+		t.Fatalf("sub/link=%q", got)
+	}
+	if got := readFile(t, filepath.Join(restored, "runs", "r1.json")); got != `{"run":1}` {
+		t.Fatalf("state=%q", got)
+	}
+	if fi, err := os.Stat(filepath.Join(restored, "runs", "r1.json")); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Fatalf("state mode: %v %v", fi, err)
+	}
+
+	// The tree now has tracked changes: refused without -force
+	errb.Reset()
+	if code := cliMain([]string{"snapshot", "restore", "-repo", repo, snap}, &out, &errb); code != 1 || !strings.Contains(errb.String(), "-force") {
+		t.Fatalf("dirty restore: exit=%d stderr=%s", code, errb.String())
+	}
+	errb.Reset()
+	if code := cliMain([]string{"snapshot", "restore", "-repo", repo, "-force", snap}, &out, &errb); code != 0 || !strings.Contains(errb.String(), "pass -state-dir") {
+		t.Fatalf("forced restore: exit=%d stderr=%s", code, errb.String())
+	}
+
+	writeFile(t, filepath.Join(repo, "b.txt"), "b\n")
+	git(t, repo, "add", ".")
+	git(t, repo, "commit", "-q", "-m", "next")
+	errb.Reset()
+	if code := cliMain([]string{"snapshot", "restore", "-repo", repo, snap}, &out, &errb); code != 1 || !strings.Contains(errb.String(), "git checkout "+sum.Head) {
+		t.Fatalf("head mismatch: exit=%d stderr=%s", code, errb.String())
+	}
+}
+
+func TestSnapshot_RejectsEscapingEntries(t *testing.T) {
+	repo := gitRepo(t)
+	head := strings.TrimSpace(git(t, repo, "rev-parse", "HEAD"))
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	manifest, err := json.Marshal(snapshotManifest{Version: "1", Head: head})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tarAddBytes(tw, "manifest.json", manifest); err != nil {
+		t.Fatal(err)
+	}
+	if err := tarAddBytes(tw, "files/../../evil", []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	snap := filepath.Join(t.TempDir(), "bad.tar.gz")
+	if err := os.WriteFile(snap, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var out, errb bytes.Buffer
+	if code := cliMain([]string{"snapshot", "restore", "-repo", repo, snap}, &out, &errb); code != 1 || !strings.Contains(errb.String(), "escapes") {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(repo), "evil")); !os.IsNotExist(err) {
+		t.Fatalf("escaping entry written: %v", err)
+	}
+}
+
+func TestSnapshot_Usage(t *testing.T) {
+	for _, args := range [][]string{
+		{"snapshot"},
+		{"snapshot", "list"},
+		{"snapshot", "create"},
+		{"snapshot", "restore"},
+		{"snapshot", "create", "-out", "x", "-force"},
+	} {
+		var out, errb bytes.Buffer
+		if code := cliMain(args, &out, &errb); code != 2 || !strings.Contains(errb.String(), "usage") {
+			t.Fatalf("%v: exit=%d stderr=%s", args, code, errb.String())
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/state"
+)
+
+// defaultStateGCKeep is how many snapshots per scope `state gc` keeps.
+const defaultStateGCKeep = 10
+
+// runStateGC implements `agentcli state gc`: it compacts the latest state
+// bundle, prunes older snapshots per scope and the call ledger, and prints what was removed and
+// how many bytes were reclaimed.
+func runStateGC(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("state gc", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	dir := fs.String("state-dir", getEnv("AGENTCLI_STATE_DIR", ""), "")
+	keep := fs.Int("keep", defaultStateGCKeep, "")
+	maxAge := fs.String("max-age", "", "")
+	dryRun := fs.Bool("dry-run", false, "")
+	if err := fs.Parse(args); err != nil {
+		safeFprintf(stderr, "error: state gc: %v\n", err)
+		return 2
+	}
+	if fs.NArg() != 0 || strings.TrimSpace(*dir) == "" {
+		safeFprintln(stderr, "error: usage: agentcli state gc [-keep N] [-max-age dur] [-dry-run] [-state-dir dir]")
+		return 2
+	}
+	policy := state.GCPolicy{Keep: *keep, DryRun: *dryRun}
+	if v := strings.TrimSpace(*maxAge); v != "" {
+		d, ok := parseStateMaxAge(v)
+		if !ok {
+			safeFprintf(stderr, "error: state gc: invalid -max-age %q; use a duration like 72h or a day count like 30d\n", v)
+			return 2
+		}
+		policy.MaxAge = d
+	}
+	if policy.Keep < 1 {
+		safeFprintln(stderr, "error: state gc: -keep must be at least 1")
+		return 2
+	}
+	res, err := state.GCStateDir(*dir, policy)
+	if err != nil {
+		safeFprintf(stderr, "error: state gc: %v\n", err)
+		return 1
+	}
+	verb, compactVerb := "removed", "compacted"
+	if policy.DryRun {
+		verb, compactVerb = "would remove", "would compact"
+	}
+	for _, s := range res.Removed {
+		safeFprintf(stdout, "%s %s (scope %s, %s, %d bytes)\n", verb, shortSHA(s.SHA256), s.ScopeKey, s.CreatedAt, s.Size)
+	}
+	if res.CompactedOutputs > 0 && res.Latest != nil {
+		safeFprintf(stdout, "%s latest %s: dropped %d truncated tool outputs\n", compactVerb, shortSHA(res.Latest.SHA256), res.CompactedOutputs)
+	}
+	if res.PrunedCalls > 0 {
+		safeFprintf(stdout, "%s %d call ledger entries\n", verb, res.PrunedCalls)
+	}
+	reclaimed := "reclaimed"
+	if policy.DryRun {
+		reclaimed = "would reclaim"
+	}
+	safeFprintf(stdout, "state gc: %d snapshots %s, %s %d bytes\n", len(res.Removed), verb, reclaimed, res.ReclaimedBytes)
+	return 0
+}
+
+// parseStateMaxAge accepts a Go duration ("72h") or whole days ("30d").
+func parseStateMaxAge(v string) (time.Duration, bool) {
+	if days, ok := strings.CutSuffix(v, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, false
+		}
+		return time.Duration(n) * 24 * time.Hour, true
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, false
+	}
+	return d, true
+}
@@ -4,10 +4,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"math/rand"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/hyperifyio/goagent/internal/clock"
 )
 
 // printStateDryRunPlan outputs a concise plan describing intended state actions.
@@ -32,10 +33,14 @@ func printStateDryRunPlan(cfg cliConfig, stdout io.Writer, stderr io.Writer) int
 		Refine        bool   `json:"refine"`
 		HasRefineText bool   `json:"has_refine_text"`
 		HasRefineFile bool   `json:"has_refine_file"`
+		Remote        string `json:"remote,omitempty"`
 		Notes         string `json:"notes"`
 	}
 	p := plan{StateDir: dir, ScopeKey: strings.TrimSpace(cfg.stateScope), Refine: cfg.stateRefine, HasRefineText: strings.TrimSpace(cfg.stateRefineText) != "", HasRefineFile: strings.TrimSpace(cfg.stateRefineFile) != ""}
 
+	if dir != "" {
+		p.Remote = strings.TrimSpace(cfg.stateRemote)
+	}
 	if dir == "" {
 		p.Action = "none"
 		p.Notes = "state-dir not set; no restore/save will occur"
@@ -47,11 +52,14 @@ func printStateDryRunPlan(cfg cliConfig, stdout io.Writer, stderr io.Writer) int
 		p.Action = "restore_or_save"
 		p.Notes = "would attempt restore-before-prep using latest.json; on success reuse without calling pre-stage; otherwise would run pre-stage and save a new snapshot"
 	}
+	if p.Remote != "" {
+		p.Notes += "; would pull from the remote first and push new files after"
+	}
 
 	// Include a synthetic SHA hint to demonstrate formatting without real IO
 	// This keeps output stable yet obviously a placeholder.
 	hint := map[string]any{
-		"sample_short_sha": fmt.Sprintf("%08x", rand.Uint32()),
+		"sample_short_sha": fmt.Sprintf("%08x", clock.Uint32()),
 	}
 	out := map[string]any{
 		"plan": p,
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/state"
+)
+
+// stateRemotePushTimeout bounds the push after a run, which must not depend
+// on the run's context: an interrupted run still shares its state.
+const stateRemotePushTimeout = 2 * time.Minute
+
+// pullStateRemote copies -state-remote into -state-dir and returns a func
+// that pushes local changes back. Without -state-remote both are no-ops.
+func pullStateRemote(ctx context.Context, cfg cliConfig, stderr io.Writer) (push func(), err error) {
+	if strings.TrimSpace(cfg.stateRemote) == "" || strings.TrimSpace(cfg.stateDir) == "" {
+		return func() {}, nil
+	}
+	remote, err := state.OpenRemote(cfg.stateRemote, &http.Client{Timeout: cfg.httpTimeout})
+	if err != nil {
+		return nil, err
+	}
+	st, err := state.Pull(ctx, remote, cfg.stateDir)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.verbose {
+		safeFprintf(stderr, "info: pulled %d state files (%d bytes) from %s\n", st.Files, st.Bytes, cfg.stateRemote)
+	}
+	return func() {
+		pctx, cancel := context.WithTimeout(context.Background(), stateRemotePushTimeout)
+		defer cancel()
+		st, err := state.Push(pctx, cfg.stateDir, remote)
+		if err != nil {
+			safeFprintf(stderr, "WARN: -state-remote: push to %s failed: %v\n", cfg.stateRemote, err)
+			return
+		}
+		if cfg.verbose {
+			safeFprintf(stderr, "info: pushed %d state files (%d bytes) to %s\n", st.Files, st.Bytes, cfg.stateRemote)
+		}
+	}, nil
+}
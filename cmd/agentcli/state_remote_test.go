@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/state"
+)
+
+// memS3 is a minimal path-style S3 for one bucket: list, get, and put.
+func memS3(t *testing.T) map[string][]byte {
+	t.Helper()
+	var mu sync.Mutex
+	objects := map[string][]byte{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		key := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/bucket"), "/")
+		switch {
+		case r.Method == http.MethodGet && key == "":
+			type content struct{ Key string }
+			var res struct {
+				XMLName  xml.Name `xml:"ListBucketResult"`
+				Contents []content
+			}
+			for k := range objects {
+				res.Contents = append(res.Contents, content{Key: k})
+			}
+			_ = xml.NewEncoder(w).Encode(res) //nolint:errcheck
+		case r.Method == http.MethodGet:
+			if data, ok := objects[key]; ok {
+				_, _ = w.Write(data) //nolint:errcheck
+				return
+			}
+			http.NotFound(w, r)
+		case r.Method == http.MethodPut:
+			objects[key], _ = io.ReadAll(r.Body) //nolint:errcheck
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("AGENTCLI_STATE_S3_ENDPOINT", srv.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "k")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "s")
+	return objects
+}
+
+func TestStateRemote_ForkSyncsThroughRemote(t *testing.T) {
+	objects := memS3(t)
+	seed := t.TempDir()
+	b := &state.StateBundle{Version: "1", CreatedAt: "2026-01-01T00:00:00Z", ModelID: "m", BaseURL: "http://example.local", ScopeKey: "main"}
+	if err := state.SaveStateBundle(seed, b); err != nil {
+		t.Fatal(err)
+	}
+	remote, err := state.OpenRemote("s3://bucket/team", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := state.Push(context.Background(), seed, remote); err != nil {
+		t.Fatal(err)
+	}
+
+	// A fresh machine forks the shared state and shares the fork
+	local := filepath.Join(t.TempDir(), "state")
+	var out, errb bytes.Buffer
+	if code := cliMain([]string{"-state-dir", local, "-state-remote", "s3://bucket/team", "-state-fork", "exp"}, &out, &errb); code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	snaps, err := state.ListSnapshots(local)
+	if err != nil || len(snaps) != 2 {
+		t.Fatalf("local snapshots %+v %v", snaps, err)
+	}
+	forked := 0
+	for k := range objects {
+		if strings.HasPrefix(k, "team/state-") {
+			forked++
+		}
+	}
+	if forked != 2 {
+		t.Fatalf("want the fork pushed, remote has %v", objects)
+	}
+}
+
+func TestStateRemote_FlagValidation(t *testing.T) {
+	t.Setenv("AGENTCLI_STATE_DIR", "")
+	for _, args := range [][]string{
+		{"-prompt", "x", "-state-remote", "s3://bucket/p"},
+		{"-prompt", "x", "-state-dir", t.TempDir(), "-state-remote", "https://bucket/p"},
+	} {
+		var out, errb bytes.Buffer
+		if code := cliMain(args, &out, &errb); code != 2 || !strings.Contains(errb.String(), "-state-remote") {
+			t.Fatalf("%v: exit=%d stderr=%s", args, code, errb.String())
+		}
+	}
+}
+
+func TestStateRemote_PullFailureExitCode(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	t.Setenv("AGENTCLI_STATE_S3_ENDPOINT", srv.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "k")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "s")
+	var out, errb bytes.Buffer
+	args := []string{"-state-dir", t.TempDir(), "-state-remote", "s3://bucket/team", "-state-list", "-error-json"}
+	if code := cliMain(args, &out, &errb); code != exitStateRemote {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if got := errorJSONLine(t, errb.String()); got["reason"] != "state_remote" {
+		t.Fatalf("error json %v", got)
+	}
+}
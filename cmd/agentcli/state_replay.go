@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"strings"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+	"github.com/hyperifyio/goagent/internal/redact"
+	"github.com/hyperifyio/goagent/internal/state"
+)
+
+// runSettings are the request-shaping settings saved with a run so a step's
+// request can be rebuilt exactly.
+type runSettings struct {
+	Model        string     `json:"model"`
+	Temperature  float64    `json:"temperature"`
+	TopP         float64    `json:"top_p,omitempty"`
+	Debug        bool       `json:"debug,omitempty"`
+	ToolProtocol string     `json:"tool_protocol,omitempty"`
+	Strategy     string     `json:"strategy,omitempty"`
+	Tools        []oai.Tool `json:"tools,omitempty"`
+	// Runs recorded before stale-read pruning existed decode as false
+	PruneStaleReads bool                       `json:"prune_stale_reads,omitempty"`
+	ChatTemplate    string                     `json:"chat_template,omitempty"`
+	ExtraBody       map[string]json.RawMessage `json:"extra_body,omitempty"`
+	// Seed is the sampling seed sent with each request, if any
+	Seed *int64 `json:"seed,omitempty"`
+}
+
+func (s runSettings) config() cliConfig {
+	cfg := cliConfig{model: s.Model, temperature: s.Temperature, topP: s.TopP, debug: s.Debug, pruneStaleReads: s.PruneStaleReads, chatTemplate: s.ChatTemplate, extraBody: s.ExtraBody, toolProtocol: s.ToolProtocol, strategy: s.Strategy}
+	if s.Seed != nil {
+		cfg.seed, cfg.sendSeed = int(*s.Seed), true
+	}
+	return cfg
+}
+
+// buildStepRequest assembles a step's chat request: transcript hygiene,
+// sampling knobs (top_p wins over temperature) and seed, tools, the
+// completion cap, and -prompt-cache markers.
+func buildStepRequest(cfg cliConfig, messages []oai.Message, oaiTools []oai.Tool, completionCap int) oai.ChatCompletionsRequest {
+	// Apply transcript hygiene before sending to the API when -debug is off
+	if cfg.pruneStaleReads && !cfg.debug {
+		messages = pruneStaleReads(messages)
+	}
+	req := oai.ChatCompletionsRequest{
+		Model:        cfg.model,
+		Messages:     applyTranscriptHygiene(messages, cfg.debug),
+		ChatTemplate: cfg.chatTemplate,
+		ExtraBody:    cfg.extraBody,
+	}
+	if cfg.topP > 0 {
+		topP := cfg.topP
+		req.TopP = &topP
+	} else if oai.SupportsTemperature(cfg.model) {
+		// Include temperature only when supported by the target model.
+		temp := cfg.temperature
+		req.Temperature = &temp
+	}
+	req.Seed = requestSeed(cfg)
+	if len(oaiTools) > 0 {
+		req.Tools = oaiTools
+		req.ToolChoice = "auto"
+	}
+	// Include MaxTokens only when a positive completionCap is set.
+	if completionCap > 0 {
+		req.MaxTokens = completionCap
+	}
+	if cfg.promptCache {
+		oai.MarkCacheBreakpoints(&req)
+	}
+	return req
+}
+
+// requestSeed returns the seed to send under -seed, or nil.
+func requestSeed(cfg cliConfig) *int64 {
+	if !cfg.sendSeed {
+		return nil
+	}
+	seed := int64(cfg.seed)
+	return &seed
+}
+
+// applyStrategyRequest translates tools and tool turns for ReAct, the plan
+// board, and the tool protocol.
+func applyStrategyRequest(cfg cliConfig, req oai.ChatCompletionsRequest, planner *planRunner) oai.ChatCompletionsRequest {
+	if cfg.strategy == strategyReAct {
+		return applyReActRequest(req)
+	}
+	if planner != nil {
+		req = planner.applyRequest(req)
+	}
+	return applyToolProtocol(cfg.toolProtocol, req)
+}
+
+// runRecorder collects the run log saved under -state-dir.
+type runRecorder struct {
+	dir      string
+	verbose  bool
+	log      state.RunLog
+	settings runSettings
+	final    []oai.Message
+}
+
+// newRunRecorder returns nil without -state-dir; its methods accept nil.
+func newRunRecorder(cfg cliConfig, oaiTools []oai.Tool) *runRecorder {
+	if strings.TrimSpace(cfg.stateDir) == "" {
+		return nil
+	}
+	return &runRecorder{
+		dir:     cfg.stateDir,
+		verbose: cfg.verbose,
+		log:     state.RunLog{Version: "1", RunID: state.NewRunID(), ScopeKey: cfg.stateScope},
+		settings: runSettings{
+			Model: cfg.model, Temperature: cfg.temperature, TopP: cfg.topP, Debug: cfg.debug, PruneStaleReads: cfg.pruneStaleReads, ChatTemplate: cfg.chatTemplate, ExtraBody: cfg.extraBody,
+			ToolProtocol: cfg.toolProtocol, Strategy: cfg.strategy, Tools: oaiTools, Seed: requestSeed(cfg),
+		},
+	}
+}
+
+// record notes the request of step (1-based); a length retry replaces it.
+func (r *runRecorder) record(step, messages, maxTokens int) {
+	if r == nil {
+		return
+	}
+	rec := state.RunStep{Step: step, Messages: messages, MaxTokens: maxTokens}
+	if n := len(r.log.Steps); n > 0 && r.log.Steps[n-1].Step == step {
+		r.log.Steps[n-1] = rec
+		return
+	}
+	r.log.Steps = append(r.log.Steps, rec)
+}
+
+// finish keeps the transcript including the final answer.
+func (r *runRecorder) finish(messages []oai.Message) {
+	if r != nil {
+		r.final = messages
+	}
+}
+
+// save writes the run log, redacted like saved messages. Failures only warn.
+func (r *runRecorder) save(messages []oai.Message, stderr io.Writer) {
+	if r == nil {
+		return
+	}
+	if r.final != nil {
+		messages = r.final
+	}
+	msgs, err := json.Marshal(messages)
+	if err == nil {
+		r.log.Messages = redact.Bytes(msgs)
+		r.log.Settings, err = json.Marshal(r.settings)
+	}
+	if err == nil {
+		err = state.SaveRunLog(r.dir, &r.log)
+	}
+	if err != nil {
+		safeFprintf(stderr, "WARN: failed to save run log: %v\n", err)
+		return
+	}
+	if r.verbose {
+		safeFprintf(stderr, "info: saved run %s\n", r.log.RunID)
+	}
+}
+
+// runState implements `agentcli state replay <run-id|latest> -step N`: it
+// rebuilds the request step N sent (or, for the step after the last, would
+// send) and prints it as indented JSON. `agentcli state gc` is runStateGC.
+func runState(args []string, stdout, stderr io.Writer) int {
+	if len(args) > 0 && args[0] == "gc" {
+		return runStateGC(args[1:], stdout, stderr)
+	}
+	if len(args) == 0 || args[0] != "replay" {
+		safeFprintln(stderr, "error: usage: agentcli state replay <run-id|latest> -step N [-state-dir dir]")
+		safeFprintln(stderr, "       agentcli state gc [-keep N] [-max-age dur] [-dry-run] [-state-dir dir]")
+		return 2
+	}
+	fs := flag.NewFlagSet("state replay", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	dir := fs.String("state-dir", getEnv("AGENTCLI_STATE_DIR", ""), "")
+	step := fs.Int("step", 0, "")
+	// Accept the run id before or after the flags
+	var ids []string
+	rest := args[1:]
+	for {
+		if err := fs.Parse(rest); err != nil {
+			safeFprintf(stderr, "error: state replay: %v\n", err)
+			return 2
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		ids = append(ids, fs.Arg(0))
+		rest = fs.Args()[1:]
+	}
+	if len(ids) != 1 || strings.TrimSpace(*dir) == "" || *step < 1 {
+		safeFprintln(stderr, "error: usage: agentcli state replay <run-id|latest> -step N [-state-dir dir]")
+		return 2
+	}
+	log, err := state.LoadRunLog(*dir, ids[0])
+	if err != nil {
+		safeFprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	var settings runSettings
+	var messages []oai.Message
+	if err := json.Unmarshal(log.Settings, &settings); err != nil {
+		safeFprintf(stderr, "error: run %s settings: %v\n", log.RunID, err)
+		return 1
+	}
+	if err := json.Unmarshal(log.Messages, &messages); err != nil {
+		safeFprintf(stderr, "error: run %s messages: %v\n", log.RunID, err)
+		return 1
+	}
+	cfg := settings.config()
+	var n, maxTokens int
+	note := "sent"
+	switch {
+	case *step <= len(log.Steps):
+		rec := log.Steps[*step-1]
+		n, maxTokens = rec.Messages, rec.MaxTokens
+	case *step == len(log.Steps)+1:
+		n, note = len(messages), "not sent; the run ended before this step"
+	default:
+		safeFprintf(stderr, "error: run %s has %d steps; -step must be 1..%d\n", log.RunID, len(log.Steps), len(log.Steps)+1)
+		return 2
+	}
+	if n > len(messages) {
+		safeFprintf(stderr, "error: run %s step %d needs %d messages but only %d were saved\n", log.RunID, *step, n, len(messages))
+		return 1
+	}
+	req := applyStrategyRequest(cfg, buildStepRequest(cfg, messages[:n], settings.Tools, maxTokens), nil)
+	safeFprintf(stderr, "run %s step %d (%s): %d messages, max_tokens=%d\n", log.RunID, *step, note, n, maxTokens)
+	if cfg.strategy == strategyPlan {
+		safeFprintln(stderr, "note: -strategy plan task-board instructions are not reconstructed")
+	}
+	if err := oai.ValidateMessageSequence(req.Messages); err != nil {
+		safeFprintf(stderr, "WARN: this request fails pre-flight validation: %v\n", err)
+	}
+	out, err := json.MarshalIndent(req, "", "  ")
+	if err != nil {
+		safeFprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	safeFprintln(stdout, string(out))
+	return 0
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+func TestStateReplay_RebuildsSentRequests(t *testing.T) {
+	toolsPath := writeEchoOKTool(t)
+	var mu sync.Mutex
+	var sent [][]byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body) //nolint:errcheck
+		var req oai.ChatCompletionsRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		mu.Lock()
+		sent = append(sent, body)
+		mu.Unlock()
+		msg := oai.Message{Role: oai.RoleAssistant, ToolCalls: []oai.ToolCall{{ID: "c1", Type: "function", Function: oai.ToolCallFunction{Name: "ping", Arguments: "{}"}}}}
+		if req.Messages[len(req.Messages)-1].Role == oai.RoleTool {
+			msg = oai.Message{Role: oai.RoleAssistant, Channel: "final", Content: "pong"}
+		}
+		_ = json.NewEncoder(w).Encode(oai.ChatCompletionsResponse{Choices: []oai.ChatCompletionsResponseChoice{{Message: msg}}}) //nolint:errcheck
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+	var out, errb bytes.Buffer
+	if code := cliMain([]string{"-prompt", "q", "-tools", toolsPath, "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-state-dir", dir, "-top-p", "0.5", "-verbose"}, &out, &errb); code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if !strings.Contains(errb.String(), "info: saved run ") {
+		t.Fatalf("run id not reported: %s", errb.String())
+	}
+	if len(sent) != 2 {
+		t.Fatalf("requests=%d", len(sent))
+	}
+	for i, body := range sent {
+		var rout, rerr bytes.Buffer
+		if code := cliMain([]string{"state", "replay", "latest", "-step", string(rune('1' + i)), "-state-dir", dir}, &rout, &rerr); code != 0 {
+			t.Fatalf("replay step %d: exit=%d stderr=%s", i+1, code, rerr.String())
+		}
+		if got, want := canonicalJSON(t, rout.Bytes()), canonicalJSON(t, body); got != want {
+			t.Fatalf("step %d replay differs from sent request:\n got %s\nwant %s", i+1, got, want)
+		}
+	}
+
+	// The step after the last shows the would-be request including the answer
+	var rout, rerr bytes.Buffer
+	if code := cliMain([]string{"state", "replay", "-state-dir", dir, "latest", "-step", "3"}, &rout, &rerr); code != 0 || !strings.Contains(rerr.String(), "not sent") || !strings.Contains(rout.String(), "pong") {
+		t.Fatalf("next step: exit=%d stdout=%s stderr=%s", code, rout.String(), rerr.String())
+	}
+	if code := cliMain([]string{"state", "replay", "latest", "-step", "4", "-state-dir", dir}, &rout, &rerr); code != 2 {
+		t.Fatalf("out of range step: exit=%d", code)
+	}
+	if code := cliMain([]string{"state", "replay", "nope", "-step", "1", "-state-dir", dir}, &rout, &rerr); code != 1 {
+		t.Fatalf("unknown run: exit=%d", code)
+	}
+	if code := cliMain([]string{"state", "replay", "latest", "-state-dir", dir}, &rout, &rerr); code != 2 {
+		t.Fatalf("missing -step: exit=%d", code)
+	}
+}
+
+func canonicalJSON(t *testing.T, b []byte) string {
+	t.Helper()
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		t.Fatalf("json: %v in %s", err, b)
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
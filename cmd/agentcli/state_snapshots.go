@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hyperifyio/goagent/internal/state"
+)
+
+// stateInspection reports whether one of -state-list, -state-show, or
+// -state-fork was given; they act on -state-dir and exit without a run.
+func stateInspection(cfg cliConfig) bool {
+	return cfg.stateList || strings.TrimSpace(cfg.stateShow) != "" || strings.TrimSpace(cfg.stateFork) != ""
+}
+
+// runStateInspection implements -state-fork, -state-show, and -state-list,
+// in that order when several are given, so a fork shows up in the listing.
+func runStateInspection(cfg cliConfig, stdout, stderr io.Writer) int {
+	push, err := pullStateRemote(context.Background(), cfg, stderr)
+	if err != nil {
+		safeFprintf(stderr, "error: -state-remote: %v\n", err)
+		return exitStateRemote
+	}
+	if scope := strings.TrimSpace(cfg.stateFork); scope != "" {
+		b, err := state.ForkLatestStateBundle(cfg.stateDir, scope)
+		if err != nil {
+			safeFprintf(stderr, "error: -state-fork: %v\n", err)
+			return 1
+		}
+		safeFprintf(stderr, "info: forked %s into scope %s; continue with -state-scope %s\n", shortSHA(b.PrevSHA), scope, scope)
+		push()
+	}
+	if id := strings.TrimSpace(cfg.stateShow); id != "" {
+		b, _, err := state.LoadSnapshot(cfg.stateDir, id)
+		if err != nil {
+			safeFprintf(stderr, "error: -state-show: %v\n", err)
+			return 1
+		}
+		data, err := json.MarshalIndent(b, "", "  ")
+		if err != nil {
+			safeFprintf(stderr, "error: -state-show: %v\n", err)
+			return 1
+		}
+		safeFprintln(stdout, string(data))
+	}
+	if cfg.stateList {
+		snaps, err := state.ListSnapshots(cfg.stateDir)
+		if err != nil {
+			safeFprintf(stderr, "error: -state-list: %v\n", err)
+			return 1
+		}
+		tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		safeFprintln(tw, "SHA\tCREATED\tSCOPE\tMODEL\tPARENT\t")
+		for _, s := range snaps {
+			sha := shortSHA(s.SHA256)
+			if s.Latest {
+				sha += "*"
+			}
+			parent := shortSHA(s.PrevSHA)
+			if parent == "" {
+				parent = "-"
+			}
+			safeFprintf(tw, "%s\t%s\t%s\t%s\t%s\t\n", sha, s.CreatedAt, s.ScopeKey, s.ModelID, parent)
+		}
+		_ = tw.Flush() //nolint:errcheck
+	}
+	return 0
+}
+
+// shortSHA abbreviates a snapshot SHA-256 for display; -state-show accepts it.
+func shortSHA(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
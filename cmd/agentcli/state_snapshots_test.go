@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/state"
+)
+
+func TestStateInspection_ListShowFork(t *testing.T) {
+	dir := t.TempDir()
+	b := &state.StateBundle{
+		Version:     "1",
+		CreatedAt:   "2026-01-01T00:00:00Z",
+		ToolVersion: "test-1",
+		ModelID:     "m",
+		BaseURL:     "http://example.local",
+		ToolsetHash: "abc",
+		ScopeKey:    "main",
+		Prompts:     map[string]string{"system": "sys"},
+		SourceHash:  state.ComputeSourceHash("m", "http://example.local", "abc", "main"),
+	}
+	if err := state.SaveStateBundle(dir, b); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	var out, errb bytes.Buffer
+	if code := cliMain([]string{"-state-dir", dir, "-state-fork", "experiment"}, &out, &errb); code != 0 {
+		t.Fatalf("fork exit=%d stderr=%s", code, errb.String())
+	}
+	if !strings.Contains(errb.String(), "into scope experiment") {
+		t.Fatalf("fork stderr=%q", errb.String())
+	}
+
+	out.Reset()
+	errb.Reset()
+	if code := cliMain([]string{"-state-dir", dir, "-state-list"}, &out, &errb); code != 0 {
+		t.Fatalf("list exit=%d stderr=%s", code, errb.String())
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "SHA") {
+		t.Fatalf("list output:\n%s", out.String())
+	}
+	if !strings.Contains(lines[1], "main") || !strings.Contains(lines[2], "experiment") || !strings.Contains(lines[2], "*") {
+		t.Fatalf("want main then latest experiment:\n%s", out.String())
+	}
+	parent := strings.Fields(lines[1])[0]
+
+	out.Reset()
+	errb.Reset()
+	if code := cliMain([]string{"-state-dir", dir, "-state-show", "latest"}, &out, &errb); code != 0 {
+		t.Fatalf("show exit=%d stderr=%s", code, errb.String())
+	}
+	var shown state.StateBundle
+	if err := json.Unmarshal(out.Bytes(), &shown); err != nil {
+		t.Fatalf("decode %q: %v", out.String(), err)
+	}
+	if shown.ScopeKey != "experiment" || !strings.HasPrefix(shown.PrevSHA, parent) || shown.Prompts["system"] != "sys" {
+		t.Fatalf("shown=%+v", shown)
+	}
+
+	out.Reset()
+	errb.Reset()
+	if code := cliMain([]string{"-state-dir", dir, "-state-show", "ffffffff"}, &out, &errb); code != 1 {
+		t.Fatalf("want exit 1 for unknown snapshot, got %d", code)
+	}
+}
+
+func TestStateInspection_RequiresStateDir(t *testing.T) {
+	t.Setenv("AGENTCLI_STATE_DIR", "")
+	var out, errb bytes.Buffer
+	if code := cliMain([]string{"-state-list"}, &out, &errb); code != 2 {
+		t.Fatalf("want exit 2, got %d stderr=%s", code, errb.String())
+	}
+	if !strings.Contains(errb.String(), "require -state-dir") {
+		t.Fatalf("stderr=%q", errb.String())
+	}
+}
+
+func TestStateGC_PrunesAndReports(t *testing.T) {
+	dir := t.TempDir()
+	for _, ts := range []string{"2026-01-01T00:00:00Z", "2026-01-02T00:00:00Z", "2026-01-03T00:00:00Z"} {
+		b := &state.StateBundle{Version: "1", CreatedAt: ts, ModelID: "m", BaseURL: "http://example.local", ScopeKey: "main"}
+		if err := state.SaveStateBundle(dir, b); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+	}
+	var out, errb bytes.Buffer
+	if code := cliMain([]string{"state", "gc", "-keep", "1", "-state-dir", dir}, &out, &errb); code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if strings.Count(out.String(), "removed ") != 2 || !strings.Contains(out.String(), "state gc: 2 snapshots removed, reclaimed ") {
+		t.Fatalf("stdout=%q", out.String())
+	}
+	if snaps, err := state.ListSnapshots(dir); err != nil || len(snaps) != 1 || snaps[0].CreatedAt != "2026-01-03T00:00:00Z" {
+		t.Fatalf("left %+v %v", snaps, err)
+	}
+
+	errb.Reset()
+	if code := cliMain([]string{"state", "gc", "-max-age", "soon", "-state-dir", dir}, &out, &errb); code != 2 {
+		t.Fatalf("want exit 2 for bad -max-age, got %d", code)
+	}
+}
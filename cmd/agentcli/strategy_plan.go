@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+	"github.com/hyperifyio/goagent/internal/state"
+)
+
+// strategyPlan first asks for a numbered JSON plan, then works through it
+// one task at a time with a persisted task board.
+const strategyPlan = "plan"
+
+// maxPlanTasks bounds plan size so a plan fits in the step budget.
+const maxPlanTasks = 12
+
+var rePlanMarker = regexp.MustCompile(`(?im)^\s*TASK\s+(DONE|FAILED)\s*:\s*(.*)$`)
+
+// planRunner tracks the task board for -strategy plan and shapes each request
+// around the current task.
+type planRunner struct {
+	cfg      cliConfig
+	goalHash string
+	board    *state.TaskBoard
+}
+
+// newPlanRunner resumes an unfinished board for the same goal and scope from
+// -state-dir when one exists; otherwise the first reply must be a plan.
+func newPlanRunner(cfg cliConfig, goal string, stderr io.Writer) *planRunner {
+	p := &planRunner{cfg: cfg, goalHash: state.GoalHash(goal)}
+	dir := strings.TrimSpace(cfg.stateDir)
+	if dir == "" {
+		return p
+	}
+	board, err := state.LoadTaskBoard(dir, cfg.stateScope)
+	if err != nil {
+		safeFprintf(stderr, "WARN: ignoring saved task board: %v\n", err)
+		return p
+	}
+	if board != nil && board.GoalHash == p.goalHash && !board.Complete() {
+		p.board = board
+		safeFprintf(stderr, "info: resuming plan at task %d of %d\n", board.Current().ID, len(board.Tasks))
+	}
+	return p
+}
+
+// applyRequest appends the planning, current-task, or wrap-up instruction to
+// the system message. Tools are withheld while planning.
+func (p *planRunner) applyRequest(req oai.ChatCompletionsRequest) oai.ChatCompletionsRequest {
+	var note string
+	switch {
+	case p.board == nil:
+		req.Tools = nil
+		req.ToolChoice = ""
+		note = fmt.Sprintf("Before doing any work, reply ONLY with a JSON plan of 1-%d concrete tasks:\n"+
+			"{\"plan\": [{\"id\": 1, \"task\": \"...\"}, {\"id\": 2, \"task\": \"...\"}]}\n"+
+			"Ids start at 1 and increase by one. Do not call tools yet.", maxPlanTasks)
+	case p.board.Complete():
+		note = "Task board:\n" + p.board.Render() + "\n\nAll tasks are finished. Reply with the final answer for the user."
+	default:
+		cur := p.board.Current()
+		note = fmt.Sprintf("Task board:\n%s\n\nCurrent task %d: %s\n"+
+			"Work only on this task. When it is complete, reply with \"TASK DONE: <short result>\"; "+
+			"if it cannot be completed, reply with \"TASK FAILED: <reason>\".", p.board.Render(), cur.ID, cur.Title)
+	}
+	msgs := make([]oai.Message, 0, len(req.Messages)+1)
+	injected := false
+	for _, m := range req.Messages {
+		if m.Role == oai.RoleSystem && !injected {
+			m.Content = strings.TrimRight(m.Content, "\n") + "\n\n" + note
+			injected = true
+		}
+		msgs = append(msgs, m)
+	}
+	if !injected {
+		msgs = append([]oai.Message{{Role: oai.RoleSystem, Content: note}}, msgs...)
+	}
+	req.Messages = msgs
+	return req
+}
+
+// handleReply consumes a content reply while a plan is being made or
+// executed and returns the transcript turns to append. handled is false once
+// the board is complete so the caller treats the reply as the final answer.
+func (p *planRunner) handleReply(msg oai.Message, stdout, stderr io.Writer) (turns []oai.Message, handled bool) {
+	if p.board == nil {
+		tasks, err := parsePlan(msg.Content)
+		if err != nil {
+			return []oai.Message{msg, {Role: oai.RoleUser, Content: fmt.Sprintf("Your plan was rejected: %v. Reply only with the JSON plan.", err)}}, true
+		}
+		accepted := "Plan accepted."
+		if p.cfg.editor {
+			edited, ok := p.review(tasks, stderr)
+			if !ok {
+				return []oai.Message{msg, {Role: oai.RoleUser, Content: "The user rejected your plan. Propose a different one; reply only with the JSON plan."}}, true
+			}
+			if renderPlan(edited) != renderPlan(tasks) {
+				accepted = "Plan accepted with changes from the user; follow the task board."
+			}
+			tasks = edited
+		}
+		p.board = &state.TaskBoard{Version: "1", GoalHash: p.goalHash, ScopeKey: p.cfg.stateScope, Tasks: tasks}
+		p.board.Tasks[0].Status = state.TaskInProgress
+		p.persist(stderr)
+		p.report(stdout, stderr)
+		return []oai.Message{msg, {Role: oai.RoleUser, Content: fmt.Sprintf("%s Start with task 1: %s", accepted, tasks[0].Title)}}, true
+	}
+	if p.board.Complete() {
+		return nil, false
+	}
+	cur := p.board.Current()
+	cur.Status, cur.Result = state.TaskDone, msg.Content
+	if m := rePlanMarker.FindStringSubmatch(msg.Content); m != nil {
+		if strings.EqualFold(m[1], "FAILED") {
+			cur.Status = state.TaskFailed
+		}
+		cur.Result = m[2]
+	}
+	cur.Result = truncateRunes(oneLine(strings.TrimSpace(cur.Result)), 200)
+	next := fmt.Sprintf("Task %d is %s. ", cur.ID, cur.Status)
+	if n := p.board.Current(); n != nil {
+		n.Status = state.TaskInProgress
+		next += fmt.Sprintf("Now work on task %d: %s", n.ID, n.Title)
+	} else {
+		next += "All tasks are finished. Reply with the final answer for the user."
+	}
+	p.persist(stderr)
+	p.report(stdout, stderr)
+	return []oai.Message{msg, {Role: oai.RoleUser, Content: next}}, true
+}
+
+// review hands a proposed plan to the user's editor under -editor and
+// returns the plan to follow. Clearing the file, or leaving a plan that does
+// not validate, rejects it so the model plans again.
+func (p *planRunner) review(tasks []state.Task, stderr io.Writer) ([]state.Task, bool) {
+	safeFprintln(stderr, "review plan: waiting for the editor")
+	edited, err := editInEditor("plan.json", renderPlan(tasks), []string{
+		"Review the plan above. Save to accept it; edit, add, or remove tasks",
+		"to change it, keeping ids numbered from 1.",
+		"Delete everything to reject the plan. Lines starting with '#' are ignored.",
+	})
+	switch {
+	case errors.Is(err, errEditorEmpty):
+		safeFprintln(stderr, "plan rejected")
+		return nil, false
+	case err != nil:
+		safeFprintf(stderr, "WARN: rejecting plan: %v\n", err)
+		return nil, false
+	}
+	out, err := parsePlan(edited)
+	if err != nil {
+		safeFprintf(stderr, "WARN: rejecting edited plan: %v\n", err)
+		return nil, false
+	}
+	return out, true
+}
+
+// renderPlan formats tasks in the JSON shape parsePlan reads.
+func renderPlan(tasks []state.Task) string {
+	type item struct {
+		ID   int    `json:"id"`
+		Task string `json:"task"`
+	}
+	doc := struct {
+		Plan []item `json:"plan"`
+	}{}
+	for _, t := range tasks {
+		doc.Plan = append(doc.Plan, item{ID: t.ID, Task: t.Title})
+	}
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// report delivers the board on the critic channel: printed under
+// -verbose, or written to a file route.
+func (p *planRunner) report(stdout, stderr io.Writer) {
+	emitChannel(p.cfg, "critic", true, p.board.Render(), stdout, stderr)
+}
+
+func (p *planRunner) persist(stderr io.Writer) {
+	if strings.TrimSpace(p.cfg.stateDir) == "" {
+		return
+	}
+	if err := state.SaveTaskBoard(p.cfg.stateDir, p.board); err != nil {
+		safeFprintf(stderr, "WARN: failed to save task board: %v\n", err)
+	}
+}
+
+// parsePlan validates a {"plan":[{"id":1,"task":"..."}]} reply, tolerating a
+// surrounding code fence or prose.
+func parsePlan(content string) ([]state.Task, error) {
+	start := strings.IndexByte(content, '{')
+	end := strings.LastIndexByte(content, '}')
+	if start < 0 || end < start {
+		return nil, errors.New("no JSON object found")
+	}
+	var doc struct {
+		Plan []struct {
+			ID   int    `json:"id"`
+			Task string `json:"task"`
+		} `json:"plan"`
+	}
+	dec := json.NewDecoder(bytes.NewReader([]byte(content[start : end+1])))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid plan JSON: %v", err)
+	}
+	if len(doc.Plan) == 0 || len(doc.Plan) > maxPlanTasks {
+		return nil, fmt.Errorf("plan must have 1-%d tasks, got %d", maxPlanTasks, len(doc.Plan))
+	}
+	tasks := make([]state.Task, 0, len(doc.Plan))
+	for i, it := range doc.Plan {
+		if it.ID != i+1 {
+			return nil, fmt.Errorf("task %d: id must be %d", i+1, i+1)
+		}
+		title := strings.TrimSpace(it.Task)
+		if title == "" {
+			return nil, fmt.Errorf("task %d: empty task", it.ID)
+		}
+		tasks = append(tasks, state.Task{ID: it.ID, Title: title, Status: state.TaskPending})
+	}
+	return tasks, nil
+}
+
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
+
+// firstUserContent returns the goal used to key a saved plan.
+func firstUserContent(messages []oai.Message) string {
+	for _, m := range messages {
+		if m.Role == oai.RoleUser {
+			return m.Content
+		}
+	}
+	return ""
+}
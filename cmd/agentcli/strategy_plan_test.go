@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+	"github.com/hyperifyio/goagent/internal/state"
+)
+
+func TestParsePlan(t *testing.T) {
+	tasks, err := parsePlan("Here you go:\n```json\n{\"plan\":[{\"id\":1,\"task\":\" Read \"},{\"id\":2,\"task\":\"Write\"}]}\n```")
+	if err != nil || len(tasks) != 2 || tasks[0].Title != "Read" || tasks[1].Status != state.TaskPending {
+		t.Fatalf("tasks=%+v err=%v", tasks, err)
+	}
+	bad := map[string]string{
+		"no json":       "I will read then write",
+		"empty":         `{"plan":[]}`,
+		"unknown field": `{"plan":[{"id":1,"task":"a","why":"b"}]}`,
+		"gap in ids":    `{"plan":[{"id":1,"task":"a"},{"id":3,"task":"b"}]}`,
+		"blank task":    `{"plan":[{"id":1,"task":"  "}]}`,
+	}
+	for name, in := range bad {
+		if _, err := parsePlan(in); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+// planServer replies with the given contents in order, the last one on the
+// final channel, and records each request.
+func planServer(t *testing.T, replies []string, seen *[]oai.ChatCompletionsRequest) *httptest.Server {
+	t.Helper()
+	return fakeChatServer(t, nil, func(req oai.ChatCompletionsRequest) (oai.ChatCompletionsResponse, error) {
+		n := len(*seen)
+		if n >= len(replies) {
+			t.Errorf("unexpected extra request %d", n+1)
+			return oai.ChatCompletionsResponse{}, errors.New("no more replies")
+		}
+		*seen = append(*seen, req)
+		msg := oai.Message{Role: oai.RoleAssistant, Content: replies[n]}
+		if n == len(replies)-1 {
+			msg.Channel = "final"
+		}
+		return answer(msg)
+	})
+}
+
+func TestRunAgent_PlanStrategy(t *testing.T) {
+	toolsPath := writeEchoOKTool(t)
+	var seen []oai.ChatCompletionsRequest
+	srv := planServer(t, []string{
+		`not a plan`,
+		`{"plan":[{"id":1,"task":"Check host"},{"id":2,"task":"Summarize"}]}`,
+		"TASK DONE: host is up",
+		"TASK FAILED: nothing to summarize",
+		"host a is up",
+	}, &seen)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	cfg := textProtocolConfig(toolsPath, srv.URL)
+	cfg.toolProtocol = oai.ToolProtocolNative
+	cfg.strategy = strategyPlan
+	cfg.maxSteps = 8
+	cfg.verbose = true
+	cfg.stateDir = dir
+	cfg.stateScope = "s"
+	var out, errb bytes.Buffer
+	if code := runAgent(cfg, &out, &errb); code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if out.String() != "host a is up\n" {
+		t.Fatalf("stdout=%q", out.String())
+	}
+	if len(seen[0].Tools) != 0 || !strings.Contains(seen[0].Messages[0].Content, `{"plan"`) {
+		t.Fatalf("planning request must withhold tools and ask for a plan: %+v", seen[0])
+	}
+	if last := seen[1].Messages[len(seen[1].Messages)-1]; !strings.HasPrefix(last.Content, "Your plan was rejected:") {
+		t.Fatalf("invalid plan not rejected: %q", last.Content)
+	}
+	if len(seen[2].Tools) == 0 || !strings.Contains(seen[2].Messages[0].Content, "Current task 1: Check host") {
+		t.Fatalf("task step must restore tools and name the current task: %q", seen[2].Messages[0].Content)
+	}
+	if !strings.Contains(seen[3].Messages[0].Content, "Current task 2: Summarize") {
+		t.Fatalf("second task not current: %q", seen[3].Messages[0].Content)
+	}
+	if !strings.Contains(seen[4].Messages[0].Content, "Reply with the final answer") {
+		t.Fatalf("wrap-up instruction missing: %q", seen[4].Messages[0].Content)
+	}
+	if !strings.Contains(errb.String(), "[x] 1. Check host — host is up") || !strings.Contains(errb.String(), "[!] 2. Summarize") {
+		t.Fatalf("task board not printed on critic channel: %s", errb.String())
+	}
+	board, err := state.LoadTaskBoard(dir, "s")
+	if err != nil || board == nil || !board.Complete() || board.Tasks[1].Status != state.TaskFailed {
+		t.Fatalf("persisted board=%+v err=%v", board, err)
+	}
+}
+
+func TestRunAgent_PlanStrategy_ResumesSavedBoard(t *testing.T) {
+	toolsPath := writeEchoOKTool(t)
+	dir := t.TempDir()
+	cfg := textProtocolConfig(toolsPath, "")
+	board := &state.TaskBoard{Version: "1", GoalHash: state.GoalHash(cfg.prompt), ScopeKey: "s", Tasks: []state.Task{
+		{ID: 1, Title: "Check host", Status: state.TaskDone, Result: "up"},
+		{ID: 2, Title: "Summarize", Status: state.TaskInProgress},
+	}}
+	if err := state.SaveTaskBoard(dir, board); err != nil {
+		t.Fatal(err)
+	}
+	var seen []oai.ChatCompletionsRequest
+	srv := planServer(t, []string{"TASK DONE: summarized", "done"}, &seen)
+	defer srv.Close()
+
+	cfg.baseURL = srv.URL
+	cfg.toolProtocol = oai.ToolProtocolNative
+	cfg.strategy = strategyPlan
+	cfg.stateDir = dir
+	cfg.stateScope = "s"
+	var out, errb bytes.Buffer
+	if code := runAgent(cfg, &out, &errb); code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if !strings.Contains(seen[0].Messages[0].Content, "Current task 2: Summarize") {
+		t.Fatalf("did not resume at task 2: %q", seen[0].Messages[0].Content)
+	}
+	if !strings.Contains(errb.String(), "resuming plan at task 2 of 2") {
+		t.Fatalf("stderr=%s", errb.String())
+	}
+}
+
+func TestStrategyFlag_Plan(t *testing.T) {
+	orig := os.Args
+	defer func() { os.Args = orig }()
+	t.Setenv("AGENTCLI_STRATEGY", "")
+	os.Args = []string{"agentcli.test", "-prompt", "p", "-strategy", "PLAN"}
+	if cfg, code := parseFlags(); code != 0 || cfg.strategy != strategyPlan {
+		t.Fatalf("code=%d strategy=%q", code, cfg.strategy)
+	}
+}
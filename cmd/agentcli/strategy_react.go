@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+	"github.com/hyperifyio/goagent/internal/tools"
+)
+
+// Loop strategies selectable via -strategy.
+const (
+	// strategyNative uses the server's tool-calling protocol (default).
+	strategyNative = "native"
+	// strategyReAct encodes Thought/Action/Observation as plain text turns.
+	strategyReAct = "react"
+)
+
+// reactStep is one parsed ReAct reply. Exactly one of Action or FinalAnswer
+// is set; a reply with neither is treated as a final answer.
+type reactStep struct {
+	Thought     string
+	Action      string
+	ActionInput string
+	FinalAnswer string
+	// Raw is the reply with any model-invented Observation trimmed off, which
+	// is what gets recorded in the transcript.
+	Raw string
+}
+
+var (
+	reReActObservation = regexp.MustCompile(`(?m)^\s*Observation:`)
+	reReActThought     = regexp.MustCompile(`(?is)Thought:\s*(.*?)\s*(?:\n\s*(?:Action|Final Answer):|$)`)
+	reReActAction      = regexp.MustCompile(`(?im)^\s*Action:\s*(.+?)\s*$`)
+	reReActInput       = regexp.MustCompile(`(?is)Action Input:\s*(.*)$`)
+	reReActFinal       = regexp.MustCompile(`(?is)Final Answer:\s*(.*)$`)
+)
+
+// reactPrompt renders the ReAct instructions and tool list appended to the
+// system message.
+func reactPrompt(toolList []oai.Tool) string {
+	var b strings.Builder
+	b.WriteString("Solve the task step by step using this exact format:\n\n")
+	b.WriteString("Thought: what you will do next and why\n")
+	b.WriteString("Action: the tool name to call\n")
+	b.WriteString("Action Input: a JSON object with the tool arguments\n\n")
+	b.WriteString("Then stop and wait. The result arrives as \"Observation: ...\" in the next message. Repeat as needed. When you know the answer, reply with:\n\n")
+	b.WriteString("Thought: I know the answer\nFinal Answer: the answer for the user\n\n")
+	if len(toolList) == 0 {
+		b.WriteString("No tools are available; reply with a Final Answer.")
+		return b.String()
+	}
+	b.WriteString("Tools:\n")
+	for _, t := range toolList {
+		b.WriteString("- " + t.Function.Name)
+		if d := strings.TrimSpace(t.Function.Description); d != "" {
+			b.WriteString(": " + d)
+		}
+		b.WriteString("\n")
+		var buf bytes.Buffer
+		if len(t.Function.Parameters) > 0 && json.Compact(&buf, t.Function.Parameters) == nil {
+			b.WriteString("  input schema: " + buf.String() + "\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// applyReActRequest strips native tools from req and appends the ReAct
+// instructions to the first system message (inserting one when absent).
+func applyReActRequest(req oai.ChatCompletionsRequest) oai.ChatCompletionsRequest {
+	prompt := reactPrompt(req.Tools)
+	req.Tools = nil
+	req.ToolChoice = ""
+	msgs := make([]oai.Message, 0, len(req.Messages)+1)
+	injected := false
+	for _, m := range req.Messages {
+		if m.Role == oai.RoleSystem && !injected {
+			m.Content = strings.TrimRight(m.Content, "\n") + "\n\n" + prompt
+			injected = true
+		}
+		msgs = append(msgs, m)
+	}
+	if !injected {
+		msgs = append([]oai.Message{{Role: oai.RoleSystem, Content: prompt}}, msgs...)
+	}
+	req.Messages = msgs
+	return req
+}
+
+// parseReActStep extracts the next action or final answer from content.
+// Whichever of "Action:" and "Final Answer:" appears first wins.
+func parseReActStep(content string) reactStep {
+	raw := content
+	if loc := reReActObservation.FindStringIndex(raw); loc != nil {
+		raw = raw[:loc[0]]
+	}
+	raw = strings.TrimSpace(raw)
+	st := reactStep{Raw: raw}
+	if m := reReActThought.FindStringSubmatch(raw); m != nil {
+		st.Thought = strings.TrimSpace(m[1])
+	}
+	actLoc := reReActAction.FindStringSubmatchIndex(raw)
+	finLoc := reReActFinal.FindStringSubmatchIndex(raw)
+	if actLoc != nil && (finLoc == nil || actLoc[0] < finLoc[0]) {
+		st.Action = strings.Trim(raw[actLoc[2]:actLoc[3]], "`\"' ")
+		if m := reReActInput.FindStringSubmatch(raw[actLoc[1]:]); m != nil {
+			st.ActionInput = stripCodeFence(m[1])
+		}
+		return st
+	}
+	if finLoc != nil {
+		st.FinalAnswer = strings.TrimSpace(raw[finLoc[2]:finLoc[3]])
+		return st
+	}
+	st.FinalAnswer = raw
+	return st
+}
+
+func stripCodeFence(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "```") {
+		s = strings.TrimPrefix(s, "```")
+		if nl := strings.IndexByte(s, '\n'); nl >= 0 {
+			s = s[nl+1:]
+		}
+		if end := strings.LastIndex(s, "```"); end >= 0 {
+			s = s[:end]
+		}
+	}
+	return strings.TrimSpace(s)
+}
+
+// handleReActReply advances the ReAct loop for one assistant reply. When the
+// reply is a final answer it returns that answer as an assistant message and
+// done=true. Otherwise it runs the requested tool and returns the assistant
+// turn plus a user "Observation:" turn to append to the transcript.
+func handleReActReply(ctx context.Context, msg oai.Message, registry map[string]tools.ToolSpec, cfg cliConfig, step int) (final oai.Message, turns []oai.Message, done bool) {
+	st := parseReActStep(msg.Content)
+	if st.Action == "" {
+		return oai.Message{Role: oai.RoleAssistant, Content: st.FinalAnswer}, nil, true
+	}
+	turns = append(turns, oai.Message{Role: oai.RoleAssistant, Content: st.Raw})
+	return oai.Message{}, append(turns, oai.Message{Role: oai.RoleUser, Content: "Observation: " + reactObserve(ctx, st, registry, cfg, step)}), false
+}
+
+// reactObserve runs the parsed action through the regular tool runner and
+// returns its sanitized output, or an error JSON the model can react to.
+func reactObserve(ctx context.Context, st reactStep, registry map[string]tools.ToolSpec, cfg cliConfig, step int) string {
+	if _, ok := registry[st.Action]; !ok {
+		return sanitizeToolContent(nil, fmt.Errorf("unknown tool: %s", st.Action))
+	}
+	args := st.ActionInput
+	if args == "" {
+		args = "{}"
+	}
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(args), &obj); err != nil {
+		return sanitizeToolContent(nil, fmt.Errorf("Action Input must be a JSON object: %v", err))
+	}
+	call := oai.Message{Role: oai.RoleAssistant, ToolCalls: []oai.ToolCall{{
+		ID:       fmt.Sprintf("react_%d", step+1),
+		Type:     "function",
+		Function: oai.ToolCallFunction{Name: st.Action, Arguments: args},
+	}}}
+	out := appendToolCallOutputs(ctx, nil, call, registry, cfg)
+	if len(out) == 0 {
+		return "{}"
+	}
+	return out[len(out)-1].Content
+}
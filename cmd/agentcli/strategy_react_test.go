@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+func TestParseReActStep(t *testing.T) {
+	st := parseReActStep("Thought: need time\nAction: get_time\nAction Input: ```json\n{\"tz\":\"UTC\"}\n```\nObservation: made up\nFinal Answer: nope")
+	if st.Thought != "need time" || st.Action != "get_time" || st.ActionInput != `{"tz":"UTC"}` || st.FinalAnswer != "" {
+		t.Fatalf("action step: %+v", st)
+	}
+	if strings.Contains(st.Raw, "Observation") {
+		t.Fatalf("invented observation must be trimmed: %q", st.Raw)
+	}
+	st = parseReActStep("Thought: done\nFinal Answer: 42\nsecond line")
+	if st.Action != "" || st.FinalAnswer != "42\nsecond line" {
+		t.Fatalf("final step: %+v", st)
+	}
+	st = parseReActStep("just an answer")
+	if st.Action != "" || st.FinalAnswer != "just an answer" {
+		t.Fatalf("plain reply: %+v", st)
+	}
+}
+
+func TestRunAgent_ReActStrategy(t *testing.T) {
+	toolsPath := writeEchoOKTool(t)
+	step := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req oai.ChatCompletionsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if len(req.Tools) != 0 {
+			t.Fatalf("react must not send native tools")
+		}
+		step++
+		var reply string
+		switch step {
+		case 1:
+			if !strings.Contains(req.Messages[0].Content, "Action Input:") || !strings.Contains(req.Messages[0].Content, "- ping") {
+				t.Fatalf("system prompt missing ReAct instructions: %q", req.Messages[0].Content)
+			}
+			reply = "Thought: check host\nAction: ping\nAction Input: {\"host\":\"a\"}"
+		case 2:
+			n := len(req.Messages)
+			if req.Messages[n-2].Role != oai.RoleAssistant || !strings.HasPrefix(req.Messages[n-2].Content, "Thought: check host") {
+				t.Fatalf("assistant turn not recorded as text: %+v", req.Messages[n-2])
+			}
+			if req.Messages[n-1].Role != oai.RoleUser || !strings.HasPrefix(req.Messages[n-1].Content, "Observation: ") {
+				t.Fatalf("observation turn missing: %+v", req.Messages[n-1])
+			}
+			reply = "Thought: host is up\nAction: nmap\nAction Input: {}"
+		case 3:
+			last := req.Messages[len(req.Messages)-1]
+			if !strings.Contains(last.Content, "unknown tool: nmap") {
+				t.Fatalf("expected unknown tool observation, got %q", last.Content)
+			}
+			reply = "Thought: I know the answer\nFinal Answer: host a is up"
+		default:
+			t.Fatalf("unexpected extra request step=%d", step)
+		}
+		_ = json.NewEncoder(w).Encode(oai.ChatCompletionsResponse{Choices: []oai.ChatCompletionsResponseChoice{{ //nolint:errcheck
+			Message: oai.Message{Role: oai.RoleAssistant, Content: reply},
+		}}})
+	}))
+	defer srv.Close()
+
+	cfg := textProtocolConfig(toolsPath, srv.URL)
+	cfg.toolProtocol = oai.ToolProtocolNative
+	cfg.strategy = strategyReAct
+	var out, errb bytes.Buffer
+	if code := runAgent(cfg, &out, &errb); code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if out.String() != "host a is up\n" {
+		t.Fatalf("stdout=%q", out.String())
+	}
+}
+
+func TestStrategyFlag_Validation(t *testing.T) {
+	orig := os.Args
+	defer func() { os.Args = orig }()
+	t.Setenv("AGENTCLI_STRATEGY", "")
+
+	os.Args = []string{"agentcli.test", "-prompt", "p"}
+	if cfg, code := parseFlags(); code != 0 || cfg.strategy != strategyNative {
+		t.Fatalf("default: code=%d strategy=%q", code, cfg.strategy)
+	}
+	t.Setenv("AGENTCLI_STRATEGY", "ReAct")
+	if cfg, code := parseFlags(); code != 0 || cfg.strategy != strategyReAct {
+		t.Fatalf("env: code=%d strategy=%q", code, cfg.strategy)
+	}
+	os.Args = []string{"agentcli.test", "-prompt", "p", "-strategy", "tree"}
+	if _, code := parseFlags(); code != 2 {
+		t.Fatalf("invalid -strategy: exit=%d want 2", code)
+	}
+}
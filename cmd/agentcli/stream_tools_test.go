@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// Streaming must reassemble tool_calls deltas, run the tool, and stream the
+// follow-up turn instead of ending the run after the tool-call turn.
+func TestRunAgent_StreamFinal_ToolCallDeltas(t *testing.T) {
+	toolsPath := writeEchoOKTool(t)
+	step := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req oai.ChatCompletionsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if !req.Stream {
+			t.Fatalf("step %d fell back to non-streaming", step+1)
+		}
+		step++
+		w.Header().Set("Content-Type", "text/event-stream")
+		var events []string
+		switch step {
+		case 1:
+			events = []string{
+				`{"choices":[{"index":0,"delta":{"role":"assistant","tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"ping","arguments":""}}]}}]}`,
+				`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"host\":"}}]}}]}`,
+				`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"a\"}"}}]}}]}`,
+				`{"choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+			}
+		case 2:
+			n := len(req.Messages)
+			if req.Messages[n-2].Role != oai.RoleAssistant || len(req.Messages[n-2].ToolCalls) != 1 || req.Messages[n-2].ToolCalls[0].Function.Arguments != `{"host":"a"}` {
+				t.Fatalf("assistant tool-call turn not recorded: %+v", req.Messages[n-2])
+			}
+			if req.Messages[n-1].Role != oai.RoleTool || req.Messages[n-1].ToolCallID != "call_1" {
+				t.Fatalf("tool result missing: %+v", req.Messages[n-1])
+			}
+			events = []string{
+				`{"choices":[{"index":0,"delta":{"role":"assistant","channel":"final","content":"host a "}}]}`,
+				`{"choices":[{"index":0,"delta":{"channel":"final","content":"is up"}}]}`,
+			}
+		default:
+			t.Fatalf("unexpected extra request step=%d", step)
+		}
+		for _, e := range events {
+			_, _ = w.Write([]byte("data: " + e + "\n\n")) //nolint:errcheck
+		}
+		_, _ = w.Write([]byte("data: [DONE]\n\n")) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	cfg := textProtocolConfig(toolsPath, srv.URL)
+	cfg.toolProtocol = oai.ToolProtocolNative
+	cfg.strategy = strategyNative
+	cfg.streamFinal = true
+	var out, errb bytes.Buffer
+	if code := runAgent(cfg, &out, &errb); code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if strings.TrimSpace(out.String()) != "host a is up" {
+		t.Fatalf("stdout=%q", out.String())
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+	"github.com/hyperifyio/goagent/internal/tools"
+)
+
+// agentRunTool is the built-in tool that runs a nested agent in-process.
+const agentRunTool = "agent.run"
+
+const agentRunSchema = `{"type":"object","properties":{` +
+	`"prompt":{"type":"string","description":"Task for the subagent; include all context it needs"},` +
+	`"system":{"type":"string","description":"Optional system prompt; defaults to the parent's"},` +
+	`"tools":{"type":"array","items":{"type":"string"},"description":"Tool names the subagent may use; omit for all of the parent's tools, [] for none"},` +
+	`"max_steps":{"type":"integer","minimum":1,"description":"Step cap; defaults to and may not exceed the parent's"},` +
+	`"max_tokens":{"type":"integer","minimum":1,"description":"Total token budget; the subagent stops once it is spent"}},` +
+	`"required":["prompt"],"additionalProperties":false}`
+
+// agentRunArgs is the agent.run argument object.
+type agentRunArgs struct {
+	Prompt    string    `json:"prompt"`
+	System    string    `json:"system"`
+	Tools     *[]string `json:"tools"`
+	MaxSteps  int       `json:"max_steps"`
+	MaxTokens int       `json:"max_tokens"`
+}
+
+// agentRunSpec returns the registry entry advertised for agent.run. It has no
+// command; appendToolCallOutputs dispatches it to runSubagent.
+func agentRunSpec() tools.ToolSpec {
+	return tools.ToolSpec{
+		Name:        agentRunTool,
+		Description: "Delegate a self-contained subtask to a nested agent with its own prompt, tool subset, step cap, and token budget. Returns the subagent's final answer.",
+		Schema:      json.RawMessage(agentRunSchema),
+	}
+}
+
+// addAgentRunTool registers agent.run when -subagent-depth allows another
+// level of nesting.
+func addAgentRunTool(cfg cliConfig, registry map[string]tools.ToolSpec, oaiTools []oai.Tool) (map[string]tools.ToolSpec, []oai.Tool, error) {
+	if cfg.subagentDepth <= 0 {
+		return registry, oaiTools, nil
+	}
+	if _, exists := registry[agentRunTool]; exists {
+		return nil, nil, fmt.Errorf("tool %q in -tools conflicts with the built-in subagent tool; rename it or pass -subagent-depth 0", agentRunTool)
+	}
+	if registry == nil {
+		registry = make(map[string]tools.ToolSpec)
+	}
+	spec := agentRunSpec()
+	registry[agentRunTool] = spec
+	oaiTools = append(oaiTools, oai.Tool{Type: "function", Function: oai.ToolFunction{Name: spec.Name, Description: spec.Description, Parameters: spec.Schema}})
+	return registry, oaiTools, nil
+}
+
+// filterTools keeps only the named tools, preserving advertisement order. A
+// name missing from the registry is an error.
+func filterTools(registry map[string]tools.ToolSpec, oaiTools []oai.Tool, names []string) (map[string]tools.ToolSpec, []oai.Tool, error) {
+	keep := make(map[string]tools.ToolSpec, len(names))
+	for _, n := range names {
+		spec, ok := registry[n]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown tool %q; available: %s", n, strings.Join(registryNames(registry), ", "))
+		}
+		keep[n] = spec
+	}
+	var out []oai.Tool
+	for _, t := range oaiTools {
+		if _, ok := keep[t.Function.Name]; ok {
+			out = append(out, t)
+		}
+	}
+	return keep, out, nil
+}
+
+// runSubagent runs a nested agent loop in-process for an agent.run call and
+// returns its final answer and token usage as the tool result. The child
+// inherits the parent's endpoint, model, timeouts, and strategy; it skips
+// the pre-stage and never prints, saves state, or takes the workspace lock
+// (the parent already holds it when mutating tools are enabled).
+func runSubagent(ctx context.Context, cfg cliConfig, registry map[string]tools.ToolSpec, argsJSON []byte) ([]byte, error) {
+	var args agentRunArgs
+	if err := json.Unmarshal(argsJSON, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %v", err)
+	}
+	if strings.TrimSpace(args.Prompt) == "" {
+		return nil, fmt.Errorf("prompt is required")
+	}
+	if args.MaxSteps < 0 || args.MaxTokens < 0 {
+		return nil, fmt.Errorf("max_steps and max_tokens must be positive")
+	}
+	child := cfg
+	child.prompt = args.Prompt
+	child.promptFile = ""
+	if strings.TrimSpace(args.System) != "" {
+		child.systemPrompt = args.System
+		child.systemFile = ""
+	}
+	child.developerPrompts = nil
+	child.developerFiles = nil
+	child.imageAttach = nil
+	child.audioPrompt = ""
+	child.promptTemplate = ""
+	child.subagentDepth = cfg.subagentDepth - 1
+	child.parentCtx = ctx
+	child.resuming = false
+	child.tokenBudget = args.MaxTokens
+	if args.MaxSteps > 0 && args.MaxSteps < cfg.maxSteps {
+		child.maxSteps = args.MaxSteps
+	}
+	if args.Tools != nil {
+		names := make([]string, 0, len(*args.Tools))
+		for _, n := range *args.Tools {
+			if _, ok := registry[n]; !ok {
+				return nil, fmt.Errorf("unknown tool %q; available: %s", n, strings.Join(registryNames(registry), ", "))
+			}
+			if n == agentRunTool && child.subagentDepth <= 0 {
+				return nil, fmt.Errorf("%s is not available at this nesting depth", agentRunTool)
+			}
+			if (n == planUpdateTool || n == taskCompleteTool) && registry[n].Command == nil {
+				return nil, fmt.Errorf("%s tracks the parent's progress and is not available to subagents", n)
+			}
+			names = append(names, n)
+		}
+		child.toolAllowlist = names
+	}
+	child.prepEnabled = false
+	child.prepEnabledSet = true
+	child.loadMessagesPath = ""
+	child.saveMessagesPath = ""
+	child.initMessages = nil
+	child.stateDir = ""
+	child.stateRemote = ""
+	child.outputFile = ""
+	child.exportJSONL = ""
+	child.succeedIfRe = nil
+	child.failIfRe = nil
+	child.goldenPath = ""
+	child.reviewModel = ""
+	child.contextReport = ""
+	child.tui = false
+	child.editor = false
+	child.events = nil
+	child.plan = nil
+	child.tasksPath = ""
+	child.checklist = nil
+	child.printPlan = false
+	child.streamFinal = false
+	child.printMessages = false
+	child.diffMessages = nil
+	child.channelRoutes = nil
+	child.verbose = false
+	child.debug = false
+	child.noLock = true
+	var usage runUsage
+	child.usageSink = &usage
+
+	var stdout, stderr bytes.Buffer
+	if code := runAgent(child, &stdout, &stderr); code != 0 {
+		return nil, fmt.Errorf("subagent exited %d: %s", code, lastLine(stderr.String()))
+	}
+	return json.Marshal(map[string]any{"answer": strings.TrimSpace(stdout.String()), "tokens": usage.totalTokens})
+}
+
+func registryNames(registry map[string]tools.ToolSpec) []string {
+	names := make([]string, 0, len(registry))
+	for n := range registry {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
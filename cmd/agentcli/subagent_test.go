@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// subagentServer plays both agents: the parent (prompt "parent task") calls
+// agent.run with childArgs and then echoes the tool result as its answer; the
+// child replies with childReply.
+func subagentServer(t *testing.T, childArgs string, childReply oai.Message) (*httptest.Server, *[]oai.ChatCompletionsRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var childReqs []oai.ChatCompletionsRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req oai.ChatCompletionsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		last := req.Messages[len(req.Messages)-1]
+		var msg oai.Message
+		switch {
+		case firstUserContent(req.Messages) != "parent task":
+			mu.Lock()
+			childReqs = append(childReqs, req)
+			mu.Unlock()
+			msg = childReply
+		case last.Role == oai.RoleTool:
+			msg = oai.Message{Role: oai.RoleAssistant, Channel: "final", Content: last.Content}
+		default:
+			msg = oai.Message{Role: oai.RoleAssistant, ToolCalls: []oai.ToolCall{{ID: "c1", Type: "function", Function: oai.ToolCallFunction{Name: agentRunTool, Arguments: childArgs}}}}
+		}
+		resp := oai.ChatCompletionsResponse{Choices: []oai.ChatCompletionsResponseChoice{{Message: msg}}, Usage: &oai.Usage{TotalTokens: 5}}
+		_ = json.NewEncoder(w).Encode(resp) //nolint:errcheck
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &childReqs
+}
+
+func TestCLIMain_AgentRun_ReturnsChildAnswer(t *testing.T) {
+	toolsPath := writeEchoOKTool(t)
+	srv, childReqs := subagentServer(t, `{"prompt":"child task","tools":["ping"],"system":"be brief"}`, oai.Message{Role: oai.RoleAssistant, Channel: "final", Content: "child answer"})
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-prompt", "parent task", "-tools", toolsPath, "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-subagent-depth", "1"}, &out, &errb)
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if got := strings.TrimSpace(out.String()); got != `{"answer":"child answer","tokens":5}` {
+		t.Fatalf("stdout=%q", got)
+	}
+	if len(*childReqs) != 1 {
+		t.Fatalf("child requests=%d", len(*childReqs))
+	}
+	req := (*childReqs)[0]
+	if len(req.Tools) != 1 || req.Tools[0].Function.Name != "ping" {
+		t.Fatalf("child must only see the requested tools (and no agent.run at depth 0): %+v", req.Tools)
+	}
+	if req.Messages[0].Role != oai.RoleSystem || req.Messages[0].Content != "be brief" {
+		t.Fatalf("child system prompt: %+v", req.Messages[0])
+	}
+}
+
+func TestCLIMain_AgentRun_ChildErrorsBecomeToolErrors(t *testing.T) {
+	cases := []struct {
+		name, args, want string
+	}{
+		{"budget", `{"prompt":"child task","max_tokens":3}`, "token budget exhausted"},
+		{"unknown tool", `{"prompt":"child task","tools":["nope"]}`, `unknown tool \"nope\"`},
+		{"depth", `{"prompt":"child task","tools":["agent.run"]}`, "not available at this nesting depth"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// The child only thinks aloud, so it keeps looping until stopped
+			srv, _ := subagentServer(t, tc.args, oai.Message{Role: oai.RoleAssistant, Channel: "analysis", Content: "thinking"})
+			var out, errb bytes.Buffer
+			if code := cliMain([]string{"-prompt", "parent task", "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-subagent-depth", "1"}, &out, &errb); code != 0 {
+				t.Fatalf("exit=%d stderr=%s", code, errb.String())
+			}
+			if !strings.Contains(out.String(), `"error"`) || !strings.Contains(out.String(), tc.want) {
+				t.Fatalf("stdout=%q want error containing %q", out.String(), tc.want)
+			}
+		})
+	}
+}
+
+func TestCLIMain_AgentRun_DisabledByDefault(t *testing.T) {
+	var got []oai.Tool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req oai.ChatCompletionsRequest
+		_ = json.NewDecoder(r.Body).Decode(&req) //nolint:errcheck
+		got = req.Tools
+		_ = json.NewEncoder(w).Encode(oai.ChatCompletionsResponse{Choices: []oai.ChatCompletionsResponseChoice{{Message: oai.Message{Role: oai.RoleAssistant, Channel: "final", Content: "done"}}}}) //nolint:errcheck
+	}))
+	defer srv.Close()
+	var out, errb bytes.Buffer
+	if code := cliMain([]string{"-prompt", "q", "-base-url", srv.URL, "-model", "m", "-prep-enabled=false"}, &out, &errb); code != 0 || len(got) != 0 {
+		t.Fatalf("exit=%d tools=%+v", code, got)
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+	"github.com/hyperifyio/goagent/internal/tools"
+)
+
+// taskCompleteTool is the built-in tool the model uses to report the outcome
+// of each -tasks checklist item.
+const taskCompleteTool = "task.complete"
+
+const taskCompleteSchema = `{"type":"object","properties":{` +
+	`"task":{"type":"integer","minimum":1,"description":"Task number from the checklist"},` +
+	`"status":{"type":"string","enum":["done","skipped","failed"]},` +
+	`"note":{"type":"string","description":"Optional short result or reason"}},` +
+	`"required":["task","status"],"additionalProperties":false}`
+
+// checklistHeader opens the developer message listing the -tasks checklist.
+const checklistHeader = "Checklist for this run. Work through every task and call task.complete with its number and status (done, skipped, or failed) as each one finishes:"
+
+// maxChecklistTasks bounds the checklist loaded from -tasks.
+const maxChecklistTasks = 100
+
+// Checklist item states; pending items were never reported.
+const (
+	checklistPending = "pending"
+	checklistDone    = "done"
+	checklistSkipped = "skipped"
+	checklistFailed  = "failed"
+)
+
+// reChecklistItem matches Markdown list items, with or without a checkbox:
+// "- [ ] task", "* [x] task", "1. task".
+var reChecklistItem = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+(?:\[([ xX])\]\s+)?(.+?)\s*$`)
+
+type checklistItem struct {
+	ID     int    `json:"id"`
+	Title  string `json:"title"`
+	Status string `json:"status"`
+	Note   string `json:"note,omitempty"`
+}
+
+// checklist is the -tasks state shared by the run's concurrent tool calls.
+type checklist struct {
+	mu    sync.Mutex
+	items []checklistItem
+}
+
+// loadChecklist reads a Markdown checklist or a JSON array of task strings
+// or {"title": ...} objects, optionally wrapped as {"tasks": [...]}. Items
+// already checked in Markdown start as done.
+func loadChecklist(path string) (*checklist, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read -tasks: %w", err)
+	}
+	var items []checklistItem
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		items, err = parseChecklistJSON(data)
+	} else {
+		items, err = parseChecklistMarkdown(string(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse -tasks %s: %w", path, err)
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("-tasks %s has no tasks", path)
+	}
+	if len(items) > maxChecklistTasks {
+		return nil, fmt.Errorf("-tasks %s has %d tasks; at most %d are supported", path, len(items), maxChecklistTasks)
+	}
+	for i := range items {
+		items[i].ID = i + 1
+	}
+	return &checklist{items: items}, nil
+}
+
+func parseChecklistMarkdown(text string) ([]checklistItem, error) {
+	var items []checklistItem
+	for _, line := range strings.Split(text, "\n") {
+		m := reChecklistItem.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		it := checklistItem{Title: m[2], Status: checklistPending}
+		if strings.EqualFold(m[1], "x") {
+			it.Status = checklistDone
+		}
+		items = append(items, it)
+	}
+	return items, nil
+}
+
+func parseChecklistJSON(data []byte) ([]checklistItem, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		var doc struct {
+			Tasks []json.RawMessage `json:"tasks"`
+		}
+		if derr := json.Unmarshal(data, &doc); derr != nil || doc.Tasks == nil {
+			return nil, errors.New(`want a JSON array of tasks or {"tasks": [...]}`)
+		}
+		raw = doc.Tasks
+	}
+	items := make([]checklistItem, 0, len(raw))
+	for i, r := range raw {
+		var title string
+		if err := json.Unmarshal(r, &title); err != nil {
+			var obj struct {
+				Title string `json:"title"`
+				Task  string `json:"task"`
+			}
+			if err := json.Unmarshal(r, &obj); err != nil {
+				return nil, fmt.Errorf("task %d: want a string or {\"title\": ...}", i+1)
+			}
+			title = obj.Title
+			if title == "" {
+				title = obj.Task
+			}
+		}
+		title = strings.Join(strings.Fields(title), " ")
+		if title == "" {
+			return nil, fmt.Errorf("task %d: empty title", i+1)
+		}
+		items = append(items, checklistItem{Title: title, Status: checklistPending})
+	}
+	return items, nil
+}
+
+// inject adds the checklist as a developer message before the first user
+// message, unless the transcript (e.g. from -load-messages) already has it.
+func (c *checklist) inject(messages []oai.Message) []oai.Message {
+	for _, m := range messages {
+		if m.Role == oai.RoleDeveloper && strings.HasPrefix(m.Content, checklistHeader) {
+			return messages
+		}
+	}
+	var sb strings.Builder
+	sb.WriteString(checklistHeader)
+	for _, it := range c.items {
+		fmt.Fprintf(&sb, "\n%d. %s", it.ID, it.Title)
+		if it.Status == checklistDone {
+			sb.WriteString(" (already done)")
+		}
+	}
+	msg := oai.Message{Role: oai.RoleDeveloper, Content: sb.String()}
+	for i, m := range messages {
+		if m.Role == oai.RoleUser {
+			out := append(append(append([]oai.Message{}, messages[:i]...), msg), messages[i:]...)
+			return out
+		}
+	}
+	return append(messages, msg)
+}
+
+// addTool registers task.complete; a -tools entry with the same name conflicts.
+func (c *checklist) addTool(registry map[string]tools.ToolSpec, oaiTools []oai.Tool) (map[string]tools.ToolSpec, []oai.Tool, error) {
+	if _, exists := registry[taskCompleteTool]; exists {
+		return nil, nil, fmt.Errorf("tool %q in -tools conflicts with the built-in -tasks tool", taskCompleteTool)
+	}
+	if registry == nil {
+		registry = make(map[string]tools.ToolSpec)
+	}
+	spec := tools.ToolSpec{
+		Name:        taskCompleteTool,
+		Description: "Report the outcome of a checklist task: done, skipped, or failed, with an optional note. Returns the tasks still open.",
+		Schema:      json.RawMessage(taskCompleteSchema),
+	}
+	registry[taskCompleteTool] = spec
+	oaiTools = append(oaiTools, oai.Tool{Type: "function", Function: oai.ToolFunction{Name: spec.Name, Description: spec.Description, Parameters: spec.Schema}})
+	return registry, oaiTools, nil
+}
+
+// complete applies a task.complete call. A task may be reported again, e.g.
+// done after an earlier failure; the last report wins.
+func (c *checklist) complete(argsJSON []byte) ([]byte, error) {
+	var args struct {
+		Task   int    `json:"task"`
+		Status string `json:"status"`
+		Note   string `json:"note"`
+	}
+	if err := json.Unmarshal(argsJSON, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %v", err)
+	}
+	switch args.Status {
+	case checklistDone, checklistSkipped, checklistFailed:
+	default:
+		return nil, fmt.Errorf("status must be one of done, skipped, failed")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if args.Task < 1 || args.Task > len(c.items) {
+		return nil, fmt.Errorf("task must be between 1 and %d", len(c.items))
+	}
+	it := &c.items[args.Task-1]
+	it.Status = args.Status
+	it.Note = strings.Join(strings.Fields(args.Note), " ")
+	open := []checklistItem{}
+	for _, it := range c.items {
+		if it.Status == checklistPending {
+			open = append(open, it)
+		}
+	}
+	return json.Marshal(map[string]any{"recorded": *it, "open": open})
+}
+
+// report prints the final checklist: a count line, then one line per task
+// marked [x] done, [-] skipped, [!] failed, or [ ] never reported.
+func (c *checklist) report(w io.Writer) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counts := map[string]int{}
+	var sb strings.Builder
+	for _, it := range c.items {
+		counts[it.Status]++
+		mark := " "
+		switch it.Status {
+		case checklistDone:
+			mark = "x"
+		case checklistSkipped:
+			mark = "-"
+		case checklistFailed:
+			mark = "!"
+		}
+		fmt.Fprintf(&sb, "\n[%s] %d. %s", mark, it.ID, it.Title)
+		if it.Note != "" {
+			fmt.Fprintf(&sb, " — %s", it.Note)
+		}
+	}
+	safeFprintf(w, "checklist: %d done, %d skipped, %d failed, %d pending%s\n",
+		counts[checklistDone], counts[checklistSkipped], counts[checklistFailed], counts[checklistPending], sb.String())
+}
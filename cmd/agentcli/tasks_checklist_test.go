@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+func TestLoadChecklist_MarkdownAndJSON(t *testing.T) {
+	dir := t.TempDir()
+	md := filepath.Join(dir, "tasks.md")
+	if err := os.WriteFile(md, []byte("# Release\n\n- [ ] Bump version\n- [x] Write notes\n* Tag release\n1. Announce\nnot a task\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := loadChecklist(md)
+	if err != nil {
+		t.Fatalf("load md: %v", err)
+	}
+	want := []checklistItem{{1, "Bump version", checklistPending, ""}, {2, "Write notes", checklistDone, ""}, {3, "Tag release", checklistPending, ""}, {4, "Announce", checklistPending, ""}}
+	if len(c.items) != len(want) {
+		t.Fatalf("items=%+v", c.items)
+	}
+	for i := range want {
+		if c.items[i] != want[i] {
+			t.Fatalf("item %d=%+v want %+v", i, c.items[i], want[i])
+		}
+	}
+	js := filepath.Join(dir, "tasks.json")
+	if err := os.WriteFile(js, []byte(`{"tasks":["a",{"title":"b"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if c, err = loadChecklist(js); err != nil || len(c.items) != 2 || c.items[1].Title != "b" {
+		t.Fatalf("load json: %+v %v", c, err)
+	}
+	empty := filepath.Join(dir, "empty.md")
+	if err := os.WriteFile(empty, []byte("nothing here\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadChecklist(empty); err == nil || !strings.Contains(err.Error(), "no tasks") {
+		t.Fatalf("empty checklist err=%v", err)
+	}
+}
+
+func TestCLIMain_Tasks_TracksAndReports(t *testing.T) {
+	tasks := filepath.Join(t.TempDir(), "tasks.md")
+	if err := os.WriteFile(tasks, []byte("- [ ] Fix bug\n- [ ] Add test\n- [ ] Update docs\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var firstReq oai.ChatCompletionsRequest
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req oai.ChatCompletionsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		calls++
+		msg := oai.Message{Role: oai.RoleAssistant, Channel: "final", Content: "finished"}
+		if calls == 1 {
+			firstReq = req
+			msg = oai.Message{Role: oai.RoleAssistant, ToolCalls: []oai.ToolCall{
+				{ID: "c1", Type: "function", Function: oai.ToolCallFunction{Name: taskCompleteTool, Arguments: `{"task":1,"status":"done","note":"nil check added"}`}},
+				{ID: "c2", Type: "function", Function: oai.ToolCallFunction{Name: taskCompleteTool, Arguments: `{"task":3,"status":"skipped","note":"no docs affected"}`}},
+			}}
+		}
+		_ = json.NewEncoder(w).Encode(oai.ChatCompletionsResponse{Choices: []oai.ChatCompletionsResponseChoice{{Message: msg}}}) //nolint:errcheck
+	}))
+	defer srv.Close()
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-prompt", "go", "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-tasks", tasks}, &out, &errb)
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if len(firstReq.Tools) != 1 || firstReq.Tools[0].Function.Name != taskCompleteTool {
+		t.Fatalf("tools=%+v", firstReq.Tools)
+	}
+	dev := firstReq.Messages[1]
+	if dev.Role != oai.RoleDeveloper || !strings.Contains(dev.Content, "\n2. Add test\n") || firstReq.Messages[2].Role != oai.RoleUser {
+		t.Fatalf("checklist message: %+v", firstReq.Messages)
+	}
+	want := "checklist: 1 done, 1 skipped, 0 failed, 1 pending\n[x] 1. Fix bug — nil check added\n[ ] 2. Add test\n[-] 3. Update docs — no docs affected\n"
+	if !strings.HasSuffix(errb.String(), want) {
+		t.Fatalf("stderr=%q want suffix %q", errb.String(), want)
+	}
+}
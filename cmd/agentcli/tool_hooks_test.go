@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/tools"
+)
+
+func TestCLIMain_ToolHookCmd_BlocksAndRewrites(t *testing.T) {
+	toolsPath := writeEchoOKTool(t)
+	hook := filepath.Join(t.TempDir(), "hook.sh")
+	script := `#!/bin/sh
+in=$(cat)
+case "$in" in
+*'"event":"before_tool_call"'*'"call_id":"c1"'*) echo '{"deny":"no c1"}' ;;
+*'"event":"after_tool_call"'*) echo '{"output":"{\"hooked\":true}"}' ;;
+esac
+`
+	if err := os.WriteFile(hook, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// In-process hooks run first and see every call
+	var seen atomic.Int32
+	defer tools.RegisterHook(tools.HookFuncs{Before: func(context.Context, *tools.Call) error {
+		seen.Add(1)
+		return nil
+	}})()
+	srv := twoPingServer(t)
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-prompt", "q", "-tools", toolsPath, "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-tool-hook-cmd", hook}, &out, &errb)
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	got := strings.TrimSpace(out.String())
+	if !strings.Contains(got, `c1={"error":"tool call blocked by hook: no c1"}`) || !strings.Contains(got, `c2={"hooked":true}`) {
+		t.Fatalf("stdout=%q", got)
+	}
+	if seen.Load() != 2 {
+		t.Fatalf("registered hook saw %d calls", seen.Load())
+	}
+}
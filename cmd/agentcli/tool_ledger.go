@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/clock"
+	"github.com/hyperifyio/goagent/internal/oai"
+	"github.com/hyperifyio/goagent/internal/tools"
+)
+
+// callLedger records the results of completed mutating tool calls under
+// -state-dir/calls, one file per idempotency key. Only a pending call of a
+// resumed transcript consults it: one that ran before a crash reuses the
+// recorded result instead of writing twice. Live calls never do, as call
+// ids such as react_1 repeat across runs and a repeated call must run.
+type callLedger struct {
+	dir string
+}
+
+// ledgerEntry is one completed call.
+type ledgerEntry struct {
+	Tool    string `json:"tool"`
+	CallID  string `json:"call_id"`
+	Content string `json:"content"`
+	TS      string `json:"ts"`
+}
+
+// callLedgerFor returns the ledger of cfg's -state-dir, or nil without one.
+func callLedgerFor(cfg cliConfig) *callLedger {
+	if strings.TrimSpace(cfg.stateDir) == "" {
+		return nil
+	}
+	return &callLedger{dir: filepath.Join(cfg.stateDir, "calls")}
+}
+
+// lookup returns the recorded tool message content for key.
+func (l *callLedger) lookup(key string) (string, bool) {
+	if l == nil {
+		return "", false
+	}
+	b, err := os.ReadFile(filepath.Join(l.dir, key+".json"))
+	if err != nil {
+		return "", false
+	}
+	var e ledgerEntry
+	if json.Unmarshal(b, &e) != nil {
+		return "", false
+	}
+	return e.Content, true
+}
+
+// record stores a completed call; it is best-effort, as a missing entry
+// only means the call would run again.
+func (l *callLedger) record(key string, call oai.ToolCall, content string) {
+	if l == nil {
+		return
+	}
+	b, err := json.Marshal(ledgerEntry{Tool: call.Function.Name, CallID: call.ID, Content: content, TS: clock.Now().UTC().Format(time.RFC3339Nano)})
+	if err != nil {
+		return
+	}
+	_ = writeFileAtomic(filepath.Join(l.dir, key+".json"), b, 0o600) //nolint:errcheck
+}
+
+// resumePendingToolCalls runs the tool calls of a loaded transcript that
+// ends in an assistant message whose calls have not all been answered, so
+// an interrupted run picks up where it stopped. Calls recorded in the
+// ledger return their recorded results without running again.
+func resumePendingToolCalls(ctx context.Context, messages []oai.Message, registry map[string]tools.ToolSpec, cfg cliConfig, stderr io.Writer) []oai.Message {
+	last := -1
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role != oai.RoleTool {
+			last = i
+			break
+		}
+	}
+	if last < 0 || messages[last].Role != oai.RoleAssistant || len(messages[last].ToolCalls) == 0 || len(registry) == 0 {
+		return messages
+	}
+	answered := map[string]bool{}
+	for _, m := range messages[last+1:] {
+		answered[m.ToolCallID] = true
+	}
+	pending := messages[last]
+	pending.ToolCalls = nil
+	for _, tc := range messages[last].ToolCalls {
+		if !answered[tc.ID] {
+			pending.ToolCalls = append(pending.ToolCalls, tc)
+		}
+	}
+	if len(pending.ToolCalls) == 0 {
+		return messages
+	}
+	safeFprintf(stderr, "info: resuming %d pending tool call(s) from the loaded transcript\n", len(pending.ToolCalls))
+	cfg.resuming = true
+	return appendToolCallOutputs(ctx, messages, pending, registry, cfg)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+	"github.com/hyperifyio/goagent/internal/tools"
+)
+
+func TestResumePendingToolCalls_SkipsRecordedMutatingCalls(t *testing.T) {
+	dir := t.TempDir()
+	runs := filepath.Join(dir, "runs")
+	mutates := true
+	// The tool logs its idempotency key and echoes it back
+	registry := map[string]tools.ToolSpec{"write": {
+		Name:    "write",
+		Command: []string{"/bin/sh", "-c", `echo "$GOAGENT_IDEMPOTENCY_KEY" >> "$0"; printf '{"key":"%s"}' "$GOAGENT_IDEMPOTENCY_KEY"`, runs},
+		Mutates: &mutates,
+	}}
+	messages := []oai.Message{
+		{Role: oai.RoleUser, Content: "write a and b"},
+		{Role: oai.RoleAssistant, ToolCalls: []oai.ToolCall{
+			{ID: "c1", Type: "function", Function: oai.ToolCallFunction{Name: "write", Arguments: `{"path":"a"}`}},
+			{ID: "c2", Type: "function", Function: oai.ToolCallFunction{Name: "write", Arguments: `{"path":"b"}`}},
+		}},
+		{Role: oai.RoleTool, ToolCallID: "c1", Name: "write", Content: `{"ok":true}`},
+	}
+	cfg := cliConfig{stateDir: filepath.Join(dir, "state"), toolTimeout: 5 * time.Second}
+	key := tools.IdempotencyKey("c2", `{ "path": "b" }`)
+
+	first := resumePendingToolCalls(context.Background(), messages, registry, cfg, io.Discard)
+	if len(first) != 4 || first[3].ToolCallID != "c2" || first[3].Content != `{"key":"`+key+`"}` {
+		t.Fatalf("messages=%+v", first)
+	}
+	// Resuming the same transcript again reuses the recorded result
+	second := resumePendingToolCalls(context.Background(), messages, registry, cfg, io.Discard)
+	if len(second) != 4 || second[3].Content != first[3].Content {
+		t.Fatalf("messages=%+v", second)
+	}
+	b, err := os.ReadFile(runs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(b)); got != key {
+		t.Fatalf("tool ran with keys %q, want once with %s", got, key)
+	}
+}
+
+func TestResumePendingToolCalls_NothingPending(t *testing.T) {
+	messages := []oai.Message{{Role: oai.RoleUser, Content: "hi"}, {Role: oai.RoleAssistant, Content: "hello"}}
+	if got := resumePendingToolCalls(context.Background(), messages, map[string]tools.ToolSpec{"x": {Name: "x"}}, cliConfig{}, io.Discard); len(got) != 2 {
+		t.Fatalf("messages=%+v", got)
+	}
+}
+
+func TestAppendToolCallOutputs_IdempotencyKeyCoversHookRewrittenArgs(t *testing.T) {
+	unregister := tools.RegisterHook(tools.HookFuncs{Before: func(ctx context.Context, call *tools.Call) error {
+		call.Args = []byte(`{"path":"rewritten"}`)
+		return nil
+	}})
+	defer unregister()
+	mutates := true
+	registry := map[string]tools.ToolSpec{"write": {
+		Name:    "write",
+		Command: []string{"/bin/sh", "-c", `printf '{"key":"%s"}' "$GOAGENT_IDEMPOTENCY_KEY"`},
+		Mutates: &mutates,
+	}}
+	assistant := oai.Message{Role: oai.RoleAssistant, ToolCalls: []oai.ToolCall{
+		{ID: "c1", Type: "function", Function: oai.ToolCallFunction{Name: "write", Arguments: `{"path":"a"}`}},
+	}}
+	cfg := cliConfig{stateDir: filepath.Join(t.TempDir(), "state"), toolTimeout: 5 * time.Second}
+	key := tools.IdempotencyKey("c1", `{"path":"rewritten"}`)
+
+	got := appendToolCallOutputs(context.Background(), nil, assistant, registry, cfg)
+	if len(got) != 1 || got[0].Content != `{"key":"`+key+`"}` {
+		t.Fatalf("messages=%+v", got)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.stateDir, "calls", key+".json")); err != nil {
+		t.Fatalf("ledger entry under the rewritten key: %v", err)
+	}
+}
+
+func TestAppendToolCallOutputs_LiveCallsIgnoreLedger(t *testing.T) {
+	runs := filepath.Join(t.TempDir(), "runs")
+	mutates := true
+	registry := map[string]tools.ToolSpec{"exec": {
+		Name:    "exec",
+		Command: []string{"/bin/sh", "-c", `echo ran >> "$0"; echo '{"ok":true}'`, runs},
+		Mutates: &mutates,
+	}}
+	// A deterministic call id repeats the same call in a later run
+	assistant := oai.Message{Role: oai.RoleAssistant, ToolCalls: []oai.ToolCall{
+		{ID: "react_1", Type: "function", Function: oai.ToolCallFunction{Name: "exec", Arguments: `{"cmd":"go test"}`}},
+	}}
+	cfg := cliConfig{stateDir: filepath.Join(t.TempDir(), "state"), toolTimeout: 5 * time.Second}
+	for i := 0; i < 2; i++ {
+		appendToolCallOutputs(context.Background(), nil, assistant, registry, cfg)
+	}
+	b, err := os.ReadFile(runs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(b), "ran"); n != 2 {
+		t.Fatalf("tool ran %d times, want 2", n)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+	"github.com/hyperifyio/goagent/internal/tools"
+)
+
+// applyToolProtocol rewrites a native request for the selected -tool-protocol.
+// The transcript itself always stays in native form; only the wire request is
+// translated, so saved messages and state bundles are protocol-independent.
+func applyToolProtocol(protocol string, req oai.ChatCompletionsRequest) oai.ChatCompletionsRequest {
+	switch protocol {
+	case oai.ToolProtocolFunctions:
+		return oai.ApplyFunctionsProtocol(req)
+	case oai.ToolProtocolText:
+		return oai.ApplyTextProtocol(req)
+	default:
+		return req
+	}
+}
+
+// adoptProtocolToolCalls normalizes an assistant reply into native tool_calls.
+// Under the text protocol, fenced ```tool blocks are parsed and strictly
+// validated against the registry; a non-nil error means the reply contained
+// tool blocks that must not be executed.
+func adoptProtocolToolCalls(protocol string, msg oai.Message, registry map[string]tools.ToolSpec, step int) (oai.Message, error) {
+	switch protocol {
+	case oai.ToolProtocolFunctions:
+		return oai.AdoptFunctionCall(msg, fmt.Sprintf("fn_call_%d", step+1)), nil
+	case oai.ToolProtocolText:
+		if len(registry) == 0 || len(msg.ToolCalls) > 0 {
+			return msg, nil
+		}
+		known := make(map[string]bool, len(registry))
+		for name := range registry {
+			known[name] = true
+		}
+		calls, err := oai.ParseTextToolCalls(msg.Content, known, fmt.Sprintf("text_call_%d", step+1))
+		if err != nil {
+			return msg, err
+		}
+		msg.ToolCalls = calls
+		return msg, nil
+	default:
+		return msg, nil
+	}
+}
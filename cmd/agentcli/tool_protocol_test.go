@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// writeEchoOKTool writes a shell tool that ignores stdin and prints {"ok":true},
+// plus a manifest advertising it as "ping". Returns the manifest path.
+func writeEchoOKTool(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell tool fixture requires a POSIX shell")
+	}
+	dir := t.TempDir()
+	script := filepath.Join(dir, "ping.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\ncat >/dev/null\necho '{\"ok\":true}'\n"), 0o755); err != nil {
+		t.Fatalf("write tool: %v", err)
+	}
+	man := map[string]any{"tools": []map[string]any{{
+		"name":    "ping",
+		"schema":  map[string]any{"type": "object", "properties": map[string]any{"host": map[string]any{"type": "string"}}},
+		"command": []string{script},
+	}}}
+	b, err := json.Marshal(man)
+	if err != nil {
+		t.Fatalf("marshal manifest: %v", err)
+	}
+	path := filepath.Join(dir, "tools.json")
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	return path
+}
+
+func textProtocolConfig(toolsPath, baseURL string) cliConfig {
+	return cliConfig{
+		prompt:         "ping it",
+		systemPrompt:   "sys",
+		toolsPath:      toolsPath,
+		baseURL:        baseURL,
+		model:          "llama-3.1-8b",
+		maxSteps:       4,
+		httpTimeout:    10 * time.Second,
+		toolTimeout:    10 * time.Second,
+		prepEnabledSet: true,
+		toolProtocol:   oai.ToolProtocolText,
+	}
+}
+
+func TestRunAgent_TextToolProtocol_ExecutesToolBlocks(t *testing.T) {
+	toolsPath := writeEchoOKTool(t)
+	step := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req oai.ChatCompletionsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if len(req.Tools) != 0 || req.ToolChoice != "" {
+			t.Fatalf("text protocol must not send native tools: %+v", req.Tools)
+		}
+		step++
+		var reply string
+		switch step {
+		case 1:
+			if !strings.Contains(req.Messages[0].Content, "```tool") || !strings.Contains(req.Messages[0].Content, "- ping") {
+				t.Fatalf("system prompt missing tool instructions: %q", req.Messages[0].Content)
+			}
+			reply = "Checking.\n```tool\n{\"name\":\"ping\",\"arguments\":{\"host\":\"a\"}}\n```"
+		case 2:
+			for _, m := range req.Messages {
+				if m.Role == oai.RoleTool || len(m.ToolCalls) > 0 {
+					t.Fatalf("native tool turns leaked into text request: %+v", m)
+				}
+			}
+			last := req.Messages[len(req.Messages)-1]
+			if last.Role != oai.RoleUser || !strings.HasPrefix(last.Content, "Tool result for ping (id text_call_1_1):") {
+				t.Fatalf("expected tool result as user message, got %+v", last)
+			}
+			reply = "pong"
+		default:
+			t.Fatalf("unexpected extra request step=%d", step)
+		}
+		_ = json.NewEncoder(w).Encode(oai.ChatCompletionsResponse{Choices: []oai.ChatCompletionsResponseChoice{{ //nolint:errcheck
+			Message: oai.Message{Role: oai.RoleAssistant, Content: reply},
+		}}})
+	}))
+	defer srv.Close()
+
+	var out, errb bytes.Buffer
+	if code := runAgent(textProtocolConfig(toolsPath, srv.URL), &out, &errb); code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if out.String() != "pong\n" {
+		t.Fatalf("stdout=%q", out.String())
+	}
+}
+
+func TestRunAgent_TextToolProtocol_RejectsUnknownTool(t *testing.T) {
+	toolsPath := writeEchoOKTool(t)
+	step := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req oai.ChatCompletionsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		step++
+		reply := "done"
+		if step == 1 {
+			reply = "```tool\n{\"name\":\"rm_rf\",\"arguments\":{}}\n```"
+		} else {
+			last := req.Messages[len(req.Messages)-1]
+			if last.Role != oai.RoleUser || !strings.Contains(last.Content, `unknown tool "rm_rf"`) {
+				t.Fatalf("expected rejection feedback, got %+v", last)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(oai.ChatCompletionsResponse{Choices: []oai.ChatCompletionsResponseChoice{{ //nolint:errcheck
+			Message: oai.Message{Role: oai.RoleAssistant, Content: reply},
+		}}})
+	}))
+	defer srv.Close()
+
+	var out, errb bytes.Buffer
+	if code := runAgent(textProtocolConfig(toolsPath, srv.URL), &out, &errb); code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if step != 2 || out.String() != "done\n" {
+		t.Fatalf("step=%d stdout=%q", step, out.String())
+	}
+}
+
+func TestToolProtocolFlag_Validation(t *testing.T) {
+	orig := os.Args
+	defer func() { os.Args = orig }()
+	t.Setenv("OAI_TOOL_PROTOCOL", "")
+
+	os.Args = []string{"agentcli.test", "-prompt", "p"}
+	if cfg, code := parseFlags(); code != 0 || cfg.toolProtocol != oai.ToolProtocolNative {
+		t.Fatalf("default: code=%d protocol=%q", code, cfg.toolProtocol)
+	}
+	os.Args = []string{"agentcli.test", "-prompt", "p", "-tool-protocol", "TEXT"}
+	if cfg, code := parseFlags(); code != 0 || cfg.toolProtocol != oai.ToolProtocolText {
+		t.Fatalf("text: code=%d protocol=%q", code, cfg.toolProtocol)
+	}
+	os.Args = []string{"agentcli.test", "-prompt", "p", "-tool-protocol", "xml"}
+	if _, code := parseFlags(); code != 2 {
+		t.Fatalf("invalid -tool-protocol: exit=%d want 2", code)
+	}
+}
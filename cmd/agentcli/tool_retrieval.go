@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// defaultEmbedModel is the -embed-model used by -tools-topk.
+const defaultEmbedModel = "text-embedding-3-small"
+
+// maxRetrievalQueryBytes bounds the step context matched against tools.
+const maxRetrievalQueryBytes = 4000
+
+// embedder is the part of the chat client tool retrieval uses.
+type embedder interface {
+	CreateEmbeddings(ctx context.Context, model string, inputs []string) ([][]float64, error)
+}
+
+// toolRetriever implements -tools-topk: each step advertises only the k
+// tools whose descriptions best match the conversation so far, plus every
+// tool already called, so large manifests do not cost their full schema on
+// every request. Tools are ranked by embedding similarity; when the
+// embeddings endpoint fails the retriever warns once and ranks by word
+// overlap instead.
+type toolRetriever struct {
+	k       int
+	model   string
+	client  embedder
+	stderr  io.Writer
+	verbose bool
+
+	// Tool vectors by tool name, embedded once per run; lexical is set
+	// once the embeddings endpoint has failed
+	toolVecs map[string][]float64
+	lexical  bool
+}
+
+func newToolRetriever(cfg cliConfig, client embedder, stderr io.Writer) *toolRetriever {
+	if cfg.toolsTopK <= 0 {
+		return nil
+	}
+	return &toolRetriever{k: cfg.toolsTopK, model: cfg.embedModel, client: client, stderr: stderr, verbose: cfg.verbose}
+}
+
+// toolsFor returns the tools to advertise at step, in manifest order.
+func (r *toolRetriever) toolsFor(ctx context.Context, step int, messages []oai.Message, tools []oai.Tool) []oai.Tool {
+	if r == nil || len(tools) <= r.k {
+		return tools
+	}
+	scores := r.score(ctx, retrievalQuery(messages), tools)
+	order := make([]int, len(tools))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+
+	called := calledTools(messages)
+	keep := make([]bool, len(tools))
+	for i, t := range tools {
+		keep[i] = called[t.Function.Name]
+	}
+	for _, i := range order[:r.k] {
+		keep[i] = true
+	}
+	out := make([]oai.Tool, 0, r.k+len(called))
+	names := make([]string, 0, cap(out))
+	for i, t := range tools {
+		if keep[i] {
+			out = append(out, t)
+			names = append(names, t.Function.Name)
+		}
+	}
+	if r.verbose {
+		safeFprintf(r.stderr, "info: step %d advertises %d of %d tools: %s\n", step, len(out), len(tools), strings.Join(names, ", "))
+	}
+	return out
+}
+
+// score ranks every tool against query, by embeddings unless they failed.
+func (r *toolRetriever) score(ctx context.Context, query string, tools []oai.Tool) []float64 {
+	if !r.lexical {
+		if scores, err := r.embeddingScores(ctx, query, tools); err == nil {
+			return scores
+		} else if ctx.Err() == nil {
+			safeFprintf(r.stderr, "WARN: -tools-topk: embeddings unavailable (%v); ranking tools by word overlap\n", err)
+			r.lexical = true
+		}
+	}
+	return lexicalScores(query, tools)
+}
+
+// embeddingScores embeds the step's query, and any tool not embedded yet,
+// in one request; tool vectors are kept for the rest of the run.
+func (r *toolRetriever) embeddingScores(ctx context.Context, query string, tools []oai.Tool) ([]float64, error) {
+	if r.toolVecs == nil {
+		r.toolVecs = make(map[string][]float64, len(tools))
+	}
+	var missing []oai.Tool
+	var inputs []string
+	for _, t := range tools {
+		if _, ok := r.toolVecs[t.Function.Name]; !ok {
+			missing = append(missing, t)
+			inputs = append(inputs, toolDocument(t))
+		}
+	}
+	vecs, err := r.client.CreateEmbeddings(ctx, r.model, append(inputs, query))
+	if err != nil {
+		return nil, err
+	}
+	for i, t := range missing {
+		r.toolVecs[t.Function.Name] = vecs[i]
+	}
+	q := vecs[len(vecs)-1]
+	scores := make([]float64, len(tools))
+	for i, t := range tools {
+		scores[i] = cosine(q, r.toolVecs[t.Function.Name])
+	}
+	return scores, nil
+}
+
+// retrievalQuery is the text tools are matched against: the latest user
+// message and everything after it, keeping the most recent bytes.
+func retrievalQuery(messages []oai.Message) string {
+	start := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == oai.RoleUser {
+			start = i
+			break
+		}
+	}
+	var b strings.Builder
+	for _, m := range messages[start:] {
+		if m.Role == oai.RoleSystem || m.Role == oai.RoleDeveloper {
+			continue
+		}
+		b.WriteString(m.Content)
+		b.WriteByte('\n')
+	}
+	q := b.String()
+	if len(q) > maxRetrievalQueryBytes {
+		q = q[len(q)-maxRetrievalQueryBytes:]
+	}
+	return q
+}
+
+// calledTools returns the names of tools the transcript has called.
+func calledTools(messages []oai.Message) map[string]bool {
+	called := map[string]bool{}
+	for _, m := range messages {
+		for _, tc := range m.ToolCalls {
+			called[tc.Function.Name] = true
+		}
+	}
+	return called
+}
+
+func toolDocument(t oai.Tool) string {
+	return t.Function.Name + ": " + t.Function.Description
+}
+
+func cosine(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// lexicalScores ranks tools by the query words in their name and
+// description, weighting words that few tools mention higher.
+func lexicalScores(query string, tools []oai.Tool) []float64 {
+	docs := make([]map[string]bool, len(tools))
+	df := map[string]int{}
+	for i, t := range tools {
+		docs[i] = map[string]bool{}
+		for _, w := range words(toolDocument(t)) {
+			if !docs[i][w] {
+				docs[i][w] = true
+				df[w]++
+			}
+		}
+	}
+	scores := make([]float64, len(tools))
+	seen := map[string]bool{}
+	for _, w := range words(query) {
+		if seen[w] || df[w] == 0 {
+			continue
+		}
+		seen[w] = true
+		idf := math.Log(1 + float64(len(tools))/float64(df[w]))
+		for i := range docs {
+			if docs[i][w] {
+				scores[i] += idf
+			}
+		}
+	}
+	return scores
+}
+
+// words splits s into lower-case words of two or more letters or digits;
+// snake_case and dotted tool names split into their parts.
+func words(s string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	out := fields[:0]
+	for _, w := range fields {
+		if len(w) >= 2 {
+			out = append(out, w)
+		}
+	}
+	return out
+}
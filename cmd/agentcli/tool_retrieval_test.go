@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// fakeEmbedder maps text to a vector of keyword hits.
+type fakeEmbedder struct {
+	keys  []string
+	calls int
+	err   error
+}
+
+func (f *fakeEmbedder) CreateEmbeddings(_ context.Context, _ string, inputs []string) ([][]float64, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	out := make([][]float64, len(inputs))
+	for i, in := range inputs {
+		out[i] = make([]float64, len(f.keys))
+		for j, k := range f.keys {
+			if strings.Contains(strings.ToLower(in), k) {
+				out[i][j] = 1
+			}
+		}
+	}
+	return out, nil
+}
+
+func retrievalTools() []oai.Tool {
+	mk := func(name, desc string) oai.Tool {
+		return oai.Tool{Type: "function", Function: oai.ToolFunction{Name: name, Description: desc}}
+	}
+	return []oai.Tool{
+		mk("fs_read_file", "Read a file from the workspace"),
+		mk("http_fetch", "Fetch a URL over HTTP"),
+		mk("exec", "Run a command"),
+		mk("img_create", "Generate an image"),
+	}
+}
+
+func names(tools []oai.Tool) string {
+	var n []string
+	for _, t := range tools {
+		n = append(n, t.Function.Name)
+	}
+	return strings.Join(n, ",")
+}
+
+func TestToolRetriever_Embeddings(t *testing.T) {
+	emb := &fakeEmbedder{keys: []string{"file", "url", "command", "image"}}
+	var errb bytes.Buffer
+	r := newToolRetriever(cliConfig{toolsTopK: 1, embedModel: "e", verbose: true}, emb, &errb)
+	msgs := []oai.Message{{Role: oai.RoleSystem, Content: "sys"}, {Role: oai.RoleUser, Content: "fetch this url"}}
+	if got := names(r.toolsFor(context.Background(), 1, msgs, retrievalTools())); got != "http_fetch" {
+		t.Fatalf("step 1 tools=%s", got)
+	}
+	// The called tool stays advertised while the best match moves on
+	msgs = append(msgs,
+		oai.Message{Role: oai.RoleAssistant, ToolCalls: []oai.ToolCall{{ID: "1", Type: "function", Function: oai.ToolCallFunction{Name: "http_fetch"}}}},
+		oai.Message{Role: oai.RoleTool, ToolCallID: "1", Content: "now save it to a file"})
+	if got := names(r.toolsFor(context.Background(), 2, msgs, retrievalTools())); got != "fs_read_file,http_fetch" {
+		t.Fatalf("step 2 tools=%s", got)
+	}
+	if emb.calls != 2 {
+		t.Fatalf("tool vectors must be reused; calls=%d", emb.calls)
+	}
+	if !strings.Contains(errb.String(), "info: step 2 advertises 2 of 4 tools: fs_read_file, http_fetch") {
+		t.Fatalf("stderr=%q", errb.String())
+	}
+}
+
+func TestToolRetriever_FallsBackToWords(t *testing.T) {
+	emb := &fakeEmbedder{err: errors.New("404")}
+	var errb bytes.Buffer
+	r := newToolRetriever(cliConfig{toolsTopK: 2}, emb, &errb)
+	// img_create matches; the rest tie and keep manifest order
+	msgs := []oai.Message{{Role: oai.RoleUser, Content: "generate an image of a cat"}}
+	if got := names(r.toolsFor(context.Background(), 1, msgs, retrievalTools())); got != "fs_read_file,img_create" {
+		t.Fatalf("tools=%s", got)
+	}
+	r.toolsFor(context.Background(), 2, msgs, retrievalTools())
+	if emb.calls != 1 || strings.Count(errb.String(), "WARN: -tools-topk") != 1 {
+		t.Fatalf("calls=%d stderr=%q", emb.calls, errb.String())
+	}
+}
+
+func TestToolRetriever_Disabled(t *testing.T) {
+	if r := newToolRetriever(cliConfig{}, nil, nil); r != nil {
+		t.Fatal("want nil retriever without -tools-topk")
+	}
+	var r *toolRetriever
+	if got := r.toolsFor(context.Background(), 1, nil, retrievalTools()); len(got) != 4 {
+		t.Fatalf("tools=%d", len(got))
+	}
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 
 	"github.com/hyperifyio/goagent/internal/oai"
+	"github.com/hyperifyio/goagent/internal/redact"
 	"github.com/hyperifyio/goagent/internal/tools"
 )
 
@@ -14,13 +17,45 @@ type toolResult struct {
 }
 
 // appendToolCallOutputs executes assistant-requested tool calls and appends their outputs.
-func appendToolCallOutputs(messages []oai.Message, assistantMsg oai.Message, toolRegistry map[string]tools.ToolSpec, cfg cliConfig) []oai.Message {
+// Canceling ctx stops the running tool processes.
+func appendToolCallOutputs(ctx context.Context, messages []oai.Message, assistantMsg oai.Message, toolRegistry map[string]tools.ToolSpec, cfg cliConfig) []oai.Message {
 	results := make(chan toolResult, len(assistantMsg.ToolCalls))
+	hooks := toolHooks(cfg)
+	ledger := callLedgerFor(cfg)
 
 	// Launch each tool call concurrently
 	for _, tc := range assistantMsg.ToolCalls {
 		toolCall := tc // capture loop var
+		cfg.events.emit(runEvent{Kind: eventToolStart, Tool: toolCall.Function.Name, CallID: toolCall.ID})
 		spec, exists := toolRegistry[toolCall.Function.Name]
+		// -read-only: run tools that support it as dry runs; refuse other
+		// side effects without running or asking
+		dryRun := false
+		if exists && cfg.readOnly && tools.MutatesWorkspace(spec) && spec.SupportsDryRun {
+			if args, err := tools.WithDryRun([]byte(toolCall.Function.Arguments)); err == nil {
+				toolCall.Function.Arguments = string(args)
+				dryRun = true
+			}
+		}
+		if exists && cfg.readOnly && tools.MutatesWorkspace(spec) && !dryRun {
+			go func() {
+				content := sanitizeToolContent(nil, fmt.Errorf("tool %s is disabled in read-only mode; use a tool that does not modify the workspace", toolCall.Function.Name))
+				results <- toolResult{msg: oai.Message{Role: oai.RoleTool, Name: toolCall.Function.Name, ToolCallID: toolCall.ID, Content: content}}
+			}()
+			continue
+		}
+		// -approve-tools: ask before launching; a denied call gets a refusal
+		if exists && cfg.approver.requires(toolCall.Function.Name) {
+			approved, ok := cfg.approver.approve(toolCall)
+			if !ok {
+				go func() {
+					content := sanitizeToolContent(nil, fmt.Errorf("tool call denied by the user"))
+					results <- toolResult{msg: oai.Message{Role: oai.RoleTool, Name: toolCall.Function.Name, ToolCallID: toolCall.ID, Content: content}}
+				}()
+				continue
+			}
+			toolCall = approved
+		}
 		if !exists {
 			// Unknown tool: synthesize deterministic error JSON
 			go func() {
@@ -29,22 +64,115 @@ func appendToolCallOutputs(messages []oai.Message, assistantMsg oai.Message, too
 			}()
 			continue
 		}
+		// -chaos: fail the call without running it; drawn here so the
+		// sequence is reproducible despite concurrent execution
+		if err := cfg.injector.toolFault(toolCall.Function.Name); err != nil {
+			go func() {
+				results <- toolResult{msg: oai.Message{Role: oai.RoleTool, Name: toolCall.Function.Name, ToolCallID: toolCall.ID, Content: sanitizeToolContent(nil, err)}}
+			}()
+			continue
+		}
 
-		go func(spec tools.ToolSpec, toolCall oai.ToolCall) {
+		go func(spec tools.ToolSpec, toolCall oai.ToolCall, dryRun bool) {
 			argsJSON := strings.TrimSpace(toolCall.Function.Arguments)
 			if argsJSON == "" {
 				argsJSON = "{}"
 			}
-			out, runErr := tools.RunToolWithJSON(context.Background(), spec, []byte(argsJSON), cfg.toolTimeout)
-			content := sanitizeToolContent(out, runErr)
+			ledgered := tools.MutatesWorkspace(spec) && !dryRun
+			var key, recorded string
+			replayed := false
+			call := tools.Call{Tool: toolCall.Function.Name, CallID: toolCall.ID, Args: json.RawMessage(argsJSON)}
+			out, runErr := hooks.Run(ctx, call, func(ctx context.Context, args []byte) ([]byte, error) {
+				// Each call carries an idempotency key over the arguments the
+				// hooks settled on; a resumed mutating call already completed
+				// under -state-dir returns its recorded result
+				key = tools.IdempotencyKey(toolCall.ID, string(args))
+				spec.IdempotencyKey = key
+				if ledgered && cfg.resuming {
+					if content, ok := ledger.lookup(key); ok {
+						recorded, replayed = content, true
+						return []byte(content), nil
+					}
+				}
+				if spec.Name == agentRunTool && len(spec.Command) == 0 {
+					// Built-in: the nested agent runs in this process under ctx
+					return runSubagent(ctx, cfg, toolRegistry, args)
+				}
+				if spec.Name == planUpdateTool && len(spec.Command) == 0 && cfg.plan != nil {
+					return cfg.plan.update(args)
+				}
+				if spec.Name == taskCompleteTool && len(spec.Command) == 0 && cfg.checklist != nil {
+					return cfg.checklist.complete(args)
+				}
+				return tools.RunToolWithJSON(ctx, spec, args, cfg.toolTimeout)
+			})
+			if replayed && runErr == nil {
+				results <- toolResult{msg: oai.Message{Role: oai.RoleTool, Name: toolCall.Function.Name, ToolCallID: toolCall.ID, Content: recorded}}
+				return
+			}
+			content, bad := checkToolOutput(spec, out, runErr)
+			if !bad {
+				out, runErr = storeToolArtifact(out, runErr)
+				content = sanitizeToolContent(out, runErr)
+				if dryRun {
+					content = markDryRun(content)
+				}
+			}
+			if ledgered && runErr == nil {
+				ledger.record(key, toolCall, content)
+			}
 			results <- toolResult{msg: oai.Message{Role: oai.RoleTool, Name: toolCall.Function.Name, ToolCallID: toolCall.ID, Content: content}}
-		}(spec, toolCall)
+		}(spec, toolCall, dryRun)
 	}
 
 	// Collect exactly one result per requested tool call
 	for i := 0; i < len(assistantMsg.ToolCalls); i++ {
 		r := <-results
+		cfg.events.emit(runEvent{Kind: eventToolEnd, Tool: r.msg.Name, CallID: r.msg.ToolCallID, Failed: isToolError(r.msg.Content)})
 		messages = append(messages, r.msg)
 	}
 	return messages
 }
+
+// toolHooks returns the hooks registered through tools.RegisterHook followed
+// by the -tool-hook-cmd hook.
+func toolHooks(cfg cliConfig) tools.Hooks {
+	hooks := tools.RegisteredHooks()
+	if strings.TrimSpace(cfg.toolHookCmd) != "" {
+		hooks = append(hooks, tools.CommandHook{Command: cfg.toolHookCmd, Timeout: cfg.toolTimeout})
+	}
+	return hooks
+}
+
+// checkToolOutput validates a successful tool's stdout against its
+// outputSchema. Nonconforming output is replaced by a structured error naming
+// the violations, so malformed data never enters the transcript.
+func checkToolOutput(spec tools.ToolSpec, out []byte, runErr error) (content string, bad bool) {
+	if runErr != nil || len(spec.OutputSchema) == 0 {
+		return "", false
+	}
+	violations := tools.ValidateOutput(spec.OutputSchema, bytes.TrimSpace(out))
+	if len(violations) == 0 {
+		return "", false
+	}
+	b, err := json.Marshal(map[string]any{"error": "tool output does not match its outputSchema", "violations": violations})
+	if err != nil {
+		return `{"error":"tool output does not match its outputSchema"}`, true
+	}
+	return redact.String(string(b)), true
+}
+
+// markDryRun adds "dryRun": true to the JSON object result of a call that
+// -read-only turned into a dry run, so the model knows nothing changed.
+func markDryRun(content string) string {
+	var obj map[string]json.RawMessage
+	if json.Unmarshal([]byte(content), &obj) != nil || obj == nil {
+		return content
+	}
+	obj["dryRun"] = json.RawMessage("true")
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return content
+	}
+	return string(b)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/tools"
+)
+
+func TestCheckToolOutput(t *testing.T) {
+	spec := tools.ToolSpec{Name: "count", OutputSchema: json.RawMessage(`{"type":"object","required":["n"],"properties":{"n":{"type":"integer"}}}`)}
+	if _, bad := checkToolOutput(spec, []byte(`{"n":3}`+"\n"), nil); bad {
+		t.Fatal("conforming output rejected")
+	}
+	if _, bad := checkToolOutput(spec, nil, errors.New("boom")); bad {
+		t.Fatal("a failed run keeps its own error")
+	}
+	content, bad := checkToolOutput(spec, []byte(`{"n":"three"}`), nil)
+	if !bad {
+		t.Fatal("nonconforming output accepted")
+	}
+	var got struct {
+		Error      string   `json:"error"`
+		Violations []string `json:"violations"`
+	}
+	if err := json.Unmarshal([]byte(content), &got); err != nil {
+		t.Fatalf("content %q: %v", content, err)
+	}
+	if got.Error != "tool output does not match its outputSchema" || len(got.Violations) != 1 || got.Violations[0] != "$.n: expected integer, got string" {
+		t.Fatalf("content=%s", content)
+	}
+	if !isToolError(content) {
+		t.Fatal("violation must count as a tool error")
+	}
+}
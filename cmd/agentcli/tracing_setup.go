@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/tracing"
+)
+
+// startTracing installs an OTLP span exporter when OTEL_EXPORTER_OTLP_ENDPOINT
+// (or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT) is set. Tracing never blocks a run:
+// configuration and export errors are reported as warnings. The returned
+// function flushes buffered spans.
+func startTracing(stderr io.Writer) func() {
+	exp, err := tracing.NewExporterFromEnv()
+	if err != nil {
+		safeFprintf(stderr, "warning: tracing disabled: %v\n", err)
+		return func() {}
+	}
+	if exp == nil {
+		return func() {}
+	}
+	tracing.SetExporter(exp)
+	return func() {
+		tracing.SetExporter(nil)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := exp.Shutdown(ctx); err != nil {
+			safeFprintf(stderr, "warning: trace export failed: %v\n", err)
+		}
+	}
+}
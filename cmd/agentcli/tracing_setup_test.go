@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+type exportedSpan struct {
+	TraceID      string `json:"traceId"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	Name         string `json:"name"`
+	Attributes   []struct {
+		Key   string         `json:"key"`
+		Value map[string]any `json:"value"`
+	} `json:"attributes"`
+}
+
+func (s exportedSpan) attr(key string) any {
+	for _, a := range s.Attributes {
+		if a.Key == key {
+			for _, v := range a.Value {
+				return v
+			}
+		}
+	}
+	return nil
+}
+
+func TestCLIMain_OTLPTracing_ExportsStepChatAndToolSpans(t *testing.T) {
+	toolsPath := writeEchoOKTool(t)
+	var mu sync.Mutex
+	var spans []exportedSpan
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("export path %s", r.URL.Path)
+		}
+		var req struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []exportedSpan `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		b, _ := io.ReadAll(r.Body) //nolint:errcheck
+		if err := json.Unmarshal(b, &req); err != nil {
+			t.Errorf("decode export: %v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+	}))
+	defer collector.Close()
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", collector.URL)
+
+	var traceparents []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		var req oai.ChatCompletionsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		msg := oai.Message{Role: oai.RoleAssistant, ToolCalls: []oai.ToolCall{{ID: "c1", Type: "function", Function: oai.ToolCallFunction{Name: "ping", Arguments: "{}"}}}}
+		if req.Messages[len(req.Messages)-1].Role == oai.RoleTool {
+			msg = oai.Message{Role: oai.RoleAssistant, Channel: "final", Content: "done"}
+		}
+		resp := oai.ChatCompletionsResponse{Choices: []oai.ChatCompletionsResponseChoice{{Message: msg}}, Usage: &oai.Usage{PromptTokens: 9, CompletionTokens: 2, TotalTokens: 11}}
+		_ = json.NewEncoder(w).Encode(resp) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	var out, errb bytes.Buffer
+	if code := cliMain([]string{"-prompt", "p", "-tools", toolsPath, "-base-url", srv.URL, "-model", "m", "-prep-enabled=false"}, &out, &errb); code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+
+	byName := map[string][]exportedSpan{}
+	byID := map[string]exportedSpan{}
+	for _, s := range spans {
+		byName[s.Name] = append(byName[s.Name], s)
+		byID[s.SpanID] = s
+	}
+	if len(byName["agent.run"]) != 1 || len(byName["step"]) != 2 || len(byName["chat.request"]) != 2 || len(byName["tool.exec"]) != 1 {
+		t.Fatalf("unexpected spans: %+v", byName)
+	}
+	run := byName["agent.run"][0]
+	tool := byName["tool.exec"][0]
+	if tool.attr("tool.name") != "ping" || tool.attr("tool.exit_code") != "0" {
+		t.Fatalf("tool attrs: %+v", tool.Attributes)
+	}
+	if step := byID[tool.ParentSpanID]; step.Name != "step" || step.ParentSpanID != run.SpanID {
+		t.Fatalf("tool.exec not nested under a step of the run: %+v", step)
+	}
+	for i, chat := range byName["chat.request"] {
+		if chat.attr("gen_ai.usage.input_tokens") != "9" || chat.attr("gen_ai.request.model") != "m" || chat.TraceID != run.TraceID {
+			t.Fatalf("chat attrs: %+v", chat)
+		}
+		if !strings.Contains(strings.Join(traceparents, ","), chat.SpanID) {
+			t.Fatalf("chat span %d not propagated via traceparent: %v", i, traceparents)
+		}
+	}
+}
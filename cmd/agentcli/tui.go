@@ -0,0 +1,298 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/clock"
+)
+
+const (
+	tuiRefresh     = 100 * time.Millisecond
+	tuiToolRows    = 6
+	tuiOutputRows  = 8
+	tuiLogRows     = 3
+	tuiDefaultCols = 80
+	tuiBarWidth    = 20
+)
+
+// tuiSpinner animates running tool calls, one frame per refresh.
+const tuiSpinner = `|/-\`
+
+// toolActivity is one row of the dashboard's tool list.
+type toolActivity struct {
+	name    string
+	callID  string
+	started time.Time
+	took    time.Duration
+	done    bool
+	failed  bool
+}
+
+// dashboard is the -tui view: a live region redrawn in place at the bottom of
+// the terminal from the run's event stream. While it is open the run's stdout
+// and stderr are captured into its output and log panes; close erases the
+// region and replays both to the real streams, so the final transcript of the
+// terminal matches a run without -tui.
+type dashboard struct {
+	mu       sync.Mutex
+	term     io.Writer
+	cols     int
+	model    string
+	price    *benchPrice
+	started  time.Time
+	step     int
+	maxSteps int
+	// tokenBudget is -token-budget; 0 hides the token bar
+	tokenBudget int
+	usage       runUsage
+	tools       []toolActivity
+	out         strings.Builder
+	log         strings.Builder
+	drawn       int // lines of the live region currently on screen
+	dirty       bool
+	// Refresh loop control; nil when the loop was never started
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// newDashboard returns nil, after a warning, when stderr is not a terminal.
+func newDashboard(cfg cliConfig, stderr io.Writer) *dashboard {
+	if f, ok := stderr.(*os.File); !ok || !isTerminal(f) {
+		safeFprintln(stderr, "warning: -tui needs a terminal on stderr; continuing without the dashboard")
+		return nil
+	}
+	d := openDashboard(cfg, stderr, terminalColumns())
+	d.stop, d.stopped = make(chan struct{}), make(chan struct{})
+	go d.refresh()
+	return d
+}
+
+// openDashboard builds the view without starting the refresh loop.
+func openDashboard(cfg cliConfig, term io.Writer, cols int) *dashboard {
+	d := &dashboard{term: term, cols: cols, model: cfg.model, started: clock.Now(), maxSteps: cfg.maxSteps, tokenBudget: cfg.tokenBudget}
+	if cfg.tuiPrice != nil {
+		p := *cfg.tuiPrice
+		d.price = &p
+	}
+	return d
+}
+
+// parseTUIPrice parses -tui-price "IN/OUT".
+func parseTUIPrice(raw string) (benchPrice, error) {
+	in, out, ok := strings.Cut(raw, "/")
+	pin, err1 := strconv.ParseFloat(strings.TrimSpace(in), 64)
+	pout, err2 := strconv.ParseFloat(strings.TrimSpace(out), 64)
+	if !ok || err1 != nil || err2 != nil || pin < 0 || pout < 0 {
+		return benchPrice{}, fmt.Errorf("invalid -tui-price %q (want in/out USD per million tokens)", raw)
+	}
+	return benchPrice{in: pin, out: pout}, nil
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// terminalColumns reads COLUMNS, which most shells export.
+func terminalColumns() int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n >= 20 {
+		return n
+	}
+	return tuiDefaultCols
+}
+
+// handle applies one run event.
+func (d *dashboard) handle(ev runEvent) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch ev.Kind {
+	case eventStep:
+		d.step, d.maxSteps = ev.Step, ev.MaxSteps
+	case eventUsage:
+		d.usage = ev.Usage
+	case eventToolStart:
+		d.tools = append(d.tools, toolActivity{name: ev.Tool, callID: ev.CallID, started: clock.Now()})
+	case eventToolEnd:
+		for i := len(d.tools) - 1; i >= 0; i-- {
+			if t := &d.tools[i]; t.callID == ev.CallID && !t.done {
+				t.done, t.failed, t.took = true, ev.Failed, clock.Since(t.started)
+				break
+			}
+		}
+	}
+	d.dirty = true
+}
+
+// paneWriter captures one of the run's output streams into a pane.
+type paneWriter struct {
+	d   *dashboard
+	buf *strings.Builder
+}
+
+func (w paneWriter) Write(p []byte) (int, error) {
+	w.d.mu.Lock()
+	defer w.d.mu.Unlock()
+	w.buf.Write(p)
+	w.d.dirty = true
+	return len(p), nil
+}
+
+func (d *dashboard) stdout() io.Writer { return paneWriter{d: d, buf: &d.out} }
+func (d *dashboard) stderr() io.Writer { return paneWriter{d: d, buf: &d.log} }
+
+func (d *dashboard) refresh() {
+	defer close(d.stopped)
+	t := time.NewTicker(tuiRefresh)
+	defer t.Stop()
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-t.C:
+			d.mu.Lock()
+			// Running calls tick their spinner and elapsed time
+			if d.dirty || d.running() {
+				d.redraw()
+			}
+			d.mu.Unlock()
+		}
+	}
+}
+
+// redraw replaces the live region with a fresh frame. Callers hold d.mu.
+func (d *dashboard) redraw() {
+	frame := d.frame()
+	var b strings.Builder
+	d.erase(&b)
+	for _, ln := range frame {
+		b.WriteString(ln)
+		b.WriteString("\x1b[K\n")
+	}
+	_, _ = io.WriteString(d.term, b.String()) //nolint:errcheck // best-effort terminal output
+	d.drawn = len(frame)
+	d.dirty = false
+}
+
+// erase moves the cursor to the top of the live region and clears below it.
+func (d *dashboard) erase(b *strings.Builder) {
+	if d.drawn > 0 {
+		fmt.Fprintf(b, "\x1b[%dF\x1b[J", d.drawn)
+	}
+}
+
+// frame renders the dashboard lines, each cut to the terminal width.
+func (d *dashboard) frame() []string {
+	var lines []string
+	add := func(format string, args ...any) {
+		s := fmt.Sprintf(format, args...)
+		if r := []rune(s); len(r) > d.cols {
+			s = string(r[:d.cols-1]) + "~"
+		}
+		lines = append(lines, s)
+	}
+	step := "-"
+	if d.step > 0 {
+		step = fmt.Sprintf("%d/%d", d.step, d.maxSteps)
+	}
+	add("agentcli  model %s  step %s  elapsed %s", d.model, step, clock.Since(d.started).Round(time.Second))
+	cost := "n/a"
+	if d.price != nil {
+		cost = fmt.Sprintf("$%.4f", (float64(d.usage.promptTokens)*d.price.in+float64(d.usage.completionTokens)*d.price.out)/1e6)
+	}
+	add("tokens in %d  out %d  total %d  calls %d  cost %s", d.usage.promptTokens, d.usage.completionTokens, d.usage.totalTokens, d.usage.calls, cost)
+	if d.maxSteps > 0 {
+		add("steps  %s %d/%d", budgetBar(d.step, d.maxSteps), d.step, d.maxSteps)
+	}
+	if d.tokenBudget > 0 {
+		add("budget %s %d/%d tokens", budgetBar(d.usage.totalTokens, d.tokenBudget), d.usage.totalTokens, d.tokenBudget)
+	}
+
+	add("-- tools (%d) --", len(d.tools))
+	from := len(d.tools) - tuiToolRows
+	if from < 0 {
+		from = 0
+	}
+	for _, t := range d.tools[from:] {
+		switch {
+		case !t.done:
+			took := clock.Since(t.started)
+			spin := tuiSpinner[int(took/tuiRefresh)%len(tuiSpinner)]
+			add("%c run   %s  %s", spin, t.name, took.Round(100*time.Millisecond))
+		case t.failed:
+			add("  fail  %s  %s", t.name, t.took.Round(100*time.Millisecond))
+		default:
+			add("  ok    %s  %s", t.name, t.took.Round(100*time.Millisecond))
+		}
+	}
+
+	add("-- output --")
+	for _, ln := range tailLines(d.out.String(), tuiOutputRows) {
+		add("  %s", ln)
+	}
+	if d.log.Len() > 0 {
+		add("-- log --")
+		for _, ln := range tailLines(d.log.String(), tuiLogRows) {
+			add("  %s", ln)
+		}
+	}
+	return lines
+}
+
+// running reports whether a tool call is in flight. Callers hold d.mu.
+func (d *dashboard) running() bool {
+	for _, t := range d.tools {
+		if !t.done {
+			return true
+		}
+	}
+	return false
+}
+
+// budgetBar renders used of total as a fixed-width bar, full past the limit.
+func budgetBar(used, total int) string {
+	n := 0
+	if total > 0 {
+		n = used * tuiBarWidth / total
+	}
+	n = min(max(n, 0), tuiBarWidth)
+	return "[" + strings.Repeat("#", n) + strings.Repeat(".", tuiBarWidth-n) + "]"
+}
+
+// tailLines returns the last n non-empty lines of s, keeping a partial last
+// line so streamed text shows as it arrives.
+func tailLines(s string, n int) []string {
+	var out []string
+	for _, ln := range strings.Split(s, "\n") {
+		if ln = strings.TrimRight(ln, "\r"); strings.TrimSpace(ln) != "" {
+			out = append(out, ln)
+		}
+	}
+	if len(out) > n {
+		out = out[len(out)-n:]
+	}
+	return out
+}
+
+// close stops the refresh loop, erases the live region, and replays the
+// captured log and output to the real streams, log first so the answer
+// ends up last on the terminal.
+func (d *dashboard) close(stdout io.Writer) {
+	if d.stop != nil {
+		close(d.stop)
+		<-d.stopped
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var b strings.Builder
+	d.erase(&b)
+	d.drawn = 0
+	b.WriteString(d.log.String())
+	_, _ = io.WriteString(d.term, b.String())     //nolint:errcheck // best-effort terminal output
+	_, _ = io.WriteString(stdout, d.out.String()) //nolint:errcheck // mirrors safeFprintf
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDashboard_FrameFromEvents(t *testing.T) {
+	var term bytes.Buffer
+	d := openDashboard(cliConfig{model: "gpt-x", tuiPrice: &benchPrice{in: 1, out: 10}, tokenBudget: 4400}, &term, 60)
+	d.handle(runEvent{Kind: eventStep, Step: 2, MaxSteps: 8})
+	d.handle(runEvent{Kind: eventUsage, Usage: runUsage{calls: 2, promptTokens: 1000, completionTokens: 100, totalTokens: 1100}})
+	d.handle(runEvent{Kind: eventToolStart, Tool: "fs_read_file", CallID: "a"})
+	d.handle(runEvent{Kind: eventToolStart, Tool: "exec", CallID: "b"})
+	d.handle(runEvent{Kind: eventToolEnd, Tool: "exec", CallID: "b", Failed: true})
+	safeFprintf(d.stdout(), "partial answer with a very long line that will not fit in sixty columns at all\nsecond")
+	safeFprintln(d.stderr(), "warning: something")
+
+	frame := strings.Join(d.frame(), "\n")
+	for _, want := range []string{"model gpt-x  step 2/8", "tokens in 1000  out 100  total 1100  calls 2  cost $0.0020", "steps  [#####...............] 2/8", "budget [#####...............] 1100/4400 tokens", "-- tools (2) --", " run   fs_read_file", "  fail  exec", "-- output --", "  second", "-- log --", "  warning: something"} {
+		if !strings.Contains(frame, want) {
+			t.Fatalf("frame lacks %q:\n%s", want, frame)
+		}
+	}
+	for _, ln := range d.frame() {
+		if n := len([]rune(ln)); n > 60 {
+			t.Fatalf("line wider than the terminal (%d): %q", n, ln)
+		}
+	}
+	plain := strings.Join(openDashboard(cliConfig{}, &term, 80).frame(), "\n")
+	if !strings.Contains(plain, "cost n/a") || strings.Contains(plain, "budget [") {
+		t.Fatalf("unpriced dashboard without a budget:\n%s", plain)
+	}
+	if !d.running() {
+		t.Fatalf("fs_read_file is still running")
+	}
+}
+
+func TestBudgetBar(t *testing.T) {
+	for _, c := range []struct {
+		used, total int
+		want        string
+	}{
+		{0, 10, "[....................]"},
+		{5, 10, "[##########..........]"},
+		{15, 10, "[####################]"},
+		{3, 0, "[....................]"},
+	} {
+		if got := budgetBar(c.used, c.total); got != c.want {
+			t.Fatalf("budgetBar(%d, %d)=%s want %s", c.used, c.total, got, c.want)
+		}
+	}
+}
+
+func TestDashboard_CloseErasesAndReplays(t *testing.T) {
+	var term, stdout bytes.Buffer
+	d := openDashboard(cliConfig{model: "m"}, &term, 80)
+	safeFprintln(d.stdout(), "the answer")
+	safeFprintln(d.stderr(), "info: done")
+	d.redraw()
+	lines := d.drawn
+	if lines == 0 || !strings.Contains(term.String(), "the answer") {
+		t.Fatalf("redraw wrote %q", term.String())
+	}
+	term.Reset()
+	d.close(&stdout)
+	if want := "\x1b[" + strconv.Itoa(lines) + "F\x1b[J" + "info: done\n"; term.String() != want {
+		t.Fatalf("terminal got %q want %q", term.String(), want)
+	}
+	if stdout.String() != "the answer\n" {
+		t.Fatalf("stdout=%q", stdout.String())
+	}
+}
+
+func TestRunAgent_EmitsEvents(t *testing.T) {
+	toolsPath := writeEchoOKTool(t)
+	srv := twoPingServer(t)
+	var mu sync.Mutex
+	var kinds []string
+	cfg := cliConfig{prompt: "p", systemPrompt: "sys", toolsPath: toolsPath, baseURL: srv.URL, model: "m", maxSteps: 4, prepEnabled: false, prepEnabledSet: true, noLock: true}
+	cfg.events = func(ev runEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		kinds = append(kinds, ev.Kind)
+	}
+	if code := runAgent(cfg, io.Discard, io.Discard); code != 0 {
+		t.Fatalf("exit=%d", code)
+	}
+	got := strings.Join(kinds, ",")
+	if want := "step,usage,tool_start,tool_start,tool_end,tool_end,step,usage"; got != want {
+		t.Fatalf("events=%s want %s", got, want)
+	}
+}
+
+func TestCLIMain_TUI_NotATerminal(t *testing.T) {
+	srv := finalAnswerServer(t)
+	defer srv.Close()
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-prompt", "p", "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-tui", "-tui-price", "1/2"}, &out, &errb)
+	if code != 0 || strings.TrimSpace(out.String()) != "done" || !strings.Contains(errb.String(), "-tui needs a terminal") {
+		t.Fatalf("exit=%d stdout=%q stderr=%s", code, out.String(), errb.String())
+	}
+}
+
+func TestParseFlags_TUIPrice(t *testing.T) {
+	if p, err := parseTUIPrice(" 1.25 / 10 "); err != nil || p.in != 1.25 || p.out != 10 {
+		t.Fatalf("price=%+v err=%v", p, err)
+	}
+	for _, bad := range []string{"1.25", "a/b", "-1/2"} {
+		if _, err := parseTUIPrice(bad); err == nil {
+			t.Fatalf("%q: want error", bad)
+		}
+	}
+	var out, errb bytes.Buffer
+	if code := cliMain([]string{"-prompt", "p", "-tui-price", "1/2"}, &out, &errb); code != 2 || !strings.Contains(errb.String(), "-tui-price requires -tui") {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+}
@@ -29,25 +29,75 @@ func versionRequested(args []string) bool {
 func printUsage(w io.Writer) {
 	var b strings.Builder
 	b.WriteString("agentcli — non-interactive CLI agent for OpenAI-compatible APIs\n\n")
-	b.WriteString("Usage:\n  agentcli [flags]\n\n")
+	b.WriteString("Usage:\n  agentcli [flags]\n  agentcli bench -suite <dir> [bench flags] [flags]\n  agentcli serve [-listen addr] [-grpc-listen addr] [serve flags] [flags]\n  agentcli fuzz-tools -tools <manifest> [fuzz flags]\n  agentcli state replay <run-id|latest> -step N [-state-dir dir]\n  agentcli state gc [-keep N] [-max-age dur] [-dry-run] [-state-dir dir]\n  agentcli snapshot create -out <file> [snapshot flags]\n  agentcli snapshot restore <file> [snapshot flags]\n  agentcli completion bash|zsh|fish|powershell\n\n")
 	b.WriteString("Flags (precedence: flag > env > default):\n")
 	b.WriteString("  -prompt string\n    User prompt (required)\n")
 	b.WriteString("  -tools string\n    Path to tools.json (optional)\n")
+	b.WriteString("  -tools-topk int\n    Advertise only the N tools whose descriptions best match each step (by -embed-model embeddings, or word overlap when the endpoint has none), plus tools already called; 0 advertises all (env AGENTCLI_TOOLS_TOPK)\n")
+	b.WriteString("  -embed-model string\n    Embedding model -tools-topk ranks tools with, via POST {base-url}/embeddings (env OAI_EMBED_MODEL; default text-embedding-3-small)\n")
+	b.WriteString("  -workspace string\n    Default root directory of the fs_* tools, passed to them as GOAGENT_WORKSPACE_ROOT; empty uses the working directory (env AGENTCLI_WORKSPACE)\n")
+	b.WriteString("  -workspace-root name=dir\n    Named root the fs_* tools may address as name:path, passed to them in GOAGENT_WORKSPACE_ROOTS (repeatable)\n")
+	b.WriteString("  -schema-simplify string\n    Flatten tool schemas (oneOf/anyOf, deep nesting) for small models: auto|always|never (env OAI_SCHEMA_SIMPLIFY; default auto)\n")
+	b.WriteString("  -schema-min-tier string\n    With -schema-simplify auto, simplify when the model tier is below this: small|medium|large (env OAI_SCHEMA_MIN_TIER; default medium)\n")
+	b.WriteString("  -tool-protocol string\n    Tool-calling protocol: native|functions|text (env OAI_TOOL_PROTOCOL; default native)\n")
+	b.WriteString("  -validate-messages string\n    Message sequence check before each request: strict|repair; repair drops orphaned tool results, moves results next to their call, and stubs missing ones (env AGENTCLI_VALIDATE_MESSAGES; default strict)\n")
+	b.WriteString("  -extra-body string\n    JSON object of provider-specific fields merged into each chat request, e.g. vLLM guided_json or use_beam_search (env OAI_EXTRA_BODY)\n")
+	b.WriteString("  -chat-template string\n    Chat template name sent as chat_template for llama.cpp/vLLM-style servers (env OAI_CHAT_TEMPLATE)\n")
+	b.WriteString("  -prompt-cache\n    Mark the stable prompt prefix (last system/developer message and last tool schema) with cache_control, or Bedrock cachePoint blocks, so providers with explicit prompt caching reuse it; OpenAI caches automatically without it (env AGENTCLI_PROMPT_CACHE)\n")
+	b.WriteString("  -strategy string\n    Agent loop strategy: native|react|plan (env AGENTCLI_STRATEGY; default native)\n")
+	b.WriteString("  -approve-tools string\n    Comma-separated tool names, or all, whose calls print the proposed call JSON to stderr and wait for y/N before running; denied calls get a refusal\n")
+	b.WriteString("  -approve-file string\n    Read approval answers (one per line) from this file or FIFO instead of the terminal\n")
+	b.WriteString("  -editor\n    Hand human input to $VISUAL or $EDITOR (default vi), like git commit: each -approve-tools call opens its arguments JSON and each -strategy plan plan opens for review. Save to accept, edit to change, or clear the file to deny\n")
+	b.WriteString("  -subagent-depth int\n    Offer the built-in agent.run tool, letting the model spawn nested agents up to this depth (default 0, disabled)\n")
+	b.WriteString("  -review-model string\n    Model that critiques the candidate final answer before it is printed (env OAI_REVIEW_MODEL)\n")
+	b.WriteString("  -review-rounds int\n    Maximum critique-and-revise rounds with -review-model; 0 disables review (env OAI_REVIEW_ROUNDS; default 1)\n")
+	b.WriteString("  -deterministic\n    Freeze the clock and seed all randomness for reproducible transcripts and audit logs (env AGENTCLI_DETERMINISTIC)\n")
+	b.WriteString("  -seed int\n    Sampling seed sent with chat requests, also used by -deterministic and -chaos (env AGENTCLI_SEED; default 1, sent only when set or with -deterministic)\n")
+	b.WriteString("  -chat-cache\n    Replay complete chat replies cached on disk (GOAGENT_CACHE_DIR/chat) for byte-identical requests instead of calling the API (env AGENTCLI_CHAT_CACHE)\n")
+	b.WriteString("  -chat-cache-ttl duration\n    How long cached chat replies are replayed; 0 keeps them until evicted (env AGENTCLI_CHAT_CACHE_TTL; default 24h)\n")
+	b.WriteString("  -discover-models\n    Query the provider's /models endpoint before the first call for context window, tool, and temperature support, cached in GOAGENT_CACHE_DIR/models.json (env AGENTCLI_DISCOVER_MODELS)\n")
+	b.WriteString("  -models-cache-ttl duration\n    How long a /models listing is reused from the cache; 0 never expires it (env AGENTCLI_MODELS_CACHE_TTL; default 24h)\n")
+	b.WriteString("  -chaos string\n    Inject faults to test retry and tool policies, e.g. \"timeout=0.1,http500=0.05,tool-fail=0.1\"; each value is a probability per HTTP attempt or tool call, drawn from -seed (env AGENTCLI_CHAOS)\n")
+	b.WriteString("  -prune-stale-reads\n    Replace earlier fs_read_file/fs_read_lines results with a stale marker once a later tool call modifies the file (default true; off under -debug)\n")
+	b.WriteString("  -tool-hook-cmd string\n    Shell command run before and after every tool call with the call JSON on stdin; it may rewrite arguments or output, or block the call\n")
+	b.WriteString("  -read-only\n    Refuse calls to tools that modify the workspace (manifest \"mutates\": true, or bundled writers such as fs_write_file, fs_apply_patch, fs_rm, fs_move, exec); the model gets an error result and can re-plan. Tools with \"supportsDryRun\": true run as dry runs instead\n")
+	b.WriteString("  -no-lock\n    Do not take the workspace locks (.goagent/run.lock in the workspace and each named root) that serialize runs with mutating tools\n")
+	b.WriteString("  -record dir\n    Record every HTTP exchange and tool run to dir/recording.jsonl (0600) for -replay; caches are bypassed\n")
+	b.WriteString("  -replay dir\n    Run offline from a -record directory: HTTP requests and tool runs are answered from the recording, and a request that differs from it fails the run\n")
+	b.WriteString("  -providers file\n    JSON or YAML routing table of base URLs, models, and API key variables; chat calls fail over to the next provider when the current one keeps failing, with a WARN on stderr and a provider_failover audit entry (env AGENTCLI_PROVIDERS)\n")
+	b.WriteString("  -metrics-listen string\n    Serve Prometheus metrics at /metrics on this address, e.g. :9090 (env AGENTCLI_METRICS_LISTEN)\n")
 	b.WriteString("  -system string\n    System prompt (default \"You are a helpful, precise assistant. Use tools when strictly helpful.\")\n")
 	b.WriteString("  -system-file string\n    Path to file containing system prompt ('-' for STDIN; mutually exclusive with -system)\n")
 	b.WriteString("  -developer string\n    Developer message (repeatable)\n")
 	b.WriteString("  -developer-file string\n    Path to file containing developer message (repeatable; '-' for STDIN)\n")
+	b.WriteString("  -image-attach string\n    Image file or http(s) URL to send with the user prompt for multimodal models (repeatable; files are inlined as base64 data URLs, up to 20 MiB each)\n")
 	b.WriteString("  -prompt-file string\n    Path to file containing user prompt ('-' for STDIN; mutually exclusive with -prompt)\n")
+	b.WriteString("  -prompt-template string\n    Go text/template file rendering the prompts: {{define \"system\"}}, {{define \"developer\"}}, and {{define \"user\"}} blocks, or a plain body as the user prompt\n")
+	b.WriteString("  -var value\n    Template variable key=value for -prompt-template (repeatable; overrides env AGENTCLI_VAR_<key> and -vars-file)\n")
+	b.WriteString("  -vars-file string\n    JSON object of template variables for -prompt-template\n")
+	b.WriteString("  -template-strict\n    Fail when -prompt-template references a variable that is not set (default renders it empty)\n")
 	b.WriteString("  -base-url string\n    OpenAI-compatible base URL (env OAI_BASE_URL or default https://api.openai.com/v1)\n")
 	b.WriteString("  -api-key string\n    API key if required (env OAI_API_KEY; falls back to OPENAI_API_KEY)\n")
 	b.WriteString("  -model string\n    Model ID (env OAI_MODEL or default oss-gpt-20b)\n")
+	b.WriteString("  -provider string\n    Chat API: openai (OpenAI-compatible -base-url, default), bedrock (Amazon Bedrock Converse API signed with SigV4 from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, and AWS_SESSION_TOKEN; region from AWS_REGION; -model is a Bedrock model ID such as anthropic.claude-3-5-sonnet-20240620-v1:0), or gemini (Google Gemini generateContent with -api-key, GEMINI_API_KEY, or GOOGLE_API_KEY; -model such as gemini-2.0-flash) (env AGENTCLI_PROVIDER)\n")
+	b.WriteString("  -model-escalate string\n    Per-step model routing, e.g. \"small:2,large\": the first 2 steps use small, later steps use large, and a step after a failed tool call moves up one tier; overrides -model (env AGENTCLI_MODEL_ESCALATE)\n")
 	b.WriteString("  -max-steps int\n    Maximum reasoning/tool steps (default 8)\n")
+	b.WriteString("  -token-budget int\n    Stop with exit 8 once the run has used this many total tokens (0 is unlimited)\n")
 	b.WriteString("  -timeout duration\n    [DEPRECATED] Global timeout; use -http-timeout and -tool-timeout (default 30s)\n")
 	b.WriteString("  -http-timeout duration\n    HTTP timeout for chat completions (env OAI_HTTP_TIMEOUT; falls back to -timeout if unset)\n")
 	b.WriteString("  -prep-http-timeout duration\n    HTTP timeout for pre-stage (env OAI_PREP_HTTP_TIMEOUT; falls back to -http-timeout if unset)\n")
 	b.WriteString("  -tool-timeout duration\n    Per-tool timeout (falls back to -timeout if unset)\n")
+	b.WriteString("  -tool-timeout-grace duration\n    After a tool times out or is canceled, wait this long following SIGTERM before SIGKILL (default 2s)\n")
 	b.WriteString("  -http-retries int\n    Number of retries for transient HTTP failures (timeouts, 429, 5xx) (env OAI_HTTP_RETRIES; default 2)\n")
 	b.WriteString("  -http-retry-backoff duration\n    Base backoff between HTTP retry attempts (exponential) (env OAI_HTTP_RETRY_BACKOFF; default 500ms)\n")
+	b.WriteString("  -http-max-idle-conns int\n    Max pooled idle connections, total and per host, shared by all API clients (env OAI_HTTP_MAX_IDLE_CONNS; default 100)\n")
+	b.WriteString("  -http-keepalive duration\n    TCP keep-alive period for API connections; 0 disables connection reuse (env OAI_HTTP_KEEPALIVE; default 30s)\n")
+	b.WriteString("  -tls-min-version string\n    Minimum TLS version for API connections: 1.2|1.3 (env OAI_TLS_MIN_VERSION; default 1.2)\n")
+	b.WriteString("  -ca-bundle string\n    PEM file with extra CA certificates to trust for API connections (env OAI_CA_BUNDLE)\n")
+	b.WriteString("  -http-max-response-bytes int\n    Max bytes read from one chat response or stream after decompression (env OAI_HTTP_MAX_RESPONSE_BYTES; default 33554432)\n")
+	b.WriteString("  -http-gzip\n    Gzip-compress chat request bodies (Content-Encoding: gzip); only for servers that accept it\n")
+	b.WriteString("  -http-breaker-threshold int\n    Consecutive 429/5xx responses from one base URL before failing fast; 0 disables (env OAI_HTTP_BREAKER_THRESHOLD; default 5)\n")
+	b.WriteString("  -http-breaker-cooldown duration\n    How long the circuit breaker stays open (env OAI_HTTP_BREAKER_COOLDOWN; default 30s)\n")
 	b.WriteString("  -image-base-url string\n    Image API base URL (env OAI_IMAGE_BASE_URL; inherits -base-url if unset)\n")
 	b.WriteString("  -image-model string\n    Image model ID (env OAI_IMAGE_MODEL; default gpt-image-1)\n")
 	b.WriteString("  -image-api-key string\n    Image API key (env OAI_IMAGE_API_KEY; inherits -api-key if unset; falls back to OPENAI_API_KEY)\n")
@@ -66,6 +116,16 @@ func printUsage(w io.Writer) {
 	b.WriteString("  -prep-top-p float\n    Nucleus sampling probability mass for pre-stage (env OAI_PREP_TOP_P; conflicts with -prep-temp; omits temperature when set)\n")
 	b.WriteString("  -prep-system string\n    Pre-stage system message (env OAI_PREP_SYSTEM; mutually exclusive with -prep-system-file)\n")
 	b.WriteString("  -prep-system-file string\n    Path to file containing pre-stage system message ('-' for STDIN; env OAI_PREP_SYSTEM_FILE; mutually exclusive with -prep-system)\n")
+	b.WriteString("  -prep-prompt string\n    Pre-stage prompt replacing the embedded default (repeatable; joined with blank lines; env OAI_PREP_PROMPT)\n")
+	b.WriteString("  -prep-prompt-file string\n    Path to file containing the pre-stage prompt (repeatable; '-' for STDIN; ignored when -prep-prompt is set)\n")
+	b.WriteString("  -audio-prompt file\n    Audio file to transcribe into the user prompt via /audio/transcriptions (appended to -prompt when both are set; up to 25 MiB)\n")
+	b.WriteString("  -audio-base-url string\n    Audio API base URL (env OAI_AUDIO_BASE_URL; inherits -base-url if unset)\n")
+	b.WriteString("  -audio-model string\n    Transcription model ID (env OAI_AUDIO_MODEL; default whisper-1)\n")
+	b.WriteString("  -audio-api-key string\n    Audio API key (env OAI_AUDIO_API_KEY; inherits -api-key if unset; falls back to OPENAI_API_KEY)\n")
+	b.WriteString("  -audio-language string\n    Spoken language hint as ISO-639-1, e.g. en (env OAI_AUDIO_LANGUAGE)\n")
+	b.WriteString("  -audio-http-timeout duration\n    Audio HTTP timeout (env OAI_AUDIO_HTTP_TIMEOUT; inherits -http-timeout if unset)\n")
+	b.WriteString("  -audio-http-retries int\n    Audio HTTP retries (env OAI_AUDIO_HTTP_RETRIES; inherits -http-retries if unset)\n")
+	b.WriteString("  -audio-http-retry-backoff duration\n    Audio HTTP retry backoff (env OAI_AUDIO_HTTP_RETRY_BACKOFF; inherits -http-retry-backoff if unset)\n")
 	b.WriteString("  -image-n int\n    Number of images to generate (env OAI_IMAGE_N; default 1)\n")
 	b.WriteString("  -image-size string\n    Image size WxH, e.g., 1024x1024 (env OAI_IMAGE_SIZE; default 1024x1024)\n")
 	b.WriteString("  -image-quality string\n    Image quality: standard|hd (env OAI_IMAGE_QUALITY; default standard)\n")
@@ -73,27 +133,83 @@ func printUsage(w io.Writer) {
 	b.WriteString("  -image-response-format string\n    Image response format: url|b64_json (env OAI_IMAGE_RESPONSE_FORMAT; default url)\n")
 	b.WriteString("  -image-transparent-background\n    Request transparent background when supported (env OAI_IMAGE_TRANSPARENT_BACKGROUND; default false)\n")
 	b.WriteString("  -debug\n    Dump request/response JSON to stderr\n")
-	b.WriteString("  -verbose\n    Also print non-final assistant channels (critic/confidence) to stderr\n")
+	b.WriteString("  -verbose\n    Also print non-final assistant channels (critic/confidence) and a final usage summary to stderr\n")
 	b.WriteString("  -quiet\n    Suppress non-final output; print only final text to stdout\n")
 	b.WriteString("  -prep-tools-allow-external\n    Allow pre-stage to execute external tools from -tools (default false)\n")
 	b.WriteString("  -prep-cache-bust\n    Skip pre-stage cache and force recompute\n")
+	b.WriteString("  -prep-cache-stats\n    Print pre-stage cache hits, misses, entry count, and size as JSON and exit (cache dir from GOAGENT_CACHE_DIR)\n")
 	b.WriteString("  -prep-tools string\n    Path to pre-stage tools.json (optional; used only with -prep-tools-allow-external)\n")
 	b.WriteString("  -prep-dry-run\n    Run pre-stage only, print refined Harmony messages to stdout, and exit 0\n")
 	b.WriteString("  -state-dir string\n    Directory to persist and restore execution state across runs (env AGENTCLI_STATE_DIR)\n")
+	b.WriteString("  -state-remote string\n    Share -state-dir through an object store: s3://bucket/prefix or gs://bucket/prefix; pulled before a run and pushed after (env AGENTCLI_STATE_REMOTE)\n")
 	b.WriteString("  -state-scope string\n    Optional scope key to partition saved state (env AGENTCLI_STATE_SCOPE); when empty, a default hash of model|base_url|toolset is used\n")
 	b.WriteString("  -state-refine\n    Refine the loaded state bundle using -state-refine-text or -state-refine-file (requires -state-dir)\n")
 	b.WriteString("  -state-refine-text string\n    Refinement input text to apply to the loaded state bundle (ignored when -state-refine-file is set; requires -state-dir)\n")
 	b.WriteString("  -state-refine-file string\n    Path to file containing refinement input (wins over -state-refine-text; requires -state-dir)\n")
+	b.WriteString("  -state-list\n    List the snapshots in -state-dir (SHA, time, scope, model, parent) and exit\n")
+	b.WriteString("  -state-show string\n    Print the snapshot in -state-dir whose SHA-256 starts with this prefix (or latest) as JSON and exit\n")
+	b.WriteString("  -state-fork string\n    Copy the latest snapshot in -state-dir into this new scope, for exploring another continuation, and exit\n")
 	b.WriteString("  -print-messages\n    Pretty-print the final merged message array to stderr before the main call\n")
+	b.WriteString("  -print-plan\n    Print the pre-stage plan to stderr before the main call and after each plan.update\n")
+	b.WriteString("  -diff-messages string\n    Compare saved messages: OLD,NEW prints a structural diff and exits (0 same, 1 different); FILE diffs against this run's merged messages on stderr\n")
 	b.WriteString("  -stream-final\n    If server supports streaming, stream only assistant{channel:\"final\"} to stdout; buffer other channels for -verbose\n")
-	b.WriteString("  -channel-route name=stdout|stderr|omit\n    Override default channel routing (final→stdout, critic/confidence→stderr); repeatable\n")
+	b.WriteString("  -channel-route name=stdout|stderr|omit|file:<path>\n    Override default channel routing (final→stdout, other channels→stderr) for any channel name, or * for custom channels without a rule; file: appends each message to a file even without -verbose; repeatable\n")
 	b.WriteString("  -save-messages string\n    Write the final merged Harmony messages to the given JSON file and continue\n")
+	b.WriteString("  -output-file string\n    Write the final assistant content to this file atomically (temp file + rename) instead of stdout\n")
+	b.WriteString("  -append\n    With -output-file, append to the file instead of replacing it\n")
+	b.WriteString("  -succeed-if string\n    Exit 4 unless the final answer matches this regular expression\n")
+	b.WriteString("  -fail-if string\n    Exit 3 when the final answer matches this regular expression (checked before -succeed-if)\n")
+	b.WriteString("  -output string\n    Result format on stdout: text|json; json suppresses all human text and prints one object {final, steps, usage, tool_calls, exit_reason} (env AGENTCLI_OUTPUT; default text)\n")
+	b.WriteString("  -error-json\n    On failure, print a final JSON line {exitCode, reason, message} to stderr\n")
+	b.WriteString("  -golden string\n    Compare the finished run's tool calls and final answer with this saved transcript; exit 4 with a JSON diff on mismatch\n")
+	b.WriteString("  -golden-call-tolerance int\n    Tool-call edits (missing, extra, or changed calls) allowed against -golden (default 0)\n")
+	b.WriteString("  -golden-final-similarity float\n    Minimum word-level similarity (0..1) of the final answer to -golden's (default 1)\n")
+	b.WriteString("  -golden-ignore-args\n    Compare only tool names, not arguments, against -golden\n")
+	b.WriteString("  -export-jsonl string\n    Append the finished transcript to this file as one OpenAI fine-tuning JSONL record\n")
+	b.WriteString("  -if-empty-fail\n    With -output-file, exit 1 and leave the file untouched when the final content is empty\n")
+	b.WriteString("  -tui\n    Show a live dashboard on stderr while the run works: current step, tool activity, token and cost meters, and the latest output and log lines. stdout and stderr are held and printed when the run ends. Needs a terminal on stderr\n")
+	b.WriteString("  -tui-price string\n    USD per million prompt/completion tokens for the -tui cost meter, e.g. 1.25/10\n")
+	b.WriteString("  -context-report string\n    After the run, print estimated prompt tokens per step by source (system, developer, user, assistant, prep, tool schemas, each tool) to stderr: table|json\n")
+	b.WriteString("  -script string\n    Run the user turns in this JSON file in order over one transcript, with optional per-turn tools and assertions (replaces -prompt)\n")
+	b.WriteString("  -tasks string\n    Checklist file (.md list or .json array) to work through; progress is tracked with the built-in task.complete tool and reported on stderr at the end\n")
+	b.WriteString("  -batch file\n    Run every prompt in this JSONL file as its own agent run, with optional per-item overrides (replaces -prompt)\n")
+	b.WriteString("  -batch-out file\n    Write one JSON result per -batch item to this file, in input order (default stdout)\n")
+	b.WriteString("  -batch-concurrency int\n    How many -batch items run at once (default 4)\n")
 	b.WriteString("  -load-messages string\n    Bypass pre-stage and prompt; load Harmony messages from the given JSON file (validator-checked)\n")
 	b.WriteString("  -prep-enabled\n    Enable pre-stage processing (default true; when false, skip pre-stage and proceed directly to main call)\n")
 	b.WriteString("  -capabilities\n    Print enabled tools and exit\n")
 	b.WriteString("  -print-config\n    Print resolved config and exit\n")
+	b.WriteString("  -config string\n    YAML or JSON file of flag defaults (env AGENTCLI_CONFIG; default .goagent.yaml in the working directory when present)\n")
+	b.WriteString("  -profile string\n    Named preset from the config file's profiles section (env AGENTCLI_PROFILE)\n")
 	b.WriteString("  -dry-run\n    Print intended state actions (restore/refine/save) and exit without writing state\n")
 	b.WriteString("  --version | -version\n    Print version and exit\n")
+	b.WriteString("\nBench flags (agentcli bench; other flags configure every run):\n")
+	b.WriteString("  -suite dir\n    Directory of *.json task files {name, prompt, system, expect_contains, expect_regex, max_steps} (required)\n")
+	b.WriteString("  -models list\n    Comma-separated model IDs to compare (default -model)\n")
+	b.WriteString("  -strategies list\n    Comma-separated strategies to compare: native,react,plan (default -strategy)\n")
+	b.WriteString("  -price model=in/out\n    USD per million prompt/completion tokens for cost estimates; repeatable\n")
+	b.WriteString("  -report string\n    Write the comparison report to this file instead of stdout\n")
+	b.WriteString("  -report-format string\n    Report format: markdown|json (default markdown)\n")
+	b.WriteString("\nServe flags (agentcli serve; other flags configure every run):\n")
+	b.WriteString("  -listen addr\n    Address of the agent HTTP API (default 127.0.0.1:8080)\n")
+	b.WriteString("  -grpc-listen addr\n    Also serve the gRPC AgentService (internal/server/agentpb/agent.proto) on this address\n")
+	b.WriteString("  -token string\n    Require \"Authorization: Bearer <token>\" on every request (env AGENTCLI_SERVE_TOKEN)\n")
+	b.WriteString("  -max-runs int\n    Runs working at once; more answer 429 (default 4)\n")
+	b.WriteString("\nFuzz flags (agentcli fuzz-tools; exits 1 when any tool breaks its contract):\n")
+	b.WriteString("  -iterations int\n    Random argument sets per tool (default 25)\n")
+	b.WriteString("  -seed int\n    Random seed; 0 picks one and prints it for reproduction\n")
+	b.WriteString("  -tool name\n    Fuzz only this tool; repeatable\n")
+	b.WriteString("  -workdir string\n    Working directory for the tools (default: a fresh temporary directory)\n")
+	b.WriteString("\nState replay flags (agentcli state replay; prints the request of a step saved under -state-dir):\n")
+	b.WriteString("  -step int\n    Step whose request to rebuild, from 1; the step after the last shows the request that would be sent next (required)\n")
+	b.WriteString("\nState gc flags (agentcli state gc; prunes snapshots under -state-dir and compacts the latest):\n")
+	b.WriteString("  -keep int\n    Snapshots to keep per scope, newest first (default 10)\n")
+	b.WriteString("  -max-age string\n    Also remove snapshots older than this (e.g. 72h or 30d); the newest per scope is always kept\n")
+	b.WriteString("  -dry-run\n    Report what would be removed and compacted without changing anything\n")
+	b.WriteString("\nSnapshot flags (agentcli snapshot create|restore; code changes, untracked files, and -state-dir as one archive):\n")
+	b.WriteString("  -out string\n    Archive to write (create; required)\n")
+	b.WriteString("  -repo string\n    Git work tree to capture or restore (default .)\n")
+	b.WriteString("  -force\n    Restore: discard uncommitted changes to tracked files instead of refusing\n")
 	b.WriteString("\nDocs:\n")
 	b.WriteString("  - Linux 5.4 sandbox compatibility and policy authoring: docs/runbooks/linux-5.4-sandbox-compatibility.md\n")
 	b.WriteString("\nExamples:\n")
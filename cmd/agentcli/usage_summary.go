@@ -0,0 +1,42 @@
+package main
+
+import (
+	"io"
+
+	"github.com/hyperifyio/goagent/internal/metrics"
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// runUsage accumulates token accounting across chat calls in one run.
+type runUsage struct {
+	calls            int
+	promptTokens     int
+	completionTokens int
+	totalTokens      int
+	// Prompt tokens read from and written to the provider's prompt cache
+	cachedTokens     int
+	cacheWriteTokens int
+}
+
+func (u *runUsage) add(r *oai.Usage) {
+	u.calls++
+	if r == nil {
+		return
+	}
+	u.promptTokens += r.PromptTokens
+	u.completionTokens += r.CompletionTokens
+	u.totalTokens += r.TotalTokens
+	u.cachedTokens += r.CachedTokens()
+	u.cacheWriteTokens += r.CacheCreationInputTokens
+	metrics.Tokens.Add(float64(r.PromptTokens), "prompt")
+	metrics.Tokens.Add(float64(r.CompletionTokens), "completion")
+	metrics.Tokens.Add(float64(r.CachedTokens()), "cached")
+}
+
+// printUsageSummary writes a one-line usage summary (tokens and HTTP
+// counters) to w. It is emitted under -verbose when the run ends.
+func printUsageSummary(w io.Writer, u runUsage, s oai.HTTPStats) {
+	safeFprintf(w, "usage: calls=%d prompt_tokens=%d completion_tokens=%d total_tokens=%d cached_tokens=%d cache_write_tokens=%d http_requests=%d retries=%d rate_limited=%d server_errors=%d breaker_rejections=%d\n",
+		u.calls, u.promptTokens, u.completionTokens, u.totalTokens, u.cachedTokens, u.cacheWriteTokens,
+		s.Requests, s.Retries, s.RateLimited, s.ServerErrors, s.BreakerRejections)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+func TestRunAgent_VerbosePrintsUsageSummary(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(oai.ChatCompletionsResponse{ //nolint:errcheck
+			Choices: []oai.ChatCompletionsResponseChoice{{Message: oai.Message{Role: oai.RoleAssistant, Content: "hi"}}},
+			Usage:   &oai.Usage{PromptTokens: 7, CompletionTokens: 2, TotalTokens: 9},
+		})
+	}))
+	defer srv.Close()
+
+	cfg := cliConfig{prompt: "p", systemPrompt: "s", baseURL: srv.URL, model: "m", maxSteps: 2, httpTimeout: 5 * time.Second, prepEnabledSet: true, verbose: true}
+	var out, errb bytes.Buffer
+	if code := runAgent(cfg, &out, &errb); code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	want := "usage: calls=1 prompt_tokens=7 completion_tokens=2 total_tokens=9 cached_tokens=0 cache_write_tokens=0 http_requests=1 retries=0 rate_limited=0 server_errors=0 breaker_rejections=0"
+	if !strings.Contains(errb.String(), want) {
+		t.Fatalf("missing usage summary in stderr: %q", errb.String())
+	}
+}
+
+func TestHTTPBreakerFlags_Resolution(t *testing.T) {
+	orig := os.Args
+	defer func() { os.Args = orig }()
+	t.Setenv("OAI_HTTP_BREAKER_THRESHOLD", "")
+	t.Setenv("OAI_HTTP_BREAKER_COOLDOWN", "")
+
+	os.Args = []string{"agentcli.test", "-prompt", "p"}
+	cfg, code := parseFlags()
+	if code != 0 || cfg.httpBreakerThreshold != 5 || cfg.httpBreakerCooldown != 30*time.Second {
+		t.Fatalf("defaults: code=%d threshold=%d cooldown=%s", code, cfg.httpBreakerThreshold, cfg.httpBreakerCooldown)
+	}
+	t.Setenv("OAI_HTTP_BREAKER_THRESHOLD", "9")
+	t.Setenv("OAI_HTTP_BREAKER_COOLDOWN", "2m")
+	os.Args = []string{"agentcli.test", "-prompt", "p", "-http-breaker-threshold", "0"}
+	cfg, code = parseFlags()
+	if code != 0 || cfg.httpBreakerThreshold != 0 || cfg.httpBreakerCooldown != 2*time.Minute {
+		t.Fatalf("flag/env: code=%d threshold=%d cooldown=%s", code, cfg.httpBreakerThreshold, cfg.httpBreakerCooldown)
+	}
+	os.Args = []string{"agentcli.test", "-prompt", "p", "-http-breaker-threshold", "-1"}
+	if _, code := parseFlags(); code != 2 {
+		t.Fatalf("negative threshold: exit=%d want 2", code)
+	}
+}
+
+func TestRunUsage_CountsCachedTokens(t *testing.T) {
+	var u runUsage
+	u.add(&oai.Usage{PromptTokens: 100, PromptTokensDetails: &oai.PromptTokensDetails{CachedTokens: 60}, CacheCreationInputTokens: 30})
+	u.add(&oai.Usage{PromptTokens: 100, PromptTokensDetails: &oai.PromptTokensDetails{CachedTokens: 90}})
+	u.add(nil)
+	var b bytes.Buffer
+	printUsageSummary(&b, u, oai.HTTPStats{})
+	if !strings.Contains(b.String(), "prompt_tokens=200 completion_tokens=0 total_tokens=0 cached_tokens=150 cache_write_tokens=30") {
+		t.Fatalf("summary=%q", b.String())
+	}
+}
+
+func TestBuildStepRequest_PromptCache(t *testing.T) {
+	msgs := []oai.Message{{Role: oai.RoleSystem, Content: "s"}, {Role: oai.RoleUser, Content: "u"}}
+	tools := []oai.Tool{{Type: "function", Function: oai.ToolFunction{Name: "t"}}}
+	req := buildStepRequest(cliConfig{model: "m"}, msgs, tools, 0)
+	if req.Messages[0].CacheControl != nil || req.Tools[0].CacheControl != nil {
+		t.Fatal("markers sent without -prompt-cache")
+	}
+	req = buildStepRequest(cliConfig{model: "m", promptCache: true}, msgs, tools, 0)
+	if req.Messages[0].CacheControl == nil || req.Tools[0].CacheControl == nil || req.Messages[1].CacheControl != nil {
+		t.Fatalf("req=%+v", req)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Exit codes for -fail-if and -succeed-if (also used by -script and -golden
+// assertions), distinct from operational errors
+// (1) and misuse (2) so CI jobs can tell a negative verdict from a broken run.
+const (
+	exitFailIfMatched   = 3
+	exitSucceedIfMissed = 4
+)
+
+// finalVerdict applies -fail-if and -succeed-if to the final answer and
+// returns the exit code. -fail-if is checked first, so an answer matching
+// both patterns fails.
+func finalVerdict(cfg cliConfig, content string, stderr io.Writer) int {
+	content = strings.TrimSpace(content)
+	if cfg.failIfRe != nil && cfg.failIfRe.MatchString(content) {
+		safeFprintf(stderr, "info: final answer matches -fail-if %q\n", cfg.failIf)
+		return exitFailIfMatched
+	}
+	if cfg.succeedIfRe != nil && !cfg.succeedIfRe.MatchString(content) {
+		safeFprintf(stderr, "info: final answer does not match -succeed-if %q\n", cfg.succeedIf)
+		return exitSucceedIfMissed
+	}
+	return 0
+}
+
+// compileVerdictPattern compiles a non-empty -succeed-if/-fail-if value.
+func compileVerdictPattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile(pattern)
+}
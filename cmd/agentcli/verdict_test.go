@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCLIMain_SucceedIfFailIf_ExitCodes(t *testing.T) {
+	srv := finalAnswerServer(t)
+	defer srv.Close()
+	cases := []struct {
+		name  string
+		flags []string
+		want  int
+	}{
+		{"no patterns", nil, 0},
+		{"succeed matches", []string{"-succeed-if", "(?m)^done$"}, 0},
+		{"succeed misses", []string{"-succeed-if", "VERDICT: PASS"}, exitSucceedIfMissed},
+		{"fail matches", []string{"-fail-if", "(?i)DONE"}, exitFailIfMatched},
+		{"fail misses", []string{"-fail-if", "VERDICT: FAIL"}, 0},
+		{"fail wins over succeed", []string{"-succeed-if", "done", "-fail-if", "done"}, exitFailIfMatched},
+		{"invalid pattern", []string{"-succeed-if", "("}, 2},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			args := append([]string{"-prompt", "q", "-base-url", srv.URL, "-model", "m", "-prep-enabled=false"}, tc.flags...)
+			var out, errb bytes.Buffer
+			if code := cliMain(args, &out, &errb); code != tc.want {
+				t.Fatalf("exit=%d want %d stderr=%s", code, tc.want, errb.String())
+			}
+			if tc.want != 2 && strings.TrimSpace(out.String()) != "done" {
+				t.Fatalf("answer must still be printed, stdout=%q", out.String())
+			}
+		})
+	}
+}
+
+func TestCLIMain_FailIf_StillWritesOutputFile(t *testing.T) {
+	srv := finalAnswerServer(t)
+	defer srv.Close()
+	path := filepath.Join(t.TempDir(), "answer.txt")
+	var out, errb bytes.Buffer
+	code := cliMain([]string{"-prompt", "q", "-base-url", srv.URL, "-model", "m", "-prep-enabled=false", "-output-file", path, "-fail-if", "done"}, &out, &errb)
+	if code != exitFailIfMatched || !strings.Contains(errb.String(), "-fail-if") {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	if b, err := os.ReadFile(path); err != nil || string(b) != "done\n" {
+		t.Fatalf("output file: %q err=%v", b, err)
+	}
+}
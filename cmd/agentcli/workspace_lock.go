@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/clock"
+	"github.com/hyperifyio/goagent/internal/filelock"
+)
+
+// workspaceLockName is the lock file under <root>/.goagent that serializes
+// runs allowed to mutate the workspace.
+const workspaceLockName = "run.lock"
+
+type workspaceLockInfo struct {
+	PID     int    `json:"pid"`
+	Started string `json:"started"`
+}
+
+// workspaceLock is a held lock; release unlocks it.
+type workspaceLock struct{ file *filelock.File }
+
+// acquireWorkspaceLock takes the kernel lock on root/.goagent/run.lock. The
+// lock dies with its holder, so a crashed run never blocks the next one; a
+// live holder yields an error naming its pid so the user can wait or pass
+// -no-lock.
+func acquireWorkspaceLock(root string) (*workspaceLock, error) {
+	dir := filepath.Join(root, ".goagent")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create lock dir: %w", err)
+	}
+	path := filepath.Join(dir, workspaceLockName)
+	f, err := filelock.TryLock(path)
+	if errors.Is(err, filelock.ErrLocked) {
+		var holder workspaceLockInfo
+		if data, rerr := os.ReadFile(path); rerr == nil && json.Unmarshal(data, &holder) == nil && holder.PID > 0 {
+			return nil, fmt.Errorf("workspace %s is locked by another agentcli run (pid %d, started %s); wait for it to finish or pass -no-lock", root, holder.PID, holder.Started)
+		}
+		return nil, fmt.Errorf("workspace %s is locked by another agentcli run; wait for it to finish or pass -no-lock", root)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock workspace: %w", err)
+	}
+	// Record the holder for the error message of a contending run
+	info, err := json.Marshal(workspaceLockInfo{PID: os.Getpid(), Started: clock.Now().UTC().Format(time.RFC3339)})
+	if err == nil {
+		if err = f.Truncate(0); err == nil {
+			_, err = f.WriteAt(info, 0)
+		}
+	}
+	if err != nil {
+		_ = f.Unlock() //nolint:errcheck // the write error is what matters
+		return nil, fmt.Errorf("write lock: %w", err)
+	}
+	return &workspaceLock{file: f}, nil
+}
+
+func (l *workspaceLock) release() {
+	if l == nil {
+		return
+	}
+	// The file stays: removing it would let a run lock a new inode while
+	// another still holds the old one
+	_ = l.file.Truncate(0) //nolint:errcheck // stale holder info is harmless
+	_ = l.file.Unlock()    //nolint:errcheck // closing releases it anyway
+}
+
+// workspaceLockRoots returns the directories a mutating run locks: the
+// -workspace root, or the repository root without one, and every named
+// root, deduplicated and sorted so concurrent runs lock them in one order
+// and cannot deadlock.
+func workspaceLockRoots(cfg cliConfig) []string {
+	seen := map[string]bool{}
+	var roots []string
+	add := func(dir string) {
+		if dir = filepath.Clean(dir); !seen[dir] {
+			seen[dir] = true
+			roots = append(roots, dir)
+		}
+	}
+	if strings.TrimSpace(cfg.workspace) != "" {
+		add(cfg.workspace)
+	} else {
+		add(findRepoRoot())
+	}
+	for _, dir := range cfg.workspaceRootDirs {
+		add(dir)
+	}
+	sort.Strings(roots)
+	return roots
+}
+
+// workspaceLocks are the held locks of several roots.
+type workspaceLocks []*workspaceLock
+
+// acquireWorkspaceLocks locks roots in order; when one is held elsewhere
+// the locks already taken are released again.
+func acquireWorkspaceLocks(roots []string) (workspaceLocks, error) {
+	var locks workspaceLocks
+	for _, root := range roots {
+		l, err := acquireWorkspaceLock(root)
+		if err != nil {
+			locks.release()
+			return nil, err
+		}
+		locks = append(locks, l)
+	}
+	return locks, nil
+}
+
+func (ls workspaceLocks) release() {
+	for i := len(ls) - 1; i >= 0; i-- {
+		ls[i].release()
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/filelock"
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// writeMutatingTool writes a manifest whose only tool declares mutates:true.
+func writeMutatingTool(t *testing.T) string {
+	t.Helper()
+	path := writeEchoOKTool(t)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var man map[string][]map[string]any
+	if err := json.Unmarshal(b, &man); err != nil {
+		t.Fatal(err)
+	}
+	man["tools"][0]["mutating"] = true
+	if b, err = json.Marshal(man); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func finalAnswerServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	return fakeChatServer(t, nil, func(oai.ChatCompletionsRequest) (oai.ChatCompletionsResponse, error) {
+		return answer(finalMessage("done"))
+	})
+}
+
+// holdLock takes root's workspace lock the way another run would: as a
+// separate open file holding the kernel lock, with that run's holder info.
+func holdLock(t *testing.T, root string, pid int) string {
+	t.Helper()
+	dir := filepath.Join(root, ".goagent")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, workspaceLockName)
+	f, err := filelock.Lock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = f.Unlock() })                                               //nolint:errcheck
+	b, _ := json.Marshal(workspaceLockInfo{PID: pid, Started: "2026-01-01T00:00:00Z"}) //nolint:errcheck
+	if _, err := f.WriteAt(b, 0); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunAgent_WorkspaceLock_BlocksConcurrentMutatingRun(t *testing.T) {
+	toolsPath := writeMutatingTool(t)
+	root := t.TempDir()
+	t.Chdir(root)
+	srv := finalAnswerServer(t)
+	defer srv.Close()
+	cfg := cliConfig{prompt: "p", toolsPath: toolsPath, baseURL: srv.URL, model: "m", maxSteps: 2, prepEnabledSet: true}
+
+	// A live holder blocks the run
+	lockPath := holdLock(t, root, 4242)
+	var out, errb bytes.Buffer
+	if code := runAgent(cfg, &out, &errb); code != exitWorkspaceLock || !strings.Contains(errb.String(), "-no-lock") || !strings.Contains(errb.String(), "pid 4242") {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+
+	// -no-lock bypasses it and leaves the other run's lock alone
+	cfg.noLock = true
+	out.Reset()
+	errb.Reset()
+	if code := runAgent(cfg, &out, &errb); code != 0 {
+		t.Fatalf("-no-lock: exit=%d stderr=%s", code, errb.String())
+	}
+	if _, err := os.Stat(lockPath); err != nil {
+		t.Fatalf("-no-lock must not touch the lock: %v", err)
+	}
+}
+
+func TestRunAgent_WorkspaceLock_LeftoverFileAndRelease(t *testing.T) {
+	toolsPath := writeMutatingTool(t)
+	root := t.TempDir()
+	t.Chdir(root)
+	srv := finalAnswerServer(t)
+	defer srv.Close()
+	// A file left by a crashed run, with no kernel lock on it, does not block
+	dir := filepath.Join(root, ".goagent")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	lockPath := filepath.Join(dir, workspaceLockName)
+	if err := os.WriteFile(lockPath, []byte(`{"pid":1073741824}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var out, errb bytes.Buffer
+	cfg := cliConfig{prompt: "p", toolsPath: toolsPath, baseURL: srv.URL, model: "m", maxSteps: 2, prepEnabledSet: true}
+	if code := runAgent(cfg, &out, &errb); code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, errb.String())
+	}
+	f, err := filelock.TryLock(lockPath)
+	if err != nil {
+		t.Fatalf("lock not released after the run: %v", err)
+	}
+	_ = f.Unlock() //nolint:errcheck
+}
+
+func TestAcquireWorkspaceLock_Exclusive(t *testing.T) {
+	root := t.TempDir()
+	first, err := acquireWorkspaceLock(root)
+	if err != nil {
+		t.Fatalf("first: %v", err)
+	}
+	if _, err := acquireWorkspaceLock(root); err == nil || !strings.Contains(err.Error(), fmt.Sprintf("pid %d", os.Getpid())) {
+		t.Fatalf("second acquire while held: err=%v", err)
+	}
+	first.release()
+	second, err := acquireWorkspaceLock(root)
+	if err != nil {
+		t.Fatalf("after release: %v", err)
+	}
+	second.release()
+}
+
+func TestRunAgent_WorkspaceLock_ReadOnlyToolsSkipLock(t *testing.T) {
+	toolsPath := writeEchoOKTool(t) // "ping" is read-only
+	root := t.TempDir()
+	t.Chdir(root)
+	srv := finalAnswerServer(t)
+	defer srv.Close()
+	holdLock(t, root, 4242)
+	var out, errb bytes.Buffer
+	cfg := cliConfig{prompt: "p", toolsPath: toolsPath, baseURL: srv.URL, model: "m", maxSteps: 2, prepEnabledSet: true}
+	if code := runAgent(cfg, &out, &errb); code != 0 {
+		t.Fatalf("read-only run should not need the lock: exit=%d stderr=%s", code, errb.String())
+	}
+}
+
+func TestRunAgent_WorkspaceLock_CoversWorkspaceAndNamedRoots(t *testing.T) {
+	toolsPath := writeMutatingTool(t)
+	t.Chdir(t.TempDir())
+	ws, docs := t.TempDir(), t.TempDir()
+	srv := finalAnswerServer(t)
+	cfg := cliConfig{prompt: "p", toolsPath: toolsPath, baseURL: srv.URL, model: "m", maxSteps: 2, prepEnabledSet: true,
+		workspace: ws, workspaceRootDirs: map[string]string{"docs": docs}}
+
+	for name, root := range map[string]string{"workspace": ws, "named root": docs} {
+		t.Run(name, func(t *testing.T) {
+			holdLock(t, root, 4242)
+			var out, errb bytes.Buffer
+			if code := runAgent(cfg, &out, &errb); code != exitWorkspaceLock || !strings.Contains(errb.String(), root) {
+				t.Fatalf("exit=%d stderr=%s", code, errb.String())
+			}
+		})
+	}
+	var out, errb bytes.Buffer
+	if code := runAgent(cfg, &out, &errb); code != 0 {
+		t.Fatalf("unlocked: exit=%d stderr=%s", code, errb.String())
+	}
+}
+
+func TestAcquireWorkspaceLocks_ReleasesTakenLocksOnConflict(t *testing.T) {
+	a, b := t.TempDir(), t.TempDir()
+	held, err := acquireWorkspaceLock(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := acquireWorkspaceLocks([]string{a, b}); err == nil {
+		t.Fatal("want the held root to fail the acquire")
+	}
+	// a was released again
+	first, err := acquireWorkspaceLock(a)
+	if err != nil {
+		t.Fatalf("lock on %s leaked: %v", a, err)
+	}
+	first.release()
+	held.release()
+	locks, err := acquireWorkspaceLocks([]string{a, b})
+	if err != nil {
+		t.Fatalf("both free: %v", err)
+	}
+	locks.release()
+}
+
+func TestWorkspaceLockRoots_SortedAndDeduplicated(t *testing.T) {
+	cfg := cliConfig{workspace: "/w/b", workspaceRootDirs: map[string]string{"aa": "/w/a", "bb": "/w/b/", "cc": "/w/c"}}
+	got := strings.Join(workspaceLockRoots(cfg), ",")
+	if want := "/w/a,/w/b,/w/c"; got != want {
+		t.Fatalf("roots=%s, want %s", got, want)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hyperifyio/goagent/tools/workspace"
+)
+
+// resolveWorkspaceRoots validates -workspace and -workspace-root: each must
+// name an existing directory, which is made absolute so tools resolve it
+// the same from any working directory. Named roots become the allowlist the
+// fs_* tools accept as "name:path" prefixes.
+func resolveWorkspaceRoots(cfg *cliConfig) error {
+	if s := strings.TrimSpace(cfg.workspace); s != "" {
+		dir, err := workspaceDir(s)
+		if err != nil {
+			return fmt.Errorf("-workspace: %v", err)
+		}
+		cfg.workspace = dir
+	}
+	cfg.workspaceRootDirs = nil
+	for _, entry := range cfg.workspaceRoots {
+		name, dir, ok := strings.Cut(entry, "=")
+		if !ok || !workspace.ValidName(name) {
+			return fmt.Errorf("-workspace-root %q: want name=dir with a name of two or more letters, digits, '_' or '-', starting with a letter", entry)
+		}
+		if _, dup := cfg.workspaceRootDirs[name]; dup {
+			return fmt.Errorf("-workspace-root %q: duplicate name", entry)
+		}
+		abs, err := workspaceDir(dir)
+		if err != nil {
+			return fmt.Errorf("-workspace-root %q: %v", entry, err)
+		}
+		if strings.ContainsRune(abs, os.PathListSeparator) {
+			return fmt.Errorf("-workspace-root %q: directory must not contain %q", entry, os.PathListSeparator)
+		}
+		if cfg.workspaceRootDirs == nil {
+			cfg.workspaceRootDirs = map[string]string{}
+		}
+		cfg.workspaceRootDirs[name] = abs
+	}
+	return nil
+}
+
+func workspaceDir(dir string) (string, error) {
+	abs, err := filepath.Abs(strings.TrimSpace(dir))
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", abs)
+	}
+	return abs, nil
+}
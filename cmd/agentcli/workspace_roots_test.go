@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveWorkspaceRoots(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "f.txt")
+	if err := os.WriteFile(file, []byte("x"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	cfg := cliConfig{workspace: dir, workspaceRoots: []string{"api=" + dir}}
+	if err := resolveWorkspaceRoots(&cfg); err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if cfg.workspace != dir || cfg.workspaceRootDirs["api"] != dir {
+		t.Fatalf("cfg workspace=%q roots=%v", cfg.workspace, cfg.workspaceRootDirs)
+	}
+
+	cases := []struct {
+		name string
+		cfg  cliConfig
+		want string
+	}{
+		{"missing workspace", cliConfig{workspace: filepath.Join(dir, "nope")}, "-workspace"},
+		{"workspace is a file", cliConfig{workspace: file}, "not a directory"},
+		{"no name", cliConfig{workspaceRoots: []string{dir}}, "want name=dir"},
+		{"bad name", cliConfig{workspaceRoots: []string{"a=" + dir}}, "want name=dir"},
+		{"duplicate", cliConfig{workspaceRoots: []string{"api=" + dir, "api=" + dir}}, "duplicate name"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := tc.cfg
+			if err := resolveWorkspaceRoots(&cfg); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("err=%v, want %q", err, tc.want)
+			}
+		})
+	}
+}
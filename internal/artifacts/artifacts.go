@@ -0,0 +1,130 @@
+// Package artifacts keeps binary and oversized tool outputs out of the
+// transcript. A tool prints {"artifact":{"path":...}} instead of inline
+// content; the file is copied into a content-addressed store (by SHA-256)
+// and the tool message carries only a compact reference to it.
+package artifacts
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Ref describes one artifact. A tool fills Path (the file it wrote) and
+// optionally Bytes, SHA256, and MIME, which are verified when set. In the
+// transcript Path is the stored copy and Ref is "sha256:<hex>".
+type Ref struct {
+	Ref    string `json:"ref,omitempty"`
+	Path   string `json:"path"`
+	Bytes  int64  `json:"bytes"`
+	SHA256 string `json:"sha256,omitempty"`
+	MIME   string `json:"mime,omitempty"`
+}
+
+// Store is a content-addressed directory: an artifact lives at
+// Dir/<first two hex digits>/<sha256 hex>.
+type Store struct {
+	Dir string
+}
+
+// Path returns where the artifact with the given SHA-256 hex digest lives.
+func (s Store) Path(sum string) string {
+	return filepath.Join(s.Dir, sum[:2], sum)
+}
+
+// Put copies the file want.Path into the store and returns its stored
+// reference. A stated size or digest that does not match the file is an
+// error; a missing MIME type is sniffed from the content.
+func (s Store) Put(want Ref) (Ref, error) {
+	if strings.TrimSpace(want.Path) == "" {
+		return Ref{}, errors.New("artifact path is required")
+	}
+	src, err := os.Open(want.Path)
+	if err != nil {
+		return Ref{}, fmt.Errorf("open artifact: %w", err)
+	}
+	defer src.Close() //nolint:errcheck // read-only
+	if info, err := src.Stat(); err != nil || !info.Mode().IsRegular() {
+		return Ref{}, fmt.Errorf("artifact %s is not a regular file", want.Path)
+	}
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return Ref{}, fmt.Errorf("create artifact store: %w", err)
+	}
+	tmp, err := os.CreateTemp(s.Dir, ".put-*")
+	if err != nil {
+		return Ref{}, fmt.Errorf("create artifact: %w", err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // gone after the rename
+	h := sha256.New()
+	var head [512]byte
+	n, _ := io.ReadFull(src, head[:]) //nolint:errcheck // a short file is fine
+	size, err := io.Copy(io.MultiWriter(tmp, h), io.MultiReader(bytes.NewReader(head[:n]), src))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return Ref{}, fmt.Errorf("copy artifact: %w", err)
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	if want.Bytes > 0 && want.Bytes != size {
+		return Ref{}, fmt.Errorf("artifact %s has %d bytes, not %d", want.Path, size, want.Bytes)
+	}
+	if want.SHA256 != "" && !strings.EqualFold(want.SHA256, sum) {
+		return Ref{}, fmt.Errorf("artifact %s has sha256 %s, not %s", want.Path, sum, want.SHA256)
+	}
+	mime := strings.TrimSpace(want.MIME)
+	if mime == "" {
+		mime = http.DetectContentType(head[:n])
+	}
+	dst := s.Path(sum)
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return Ref{}, fmt.Errorf("create artifact store: %w", err)
+	}
+	// Identical content is stored once
+	if _, err := os.Stat(dst); err != nil {
+		if err := os.Rename(tmp.Name(), dst); err != nil {
+			return Ref{}, fmt.Errorf("store artifact: %w", err)
+		}
+	}
+	return Ref{Ref: "sha256:" + sum, Path: dst, Bytes: size, SHA256: sum, MIME: mime}, nil
+}
+
+// StoreOutput stores the artifact of a tool output of the form
+// {"artifact":{...},...} and returns the output with the artifact replaced
+// by its stored reference; other fields are kept. Any other output is
+// returned unchanged with ok false.
+func (s Store) StoreOutput(out []byte) (rewritten []byte, ok bool, err error) {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(out, &fields) != nil {
+		return out, false, nil
+	}
+	raw, found := fields["artifact"]
+	if !found {
+		return out, false, nil
+	}
+	var want Ref
+	if err := json.Unmarshal(raw, &want); err != nil {
+		return nil, false, fmt.Errorf("artifact: %w", err)
+	}
+	ref, err := s.Put(want)
+	if err != nil {
+		return nil, false, err
+	}
+	// The digest is already in Ref
+	ref.SHA256 = ""
+	b, err := json.Marshal(ref)
+	if err != nil {
+		return nil, false, err
+	}
+	fields["artifact"] = b
+	rewritten, err = json.Marshal(fields)
+	return rewritten, err == nil, err
+}
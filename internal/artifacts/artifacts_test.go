@@ -0,0 +1,63 @@
+package artifacts
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStoreOutput(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "out.png")
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 100)...)
+	if err := os.WriteFile(src, png, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store := Store{Dir: filepath.Join(dir, "store")}
+	out, ok, err := store.StoreOutput([]byte(`{"artifact":{"path":"` + src + `","bytes":108},"note":"chart"}`))
+	if err != nil || !ok {
+		t.Fatalf("ok=%v err=%v", ok, err)
+	}
+	var got struct {
+		Artifact Ref    `json:"artifact"`
+		Note     string `json:"note"`
+	}
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatal(err)
+	}
+	ref := got.Artifact
+	if got.Note != "chart" || ref.Bytes != 108 || ref.MIME != "image/png" || ref.SHA256 != "" || !strings.HasPrefix(ref.Ref, "sha256:") {
+		t.Fatalf("output=%s", out)
+	}
+	if ref.Path != store.Path(strings.TrimPrefix(ref.Ref, "sha256:")) {
+		t.Fatalf("path=%s", ref.Path)
+	}
+	if stored, err := os.ReadFile(ref.Path); err != nil || !bytes.Equal(stored, png) {
+		t.Fatalf("stored copy differs: %v", err)
+	}
+
+	// The same content is stored once and gets the same reference
+	again, _, err := store.StoreOutput([]byte(`{"artifact":{"path":"` + src + `"}}`))
+	if err != nil || !strings.Contains(string(again), ref.Ref) {
+		t.Fatalf("again=%s err=%v", again, err)
+	}
+
+	if out, ok, err := store.StoreOutput([]byte(`{"ok":true}`)); ok || err != nil || string(out) != `{"ok":true}` {
+		t.Fatalf("plain output: %s %v %v", out, ok, err)
+	}
+	for body, want := range map[string]string{
+		`{"artifact":{"path":"` + src + `","bytes":1}}`:      "has 108 bytes, not 1",
+		`{"artifact":{"path":"` + src + `","sha256":"abc"}}`: "not abc",
+		`{"artifact":{"path":"` + dir + `"}}`:                "not a regular file",
+		`{"artifact":{"path":"` + dir + `/missing"}}`:        "open artifact",
+		`{"artifact":{}}`:  "path is required",
+		`{"artifact":"x"}`: "artifact:",
+	} {
+		if _, _, err := store.StoreOutput([]byte(body)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err=%v want %q", body, err, want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// Package audit implements the shared NDJSON audit log used by the HTTP
+// client, the tool runner, and the in-process sandboxes. Lines are appended to
+// .goagent/audit/YYYYMMDD.log under a base directory (normally the module
+// root). Files rotate by UTC day and by size, rotated files are gzip
+// compressed, and files older than the retention window are removed.
+package audit
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/clock"
+	"github.com/hyperifyio/goagent/internal/filelock"
+	"github.com/hyperifyio/goagent/internal/redact"
+)
+
+const (
+	// DefaultMaxBytes is the size cap for a single active log file.
+	DefaultMaxBytes int64 = 10 * 1024 * 1024
+	// DefaultRetention is how long rotated and day files are kept.
+	DefaultRetention = 30 * 24 * time.Hour
+)
+
+// Policy controls rotation and retention. Zero MaxBytes disables size
+// rotation; zero Retention keeps files forever.
+type Policy struct {
+	MaxBytes  int64
+	Retention time.Duration
+}
+
+// PolicyFromEnv resolves the policy from GOAGENT_AUDIT_MAX_BYTES and
+// GOAGENT_AUDIT_RETENTION. Retention accepts Go durations ("72h") or a
+// whole number of days ("14d" or "14"); "0" disables pruning. Invalid values
+// fall back to the defaults.
+func PolicyFromEnv() Policy {
+	p := Policy{MaxBytes: DefaultMaxBytes, Retention: DefaultRetention}
+	if v := strings.TrimSpace(os.Getenv("GOAGENT_AUDIT_MAX_BYTES")); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			p.MaxBytes = n
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("GOAGENT_AUDIT_RETENTION")); v != "" {
+		if d, ok := parseRetention(v); ok {
+			p.Retention = d
+		}
+	}
+	return p
+}
+
+func parseRetention(v string) (time.Duration, bool) {
+	days := strings.TrimSuffix(v, "d")
+	if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+		return time.Duration(n) * 24 * time.Hour, true
+	}
+	if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+		return d, true
+	}
+	return 0, false
+}
+
+// mu serializes appends and rotation within the process so concurrent tool
+// goroutines cannot interleave a rotation with a write. Other processes
+// writing the same directory, such as tool binaries, are kept out by the
+// file lock at lockName, which sits beside the audit directory so readers
+// of the directory only see log files.
+var mu sync.Mutex
+
+// lockName is the cross-process lock file held while appending, rotating,
+// and sweeping, so no line lands in a segment being compressed away.
+const lockName = "audit.lock"
+
+// swept records base directories whose retention sweep already ran in this
+// process; the sweep touches every file so it is done once per directory.
+var swept = map[string]bool{}
+
+// Append writes entry under the module root using the current time.
+func Append(entry any) error {
+	return AppendAt(ModuleRoot(), clock.Now(), entry)
+}
+
+// AppendAt marshals entry as one JSON line and appends it to the day file for
+// now under base/.goagent/audit, rotating and pruning per PolicyFromEnv.
+func AppendAt(base string, now time.Time, entry any) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return AppendLine(base, now, b)
+}
+
+// AppendLine appends an already-encoded JSON line after masking secrets.
+func AppendLine(base string, now time.Time, line []byte) error {
+	dir := Dir(base)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	policy := PolicyFromEnv()
+	line = redact.Bytes(line)
+	mu.Lock()
+	defer mu.Unlock()
+	lock, err := filelock.Lock(filepath.Join(filepath.Dir(dir), lockName))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = lock.Unlock() }() //nolint:errcheck // closing releases it anyway
+	if !swept[dir] {
+		swept[dir] = true
+		sweep(dir, clock.Now(), policy)
+	}
+	path := filepath.Join(dir, now.UTC().Format("20060102")+".log")
+	if policy.MaxBytes > 0 {
+		if fi, err := os.Stat(path); err == nil && fi.Size() > 0 && fi.Size()+int64(len(line))+1 > policy.MaxBytes {
+			if err := rotate(path); err != nil {
+				return err
+			}
+		}
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }() //nolint:errcheck // best-effort close
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Dir returns the audit directory under base.
+func Dir(base string) string {
+	return filepath.Join(base, ".goagent", "audit")
+}
+
+// ModuleRoot walks upward from the current working directory to locate the
+// directory containing go.mod. If none is found, it returns the current
+// working directory.
+func ModuleRoot() string {
+	cwd, err := os.Getwd()
+	if err != nil || cwd == "" {
+		return "."
+	}
+	dir := cwd
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			// Reached filesystem root; fallback to original cwd
+			return cwd
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,146 @@
+package audit
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/filelock"
+)
+
+func TestAppendAt_RotatesBySizeAndCompresses(t *testing.T) {
+	base := t.TempDir()
+	t.Setenv("GOAGENT_AUDIT_MAX_BYTES", "64")
+	now := time.Date(2025, 3, 4, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		if err := AppendAt(base, now, map[string]any{"event": "x", "pad": strings.Repeat("a", 30)}); err != nil {
+			t.Fatalf("append %d: %v", i, err)
+		}
+	}
+	dir := Dir(base)
+	if _, err := os.Stat(filepath.Join(dir, "20250304.log")); err != nil {
+		t.Fatalf("active file missing: %v", err)
+	}
+	gz := filepath.Join(dir, "20250304.1.log.gz")
+	f, err := os.Open(gz)
+	if err != nil {
+		t.Fatalf("rotated file missing: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("read gz: %v", err)
+	}
+	if !strings.HasSuffix(string(b), "}\n") {
+		t.Fatalf("rotated content not NDJSON: %q", string(b))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "20250304.1.log")); !os.IsNotExist(err) {
+		t.Fatalf("uncompressed rotated file should be removed, err=%v", err)
+	}
+}
+
+func TestSweep_RetentionAndCompression(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	write := func(name string, age time.Duration) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte("{}\n"), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		mt := now.Add(-age)
+		if err := os.Chtimes(p, mt, mt); err != nil {
+			t.Fatalf("chtimes: %v", err)
+		}
+		return p
+	}
+	expired := write("20240101.log.gz", 40*24*time.Hour)
+	stale := write("20250101.log", 48*time.Hour)
+	fresh := write("20250103.log", time.Minute)
+	other := write("notes.txt", 90*24*time.Hour)
+
+	sweep(dir, now, Policy{Retention: 30 * 24 * time.Hour})
+
+	if _, err := os.Stat(expired); !os.IsNotExist(err) {
+		t.Fatalf("expired file should be pruned")
+	}
+	if _, err := os.Stat(stale + ".gz"); err != nil {
+		t.Fatalf("stale day file should be compressed: %v", err)
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Fatalf("fresh file must stay: %v", err)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Fatalf("non-audit file must be ignored: %v", err)
+	}
+}
+
+func TestPolicyFromEnv(t *testing.T) {
+	t.Setenv("GOAGENT_AUDIT_MAX_BYTES", "")
+	t.Setenv("GOAGENT_AUDIT_RETENTION", "")
+	if p := PolicyFromEnv(); p.MaxBytes != DefaultMaxBytes || p.Retention != DefaultRetention {
+		t.Fatalf("defaults: %+v", p)
+	}
+	t.Setenv("GOAGENT_AUDIT_MAX_BYTES", "0")
+	t.Setenv("GOAGENT_AUDIT_RETENTION", "7d")
+	if p := PolicyFromEnv(); p.MaxBytes != 0 || p.Retention != 7*24*time.Hour {
+		t.Fatalf("env days: %+v", p)
+	}
+	t.Setenv("GOAGENT_AUDIT_RETENTION", "36h")
+	if p := PolicyFromEnv(); p.Retention != 36*time.Hour {
+		t.Fatalf("env duration: %+v", p)
+	}
+}
+
+func TestAppendAt_RedactsSecrets(t *testing.T) {
+	t.Setenv("GOAGENT_REDACT", "")
+	base := t.TempDir()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := AppendAt(base, now, map[string]string{"header": "Bearer abcdefghijklmnop"}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(Dir(base), "20250301.log"))
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if strings.Contains(string(data), "abcdefghijklmnop") || !strings.Contains(string(data), "[REDACTED:bearer]") {
+		t.Fatalf("audit line not redacted: %s", data)
+	}
+}
+
+func TestAppendAt_WaitsForCrossProcessLock(t *testing.T) {
+	base := t.TempDir()
+	dir := Dir(base)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	// Another process rotating the directory holds the lock
+	held, err := filelock.Lock(filepath.Join(base, ".goagent", lockName))
+	if err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+	now := time.Date(2025, 3, 5, 12, 0, 0, 0, time.UTC)
+	done := make(chan error, 1)
+	go func() { done <- AppendAt(base, now, map[string]string{"event": "x"}) }()
+	select {
+	case err := <-done:
+		t.Fatalf("append finished while the lock was held: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if err := held.Unlock(); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "20250305.log")); err != nil {
+		t.Fatalf("line not written after unlock: %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package audit
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// rotate moves the active file at path to the next free YYYYMMDD.N.log name
+// and compresses it to YYYYMMDD.N.log.gz.
+func rotate(path string) error {
+	base := strings.TrimSuffix(path, ".log")
+	var target string
+	for n := 1; ; n++ {
+		candidate := fmt.Sprintf("%s.%d.log", base, n)
+		if _, err := os.Stat(candidate); err == nil {
+			continue
+		}
+		if _, err := os.Stat(candidate + ".gz"); err == nil {
+			continue
+		}
+		target = candidate
+		break
+	}
+	if err := os.Rename(path, target); err != nil {
+		return err
+	}
+	return compressFile(target)
+}
+
+// compressFile writes src.gz and removes src on success.
+func compressFile(src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }() //nolint:errcheck // read-only handle
+	tmp := src + ".gz.tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	zw.Name = filepath.Base(src)
+	if _, err := io.Copy(zw, in); err != nil {
+		_ = out.Close()    //nolint:errcheck // abandoning temp file
+		_ = os.Remove(tmp) //nolint:errcheck // best-effort cleanup
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		_ = out.Close()    //nolint:errcheck // abandoning temp file
+		_ = os.Remove(tmp) //nolint:errcheck // best-effort cleanup
+		return err
+	}
+	if err := out.Close(); err != nil {
+		_ = os.Remove(tmp) //nolint:errcheck // best-effort cleanup
+		return err
+	}
+	if err := os.Rename(tmp, src+".gz"); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// sweep applies retention and compresses finished day files. Files whose
+// modification time is older than the retention window are deleted. Plain
+// .log files not written to for a full day are compressed. Errors are
+// ignored so auditing never fails a run.
+func sweep(dir string, now time.Time, policy Policy) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		name := e.Name()
+		if !strings.HasSuffix(name, ".log") && !strings.HasSuffix(name, ".log.gz") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		age := now.Sub(info.ModTime())
+		full := filepath.Join(dir, name)
+		if policy.Retention > 0 && age > policy.Retention {
+			_ = os.Remove(full) //nolint:errcheck // best-effort prune
+			continue
+		}
+		if strings.HasSuffix(name, ".log") && age > 24*time.Hour {
+			_ = compressFile(full) //nolint:errcheck // best-effort compaction
+		}
+	}
+}
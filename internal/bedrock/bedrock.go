@@ -0,0 +1,202 @@
+// Package bedrock serves the agent's OpenAI-compatible chat requests from
+// Amazon Bedrock's Converse API. Transport is an http.RoundTripper that the
+// chat client uses in place of the network: it rewrites each
+// POST .../chat/completions into POST /model/{modelId}/converse, signs it
+// with SigV4, and turns the reply back into a chat completion, so retries,
+// hooks, limits, and the rest of the client work unchanged.
+package bedrock
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+	"github.com/hyperifyio/goagent/internal/sigv4"
+)
+
+// DefaultRegion is used when neither AWS_REGION nor AWS_DEFAULT_REGION is set.
+const DefaultRegion = "us-east-1"
+
+// RegionFromEnv returns AWS_REGION, then AWS_DEFAULT_REGION, then DefaultRegion.
+func RegionFromEnv() string {
+	for _, k := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if v := strings.TrimSpace(os.Getenv(k)); v != "" {
+			return v
+		}
+	}
+	return DefaultRegion
+}
+
+// Endpoint returns the Bedrock runtime endpoint of region.
+func Endpoint(region string) string {
+	return "https://bedrock-runtime." + region + ".amazonaws.com"
+}
+
+// Transport converts chat completion requests to signed Converse calls.
+type Transport struct {
+	region string
+	creds  sigv4.Credentials
+	base   http.RoundTripper
+	// limits caps the Converse reply read by the transport (see WithLimits)
+	limits oai.Limits
+}
+
+// NewTransport returns a transport for region that signs with credentials
+// from the environment and sends through base; nil uses oai.SharedTransport
+// as it is at each request.
+func NewTransport(region string, base http.RoundTripper) (*Transport, error) {
+	creds, err := sigv4.CredentialsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return &Transport{region: region, creds: creds, base: base}, nil
+}
+
+// WithLimits returns a copy of t that reads Converse replies within
+// l.MaxResponseBytes, the limit the client applies to the converted body.
+func (t *Transport) WithLimits(l oai.Limits) *Transport {
+	c := *t
+	c.limits = l
+	return &c
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodPost || !strings.HasSuffix(req.URL.Path, "/chat/completions") {
+		return reply(req, http.StatusNotFound, fmt.Sprintf(`{"error":{"message":"bedrock: %s %s is not supported"}}`, req.Method, req.URL.Path)), nil
+	}
+	body, err := requestJSON(req)
+	if err != nil {
+		return nil, err
+	}
+	var chat oai.ChatCompletionsRequest
+	if err := json.Unmarshal(body, &chat); err != nil {
+		return nil, fmt.Errorf("bedrock: decode chat request: %w", err)
+	}
+	if chat.Stream {
+		// Answer without calling Bedrock: a non-SSE reply makes the chat
+		// client fall back to a non-streaming request
+		return reply(req, http.StatusOK, `{}`), nil
+	}
+	chat.ExtraBody = extraFields(body)
+	if chat.Model == "" {
+		return reply(req, http.StatusBadRequest, `{"error":{"message":"bedrock: model is required"}}`), nil
+	}
+	conv, names, err := toConverse(chat)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(conv)
+	if err != nil {
+		return nil, fmt.Errorf("bedrock: encode converse request: %w", err)
+	}
+
+	// Model ids such as anthropic.claude-3-5-sonnet-20240620-v1:0 carry a
+	// colon, which must stay percent-encoded in the path
+	u := &url.URL{Scheme: req.URL.Scheme, Host: req.URL.Host, Path: "/model/" + chat.Model + "/converse", RawPath: "/model/" + strings.ReplaceAll(url.PathEscape(chat.Model), ":", "%3A") + "/converse"}
+	out, err := http.NewRequestWithContext(req.Context(), http.MethodPost, u.String(), bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	out.Header.Set("Content-Type", "application/json")
+	out.Header.Set("Accept", "application/json")
+	if ua := req.Header.Get("User-Agent"); ua != "" {
+		out.Header.Set("User-Agent", ua)
+	}
+	// AWS rejects signatures more than minutes off real time, so sign with
+	// the wall clock even when -deterministic freezes clock.Now
+	sigv4.SignService(out, sigv4.PayloadHash(payload), t.creds, t.region, "bedrock", time.Now())
+
+	base := t.base
+	if base == nil {
+		base = oai.SharedTransport()
+	}
+	resp, err := base.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	raw, err := t.limits.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("bedrock: read converse response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// Errors pass through so the client's retry and status handling apply
+		r := reply(req, resp.StatusCode, string(raw))
+		r.Header = resp.Header.Clone()
+		r.Header.Del("Content-Length")
+		r.Header.Del("Content-Encoding")
+		return r, nil
+	}
+	var cr converseResponse
+	if err := json.Unmarshal(raw, &cr); err != nil {
+		return nil, fmt.Errorf("bedrock: decode converse response: %w", err)
+	}
+	b, err := json.Marshal(fromConverse(cr, chat.Model, names))
+	if err != nil {
+		return nil, err
+	}
+	r := reply(req, http.StatusOK, string(b))
+	if id := resp.Header.Get("X-Amzn-Requestid"); id != "" {
+		r.Header.Set("X-Request-Id", id)
+	}
+	return r, nil
+}
+
+// requestJSON reads the chat request body, undoing -http-gzip.
+func requestJSON(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, fmt.Errorf("bedrock: empty chat request")
+	}
+	defer req.Body.Close() //nolint:errcheck
+	var r io.Reader = req.Body
+	if strings.EqualFold(req.Header.Get("Content-Encoding"), "gzip") {
+		zr, err := gzip.NewReader(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("bedrock: gzip request: %w", err)
+		}
+		r = zr
+	}
+	return io.ReadAll(r)
+}
+
+// extraFields returns the top-level fields of body that ChatCompletionsRequest
+// does not encode itself, i.e. its ExtraBody.
+func extraFields(body []byte) map[string]json.RawMessage {
+	var all map[string]json.RawMessage
+	if json.Unmarshal(body, &all) != nil {
+		return nil
+	}
+	for k := range all {
+		if oai.IsRequestField(k) {
+			delete(all, k)
+		}
+	}
+	if len(all) == 0 {
+		return nil
+	}
+	return all
+}
+
+// reply builds a JSON response to req.
+func reply(req *http.Request, status int, body string) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,200 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/clock"
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+func TestTransport_ConverseRoundTrip(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+
+	var got converseRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/model/anthropic.claude-v2%3A1/converse" {
+			t.Errorf("path=%q", r.URL.EscapedPath())
+		}
+		if auth := r.Header.Get("Authorization"); !strings.Contains(auth, "/us-west-2/bedrock/aws4_request") || strings.Contains(auth, "Bearer") {
+			t.Errorf("Authorization=%q", auth)
+		}
+		b, _ := io.ReadAll(r.Body) //nolint:errcheck
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Errorf("body=%s err=%v", b, err)
+		}
+		_, _ = io.WriteString(w, `{"output":{"message":{"role":"assistant","content":[{"text":"reading"},{"toolUse":{"toolUseId":"tu2","name":"fs_read","input":{"path":"b"}}}]}},"stopReason":"tool_use","usage":{"inputTokens":7,"outputTokens":3,"totalTokens":10}}`) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	tr, err := NewTransport("us-west-2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := oai.NewClient(srv.URL, "sk-unused", 5*time.Second)
+	temp := 0.2
+	req := oai.ChatCompletionsRequest{
+		Model:       "anthropic.claude-v2:1",
+		Temperature: &temp,
+		MaxTokens:   100,
+		Tools:       []oai.Tool{{Type: "function", Function: oai.ToolFunction{Name: "fs.read", Parameters: json.RawMessage(`{"type":"object"}`)}}},
+		Messages: []oai.Message{
+			{Role: oai.RoleSystem, Content: "be brief"},
+			{Role: oai.RoleUser, Content: "read a"},
+			{Role: oai.RoleAssistant, ToolCalls: []oai.ToolCall{{ID: "tu1", Type: "function", Function: oai.ToolCallFunction{Name: "fs.read", Arguments: `{"path":"a"}`}}}},
+			{Role: oai.RoleTool, ToolCallID: "tu1", Content: `{"error":"not found"}`},
+			{Role: oai.RoleUser, Content: "try b"},
+		},
+	}
+	resp, err := c.WithTransport(tr).CreateChatCompletion(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.System) != 1 || got.System[0].Text != "be brief" {
+		t.Fatalf("system=%+v", got.System)
+	}
+	// user, assistant(toolUse), user(toolResult + text)
+	if len(got.Messages) != 3 || got.Messages[2].Role != "user" || len(got.Messages[2].Content) != 2 {
+		t.Fatalf("messages=%+v", got.Messages)
+	}
+	use := got.Messages[1].Content[0].ToolUse
+	if use == nil || use.ToolUseID != "tu1" || use.Name != "fs_read" || string(use.Input) != `{"path":"a"}` {
+		t.Fatalf("toolUse=%+v", use)
+	}
+	res := got.Messages[2].Content[0].ToolResult
+	if res == nil || res.ToolUseID != "tu1" || res.Status != "error" || string(res.Content[0].JSON) != `{"error":"not found"}` {
+		t.Fatalf("toolResult=%+v", res)
+	}
+	if got.ToolConfig == nil || got.ToolConfig.Tools[0].ToolSpec == nil || got.ToolConfig.Tools[0].ToolSpec.Name != "fs_read" {
+		t.Fatalf("toolConfig=%+v", got.ToolConfig)
+	}
+	if got.InferenceConfig == nil || got.InferenceConfig.MaxTokens != 100 || *got.InferenceConfig.Temperature != 0.2 {
+		t.Fatalf("inferenceConfig=%+v", got.InferenceConfig)
+	}
+
+	ch := resp.Choices[0]
+	if ch.FinishReason != "tool_calls" || ch.Message.Content != "reading" || len(ch.Message.ToolCalls) != 1 {
+		t.Fatalf("choice=%+v", ch)
+	}
+	if tc := ch.Message.ToolCalls[0]; tc.ID != "tu2" || tc.Function.Name != "fs.read" || tc.Function.Arguments != `{"path":"b"}` {
+		t.Fatalf("tool call=%+v", tc)
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 10 {
+		t.Fatalf("usage=%+v", resp.Usage)
+	}
+}
+
+func TestTransport_ErrorsPassThrough(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, `{"message":"The provided model identifier is invalid."}`) //nolint:errcheck
+	}))
+	defer srv.Close()
+	tr, err := NewTransport(DefaultRegion, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = oai.NewClient(srv.URL, "", 5*time.Second).WithTransport(tr).CreateChatCompletion(context.Background(), oai.ChatCompletionsRequest{Model: "nope", Messages: []oai.Message{{Role: oai.RoleUser, Content: "hi"}}})
+	if err == nil || !strings.Contains(err.Error(), "400") || !strings.Contains(err.Error(), "model identifier is invalid") {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+func TestTransport_SignsWithWallClockWhenDeterministic(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	clock.SetDeterministic(0)
+	t.Cleanup(clock.Reset)
+	var signed string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signed = r.Header.Get("X-Amz-Date")
+		_, _ = io.WriteString(w, `{"output":{"message":{"role":"assistant","content":[{"text":"hi"}]}},"stopReason":"end_turn"}`) //nolint:errcheck
+	}))
+	defer srv.Close()
+	tr, err := NewTransport(DefaultRegion, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := oai.NewClient(srv.URL, "", 5*time.Second).WithTransport(tr).CreateChatCompletion(context.Background(), oai.ChatCompletionsRequest{Model: "m", Messages: []oai.Message{{Role: oai.RoleUser, Content: "hi"}}}); err != nil {
+		t.Fatal(err)
+	}
+	at, err := time.Parse("20060102T150405Z", signed)
+	if err != nil || time.Since(at).Abs() > time.Minute {
+		t.Fatalf("X-Amz-Date=%q err=%v", signed, err)
+	}
+}
+
+func TestTransport_ResponseLimit(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Fields the conversion drops still count against the limit
+		_, _ = io.WriteString(w, `{"output":{"message":{"role":"assistant","content":[{"text":"hi"}]}},"stopReason":"end_turn","trace":"`+strings.Repeat("x", 4096)+`"}`) //nolint:errcheck
+	}))
+	defer srv.Close()
+	tr, err := NewTransport(DefaultRegion, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limits := oai.Limits{MaxResponseBytes: 1024}
+	_, err = oai.NewClientWithRetry(srv.URL, "", 5*time.Second, oai.RetryPolicy{MaxRetries: 2}).WithLimits(limits).WithTransport(tr.WithLimits(limits)).CreateChatCompletion(context.Background(), oai.ChatCompletionsRequest{Model: "m", Messages: []oai.Message{{Role: oai.RoleUser, Content: "hi"}}})
+	var le *oai.LimitError
+	if !errors.As(err, &le) || le.Bytes != 1024 {
+		t.Fatalf("want LimitError at 1024 bytes, got %v", err)
+	}
+}
+
+func TestToolNames_Collisions(t *testing.T) {
+	n := newToolNames([]oai.Tool{{Function: oai.ToolFunction{Name: "a.b"}}, {Function: oai.ToolFunction{Name: "a_b"}}})
+	if n.out["a.b"] != "a_b" || n.out["a_b"] != "a_b_2" || n.agent("a_b_2") != "a_b" || n.agent("a_b") != "a.b" {
+		t.Fatalf("names=%+v", n)
+	}
+}
+
+func TestNewTransport_RequiresCredentials(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	if _, err := NewTransport(DefaultRegion, nil); err == nil {
+		t.Fatal("want error without credentials")
+	}
+}
+
+func TestToConverse_CachePoints(t *testing.T) {
+	req := oai.ChatCompletionsRequest{
+		Model:    "m",
+		Tools:    []oai.Tool{{Type: "function", Function: oai.ToolFunction{Name: "a"}}},
+		Messages: []oai.Message{{Role: oai.RoleSystem, Content: "sys"}, {Role: oai.RoleUser, Content: "hi"}},
+	}
+	oai.MarkCacheBreakpoints(&req)
+	conv, _, err := toConverse(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conv.System) != 2 || conv.System[1].CachePoint == nil {
+		t.Fatalf("system=%+v", conv.System)
+	}
+	if tools := conv.ToolConfig.Tools; len(tools) != 2 || tools[1].CachePoint == nil || tools[1].ToolSpec != nil {
+		t.Fatalf("tools=%+v", tools)
+	}
+
+	var resp converseResponse
+	if err := json.Unmarshal([]byte(`{"usage":{"inputTokens":10,"outputTokens":5,"totalTokens":115,"cacheReadInputTokens":80,"cacheWriteInputTokens":20}}`), &resp); err != nil {
+		t.Fatal(err)
+	}
+	u := converseUsage(resp)
+	if u.PromptTokens != 110 || u.CachedTokens() != 80 || u.CacheCreationInputTokens != 20 {
+		t.Fatalf("usage=%+v", u)
+	}
+}
@@ -0,0 +1,350 @@
+package bedrock
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// converseRequest is the body of POST /model/{modelId}/converse.
+type converseRequest struct {
+	Messages                     []converseMessage          `json:"messages"`
+	System                       []contentBlock             `json:"system,omitempty"`
+	InferenceConfig              *inferenceConfig           `json:"inferenceConfig,omitempty"`
+	ToolConfig                   *toolConfig                `json:"toolConfig,omitempty"`
+	AdditionalModelRequestFields map[string]json.RawMessage `json:"additionalModelRequestFields,omitempty"`
+}
+
+type converseMessage struct {
+	Role    string         `json:"role"`
+	Content []contentBlock `json:"content"`
+}
+
+// contentBlock is one element of a Converse message; exactly one field is set.
+type contentBlock struct {
+	Text       string      `json:"text,omitempty"`
+	Image      *imageBlock `json:"image,omitempty"`
+	ToolUse    *toolUse    `json:"toolUse,omitempty"`
+	ToolResult *toolResult `json:"toolResult,omitempty"`
+	CachePoint *cachePoint `json:"cachePoint,omitempty"`
+}
+
+// cachePoint ends a prefix Bedrock may cache, Converse's form of
+// cache_control.
+type cachePoint struct {
+	Type string `json:"type"`
+}
+
+var defaultCachePoint = &cachePoint{Type: "default"}
+
+type imageBlock struct {
+	Format string `json:"format"`
+	Source struct {
+		Bytes string `json:"bytes"`
+	} `json:"source"`
+}
+
+type toolUse struct {
+	ToolUseID string          `json:"toolUseId"`
+	Name      string          `json:"name"`
+	Input     json.RawMessage `json:"input"`
+}
+
+type toolResult struct {
+	ToolUseID string              `json:"toolUseId"`
+	Content   []toolResultContent `json:"content"`
+	Status    string              `json:"status,omitempty"`
+}
+
+type toolResultContent struct {
+	Text string          `json:"text,omitempty"`
+	JSON json.RawMessage `json:"json,omitempty"`
+}
+
+type inferenceConfig struct {
+	MaxTokens   int      `json:"maxTokens,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"topP,omitempty"`
+}
+
+type toolConfig struct {
+	Tools      []toolDef       `json:"tools"`
+	ToolChoice json.RawMessage `json:"toolChoice,omitempty"`
+}
+
+// toolDef is one entry of toolConfig.tools: a tool or a cache point.
+type toolDef struct {
+	ToolSpec   *toolSpec   `json:"toolSpec,omitempty"`
+	CachePoint *cachePoint `json:"cachePoint,omitempty"`
+}
+
+type toolSpec struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	InputSchema struct {
+		JSON json.RawMessage `json:"json"`
+	} `json:"inputSchema"`
+}
+
+// converseResponse is the subset of the Converse reply the agent uses.
+type converseResponse struct {
+	Output struct {
+		Message converseMessage `json:"message"`
+	} `json:"output"`
+	StopReason string `json:"stopReason"`
+	Usage      struct {
+		InputTokens           int `json:"inputTokens"`
+		OutputTokens          int `json:"outputTokens"`
+		TotalTokens           int `json:"totalTokens"`
+		CacheReadInputTokens  int `json:"cacheReadInputTokens"`
+		CacheWriteInputTokens int `json:"cacheWriteInputTokens"`
+	} `json:"usage"`
+}
+
+// reToolName is the tool name Bedrock accepts.
+var reToolName = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// toolNames maps agent tool names, which may contain dots such as
+// "agent.run", to names Bedrock accepts and back.
+type toolNames struct {
+	out map[string]string
+	in  map[string]string
+}
+
+func newToolNames(tools []oai.Tool) toolNames {
+	n := toolNames{out: map[string]string{}, in: map[string]string{}}
+	for _, t := range tools {
+		n.add(t.Function.Name)
+	}
+	return n
+}
+
+func (n toolNames) add(name string) string {
+	if b, ok := n.out[name]; ok {
+		return b
+	}
+	b := name
+	if !reToolName.MatchString(b) {
+		b = strings.Map(func(r rune) rune {
+			if r == '_' || r == '-' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+				return r
+			}
+			return '_'
+		}, name)
+		if len(b) > 64 {
+			b = b[:64]
+		}
+	}
+	// Two names that map alike keep the first; the second gets a suffix
+	for i, base := 2, b; n.in[b] != "" && n.in[b] != name; i++ {
+		suffix := fmt.Sprintf("_%d", i)
+		b = base[:min(len(base), 64-len(suffix))] + suffix
+	}
+	n.out[name], n.in[b] = b, name
+	return b
+}
+
+func (n toolNames) agent(name string) string {
+	if a, ok := n.in[name]; ok {
+		return a
+	}
+	return name
+}
+
+// toConverse maps a chat completions request onto the Converse API. System
+// and developer messages become the system prompt, tool results become
+// toolResult blocks in user turns, and consecutive turns of one role merge,
+// since Converse requires user and assistant turns to alternate.
+func toConverse(req oai.ChatCompletionsRequest) (converseRequest, toolNames, error) {
+	names := newToolNames(req.Tools)
+	var out converseRequest
+	for _, m := range req.Messages {
+		var role string
+		var blocks []contentBlock
+		switch m.Role {
+		case oai.RoleSystem, oai.RoleDeveloper:
+			if strings.TrimSpace(m.Content) != "" {
+				out.System = append(out.System, contentBlock{Text: m.Content})
+				if m.CacheControl != nil {
+					out.System = append(out.System, contentBlock{CachePoint: defaultCachePoint})
+				}
+			}
+			continue
+		case oai.RoleUser:
+			role = "user"
+			if strings.TrimSpace(m.Content) != "" {
+				blocks = append(blocks, contentBlock{Text: m.Content})
+			}
+			for _, p := range m.Parts {
+				img, err := imageFromPart(p)
+				if err != nil {
+					return converseRequest{}, names, err
+				}
+				if img != nil {
+					blocks = append(blocks, contentBlock{Image: img})
+				}
+			}
+		case oai.RoleAssistant:
+			role = "assistant"
+			if strings.TrimSpace(m.Content) != "" {
+				blocks = append(blocks, contentBlock{Text: m.Content})
+			}
+			for _, tc := range m.ToolCalls {
+				blocks = append(blocks, contentBlock{ToolUse: &toolUse{ToolUseID: tc.ID, Name: names.add(tc.Function.Name), Input: toolInput(tc.Function.Arguments)}})
+			}
+		case oai.RoleTool:
+			role = "user"
+			blocks = append(blocks, contentBlock{ToolResult: toToolResult(m)})
+		default:
+			return converseRequest{}, names, fmt.Errorf("bedrock: unsupported message role %q", m.Role)
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+		if n := len(out.Messages); n > 0 && out.Messages[n-1].Role == role {
+			out.Messages[n-1].Content = append(out.Messages[n-1].Content, blocks...)
+			continue
+		}
+		out.Messages = append(out.Messages, converseMessage{Role: role, Content: blocks})
+	}
+	if req.MaxTokens > 0 || req.Temperature != nil || req.TopP != nil {
+		out.InferenceConfig = &inferenceConfig{MaxTokens: req.MaxTokens, Temperature: req.Temperature, TopP: req.TopP}
+	}
+	if len(req.Tools) > 0 {
+		tc := &toolConfig{}
+		for _, t := range req.Tools {
+			spec := &toolSpec{Name: names.add(t.Function.Name), Description: t.Function.Description}
+			spec.InputSchema.JSON = t.Function.Parameters
+			if len(spec.InputSchema.JSON) == 0 {
+				spec.InputSchema.JSON = json.RawMessage(`{"type":"object","properties":{}}`)
+			}
+			tc.Tools = append(tc.Tools, toolDef{ToolSpec: spec})
+			if t.CacheControl != nil {
+				tc.Tools = append(tc.Tools, toolDef{CachePoint: defaultCachePoint})
+			}
+		}
+		// Converse has no "none"; the agent only sends it with no tools
+		if req.ToolChoice == "required" {
+			tc.ToolChoice = json.RawMessage(`{"any":{}}`)
+		}
+		out.ToolConfig = tc
+	}
+	if len(req.ExtraBody) > 0 {
+		out.AdditionalModelRequestFields = req.ExtraBody
+	}
+	return out, names, nil
+}
+
+// toolInput returns tool call arguments as the JSON object Converse expects.
+func toolInput(args string) json.RawMessage {
+	args = strings.TrimSpace(args)
+	if args == "" {
+		return json.RawMessage(`{}`)
+	}
+	var obj map[string]json.RawMessage
+	if json.Unmarshal([]byte(args), &obj) != nil {
+		b, _ := json.Marshal(map[string]string{"arguments": args}) //nolint:errcheck
+		return b
+	}
+	return json.RawMessage(args)
+}
+
+// toToolResult sends a JSON object result as a json block and anything else
+// as text; the agent's {"error": ...} results are marked as errors.
+func toToolResult(m oai.Message) *toolResult {
+	r := &toolResult{ToolUseID: m.ToolCallID, Status: "success"}
+	content := strings.TrimSpace(m.Content)
+	var obj map[string]json.RawMessage
+	if json.Unmarshal([]byte(content), &obj) == nil {
+		r.Content = []toolResultContent{{JSON: json.RawMessage(content)}}
+		if _, failed := obj["error"]; failed {
+			r.Status = "error"
+		}
+		return r
+	}
+	if content == "" {
+		content = "{}"
+	}
+	r.Content = []toolResultContent{{Text: content}}
+	return r
+}
+
+// imageFromPart maps a base64 data URL image part to an image block. Other
+// part types are dropped; remote image URLs are not supported by Converse.
+func imageFromPart(p oai.ContentPart) (*imageBlock, error) {
+	if p.Type != "image_url" || p.ImageURL == nil {
+		return nil, nil
+	}
+	mime, data, ok := strings.Cut(strings.TrimPrefix(p.ImageURL.URL, "data:"), ";base64,")
+	if !ok || !strings.HasPrefix(p.ImageURL.URL, "data:image/") {
+		return nil, fmt.Errorf("bedrock: only base64 data URL images are supported, got %.40q", p.ImageURL.URL)
+	}
+	if _, err := base64.StdEncoding.DecodeString(data); err != nil {
+		return nil, fmt.Errorf("bedrock: invalid image data: %v", err)
+	}
+	img := &imageBlock{Format: strings.TrimPrefix(mime, "image/")}
+	if img.Format == "jpg" {
+		img.Format = "jpeg"
+	}
+	img.Source.Bytes = data
+	return img, nil
+}
+
+// fromConverse maps a Converse reply onto a chat completions response.
+func fromConverse(resp converseResponse, model string, names toolNames) oai.ChatCompletionsResponse {
+	msg := oai.Message{Role: oai.RoleAssistant}
+	var texts []string
+	for _, b := range resp.Output.Message.Content {
+		switch {
+		case b.ToolUse != nil:
+			args := string(b.ToolUse.Input)
+			if strings.TrimSpace(args) == "" || args == "null" {
+				args = "{}"
+			}
+			msg.ToolCalls = append(msg.ToolCalls, oai.ToolCall{ID: b.ToolUse.ToolUseID, Type: "function", Function: oai.ToolCallFunction{Name: names.agent(b.ToolUse.Name), Arguments: args}})
+		case b.Text != "":
+			texts = append(texts, b.Text)
+		}
+	}
+	msg.Content = strings.Join(texts, "\n")
+	return oai.ChatCompletionsResponse{
+		Object:  "chat.completion",
+		Model:   model,
+		Choices: []oai.ChatCompletionsResponseChoice{{Message: msg, FinishReason: finishReason(resp.StopReason)}},
+		Usage:   converseUsage(resp),
+	}
+}
+
+// converseUsage reports Bedrock's token counts the OpenAI way: inputTokens
+// excludes cache reads and writes, while prompt_tokens includes them.
+func converseUsage(resp converseResponse) *oai.Usage {
+	u := resp.Usage
+	out := &oai.Usage{
+		PromptTokens:             u.InputTokens + u.CacheReadInputTokens + u.CacheWriteInputTokens,
+		CompletionTokens:         u.OutputTokens,
+		TotalTokens:              u.TotalTokens,
+		CacheCreationInputTokens: u.CacheWriteInputTokens,
+	}
+	if u.CacheReadInputTokens > 0 {
+		out.PromptTokensDetails = &oai.PromptTokensDetails{CachedTokens: u.CacheReadInputTokens}
+	}
+	return out
+}
+
+// finishReason maps a Converse stopReason to the OpenAI finish_reason.
+func finishReason(stop string) string {
+	switch stop {
+	case "tool_use":
+		return "tool_calls"
+	case "max_tokens":
+		return "length"
+	case "guardrail_intervened", "content_filtered":
+		return "content_filter"
+	default:
+		return "stop"
+	}
+}
@@ -0,0 +1,98 @@
+// Package clock is the single source of wall-clock time and randomness for
+// values that end up in transcripts, audit logs, caches, and state snapshots.
+// By default it reads the system clock and a time-seeded generator;
+// SetDeterministic freezes time and seeds the generator so repeated runs
+// produce byte-identical output.
+package clock
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Clock reports the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// Fixed is a Clock that always reports T.
+type Fixed struct{ T time.Time }
+
+// Now returns f.T.
+func (f Fixed) Now() time.Time { return f.T }
+
+// DeterministicEpoch is the instant reported by every Now call in
+// deterministic mode.
+var DeterministicEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var (
+	mu            sync.Mutex
+	current       Clock = systemClock{}
+	rng                 = rand.New(rand.NewSource(time.Now().UnixNano()))
+	deterministic bool
+)
+
+// Now returns the current time from the configured clock.
+func Now() time.Time {
+	mu.Lock()
+	c := current
+	mu.Unlock()
+	return c.Now()
+}
+
+// Since returns the time elapsed since t according to the configured clock;
+// it is always zero in deterministic mode.
+func Since(t time.Time) time.Duration { return Now().Sub(t) }
+
+// Float64 returns a pseudo-random number in [0.0, 1.0).
+func Float64() float64 {
+	mu.Lock()
+	defer mu.Unlock()
+	return rng.Float64()
+}
+
+// Uint32 returns a pseudo-random 32-bit value.
+func Uint32() uint32 {
+	mu.Lock()
+	defer mu.Unlock()
+	return rng.Uint32()
+}
+
+// Read fills b with pseudo-random bytes. Callers needing unpredictable bytes
+// outside deterministic mode should use crypto/rand instead.
+func Read(b []byte) {
+	mu.Lock()
+	defer mu.Unlock()
+	_, _ = rng.Read(b) //nolint:errcheck // math/rand Read never fails
+}
+
+// Deterministic reports whether SetDeterministic is in effect.
+func Deterministic() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return deterministic
+}
+
+// SetDeterministic freezes the clock at DeterministicEpoch and seeds the
+// generator with seed.
+func SetDeterministic(seed int64) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = Fixed{T: DeterministicEpoch}
+	rng = rand.New(rand.NewSource(seed))
+	deterministic = true
+}
+
+// Reset restores the system clock and a time-seeded generator.
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	current = systemClock{}
+	rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	deterministic = false
+}
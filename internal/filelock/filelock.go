@@ -0,0 +1,50 @@
+// Package filelock takes advisory locks on files that hold across
+// processes: flock(2) on Unix and LockFileEx on Windows. The kernel drops a
+// lock when its holder exits, so a crashed process never leaves a stale
+// lock behind, and the lock file itself is never removed while in use.
+package filelock
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrLocked reports that TryLock found the lock held by another holder.
+var ErrLocked = errors.New("file is locked")
+
+// File is a held lock on an open file.
+type File struct {
+	*os.File
+}
+
+// Lock opens or creates path and blocks until it holds an exclusive lock.
+func Lock(path string) (*File, error) {
+	return lock(path, true)
+}
+
+// TryLock is Lock without waiting; it returns ErrLocked when the lock is
+// held elsewhere.
+func TryLock(path string) (*File, error) {
+	return lock(path, false)
+}
+
+func lock(path string, wait bool) (*File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f, wait); err != nil {
+		_ = f.Close() //nolint:errcheck // the lock error is what matters
+		return nil, err
+	}
+	return &File{File: f}, nil
+}
+
+// Unlock releases the lock and closes the file.
+func (l *File) Unlock() error {
+	if l == nil {
+		return nil
+	}
+	uerr := unlockFile(l.File)
+	return errors.Join(uerr, l.Close())
+}
@@ -0,0 +1,29 @@
+package filelock
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestTryLock_HeldUntilUnlock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "x.lock")
+	held, err := Lock(path)
+	if err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	// A second open file description contends like another process would
+	if _, err := TryLock(path); !errors.Is(err, ErrLocked) {
+		t.Fatalf("TryLock while held: err=%v, want ErrLocked", err)
+	}
+	if err := held.Unlock(); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	again, err := TryLock(path)
+	if err != nil {
+		t.Fatalf("TryLock after unlock: %v", err)
+	}
+	if err := again.Unlock(); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+}
@@ -0,0 +1,290 @@
+package gemini
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// generateRequest is the body of models/{model}:generateContent and
+// :streamGenerateContent.
+type generateRequest struct {
+	Contents          []content         `json:"contents"`
+	SystemInstruction *content          `json:"systemInstruction,omitempty"`
+	Tools             []toolDecls       `json:"tools,omitempty"`
+	ToolConfig        *toolConfig       `json:"toolConfig,omitempty"`
+	GenerationConfig  *generationConfig `json:"generationConfig,omitempty"`
+}
+
+type content struct {
+	Role  string `json:"role,omitempty"`
+	Parts []part `json:"parts"`
+}
+
+// part is one element of a Gemini content; exactly one field is set, apart
+// from Thought, which marks a thinking summary the agent does not show.
+type part struct {
+	Text             string            `json:"text,omitempty"`
+	Thought          bool              `json:"thought,omitempty"`
+	InlineData       *inlineData       `json:"inlineData,omitempty"`
+	FileData         *fileData         `json:"fileData,omitempty"`
+	FunctionCall     *functionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *functionResponse `json:"functionResponse,omitempty"`
+}
+
+type inlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type fileData struct {
+	MimeType string `json:"mimeType,omitempty"`
+	FileURI  string `json:"fileUri"`
+}
+
+type functionCall struct {
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+type functionResponse struct {
+	Name     string          `json:"name"`
+	Response json.RawMessage `json:"response"`
+}
+
+type toolDecls struct {
+	FunctionDeclarations []functionDecl `json:"functionDeclarations"`
+}
+
+type functionDecl struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+type toolConfig struct {
+	FunctionCallingConfig struct {
+		Mode string `json:"mode"`
+	} `json:"functionCallingConfig"`
+}
+
+type generationConfig struct {
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"topP,omitempty"`
+	MaxOutputTokens  int      `json:"maxOutputTokens,omitempty"`
+	Seed             *int64   `json:"seed,omitempty"`
+	ResponseMimeType string   `json:"responseMimeType,omitempty"`
+}
+
+// generateResponse is one reply, or one streamed chunk of a reply.
+type generateResponse struct {
+	Candidates []struct {
+		Content      content `json:"content"`
+		FinishReason string  `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback,omitempty"`
+	UsageMetadata *usageMetadata `json:"usageMetadata,omitempty"`
+	ResponseID    string         `json:"responseId"`
+}
+
+// usageMetadata counts a reply's tokens; Gemini caches prompt prefixes
+// implicitly and reports the hits in cachedContentTokenCount.
+type usageMetadata struct {
+	PromptTokenCount        int `json:"promptTokenCount"`
+	CandidatesTokenCount    int `json:"candidatesTokenCount"`
+	TotalTokenCount         int `json:"totalTokenCount"`
+	CachedContentTokenCount int `json:"cachedContentTokenCount"`
+}
+
+func (u *usageMetadata) usage() *oai.Usage {
+	out := &oai.Usage{PromptTokens: u.PromptTokenCount, CompletionTokens: u.CandidatesTokenCount, TotalTokens: u.TotalTokenCount}
+	if u.CachedContentTokenCount > 0 {
+		out.PromptTokensDetails = &oai.PromptTokensDetails{CachedTokens: u.CachedContentTokenCount}
+	}
+	return out
+}
+
+// toGenerate maps a chat completions request onto generateContent. System
+// and developer messages become the system instruction, tool results become
+// functionResponse parts in user turns, and consecutive turns of one role
+// merge so parallel calls are answered in a single turn.
+func toGenerate(req oai.ChatCompletionsRequest) (generateRequest, error) {
+	var out generateRequest
+	var system []part
+	// Gemini answers a call by function name; tool messages carry the id
+	callNames := map[string]string{}
+	for _, m := range req.Messages {
+		var role string
+		var parts []part
+		switch m.Role {
+		case oai.RoleSystem, oai.RoleDeveloper:
+			if strings.TrimSpace(m.Content) != "" {
+				system = append(system, part{Text: m.Content})
+			}
+			continue
+		case oai.RoleUser:
+			role = "user"
+			if strings.TrimSpace(m.Content) != "" {
+				parts = append(parts, part{Text: m.Content})
+			}
+			for _, p := range m.Parts {
+				if ip, ok := imagePart(p); ok {
+					parts = append(parts, ip)
+				}
+			}
+		case oai.RoleAssistant:
+			role = "model"
+			if strings.TrimSpace(m.Content) != "" {
+				parts = append(parts, part{Text: m.Content})
+			}
+			for _, tc := range m.ToolCalls {
+				callNames[tc.ID] = tc.Function.Name
+				parts = append(parts, part{FunctionCall: &functionCall{Name: tc.Function.Name, Args: jsonObject(tc.Function.Arguments, "arguments")}})
+			}
+		case oai.RoleTool:
+			role = "user"
+			name := m.Name
+			if name == "" {
+				name = callNames[m.ToolCallID]
+			}
+			parts = append(parts, part{FunctionResponse: &functionResponse{Name: name, Response: jsonObject(m.Content, "content")}})
+		default:
+			return generateRequest{}, fmt.Errorf("gemini: unsupported message role %q", m.Role)
+		}
+		if len(parts) == 0 {
+			continue
+		}
+		if n := len(out.Contents); n > 0 && out.Contents[n-1].Role == role {
+			out.Contents[n-1].Parts = append(out.Contents[n-1].Parts, parts...)
+			continue
+		}
+		out.Contents = append(out.Contents, content{Role: role, Parts: parts})
+	}
+	if len(system) > 0 {
+		out.SystemInstruction = &content{Parts: system}
+	}
+	if len(req.Tools) > 0 {
+		decls := toolDecls{}
+		for _, t := range req.Tools {
+			decls.FunctionDeclarations = append(decls.FunctionDeclarations, functionDecl{Name: t.Function.Name, Description: t.Function.Description, Parameters: t.Function.Parameters})
+		}
+		out.Tools = []toolDecls{decls}
+		mode := map[string]string{"auto": "AUTO", "required": "ANY", "none": "NONE"}[req.ToolChoice]
+		if mode != "" {
+			out.ToolConfig = &toolConfig{}
+			out.ToolConfig.FunctionCallingConfig.Mode = mode
+		}
+	}
+	gc := generationConfig{Temperature: req.Temperature, TopP: req.TopP, MaxOutputTokens: req.MaxTokens, Seed: req.Seed}
+	if req.ResponseFormat != nil && req.ResponseFormat.Type == "json_object" {
+		gc.ResponseMimeType = "application/json"
+	}
+	if gc != (generationConfig{}) {
+		out.GenerationConfig = &gc
+	}
+	return out, nil
+}
+
+// jsonObject returns s when it is a JSON object and otherwise wraps it as
+// {key: s}; Gemini requires objects for call arguments and responses.
+func jsonObject(s, key string) json.RawMessage {
+	s = strings.TrimSpace(s)
+	var obj map[string]json.RawMessage
+	if s != "" && json.Unmarshal([]byte(s), &obj) == nil {
+		return json.RawMessage(s)
+	}
+	if s == "" && key == "arguments" {
+		return json.RawMessage(`{}`)
+	}
+	b, _ := json.Marshal(map[string]string{key: s}) //nolint:errcheck
+	return b
+}
+
+// imagePart maps an image part: data URLs are sent inline, other URLs as
+// file data, which Gemini fetches itself.
+func imagePart(p oai.ContentPart) (part, bool) {
+	if p.Type != "image_url" || p.ImageURL == nil || p.ImageURL.URL == "" {
+		return part{}, false
+	}
+	u := p.ImageURL.URL
+	if rest, ok := strings.CutPrefix(u, "data:"); ok {
+		if mime, data, ok := strings.Cut(rest, ";base64,"); ok {
+			return part{InlineData: &inlineData{MimeType: mime, Data: data}}, true
+		}
+	}
+	return part{FileData: &fileData{FileURI: u}}, true
+}
+
+// fromGenerate maps a reply onto a chat completions response. Call ids
+// derive from turn, the number of request messages, so they are unique in
+// the conversation and stable across -record and -replay.
+func fromGenerate(resp generateResponse, model string, turn int) oai.ChatCompletionsResponse {
+	msg, finish := candidateMessage(resp, turn)
+	out := oai.ChatCompletionsResponse{
+		ID:      resp.ResponseID,
+		Object:  "chat.completion",
+		Model:   model,
+		Choices: []oai.ChatCompletionsResponseChoice{{Message: msg, FinishReason: finish}},
+	}
+	if resp.UsageMetadata != nil {
+		out.Usage = resp.UsageMetadata.usage()
+	}
+	return out
+}
+
+// candidateMessage returns the first candidate as an assistant message and
+// its finish reason ("" while a stream is still going).
+func candidateMessage(resp generateResponse, turn int) (oai.Message, string) {
+	msg := oai.Message{Role: oai.RoleAssistant}
+	if len(resp.Candidates) == 0 {
+		if resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != "" {
+			return msg, "content_filter"
+		}
+		return msg, ""
+	}
+	c := resp.Candidates[0]
+	var texts []string
+	for _, p := range c.Content.Parts {
+		switch {
+		case p.Thought:
+		case p.FunctionCall != nil:
+			args := strings.TrimSpace(string(p.FunctionCall.Args))
+			if args == "" || args == "null" {
+				args = "{}"
+			}
+			msg.ToolCalls = append(msg.ToolCalls, oai.ToolCall{ID: callID(turn, len(msg.ToolCalls)), Type: "function", Function: oai.ToolCallFunction{Name: p.FunctionCall.Name, Arguments: args}})
+		case p.Text != "":
+			texts = append(texts, p.Text)
+		}
+	}
+	msg.Content = strings.Join(texts, "")
+	return msg, finishReason(c.FinishReason, len(msg.ToolCalls) > 0)
+}
+
+func callID(turn, i int) string {
+	return fmt.Sprintf("call_%d_%d", turn, i)
+}
+
+// finishReason maps a Gemini finishReason to the OpenAI finish_reason;
+// Gemini reports STOP for replies that call functions.
+func finishReason(reason string, calls bool) string {
+	switch reason {
+	case "":
+		return ""
+	case "STOP":
+		if calls {
+			return "tool_calls"
+		}
+		return "stop"
+	case "MAX_TOKENS":
+		return "length"
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "IMAGE_SAFETY":
+		return "content_filter"
+	default:
+		return "stop"
+	}
+}
@@ -0,0 +1,232 @@
+// Package gemini serves the agent's OpenAI-compatible chat requests from the
+// Google Gemini generateContent API. Transport is an http.RoundTripper that
+// the chat client uses in place of the network: it rewrites each
+// POST {base}/chat/completions into POST {base}/models/{model}:generateContent
+// (or :streamGenerateContent?alt=sse for streaming requests) and turns the
+// reply back into a chat completion or a stream of completion chunks.
+package gemini
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// DefaultBaseURL is the Gemini API root used when -base-url is not set.
+const DefaultBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
+// maxReplyBytes bounds a non-streaming reply, and maxEventBytes one streamed
+// event, read by the transport; the client applies its own limits to the
+// converted data.
+const (
+	maxReplyBytes = 64 << 20
+	maxEventBytes = 16 << 20
+)
+
+// Transport converts chat completion requests to generateContent calls.
+type Transport struct {
+	apiKey string
+	base   http.RoundTripper
+}
+
+// NewTransport returns a transport authenticating with apiKey that sends
+// through base; nil uses oai.SharedTransport as it is at each request.
+func NewTransport(apiKey string, base http.RoundTripper) (*Transport, error) {
+	if strings.TrimSpace(apiKey) == "" {
+		return nil, fmt.Errorf("gemini: an API key is required (-api-key, GEMINI_API_KEY, or GOOGLE_API_KEY)")
+	}
+	return &Transport{apiKey: strings.TrimSpace(apiKey), base: base}, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	root, ok := strings.CutSuffix(req.URL.Path, "/chat/completions")
+	if req.Method != http.MethodPost || !ok {
+		return reply(req, http.StatusNotFound, "application/json", fmt.Sprintf(`{"error":{"message":"gemini: %s %s is not supported"}}`, req.Method, req.URL.Path)), nil
+	}
+	body, err := requestJSON(req)
+	if err != nil {
+		return nil, err
+	}
+	var chat oai.ChatCompletionsRequest
+	if err := json.Unmarshal(body, &chat); err != nil {
+		return nil, fmt.Errorf("gemini: decode chat request: %w", err)
+	}
+	if chat.Model == "" {
+		return reply(req, http.StatusBadRequest, "application/json", `{"error":{"message":"gemini: model is required"}}`), nil
+	}
+	gen, err := toGenerate(chat)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(gen)
+	if err != nil {
+		return nil, fmt.Errorf("gemini: encode generateContent request: %w", err)
+	}
+
+	model := strings.TrimPrefix(chat.Model, "models/")
+	method, query := ":generateContent", ""
+	if chat.Stream {
+		method, query = ":streamGenerateContent", "alt=sse"
+	}
+	u := &url.URL{Scheme: req.URL.Scheme, Host: req.URL.Host, Path: root + "/models/" + model + method, RawQuery: query}
+	out, err := http.NewRequestWithContext(req.Context(), http.MethodPost, u.String(), bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	out.Header.Set("Content-Type", "application/json")
+	out.Header.Set("x-goog-api-key", t.apiKey)
+	if ua := req.Header.Get("User-Agent"); ua != "" {
+		out.Header.Set("User-Agent", ua)
+	}
+
+	base := t.base
+	if base == nil {
+		base = oai.SharedTransport()
+	}
+	resp, err := base.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// Errors pass through so the client's retry and status handling apply
+		defer resp.Body.Close()                                        //nolint:errcheck
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, maxReplyBytes)) //nolint:errcheck
+		r := reply(req, resp.StatusCode, "application/json", string(raw))
+		r.Header = resp.Header.Clone()
+		r.Header.Del("Content-Length")
+		r.Header.Del("Content-Encoding")
+		return r, nil
+	}
+	turn := len(chat.Messages)
+	if chat.Stream {
+		pr, pw := io.Pipe()
+		go func() {
+			defer resp.Body.Close() //nolint:errcheck
+			pw.CloseWithError(translateStream(resp.Body, pw, chat.Model, turn))
+		}()
+		r := reply(req, http.StatusOK, "text/event-stream", "")
+		r.Body, r.ContentLength = pr, -1
+		return r, nil
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxReplyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("gemini: read generateContent response: %w", err)
+	}
+	var gr generateResponse
+	if err := json.Unmarshal(raw, &gr); err != nil {
+		return nil, fmt.Errorf("gemini: decode generateContent response: %w", err)
+	}
+	b, err := json.Marshal(fromGenerate(gr, chat.Model, turn))
+	if err != nil {
+		return nil, err
+	}
+	return reply(req, http.StatusOK, "application/json", string(b)), nil
+}
+
+// streamChoice and streamChunk encode chat.completion.chunk events.
+type streamChoice struct {
+	Index int `json:"index"`
+	Delta struct {
+		Role      string                    `json:"role,omitempty"`
+		Content   string                    `json:"content,omitempty"`
+		ToolCalls []oai.StreamToolCallDelta `json:"tool_calls,omitempty"`
+	} `json:"delta"`
+	FinishReason string `json:"finish_reason,omitempty"`
+}
+
+type streamChunk struct {
+	ID      string         `json:"id,omitempty"`
+	Object  string         `json:"object"`
+	Model   string         `json:"model"`
+	Choices []streamChoice `json:"choices"`
+	Usage   *oai.Usage     `json:"usage,omitempty"`
+}
+
+// translateStream rewrites Gemini's SSE events, each a whole
+// generateContent response, as chat completion chunks ending in [DONE].
+// Function calls arrive complete, so each becomes one tool call delta.
+func translateStream(r io.Reader, w io.Writer, model string, turn int) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), maxEventBytes)
+	calls := 0
+	for sc.Scan() {
+		data, ok := strings.CutPrefix(sc.Text(), "data:")
+		if !ok || strings.TrimSpace(data) == "" {
+			continue
+		}
+		var gr generateResponse
+		if err := json.Unmarshal([]byte(data), &gr); err != nil {
+			return fmt.Errorf("gemini: decode stream event: %w", err)
+		}
+		msg, finish := candidateMessage(gr, turn)
+		var ch streamChoice
+		ch.Delta.Role = oai.RoleAssistant
+		ch.Delta.Content = msg.Content
+		for _, tc := range msg.ToolCalls {
+			ch.Delta.ToolCalls = append(ch.Delta.ToolCalls, oai.StreamToolCallDelta{Index: calls, ID: callID(turn, calls), Type: "function", Function: tc.Function})
+			calls++
+		}
+		if finish == "stop" && calls > 0 {
+			finish = "tool_calls"
+		}
+		ch.FinishReason = finish
+		chunk := streamChunk{ID: gr.ResponseID, Object: "chat.completion.chunk", Model: model, Choices: []streamChoice{ch}}
+		if gr.UsageMetadata != nil && finish != "" {
+			chunk.Usage = gr.UsageMetadata.usage()
+		}
+		b, err := json.Marshal(chunk)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", b); err != nil {
+			return err
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("gemini: read stream: %w", err)
+	}
+	_, err := io.WriteString(w, "data: [DONE]\n\n")
+	return err
+}
+
+// requestJSON reads the chat request body, undoing -http-gzip.
+func requestJSON(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, fmt.Errorf("gemini: empty chat request")
+	}
+	defer req.Body.Close() //nolint:errcheck
+	var r io.Reader = req.Body
+	if strings.EqualFold(req.Header.Get("Content-Encoding"), "gzip") {
+		zr, err := gzip.NewReader(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("gemini: gzip request: %w", err)
+		}
+		r = zr
+	}
+	return io.ReadAll(r)
+}
+
+// reply builds a response to req.
+func reply(req *http.Request, status int, contentType, body string) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {contentType}},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,147 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+func toolTurns() oai.ChatCompletionsRequest {
+	return oai.ChatCompletionsRequest{
+		Model: "gemini-2.0-flash",
+		Tools: []oai.Tool{{Type: "function", Function: oai.ToolFunction{Name: "fs_read", Description: "read", Parameters: json.RawMessage(`{"type":"object"}`)}}},
+		Messages: []oai.Message{
+			{Role: oai.RoleSystem, Content: "be brief"},
+			{Role: oai.RoleUser, Content: "read a and b"},
+			{Role: oai.RoleAssistant, ToolCalls: []oai.ToolCall{
+				{ID: "c1", Type: "function", Function: oai.ToolCallFunction{Name: "fs_read", Arguments: `{"path":"a"}`}},
+				{ID: "c2", Type: "function", Function: oai.ToolCallFunction{Name: "fs_read", Arguments: `{"path":"b"}`}},
+			}},
+			{Role: oai.RoleTool, ToolCallID: "c1", Content: `{"content":"A"}`},
+			{Role: oai.RoleTool, ToolCallID: "c2", Content: "plain"},
+		},
+	}
+}
+
+func TestTransport_GenerateContent(t *testing.T) {
+	var got generateRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/models/gemini-2.0-flash:generateContent" || r.Header.Get("x-goog-api-key") != "g-key" || r.Header.Get("Authorization") != "" {
+			t.Errorf("path=%q key=%q auth=%q", r.URL.Path, r.Header.Get("x-goog-api-key"), r.Header.Get("Authorization"))
+		}
+		b, _ := io.ReadAll(r.Body) //nolint:errcheck
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Errorf("body=%s err=%v", b, err)
+		}
+		_, _ = io.WriteString(w, `{"candidates":[{"content":{"role":"model","parts":[{"text":"thinking","thought":true},{"text":"checking c"},{"functionCall":{"name":"fs_read","args":{"path":"c"}}}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":9,"candidatesTokenCount":4,"totalTokenCount":13}}`) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	tr, err := NewTransport("g-key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := oai.NewClient(srv.URL+"/v1beta", "sk-unused", 5*time.Second).WithTransport(tr).CreateChatCompletion(context.Background(), toolTurns())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.SystemInstruction == nil || got.SystemInstruction.Parts[0].Text != "be brief" {
+		t.Fatalf("systemInstruction=%+v", got.SystemInstruction)
+	}
+	if len(got.Contents) != 3 || got.Contents[1].Role != "model" || len(got.Contents[1].Parts) != 2 {
+		t.Fatalf("contents=%+v", got.Contents)
+	}
+	// Both results answer the parallel calls in one user turn, by name
+	results := got.Contents[2].Parts
+	if len(results) != 2 || results[0].FunctionResponse.Name != "fs_read" || string(results[0].FunctionResponse.Response) != `{"content":"A"}` || string(results[1].FunctionResponse.Response) != `{"content":"plain"}` {
+		t.Fatalf("results=%+v", results)
+	}
+	if len(got.Tools) != 1 || got.Tools[0].FunctionDeclarations[0].Name != "fs_read" {
+		t.Fatalf("tools=%+v", got.Tools)
+	}
+
+	ch := resp.Choices[0]
+	if ch.FinishReason != "tool_calls" || ch.Message.Content != "checking c" || len(ch.Message.ToolCalls) != 1 {
+		t.Fatalf("choice=%+v", ch)
+	}
+	if tc := ch.Message.ToolCalls[0]; tc.ID != "call_5_0" || tc.Function.Name != "fs_read" || tc.Function.Arguments != `{"path":"c"}` {
+		t.Fatalf("tool call=%+v", tc)
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 13 {
+		t.Fatalf("usage=%+v", resp.Usage)
+	}
+}
+
+func TestTransport_Stream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/models/gemini-2.0-flash:streamGenerateContent" || r.URL.RawQuery != "alt=sse" {
+			t.Errorf("url=%s", r.URL)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"Hel\"}]}}]}\r\n\r\n"+ //nolint:errcheck
+			"data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"lo\"},{\"functionCall\":{\"name\":\"fs_read\",\"args\":{\"path\":\"x\"}}}]},\"finishReason\":\"STOP\"}],\"usageMetadata\":{\"promptTokenCount\":3,\"candidatesTokenCount\":2,\"totalTokenCount\":5}}\r\n\r\n")
+	}))
+	defer srv.Close()
+
+	tr, err := NewTransport("g-key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := oai.ChatCompletionsRequest{Model: "gemini-2.0-flash", Stream: true, Messages: []oai.Message{{Role: oai.RoleUser, Content: "hi"}}}
+	var text strings.Builder
+	var calls []oai.StreamToolCallDelta
+	var finish string
+	var usage *oai.Usage
+	err = oai.NewClient(srv.URL+"/v1beta", "", 5*time.Second).WithTransport(tr).StreamChat(context.Background(), req, func(c oai.StreamChunk) error {
+		for _, ch := range c.Choices {
+			text.WriteString(ch.Delta.Content)
+			calls = append(calls, ch.Delta.ToolCalls...)
+			if ch.FinishReason != "" {
+				finish = ch.FinishReason
+			}
+		}
+		if c.Usage != nil {
+			usage = c.Usage
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text.String() != "Hello" || finish != "tool_calls" || len(calls) != 1 || calls[0].ID != "call_1_0" || calls[0].Function.Arguments != `{"path":"x"}` {
+		t.Fatalf("text=%q finish=%q calls=%+v", text.String(), finish, calls)
+	}
+	if usage == nil || usage.TotalTokens != 5 {
+		t.Fatalf("usage=%+v", usage)
+	}
+}
+
+func TestTransport_ErrorsPassThrough(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = io.WriteString(w, `{"error":{"code":429,"message":"Resource has been exhausted"}}`) //nolint:errcheck
+	}))
+	defer srv.Close()
+	tr, err := NewTransport("g-key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = oai.NewClient(srv.URL, "", 5*time.Second).WithTransport(tr).CreateChatCompletion(context.Background(), oai.ChatCompletionsRequest{Model: "m", Messages: []oai.Message{{Role: oai.RoleUser, Content: "hi"}}})
+	if err == nil || !strings.Contains(err.Error(), "429") || !strings.Contains(err.Error(), "exhausted") {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+func TestNewTransport_RequiresKey(t *testing.T) {
+	if _, err := NewTransport(" ", nil); err == nil {
+		t.Fatal("want error without an API key")
+	}
+}
@@ -0,0 +1,206 @@
+// Package metrics records process-wide counters and histograms (HTTP latency,
+// retries, tool latency, token usage) and renders them in the Prometheus text
+// exposition format. It has no dependencies beyond the standard library.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are histogram upper bounds in seconds, spanning fast local
+// tools up to slow model calls.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// Registry holds metric families in registration order.
+type Registry struct {
+	mu       sync.Mutex
+	families []*family
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry { return &Registry{} }
+
+// Default is the registry the agent records into and serves at /metrics.
+var Default = NewRegistry()
+
+// Metrics recorded by the agent.
+var (
+	HTTPRequestDuration = Default.Histogram("goagent_http_request_duration_seconds", "Chat completion HTTP attempt latency.", DefaultBuckets, "stage", "status")
+	HTTPRetries         = Default.Counter("goagent_http_retries_total", "Chat completion attempts after the first for a call.", "stage")
+	ToolDuration        = Default.Histogram("goagent_tool_duration_seconds", "Tool execution latency.", DefaultBuckets, "tool", "outcome")
+	ToolOutputBytes     = Default.Counter("goagent_tool_output_bytes_total", "Bytes a tool wrote to stdout, a proxy for the context it costs.", "tool")
+	Tokens              = Default.Counter("goagent_tokens_total", "Tokens reported by the API.", "kind")
+)
+
+type family struct {
+	name, help, kind string
+	labels           []string
+	buckets          []float64
+	series           map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64  // counter value or histogram sum
+	counts      []uint64 // per-bucket (non-cumulative) counts
+	count       uint64   // histogram observations
+}
+
+func (r *Registry) register(f *family) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.families {
+		if existing.name == f.name {
+			panic("metrics: duplicate metric " + f.name)
+		}
+	}
+	r.families = append(r.families, f)
+	return f
+}
+
+// CounterVec is a monotonically increasing value partitioned by labels.
+type CounterVec struct {
+	r *Registry
+	f *family
+}
+
+// Counter registers a counter with the given label names.
+func (r *Registry) Counter(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{r: r, f: r.register(&family{name: name, help: help, kind: "counter", labels: labels, series: map[string]*series{}})}
+}
+
+// Add increases the series identified by labelValues (in label-name order)
+// by v. Negative values are ignored.
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	c.r.mu.Lock()
+	defer c.r.mu.Unlock()
+	c.f.get(labelValues).value += v
+}
+
+// Value returns the current value of one series.
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	c.r.mu.Lock()
+	defer c.r.mu.Unlock()
+	return c.f.get(labelValues).value
+}
+
+// HistogramVec counts observations into buckets, partitioned by labels.
+type HistogramVec struct {
+	r *Registry
+	f *family
+}
+
+// Histogram registers a histogram with sorted upper bounds and label names.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	return &HistogramVec{r: r, f: r.register(&family{name: name, help: help, kind: "histogram", labels: labels, buckets: b, series: map[string]*series{}})}
+}
+
+// Observe records v in the series identified by labelValues.
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	h.r.mu.Lock()
+	defer h.r.mu.Unlock()
+	s := h.f.get(labelValues)
+	s.value += v
+	s.count++
+	for i, ub := range h.f.buckets {
+		if v <= ub {
+			s.counts[i]++
+			return
+		}
+	}
+}
+
+// Count returns the number of observations in one series.
+func (h *HistogramVec) Count(labelValues ...string) uint64 {
+	h.r.mu.Lock()
+	defer h.r.mu.Unlock()
+	return h.f.get(labelValues).count
+}
+
+// get returns the series for labelValues, creating it on first use. Missing
+// values are treated as empty and extras are dropped. Callers hold the lock.
+func (f *family) get(labelValues []string) *series {
+	vals := make([]string, len(f.labels))
+	copy(vals, labelValues)
+	key := strings.Join(vals, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: vals, counts: make([]uint64, len(f.buckets))}
+		f.series[key] = s
+	}
+	return s
+}
+
+// WriteText renders every family in the Prometheus text exposition format
+// (version 0.0.4). Series are sorted by label values for stable output.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var b strings.Builder
+	for _, f := range r.families {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", f.name, escapeHelp(f.help), f.name, f.kind)
+		keys := make([]string, 0, len(f.series))
+		for k := range f.series {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			s := f.series[k]
+			if f.kind == "counter" {
+				fmt.Fprintf(&b, "%s%s %s\n", f.name, labelPairs(f.labels, s.labelValues, "", ""), formatFloat(s.value))
+				continue
+			}
+			var cumulative uint64
+			for i, ub := range f.buckets {
+				cumulative += s.counts[i]
+				fmt.Fprintf(&b, "%s_bucket%s %d\n", f.name, labelPairs(f.labels, s.labelValues, "le", formatFloat(ub)), cumulative)
+			}
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", f.name, labelPairs(f.labels, s.labelValues, "le", "+Inf"), s.count)
+			fmt.Fprintf(&b, "%s_sum%s %s\n", f.name, labelPairs(f.labels, s.labelValues, "", ""), formatFloat(s.value))
+			fmt.Fprintf(&b, "%s_count%s %d\n", f.name, labelPairs(f.labels, s.labelValues, "", ""), s.count)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func labelPairs(names, values []string, extraName, extraValue string) string {
+	parts := make([]string, 0, len(names)+1)
+	for i, n := range names {
+		parts = append(parts, n+`="`+escapeLabel(values[i])+`"`)
+	}
+	if extraName != "" {
+		parts = append(parts, extraName+`="`+extraValue+`"`)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+
+func escapeHelp(s string) string { return helpEscaper.Replace(s) }
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestWriteText_CountersAndHistograms(t *testing.T) {
+	r := NewRegistry()
+	c := r.Counter("test_total", "A counter.", "kind")
+	h := r.Histogram("test_seconds", "A histogram.", []float64{1, 0.1}, "tool")
+	c.Add(2, "b")
+	c.Add(1, "a")
+	c.Add(-5, "a") // ignored
+	h.Observe(0.05, `we"ird`)
+	h.Observe(0.5, `we"ird`)
+	h.Observe(3, `we"ird`)
+
+	var b bytes.Buffer
+	if err := r.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP test_total A counter.
+# TYPE test_total counter
+test_total{kind="a"} 1
+test_total{kind="b"} 2
+# HELP test_seconds A histogram.
+# TYPE test_seconds histogram
+test_seconds_bucket{tool="we\"ird",le="0.1"} 1
+test_seconds_bucket{tool="we\"ird",le="1"} 2
+test_seconds_bucket{tool="we\"ird",le="+Inf"} 3
+test_seconds_sum{tool="we\"ird"} 3.55
+test_seconds_count{tool="we\"ird"} 3
+`
+	if b.String() != want {
+		t.Fatalf("exposition mismatch:\n%s\nwant:\n%s", b.String(), want)
+	}
+	if c.Value("b") != 2 || h.Count(`we"ird`) != 3 {
+		t.Fatalf("accessors: counter=%v count=%d", c.Value("b"), h.Count(`we"ird`))
+	}
+}
+
+func TestRegistry_DuplicateNamePanics(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("dup_total", "x")
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic on duplicate registration")
+		}
+	}()
+	r.Counter("dup_total", "x")
+}
+
+func TestListen_ServesDefaultRegistry(t *testing.T) {
+	Tokens.Add(7, "prompt")
+	s, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = s.Close() }() //nolint:errcheck
+	resp, err := http.Get("http://" + s.Addr() + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }() //nolint:errcheck
+	body, _ := io.ReadAll(resp.Body)         //nolint:errcheck
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("content type: %s", resp.Header.Get("Content-Type"))
+	}
+	for _, want := range []string{"# TYPE goagent_http_request_duration_seconds histogram", `goagent_tokens_total{kind="prompt"}`} {
+		if !strings.Contains(string(body), want) {
+			t.Fatalf("missing %q in:\n%s", want, body)
+		}
+	}
+	if _, err := Listen(s.Addr()); err == nil {
+		t.Fatal("expected bind error on a used address")
+	}
+}
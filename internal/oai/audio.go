@@ -0,0 +1,137 @@
+package oai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// AudioClient calls an OpenAI-compatible audio API. Retries follow the same
+// policy as chat calls: transport errors, 429, and 5xx are retried with
+// backoff, honoring Retry-After.
+type AudioClient struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+	retry      RetryPolicy
+}
+
+// NewAudioClient creates an audio client; retry.MaxRetries 0 means a single
+// attempt.
+func NewAudioClient(baseURL, apiKey string, timeout time.Duration, retry RetryPolicy) *AudioClient {
+	if retry.MaxRetries < 0 {
+		retry.MaxRetries = 0
+	}
+	return &AudioClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		httpClient: &http.Client{
+			Timeout:   timeout,
+			Transport: SharedTransport(),
+		},
+		retry: retry,
+	}
+}
+
+// TranscriptionRequest is a POST /audio/transcriptions call.
+type TranscriptionRequest struct {
+	Model string
+	// Filename is sent with the upload; servers use its extension to pick a
+	// decoder
+	Filename string
+	Audio    []byte
+	// Language is an optional ISO-639-1 hint such as "en"
+	Language string
+}
+
+// Transcribe uploads req.Audio and returns the transcribed text.
+func (c *AudioClient) Transcribe(ctx context.Context, req TranscriptionRequest) (string, error) {
+	var form bytes.Buffer
+	w := multipart.NewWriter(&form)
+	fields := [][2]string{{"model", req.Model}, {"response_format", "json"}}
+	if req.Language != "" {
+		fields = append(fields, [2]string{"language", req.Language})
+	}
+	for _, f := range fields {
+		if err := w.WriteField(f[0], f[1]); err != nil {
+			return "", fmt.Errorf("build transcription request: %w", err)
+		}
+	}
+	part, err := w.CreateFormFile("file", req.Filename)
+	if err == nil {
+		_, err = part.Write(req.Audio)
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		return "", fmt.Errorf("build transcription request: %w", err)
+	}
+
+	endpoint := c.baseURL + "/audio/transcriptions"
+	stage := auditStageFromContext(ctx)
+	idemKey := generateIdempotencyKey()
+	attempts := c.retry.MaxRetries + 1
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(form.Bytes()))
+		if err != nil {
+			return "", fmt.Errorf("new request: %w", err)
+		}
+		httpReq.Header.Set("Content-Type", w.FormDataContentType())
+		if c.apiKey != "" {
+			httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+		}
+		httpReq.Header.Set("Idempotency-Key", idemKey)
+		resp, err := c.httpClient.Do(httpReq)
+		if err != nil {
+			lastErr = err
+			if attempt < attempts-1 && ctx.Err() == nil && isRetryableError(err) {
+				back := backoffWithJitter(c.retry.Backoff, attempt, c.retry.JitterFraction, c.retry.Rand)
+				logHTTPAttempt(stage, idemKey, attempt+1, attempts, 0, back.Milliseconds(), endpoint, err.Error())
+				sleepCtx(ctx, back)
+				continue
+			}
+			logHTTPAttempt(stage, idemKey, attempt+1, attempts, 0, 0, endpoint, err.Error())
+			return "", fmt.Errorf("audio POST failed: %v (base=%s, http-timeout=%s)", err, c.baseURL, c.httpClient.Timeout)
+		}
+		body, readErr := io.ReadAll(resp.Body)
+		_ = resp.Body.Close() //nolint:errcheck // fully read
+		if readErr != nil {
+			return "", fmt.Errorf("read response body: %w", readErr)
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			lastErr = fmt.Errorf("audio API %s: %d: %s", endpoint, resp.StatusCode, truncate(string(body), 2000))
+			if attempt < attempts-1 && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500) {
+				back, ok := serverRetryWait(resp.Header, time.Now(), c.retry.MaxRetryWait)
+				if !ok {
+					back = backoffWithJitter(c.retry.Backoff, attempt, c.retry.JitterFraction, c.retry.Rand)
+				}
+				logHTTPAttempt(stage, idemKey, attempt+1, attempts, resp.StatusCode, back.Milliseconds(), endpoint, "")
+				sleepCtx(ctx, back)
+				continue
+			}
+			logHTTPAttempt(stage, idemKey, attempt+1, attempts, resp.StatusCode, 0, endpoint, truncate(string(body), 2000))
+			return "", lastErr
+		}
+		logHTTPAttempt(stage, idemKey, attempt+1, attempts, resp.StatusCode, 0, endpoint, "")
+		// Some servers answer text/plain regardless of response_format
+		if !strings.Contains(resp.Header.Get("Content-Type"), "json") {
+			return strings.TrimSpace(string(body)), nil
+		}
+		var out struct {
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(body, &out); err != nil {
+			return "", fmt.Errorf("decode transcription: %w; body: %s", err, truncate(string(body), 1000))
+		}
+		return strings.TrimSpace(out.Text), nil
+	}
+	return "", lastErr
+}
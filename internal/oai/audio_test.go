@@ -0,0 +1,68 @@
+package oai
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAudioClient_TranscribeRetriesAndSendsForm(t *testing.T) {
+	t.Chdir(t.TempDir())
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path != "/v1/audio/transcriptions" || r.Header.Get("Authorization") != "Bearer k" {
+			t.Errorf("path=%s auth=%q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		if calls == 1 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatal(err)
+		}
+		f, hdr, err := r.FormFile("file")
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(f) //nolint:errcheck
+		if hdr.Filename != "memo.wav" || string(data) != "RIFF" || r.FormValue("model") != "whisper-1" || r.FormValue("language") != "fi" || r.FormValue("response_format") != "json" {
+			t.Errorf("form: file=%s data=%q model=%s lang=%s", hdr.Filename, data, r.FormValue("model"), r.FormValue("language"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"text":" hello there \n"}`) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	c := NewAudioClient(srv.URL+"/v1/", "k", 5*time.Second, RetryPolicy{MaxRetries: 1, Backoff: time.Millisecond})
+	text, err := c.Transcribe(context.Background(), TranscriptionRequest{Model: "whisper-1", Filename: "memo.wav", Audio: []byte("RIFF"), Language: "fi"})
+	if err != nil || text != "hello there" || calls != 2 {
+		t.Fatalf("text=%q err=%v calls=%d", text, err, calls)
+	}
+}
+
+func TestAudioClient_TranscribeErrors(t *testing.T) {
+	t.Chdir(t.TempDir())
+	status := http.StatusBadRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status != http.StatusOK {
+			http.Error(w, "unsupported format", status)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = io.WriteString(w, "plain transcript\n") //nolint:errcheck
+	}))
+	defer srv.Close()
+	c := NewAudioClient(srv.URL, "", 5*time.Second, RetryPolicy{MaxRetries: 3, Backoff: time.Millisecond})
+	if _, err := c.Transcribe(context.Background(), TranscriptionRequest{Model: "m", Filename: "a.ogg"}); err == nil || !strings.Contains(err.Error(), ": 400: unsupported format") {
+		t.Fatalf("err=%v", err)
+	}
+	status = http.StatusOK
+	if text, err := c.Transcribe(context.Background(), TranscriptionRequest{Model: "m", Filename: "a.ogg"}); err != nil || text != "plain transcript" {
+		t.Fatalf("text=%q err=%v", text, err)
+	}
+}
@@ -4,9 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"os"
-	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/hyperifyio/goagent/internal/audit"
+	"github.com/hyperifyio/goagent/internal/clock"
+	"github.com/hyperifyio/goagent/internal/metrics"
 )
 
 // audit context keys are unexported to avoid collisions. Use helper to set.
@@ -53,7 +57,7 @@ func logHTTPAttempt(stage, idemKey string, attempt, maxAttempts, status int, bac
 		Error          string `json:"error,omitempty"`
 	}
 	entry := audit{
-		TS:             time.Now().UTC().Format(time.RFC3339Nano),
+		TS:             clock.Now().UTC().Format(time.RFC3339Nano),
 		Event:          "http_attempt",
 		Stage:          stage,
 		IdempotencyKey: idemKey,
@@ -69,7 +73,8 @@ func logHTTPAttempt(stage, idemKey string, attempt, maxAttempts, status int, bac
 	}
 }
 
-// logHTTPTiming appends detailed HTTP timing metrics to the audit log.
+// logHTTPTiming appends detailed HTTP timing metrics to the audit log and
+// records the attempt latency in the metrics registry.
 func logHTTPTiming(stage, idemKey string, attempt int, endpoint string, status int, start time.Time, dnsDur, connDur, tlsDur time.Duration, wroteAt, firstByteAt, end time.Time, cause, hint string) {
 	type timing struct {
 		TS             string `json:"ts"`
@@ -103,8 +108,9 @@ func logHTTPTiming(stage, idemKey string, attempt int, endpoint string, status i
 			readMs = end.Sub(firstByteAt).Milliseconds()
 		}
 	}
+	metrics.HTTPRequestDuration.Observe(end.Sub(start).Seconds(), stage, strconv.Itoa(status))
 	entry := timing{
-		TS:             time.Now().UTC().Format(time.RFC3339Nano),
+		TS:             clock.Now().UTC().Format(time.RFC3339Nano),
 		Event:          "http_timing",
 		Stage:          stage,
 		IdempotencyKey: idemKey,
@@ -126,59 +132,22 @@ func logHTTPTiming(stage, idemKey string, attempt int, endpoint string, status i
 	}
 }
 
-// appendAuditLog writes an NDJSON audit line to .goagent/audit/YYYYMMDD.log (same location used by tool runner).
+// appendAuditLog writes an NDJSON audit line via the shared audit writer
+// (same location, rotation, and retention as the tool runner).
 func appendAuditLog(entry any) error {
 	b, err := json.Marshal(entry)
 	if err != nil {
 		return err
 	}
 	// Primary location under module root
-	root := moduleRoot()
-	if err := writeAuditLine(root, b); err != nil {
+	root := audit.ModuleRoot()
+	now := clock.Now()
+	if err := audit.AppendLine(root, now, b); err != nil {
 		return err
 	}
 	// Also mirror under current working directory to ease local tooling/tests
 	if cwd, _ := os.Getwd(); cwd != root {
-		_ = writeAuditLine(cwd, b)
+		_ = audit.AppendLine(cwd, now, b)
 	}
 	return nil
 }
-
-func writeAuditLine(base string, line []byte) error {
-	dir := filepath.Join(base, ".goagent", "audit")
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return err
-	}
-	fname := time.Now().UTC().Format("20060102") + ".log"
-	path := filepath.Join(dir, fname)
-	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
-	if err != nil {
-		return err
-	}
-	defer func() { _ = f.Close() }()
-	if _, err := f.Write(append(line, '\n')); err != nil {
-		return err
-	}
-	return nil
-}
-
-// moduleRoot walks upward from the current working directory to locate the directory
-// containing go.mod. If none is found, it returns the current working directory.
-func moduleRoot() string {
-	cwd, err := os.Getwd()
-	if err != nil || cwd == "" {
-		return "."
-	}
-	dir := cwd
-	for {
-		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
-			return dir
-		}
-		parent := filepath.Dir(dir)
-		if parent == dir {
-			// Reached filesystem root; fallback to original cwd
-			return cwd
-		}
-		dir = parent
-	}
-}
@@ -1,10 +1,13 @@
 package oai
 
 import (
+	"context"
 	mathrand "math/rand"
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/hyperifyio/goagent/internal/clock"
 )
 
 // RetryPolicy controls HTTP retry behavior for transient failures.
@@ -12,11 +15,19 @@ import (
 // Backoff specifies the base delay between attempts; exponential backoff is applied.
 // JitterFraction specifies the +/- fractional jitter applied to each computed backoff.
 // When Rand is non-nil, it is used to sample jitter for deterministic tests.
+// MaxRetryWait caps waits requested by the server via Retry-After or
+// x-ratelimit-reset-* headers (DefaultMaxRetryWait when zero).
+// BreakerThreshold opens the per-base-URL circuit breaker after that many
+// consecutive 429/5xx responses (0 disables it); while open, calls fail fast
+// with ErrCircuitOpen for BreakerCooldown.
 type RetryPolicy struct {
-	MaxRetries     int
-	Backoff        time.Duration
-	JitterFraction float64
-	Rand           *mathrand.Rand
+	MaxRetries       int
+	Backoff          time.Duration
+	JitterFraction   float64
+	Rand             *mathrand.Rand
+	MaxRetryWait     time.Duration
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
 }
 
 // backoffDuration returns the duration that sleepBackoff would sleep for a given attempt.
@@ -33,7 +44,7 @@ func backoffDuration(base time.Duration, attempt int) time.Duration {
 
 // backoffWithJitter returns an exponential backoff adjusted by +/- jitter fraction.
 // When jitterFraction <= 0, this falls back to backoffDuration. When r is nil,
-// a time-seeded RNG is used for production randomness.
+// the shared clock package source is used (seeded under -deterministic).
 func backoffWithJitter(base time.Duration, attempt int, jitterFraction float64, r *mathrand.Rand) time.Duration {
 	d := backoffDuration(base, attempt)
 	if jitterFraction <= 0 {
@@ -42,14 +53,15 @@ func backoffWithJitter(base time.Duration, attempt int, jitterFraction float64,
 	if jitterFraction > 0.9 { // prevent extreme factors
 		jitterFraction = 0.9
 	}
-	if r == nil {
-		// Seed with current time for production; tests can pass a custom Rand
-		r = mathrand.New(mathrand.NewSource(time.Now().UnixNano()))
+	// Tests can pass a custom Rand; otherwise use the shared (seedable) source
+	sample := clock.Float64
+	if r != nil {
+		sample = r.Float64
 	}
 	// factor in [1 - f, 1 + f]
 	minF := 1.0 - jitterFraction
 	maxF := 1.0 + jitterFraction
-	factor := minF + r.Float64()*(maxF-minF)
+	factor := minF + sample()*(maxF-minF)
 	// Guard against rounding to zero
 	jittered := time.Duration(float64(d) * factor)
 	if jittered < time.Millisecond {
@@ -90,3 +102,17 @@ func sleepFor(d time.Duration) {
 	}
 	time.Sleep(d)
 }
+
+// sleepCtx waits like sleepFunc but returns early when ctx is canceled, so an
+// interrupt is not held up by a long server-requested retry wait.
+func sleepCtx(ctx context.Context, d time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		sleepFunc(d)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
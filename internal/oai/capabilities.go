@@ -3,13 +3,17 @@ package oai
 import "strings"
 
 // SupportsTemperature reports whether the given model id accepts the
-// temperature parameter. Defaults to true for forward compatibility.
+// temperature parameter. Capabilities registered by RegisterModels win;
+// otherwise it defaults to true for forward compatibility.
 // Known exceptions are listed explicitly below with brief rationale.
 func SupportsTemperature(modelID string) bool {
 	id := strings.ToLower(strings.TrimSpace(modelID))
 	if id == "" {
 		return true
 	}
+	if m, ok := discoveredModel(id); ok && m.Temperature != nil {
+		return *m.Temperature
+	}
 	// Known exceptions: OpenAI "o*" reasoning models ignore or reject sampling knobs.
 	// We treat these as not supporting temperature to avoid 400s and no-op params.
 	if strings.HasPrefix(id, "o3") || strings.HasPrefix(id, "o4") {
@@ -18,3 +22,13 @@ func SupportsTemperature(modelID string) bool {
 	// Otherwise allow by default (e.g., GPT-5 variants, oss-gpt-*).
 	return true
 }
+
+// SupportsTools reports whether the given model id accepts tool
+// definitions. Only a registered listing (see RegisterModels) can say no.
+func SupportsTools(modelID string) bool {
+	id := strings.ToLower(strings.TrimSpace(modelID))
+	if m, ok := discoveredModel(id); ok && m.Tools != nil {
+		return *m.Tools
+	}
+	return true
+}
@@ -25,3 +25,30 @@ func TestSupportsTemperature(t *testing.T) {
 		})
 	}
 }
+
+func TestModelTierFor(t *testing.T) {
+	tests := []struct {
+		model string
+		want  ModelTier
+	}{
+		{"llama-3.1-8b-instruct", TierSmall},
+		{"qwen2.5:7b", TierSmall},
+		{"phi-1.5b", TierSmall},
+		{"oss-gpt-20b", TierMedium},
+		{"llama-3.3-70b", TierLarge},
+		{"tinyllama", TierSmall},
+		{"gpt-5", TierLarge},
+		{"", TierLarge},
+	}
+	for _, tc := range tests {
+		if got := ModelTierFor(tc.model); got != tc.want {
+			t.Fatalf("ModelTierFor(%q)=%v want %v", tc.model, got, tc.want)
+		}
+	}
+	if tier, ok := ParseModelTier(" Medium "); !ok || tier != TierMedium {
+		t.Fatalf("ParseModelTier: got %v %v", tier, ok)
+	}
+	if _, ok := ParseModelTier("huge"); ok {
+		t.Fatalf("ParseModelTier should reject unknown tiers")
+	}
+}
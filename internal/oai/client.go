@@ -11,6 +11,10 @@ import (
 	"net/http/httptrace"
 	"strings"
 	"time"
+
+	"github.com/hyperifyio/goagent/internal/clock"
+	"github.com/hyperifyio/goagent/internal/metrics"
+	"github.com/hyperifyio/goagent/internal/tracing"
 )
 
 type Client struct {
@@ -18,6 +22,25 @@ type Client struct {
 	apiKey     string
 	httpClient *http.Client
 	retry      RetryPolicy
+	counters   *httpCounters
+	// Embedder hooks around each HTTP attempt (see OnRequest and OnResponse)
+	requestHooks  []RequestHook
+	responseHooks []ResponseHook
+	// Optional -providers routing (see WithProviders)
+	failover *failover
+	// Response size caps and request compression (see WithLimits)
+	limits Limits
+}
+
+// StatusError is a chat API reply with a non-2xx status.
+type StatusError struct {
+	Endpoint string
+	Status   int
+	Body     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("chat API %s: %d: %s", e.Endpoint, e.Status, e.Body)
 }
 
 // NewClient creates a client without retries (single attempt only).
@@ -27,9 +50,11 @@ func NewClient(baseURL, apiKey string, timeout time.Duration) *Client {
 		baseURL: trimmed,
 		apiKey:  apiKey,
 		httpClient: &http.Client{
-			Timeout: timeout,
+			Timeout:   timeout,
+			Transport: SharedTransport(),
 		},
-		retry: RetryPolicy{MaxRetries: 0, Backoff: 0},
+		retry:    RetryPolicy{MaxRetries: 0, Backoff: 0},
+		counters: &httpCounters{},
 	}
 }
 
@@ -43,14 +68,48 @@ func NewClientWithRetry(baseURL, apiKey string, timeout time.Duration, retry Ret
 		baseURL: trimmed,
 		apiKey:  apiKey,
 		httpClient: &http.Client{
-			Timeout: timeout,
+			Timeout:   timeout,
+			Transport: SharedTransport(),
 		},
-		retry: retry,
+		retry:    retry,
+		counters: &httpCounters{},
 	}
 }
 
-// nolint:gocyclo // Orchestrates retries and timing; complexity acceptable and tested.
+// WithTransport sends the client's requests through rt instead of the shared
+// transport, e.g. a provider adapter such as bedrock.Transport. Configure it
+// before the client is shared.
+func (c *Client) WithTransport(rt http.RoundTripper) *Client {
+	hc := *c.httpClient
+	hc.Transport = rt
+	c.httpClient = &hc
+	return c
+}
+
+// CreateChatCompletion sends a non-streaming chat request, retrying transient
+// failures per the client's policy. With tracing enabled the call is recorded
+// as a chat.request span carrying the model, status, attempts, and token usage.
 func (c *Client) CreateChatCompletion(ctx context.Context, req ChatCompletionsRequest) (ChatCompletionsResponse, error) {
+	if c.failover != nil {
+		var resp ChatCompletionsResponse
+		err := c.failover.do(ctx, c, req, func(rc *Client, req ChatCompletionsRequest) (bool, error) {
+			var err error
+			resp, err = rc.CreateChatCompletion(ctx, req)
+			return false, err
+		})
+		return resp, err
+	}
+	ctx, span := startChatSpan(ctx, req)
+	resp, err := c.createChatCompletion(ctx, req)
+	if resp.Usage != nil {
+		span.SetAttributes(usageAttrs(resp.Usage)...)
+	}
+	span.End(err)
+	return resp, err
+}
+
+// nolint:gocyclo // Orchestrates retries and timing; complexity acceptable and tested.
+func (c *Client) createChatCompletion(ctx context.Context, req ChatCompletionsRequest) (ChatCompletionsResponse, error) {
 	// Encoder guard: omit temperature entirely for models that do not support it.
 	// This complements higher-level callers which may or may not set the field.
 	if !SupportsTemperature(req.Model) {
@@ -77,58 +136,74 @@ func (c *Client) CreateChatCompletion(ctx context.Context, req ChatCompletionsRe
 	idemKey := generateIdempotencyKey()
 	// Capture any stage label from context for audit enrichment
 	stage := auditStageFromContext(ctx)
+	breaker := breakerFor(c.baseURL)
 	for attempt := 0; attempt < attempts; attempt++ {
+		// Fail fast while the base URL's breaker is open
+		if c.retry.BreakerThreshold > 0 {
+			if remaining, ok := breaker.allow(time.Now()); !ok {
+				c.counters.breakerRejections.Add(1)
+				logHTTPAttempt(stage, idemKey, attempt+1, attempts, 0, 0, endpoint, ErrCircuitOpen.Error())
+				return zero, fmt.Errorf("chat POST %s: %w after %d consecutive 429/5xx responses; retry in %s", c.baseURL, ErrCircuitOpen, c.retry.BreakerThreshold, remaining.Round(time.Second))
+			}
+		}
+		c.counters.requests.Add(1)
+		if attempt > 0 {
+			c.counters.retries.Add(1)
+			metrics.HTTPRetries.Add(1, stage)
+		}
 		// Per-attempt timing capture using httptrace
-		attemptStart := time.Now()
+		attemptStart := clock.Now()
 		var (
 			dnsStart, connStart  time.Time
 			dnsDur, connDur      time.Duration
 			wroteAt, firstByteAt time.Time
 		)
 		trace := &httptrace.ClientTrace{
-			DNSStart: func(info httptrace.DNSStartInfo) { dnsStart = time.Now() },
+			DNSStart: func(info httptrace.DNSStartInfo) { dnsStart = clock.Now() },
 			DNSDone: func(info httptrace.DNSDoneInfo) {
 				if !dnsStart.IsZero() {
-					dnsDur += time.Since(dnsStart)
+					dnsDur += clock.Since(dnsStart)
 				}
 			},
-			ConnectStart: func(network, addr string) { connStart = time.Now() },
+			ConnectStart: func(network, addr string) { connStart = clock.Now() },
 			ConnectDone: func(network, addr string, err error) {
 				if !connStart.IsZero() {
-					connDur += time.Since(connStart)
+					connDur += clock.Since(connStart)
 				}
 			},
 			GotConn:              func(info httptrace.GotConnInfo) {},
-			WroteRequest:         func(info httptrace.WroteRequestInfo) { wroteAt = time.Now() },
-			GotFirstResponseByte: func() { firstByteAt = time.Now() },
+			WroteRequest:         func(info httptrace.WroteRequestInfo) { wroteAt = clock.Now() },
+			GotFirstResponseByte: func() { firstByteAt = clock.Now() },
 		}
 		// Fallback for TLS duration using httptrace hooks available: emulate by measuring from TLSHandshakeStart/Done via GotConn workaround.
 		// Since httptrace.TLSHandshakeDone requires crypto/tls type, replicate using any to avoid import on older Go.
 		// Note: we will compute tlsDur as zero unless supported; acceptable for audit purposes.
 
-		httpReq, nerr := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+		httpReq, nerr := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(c.requestBody(body)))
 		if nerr != nil {
 			return zero, fmt.Errorf("new request: %w", nerr)
 		}
-		httpReq.Header.Set("Content-Type", "application/json")
+		c.setBodyHeaders(httpReq)
 		if c.apiKey != "" {
 			httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
 		}
 		httpReq.Header.Set("Idempotency-Key", idemKey)
+		setTraceHeaders(ctx, httpReq, attempt+1)
 		httpReq = httpReq.WithContext(httptrace.WithClientTrace(httpReq.Context(), trace))
 
-		resp, derr := c.httpClient.Do(httpReq)
+		resp, derr := c.do(httpReq)
 		if derr != nil {
 			lastErr = derr
 			// Log attempt with error
 			logHTTPAttempt(stage, idemKey, attempt+1, attempts, 0, 0, endpoint, derr.Error())
 			// Emit timing audit for error case
-			logHTTPTiming(stage, idemKey, attempt+1, endpoint, 0, attemptStart, dnsDur, connDur, 0, wroteAt, firstByteAt, time.Now(), classifyHTTPCause(ctx, derr), userHintForCause(ctx, derr))
-			if attempt < attempts-1 && isRetryableError(derr) {
+			logHTTPTiming(stage, idemKey, attempt+1, endpoint, 0, attemptStart, dnsDur, connDur, 0, wroteAt, firstByteAt, clock.Now(), classifyHTTPCause(ctx, derr), userHintForCause(ctx, derr))
+			// A canceled caller (e.g. Ctrl-C) ends the retry loop immediately
+			if attempt < attempts-1 && ctx.Err() == nil && !isLimitError(derr) && isRetryableError(derr) {
 				// compute backoff (with jitter) for audit then sleep
 				back := backoffWithJitter(c.retry.Backoff, attempt, c.retry.JitterFraction, c.retry.Rand)
 				logHTTPAttempt(stage, idemKey, attempt+1, attempts, 0, back.Milliseconds(), endpoint, derr.Error())
-				sleepFunc(back)
+				sleepCtx(ctx, back)
 				continue
 			}
 			// Upgrade error with base URL, configured timeout, and actionable hint
@@ -136,9 +211,9 @@ func (c *Client) CreateChatCompletion(ctx context.Context, req ChatCompletionsRe
 			// c.httpClient.Timeout reflects configured HTTP timeout
 			tmo := c.httpClient.Timeout
 			if hint != "" {
-				return zero, fmt.Errorf("chat POST failed: %v (base=%s, http-timeout=%s). Hint: %s", derr, c.baseURL, tmo, hint)
+				return zero, fmt.Errorf("chat POST failed: %w (base=%s, http-timeout=%s). Hint: %s", derr, c.baseURL, tmo, hint)
 			}
-			return zero, fmt.Errorf("chat POST failed: %v (base=%s, http-timeout=%s)", derr, c.baseURL, tmo)
+			return zero, fmt.Errorf("chat POST failed: %w (base=%s, http-timeout=%s)", derr, c.baseURL, tmo)
 		}
 
 		// When streaming is requested, the server should respond with SSE. We do not
@@ -148,7 +223,7 @@ func (c *Client) CreateChatCompletion(ctx context.Context, req ChatCompletionsRe
 			_ = resp.Body.Close() //nolint:errcheck // best-effort close
 			return zero, fmt.Errorf("stream=true not supported in CreateChatCompletion; use StreamChat")
 		}
-		respBody, readErr := io.ReadAll(resp.Body)
+		respBody, readErr := c.readResponse(resp)
 		if cerr := resp.Body.Close(); cerr != nil {
 			// best-effort: record close error as lastErr if none
 			if lastErr == nil {
@@ -160,15 +235,22 @@ func (c *Client) CreateChatCompletion(ctx context.Context, req ChatCompletionsRe
 			// Log attempt with read error
 			logHTTPAttempt(stage, idemKey, attempt+1, attempts, resp.StatusCode, 0, endpoint, readErr.Error())
 			// Emit timing audit including read duration up to error
-			logHTTPTiming(stage, idemKey, attempt+1, endpoint, resp.StatusCode, attemptStart, dnsDur, connDur, 0, wroteAt, firstByteAt, time.Now(), classifyHTTPCause(ctx, readErr), userHintForCause(ctx, readErr))
-			if attempt < attempts-1 && isRetryableError(readErr) {
+			logHTTPTiming(stage, idemKey, attempt+1, endpoint, resp.StatusCode, attemptStart, dnsDur, connDur, 0, wroteAt, firstByteAt, clock.Now(), classifyHTTPCause(ctx, readErr), userHintForCause(ctx, readErr))
+			if attempt < attempts-1 && !isLimitError(readErr) && isRetryableError(readErr) {
 				back := backoffWithJitter(c.retry.Backoff, attempt, c.retry.JitterFraction, c.retry.Rand)
 				logHTTPAttempt(stage, idemKey, attempt+1, attempts, resp.StatusCode, back.Milliseconds(), endpoint, readErr.Error())
-				sleepFunc(back)
+				sleepCtx(ctx, back)
 				continue
 			}
 			return zero, fmt.Errorf("read response body: %w", readErr)
 		}
+		breaker.record(resp.StatusCode, c.retry.BreakerThreshold, c.retry.BreakerCooldown, time.Now())
+		tracing.FromContext(ctx).SetAttributes(tracing.Int("http.response.status_code", resp.StatusCode))
+		if resp.StatusCode == http.StatusTooManyRequests {
+			c.counters.rateLimited.Add(1)
+		} else if resp.StatusCode >= 500 {
+			c.counters.serverErrors.Add(1)
+		}
 		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 			// Parameter-recovery: if 400 mentions invalid/unsupported temperature and
 			// the request included temperature, remove it and retry once immediately.
@@ -187,40 +269,53 @@ func (c *Client) CreateChatCompletion(ctx context.Context, req ChatCompletionsRe
 						recoveryGranted = true
 						attempts++
 						// Emit timing audit for the failed attempt before retrying
-						logHTTPTiming(stage, idemKey, attempt+1, endpoint, resp.StatusCode, attemptStart, dnsDur, connDur, 0, wroteAt, firstByteAt, time.Now(), "http_status", "param_recovery_temperature")
+						logHTTPTiming(stage, idemKey, attempt+1, endpoint, resp.StatusCode, attemptStart, dnsDur, connDur, 0, wroteAt, firstByteAt, clock.Now(), "http_status", "param_recovery_temperature")
 						// Perform immediate recovery retry without consuming a normal retry slot
 						continue
 					}
 					// If marshal fails, fall through to normal error handling
+				} else if !recoveryGranted && req.Seed != nil && mentionsUnsupportedSeed(bodyStr) {
+					// Servers without seeded sampling get the same one-time recovery
+					logHTTPAttempt(stage, idemKey, attempt+1, attempts, resp.StatusCode, 0, endpoint, "param_recovery: seed")
+					req.Seed = nil
+					nb, merr := json.Marshal(req)
+					if merr == nil {
+						body = nb
+						recoveryGranted = true
+						attempts++
+						logHTTPTiming(stage, idemKey, attempt+1, endpoint, resp.StatusCode, attemptStart, dnsDur, connDur, 0, wroteAt, firstByteAt, clock.Now(), "http_status", "param_recovery_seed")
+						continue
+					}
 				}
 			}
 			// Retry on 429 and 5xx; otherwise return immediately
 			if attempt < attempts-1 && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500) {
-				// Respect Retry-After when present; otherwise use exponential backoff
-				if ra, ok := retryAfterDuration(resp.Header.Get("Retry-After"), time.Now()); ok {
-					// Log with Retry-After derived backoff
+				// Respect Retry-After/x-ratelimit-* when present; otherwise use exponential backoff
+				if ra, ok := serverRetryWait(resp.Header, time.Now(), c.retry.MaxRetryWait); ok {
+					// Log with server-requested backoff
 					logHTTPAttempt(stage, idemKey, attempt+1, attempts, resp.StatusCode, ra.Milliseconds(), endpoint, "")
-					sleepFunc(ra)
+					sleepCtx(ctx, ra)
 				} else {
 					back := backoffWithJitter(c.retry.Backoff, attempt, c.retry.JitterFraction, c.retry.Rand)
 					logHTTPAttempt(stage, idemKey, attempt+1, attempts, resp.StatusCode, back.Milliseconds(), endpoint, "")
-					sleepFunc(back)
+					sleepCtx(ctx, back)
 				}
 				// Emit timing audit for non-2xx attempt
-				logHTTPTiming(stage, idemKey, attempt+1, endpoint, resp.StatusCode, attemptStart, dnsDur, connDur, 0, wroteAt, firstByteAt, time.Now(), "http_status", "")
+				logHTTPTiming(stage, idemKey, attempt+1, endpoint, resp.StatusCode, attemptStart, dnsDur, connDur, 0, wroteAt, firstByteAt, clock.Now(), "http_status", "")
 				continue
 			}
 			// Final non-retryable failure: log attempt (no backoff) and return
 			logHTTPAttempt(stage, idemKey, attempt+1, attempts, resp.StatusCode, 0, endpoint, truncate(string(respBody), 2000))
-			logHTTPTiming(stage, idemKey, attempt+1, endpoint, resp.StatusCode, attemptStart, dnsDur, connDur, 0, wroteAt, firstByteAt, time.Now(), "http_status", "")
-			return zero, fmt.Errorf("chat API %s: %d: %s", endpoint, resp.StatusCode, truncate(string(respBody), 2000))
+			logHTTPTiming(stage, idemKey, attempt+1, endpoint, resp.StatusCode, attemptStart, dnsDur, connDur, 0, wroteAt, firstByteAt, clock.Now(), "http_status", "")
+			return zero, &StatusError{Endpoint: endpoint, Status: resp.StatusCode, Body: truncate(string(respBody), 2000)}
 		}
 		if err := json.Unmarshal(respBody, &zero); err != nil {
 			return ChatCompletionsResponse{}, fmt.Errorf("decode response: %w; body: %s", err, truncate(string(respBody), 1000))
 		}
+		normalizeResponse(&zero)
 		// Success: log attempt with status and no backoff
 		logHTTPAttempt(stage, idemKey, attempt+1, attempts, resp.StatusCode, 0, endpoint, "")
-		logHTTPTiming(stage, idemKey, attempt+1, endpoint, resp.StatusCode, attemptStart, dnsDur, connDur, 0, wroteAt, firstByteAt, time.Now(), "success", "")
+		logHTTPTiming(stage, idemKey, attempt+1, endpoint, resp.StatusCode, attemptStart, dnsDur, connDur, 0, wroteAt, firstByteAt, clock.Now(), "success", "")
 		return zero, nil
 	}
 	if lastErr != nil {
@@ -234,6 +329,35 @@ func (c *Client) CreateChatCompletion(ctx context.Context, req ChatCompletionsRe
 // fast and non-blocking. The function returns when the stream completes or an
 // error occurs. Retries are not applied in streaming mode.
 func (c *Client) StreamChat(ctx context.Context, req ChatCompletionsRequest, onChunk func(StreamChunk) error) error {
+	if c.failover != nil {
+		return c.failover.do(ctx, c, req, func(rc *Client, req ChatCompletionsRequest) (bool, error) {
+			delivered := false
+			err := rc.StreamChat(ctx, req, func(chunk StreamChunk) error {
+				delivered = true
+				if onChunk != nil {
+					return onChunk(chunk)
+				}
+				return nil
+			})
+			return delivered, err
+		})
+	}
+	ctx, span := startChatSpan(ctx, req)
+	span.SetAttributes(tracing.Bool("gen_ai.request.stream", true))
+	err := c.streamChat(ctx, req, func(chunk StreamChunk) error {
+		if chunk.Usage != nil {
+			span.SetAttributes(usageAttrs(chunk.Usage)...)
+		}
+		if onChunk != nil {
+			return onChunk(chunk)
+		}
+		return nil
+	})
+	span.End(err)
+	return err
+}
+
+func (c *Client) streamChat(ctx context.Context, req ChatCompletionsRequest, onChunk func(StreamChunk) error) error {
 	// Encoder guard: omit temperature when unsupported
 	if !SupportsTemperature(req.Model) {
 		req.Temperature = nil
@@ -244,28 +368,30 @@ func (c *Client) StreamChat(ctx context.Context, req ChatCompletionsRequest, onC
 		return fmt.Errorf("marshal request: %w", err)
 	}
 	endpoint := c.baseURL + "/chat/completions"
-	httpReq, nerr := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	httpReq, nerr := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(c.requestBody(body)))
 	if nerr != nil {
 		return fmt.Errorf("new request: %w", nerr)
 	}
-	httpReq.Header.Set("Content-Type", "application/json")
+	c.setBodyHeaders(httpReq)
 	if c.apiKey != "" {
 		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
 	}
 	// Idempotency not relevant for streaming; still set for consistency
 	httpReq.Header.Set("Idempotency-Key", generateIdempotencyKey())
+	setTraceHeaders(ctx, httpReq, 1)
 
-	resp, derr := c.httpClient.Do(httpReq)
+	resp, derr := c.do(httpReq)
 	if derr != nil {
 		return derr
 	}
+	tracing.FromContext(ctx).SetAttributes(tracing.Int("http.response.status_code", resp.StatusCode))
 	defer func() { _ = resp.Body.Close() }() //nolint:errcheck // best-effort close
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		b, rerr := io.ReadAll(resp.Body)
+		b, rerr := c.readResponse(resp)
 		if rerr != nil {
-			return fmt.Errorf("chat API %s: %d: <read error>", endpoint, resp.StatusCode)
+			return &StatusError{Endpoint: endpoint, Status: resp.StatusCode, Body: "<read error>"}
 		}
-		return fmt.Errorf("chat API %s: %d: %s", endpoint, resp.StatusCode, truncate(string(b), 2000))
+		return &StatusError{Endpoint: endpoint, Status: resp.StatusCode, Body: truncate(string(b), 2000)}
 	}
 	// Require SSE content type for streaming
 	ct := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Type")))
@@ -274,14 +400,23 @@ func (c *Client) StreamChat(ctx context.Context, req ChatCompletionsRequest, onC
 		_, _ = io.ReadAll(resp.Body) //nolint:errcheck // ignore read error; fallback remains informative
 		return fmt.Errorf("server does not support streaming (content-type=%q)", ct)
 	}
-	// Simple SSE parser: read lines; handle "data: ..." and [DONE]
-	dec := newLineReader(resp.Body)
+	// Simple SSE parser: read lines; handle "data: ..." and [DONE]. The
+	// stream as a whole is capped like a response body, and each line too.
+	stream, rerr := c.responseReader(resp)
+	if rerr != nil {
+		return rerr
+	}
+	dec := newLineReader(stream, c.limits.maxSSELine())
+	var bos bosStripper
 	for {
 		line, err := dec()
 		if err != nil {
 			if errors.Is(err, io.EOF) {
 				return nil
 			}
+			if isLimitError(err) {
+				return err
+			}
 			return fmt.Errorf("stream read: %w", err)
 		}
 		s := strings.TrimSpace(line)
@@ -298,6 +433,7 @@ func (c *Client) StreamChat(ctx context.Context, req ChatCompletionsRequest, onC
 				// Skip malformed chunk
 				continue
 			}
+			bos.apply(&chunk)
 			if onChunk != nil {
 				if err := onChunk(chunk); err != nil {
 					return err
@@ -13,6 +13,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/hyperifyio/goagent/internal/clock"
 )
 
 // https://github.com/hyperifyio/goagent/issues/1
@@ -227,6 +229,41 @@ func TestCreateChatCompletion_ParameterRecovery_InvalidTemperature(t *testing.T)
 	}
 }
 
+func TestCreateChatCompletion_ParameterRecovery_UnsupportedSeed(t *testing.T) {
+	var seeds []*int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatCompletionsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		seeds = append(seeds, req.Seed)
+		if len(seeds) == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			if _, err := w.Write([]byte(`{"error":{"message":"Unrecognized request argument supplied: seed"}}`)); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			return
+		}
+		if _, err := w.Write([]byte(`{"system_fingerprint":"fp_1","choices":[{"message":{"role":"assistant","content":"ok"}}]}`)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClientWithRetry(srv.URL, "", 2*time.Second, RetryPolicy{MaxRetries: 0})
+	seed := int64(42)
+	out, err := c.CreateChatCompletion(context.Background(), ChatCompletionsRequest{Model: "m", Messages: []Message{{Role: RoleUser, Content: "x"}}, Seed: &seed})
+	if err != nil {
+		t.Fatalf("call: %v", err)
+	}
+	if len(seeds) != 2 || seeds[0] == nil || *seeds[0] != 42 || seeds[1] != nil {
+		t.Fatalf("seeds per attempt: %v", seeds)
+	}
+	if out.SystemFingerprint != "fp_1" {
+		t.Fatalf("system_fingerprint=%q", out.SystemFingerprint)
+	}
+}
+
 // https://github.com/hyperifyio/goagent/issues/216
 func TestCreateChatCompletion_RetryTimeoutThenSuccess(t *testing.T) {
 	attempts := 0
@@ -386,6 +423,21 @@ func TestBackoffWithJitter_GrowthAndBounds(t *testing.T) {
 	}
 }
 
+func TestBackoffWithJitter_DeterministicSharedSource(t *testing.T) {
+	defer clock.Reset()
+	base := 100 * time.Millisecond
+	clock.SetDeterministic(42)
+	a := []time.Duration{backoffWithJitter(base, 0, 0.5, nil), backoffWithJitter(base, 1, 0.5, nil)}
+	clock.SetDeterministic(42)
+	b := []time.Duration{backoffWithJitter(base, 0, 0.5, nil), backoffWithJitter(base, 1, 0.5, nil)}
+	if a[0] != b[0] || a[1] != b[1] {
+		t.Fatalf("same seed must reproduce jitter: %v vs %v", a, b)
+	}
+	if k1, k2 := generateIdempotencyKey(), generateIdempotencyKey(); k1 == k2 {
+		t.Fatalf("keys must still differ within a run: %s", k1)
+	}
+}
+
 // Verify jittered backoff is used for 429 without Retry-After.
 func TestCreateChatCompletion_Retry429_UsesJitteredBackoff(t *testing.T) {
 	attempts := 0
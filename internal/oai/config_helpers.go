@@ -25,19 +25,35 @@ type ImageConfig struct {
 //     For the API key, also allow OPENAI_API_KEY as a fallback environment variable.
 //   - Else inherit from baseURL/apiKey (source: "inherit"). When the inherited key is empty, the source is "empty".
 func ResolveImageConfig(imageBaseURL, imageAPIKey, baseURL, apiKey string) (ImageConfig, string, string) {
-	var cfg ImageConfig
-	var baseSrc, keySrc string
+	base, key, baseSrc, keySrc := resolveSideEndpoint(imageBaseURL, imageAPIKey, "OAI_IMAGE_BASE_URL", "OAI_IMAGE_API_KEY", baseURL, apiKey)
+	return ImageConfig{BaseURL: base, APIKey: key}, baseSrc, keySrc
+}
+
+// AudioConfig bundles resolved audio transcription API connection settings.
+type AudioConfig struct {
+	BaseURL string
+	APIKey  string
+}
+
+// ResolveAudioConfig resolves the audio API BaseURL and API Key with the same
+// precedence as ResolveImageConfig, reading OAI_AUDIO_BASE_URL and
+// OAI_AUDIO_API_KEY (then OPENAI_API_KEY) from the environment.
+func ResolveAudioConfig(audioBaseURL, audioAPIKey, baseURL, apiKey string) (AudioConfig, string, string) {
+	base, key, baseSrc, keySrc := resolveSideEndpoint(audioBaseURL, audioAPIKey, "OAI_AUDIO_BASE_URL", "OAI_AUDIO_API_KEY", baseURL, apiKey)
+	return AudioConfig{BaseURL: base, APIKey: key}, baseSrc, keySrc
+}
 
+// resolveSideEndpoint applies flag > env > OPENAI_API_KEY (key only) >
+// inherit precedence for an auxiliary API such as images or audio.
+func resolveSideEndpoint(flagBase, flagKey, baseEnv, keyEnv, baseURL, apiKey string) (base, key, baseSrc, keySrc string) {
 	// Base URL
-	if strings.TrimSpace(imageBaseURL) != "" {
-		cfg.BaseURL = strings.TrimSpace(imageBaseURL)
-		baseSrc = "flag"
-	} else if v := strings.TrimSpace(os.Getenv("OAI_IMAGE_BASE_URL")); v != "" {
-		cfg.BaseURL = v
-		baseSrc = "env"
+	if strings.TrimSpace(flagBase) != "" {
+		base, baseSrc = strings.TrimSpace(flagBase), "flag"
+	} else if v := strings.TrimSpace(os.Getenv(baseEnv)); v != "" {
+		base, baseSrc = v, "env"
 	} else {
-		cfg.BaseURL = strings.TrimSpace(baseURL)
-		if cfg.BaseURL != "" {
+		base = strings.TrimSpace(baseURL)
+		if base != "" {
 			baseSrc = "inherit"
 		} else {
 			baseSrc = "empty"
@@ -45,26 +61,22 @@ func ResolveImageConfig(imageBaseURL, imageAPIKey, baseURL, apiKey string) (Imag
 	}
 
 	// API key
-	if strings.TrimSpace(imageAPIKey) != "" {
-		cfg.APIKey = strings.TrimSpace(imageAPIKey)
-		keySrc = "flag"
-	} else if v := strings.TrimSpace(os.Getenv("OAI_IMAGE_API_KEY")); v != "" {
-		cfg.APIKey = v
-		keySrc = "env"
+	if strings.TrimSpace(flagKey) != "" {
+		key, keySrc = strings.TrimSpace(flagKey), "flag"
+	} else if v := strings.TrimSpace(os.Getenv(keyEnv)); v != "" {
+		key, keySrc = v, "env"
 	} else if v := strings.TrimSpace(os.Getenv("OPENAI_API_KEY")); v != "" {
-		// Compatibility: allow OPENAI_API_KEY for images too
-		cfg.APIKey = v
-		keySrc = "env:OPENAI_API_KEY"
+		// Compatibility: allow OPENAI_API_KEY for side APIs too
+		key, keySrc = v, "env:OPENAI_API_KEY"
 	} else {
-		cfg.APIKey = strings.TrimSpace(apiKey)
-		if cfg.APIKey != "" {
+		key = strings.TrimSpace(apiKey)
+		if key != "" {
 			keySrc = "inherit"
 		} else {
 			keySrc = "empty"
 		}
 	}
-
-	return cfg, baseSrc, keySrc
+	return base, key, baseSrc, keySrc
 }
 
 // MaskAPIKeyLast4 returns a redacted representation of a secret showing only the last 4 characters.
@@ -0,0 +1,97 @@
+package oai
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// ContentPart is one element of array-form message content, as accepted by
+// multimodal models: {"type":"text","text":...} or
+// {"type":"image_url","image_url":{"url":...}}.
+type ContentPart struct {
+	Type     string        `json:"type"`
+	Text     string        `json:"text,omitempty"`
+	ImageURL *ImageURLPart `json:"image_url,omitempty"`
+	// CacheControl is set on the part that ends a cacheable prefix
+	CacheControl *CacheControl `json:"cache_control,omitempty"`
+}
+
+// ImageURLPart points at an image: an http(s) URL or a base64 data URL.
+type ImageURLPart struct {
+	URL string `json:"url"`
+	// Detail is the optional fidelity hint: "low", "high", or "auto"
+	Detail string `json:"detail,omitempty"`
+}
+
+// ImagePart returns an image_url content part for url.
+func ImagePart(url string) ContentPart {
+	return ContentPart{Type: "image_url", ImageURL: &ImageURLPart{URL: url}}
+}
+
+// messageFields is Message without its JSON methods.
+type messageFields Message
+
+// MarshalJSON sends Content as a plain string unless the message carries
+// Parts or CacheControl; then content becomes an array with Content as its
+// leading text part, and CacheControl goes on the last text part.
+func (m Message) MarshalJSON() ([]byte, error) {
+	if len(m.Parts) == 0 && (m.CacheControl == nil || m.Content == "") {
+		return json.Marshal(messageFields(m))
+	}
+	parts := m.Parts
+	if m.Content != "" {
+		parts = append([]ContentPart{{Type: "text", Text: m.Content}}, parts...)
+	}
+	if m.CacheControl != nil {
+		parts = append([]ContentPart(nil), parts...)
+		for i := len(parts) - 1; i >= 0; i-- {
+			if parts[i].Type == "text" {
+				parts[i].CacheControl = m.CacheControl
+				break
+			}
+		}
+	}
+	return json.Marshal(struct {
+		messageFields
+		Content []ContentPart `json:"content"`
+	}{messageFields(m), parts})
+}
+
+// UnmarshalJSON accepts content as a string or as an array of parts. Text
+// parts are joined into Content and the others are kept in Parts, so code
+// that only reads Content keeps working.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		messageFields
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*m = Message(raw.messageFields)
+	content := bytes.TrimSpace(raw.Content)
+	if len(content) == 0 || content[0] != '[' {
+		if len(content) == 0 || string(content) == "null" {
+			return nil
+		}
+		return json.Unmarshal(content, &m.Content)
+	}
+	var parts []ContentPart
+	if err := json.Unmarshal(content, &parts); err != nil {
+		return err
+	}
+	var texts []string
+	for _, p := range parts {
+		if p.Type == "text" {
+			texts = append(texts, p.Text)
+			if p.CacheControl != nil {
+				m.CacheControl = p.CacheControl
+			}
+			continue
+		}
+		m.Parts = append(m.Parts, p)
+	}
+	m.Content = strings.Join(texts, "\n")
+	return nil
+}
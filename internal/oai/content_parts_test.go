@@ -0,0 +1,40 @@
+package oai
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMessage_JSONContentParts(t *testing.T) {
+	plain, err := json.Marshal(Message{Role: RoleUser, Content: "hi"})
+	if err != nil || string(plain) != `{"role":"user","content":"hi"}` {
+		t.Fatalf("plain: %s %v", plain, err)
+	}
+
+	m := Message{Role: RoleUser, Content: "what is this?", Parts: []ContentPart{ImagePart("data:image/png;base64,AAAA")}}
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"role":"user","content":[{"type":"text","text":"what is this?"},{"type":"image_url","image_url":{"url":"data:image/png;base64,AAAA"}}]}`
+	if string(b) != want {
+		t.Fatalf("got  %s\nwant %s", b, want)
+	}
+	var back Message
+	if err := json.Unmarshal(b, &back); err != nil || !reflect.DeepEqual(back, m) {
+		t.Fatalf("round trip: %+v %v", back, err)
+	}
+
+	var multi Message
+	if err := json.Unmarshal([]byte(`{"role":"user","content":[{"type":"text","text":"a"},{"type":"text","text":"b"}]}`), &multi); err != nil || multi.Content != "a\nb" || len(multi.Parts) != 0 {
+		t.Fatalf("text parts: %+v %v", multi, err)
+	}
+	var null Message
+	if err := json.Unmarshal([]byte(`{"role":"assistant","content":null,"tool_calls":[{"id":"1","type":"function","function":{"name":"f","arguments":"{}"}}]}`), &null); err != nil || null.Content != "" || len(null.ToolCalls) != 1 {
+		t.Fatalf("null content: %+v %v", null, err)
+	}
+	if err := json.Unmarshal([]byte(`{"role":"user","content":42}`), &null); err == nil {
+		t.Fatal("expected error for numeric content")
+	}
+}
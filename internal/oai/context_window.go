@@ -11,13 +11,17 @@ var modelToContextWindow = map[string]int{
 	"oss-gpt-20b": 131072,
 }
 
-// ContextWindowForModel returns the total token window for a given model.
-// When the model is unknown or empty, it returns DefaultContextWindow.
+// ContextWindowForModel returns the total token window for a given model,
+// preferring a window registered by RegisterModels. When the model is
+// unknown or empty, it returns DefaultContextWindow.
 func ContextWindowForModel(model string) int {
 	m := strings.TrimSpace(strings.ToLower(model))
 	if m == "" {
 		return DefaultContextWindow
 	}
+	if d, ok := discoveredModel(m); ok && d.ContextWindow > 0 {
+		return d.ContextWindow
+	}
 	if w, ok := modelToContextWindow[m]; ok {
 		return w
 	}
@@ -25,14 +29,15 @@ func ContextWindowForModel(model string) int {
 }
 
 // ClampCompletionCap bounds a desired completion cap to the remaining context
-// window after accounting for the estimated tokens of the prompt messages. It
-// ensures a minimum of 1 token and subtracts a small safety margin.
+// window after accounting for the prompt tokens of messages, counted with
+// model's tokenizer. It ensures a minimum of 1 token and subtracts a small
+// safety margin.
 //
-// The clamp rule is: max(1, window - EstimateTokens(messages) - 32), then
-// bounded above by the requested cap.
-func ClampCompletionCap(messages []Message, requestedCap int, window int) int {
+// The clamp rule is: max(1, window - CountTokens(model, messages) - 32),
+// then bounded above by the requested cap.
+func ClampCompletionCap(model string, messages []Message, requestedCap int, window int) int {
 	// Remaining space after considering prompt tokens and a small margin.
-	remaining := window - EstimateTokens(messages) - 32
+	remaining := window - CountTokens(model, messages) - 32
 	if remaining < 1 {
 		remaining = 1
 	}
@@ -0,0 +1,62 @@
+package oai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// CreateEmbeddings sends POST {base}/embeddings for inputs and returns one
+// vector per input, in input order. It makes a single attempt.
+func (c *Client) CreateEmbeddings(ctx context.Context, model string, inputs []string) ([][]float64, error) {
+	endpoint := c.baseURL + "/embeddings"
+	payload, err := json.Marshal(map[string]any{"model": model, "input": inputs})
+	if err != nil {
+		return nil, fmt.Errorf("encode embeddings request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embeddings POST failed: %w", err)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	_ = resp.Body.Close() //nolint:errcheck // fully read
+	if err != nil {
+		return nil, fmt.Errorf("read response body: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &StatusError{Endpoint: endpoint, Status: resp.StatusCode, Body: truncate(string(body), 2000)}
+	}
+	var out struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return nil, fmt.Errorf("decode embeddings: %w", err)
+	}
+	vecs := make([][]float64, len(inputs))
+	for _, d := range out.Data {
+		if d.Index < 0 || d.Index >= len(vecs) || len(d.Embedding) == 0 {
+			return nil, fmt.Errorf("decode embeddings: bad entry at index %d", d.Index)
+		}
+		vecs[d.Index] = d.Embedding
+	}
+	for i, v := range vecs {
+		if v == nil {
+			return nil, fmt.Errorf("decode embeddings: no vector for input %d", i)
+		}
+	}
+	return vecs, nil
+}
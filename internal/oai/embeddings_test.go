@@ -0,0 +1,34 @@
+package oai
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCreateEmbeddings(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		b, _ := io.ReadAll(r.Body) //nolint:errcheck
+		if r.URL.Path != "/v1/embeddings" || json.Unmarshal(b, &body) != nil || body.Model != "e" || len(body.Input) < 2 || r.Header.Get("Authorization") != "Bearer k" {
+			t.Errorf("path=%s body=%s", r.URL.Path, b)
+		}
+		// Entries may come back out of order
+		_, _ = io.WriteString(w, `{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`) //nolint:errcheck
+	}))
+	defer srv.Close()
+	vecs, err := NewClient(srv.URL+"/v1", "k", 5*time.Second).CreateEmbeddings(context.Background(), "e", []string{"a", "b"})
+	if err != nil || len(vecs) != 2 || vecs[0][0] != 1 || vecs[1][1] != 1 {
+		t.Fatalf("vecs=%v err=%v", vecs, err)
+	}
+	if _, err := NewClient(srv.URL+"/v1", "k", 5*time.Second).CreateEmbeddings(context.Background(), "e", []string{"a", "b", "c"}); err == nil {
+		t.Fatal("want error when an input has no vector")
+	}
+}
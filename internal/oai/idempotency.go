@@ -5,11 +5,17 @@ import (
 	"encoding/hex"
 	"fmt"
 	"time"
+
+	"github.com/hyperifyio/goagent/internal/clock"
 )
 
 // generateIdempotencyKey returns a random hex string suitable for Idempotency-Key.
 func generateIdempotencyKey() string {
 	var b [16]byte
+	if clock.Deterministic() {
+		clock.Read(b[:])
+		return "goagent-" + hex.EncodeToString(b[:])
+	}
 	if _, err := rand.Read(b[:]); err != nil {
 		// Fallback to timestamp-based key if crypto/rand fails; extremely unlikely
 		return fmt.Sprintf("goagent-%d", time.Now().UnixNano())
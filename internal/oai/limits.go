@@ -0,0 +1,147 @@
+package oai
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Default body limits; see Limits.
+const (
+	DefaultMaxResponseBytes = 32 << 20
+	DefaultMaxSSELineBytes  = 4 << 20
+)
+
+// Limits bounds the chat responses a Client reads and selects request
+// compression. Zero fields take the defaults.
+type Limits struct {
+	// MaxResponseBytes caps a chat completion body, and the total data of
+	// one streamed response, after decompression.
+	MaxResponseBytes int64
+	// MaxSSELineBytes caps a single line of a streamed response, so a server
+	// that never ends a line cannot grow the buffer without bound.
+	MaxSSELineBytes int64
+	// GzipRequests sends request bodies gzip-compressed with
+	// Content-Encoding: gzip. Only enable it for servers that accept it.
+	GzipRequests bool
+}
+
+func (l Limits) maxResponse() int64 {
+	if l.MaxResponseBytes > 0 {
+		return l.MaxResponseBytes
+	}
+	return DefaultMaxResponseBytes
+}
+
+func (l Limits) maxSSELine() int64 {
+	if l.MaxSSELineBytes > 0 {
+		return l.MaxSSELineBytes
+	}
+	return DefaultMaxSSELineBytes
+}
+
+// LimitError reports a response that crossed one of the client's limits.
+// It is not retried: the same server would send the same data again.
+type LimitError struct {
+	// Limit names the setting: "max response bytes" or "max SSE line bytes"
+	Limit string
+	Bytes int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("chat response exceeds the %s limit of %d bytes", e.Limit, e.Bytes)
+}
+
+// WithLimits sets the client's response limits and request compression.
+// Configure it before the client is shared.
+func (c *Client) WithLimits(l Limits) *Client {
+	c.limits = l
+	return c
+}
+
+// requestBody returns body as sent on the wire, gzip-compressed under
+// GzipRequests.
+func (c *Client) requestBody(body []byte) []byte {
+	if !c.limits.GzipRequests {
+		return body
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	// Writes to a bytes.Buffer cannot fail
+	_, _ = zw.Write(body) //nolint:errcheck
+	_ = zw.Close()        //nolint:errcheck
+	return buf.Bytes()
+}
+
+// setBodyHeaders sets the content headers of a chat request.
+func (c *Client) setBodyHeaders(req *http.Request) {
+	req.Header.Set("Content-Type", "application/json")
+	if c.limits.GzipRequests {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+}
+
+// responseReader decodes a gzip body the transport left compressed (a hook
+// or proxy asked for it explicitly) and caps the decoded data at the
+// response limit.
+func (c *Client) responseReader(resp *http.Response) (io.Reader, error) {
+	var r io.Reader = resp.Body
+	if !resp.Uncompressed && strings.EqualFold(strings.TrimSpace(resp.Header.Get("Content-Encoding")), "gzip") {
+		zr, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("gzip response: %w", err)
+		}
+		r = zr
+	}
+	return &cappedReader{r: r, left: c.limits.maxResponse(), limit: c.limits.maxResponse()}, nil
+}
+
+// readResponse reads the whole body within the response limit.
+func (c *Client) readResponse(resp *http.Response) ([]byte, error) {
+	r, err := c.responseReader(resp)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// ReadAll reads r to the end within the response limit, failing with a
+// LimitError past it. Provider transports read their native replies with it
+// so -http-max-response-bytes holds before conversion too.
+func (l Limits) ReadAll(r io.Reader) ([]byte, error) {
+	return io.ReadAll(&cappedReader{r: r, left: l.maxResponse(), limit: l.maxResponse()})
+}
+
+// cappedReader fails with a LimitError once more than limit bytes are read.
+type cappedReader struct {
+	r     io.Reader
+	left  int64
+	limit int64
+}
+
+func (c *cappedReader) Read(p []byte) (int, error) {
+	if c.left <= 0 {
+		// Distinguish a body of exactly limit bytes from a longer one
+		var one [1]byte
+		if n, _ := io.ReadFull(c.r, one[:]); n > 0 {
+			return 0, &LimitError{Limit: "max response bytes", Bytes: c.limit}
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > c.left {
+		p = p[:c.left]
+	}
+	n, err := c.r.Read(p)
+	c.left -= int64(n)
+	return n, err
+}
+
+// isLimitError reports whether err is a LimitError.
+func isLimitError(err error) bool {
+	var le *LimitError
+	return errors.As(err, &le)
+}
@@ -0,0 +1,106 @@
+package oai
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func gzipBytes(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+const okCompletion = `{"id":"x","object":"chat.completion","created":1,"model":"m","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`
+
+func TestCreateChatCompletion_ResponseLimit(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		_, _ = io.WriteString(w, okCompletion) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	c := NewClientWithRetry(srv.URL, "", 5*time.Second, RetryPolicy{MaxRetries: 2, Backoff: time.Millisecond})
+	if _, err := c.WithLimits(Limits{MaxResponseBytes: int64(len(okCompletion))}).CreateChatCompletion(context.Background(), ChatCompletionsRequest{Model: "m"}); err != nil {
+		t.Fatalf("body of exactly the limit must pass: %v", err)
+	}
+	calls.Store(0)
+	_, err := c.WithLimits(Limits{MaxResponseBytes: 64}).CreateChatCompletion(context.Background(), ChatCompletionsRequest{Model: "m"})
+	var le *LimitError
+	if !errors.As(err, &le) || le.Bytes != 64 || !strings.Contains(err.Error(), "max response bytes limit of 64 bytes") {
+		t.Fatalf("want LimitError, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("limit errors must not be retried; calls=%d", calls.Load())
+	}
+}
+
+func TestCreateChatCompletion_Gzip(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "gzip" {
+			t.Errorf("request Content-Encoding=%q", r.Header.Get("Content-Encoding"))
+		}
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Errorf("request body is not gzip: %v", err)
+			return
+		}
+		body, _ := io.ReadAll(zr) //nolint:errcheck
+		if !strings.Contains(string(body), `"model":"m"`) {
+			t.Errorf("request body=%s", body)
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		_, _ = w.Write(gzipBytes(t, okCompletion)) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "", 5*time.Second).WithLimits(Limits{GzipRequests: true})
+	// Asking for gzip explicitly leaves decoding to the client, not the transport
+	c.OnRequest(func(r *http.Request) error { r.Header.Set("Accept-Encoding", "gzip"); return nil })
+	resp, err := c.CreateChatCompletion(context.Background(), ChatCompletionsRequest{Model: "m"})
+	if err != nil || len(resp.Choices) != 1 || resp.Choices[0].Message.Content != "hi" {
+		t.Fatalf("resp=%+v err=%v", resp, err)
+	}
+}
+
+func TestStreamChat_Limits(t *testing.T) {
+	cases := []struct {
+		name   string
+		body   string
+		limits Limits
+		want   string
+	}{
+		{"line", "data: " + strings.Repeat("x", 200), Limits{MaxSSELineBytes: 100}, "max SSE line bytes limit of 100 bytes"},
+		{"total", strings.Repeat("data: {\"choices\":[]}\n\n", 100), Limits{MaxResponseBytes: 500}, "max response bytes limit of 500 bytes"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				_, _ = io.WriteString(w, tc.body) //nolint:errcheck
+			}))
+			defer srv.Close()
+			err := NewClient(srv.URL, "", 5*time.Second).WithLimits(tc.limits).StreamChat(context.Background(), ChatCompletionsRequest{Model: "m"}, nil)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("err=%v want %q", err, tc.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package oai
 
 import (
 	"time"
+
+	"github.com/hyperifyio/goagent/internal/clock"
 )
 
 func truncate(s string, n int) string {
@@ -26,7 +28,7 @@ func LogLengthBackoff(model string, prevCap, newCap, window, estimatedPromptToke
 		EstimatedPromptTokens int    `json:"estimated_prompt_tokens"`
 	}
 	entry := audit{
-		TS:                    time.Now().UTC().Format(time.RFC3339Nano),
+		TS:                    clock.Now().UTC().Format(time.RFC3339Nano),
 		Event:                 "length_backoff",
 		Model:                 model,
 		PrevCap:               prevCap,
@@ -39,7 +41,8 @@ func LogLengthBackoff(model string, prevCap, newCap, window, estimatedPromptToke
 
 // emitChatMetaAudit writes a one-line NDJSON entry describing request-level
 // observability fields such as the effective temperature and whether the
-// temperature parameter is included in the payload for the target model.
+// temperature parameter is included in the payload for the target model,
+// plus the sampling seed when one is sent.
 func emitChatMetaAudit(req ChatCompletionsRequest) {
 	// Compute effective temperature based on model support and clamp rules.
 	effectiveTemp, supported := EffectiveTemperatureForModel(req.Model, valueOrDefault(req.Temperature, 1.0))
@@ -49,13 +52,15 @@ func emitChatMetaAudit(req ChatCompletionsRequest) {
 		Model                string  `json:"model"`
 		TemperatureEffective float64 `json:"temperature_effective"`
 		TemperatureInPayload bool    `json:"temperature_in_payload"`
+		Seed                 *int64  `json:"seed,omitempty"`
 	}
 	entry := meta{
-		TS:                   time.Now().UTC().Format(time.RFC3339Nano),
+		TS:                   clock.Now().UTC().Format(time.RFC3339Nano),
 		Event:                "chat_meta",
 		Model:                req.Model,
 		TemperatureEffective: effectiveTemp,
 		TemperatureInPayload: supported && req.Temperature != nil,
+		Seed:                 req.Seed,
 	}
 	_ = appendAuditLog(entry)
 }
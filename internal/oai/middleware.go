@@ -0,0 +1,69 @@
+package oai
+
+import (
+	"errors"
+	"net/http"
+)
+
+// RequestHook runs on every outgoing HTTP attempt after the client has set
+// its own headers, so it may add or override headers, rewrite the URL, or
+// wrap the body. A non-nil error aborts the attempt before anything is sent
+// and is handled like a transport failure: it is retried when it looks
+// transient (for example a net.Error reporting Timeout), which lets tests
+// inject faults that exercise the retry path.
+type RequestHook func(*http.Request) error
+
+// HookResponse is returned by a RequestHook to answer the attempt with
+// Response instead of sending it. The client handles Response exactly as if
+// the server had returned it, including status-based retries, so hooks can
+// simulate server errors.
+type HookResponse struct {
+	Response *http.Response
+}
+
+func (r *HookResponse) Error() string {
+	return "request answered by hook"
+}
+
+// ResponseHook observes the outcome of every HTTP attempt. It receives the
+// response with its body still unread, or the error from the transport or a
+// RequestHook. Hooks must not read or close the body.
+type ResponseHook func(req *http.Request, resp *http.Response, err error)
+
+// OnRequest appends hooks run, in order, before each HTTP attempt. Register
+// hooks before the client is shared; registration is not synchronized with
+// in-flight requests.
+func (c *Client) OnRequest(hooks ...RequestHook) *Client {
+	c.requestHooks = append(c.requestHooks, hooks...)
+	return c
+}
+
+// OnResponse appends hooks run, in order, after each HTTP attempt. The same
+// registration rules as OnRequest apply.
+func (c *Client) OnResponse(hooks ...ResponseHook) *Client {
+	c.responseHooks = append(c.responseHooks, hooks...)
+	return c
+}
+
+// do sends req through the registered hooks and the underlying http.Client.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	var resp *http.Response
+	var err error
+	for _, h := range c.requestHooks {
+		if err = h(req); err != nil {
+			break
+		}
+	}
+	var short *HookResponse
+	switch {
+	case errors.As(err, &short) && short.Response != nil:
+		resp, err = short.Response, nil
+		resp.Request = req
+	case err == nil:
+		resp, err = c.httpClient.Do(req)
+	}
+	for _, h := range c.responseHooks {
+		h(req, resp, err)
+	}
+	return resp, err
+}
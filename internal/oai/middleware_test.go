@@ -0,0 +1,120 @@
+package oai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func okChatServer(t *testing.T, seen *[]http.Header) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*seen = append(*seen, r.Header.Clone())
+		resp := ChatCompletionsResponse{Choices: []ChatCompletionsResponseChoice{{FinishReason: "stop", Message: Message{Role: RoleAssistant, Content: "ok"}}}}
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			t.Errorf("encode: %v", err)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestClientHooks_MutateRequestAndObserveResponse(t *testing.T) {
+	var seen []http.Header
+	ts := okChatServer(t, &seen)
+	var order []string
+	var statuses []int
+	c := NewClient(ts.URL, "secret", 2*time.Second).
+		OnRequest(func(r *http.Request) error {
+			order = append(order, "first")
+			r.Header.Set("X-Tenant", "acme")
+			return nil
+		}, func(r *http.Request) error {
+			order = append(order, "second:"+r.Header.Get("X-Tenant"))
+			return nil
+		}).
+		OnResponse(func(r *http.Request, resp *http.Response, err error) {
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			statuses = append(statuses, resp.StatusCode)
+		})
+	out, err := c.CreateChatCompletion(context.Background(), ChatCompletionsRequest{Model: "m", Messages: []Message{{Role: RoleUser, Content: "hi"}}})
+	if err != nil || out.Choices[0].Message.Content != "ok" {
+		t.Fatalf("out=%+v err=%v", out, err)
+	}
+	if len(seen) != 1 || seen[0].Get("X-Tenant") != "acme" || seen[0].Get("Authorization") != "Bearer secret" {
+		t.Fatalf("server headers=%v", seen)
+	}
+	if strings.Join(order, ",") != "first,second:acme" || len(statuses) != 1 || statuses[0] != http.StatusOK {
+		t.Fatalf("order=%v statuses=%v", order, statuses)
+	}
+}
+
+// faultTimeout is a net.Error-style fault that the retry loop treats as transient.
+type faultTimeout struct{}
+
+func (faultTimeout) Error() string   { return "injected fault: timeout" }
+func (faultTimeout) Timeout() bool   { return true }
+func (faultTimeout) Temporary() bool { return true }
+
+func TestClientHooks_InjectedFaultIsRetried(t *testing.T) {
+	var seen []http.Header
+	ts := okChatServer(t, &seen)
+	faults := 1
+	var observed []error
+	c := NewClientWithRetry(ts.URL, "", 2*time.Second, RetryPolicy{MaxRetries: 1, Backoff: time.Millisecond}).
+		OnRequest(func(*http.Request) error {
+			if faults > 0 {
+				faults--
+				return faultTimeout{}
+			}
+			return nil
+		}).
+		OnResponse(func(_ *http.Request, _ *http.Response, err error) { observed = append(observed, err) })
+	if _, err := c.CreateChatCompletion(context.Background(), ChatCompletionsRequest{Model: "m"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(seen) != 1 || len(observed) != 2 || !errors.As(observed[0], new(faultTimeout)) || observed[1] != nil {
+		t.Fatalf("server calls=%d observed=%v", len(seen), observed)
+	}
+}
+
+func TestClientHooks_RequestErrorAbortsStream(t *testing.T) {
+	var seen []http.Header
+	ts := okChatServer(t, &seen)
+	c := NewClient(ts.URL, "", 2*time.Second).OnRequest(func(r *http.Request) error {
+		return fmt.Errorf("blocked %s", r.URL.Path)
+	})
+	err := c.StreamChat(context.Background(), ChatCompletionsRequest{Model: "m"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "blocked /chat/completions") || len(seen) != 0 {
+		t.Fatalf("err=%v server calls=%d", err, len(seen))
+	}
+}
+
+func TestClientHooks_HookResponseIsRetriedLikeServerError(t *testing.T) {
+	var seen []http.Header
+	ts := okChatServer(t, &seen)
+	answered := false
+	c := NewClientWithRetry(ts.URL, "", 2*time.Second, RetryPolicy{MaxRetries: 1, Backoff: time.Millisecond}).
+		OnRequest(func(*http.Request) error {
+			if answered {
+				return nil
+			}
+			answered = true
+			return &HookResponse{Response: &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{}, Body: http.NoBody}}
+		})
+	if _, err := c.CreateChatCompletion(context.Background(), ChatCompletionsRequest{Model: "m"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if st := c.Stats(); len(seen) != 1 || st.Requests != 2 || st.ServerErrors != 1 {
+		t.Fatalf("server calls=%d stats=%+v", len(seen), st)
+	}
+}
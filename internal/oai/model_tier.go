@@ -0,0 +1,72 @@
+package oai
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ModelTier is a coarse capability bucket used to adapt what the agent
+// advertises to a model (e.g., schema complexity).
+type ModelTier int
+
+const (
+	TierSmall ModelTier = iota + 1
+	TierMedium
+	TierLarge
+)
+
+// String returns the lower-case tier name.
+func (t ModelTier) String() string {
+	switch t {
+	case TierSmall:
+		return "small"
+	case TierMedium:
+		return "medium"
+	case TierLarge:
+		return "large"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseModelTier parses "small", "medium", or "large" (case-insensitive).
+func ParseModelTier(s string) (ModelTier, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "small":
+		return TierSmall, true
+	case "medium":
+		return TierMedium, true
+	case "large":
+		return TierLarge, true
+	default:
+		return 0, false
+	}
+}
+
+// paramSizeRe matches parameter-count tokens such as "8b", "20b", "1.5b".
+var paramSizeRe = regexp.MustCompile(`(?:^|[^a-z0-9.])(\d+(?:\.\d+)?)b(?:$|[^a-z0-9])`)
+
+// ModelTierFor estimates the capability tier of modelID. When the ID carries a
+// parameter count, up to 14B is small, up to 40B is medium, and larger is
+// large. Otherwise "tiny"/"nano" names are small and everything else (hosted
+// frontier models) is large.
+func ModelTierFor(modelID string) ModelTier {
+	id := strings.ToLower(strings.TrimSpace(modelID))
+	if m := paramSizeRe.FindStringSubmatch(id); m != nil {
+		if n, err := strconv.ParseFloat(m[1], 64); err == nil {
+			switch {
+			case n <= 14:
+				return TierSmall
+			case n <= 40:
+				return TierMedium
+			default:
+				return TierLarge
+			}
+		}
+	}
+	if strings.Contains(id, "tiny") || strings.Contains(id, "nano") {
+		return TierSmall
+	}
+	return TierLarge
+}
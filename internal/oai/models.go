@@ -0,0 +1,133 @@
+package oai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ModelInfo is what a provider's /models endpoint reports about one model.
+// Capabilities the listing does not mention stay unknown (zero or nil), so
+// the built-in rules keep deciding them.
+type ModelInfo struct {
+	ID            string `json:"id"`
+	ContextWindow int    `json:"context_window,omitempty"`
+	Tools         *bool  `json:"tools,omitempty"`
+	Temperature   *bool  `json:"temperature,omitempty"`
+}
+
+// contextWindowFields are the per-model context size fields used by
+// OpenAI-compatible servers: OpenRouter and Together (context_length),
+// vLLM (max_model_len), LM Studio (max_context_length), and others.
+var contextWindowFields = []string{"context_window", "context_length", "max_context_length", "max_model_len", "context_size"}
+
+// ListModels fetches GET {baseURL}/models and returns the capabilities each
+// entry reports.
+func (c *Client) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	endpoint := c.baseURL + "/models"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("models GET failed: %w", err)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	_ = resp.Body.Close() //nolint:errcheck // fully read
+	if err != nil {
+		return nil, fmt.Errorf("read response body: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &StatusError{Endpoint: endpoint, Status: resp.StatusCode, Body: truncate(string(body), 2000)}
+	}
+	return ParseModels(body)
+}
+
+// ParseModels decodes a /models listing: {"data":[...]} as sent by
+// OpenAI-compatible servers, or a bare array.
+func ParseModels(body []byte) ([]ModelInfo, error) {
+	var list struct {
+		Data []map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		if err := json.Unmarshal(body, &list.Data); err != nil {
+			return nil, fmt.Errorf("decode models: %w", err)
+		}
+	}
+	models := make([]ModelInfo, 0, len(list.Data))
+	for _, raw := range list.Data {
+		var m ModelInfo
+		if json.Unmarshal(raw["id"], &m.ID) != nil || strings.TrimSpace(m.ID) == "" {
+			continue
+		}
+		for _, f := range contextWindowFields {
+			if json.Unmarshal(raw[f], &m.ContextWindow) == nil && m.ContextWindow > 0 {
+				break
+			}
+			m.ContextWindow = 0
+		}
+		// OpenRouter lists accepted request parameters; Ollama lists
+		// capabilities such as "tools"
+		var params []string
+		if json.Unmarshal(raw["supported_parameters"], &params) == nil && len(params) > 0 {
+			m.Tools = boolPtr(contains(params, "tools"))
+			m.Temperature = boolPtr(contains(params, "temperature"))
+		}
+		var capabilities []string
+		if json.Unmarshal(raw["capabilities"], &capabilities) == nil && len(capabilities) > 0 {
+			m.Tools = boolPtr(contains(capabilities, "tools"))
+		}
+		models = append(models, m)
+	}
+	return models, nil
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+var discoveredModels = struct {
+	sync.RWMutex
+	m map[string]ModelInfo
+}{m: map[string]ModelInfo{}}
+
+// RegisterModels records discovered capabilities. What they report takes
+// precedence over the built-in rules of SupportsTemperature, SupportsTools,
+// and ContextWindowForModel for the same model id (case-insensitive).
+func RegisterModels(models []ModelInfo) {
+	discoveredModels.Lock()
+	defer discoveredModels.Unlock()
+	for _, m := range models {
+		discoveredModels.m[strings.ToLower(strings.TrimSpace(m.ID))] = m
+	}
+}
+
+// ResetModels forgets every registered model; used by tests.
+func ResetModels() {
+	discoveredModels.Lock()
+	defer discoveredModels.Unlock()
+	discoveredModels.m = map[string]ModelInfo{}
+}
+
+// discoveredModel returns the registered capabilities of a normalized id.
+func discoveredModel(id string) (ModelInfo, bool) {
+	discoveredModels.RLock()
+	defer discoveredModels.RUnlock()
+	m, ok := discoveredModels.m[id]
+	return m, ok
+}
@@ -0,0 +1,79 @@
+package oai
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseModels(t *testing.T) {
+	models, err := ParseModels([]byte(`{"object":"list","data":[
+		{"id":"gpt-4o","object":"model"},
+		{"id":"llama-3","max_model_len":8192},
+		{"id":"router/model","context_length":32768,"supported_parameters":["max_tokens","seed"]},
+		{"id":"qwen","capabilities":["completion","tools"]},
+		{"object":"model"}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(models) != 4 {
+		t.Fatalf("models=%+v", models)
+	}
+	if m := models[0]; m.ContextWindow != 0 || m.Tools != nil || m.Temperature != nil {
+		t.Errorf("bare id should stay unknown: %+v", m)
+	}
+	if models[1].ContextWindow != 8192 {
+		t.Errorf("max_model_len: %+v", models[1])
+	}
+	if m := models[2]; m.ContextWindow != 32768 || m.Tools == nil || *m.Tools || m.Temperature == nil || *m.Temperature {
+		t.Errorf("supported_parameters: %+v", m)
+	}
+	if m := models[3]; m.Tools == nil || !*m.Tools {
+		t.Errorf("capabilities: %+v", m)
+	}
+	if bare, err := ParseModels([]byte(`[{"id":"a"}]`)); err != nil || len(bare) != 1 {
+		t.Errorf("bare array: %+v %v", bare, err)
+	}
+	if _, err := ParseModels([]byte(`<html>`)); err == nil {
+		t.Error("garbage decoded")
+	}
+}
+
+func TestRegisterModels_OverridesBuiltins(t *testing.T) {
+	defer ResetModels()
+	no, yes := false, true
+	RegisterModels([]ModelInfo{
+		{ID: "Local-Model", ContextWindow: 4096, Tools: &no, Temperature: &no},
+		{ID: "o3-custom", Temperature: &yes},
+	})
+	if ContextWindowForModel("local-model") != 4096 || SupportsTools("local-model") || SupportsTemperature("LOCAL-MODEL") {
+		t.Fatal("registered capabilities ignored")
+	}
+	if !SupportsTemperature("o3-custom") {
+		t.Fatal("listing must win over the o3 rule")
+	}
+	if ContextWindowForModel("o3-custom") != DefaultContextWindow || !SupportsTools("o3-custom") || !SupportsTools("other") {
+		t.Fatal("unreported capabilities must keep the defaults")
+	}
+}
+
+func TestListModels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/v1/models" || r.Header.Get("Authorization") != "Bearer k" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[{"id":"m","context_window":1000}]}`)) //nolint:errcheck
+	}))
+	defer srv.Close()
+	models, err := NewClient(srv.URL+"/v1", "k", time.Second).ListModels(context.Background())
+	if err != nil || len(models) != 1 || models[0].ContextWindow != 1000 {
+		t.Fatalf("models=%+v err=%v", models, err)
+	}
+	if _, err := NewClient(srv.URL, "k", time.Second).ListModels(context.Background()); err == nil {
+		t.Fatal("404 succeeded")
+	}
+}
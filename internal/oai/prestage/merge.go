@@ -21,13 +21,15 @@ type PrestageParsed struct {
 	Developers        []string       // zero-or-more developer prompts to append
 	ToolConfig        *ToolConfig    // optional tool configuration hints
 	ImageInstructions map[string]any // optional defaults for downstream image tools
+	Plan              []string       // optional ordered task breakdown for the main loop
 }
 
 // ParsePrestagePayload parses a JSON payload returned by the pre-stage model.
 // The expected format is a JSON array where elements are either Harmony
 // messages with {"role":"system|developer","content":"..."} or objects
 // containing one of the keys {"system": string}, {"developer": string},
-// {"tool_config": {enable_tools:[], hints:{}}}, or {"image_instructions": {...}}.
+// {"tool_config": {enable_tools:[], hints:{}}}, {"image_instructions": {...}},
+// or {"plan": [...]} whose steps are strings or {"title": string} objects.
 // Unknown objects are ignored to keep parsing forward-compatible.
 func ParsePrestagePayload(payload string) (PrestageParsed, error) {
 	var out PrestageParsed
@@ -137,6 +139,12 @@ func tryKeyBased(obj map[string]json.RawMessage, out *PrestageParsed) bool {
 		}
 		return true
 	}
+	if rawPlan, ok := obj["plan"]; ok {
+		if out.Plan == nil {
+			out.Plan = parsePlanSteps(rawPlan)
+		}
+		return true
+	}
 	if rawImg, ok := obj["image_instructions"]; ok {
 		var ii map[string]any
 		if err := json.Unmarshal(rawImg, &ii); err == nil {
@@ -155,6 +163,8 @@ func tryKeyBased(obj map[string]json.RawMessage, out *PrestageParsed) bool {
 //  2. Append parsed.Developers immediately before the first user message; when
 //     no user message exists, append them to the end. CLI-provided developer
 //     messages in the seed remain first, preserving precedence.
+//  3. A parsed.Plan follows the developer messages as one more developer
+//     message rendered by RenderPlan.
 //
 // Messages with other roles are preserved in their original order.
 func MergePrestageIntoMessages(seed []oai.Message, parsed PrestageParsed) []oai.Message {
@@ -179,7 +189,7 @@ func MergePrestageIntoMessages(seed []oai.Message, parsed PrestageParsed) []oai.
 		}
 	}
 
-	if len(parsed.Developers) == 0 {
+	if len(parsed.Developers) == 0 && len(parsed.Plan) == 0 {
 		return out
 	}
 
@@ -192,6 +202,9 @@ func MergePrestageIntoMessages(seed []oai.Message, parsed PrestageParsed) []oai.
 		}
 		devMsgs = append(devMsgs, oai.Message{Role: oai.RoleDeveloper, Content: d})
 	}
+	if len(parsed.Plan) > 0 {
+		devMsgs = append(devMsgs, oai.Message{Role: oai.RoleDeveloper, Content: RenderPlan(parsed.Plan)})
+	}
 	if len(devMsgs) == 0 {
 		return out
 	}
@@ -0,0 +1,88 @@
+package prestage
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// PlanHeader opens the developer message that carries a pre-stage plan.
+// PlanFromMessages finds the plan again by it, so a plan survives the
+// pre-stage cache and -load-messages.
+const PlanHeader = "Plan for this task. Work through the steps in order and call plan.update with the step number and its status as you go:"
+
+// maxPlanSteps bounds the plan kept from a pre-stage payload.
+const maxPlanSteps = 50
+
+var rePlanLine = regexp.MustCompile(`^(\d+)\. (.+)$`)
+
+// parsePlanSteps accepts an array of strings or of objects with a "title"
+// (or "task"/"step") field and drops empty steps.
+func parsePlanSteps(raw json.RawMessage) []string {
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil
+	}
+	var steps []string
+	for _, it := range items {
+		var title string
+		if err := json.Unmarshal(it, &title); err != nil {
+			var obj struct {
+				Title string `json:"title"`
+				Task  string `json:"task"`
+				Step  string `json:"step"`
+			}
+			if err := json.Unmarshal(it, &obj); err != nil {
+				continue
+			}
+			title = obj.Title
+			if title == "" {
+				title = obj.Task
+			}
+			if title == "" {
+				title = obj.Step
+			}
+		}
+		// One line per step keeps the rendered plan parseable
+		title = strings.Join(strings.Fields(title), " ")
+		if title == "" {
+			continue
+		}
+		steps = append(steps, title)
+		if len(steps) == maxPlanSteps {
+			break
+		}
+	}
+	return steps
+}
+
+// RenderPlan formats steps as PlanHeader followed by "N. title" lines.
+func RenderPlan(steps []string) string {
+	var sb strings.Builder
+	sb.WriteString(PlanHeader)
+	for i, s := range steps {
+		fmt.Fprintf(&sb, "\n%d. %s", i+1, s)
+	}
+	return sb.String()
+}
+
+// PlanFromMessages returns the steps of the first developer message written
+// by RenderPlan, or nil when the transcript carries no plan.
+func PlanFromMessages(messages []oai.Message) []string {
+	for _, m := range messages {
+		if m.Role != oai.RoleDeveloper || !strings.HasPrefix(m.Content, PlanHeader) {
+			continue
+		}
+		var steps []string
+		for _, line := range strings.Split(strings.TrimPrefix(m.Content, PlanHeader), "\n") {
+			if sm := rePlanLine.FindStringSubmatch(strings.TrimSpace(line)); sm != nil && sm[1] == fmt.Sprint(len(steps)+1) {
+				steps = append(steps, sm[2])
+			}
+		}
+		return steps
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package prestage
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+func TestParsePrestagePayload_Plan(t *testing.T) {
+	parsed, err := ParsePrestagePayload(`[{"developer":"D"},{"plan":["  Read\n the file ",{"title":"Fix"},{"task":"Test"},"",7]},{"plan":["ignored"]}]`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if want := []string{"Read the file", "Fix", "Test"}; !reflect.DeepEqual(parsed.Plan, want) {
+		t.Fatalf("plan=%q want %q", parsed.Plan, want)
+	}
+	seed := []oai.Message{{Role: oai.RoleSystem, Content: "S"}, {Role: oai.RoleUser, Content: "U"}}
+	merged := MergePrestageIntoMessages(seed, parsed)
+	if len(merged) != 4 || merged[1].Content != "D" || merged[2].Role != oai.RoleDeveloper || merged[3].Role != oai.RoleUser {
+		t.Fatalf("merged=%+v", merged)
+	}
+	if got := PlanFromMessages(merged); !reflect.DeepEqual(got, parsed.Plan) {
+		t.Fatalf("round trip=%q", got)
+	}
+}
+
+func TestPlanFromMessages_NoPlan(t *testing.T) {
+	if got := PlanFromMessages([]oai.Message{{Role: oai.RoleUser, Content: RenderPlan([]string{"a"})}}); got != nil {
+		t.Fatalf("only developer messages carry the plan, got %q", got)
+	}
+}
@@ -0,0 +1,53 @@
+package oai
+
+// CacheControl marks the end of a prompt prefix that providers with explicit
+// prompt caching (Anthropic's cache_control, Bedrock's cachePoint) may
+// cache and reuse across requests.
+type CacheControl struct {
+	// Type is "ephemeral", the only kind providers accept today
+	Type string `json:"type"`
+}
+
+// EphemeralCache is the cache_control value for a cacheable prefix.
+func EphemeralCache() *CacheControl {
+	return &CacheControl{Type: "ephemeral"}
+}
+
+// MarkCacheBreakpoints marks the stable prefix of req for prompt caching:
+// the last tool schema and the last of the leading system and developer
+// messages. Providers cache everything up to a marker, and servers with
+// automatic caching (OpenAI) need no markers at all. The request's message
+// and tool slices are copied before they are marked.
+func MarkCacheBreakpoints(req *ChatCompletionsRequest) {
+	if n := len(req.Tools); n > 0 {
+		req.Tools = append([]Tool(nil), req.Tools...)
+		req.Tools[n-1].CacheControl = EphemeralCache()
+	}
+	last := -1
+	for i, m := range req.Messages {
+		if m.Role != RoleSystem && m.Role != RoleDeveloper {
+			break
+		}
+		if m.Content != "" {
+			last = i
+		}
+	}
+	if last >= 0 {
+		req.Messages = append([]Message(nil), req.Messages...)
+		req.Messages[last].CacheControl = EphemeralCache()
+	}
+}
+
+// PromptTokensDetails breaks down the prompt tokens of a reply.
+type PromptTokensDetails struct {
+	// CachedTokens were served from the provider's prompt cache
+	CachedTokens int `json:"cached_tokens"`
+}
+
+// CachedTokens returns the prompt tokens the provider read from its cache.
+func (u *Usage) CachedTokens() int {
+	if u == nil || u.PromptTokensDetails == nil {
+		return 0
+	}
+	return u.PromptTokensDetails.CachedTokens
+}
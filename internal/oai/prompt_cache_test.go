@@ -0,0 +1,59 @@
+package oai
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestMarkCacheBreakpoints(t *testing.T) {
+	msgs := []Message{
+		{Role: RoleSystem, Content: "sys"},
+		{Role: RoleDeveloper, Content: "dev"},
+		{Role: RoleUser, Content: "hi"},
+		{Role: RoleDeveloper, Content: "late"},
+	}
+	tools := []Tool{{Type: "function", Function: ToolFunction{Name: "a"}}, {Type: "function", Function: ToolFunction{Name: "b"}}}
+	req := ChatCompletionsRequest{Model: "m", Messages: msgs, Tools: tools}
+	MarkCacheBreakpoints(&req)
+	if msgs[1].CacheControl != nil || tools[1].CacheControl != nil {
+		t.Fatal("caller slices must not be modified")
+	}
+	b, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(b)
+	for _, want := range []string{
+		`{"role":"system","content":"sys"}`,
+		`{"role":"developer","content":[{"type":"text","text":"dev","cache_control":{"type":"ephemeral"}}]}`,
+		`{"role":"developer","content":"late"}`,
+		`{"type":"function","function":{"name":"a"}}`,
+		`{"type":"function","function":{"name":"b"},"cache_control":{"type":"ephemeral"}}`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Fatalf("missing %s in %s", want, s)
+		}
+	}
+
+	var back ChatCompletionsRequest
+	if err := json.Unmarshal(b, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.Messages[1].Content != "dev" || back.Messages[1].CacheControl == nil || back.Tools[1].CacheControl == nil {
+		t.Fatalf("round trip lost markers: %+v", back)
+	}
+}
+
+func TestUsage_CachedTokens(t *testing.T) {
+	var u *Usage
+	if u.CachedTokens() != 0 {
+		t.Fatal("nil usage")
+	}
+	if err := json.Unmarshal([]byte(`{"prompt_tokens":100,"prompt_tokens_details":{"cached_tokens":64},"cache_creation_input_tokens":8}`), &u); err != nil {
+		t.Fatal(err)
+	}
+	if u.CachedTokens() != 64 || u.CacheCreationInputTokens != 8 {
+		t.Fatalf("usage=%+v", u)
+	}
+}
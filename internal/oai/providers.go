@@ -0,0 +1,307 @@
+package oai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/clock"
+	"gopkg.in/yaml.v3"
+)
+
+// ProviderTable is a -providers routing table: endpoints in order of
+// preference. Each call goes to the first healthy provider; one that fails
+// FailAfter consecutive calls is skipped for Cooldown, then health-checked
+// before it serves again.
+type ProviderTable struct {
+	Providers []Provider `json:"providers"`
+	// FailAfter is the number of consecutive failed calls, each after the
+	// client's own retries, that takes a provider out of rotation (default 1)
+	FailAfter int `json:"failAfter,omitempty"`
+	// Cooldown is how long a failed provider is skipped, as a Go duration
+	// (default 60s)
+	Cooldown string `json:"cooldown,omitempty"`
+
+	cooldown time.Duration
+}
+
+// Provider is one endpoint of a ProviderTable.
+type Provider struct {
+	Name    string `json:"name,omitempty"`
+	BaseURL string `json:"baseURL"`
+	// Model replaces the request's model when set
+	Model string `json:"model,omitempty"`
+	// APIKeyEnv names the environment variable holding this provider's key;
+	// empty uses the default key
+	APIKeyEnv string `json:"apiKeyEnv,omitempty"`
+	// HealthPath is requested with GET before a failed provider serves again;
+	// any 2xx passes (default /models)
+	HealthPath string `json:"healthPath,omitempty"`
+}
+
+// FailoverEvent describes a switch from one provider to the next.
+type FailoverEvent struct {
+	From, To Provider
+	Err      error
+}
+
+// LoadProviderTable reads a JSON table, or YAML when the file name ends in
+// .yaml or .yml. YAML is converted to JSON first so both formats share the
+// field names and the unknown-field check.
+func LoadProviderTable(path string) (ProviderTable, error) {
+	var t ProviderTable
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return t, fmt.Errorf("read providers: %w", err)
+	}
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".yaml" || ext == ".yml" {
+		var v any
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return t, fmt.Errorf("providers %s: %w", path, err)
+		}
+		if data, err = json.Marshal(v); err != nil {
+			return t, fmt.Errorf("providers %s: %w", path, err)
+		}
+	}
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&t); err != nil {
+		return t, fmt.Errorf("providers %s: %w", path, err)
+	}
+	if err := t.normalize(); err != nil {
+		return t, fmt.Errorf("providers %s: %w", path, err)
+	}
+	return t, nil
+}
+
+func (t *ProviderTable) normalize() error {
+	if len(t.Providers) == 0 {
+		return errors.New("no providers listed")
+	}
+	if t.FailAfter < 0 {
+		return errors.New("failAfter must be >= 0")
+	}
+	if t.FailAfter == 0 {
+		t.FailAfter = 1
+	}
+	t.cooldown = 60 * time.Second
+	if s := strings.TrimSpace(t.Cooldown); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid cooldown %q", s)
+		}
+		t.cooldown = d
+	}
+	for i := range t.Providers {
+		p := &t.Providers[i]
+		p.BaseURL = strings.TrimRight(strings.TrimSpace(p.BaseURL), "/")
+		u, err := url.Parse(p.BaseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("provider %d: baseURL must be an http(s) URL, got %q", i+1, p.BaseURL)
+		}
+		if p.Name == "" {
+			p.Name = u.Host
+		}
+		if p.HealthPath == "" {
+			p.HealthPath = "/models"
+		}
+	}
+	return nil
+}
+
+// route is a provider's runtime health.
+type route struct {
+	Provider
+	apiKey    string
+	failures  int
+	downUntil time.Time
+}
+
+// failover holds a client's routing state; see WithProviders.
+type failover struct {
+	mu         sync.Mutex
+	routes     []*route
+	failAfter  int
+	cooldown   time.Duration
+	onFailover func(FailoverEvent)
+}
+
+// WithProviders routes the client's chat calls through table instead of its
+// own base URL. defaultAPIKey serves providers without APIKeyEnv. A call that
+// fails on one provider is retried on the next available one; onFailover,
+// when set, and the audit log see each switch. Errors the next provider would
+// repeat, such as a 4xx other than 429, are returned unchanged.
+func (c *Client) WithProviders(table ProviderTable, defaultAPIKey string, onFailover func(FailoverEvent)) *Client {
+	f := &failover{failAfter: table.FailAfter, cooldown: table.cooldown, onFailover: onFailover}
+	if f.failAfter < 1 {
+		f.failAfter = 1
+	}
+	for _, p := range table.Providers {
+		key := defaultAPIKey
+		if p.APIKeyEnv != "" {
+			key = os.Getenv(p.APIKeyEnv)
+		}
+		if p.HealthPath == "" {
+			p.HealthPath = "/models"
+		}
+		f.routes = append(f.routes, &route{Provider: p, apiKey: key})
+	}
+	c.failover = f
+	return c
+}
+
+// via returns a copy of c that sends to r; hooks, retry policy, and counters
+// are shared with c.
+func (c *Client) via(r *route) *Client {
+	rc := *c
+	rc.baseURL, rc.apiKey, rc.failover = strings.TrimRight(r.BaseURL, "/"), r.apiKey, nil
+	return &rc
+}
+
+// shouldFailover reports whether another provider might succeed where this
+// call failed.
+func shouldFailover(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || strings.Contains(err.Error(), "does not support streaming") {
+		return false
+	}
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Status == http.StatusTooManyRequests || se.Status >= 500
+	}
+	return true
+}
+
+// available reports whether r may serve now. A provider whose cooldown has
+// passed must answer its health check first.
+func (f *failover) available(ctx context.Context, c *Client, r *route) bool {
+	f.mu.Lock()
+	down, until := r.failures >= f.failAfter, r.downUntil
+	f.mu.Unlock()
+	if !down {
+		return true
+	}
+	if time.Now().Before(until) || !c.healthy(ctx, r) {
+		return false
+	}
+	f.mu.Lock()
+	r.failures = 0
+	f.mu.Unlock()
+	return true
+}
+
+// result records a call's outcome and reports whether r is still in rotation.
+func (f *failover) result(r *route, err error) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		r.failures = 0
+		return true
+	}
+	r.failures++
+	if r.failures >= f.failAfter {
+		r.downUntil = time.Now().Add(f.cooldown)
+		return false
+	}
+	return true
+}
+
+func (c *Client) healthy(ctx context.Context, r *route) bool {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(r.BaseURL, "/")+r.HealthPath, nil)
+	if err != nil {
+		return false
+	}
+	if r.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+r.apiKey)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return false
+	}
+	_ = resp.Body.Close() //nolint:errcheck // status is all that matters
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
+}
+
+// do runs call on the first available provider and moves down the table on
+// failures worth retrying elsewhere. call reports whether it already
+// delivered output (a partly read stream), which rules out a switch. When
+// every provider is cooling down the first one is tried anyway rather than
+// failing without a request.
+func (f *failover) do(ctx context.Context, c *Client, req ChatCompletionsRequest, call func(*Client, ChatCompletionsRequest) (bool, error)) error {
+	var prev *route
+	var lastErr error
+	// attempt keeps calling r until it succeeds, fails for good, or leaves
+	// the rotation; it reports whether the caller is done
+	attempt := func(r *route) (bool, error) {
+		if prev != nil {
+			f.switched(prev, r, lastErr, req.Model)
+		}
+		prev = r
+		rreq := req
+		if r.Model != "" {
+			rreq.Model = r.Model
+		}
+		for {
+			delivered, err := call(c.via(r), rreq)
+			if err == nil {
+				f.result(r, nil)
+				return true, nil
+			}
+			if delivered || !shouldFailover(ctx, err) {
+				return true, err
+			}
+			lastErr = err
+			if !f.result(r, err) {
+				return false, err
+			}
+		}
+	}
+	for _, r := range f.routes {
+		if !f.available(ctx, c, r) {
+			continue
+		}
+		if done, err := attempt(r); done {
+			return err
+		}
+	}
+	if prev == nil {
+		if done, err := attempt(f.routes[0]); done {
+			return err
+		}
+	}
+	return fmt.Errorf("all providers failed; last error: %w", lastErr)
+}
+
+// switched reports a failover on stderr (via onFailover) and in the audit log.
+func (f *failover) switched(from, to *route, err error, model string) {
+	if f.onFailover != nil {
+		f.onFailover(FailoverEvent{From: from.Provider, To: to.Provider, Err: err})
+	}
+	type audit struct {
+		TS      string `json:"ts"`
+		Event   string `json:"event"`
+		From    string `json:"from"`
+		To      string `json:"to"`
+		FromURL string `json:"fromURL"`
+		ToURL   string `json:"toURL"`
+		Model   string `json:"model,omitempty"`
+		Error   string `json:"error,omitempty"`
+	}
+	if to.Model != "" {
+		model = to.Model
+	}
+	entry := audit{TS: clock.Now().UTC().Format(time.RFC3339Nano), Event: "provider_failover", From: from.Name, To: to.Name, FromURL: from.BaseURL, ToURL: to.BaseURL, Model: model}
+	if err != nil {
+		entry.Error = truncate(err.Error(), 500)
+	}
+	_ = appendAuditLog(entry) //nolint:errcheck // audit is best-effort
+}
@@ -0,0 +1,150 @@
+package oai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoadProviderTable(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	table, err := LoadProviderTable(write("p.yaml", "providers:\n  - baseURL: http://a.example/v1/\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := table.Providers[0]
+	if p.Name != "a.example" || p.BaseURL != "http://a.example/v1" || p.HealthPath != "/models" || table.FailAfter != 1 || table.cooldown != time.Minute {
+		t.Fatalf("defaults: %+v %+v", table, p)
+	}
+	// Full YAML: flow mappings and folded scalars
+	if table, err = LoadProviderTable(write("full.yml", "providers:\n  - {baseURL: 'http://c.example', name: c}\n  - baseURL: >-\n      http://d.example\n    apiKeyEnv: OTHER\n")); err != nil || table.Providers[0].Name != "c" || table.Providers[1].BaseURL != "http://d.example" {
+		t.Fatalf("yaml: %+v %v", table, err)
+	}
+	if table, err = LoadProviderTable(write("p.json", `{"providers":[{"baseURL":"https://b.example"}],"failAfter":3,"cooldown":"5s"}`)); err != nil || table.FailAfter != 3 || table.cooldown != 5*time.Second {
+		t.Fatalf("json: %+v %v", table, err)
+	}
+	for body, want := range map[string]string{
+		`{"providers":[]}`:                                         "no providers",
+		`{"providers":[{"baseURL":"ftp://x"}]}`:                    "http(s) URL",
+		`{"providers":[{"baseURL":"http://x"}],"cooldown":"soon"}`: "invalid cooldown",
+		`{"providers":[{"baseURL":"http://x","weight":2}]}`:        "unknown field",
+	} {
+		if _, err := LoadProviderTable(write("bad.json", body)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err=%v want %q", body, err, want)
+		}
+	}
+}
+
+// providerServer answers chat calls with status (200 replies with its name)
+// and counts the chat requests and the models they asked for.
+func providerServer(t *testing.T, name string, status *int32, calls *int32, models *[]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code := int(atomic.LoadInt32(status))
+		if r.URL.Path == "/models" {
+			w.WriteHeader(code)
+			return
+		}
+		atomic.AddInt32(calls, 1)
+		var req ChatCompletionsRequest
+		_ = json.NewDecoder(r.Body).Decode(&req) //nolint:errcheck
+		if models != nil {
+			*models = append(*models, req.Model)
+		}
+		if code != http.StatusOK {
+			http.Error(w, "down", code)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(ChatCompletionsResponse{Choices: []ChatCompletionsResponseChoice{{Message: Message{Role: RoleAssistant, Content: name}}}}) //nolint:errcheck
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestWithProviders_FailsOverAndRecovers(t *testing.T) {
+	t.Chdir(t.TempDir())
+	primaryStatus, backupStatus := int32(http.StatusInternalServerError), int32(http.StatusOK)
+	var primaryCalls, backupCalls int32
+	var backupModels []string
+	primary := providerServer(t, "primary", &primaryStatus, &primaryCalls, nil)
+	backup := providerServer(t, "backup", &backupStatus, &backupCalls, &backupModels)
+	table := ProviderTable{Providers: []Provider{{Name: "primary", BaseURL: primary.URL}, {Name: "backup", BaseURL: backup.URL, Model: "m2"}}}
+	if err := table.normalize(); err != nil {
+		t.Fatal(err)
+	}
+	var events []FailoverEvent
+	c := NewClient("http://unused.invalid", "", 5*time.Second).WithProviders(table, "", func(ev FailoverEvent) { events = append(events, ev) })
+	req := ChatCompletionsRequest{Model: "m1", Messages: []Message{{Role: RoleUser, Content: "hi"}}}
+
+	resp, err := c.CreateChatCompletion(context.Background(), req)
+	if err != nil || resp.Choices[0].Message.Content != "backup" {
+		t.Fatalf("resp=%+v err=%v", resp, err)
+	}
+	if len(events) != 1 || events[0].From.Name != "primary" || events[0].To.Name != "backup" || !strings.Contains(events[0].Err.Error(), "500") {
+		t.Fatalf("events=%+v", events)
+	}
+	if len(backupModels) != 1 || backupModels[0] != "m2" {
+		t.Fatalf("backup models=%v", backupModels)
+	}
+	data, err := os.ReadFile(filepath.Join(".goagent", "audit", time.Now().UTC().Format("20060102")+".log"))
+	if err != nil || !strings.Contains(string(data), `"event":"provider_failover"`) || !strings.Contains(string(data), `"from":"primary"`) {
+		t.Fatalf("audit: %s %v", data, err)
+	}
+
+	// The primary stays out of rotation during its cooldown
+	if _, err := c.CreateChatCompletion(context.Background(), req); err != nil || primaryCalls != 1 || backupCalls != 2 {
+		t.Fatalf("cooldown: err=%v primary=%d backup=%d", err, primaryCalls, backupCalls)
+	}
+
+	// After the cooldown it must pass its health check before serving again
+	c.failover.routes[0].downUntil = time.Time{}
+	if _, err := c.CreateChatCompletion(context.Background(), req); err != nil || primaryCalls != 1 || backupCalls != 3 {
+		t.Fatalf("unhealthy: err=%v primary=%d backup=%d", err, primaryCalls, backupCalls)
+	}
+	atomic.StoreInt32(&primaryStatus, http.StatusOK)
+	c.failover.routes[0].downUntil = time.Time{}
+	if resp, err := c.CreateChatCompletion(context.Background(), req); err != nil || resp.Choices[0].Message.Content != "primary" {
+		t.Fatalf("recovered: resp=%+v err=%v", resp, err)
+	}
+}
+
+func TestWithProviders_NoFailoverOnClientErrors(t *testing.T) {
+	t.Chdir(t.TempDir())
+	badRequest, ok := int32(http.StatusBadRequest), int32(http.StatusOK)
+	var primaryCalls, backupCalls int32
+	primary := providerServer(t, "primary", &badRequest, &primaryCalls, nil)
+	backup := providerServer(t, "backup", &ok, &backupCalls, nil)
+	table := ProviderTable{Providers: []Provider{{BaseURL: primary.URL}, {BaseURL: backup.URL}}}
+	if err := table.normalize(); err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient("http://unused.invalid", "", 5*time.Second).WithProviders(table, "", nil)
+	_, err := c.CreateChatCompletion(context.Background(), ChatCompletionsRequest{Model: "m", Messages: []Message{{Role: RoleUser, Content: "hi"}}})
+	var se *StatusError
+	if !errors.As(err, &se) || se.Status != http.StatusBadRequest || backupCalls != 0 {
+		t.Fatalf("err=%v backup=%d", err, backupCalls)
+	}
+
+	// When every provider fails the last error is reported
+	atomic.StoreInt32(&badRequest, http.StatusBadGateway)
+	atomic.StoreInt32(&ok, http.StatusServiceUnavailable)
+	_, err = c.CreateChatCompletion(context.Background(), ChatCompletionsRequest{Model: "m", Messages: []Message{{Role: RoleUser, Content: "hi"}}})
+	if err == nil || !strings.Contains(err.Error(), "all providers failed") || !strings.Contains(err.Error(), ": 503: ") {
+		t.Fatalf("err=%v", err)
+	}
+}
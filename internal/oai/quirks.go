@@ -0,0 +1,80 @@
+package oai
+
+import "strings"
+
+// Workarounds for llama.cpp-style local servers, applied to every response so
+// local backends look like hosted ones to callers.
+
+// bosArtifacts are beginning-of-sequence markers that some local servers leak
+// at the start of generated text.
+var bosArtifacts = []string{"\ufeff", "<|begin_of_text|>", "<|startoftext|>", "<bos>", "<s>"}
+
+// StripBOS removes leading BOS artifacts from generated text. Other leading
+// characters, including whitespace, are kept.
+func StripBOS(s string) string {
+	for {
+		trimmed := false
+		for _, bos := range bosArtifacts {
+			if strings.HasPrefix(s, bos) {
+				s = s[len(bos):]
+				trimmed = true
+			}
+		}
+		if !trimmed {
+			return s
+		}
+	}
+}
+
+// NormalizeFinishReason maps a missing or non-standard finish_reason to the
+// OpenAI values. A missing one is inferred from the message: "tool_calls"
+// when it requests tools, otherwise "stop". llama.cpp and similar servers'
+// stop and limit spellings become "stop" and "length".
+func NormalizeFinishReason(reason string, msg Message) string {
+	switch r := strings.ToLower(strings.TrimSpace(reason)); r {
+	case "", "null":
+		if len(msg.ToolCalls) > 0 {
+			return "tool_calls"
+		}
+		return "stop"
+	case "eos", "stopped_eos", "stopped_word", "end_turn", "stop_sequence":
+		return "stop"
+	case "limit", "stopped_limit", "max_tokens":
+		return "length"
+	default:
+		return r
+	}
+}
+
+// normalizeResponse applies StripBOS and NormalizeFinishReason to each
+// choice of a non-streaming response.
+func normalizeResponse(resp *ChatCompletionsResponse) {
+	for i := range resp.Choices {
+		ch := &resp.Choices[i]
+		ch.Message.Content = StripBOS(ch.Message.Content)
+		ch.FinishReason = NormalizeFinishReason(ch.FinishReason, ch.Message)
+	}
+}
+
+// bosStripper removes BOS artifacts from the start of each streamed choice.
+// A delta that is only an artifact becomes empty; stripping stops at the
+// first delta with other content.
+type bosStripper struct {
+	started map[int]bool
+}
+
+func (b *bosStripper) apply(chunk *StreamChunk) {
+	for i := range chunk.Choices {
+		ch := &chunk.Choices[i]
+		if b.started[ch.Index] || ch.Delta.Content == "" {
+			continue
+		}
+		ch.Delta.Content = StripBOS(ch.Delta.Content)
+		if ch.Delta.Content != "" {
+			if b.started == nil {
+				b.started = make(map[int]bool)
+			}
+			b.started[ch.Index] = true
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package oai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStripBOS(t *testing.T) {
+	cases := map[string]string{
+		"<s> Hello":              " Hello",
+		"<|begin_of_text|><s>Hi": "Hi",
+		"\ufeffok":               "ok",
+		"plain <s> inside":       "plain <s> inside",
+		"":                       "",
+	}
+	for in, want := range cases {
+		if got := StripBOS(in); got != want {
+			t.Errorf("StripBOS(%q)=%q want %q", in, got, want)
+		}
+	}
+}
+
+func TestNormalizeFinishReason(t *testing.T) {
+	call := Message{ToolCalls: []ToolCall{{ID: "1"}}}
+	cases := []struct {
+		reason string
+		msg    Message
+		want   string
+	}{
+		{"", Message{}, "stop"},
+		{"", call, "tool_calls"},
+		{"stopped_limit", Message{}, "length"},
+		{"stopped_eos", Message{}, "stop"},
+		{"Length", Message{}, "length"},
+		{"content_filter", Message{}, "content_filter"},
+	}
+	for _, c := range cases {
+		if got := NormalizeFinishReason(c.reason, c.msg); got != c.want {
+			t.Errorf("NormalizeFinishReason(%q)=%q want %q", c.reason, got, c.want)
+		}
+	}
+}
+
+func TestCreateChatCompletion_LocalServerQuirks(t *testing.T) {
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"<s>Hello"}}]}`)) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "", 5*time.Second)
+	resp, err := c.CreateChatCompletion(context.Background(), ChatCompletionsRequest{Model: "m", ChatTemplate: "chatml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body["chat_template"] != "chatml" {
+		t.Fatalf("chat_template not sent: %v", body)
+	}
+	if ch := resp.Choices[0]; ch.Message.Content != "Hello" || ch.FinishReason != "stop" {
+		t.Fatalf("choice=%+v", ch)
+	}
+}
+
+func TestStreamChat_StripsLeadingBOS(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		// llama.cpp-style: BOS as its own delta, no finish_reason, no [DONE]
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"<s>\"}}]}\n\n" + //nolint:errcheck
+			"data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"<s>Hi\"}}]}\n\n" +
+			"data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\" <s>\"}}]}\n\n"))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "", 5*time.Second)
+	var acc StreamAccumulator
+	if err := c.StreamChat(context.Background(), ChatCompletionsRequest{Model: "m"}, func(ch StreamChunk) error {
+		return acc.Add(ch)
+	}); err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	if m := acc.Message(); m.Content != "Hi <s>" {
+		t.Fatalf("content=%q", m.Content)
+	}
+	if acc.FinishReason() != "stop" {
+		t.Fatalf("finish=%q", acc.FinishReason())
+	}
+}
@@ -0,0 +1,177 @@
+package oai
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultMaxRetryWait caps server-requested waits (Retry-After and
+// x-ratelimit-reset-*) when RetryPolicy.MaxRetryWait is zero.
+const DefaultMaxRetryWait = 60 * time.Second
+
+// ErrCircuitOpen is returned without contacting the server while the circuit
+// breaker for a base URL is open.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// HTTPStats counts request outcomes for the usage summary.
+type HTTPStats struct {
+	Requests          int64 // HTTP attempts sent
+	Retries           int64 // attempts after the first for a call
+	RateLimited       int64 // 429 responses
+	ServerErrors      int64 // 5xx responses
+	BreakerRejections int64 // calls failed fast by an open breaker
+}
+
+type httpCounters struct {
+	requests, retries, rateLimited, serverErrors, breakerRejections atomic.Int64
+}
+
+func (c *httpCounters) snapshot() HTTPStats {
+	return HTTPStats{
+		Requests:          c.requests.Load(),
+		Retries:           c.retries.Load(),
+		RateLimited:       c.rateLimited.Load(),
+		ServerErrors:      c.serverErrors.Load(),
+		BreakerRejections: c.breakerRejections.Load(),
+	}
+}
+
+// Stats returns the counters accumulated by this client.
+func (c *Client) Stats() HTTPStats {
+	if c == nil || c.counters == nil {
+		return HTTPStats{}
+	}
+	return c.counters.snapshot()
+}
+
+// Add returns the sum of two stats, for combining pre-stage and main clients.
+func (s HTTPStats) Add(o HTTPStats) HTTPStats {
+	return HTTPStats{
+		Requests:          s.Requests + o.Requests,
+		Retries:           s.Retries + o.Retries,
+		RateLimited:       s.RateLimited + o.RateLimited,
+		ServerErrors:      s.ServerErrors + o.ServerErrors,
+		BreakerRejections: s.BreakerRejections + o.BreakerRejections,
+	}
+}
+
+// circuitBreaker opens after threshold consecutive 429/5xx responses and
+// rejects calls until the cooldown elapses. The consecutive count is only
+// reset by a success, so the first failure after a cooldown reopens it.
+type circuitBreaker struct {
+	mu          sync.Mutex
+	consecutive int
+	openUntil   time.Time
+}
+
+// breakers is shared per base URL so every client talking to the same
+// server (pre-stage, main, retries) sees the same state.
+var breakers sync.Map // map[string]*circuitBreaker
+
+func breakerFor(baseURL string) *circuitBreaker {
+	b, _ := breakers.LoadOrStore(baseURL, &circuitBreaker{})
+	return b.(*circuitBreaker) //nolint:forcetypeassert // only *circuitBreaker is stored
+}
+
+// allow reports the remaining cooldown when the breaker is open.
+func (b *circuitBreaker) allow(now time.Time) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if now.Before(b.openUntil) {
+		return b.openUntil.Sub(now), false
+	}
+	return 0, true
+}
+
+// record updates the breaker with an attempt outcome. Non-breaker failures
+// (4xx other than 429, transport errors) leave the count unchanged.
+func (b *circuitBreaker) record(status int, threshold int, cooldown time.Duration, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case status >= 200 && status < 300:
+		b.consecutive = 0
+		b.openUntil = time.Time{}
+	case status == http.StatusTooManyRequests || status >= 500:
+		b.consecutive++
+		if threshold > 0 && b.consecutive >= threshold {
+			b.openUntil = now.Add(cooldown)
+		}
+	}
+}
+
+// serverRetryWait returns how long the server asked us to wait before the
+// next attempt: Retry-After (seconds or HTTP-date), then retry-after-ms, then
+// the x-ratelimit-reset-* header of whichever limit is exhausted (or the
+// longest reset when remaining counts are absent). Waits are capped at max.
+func serverRetryWait(h http.Header, now time.Time, max time.Duration) (time.Duration, bool) {
+	if max <= 0 {
+		max = DefaultMaxRetryWait
+	}
+	capped := func(d time.Duration) (time.Duration, bool) {
+		if d > max {
+			d = max
+		}
+		return d, true
+	}
+	if d, ok := retryAfterDuration(h.Get("Retry-After"), now); ok {
+		return capped(d)
+	}
+	if ms, err := strconv.ParseFloat(strings.TrimSpace(h.Get("Retry-After-Ms")), 64); err == nil && ms > 0 {
+		return capped(time.Duration(ms * float64(time.Millisecond)))
+	}
+	var wait time.Duration
+	anyExhausted := false
+	for _, kind := range []string{"requests", "tokens"} {
+		reset, ok := parseRateLimitReset(h.Get("X-Ratelimit-Reset-"+kind), now)
+		if !ok {
+			continue
+		}
+		remaining := strings.TrimSpace(h.Get("X-Ratelimit-Remaining-" + kind))
+		exhausted := remaining == "0"
+		if exhausted && !anyExhausted {
+			// Prefer exhausted limits over informational ones
+			anyExhausted = true
+			wait = 0
+		}
+		if (exhausted || !anyExhausted) && reset > wait {
+			wait = reset
+		}
+	}
+	if wait <= 0 {
+		// Generic single-limit headers used by some gateways
+		if reset, ok := parseRateLimitReset(h.Get("X-Ratelimit-Reset"), now); ok {
+			wait = reset
+		}
+	}
+	if wait <= 0 {
+		return 0, false
+	}
+	return capped(wait)
+}
+
+// parseRateLimitReset accepts Go/OpenAI style durations ("1s", "6m0s",
+// "20ms"), plain seconds ("2.5"), or a Unix epoch timestamp in seconds.
+func parseRateLimitReset(v string, now time.Time) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+	if d, err := time.ParseDuration(v); err == nil && d > 0 {
+		return d, true
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f <= 0 {
+		return 0, false
+	}
+	if f > 1e9 {
+		d := time.Unix(int64(f), 0).Sub(now)
+		return d, d > 0
+	}
+	return time.Duration(f * float64(time.Second)), true
+}
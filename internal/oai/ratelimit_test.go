@@ -0,0 +1,121 @@
+package oai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestServerRetryWait(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name    string
+		headers map[string]string
+		want    time.Duration
+		ok      bool
+	}{
+		{"none", nil, 0, false},
+		{"retry-after seconds", map[string]string{"Retry-After": "3"}, 3 * time.Second, true},
+		{"retry-after capped", map[string]string{"Retry-After": "600"}, DefaultMaxRetryWait, true},
+		{"retry-after-ms", map[string]string{"Retry-After-Ms": "250"}, 250 * time.Millisecond, true},
+		{"exhausted tokens win", map[string]string{
+			"X-Ratelimit-Remaining-Requests": "10", "X-Ratelimit-Reset-Requests": "20s",
+			"X-Ratelimit-Remaining-Tokens": "0", "X-Ratelimit-Reset-Tokens": "1.5s",
+		}, 1500 * time.Millisecond, true},
+		{"longest reset without remaining", map[string]string{
+			"X-Ratelimit-Reset-Requests": "6m0s", "X-Ratelimit-Reset-Tokens": "2s",
+		}, DefaultMaxRetryWait, true},
+		{"epoch reset", map[string]string{"X-Ratelimit-Reset": "1735689610"}, 10 * time.Second, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := http.Header{}
+			for k, v := range tc.headers {
+				h.Set(k, v)
+			}
+			got, ok := serverRetryWait(h, now, 0)
+			if ok != tc.ok || got != tc.want {
+				t.Fatalf("got (%s,%v) want (%s,%v)", got, ok, tc.want, tc.ok)
+			}
+		})
+	}
+}
+
+func TestCreateChatCompletion_RateLimitResetHeaderDrivesBackoff(t *testing.T) {
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("X-Ratelimit-Remaining-Requests", "0")
+			w.Header().Set("X-Ratelimit-Reset-Requests", "750ms")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(ChatCompletionsResponse{Choices: []ChatCompletionsResponseChoice{{Message: Message{Role: RoleAssistant, Content: "ok"}}}}) //nolint:errcheck
+	}))
+	defer ts.Close()
+
+	var slept []time.Duration
+	oldSleep := sleepFunc
+	sleepFunc = func(d time.Duration) { slept = append(slept, d) }
+	defer func() { sleepFunc = oldSleep }()
+
+	c := NewClientWithRetry(ts.URL, "", time.Second, RetryPolicy{MaxRetries: 2, Backoff: time.Millisecond})
+	if _, err := c.CreateChatCompletion(context.Background(), ChatCompletionsRequest{Model: "m", Messages: []Message{{Role: RoleUser, Content: "hi"}}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(slept) != 1 || slept[0] != 750*time.Millisecond {
+		t.Fatalf("slept=%v want [750ms]", slept)
+	}
+	st := c.Stats()
+	if st.Requests != 2 || st.Retries != 1 || st.RateLimited != 1 || st.ServerErrors != 0 {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+}
+
+func TestCreateChatCompletion_CircuitBreakerFailsFast(t *testing.T) {
+	hits := 0
+	ok := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if ok {
+			_ = json.NewEncoder(w).Encode(ChatCompletionsResponse{Choices: []ChatCompletionsResponseChoice{{Message: Message{Role: RoleAssistant, Content: "ok"}}}}) //nolint:errcheck
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	oldSleep := sleepFunc
+	sleepFunc = func(time.Duration) {}
+	defer func() { sleepFunc = oldSleep }()
+
+	policy := RetryPolicy{MaxRetries: 5, Backoff: time.Millisecond, BreakerThreshold: 3, BreakerCooldown: 50 * time.Millisecond}
+	c := NewClientWithRetry(ts.URL, "", time.Second, policy)
+	req := ChatCompletionsRequest{Model: "m", Messages: []Message{{Role: RoleUser, Content: "hi"}}}
+	_, err := c.CreateChatCompletion(context.Background(), req)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if hits != 3 {
+		t.Fatalf("breaker should stop after 3 failures, hits=%d", hits)
+	}
+	// A second client for the same base URL shares the open breaker
+	c2 := NewClientWithRetry(ts.URL, "", time.Second, policy)
+	if _, err := c2.CreateChatCompletion(context.Background(), req); !errors.Is(err, ErrCircuitOpen) || hits != 3 {
+		t.Fatalf("expected fail-fast without contacting server, err=%v hits=%d", err, hits)
+	}
+	if st := c2.Stats(); st.BreakerRejections != 1 || st.Requests != 0 {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+	// After the cooldown a success closes the breaker
+	time.Sleep(60 * time.Millisecond)
+	ok = true
+	if _, err := c2.CreateChatCompletion(context.Background(), req); err != nil {
+		t.Fatalf("expected recovery after cooldown: %v", err)
+	}
+}
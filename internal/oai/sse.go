@@ -2,17 +2,30 @@ package oai
 
 import (
 	"bufio"
+	"errors"
 	"io"
 )
 
 // newLineReader returns a closure that reads one line (terminated by \n) from r each call.
-func newLineReader(r io.Reader) func() (string, error) {
+// A line longer than maxLine bytes fails with a LimitError.
+func newLineReader(r io.Reader, maxLine int64) func() (string, error) {
 	br := bufio.NewReader(r)
 	return func() (string, error) {
-		b, err := br.ReadBytes('\n')
-		if err != nil {
-			return "", err
+		var line []byte
+		for {
+			chunk, err := br.ReadSlice('\n')
+			if int64(len(line)+len(chunk)) > maxLine {
+				return "", &LimitError{Limit: "max SSE line bytes", Bytes: maxLine}
+			}
+			line = append(line, chunk...)
+			switch {
+			case err == nil:
+				return string(line), nil
+			case errors.Is(err, bufio.ErrBufferFull):
+				continue
+			default:
+				return "", err
+			}
 		}
-		return string(b), nil
 	}
 }
@@ -0,0 +1,117 @@
+package oai
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxStreamToolCalls bounds the tool calls of one streamed message; a delta
+// with a larger index fails the stream instead of growing the call list.
+const MaxStreamToolCalls = 128
+
+// StreamAccumulator rebuilds the assistant message of the first choice from
+// streamed chunks, joining content and tool_calls fragments.
+type StreamAccumulator struct {
+	role         string
+	channel      string
+	content      strings.Builder
+	calls        []ToolCall
+	finishReason string
+	usage        *Usage
+	fingerprint  string
+}
+
+// Add folds one chunk into the accumulated message. It fails when a tool
+// call index is MaxStreamToolCalls or more.
+func (a *StreamAccumulator) Add(chunk StreamChunk) error {
+	if chunk.Usage != nil {
+		a.usage = chunk.Usage
+	}
+	if chunk.SystemFingerprint != "" {
+		a.fingerprint = chunk.SystemFingerprint
+	}
+	for _, ch := range chunk.Choices {
+		if ch.Index != 0 {
+			continue
+		}
+		d := ch.Delta
+		if d.Role != "" {
+			a.role = d.Role
+		}
+		if a.channel == "" && strings.TrimSpace(d.Channel) != "" {
+			a.channel = strings.TrimSpace(d.Channel)
+		}
+		a.content.WriteString(d.Content)
+		for _, tc := range d.ToolCalls {
+			if err := a.addToolCall(tc); err != nil {
+				return err
+			}
+		}
+		if ch.FinishReason != "" {
+			a.finishReason = ch.FinishReason
+		}
+	}
+	return nil
+}
+
+func (a *StreamAccumulator) addToolCall(d StreamToolCallDelta) error {
+	if d.Index < 0 {
+		return nil
+	}
+	if d.Index >= MaxStreamToolCalls {
+		return fmt.Errorf("stream tool call index %d exceeds the limit of %d calls", d.Index, MaxStreamToolCalls)
+	}
+	for len(a.calls) <= d.Index {
+		a.calls = append(a.calls, ToolCall{Type: "function"})
+	}
+	tc := &a.calls[d.Index]
+	if d.ID != "" {
+		tc.ID = d.ID
+	}
+	if d.Type != "" {
+		tc.Type = d.Type
+	}
+	// Some servers repeat the full name on every fragment; others split it
+	if d.Function.Name != "" && d.Function.Name != tc.Function.Name {
+		tc.Function.Name += d.Function.Name
+	}
+	tc.Function.Arguments += d.Function.Arguments
+	return nil
+}
+
+// Message returns the accumulated assistant message. Tool calls without a
+// function name are dropped and calls without an ID get a synthetic one.
+func (a *StreamAccumulator) Message() Message {
+	m := Message{Role: a.role, Channel: a.channel, Content: a.content.String()}
+	if m.Role == "" {
+		m.Role = RoleAssistant
+	}
+	for i, tc := range a.calls {
+		if strings.TrimSpace(tc.Function.Name) == "" {
+			continue
+		}
+		if tc.ID == "" {
+			tc.ID = fmt.Sprintf("stream_call_%d", i)
+		}
+		if strings.TrimSpace(tc.Function.Arguments) == "" {
+			tc.Function.Arguments = "{}"
+		}
+		m.ToolCalls = append(m.ToolCalls, tc)
+	}
+	return m
+}
+
+// FinishReason returns the last finish_reason reported for the first choice,
+// normalized with NormalizeFinishReason so streams that never send one still
+// report "stop" or "tool_calls".
+func (a *StreamAccumulator) FinishReason() string {
+	return NormalizeFinishReason(a.finishReason, a.Message())
+}
+
+// Usage returns the token accounting from the stream, or nil when the server
+// sent none.
+func (a *StreamAccumulator) Usage() *Usage { return a.usage }
+
+// SystemFingerprint returns the system_fingerprint from the stream, or ""
+// when the server sent none.
+func (a *StreamAccumulator) SystemFingerprint() string { return a.fingerprint }
@@ -0,0 +1,82 @@
+package oai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreamAccumulator_ToolCallFragments(t *testing.T) {
+	events := []string{
+		`{"choices":[{"index":0,"delta":{"role":"assistant","content":"Checking"}}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_a","type":"function","function":{"name":"get_","arguments":""}}]}}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"name":"time","arguments":"{\"tz\":"}}]}}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"id":"call_b","function":{"name":"ping"}}]}}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"UTC\"}"}}]}}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"function":{"name":"ping"}}]}}]}`,
+		`{"choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":5,"completion_tokens":7,"total_tokens":12}}`,
+	}
+	var acc StreamAccumulator
+	for _, e := range events {
+		var c StreamChunk
+		if err := json.Unmarshal([]byte(e), &c); err != nil {
+			t.Fatal(err)
+		}
+		if err := acc.Add(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := acc.Message()
+	if m.Role != RoleAssistant || m.Content != "Checking" || len(m.ToolCalls) != 2 {
+		t.Fatalf("message=%+v", m)
+	}
+	if tc := m.ToolCalls[0]; tc.ID != "call_a" || tc.Type != "function" || tc.Function.Name != "get_time" || tc.Function.Arguments != `{"tz":"UTC"}` {
+		t.Fatalf("call 0=%+v", tc)
+	}
+	if tc := m.ToolCalls[1]; tc.ID != "call_b" || tc.Function.Name != "ping" || tc.Function.Arguments != "{}" {
+		t.Fatalf("call 1=%+v", tc)
+	}
+	if acc.FinishReason() != "tool_calls" || acc.Usage() == nil || acc.Usage().TotalTokens != 12 {
+		t.Fatalf("finish=%q usage=%+v", acc.FinishReason(), acc.Usage())
+	}
+}
+
+func TestStreamAccumulator_RejectsHugeToolCallIndex(t *testing.T) {
+	var acc StreamAccumulator
+	var c StreamChunk
+	if err := json.Unmarshal([]byte(`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":1000000000,"function":{"name":"ping"}}]}}]}`), &c); err != nil {
+		t.Fatal(err)
+	}
+	if err := acc.Add(c); err == nil || !strings.Contains(err.Error(), "exceeds the limit of 128 calls") {
+		t.Fatalf("err=%v", err)
+	}
+	if m := acc.Message(); len(m.ToolCalls) != 0 {
+		t.Fatalf("calls grown: %d", len(m.ToolCalls))
+	}
+}
+
+func TestStreamChat_DeliversToolCallDeltas(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"tool_calls\":[{\"index\":0,\"id\":\"c1\",\"function\":{\"name\":\"ping\",\"arguments\":\"{\\\"host\\\"\"}}]}}]}\n\n" + //nolint:errcheck
+			"data: {\"choices\":[{\"index\":0,\"delta\":{\"tool_calls\":[{\"index\":0,\"function\":{\"arguments\":\":\\\"a\\\"}\"}}]}}]}\n\n" +
+			"data: [DONE]\n\n"))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "", 5*time.Second)
+	var acc StreamAccumulator
+	if err := c.StreamChat(context.Background(), ChatCompletionsRequest{Model: "m"}, func(ch StreamChunk) error {
+		return acc.Add(ch)
+	}); err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	m := acc.Message()
+	if len(m.ToolCalls) != 1 || m.ToolCalls[0].ID != "c1" || m.ToolCalls[0].Function.Arguments != `{"host":"a"}` {
+		t.Fatalf("message=%+v", m)
+	}
+}
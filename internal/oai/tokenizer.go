@@ -0,0 +1,108 @@
+package oai
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Tokenizer counts the tokens a model family spends on a piece of text.
+type Tokenizer interface {
+	CountTokens(text string) int
+}
+
+// HeuristicTokenizer assumes about four characters per token. It is the
+// fallback for model families without a registered tokenizer.
+type HeuristicTokenizer struct{}
+
+// CountTokens implements Tokenizer.
+func (HeuristicTokenizer) CountTokens(text string) int {
+	return int(math.Ceil(float64(len(text)) / 4.0))
+}
+
+type tokenizerEntry struct {
+	prefix string
+	tok    Tokenizer
+}
+
+var tokenizers = struct {
+	sync.RWMutex
+	entries []tokenizerEntry // longest prefix first
+}{}
+
+// RegisterTokenizer makes tok count tokens for model ids starting with
+// prefix (case-insensitive). The longest matching prefix wins, and a later
+// registration replaces an earlier one for the same prefix.
+func RegisterTokenizer(prefix string, tok Tokenizer) {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	tokenizers.Lock()
+	defer tokenizers.Unlock()
+	for i, e := range tokenizers.entries {
+		if e.prefix == prefix {
+			tokenizers.entries[i].tok = tok
+			return
+		}
+	}
+	tokenizers.entries = append(tokenizers.entries, tokenizerEntry{prefix: prefix, tok: tok})
+	sort.SliceStable(tokenizers.entries, func(i, j int) bool {
+		return len(tokenizers.entries[i].prefix) > len(tokenizers.entries[j].prefix)
+	})
+}
+
+// TokenizerForModel returns the tokenizer registered for model, or
+// HeuristicTokenizer when none matches.
+func TokenizerForModel(model string) Tokenizer {
+	id := strings.ToLower(strings.TrimSpace(model))
+	// Provider-qualified ids such as "openai/gpt-4o" match on the model part
+	if i := strings.LastIndexByte(id, '/'); i >= 0 {
+		id = id[i+1:]
+	}
+	tokenizers.RLock()
+	defer tokenizers.RUnlock()
+	if id != "" {
+		for _, e := range tokenizers.entries {
+			if strings.HasPrefix(id, e.prefix) {
+				return e.tok
+			}
+		}
+	}
+	return HeuristicTokenizer{}
+}
+
+// CountTokens returns the prompt tokens of messages for model using its
+// tokenizer (see TokenizerForModel), plus a small fixed overhead per message
+// and per tool call for roles and formatting. Unknown models get the same
+// result as EstimateTokens.
+func CountTokens(model string, messages []Message) int {
+	return CountTokensWith(TokenizerForModel(model), messages)
+}
+
+// CountTokensWith is CountTokens with an explicit tokenizer.
+func CountTokensWith(tok Tokenizer, messages []Message) int {
+	const perMessageOverheadTokens = 4
+	const perToolCallOverheadTokens = 8
+
+	total := 0
+	for _, msg := range messages {
+		for _, s := range []string{msg.Content, msg.Name, msg.ToolCallID} {
+			if s != "" {
+				total += tok.CountTokens(s)
+			}
+		}
+		for _, tc := range msg.ToolCalls {
+			total += perToolCallOverheadTokens
+			for _, s := range []string{tc.Function.Name, tc.Function.Arguments} {
+				if s != "" {
+					total += tok.CountTokens(s)
+				}
+			}
+		}
+		total += perMessageOverheadTokens
+	}
+	// At least one token per message in extreme edge cases
+	if total < len(messages) {
+		total = len(messages)
+	}
+	return total
+}
@@ -0,0 +1,47 @@
+package oai
+
+import "testing"
+
+type fixedTokenizer int
+
+func (f fixedTokenizer) CountTokens(string) int { return int(f) }
+
+func TestTokenizerForModel(t *testing.T) {
+	if _, ok := TokenizerForModel("some-local-llama").(HeuristicTokenizer); !ok {
+		t.Fatal("unknown model should use the heuristic")
+	}
+	if _, ok := TokenizerForModel("").(HeuristicTokenizer); !ok {
+		t.Fatal("empty model should use the heuristic")
+	}
+	// "hello world" is two tokens in both OpenAI encodings, three by heuristic
+	for _, model := range []string{"gpt-4o-mini", "GPT-5", "openai/gpt-4-turbo", "oss-gpt-20b"} {
+		if got := TokenizerForModel(model).CountTokens("hello world"); got != 2 {
+			t.Errorf("%s: got %d tokens", model, got)
+		}
+	}
+	// The longest registered prefix wins
+	RegisterTokenizer("gpt-4o-mini-custom", fixedTokenizer(7))
+	if got := TokenizerForModel("gpt-4o-mini-custom-1").CountTokens("x"); got != 7 {
+		t.Errorf("custom prefix: got %d", got)
+	}
+	if got := TokenizerForModel("gpt-4o-mini").CountTokens("hello world"); got != 2 {
+		t.Errorf("shorter prefix overridden: got %d", got)
+	}
+}
+
+func TestCountTokens(t *testing.T) {
+	msgs := []Message{
+		{Role: RoleUser, Content: "hello world"},
+		{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "c1", Function: ToolCallFunction{Name: "ping", Arguments: "{}"}}}},
+	}
+	if got, want := CountTokens("unknown-model", msgs), EstimateTokens(msgs); got != want {
+		t.Fatalf("fallback=%d want heuristic %d", got, want)
+	}
+	// 2 content tokens + per-message overhead, then tool call overhead + name + args
+	if got := CountTokensWith(fixedTokenizer(1), msgs); got != 1+4+8+1+1+4 {
+		t.Fatalf("fixed count=%d", got)
+	}
+	if got := ClampCompletionCap("gpt-4o", msgs, 1000, 100); got != 100-CountTokens("gpt-4o", msgs)-32 {
+		t.Fatalf("clamp=%d", got)
+	}
+}
@@ -0,0 +1,43 @@
+package oai
+
+import (
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+// tiktokenTokenizer counts with an OpenAI BPE encoding embedded in the
+// binary. The encoding is loaded on first use.
+type tiktokenTokenizer struct {
+	encoding string
+	once     sync.Once
+	enc      *tiktoken.Tiktoken
+}
+
+// CountTokens implements Tokenizer, falling back to the heuristic when the
+// encoding cannot be loaded.
+func (t *tiktokenTokenizer) CountTokens(text string) int {
+	t.once.Do(func() {
+		enc, err := tiktoken.GetEncoding(t.encoding)
+		if err == nil {
+			t.enc = enc
+		}
+	})
+	if t.enc == nil {
+		return HeuristicTokenizer{}.CountTokens(text)
+	}
+	return len(t.enc.EncodeOrdinary(text))
+}
+
+func init() {
+	tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+	o200k := &tiktokenTokenizer{encoding: tiktoken.MODEL_O200K_BASE}
+	cl100k := &tiktokenTokenizer{encoding: tiktoken.MODEL_CL100K_BASE}
+	for _, prefix := range []string{"gpt-5", "gpt-4.1", "gpt-4.5", "gpt-4o", "o1", "o3", "o4", "gpt-oss", "oss-gpt", "chatgpt-4o"} {
+		RegisterTokenizer(prefix, o200k)
+	}
+	for _, prefix := range []string{"gpt-4", "gpt-3.5", "text-embedding-3", "text-embedding-ada-002"} {
+		RegisterTokenizer(prefix, cl100k)
+	}
+}
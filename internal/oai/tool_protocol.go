@@ -0,0 +1,222 @@
+package oai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Tool protocols select how tool specs and calls travel over the wire.
+const (
+	// ToolProtocolNative uses tools/tool_calls (default).
+	ToolProtocolNative = "native"
+	// ToolProtocolFunctions uses the legacy functions/function_call fields.
+	ToolProtocolFunctions = "functions"
+	// ToolProtocolText renders tools into the system prompt and parses fenced
+	// ```tool blocks from assistant text.
+	ToolProtocolText = "text"
+)
+
+// RoleFunction is the legacy role for function results.
+const RoleFunction = "function"
+
+// ApplyFunctionsProtocol rewrites a native request for servers that only
+// understand legacy function calling. Tools become functions, and each
+// assistant tool_calls turn is expanded into one function_call message per
+// call followed by its result as a role:"function" message.
+func ApplyFunctionsProtocol(req ChatCompletionsRequest) ChatCompletionsRequest {
+	out := req
+	if len(req.Tools) > 0 {
+		out.Functions = make([]ToolFunction, 0, len(req.Tools))
+		for _, t := range req.Tools {
+			out.Functions = append(out.Functions, t.Function)
+		}
+		out.FunctionCall = "auto"
+	}
+	out.Tools = nil
+	out.ToolChoice = ""
+	msgs := make([]Message, 0, len(req.Messages))
+	for i := 0; i < len(req.Messages); i++ {
+		m := req.Messages[i]
+		if m.Role != RoleAssistant || len(m.ToolCalls) == 0 {
+			if m.Role == RoleTool {
+				msgs = append(msgs, Message{Role: RoleFunction, Name: m.Name, Content: m.Content})
+				continue
+			}
+			msgs = append(msgs, m)
+			continue
+		}
+		// Gather the contiguous tool results that answer this assistant turn
+		results := map[string]Message{}
+		j := i + 1
+		for ; j < len(req.Messages) && req.Messages[j].Role == RoleTool; j++ {
+			results[req.Messages[j].ToolCallID] = req.Messages[j]
+		}
+		for k, tc := range m.ToolCalls {
+			fc := tc.Function
+			am := Message{Role: RoleAssistant, FunctionCall: &fc}
+			if k == 0 {
+				am.Content = m.Content
+			}
+			msgs = append(msgs, am)
+			if r, ok := results[tc.ID]; ok {
+				name := r.Name
+				if name == "" {
+					name = tc.Function.Name
+				}
+				msgs = append(msgs, Message{Role: RoleFunction, Name: name, Content: r.Content})
+				delete(results, tc.ID)
+			}
+		}
+		i = j - 1
+	}
+	out.Messages = msgs
+	return out
+}
+
+// AdoptFunctionCall converts a legacy function_call on a response message into
+// a single native tool call with the given id so the agent loop can treat both
+// protocols uniformly.
+func AdoptFunctionCall(m Message, id string) Message {
+	if m.FunctionCall == nil || len(m.ToolCalls) > 0 {
+		m.FunctionCall = nil
+		return m
+	}
+	m.ToolCalls = []ToolCall{{ID: id, Type: "function", Function: *m.FunctionCall}}
+	m.FunctionCall = nil
+	return m
+}
+
+// RenderTextToolPrompt describes the available tools and the fenced block
+// format the model must use under the text protocol.
+func RenderTextToolPrompt(tools []Tool) string {
+	var b strings.Builder
+	b.WriteString("You can call tools. To call a tool, reply with one or more fenced blocks exactly like:\n")
+	b.WriteString("```tool\n{\"name\": \"<tool name>\", \"arguments\": {<arguments matching the tool parameters>}}\n```\n")
+	b.WriteString("Use only the tools listed below. Tool results arrive in the next user message. When you are done, reply with the final answer and no tool block.\n\nTools:\n")
+	for _, t := range tools {
+		b.WriteString("- ")
+		b.WriteString(t.Function.Name)
+		if d := strings.TrimSpace(t.Function.Description); d != "" {
+			b.WriteString(": ")
+			b.WriteString(d)
+		}
+		b.WriteString("\n")
+		if len(bytes.TrimSpace(t.Function.Parameters)) > 0 {
+			var buf bytes.Buffer
+			if err := json.Compact(&buf, t.Function.Parameters); err == nil {
+				b.WriteString("  parameters: ")
+				b.WriteString(buf.String())
+				b.WriteString("\n")
+			}
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// ApplyTextProtocol rewrites a native request for models without tool
+// calling. The tool prompt is appended to the first system message (or
+// inserted as one), prior tool calls are rendered back as ```tool blocks in
+// assistant content, and tool results become user messages.
+func ApplyTextProtocol(req ChatCompletionsRequest) ChatCompletionsRequest {
+	out := req
+	out.Tools = nil
+	out.ToolChoice = ""
+	prompt := ""
+	if len(req.Tools) > 0 {
+		prompt = RenderTextToolPrompt(req.Tools)
+	}
+	msgs := make([]Message, 0, len(req.Messages)+1)
+	injected := prompt == ""
+	for _, m := range req.Messages {
+		switch {
+		case m.Role == RoleSystem && !injected:
+			m.Content = strings.TrimRight(m.Content, "\n") + "\n\n" + prompt
+			injected = true
+		case m.Role == RoleAssistant && len(m.ToolCalls) > 0:
+			if strings.TrimSpace(m.Content) == "" {
+				m.Content = renderToolBlocks(m.ToolCalls)
+			}
+			m.ToolCalls = nil
+		case m.Role == RoleTool:
+			m = Message{Role: RoleUser, Content: fmt.Sprintf("Tool result for %s (id %s):\n%s", m.Name, m.ToolCallID, m.Content)}
+		}
+		msgs = append(msgs, m)
+	}
+	if !injected {
+		msgs = append([]Message{{Role: RoleSystem, Content: prompt}}, msgs...)
+	}
+	out.Messages = msgs
+	return out
+}
+
+func renderToolBlocks(calls []ToolCall) string {
+	var parts []string
+	for _, tc := range calls {
+		args := strings.TrimSpace(tc.Function.Arguments)
+		if args == "" {
+			args = "{}"
+		}
+		name, err := json.Marshal(tc.Function.Name)
+		if err != nil {
+			continue
+		}
+		parts = append(parts, "```tool\n{\"name\": "+string(name)+", \"arguments\": "+args+"}\n```")
+	}
+	return strings.Join(parts, "\n")
+}
+
+var toolBlockRe = regexp.MustCompile("(?s)```tool[ \\t]*\\r?\\n(.*?)```")
+
+// ParseTextToolCalls extracts ```tool blocks from assistant text. Each block
+// must be a single JSON object with exactly the keys "name" (a tool listed in
+// known) and optional "arguments" (a JSON object). Any malformed block fails
+// the whole parse so no partial set of calls is executed. Calls receive ids
+// of the form <idPrefix>_<n>.
+func ParseTextToolCalls(content string, known map[string]bool, idPrefix string) ([]ToolCall, error) {
+	matches := toolBlockRe.FindAllStringSubmatch(content, -1)
+	if len(matches) == 0 {
+		return nil, nil
+	}
+	calls := make([]ToolCall, 0, len(matches))
+	for i, m := range matches {
+		var block struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		dec := json.NewDecoder(strings.NewReader(strings.TrimSpace(m[1])))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&block); err != nil {
+			return nil, fmt.Errorf("tool block %d: invalid JSON: %v", i+1, err)
+		}
+		if dec.More() {
+			return nil, fmt.Errorf("tool block %d: expected a single JSON object", i+1)
+		}
+		name := strings.TrimSpace(block.Name)
+		if name == "" {
+			return nil, fmt.Errorf("tool block %d: name is required", i+1)
+		}
+		if !known[name] {
+			return nil, fmt.Errorf("tool block %d: unknown tool %q", i+1, name)
+		}
+		args := bytes.TrimSpace(block.Arguments)
+		if len(args) == 0 || string(args) == "null" {
+			args = []byte("{}")
+		}
+		if args[0] != '{' {
+			return nil, fmt.Errorf("tool block %d: arguments must be a JSON object", i+1)
+		}
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, args); err != nil {
+			return nil, fmt.Errorf("tool block %d: invalid arguments: %v", i+1, err)
+		}
+		calls = append(calls, ToolCall{
+			ID:       fmt.Sprintf("%s_%d", idPrefix, i+1),
+			Type:     "function",
+			Function: ToolCallFunction{Name: name, Arguments: buf.String()},
+		})
+	}
+	return calls, nil
+}
@@ -0,0 +1,119 @@
+package oai
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func protocolFixture() ChatCompletionsRequest {
+	return ChatCompletionsRequest{
+		Model: "m",
+		Messages: []Message{
+			{Role: RoleSystem, Content: "sys"},
+			{Role: RoleUser, Content: "hi"},
+			{Role: RoleAssistant, ToolCalls: []ToolCall{
+				{ID: "a", Type: "function", Function: ToolCallFunction{Name: "get_time", Arguments: `{"tz":"UTC"}`}},
+				{ID: "b", Type: "function", Function: ToolCallFunction{Name: "echo", Arguments: `{}`}},
+			}},
+			{Role: RoleTool, ToolCallID: "a", Name: "get_time", Content: `{"t":1}`},
+			{Role: RoleTool, ToolCallID: "b", Name: "echo", Content: `{"e":2}`},
+		},
+		Tools: []Tool{
+			{Type: "function", Function: ToolFunction{Name: "get_time", Description: "Get time", Parameters: json.RawMessage(`{"type": "object"}`)}},
+			{Type: "function", Function: ToolFunction{Name: "echo"}},
+		},
+		ToolChoice: "auto",
+	}
+}
+
+func TestApplyFunctionsProtocol_ExpandsToolTurns(t *testing.T) {
+	out := ApplyFunctionsProtocol(protocolFixture())
+	if out.Tools != nil || out.ToolChoice != "" || len(out.Functions) != 2 || out.FunctionCall != "auto" {
+		t.Fatalf("unexpected tool fields: %+v", out)
+	}
+	wantRoles := []string{RoleSystem, RoleUser, RoleAssistant, RoleFunction, RoleAssistant, RoleFunction}
+	if len(out.Messages) != len(wantRoles) {
+		t.Fatalf("messages=%d want %d: %+v", len(out.Messages), len(wantRoles), out.Messages)
+	}
+	for i, r := range wantRoles {
+		if out.Messages[i].Role != r {
+			t.Fatalf("msg[%d].role=%q want %q", i, out.Messages[i].Role, r)
+		}
+	}
+	if fc := out.Messages[4].FunctionCall; fc == nil || fc.Name != "echo" {
+		t.Fatalf("second function_call: %+v", fc)
+	}
+	if out.Messages[5].Name != "echo" || out.Messages[5].Content != `{"e":2}` {
+		t.Fatalf("function result: %+v", out.Messages[5])
+	}
+}
+
+func TestAdoptFunctionCall(t *testing.T) {
+	m := AdoptFunctionCall(Message{Role: RoleAssistant, FunctionCall: &ToolCallFunction{Name: "echo", Arguments: "{}"}}, "fn_1")
+	if m.FunctionCall != nil || len(m.ToolCalls) != 1 || m.ToolCalls[0].ID != "fn_1" || m.ToolCalls[0].Function.Name != "echo" {
+		t.Fatalf("unexpected: %+v", m)
+	}
+}
+
+func TestApplyTextProtocol_RendersPromptAndFlattensTurns(t *testing.T) {
+	out := ApplyTextProtocol(protocolFixture())
+	if out.Tools != nil || out.ToolChoice != "" {
+		t.Fatalf("tools must be stripped")
+	}
+	sys := out.Messages[0].Content
+	if !strings.HasPrefix(sys, "sys\n\n") || !strings.Contains(sys, "- get_time: Get time") || !strings.Contains(sys, `parameters: {"type":"object"}`) {
+		t.Fatalf("system prompt: %q", sys)
+	}
+	asst := out.Messages[2]
+	if len(asst.ToolCalls) != 0 || strings.Count(asst.Content, "```tool") != 2 {
+		t.Fatalf("assistant not rendered as blocks: %+v", asst)
+	}
+	for _, m := range out.Messages[3:] {
+		if m.Role != RoleUser || !strings.HasPrefix(m.Content, "Tool result for ") {
+			t.Fatalf("tool result not converted: %+v", m)
+		}
+	}
+	// Round-trip: the rendered blocks parse back into the same calls
+	calls, err := ParseTextToolCalls(asst.Content, map[string]bool{"get_time": true, "echo": true}, "c")
+	if err != nil || len(calls) != 2 || calls[0].Function.Arguments != `{"tz":"UTC"}` {
+		t.Fatalf("round-trip: calls=%+v err=%v", calls, err)
+	}
+}
+
+func TestApplyTextProtocol_InsertsSystemWhenMissing(t *testing.T) {
+	req := protocolFixture()
+	req.Messages = req.Messages[1:2]
+	out := ApplyTextProtocol(req)
+	if len(out.Messages) != 2 || out.Messages[0].Role != RoleSystem {
+		t.Fatalf("expected inserted system message: %+v", out.Messages)
+	}
+}
+
+func TestParseTextToolCalls(t *testing.T) {
+	known := map[string]bool{"echo": true}
+	calls, err := ParseTextToolCalls("no tools here", known, "c")
+	if err != nil || calls != nil {
+		t.Fatalf("plain text: %v %v", calls, err)
+	}
+	calls, err = ParseTextToolCalls("x\n```tool\n{\"name\":\"echo\"}\n```\n```tool\n{\"name\": \"echo\", \"arguments\": { \"a\" : 1 }}\n```", known, "c")
+	if err != nil || len(calls) != 2 {
+		t.Fatalf("valid blocks: %v %v", calls, err)
+	}
+	if calls[0].ID != "c_1" || calls[0].Function.Arguments != "{}" || calls[1].Function.Arguments != `{"a":1}` {
+		t.Fatalf("unexpected calls: %+v", calls)
+	}
+	bad := []string{
+		"```tool\nnot json\n```",
+		"```tool\n{\"name\":\"nope\"}\n```",
+		"```tool\n{\"arguments\":{}}\n```",
+		"```tool\n{\"name\":\"echo\",\"arguments\":[1]}\n```",
+		"```tool\n{\"name\":\"echo\",\"extra\":true}\n```",
+		"```tool\n{\"name\":\"echo\"}{\"name\":\"echo\"}\n```",
+	}
+	for _, in := range bad {
+		if _, err := ParseTextToolCalls(in, known, "c"); err == nil {
+			t.Fatalf("expected error for %q", in)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package oai
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/hyperifyio/goagent/internal/tracing"
+)
+
+// startChatSpan opens a client span for one chat completion call, named and
+// attributed after the OpenTelemetry GenAI conventions.
+func startChatSpan(ctx context.Context, req ChatCompletionsRequest) (context.Context, *tracing.Span) {
+	attrs := []tracing.Attr{tracing.String("gen_ai.request.model", req.Model), tracing.Int("gen_ai.request.messages", len(req.Messages))}
+	if stage := auditStageFromContext(ctx); stage != "" {
+		attrs = append(attrs, tracing.String("goagent.stage", stage))
+	}
+	return tracing.StartKind(ctx, "chat.request", tracing.KindClient, attrs...)
+}
+
+// usageAttrs maps token accounting to span attributes.
+func usageAttrs(u *Usage) []tracing.Attr {
+	return []tracing.Attr{
+		tracing.Int("gen_ai.usage.input_tokens", u.PromptTokens),
+		tracing.Int("gen_ai.usage.output_tokens", u.CompletionTokens),
+		tracing.Int("gen_ai.usage.total_tokens", u.TotalTokens),
+	}
+}
+
+// setTraceHeaders propagates the current span to the server as a W3C
+// traceparent header and records the attempt number.
+func setTraceHeaders(ctx context.Context, r *http.Request, attempt int) {
+	span := tracing.FromContext(ctx)
+	if tp := span.TraceParent(); tp != "" {
+		r.Header.Set("traceparent", tp)
+	}
+	span.SetAttributes(tracing.Int("http.attempts", attempt))
+}
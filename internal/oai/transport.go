@@ -0,0 +1,150 @@
+package oai
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TransportOptions tunes the shared HTTP transport used by every API client
+// in the process (pre-stage, main chat, and image calls).
+type TransportOptions struct {
+	// MaxIdleConns bounds pooled idle connections, total and per host; zero
+	// keeps Go's defaults (unlimited total, two per host).
+	MaxIdleConns int
+	// KeepAlive is the TCP keep-alive period; zero disables HTTP keep-alives
+	// so every request opens a fresh connection.
+	KeepAlive time.Duration
+	// IdleConnTimeout closes pooled connections idle for longer than this.
+	IdleConnTimeout time.Duration
+	// TLSMinVersion is a crypto/tls version constant (e.g., tls.VersionTLS12).
+	TLSMinVersion uint16
+	// CABundlePath optionally names a PEM file whose certificates are trusted
+	// in addition to the system roots.
+	CABundlePath string
+}
+
+// DefaultTransportOptions mirrors the CLI defaults.
+func DefaultTransportOptions() TransportOptions {
+	return TransportOptions{
+		MaxIdleConns:    100,
+		KeepAlive:       30 * time.Second,
+		IdleConnTimeout: 90 * time.Second,
+		TLSMinVersion:   tls.VersionTLS12,
+	}
+}
+
+// NewTransport builds an HTTP/2-capable transport from opts. Proxies are
+// taken from HTTPS_PROXY/HTTP_PROXY/NO_PROXY.
+func NewTransport(opts TransportOptions) (*http.Transport, error) {
+	tlsCfg := &tls.Config{MinVersion: opts.TLSMinVersion}
+	if tlsCfg.MinVersion == 0 {
+		tlsCfg.MinVersion = tls.VersionTLS12
+	}
+	if p := strings.TrimSpace(opts.CABundlePath); p != "" {
+		pem, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA bundle %s: no PEM certificates found", p)
+		}
+		tlsCfg.RootCAs = pool
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: opts.KeepAlive}
+	if opts.KeepAlive <= 0 {
+		dialer.KeepAlive = -1
+	}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          opts.MaxIdleConns,
+		MaxIdleConnsPerHost:   opts.MaxIdleConns,
+		IdleConnTimeout:       opts.IdleConnTimeout,
+		DisableKeepAlives:     opts.KeepAlive <= 0,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+		TLSClientConfig:       tlsCfg,
+	}, nil
+}
+
+var (
+	sharedMu        sync.Mutex
+	sharedTransport http.RoundTripper
+)
+
+// SharedTransport returns the process-wide transport, creating one with
+// DefaultTransportOptions on first use, so connections are pooled and reused
+// across clients.
+func SharedTransport() http.RoundTripper {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	if sharedTransport == nil {
+		t, err := NewTransport(DefaultTransportOptions())
+		if err != nil {
+			// Defaults never read files; fall back defensively
+			return http.DefaultTransport
+		}
+		sharedTransport = t
+	}
+	return sharedTransport
+}
+
+// ConfigureSharedTransport replaces the process-wide transport. Clients
+// created afterwards use it; call it once at startup before creating clients.
+func ConfigureSharedTransport(opts TransportOptions) error {
+	t, err := NewTransport(opts)
+	if err != nil {
+		return err
+	}
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	if old, ok := sharedTransport.(*http.Transport); ok {
+		old.CloseIdleConnections()
+	}
+	sharedTransport = t
+	return nil
+}
+
+// InterceptSharedTransport wraps the process-wide transport, for example to
+// record or replay every API exchange. Clients created afterwards use the
+// wrapped transport; restore puts the previous one back.
+func InterceptSharedTransport(wrap func(http.RoundTripper) http.RoundTripper) (restore func()) {
+	inner := SharedTransport()
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	sharedTransport = wrap(inner)
+	return func() {
+		sharedMu.Lock()
+		defer sharedMu.Unlock()
+		sharedTransport = inner
+	}
+}
+
+// ParseTLSVersion maps "1.0".."1.3" (optionally prefixed "tls") to a
+// crypto/tls version constant.
+func ParseTLSVersion(s string) (uint16, bool) {
+	v := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), "tls")
+	switch strings.TrimSpace(v) {
+	case "1.0", "10":
+		return tls.VersionTLS10, true
+	case "1.1", "11":
+		return tls.VersionTLS11, true
+	case "1.2", "12":
+		return tls.VersionTLS12, true
+	case "1.3", "13":
+		return tls.VersionTLS13, true
+	}
+	return 0, false
+}
@@ -0,0 +1,68 @@
+package oai
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseTLSVersion(t *testing.T) {
+	for in, want := range map[string]uint16{"1.2": tls.VersionTLS12, "TLS1.3": tls.VersionTLS13, " 13 ": tls.VersionTLS13} {
+		if got, ok := ParseTLSVersion(in); !ok || got != want {
+			t.Fatalf("ParseTLSVersion(%q)=%x,%v", in, got, ok)
+		}
+	}
+	if _, ok := ParseTLSVersion("2.0"); ok {
+		t.Fatalf("expected failure for 2.0")
+	}
+}
+
+func TestNewTransport_Options(t *testing.T) {
+	tr, err := NewTransport(TransportOptions{MaxIdleConns: 7, KeepAlive: 0, TLSMinVersion: tls.VersionTLS13})
+	if err != nil {
+		t.Fatalf("NewTransport: %v", err)
+	}
+	if tr.MaxIdleConns != 7 || tr.MaxIdleConnsPerHost != 7 || !tr.DisableKeepAlives || !tr.ForceAttemptHTTP2 {
+		t.Fatalf("unexpected transport: %+v", tr)
+	}
+	if tr.TLSClientConfig.MinVersion != tls.VersionTLS13 || tr.Proxy == nil {
+		t.Fatalf("unexpected TLS/proxy config")
+	}
+	if _, err := NewTransport(TransportOptions{CABundlePath: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Fatalf("expected error for missing CA bundle")
+	}
+}
+
+// A CA bundle containing the test server's certificate makes TLS calls succeed
+// through the shared transport used by NewClient.
+func TestConfigureSharedTransport_CABundle(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`)) //nolint:errcheck
+	}))
+	defer srv.Close()
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caPath, pemBytes, 0o644); err != nil {
+		t.Fatalf("write ca: %v", err)
+	}
+	opts := DefaultTransportOptions()
+	opts.CABundlePath = caPath
+	if err := ConfigureSharedTransport(opts); err != nil {
+		t.Fatalf("configure: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := ConfigureSharedTransport(DefaultTransportOptions()); err != nil {
+			t.Errorf("restore: %v", err)
+		}
+	})
+	c := NewClient(srv.URL, "", 5*time.Second)
+	out, err := c.CreateChatCompletion(t.Context(), ChatCompletionsRequest{Model: "m", Messages: []Message{{Role: RoleUser, Content: "hi"}}})
+	if err != nil || len(out.Choices) != 1 {
+		t.Fatalf("TLS call via CA bundle failed: %v", err)
+	}
+}
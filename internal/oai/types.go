@@ -3,6 +3,7 @@ package oai
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"strings"
 )
 
@@ -32,6 +33,15 @@ type Message struct {
 	Channel string `json:"channel,omitempty"`
 	// The OpenAI-compatible schema also allows "tool_calls" on assistant messages.
 	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// FunctionCall carries a single call under the legacy function-calling
+	// protocol. It is only populated on the wire when -tool-protocol=functions.
+	FunctionCall *ToolCallFunction `json:"function_call,omitempty"`
+	// Parts are non-text content such as attached images. When present the
+	// message is sent with array-form content (see MarshalJSON).
+	Parts []ContentPart `json:"-"`
+	// CacheControl marks the message as the end of a cacheable prefix; it is
+	// sent on the last text part of array-form content (see MarshalJSON).
+	CacheControl *CacheControl `json:"-"`
 }
 
 // ToolCall mirrors the OpenAI tool call structure.
@@ -50,6 +60,8 @@ type ToolCallFunction struct {
 type Tool struct {
 	Type     string       `json:"type"`
 	Function ToolFunction `json:"function"`
+	// CacheControl marks the tool list up to this tool as cacheable
+	CacheControl *CacheControl `json:"cache_control,omitempty"`
 }
 
 type ToolFunction struct {
@@ -65,10 +77,17 @@ type ChatCompletionsRequest struct {
 	Messages   []Message `json:"messages"`
 	Tools      []Tool    `json:"tools,omitempty"`
 	ToolChoice string    `json:"tool_choice,omitempty"`
+	// Functions and FunctionCall are the legacy function-calling fields used
+	// instead of Tools/ToolChoice when -tool-protocol=functions.
+	Functions    []ToolFunction `json:"functions,omitempty"`
+	FunctionCall string         `json:"function_call,omitempty"`
 	// TopP enables nucleus sampling when provided. One‑knob rule ensures either
 	// top_p or temperature is set, but never both.
 	TopP        *float64 `json:"top_p,omitempty"`
 	Temperature *float64 `json:"temperature,omitempty"`
+	// Seed asks the server for reproducible sampling where it supports it.
+	// Omitted when nil.
+	Seed *int64 `json:"seed,omitempty"`
 	// ResponseFormat requests a specific response format from the model, such as
 	// JSON mode: {"type":"json_object"}. Omitted by default.
 	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
@@ -79,6 +98,45 @@ type ChatCompletionsRequest struct {
 	// When enabled, the server responds with text/event-stream and emits
 	// incremental deltas under choices[].delta.
 	Stream bool `json:"stream,omitempty"`
+	// ChatTemplate names the chat template for servers that choose it per
+	// request (llama.cpp-style and vLLM); omitted when empty.
+	ChatTemplate string `json:"chat_template,omitempty"`
+	// ExtraBody holds provider-specific top-level fields, such as vLLM's
+	// guided_json or use_beam_search, merged into the encoded request. Keys
+	// naming a field above are ignored; see IsRequestField.
+	ExtraBody map[string]json.RawMessage `json:"-"`
+}
+
+// MarshalJSON encodes the request with ExtraBody merged in at the top level.
+func (r ChatCompletionsRequest) MarshalJSON() ([]byte, error) {
+	type plain ChatCompletionsRequest
+	b, err := json.Marshal(plain(r))
+	if err != nil || len(r.ExtraBody) == 0 {
+		return b, err
+	}
+	var merged map[string]json.RawMessage
+	if err := json.Unmarshal(b, &merged); err != nil {
+		return nil, err
+	}
+	for k, v := range r.ExtraBody {
+		if !IsRequestField(k) {
+			merged[k] = v
+		}
+	}
+	return json.Marshal(merged)
+}
+
+// IsRequestField reports whether name is a top-level field that
+// ChatCompletionsRequest itself encodes, and so cannot come from ExtraBody.
+func IsRequestField(name string) bool {
+	t := reflect.TypeOf(ChatCompletionsRequest{})
+	for i := 0; i < t.NumField(); i++ {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if tag != "" && tag != "-" && tag == name {
+			return true
+		}
+	}
+	return false
 }
 
 // ResponseFormat models the OpenAI response_format option.
@@ -87,6 +145,14 @@ type ResponseFormat struct {
 	Type string `json:"type"`
 }
 
+// mentionsUnsupportedSeed detects API error messages rejecting the seed
+// parameter.
+func mentionsUnsupportedSeed(body string) bool {
+	s := strings.ToLower(body)
+	return strings.Contains(s, "seed") && (strings.Contains(s, "unsupported") || strings.Contains(s, "invalid") ||
+		strings.Contains(s, "unrecognized") || strings.Contains(s, "unknown") || strings.Contains(s, "not permitted"))
+}
+
 // includesTemperature reports whether the request currently has a temperature set.
 func includesTemperature(req ChatCompletionsRequest) bool { return req.Temperature != nil }
 
@@ -163,6 +229,24 @@ type ChatCompletionsResponse struct {
 	Created int64                           `json:"created"`
 	Model   string                          `json:"model"`
 	Choices []ChatCompletionsResponseChoice `json:"choices"`
+	// Usage reports token accounting when the server provides it.
+	Usage *Usage `json:"usage,omitempty"`
+	// SystemFingerprint identifies the backend configuration that served
+	// the request; a change between runs explains differing seeded output.
+	SystemFingerprint string `json:"system_fingerprint,omitempty"`
+}
+
+// Usage is the token accounting block of a chat completions response.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+	// PromptTokensDetails reports cache hits, as OpenAI and compatible
+	// proxies send them; the cached tokens are part of PromptTokens
+	PromptTokensDetails *PromptTokensDetails `json:"prompt_tokens_details,omitempty"`
+	// CacheCreationInputTokens are prompt tokens written to the cache, as
+	// Anthropic-backed servers report them
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
 }
 
 type ChatCompletionsResponseChoice struct {
@@ -180,10 +264,25 @@ type StreamChunk struct {
 	Choices []struct {
 		Index int `json:"index"`
 		Delta struct {
-			Role    string `json:"role"`
-			Channel string `json:"channel"`
-			Content string `json:"content"`
+			Role      string                `json:"role"`
+			Channel   string                `json:"channel"`
+			Content   string                `json:"content"`
+			ToolCalls []StreamToolCallDelta `json:"tool_calls,omitempty"`
 		} `json:"delta"`
 		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
+	// Usage is sent on the last chunk by servers that support it.
+	Usage *Usage `json:"usage,omitempty"`
+	// SystemFingerprint is repeated on every chunk by servers that send it.
+	SystemFingerprint string `json:"system_fingerprint,omitempty"`
+}
+
+// StreamToolCallDelta is one fragment of a streamed tool call. Fragments
+// sharing an Index belong to the same call: the first usually carries the ID
+// and function name, and later ones append to the arguments.
+type StreamToolCallDelta struct {
+	Index    int              `json:"index"`
+	ID       string           `json:"id,omitempty"`
+	Type     string           `json:"type,omitempty"`
+	Function ToolCallFunction `json:"function"`
 }
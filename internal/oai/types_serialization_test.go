@@ -40,3 +40,32 @@ func TestChatCompletionsRequest_MaxTokens_IncludedWhenSet(t *testing.T) {
 		t.Fatalf("expected max_tokens=123, got: %s", s)
 	}
 }
+
+func TestChatCompletionsRequest_ExtraBody_Merged(t *testing.T) {
+	req := ChatCompletionsRequest{
+		Model:    "m",
+		Messages: []Message{{Role: RoleUser, Content: "hi"}},
+		ExtraBody: map[string]json.RawMessage{
+			"guided_regex":    json.RawMessage(`"(yes|no)"`),
+			"use_beam_search": json.RawMessage(`true`),
+			"model":           json.RawMessage(`"override"`),
+		},
+	}
+	b, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got["guided_regex"] != "(yes|no)" || got["use_beam_search"] != true || got["model"] != "m" {
+		t.Fatalf("unexpected body: %s", b)
+	}
+	if _, ok := got["ExtraBody"]; ok {
+		t.Fatalf("ExtraBody leaked as a field: %s", b)
+	}
+	if !IsRequestField("max_tokens") || IsRequestField("guided_json") {
+		t.Fatal("IsRequestField classification wrong")
+	}
+}
@@ -1,6 +1,10 @@
 package oai
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
 
 // ValidateMessageSequence enforces that any tool message responds to the most
 // recent assistant message that contains tool_calls and that the tool_call_id
@@ -37,6 +41,127 @@ func ValidateMessageSequence(messages []Message) error {
 	return nil
 }
 
+// Repair action kinds reported by RepairMessageSequence.
+const (
+	RepairDropOrphan     = "drop_orphan"
+	RepairDropDuplicate  = "drop_duplicate"
+	RepairReorder        = "reorder"
+	RepairSynthesizeStub = "synthesize_stub"
+)
+
+// RepairAction is one change RepairMessageSequence made. Index is the
+// position in the input of the tool message, or for a synthesized stub,
+// of the assistant message whose call lacked a result.
+type RepairAction struct {
+	Kind       string `json:"kind"`
+	Index      int    `json:"index"`
+	ToolCallID string `json:"tool_call_id,omitempty"`
+	Detail     string `json:"detail"`
+}
+
+func (a RepairAction) String() string {
+	return fmt.Sprintf("index %d: %s", a.Index, a.Detail)
+}
+
+// RepairMessageSequence returns a copy of messages that passes
+// ValidateMessageSequence, with every change it made:
+//
+//   - tool messages without a tool_call_id, or whose id no assistant tool
+//     call has, are dropped, as are second results for the same id;
+//   - each assistant message with tool_calls is followed directly by its
+//     results in call order, moving results that were separated from it;
+//   - calls without a result get an error stub result.
+//
+// A valid sequence is returned unchanged with no actions.
+func RepairMessageSequence(messages []Message) ([]Message, []RepairAction) {
+	var actions []RepairAction
+	// First result per id; later ones are duplicates
+	result := map[string]int{}
+	called := map[string]bool{}
+	for _, m := range messages {
+		if m.Role == RoleAssistant {
+			for _, tc := range m.ToolCalls {
+				if tc.ID != "" {
+					called[tc.ID] = true
+				}
+			}
+		}
+	}
+	for i, m := range messages {
+		if m.Role != RoleTool {
+			continue
+		}
+		switch {
+		case m.ToolCallID == "":
+			actions = append(actions, RepairAction{Kind: RepairDropOrphan, Index: i, Detail: "dropped tool message without tool_call_id"})
+		case !called[m.ToolCallID]:
+			actions = append(actions, RepairAction{Kind: RepairDropOrphan, Index: i, ToolCallID: m.ToolCallID,
+				Detail: fmt.Sprintf("dropped tool message with tool_call_id %q: no assistant tool call has that id", m.ToolCallID)})
+		default:
+			if first, dup := result[m.ToolCallID]; dup {
+				actions = append(actions, RepairAction{Kind: RepairDropDuplicate, Index: i, ToolCallID: m.ToolCallID,
+					Detail: fmt.Sprintf("dropped duplicate result for tool_call_id %q (first at index %d)", m.ToolCallID, first)})
+				continue
+			}
+			result[m.ToolCallID] = i
+		}
+	}
+
+	out := make([]Message, 0, len(messages))
+	placed := map[string]bool{}
+	for a, m := range messages {
+		if m.Role == RoleTool {
+			continue
+		}
+		out = append(out, m)
+		if m.Role != RoleAssistant {
+			continue
+		}
+		last := a
+		for _, tc := range m.ToolCalls {
+			if tc.ID == "" || placed[tc.ID] {
+				continue
+			}
+			placed[tc.ID] = true
+			j, ok := result[tc.ID]
+			if !ok {
+				stub, _ := json.Marshal(map[string]string{"error": "no result was recorded for this tool call"}) //nolint:errcheck
+				out = append(out, Message{Role: RoleTool, Name: tc.Function.Name, ToolCallID: tc.ID, Content: string(stub)})
+				actions = append(actions, RepairAction{Kind: RepairSynthesizeStub, Index: a, ToolCallID: tc.ID,
+					Detail: fmt.Sprintf("synthesized an error result for tool call %q (%s) that had none", tc.ID, tc.Function.Name)})
+				continue
+			}
+			if j < last || separated(messages, a, j) {
+				actions = append(actions, RepairAction{Kind: RepairReorder, Index: j, ToolCallID: tc.ID,
+					Detail: fmt.Sprintf("moved result for tool_call_id %q directly after its assistant message at index %d", tc.ID, a)})
+			}
+			if j > last {
+				last = j
+			}
+			out = append(out, messages[j])
+		}
+	}
+	if len(actions) == 0 {
+		return messages, nil
+	}
+	sort.SliceStable(actions, func(i, j int) bool { return actions[i].Index < actions[j].Index })
+	return out, actions
+}
+
+// separated reports whether a message other than a tool result lies
+// between the assistant message at a and its result at j.
+func separated(messages []Message, a, j int) bool {
+	if j < a {
+		return true
+	}
+	for k := a + 1; k < j; k++ {
+		if messages[k].Role != RoleTool {
+			return true
+		}
+	}
+	return false
+}
+
 // ValidatePrestageHarmony enforces the pre-stage output contract for Harmony
 // messages. The contract requires that the array contains only roles "system"
 // and/or "developer". Messages MUST NOT include role "tool", role
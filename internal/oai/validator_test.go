@@ -0,0 +1,78 @@
+package oai
+
+import (
+	"strings"
+	"testing"
+)
+
+func call(id, name string) ToolCall {
+	return ToolCall{ID: id, Type: "function", Function: ToolCallFunction{Name: name, Arguments: "{}"}}
+}
+
+func TestRepairMessageSequence_ValidIsUnchanged(t *testing.T) {
+	in := []Message{
+		{Role: RoleUser, Content: "hi"},
+		{Role: RoleAssistant, ToolCalls: []ToolCall{call("a", "t1"), call("b", "t2")}},
+		{Role: RoleTool, ToolCallID: "a", Content: "1"},
+		{Role: RoleTool, ToolCallID: "b", Content: "2"},
+		{Role: RoleAssistant, Content: "done"},
+	}
+	out, actions := RepairMessageSequence(in)
+	if len(actions) != 0 || len(out) != len(in) {
+		t.Fatalf("actions=%v out=%v", actions, out)
+	}
+}
+
+func TestRepairMessageSequence_FixesEveryProblem(t *testing.T) {
+	in := []Message{
+		{Role: RoleTool, ToolCallID: "ghost", Content: "orphan"},                                        // 0: no such call
+		{Role: RoleUser, Content: "hi"},                                                                 // 1
+		{Role: RoleAssistant, ToolCalls: []ToolCall{call("a", "t1"), call("b", "t2"), call("c", "t3")}}, // 2
+		{Role: RoleTool, ToolCallID: "b", Content: "2"},                                                 // 3: before a
+		{Role: RoleUser, Content: "interjection"},                                                       // 4
+		{Role: RoleTool, ToolCallID: "a", Content: "1"},                                                 // 5: separated
+		{Role: RoleTool, ToolCallID: "a", Content: "1 again"},                                           // 6: duplicate
+		{Role: RoleTool, Content: "no id"},                                                              // 7
+	}
+	if ValidateMessageSequence(in) == nil {
+		t.Fatal("input should be invalid")
+	}
+	out, actions := RepairMessageSequence(in)
+	if err := ValidateMessageSequence(out); err != nil {
+		t.Fatalf("repaired sequence invalid: %v\n%v", err, out)
+	}
+	want := []Message{
+		{Role: RoleUser, Content: "hi"},
+		in[2],
+		{Role: RoleTool, ToolCallID: "a", Content: "1"},
+		{Role: RoleTool, ToolCallID: "b", Content: "2"},
+		{Role: RoleTool, ToolCallID: "c", Name: "t3"},
+		{Role: RoleUser, Content: "interjection"},
+	}
+	if len(out) != len(want) {
+		t.Fatalf("out=%+v", out)
+	}
+	for i := range want {
+		if out[i].Role != want[i].Role || out[i].ToolCallID != want[i].ToolCallID || (want[i].Content != "" && out[i].Content != want[i].Content) {
+			t.Fatalf("out[%d]=%+v want %+v", i, out[i], want[i])
+		}
+	}
+	if !strings.Contains(out[4].Content, "error") {
+		t.Fatalf("stub content %q", out[4].Content)
+	}
+	kinds := map[string]int{}
+	for _, a := range actions {
+		kinds[a.Kind]++
+	}
+	if kinds[RepairDropOrphan] != 2 || kinds[RepairDropDuplicate] != 1 || kinds[RepairSynthesizeStub] != 1 || kinds[RepairReorder] != 2 {
+		t.Fatalf("actions=%v", actions)
+	}
+	for i := 1; i < len(actions); i++ {
+		if actions[i].Index < actions[i-1].Index {
+			t.Fatalf("actions not ordered by index: %v", actions)
+		}
+	}
+	if s := actions[0].String(); !strings.HasPrefix(s, "index 0: dropped tool message") {
+		t.Fatalf("first action %q", s)
+	}
+}
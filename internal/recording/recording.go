@@ -0,0 +1,243 @@
+// Package recording captures every API exchange and tool run of an agent
+// session to a directory and plays them back offline. internal/oai routes
+// HTTP through Session.Transport and internal/tools routes tool runs through
+// Session.RunTool, so a replayed run makes no network calls and starts no
+// tool processes.
+package recording
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// FileName is the recording inside the session directory: one JSON Entry
+// per line, in the order the interactions finished.
+const FileName = "recording.jsonl"
+
+// Entry is one recorded interaction: an HTTP attempt or a tool run.
+type Entry struct {
+	Kind string `json:"kind"` // "http" or "tool"
+	// HTTP attempt; request headers are not recorded so keys never reach disk
+	Method      string `json:"method,omitempty"`
+	Path        string `json:"path,omitempty"`
+	RequestBody string `json:"requestBody,omitempty"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	Body        string `json:"body,omitempty"`
+	// Tool run
+	Tool   string `json:"tool,omitempty"`
+	Input  string `json:"input,omitempty"`
+	Output string `json:"output,omitempty"`
+	// Error is a transport or tool failure, replayed as the same message
+	Error string `json:"error,omitempty"`
+}
+
+// Session records to or replays from one directory. Interactions are matched
+// by content rather than position, because tool calls run concurrently: an
+// HTTP attempt by method, path, and body; a tool run by name and input.
+// Identical interactions replay in recorded order.
+type Session struct {
+	replay bool
+	mu     sync.Mutex
+	file   *os.File
+	queued map[string][]Entry
+}
+
+// Record creates dir (0700) and starts a new recording in it, replacing any
+// previous one.
+func Record(dir string) (*Session, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create recording dir: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(dir, FileName), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("create recording: %w", err)
+	}
+	return &Session{file: f}, nil
+}
+
+// Replay loads the recording in dir.
+func Replay(dir string) (*Session, error) {
+	data, err := os.ReadFile(filepath.Join(dir, FileName))
+	if err != nil {
+		return nil, fmt.Errorf("read recording: %w", err)
+	}
+	s := &Session{replay: true, queued: map[string][]Entry{}}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for n := 1; sc.Scan(); n++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", FileName, n, err)
+		}
+		k := e.key()
+		s.queued[k] = append(s.queued[k], e)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read recording: %w", err)
+	}
+	return s, nil
+}
+
+// Close finishes a recording; it is a no-op when replaying.
+func (s *Session) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Close()
+}
+
+func (e Entry) key() string {
+	if e.Kind == "tool" {
+		return "tool " + e.Tool + " " + digest(e.Input)
+	}
+	return "http " + e.Method + " " + e.Path + " " + digest(e.RequestBody)
+}
+
+func digest(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *Session) write(e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.file.Write(append(line, '\n'))
+	return err
+}
+
+// next removes and returns the first recorded entry matching e.
+func (s *Session) next(e Entry) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := e.key()
+	q := s.queued[k]
+	if len(q) == 0 {
+		if e.Kind == "tool" {
+			return Entry{}, fmt.Errorf("replay: no recorded run of tool %s with input %s", e.Tool, truncate(e.Input))
+		}
+		return Entry{}, fmt.Errorf("replay: no recorded response for %s %s with this request body; the run diverged from the recording", e.Method, e.Path)
+	}
+	s.queued[k] = q[1:]
+	return q[0], nil
+}
+
+func truncate(s string) string {
+	if len(s) > 200 {
+		return s[:200] + "..."
+	}
+	return s
+}
+
+// Transport wraps next: recording passes each attempt through and saves it;
+// replaying answers from the recording without using next.
+func (s *Session) Transport(next http.RoundTripper) http.RoundTripper {
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body, err := readBody(req)
+		if err != nil {
+			return nil, err
+		}
+		e := Entry{Kind: "http", Method: req.Method, Path: req.URL.Path, RequestBody: string(body)}
+		if s.replay {
+			rec, err := s.next(e)
+			if err != nil {
+				return nil, err
+			}
+			if rec.Error != "" {
+				return nil, errors.New(rec.Error)
+			}
+			return &http.Response{
+				Status:        strconv.Itoa(rec.Status) + " " + http.StatusText(rec.Status),
+				StatusCode:    rec.Status,
+				Proto:         "HTTP/1.1",
+				ProtoMajor:    1,
+				ProtoMinor:    1,
+				Header:        http.Header{"Content-Type": []string{rec.ContentType}},
+				Body:          io.NopCloser(bytes.NewReader([]byte(rec.Body))),
+				ContentLength: int64(len(rec.Body)),
+				Request:       req,
+			}, nil
+		}
+		resp, err := next.RoundTrip(req)
+		if err != nil {
+			e.Error = err.Error()
+			if werr := s.write(e); werr != nil {
+				return nil, fmt.Errorf("record: %w", werr)
+			}
+			return nil, err
+		}
+		// Read the whole body (streams included) so it can be saved, then
+		// hand the caller an equivalent copy
+		data, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close() //nolint:errcheck // fully read
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(data))
+		e.Status, e.ContentType, e.Body = resp.StatusCode, resp.Header.Get("Content-Type"), string(data)
+		if err := s.write(e); err != nil {
+			return nil, fmt.Errorf("record: %w", err)
+		}
+		return resp, nil
+	})
+}
+
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close() //nolint:errcheck // fully read
+	if err != nil {
+		return nil, fmt.Errorf("read request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// RunTool records or replays one tool run; run executes the tool for real.
+// Its signature matches tools.Interceptor.
+func (s *Session) RunTool(name string, input []byte, run func() ([]byte, error)) ([]byte, error) {
+	e := Entry{Kind: "tool", Tool: name, Input: string(input)}
+	if s.replay {
+		rec, err := s.next(e)
+		if err != nil {
+			return nil, err
+		}
+		if rec.Error != "" {
+			return nil, errors.New(rec.Error)
+		}
+		return []byte(rec.Output), nil
+	}
+	out, runErr := run()
+	e.Output = string(out)
+	if runErr != nil {
+		e.Error = runErr.Error()
+	}
+	if err := s.write(e); err != nil {
+		return nil, fmt.Errorf("record: %w", err)
+	}
+	return out, runErr
+}
@@ -0,0 +1,113 @@
+package recording
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func post(t *testing.T, rt http.RoundTripper, url, body string) (int, string, error) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer secret-key")
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer func() { _ = resp.Body.Close() }() //nolint:errcheck
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(data), nil
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body) //nolint:errcheck
+		w.Header().Set("Content-Type", "text/event-stream")
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_, _ = w.Write([]byte("reply to " + string(body))) //nolint:errcheck
+	}))
+	defer srv.Close()
+	dir := filepath.Join(t.TempDir(), "rec")
+
+	rec, err := Record(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt := rec.Transport(http.DefaultTransport)
+	for _, body := range []string{"a", "a", "b"} {
+		if _, _, err := post(t, rt, srv.URL+"/v1/chat", body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out, err := rec.RunTool("ping", []byte(`{"n":1}`), func() ([]byte, error) { return []byte(`{"ok":true}`), nil })
+	if err != nil || string(out) != `{"ok":true}` {
+		t.Fatalf("record tool: %s %v", out, err)
+	}
+	if _, err := rec.RunTool("ping", []byte(`{"n":2}`), func() ([]byte, error) { return nil, errors.New("boom") }); err == nil {
+		t.Fatal("tool error must pass through")
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret-key") {
+		t.Fatalf("request headers leaked into the recording")
+	}
+	if fi, err := os.Stat(filepath.Join(dir, FileName)); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Fatalf("mode: %v %v", fi, err)
+	}
+
+	srv.Close()
+	rep, err := Replay(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt = rep.Transport(nil)
+	// Out of order: b first, then both a attempts in recorded order
+	for _, want := range []struct {
+		body   string
+		status int
+	}{{"b", 200}, {"a", 503}, {"a", 200}} {
+		status, got, err := post(t, rt, "http://elsewhere.invalid/v1/chat", want.body)
+		if err != nil || status != want.status || got != "reply to "+want.body {
+			t.Fatalf("%s: status=%d body=%q err=%v", want.body, status, got, err)
+		}
+	}
+	if _, _, err := post(t, rt, "http://elsewhere.invalid/v1/chat", "a"); err == nil || !strings.Contains(err.Error(), "diverged") {
+		t.Fatalf("exhausted: %v", err)
+	}
+	notRun := func() ([]byte, error) { t.Fatal("replay must not run tools"); return nil, nil }
+	if _, err := rep.RunTool("ping", []byte(`{"n":2}`), notRun); err == nil || err.Error() != "boom" {
+		t.Fatalf("replayed tool error: %v", err)
+	}
+	if out, err := rep.RunTool("ping", []byte(`{"n":1}`), notRun); err != nil || string(out) != `{"ok":true}` {
+		t.Fatalf("replayed tool: %s %v", out, err)
+	}
+	if _, err := rep.RunTool("ping", []byte(`{"n":3}`), notRun); err == nil || !strings.Contains(err.Error(), "no recorded run of tool ping") {
+		t.Fatalf("unknown input: %v", err)
+	}
+}
+
+func TestReplay_MissingRecording(t *testing.T) {
+	if _, err := Replay(t.TempDir()); err == nil || !strings.Contains(err.Error(), "read recording") {
+		t.Fatalf("err=%v", err)
+	}
+}
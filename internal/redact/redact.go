@@ -0,0 +1,157 @@
+// Package redact masks secrets before text leaves the process: tool outputs
+// appended to the transcript, -save-messages files, -debug dumps, and audit
+// lines. Matches are replaced with "[REDACTED:<type>]" so readers can still
+// see what kind of value was removed.
+package redact
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Types reported in replacement markers.
+const (
+	TypeAPIKey      = "api_key"
+	TypeBearer      = "bearer"
+	TypeAWSKey      = "aws_key"
+	TypeGitHubToken = "github_token"
+	TypeEnv         = "env"
+	TypeCustom      = "custom"
+)
+
+// secretEnvKeys are environment variables whose values are masked verbatim
+// wherever they appear.
+var secretEnvKeys = []string{"OAI_API_KEY", "OPENAI_API_KEY", "OAI_PREP_API_KEY", "OAI_IMAGE_API_KEY"}
+
+type rule struct {
+	typ string
+	rx  *regexp.Regexp
+	// keep is a capture group index preserved before the marker (0 = none)
+	keep int
+}
+
+var builtinRules = []rule{
+	{typ: TypeBearer, rx: regexp.MustCompile(`(?i)(\bbearer\s+)[A-Za-z0-9._~+/\-]{8,}=*`), keep: 1},
+	{typ: TypeAPIKey, rx: regexp.MustCompile(`\bsk-[A-Za-z0-9_\-]{16,}`)},
+	{typ: TypeAWSKey, rx: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{typ: TypeGitHubToken, rx: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+}
+
+// Marker returns the replacement text for a secret of the given type.
+func Marker(typ string) string {
+	return "[REDACTED:" + typ + "]"
+}
+
+// String masks secrets in s. Values of well-known secret environment
+// variables are replaced first, then built-in detectors (API keys, bearer
+// tokens, AWS access keys, GitHub tokens), then user patterns from
+// GOAGENT_REDACT (newline-separated regexes; see Check).
+func String(s string) string {
+	if s == "" {
+		return s
+	}
+	for _, lit := range envSecrets() {
+		s = strings.ReplaceAll(s, lit, Marker(TypeEnv))
+	}
+	for _, r := range builtinRules {
+		s = apply(r, s)
+	}
+	for _, rx := range customPatterns().regexps {
+		s = apply(rule{typ: TypeCustom, rx: rx}, s)
+	}
+	return s
+}
+
+// Strings applies String to each element and returns a new slice.
+func Strings(values []string) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = String(v)
+	}
+	return out
+}
+
+// Bytes is String for byte slices such as encoded JSON lines. Markers contain
+// no quotes or backslashes, so valid JSON stays valid as long as custom
+// patterns do not match across string delimiters.
+func Bytes(b []byte) []byte {
+	if len(b) == 0 {
+		return b
+	}
+	return []byte(String(string(b)))
+}
+
+func apply(r rule, s string) string {
+	if r.keep == 0 {
+		return r.rx.ReplaceAllLiteralString(s, Marker(r.typ))
+	}
+	return r.rx.ReplaceAllStringFunc(s, func(m string) string {
+		sub := r.rx.FindStringSubmatch(m)
+		return sub[r.keep] + Marker(r.typ)
+	})
+}
+
+// envSecrets returns non-trivial secret env values, longest first so that a
+// value containing another is masked whole.
+func envSecrets() []string {
+	var out []string
+	for _, k := range secretEnvKeys {
+		if v := strings.TrimSpace(os.Getenv(k)); len(v) >= 4 {
+			out = append(out, v)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return len(out[i]) > len(out[j]) })
+	return out
+}
+
+// Check reports the first GOAGENT_REDACT entry that does not compile, so a
+// typo fails the run at startup instead of leaving a secret unmasked; String
+// skips such entries.
+func Check() error {
+	return customPatterns().err
+}
+
+// patternSet is GOAGENT_REDACT compiled once per distinct value.
+type patternSet struct {
+	env     string
+	regexps []*regexp.Regexp
+	err     error
+}
+
+var (
+	patternsMu sync.Mutex
+	patterns   *patternSet
+)
+
+// customPatterns compiles GOAGENT_REDACT, one regex per line; lines are the
+// separator because commas and semicolons are regex syntax, as in {2,4}.
+// The result is cached until the variable changes.
+func customPatterns() *patternSet {
+	cfg := os.Getenv("GOAGENT_REDACT")
+	patternsMu.Lock()
+	defer patternsMu.Unlock()
+	if patterns != nil && patterns.env == cfg {
+		return patterns
+	}
+	set := &patternSet{env: cfg}
+	for _, line := range strings.Split(cfg, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		rx, err := regexp.Compile(line)
+		if err != nil {
+			if set.err == nil {
+				set.err = fmt.Errorf("GOAGENT_REDACT: invalid pattern %q: %v", line, err)
+			}
+			continue
+		}
+		set.regexps = append(set.regexps, rx)
+	}
+	patterns = set
+	return set
+}
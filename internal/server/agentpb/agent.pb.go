@@ -0,0 +1,699 @@
+// Agent service for driving agentcli runs programmatically; served by
+// `agentcli serve -grpc-listen`. Regenerate the Go code with `make proto`.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: internal/server/agentpb/agent.proto
+
+package agentpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type EventKind int32
+
+const (
+	EventKind_EVENT_KIND_UNSPECIFIED EventKind = 0
+	EventKind_EVENT_KIND_STARTED     EventKind = 1
+	EventKind_EVENT_KIND_STEP        EventKind = 2
+	EventKind_EVENT_KIND_USAGE       EventKind = 3
+	EventKind_EVENT_KIND_TOOL_START  EventKind = 4
+	EventKind_EVENT_KIND_TOOL_END    EventKind = 5
+	EventKind_EVENT_KIND_DONE        EventKind = 6
+)
+
+// Enum value maps for EventKind.
+var (
+	EventKind_name = map[int32]string{
+		0: "EVENT_KIND_UNSPECIFIED",
+		1: "EVENT_KIND_STARTED",
+		2: "EVENT_KIND_STEP",
+		3: "EVENT_KIND_USAGE",
+		4: "EVENT_KIND_TOOL_START",
+		5: "EVENT_KIND_TOOL_END",
+		6: "EVENT_KIND_DONE",
+	}
+	EventKind_value = map[string]int32{
+		"EVENT_KIND_UNSPECIFIED": 0,
+		"EVENT_KIND_STARTED":     1,
+		"EVENT_KIND_STEP":        2,
+		"EVENT_KIND_USAGE":       3,
+		"EVENT_KIND_TOOL_START":  4,
+		"EVENT_KIND_TOOL_END":    5,
+		"EVENT_KIND_DONE":        6,
+	}
+)
+
+func (x EventKind) Enum() *EventKind {
+	p := new(EventKind)
+	*p = x
+	return p
+}
+
+func (x EventKind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (EventKind) Descriptor() protoreflect.EnumDescriptor {
+	return file_internal_server_agentpb_agent_proto_enumTypes[0].Descriptor()
+}
+
+func (EventKind) Type() protoreflect.EnumType {
+	return &file_internal_server_agentpb_agent_proto_enumTypes[0]
+}
+
+func (x EventKind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use EventKind.Descriptor instead.
+func (EventKind) EnumDescriptor() ([]byte, []int) {
+	return file_internal_server_agentpb_agent_proto_rawDescGZIP(), []int{0}
+}
+
+// RunRequest is one prompt. Only prompt is required; the other fields
+// override the server's command-line settings for this run.
+type RunRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Names the run for CancelRun; the server picks one when empty.
+	RunId       string   `protobuf:"bytes,1,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`
+	Prompt      string   `protobuf:"bytes,2,opt,name=prompt,proto3" json:"prompt,omitempty"`
+	System      string   `protobuf:"bytes,3,opt,name=system,proto3" json:"system,omitempty"`
+	Developer   []string `protobuf:"bytes,4,rep,name=developer,proto3" json:"developer,omitempty"`
+	Model       string   `protobuf:"bytes,5,opt,name=model,proto3" json:"model,omitempty"`
+	Temperature *float64 `protobuf:"fixed64,6,opt,name=temperature,proto3,oneof" json:"temperature,omitempty"`
+	MaxSteps    int32    `protobuf:"varint,7,opt,name=max_steps,json=maxSteps,proto3" json:"max_steps,omitempty"`
+	// Limits the -tools entries offered; unset offers all of them.
+	Tools         *ToolList `protobuf:"bytes,8,opt,name=tools,proto3" json:"tools,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RunRequest) Reset() {
+	*x = RunRequest{}
+	mi := &file_internal_server_agentpb_agent_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RunRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RunRequest) ProtoMessage() {}
+
+func (x *RunRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_server_agentpb_agent_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RunRequest.ProtoReflect.Descriptor instead.
+func (*RunRequest) Descriptor() ([]byte, []int) {
+	return file_internal_server_agentpb_agent_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *RunRequest) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
+}
+
+func (x *RunRequest) GetPrompt() string {
+	if x != nil {
+		return x.Prompt
+	}
+	return ""
+}
+
+func (x *RunRequest) GetSystem() string {
+	if x != nil {
+		return x.System
+	}
+	return ""
+}
+
+func (x *RunRequest) GetDeveloper() []string {
+	if x != nil {
+		return x.Developer
+	}
+	return nil
+}
+
+func (x *RunRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *RunRequest) GetTemperature() float64 {
+	if x != nil && x.Temperature != nil {
+		return *x.Temperature
+	}
+	return 0
+}
+
+func (x *RunRequest) GetMaxSteps() int32 {
+	if x != nil {
+		return x.MaxSteps
+	}
+	return 0
+}
+
+func (x *RunRequest) GetTools() *ToolList {
+	if x != nil {
+		return x.Tools
+	}
+	return nil
+}
+
+type ToolList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Names         []string               `protobuf:"bytes,1,rep,name=names,proto3" json:"names,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ToolList) Reset() {
+	*x = ToolList{}
+	mi := &file_internal_server_agentpb_agent_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ToolList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ToolList) ProtoMessage() {}
+
+func (x *ToolList) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_server_agentpb_agent_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ToolList.ProtoReflect.Descriptor instead.
+func (*ToolList) Descriptor() ([]byte, []int) {
+	return file_internal_server_agentpb_agent_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ToolList) GetNames() []string {
+	if x != nil {
+		return x.Names
+	}
+	return nil
+}
+
+// RunResult is the outcome of a run, as in the -batch-out results.
+type RunResult struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	RunId string                 `protobuf:"bytes,1,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`
+	Model string                 `protobuf:"bytes,2,opt,name=model,proto3" json:"model,omitempty"`
+	// The exit code agentcli would have returned, with its -error-json reason
+	// and last error line when nonzero.
+	ExitCode         int32  `protobuf:"varint,3,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
+	Reason           string `protobuf:"bytes,4,opt,name=reason,proto3" json:"reason,omitempty"`
+	Error            string `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	Output           string `protobuf:"bytes,6,opt,name=output,proto3" json:"output,omitempty"`
+	Steps            int32  `protobuf:"varint,7,opt,name=steps,proto3" json:"steps,omitempty"`
+	PromptTokens     int64  `protobuf:"varint,8,opt,name=prompt_tokens,json=promptTokens,proto3" json:"prompt_tokens,omitempty"`
+	CompletionTokens int64  `protobuf:"varint,9,opt,name=completion_tokens,json=completionTokens,proto3" json:"completion_tokens,omitempty"`
+	TotalTokens      int64  `protobuf:"varint,10,opt,name=total_tokens,json=totalTokens,proto3" json:"total_tokens,omitempty"`
+	LatencyMs        int64  `protobuf:"varint,11,opt,name=latency_ms,json=latencyMs,proto3" json:"latency_ms,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *RunResult) Reset() {
+	*x = RunResult{}
+	mi := &file_internal_server_agentpb_agent_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RunResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RunResult) ProtoMessage() {}
+
+func (x *RunResult) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_server_agentpb_agent_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RunResult.ProtoReflect.Descriptor instead.
+func (*RunResult) Descriptor() ([]byte, []int) {
+	return file_internal_server_agentpb_agent_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RunResult) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
+}
+
+func (x *RunResult) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *RunResult) GetExitCode() int32 {
+	if x != nil {
+		return x.ExitCode
+	}
+	return 0
+}
+
+func (x *RunResult) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *RunResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *RunResult) GetOutput() string {
+	if x != nil {
+		return x.Output
+	}
+	return ""
+}
+
+func (x *RunResult) GetSteps() int32 {
+	if x != nil {
+		return x.Steps
+	}
+	return 0
+}
+
+func (x *RunResult) GetPromptTokens() int64 {
+	if x != nil {
+		return x.PromptTokens
+	}
+	return 0
+}
+
+func (x *RunResult) GetCompletionTokens() int64 {
+	if x != nil {
+		return x.CompletionTokens
+	}
+	return 0
+}
+
+func (x *RunResult) GetTotalTokens() int64 {
+	if x != nil {
+		return x.TotalTokens
+	}
+	return 0
+}
+
+func (x *RunResult) GetLatencyMs() int64 {
+	if x != nil {
+		return x.LatencyMs
+	}
+	return 0
+}
+
+// RunEvent is one event of a streamed run. Every event carries the step it
+// happened in; the other fields are set by the kinds noted.
+type RunEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Kind  EventKind              `protobuf:"varint,1,opt,name=kind,proto3,enum=goagent.v1.EventKind" json:"kind,omitempty"`
+	RunId string                 `protobuf:"bytes,2,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`
+	Step  int32                  `protobuf:"varint,3,opt,name=step,proto3" json:"step,omitempty"`
+	// STEP
+	MaxSteps int32 `protobuf:"varint,4,opt,name=max_steps,json=maxSteps,proto3" json:"max_steps,omitempty"`
+	// TOOL_START and TOOL_END
+	Tool   string `protobuf:"bytes,5,opt,name=tool,proto3" json:"tool,omitempty"`
+	CallId string `protobuf:"bytes,6,opt,name=call_id,json=callId,proto3" json:"call_id,omitempty"`
+	Failed bool   `protobuf:"varint,7,opt,name=failed,proto3" json:"failed,omitempty"`
+	// USAGE: the tokens of one model call
+	PromptTokens     int64 `protobuf:"varint,8,opt,name=prompt_tokens,json=promptTokens,proto3" json:"prompt_tokens,omitempty"`
+	CompletionTokens int64 `protobuf:"varint,9,opt,name=completion_tokens,json=completionTokens,proto3" json:"completion_tokens,omitempty"`
+	TotalTokens      int64 `protobuf:"varint,10,opt,name=total_tokens,json=totalTokens,proto3" json:"total_tokens,omitempty"`
+	// DONE
+	Result        *RunResult `protobuf:"bytes,11,opt,name=result,proto3" json:"result,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RunEvent) Reset() {
+	*x = RunEvent{}
+	mi := &file_internal_server_agentpb_agent_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RunEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RunEvent) ProtoMessage() {}
+
+func (x *RunEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_server_agentpb_agent_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RunEvent.ProtoReflect.Descriptor instead.
+func (*RunEvent) Descriptor() ([]byte, []int) {
+	return file_internal_server_agentpb_agent_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *RunEvent) GetKind() EventKind {
+	if x != nil {
+		return x.Kind
+	}
+	return EventKind_EVENT_KIND_UNSPECIFIED
+}
+
+func (x *RunEvent) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
+}
+
+func (x *RunEvent) GetStep() int32 {
+	if x != nil {
+		return x.Step
+	}
+	return 0
+}
+
+func (x *RunEvent) GetMaxSteps() int32 {
+	if x != nil {
+		return x.MaxSteps
+	}
+	return 0
+}
+
+func (x *RunEvent) GetTool() string {
+	if x != nil {
+		return x.Tool
+	}
+	return ""
+}
+
+func (x *RunEvent) GetCallId() string {
+	if x != nil {
+		return x.CallId
+	}
+	return ""
+}
+
+func (x *RunEvent) GetFailed() bool {
+	if x != nil {
+		return x.Failed
+	}
+	return false
+}
+
+func (x *RunEvent) GetPromptTokens() int64 {
+	if x != nil {
+		return x.PromptTokens
+	}
+	return 0
+}
+
+func (x *RunEvent) GetCompletionTokens() int64 {
+	if x != nil {
+		return x.CompletionTokens
+	}
+	return 0
+}
+
+func (x *RunEvent) GetTotalTokens() int64 {
+	if x != nil {
+		return x.TotalTokens
+	}
+	return 0
+}
+
+func (x *RunEvent) GetResult() *RunResult {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+type CancelRunRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RunId         string                 `protobuf:"bytes,1,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelRunRequest) Reset() {
+	*x = CancelRunRequest{}
+	mi := &file_internal_server_agentpb_agent_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelRunRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelRunRequest) ProtoMessage() {}
+
+func (x *CancelRunRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_server_agentpb_agent_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelRunRequest.ProtoReflect.Descriptor instead.
+func (*CancelRunRequest) Descriptor() ([]byte, []int) {
+	return file_internal_server_agentpb_agent_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CancelRunRequest) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
+}
+
+type CancelRunResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelRunResponse) Reset() {
+	*x = CancelRunResponse{}
+	mi := &file_internal_server_agentpb_agent_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelRunResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelRunResponse) ProtoMessage() {}
+
+func (x *CancelRunResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_server_agentpb_agent_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelRunResponse.ProtoReflect.Descriptor instead.
+func (*CancelRunResponse) Descriptor() ([]byte, []int) {
+	return file_internal_server_agentpb_agent_proto_rawDescGZIP(), []int{5}
+}
+
+var File_internal_server_agentpb_agent_proto protoreflect.FileDescriptor
+
+const file_internal_server_agentpb_agent_proto_rawDesc = "" +
+	"\n" +
+	"#internal/server/agentpb/agent.proto\x12\n" +
+	"goagent.v1\"\x87\x02\n" +
+	"\n" +
+	"RunRequest\x12\x15\n" +
+	"\x06run_id\x18\x01 \x01(\tR\x05runId\x12\x16\n" +
+	"\x06prompt\x18\x02 \x01(\tR\x06prompt\x12\x16\n" +
+	"\x06system\x18\x03 \x01(\tR\x06system\x12\x1c\n" +
+	"\tdeveloper\x18\x04 \x03(\tR\tdeveloper\x12\x14\n" +
+	"\x05model\x18\x05 \x01(\tR\x05model\x12%\n" +
+	"\vtemperature\x18\x06 \x01(\x01H\x00R\vtemperature\x88\x01\x01\x12\x1b\n" +
+	"\tmax_steps\x18\a \x01(\x05R\bmaxSteps\x12*\n" +
+	"\x05tools\x18\b \x01(\v2\x14.goagent.v1.ToolListR\x05toolsB\x0e\n" +
+	"\f_temperature\" \n" +
+	"\bToolList\x12\x14\n" +
+	"\x05names\x18\x01 \x03(\tR\x05names\"\xc5\x02\n" +
+	"\tRunResult\x12\x15\n" +
+	"\x06run_id\x18\x01 \x01(\tR\x05runId\x12\x14\n" +
+	"\x05model\x18\x02 \x01(\tR\x05model\x12\x1b\n" +
+	"\texit_code\x18\x03 \x01(\x05R\bexitCode\x12\x16\n" +
+	"\x06reason\x18\x04 \x01(\tR\x06reason\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\x12\x16\n" +
+	"\x06output\x18\x06 \x01(\tR\x06output\x12\x14\n" +
+	"\x05steps\x18\a \x01(\x05R\x05steps\x12#\n" +
+	"\rprompt_tokens\x18\b \x01(\x03R\fpromptTokens\x12+\n" +
+	"\x11completion_tokens\x18\t \x01(\x03R\x10completionTokens\x12!\n" +
+	"\ftotal_tokens\x18\n" +
+	" \x01(\x03R\vtotalTokens\x12\x1d\n" +
+	"\n" +
+	"latency_ms\x18\v \x01(\x03R\tlatencyMs\"\xe6\x02\n" +
+	"\bRunEvent\x12)\n" +
+	"\x04kind\x18\x01 \x01(\x0e2\x15.goagent.v1.EventKindR\x04kind\x12\x15\n" +
+	"\x06run_id\x18\x02 \x01(\tR\x05runId\x12\x12\n" +
+	"\x04step\x18\x03 \x01(\x05R\x04step\x12\x1b\n" +
+	"\tmax_steps\x18\x04 \x01(\x05R\bmaxSteps\x12\x12\n" +
+	"\x04tool\x18\x05 \x01(\tR\x04tool\x12\x17\n" +
+	"\acall_id\x18\x06 \x01(\tR\x06callId\x12\x16\n" +
+	"\x06failed\x18\a \x01(\bR\x06failed\x12#\n" +
+	"\rprompt_tokens\x18\b \x01(\x03R\fpromptTokens\x12+\n" +
+	"\x11completion_tokens\x18\t \x01(\x03R\x10completionTokens\x12!\n" +
+	"\ftotal_tokens\x18\n" +
+	" \x01(\x03R\vtotalTokens\x12-\n" +
+	"\x06result\x18\v \x01(\v2\x15.goagent.v1.RunResultR\x06result\")\n" +
+	"\x10CancelRunRequest\x12\x15\n" +
+	"\x06run_id\x18\x01 \x01(\tR\x05runId\"\x13\n" +
+	"\x11CancelRunResponse*\xb3\x01\n" +
+	"\tEventKind\x12\x1a\n" +
+	"\x16EVENT_KIND_UNSPECIFIED\x10\x00\x12\x16\n" +
+	"\x12EVENT_KIND_STARTED\x10\x01\x12\x13\n" +
+	"\x0fEVENT_KIND_STEP\x10\x02\x12\x14\n" +
+	"\x10EVENT_KIND_USAGE\x10\x03\x12\x19\n" +
+	"\x15EVENT_KIND_TOOL_START\x10\x04\x12\x17\n" +
+	"\x13EVENT_KIND_TOOL_END\x10\x05\x12\x13\n" +
+	"\x0fEVENT_KIND_DONE\x10\x062\xcb\x01\n" +
+	"\fAgentService\x124\n" +
+	"\x03Run\x12\x16.goagent.v1.RunRequest\x1a\x15.goagent.v1.RunResult\x12;\n" +
+	"\tStreamRun\x12\x16.goagent.v1.RunRequest\x1a\x14.goagent.v1.RunEvent0\x01\x12H\n" +
+	"\tCancelRun\x12\x1c.goagent.v1.CancelRunRequest\x1a\x1d.goagent.v1.CancelRunResponseB7Z5github.com/hyperifyio/goagent/internal/server/agentpbb\x06proto3"
+
+var (
+	file_internal_server_agentpb_agent_proto_rawDescOnce sync.Once
+	file_internal_server_agentpb_agent_proto_rawDescData []byte
+)
+
+func file_internal_server_agentpb_agent_proto_rawDescGZIP() []byte {
+	file_internal_server_agentpb_agent_proto_rawDescOnce.Do(func() {
+		file_internal_server_agentpb_agent_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_internal_server_agentpb_agent_proto_rawDesc), len(file_internal_server_agentpb_agent_proto_rawDesc)))
+	})
+	return file_internal_server_agentpb_agent_proto_rawDescData
+}
+
+var file_internal_server_agentpb_agent_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_internal_server_agentpb_agent_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_internal_server_agentpb_agent_proto_goTypes = []any{
+	(EventKind)(0),            // 0: goagent.v1.EventKind
+	(*RunRequest)(nil),        // 1: goagent.v1.RunRequest
+	(*ToolList)(nil),          // 2: goagent.v1.ToolList
+	(*RunResult)(nil),         // 3: goagent.v1.RunResult
+	(*RunEvent)(nil),          // 4: goagent.v1.RunEvent
+	(*CancelRunRequest)(nil),  // 5: goagent.v1.CancelRunRequest
+	(*CancelRunResponse)(nil), // 6: goagent.v1.CancelRunResponse
+}
+var file_internal_server_agentpb_agent_proto_depIdxs = []int32{
+	2, // 0: goagent.v1.RunRequest.tools:type_name -> goagent.v1.ToolList
+	0, // 1: goagent.v1.RunEvent.kind:type_name -> goagent.v1.EventKind
+	3, // 2: goagent.v1.RunEvent.result:type_name -> goagent.v1.RunResult
+	1, // 3: goagent.v1.AgentService.Run:input_type -> goagent.v1.RunRequest
+	1, // 4: goagent.v1.AgentService.StreamRun:input_type -> goagent.v1.RunRequest
+	5, // 5: goagent.v1.AgentService.CancelRun:input_type -> goagent.v1.CancelRunRequest
+	3, // 6: goagent.v1.AgentService.Run:output_type -> goagent.v1.RunResult
+	4, // 7: goagent.v1.AgentService.StreamRun:output_type -> goagent.v1.RunEvent
+	6, // 8: goagent.v1.AgentService.CancelRun:output_type -> goagent.v1.CancelRunResponse
+	6, // [6:9] is the sub-list for method output_type
+	3, // [3:6] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_internal_server_agentpb_agent_proto_init() }
+func file_internal_server_agentpb_agent_proto_init() {
+	if File_internal_server_agentpb_agent_proto != nil {
+		return
+	}
+	file_internal_server_agentpb_agent_proto_msgTypes[0].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_internal_server_agentpb_agent_proto_rawDesc), len(file_internal_server_agentpb_agent_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_internal_server_agentpb_agent_proto_goTypes,
+		DependencyIndexes: file_internal_server_agentpb_agent_proto_depIdxs,
+		EnumInfos:         file_internal_server_agentpb_agent_proto_enumTypes,
+		MessageInfos:      file_internal_server_agentpb_agent_proto_msgTypes,
+	}.Build()
+	File_internal_server_agentpb_agent_proto = out.File
+	file_internal_server_agentpb_agent_proto_goTypes = nil
+	file_internal_server_agentpb_agent_proto_depIdxs = nil
+}
@@ -0,0 +1,183 @@
+// Package server implements the gRPC AgentService defined in
+// agentpb/agent.proto. The agent itself lives in cmd/agentcli, which hands
+// its runs to the server through a Runner, so the service stays a thin layer
+// of run bookkeeping, cancellation, and auth over typed messages.
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/hyperifyio/goagent/internal/server/agentpb"
+	"github.com/hyperifyio/goagent/internal/state"
+)
+
+// Runner executes agent runs for the server.
+type Runner interface {
+	// Check validates req before a run slot is taken.
+	Check(req *agentpb.RunRequest) error
+	// Run runs req until it ends or ctx is canceled, passing its events to
+	// events as they happen. Events may arrive from several goroutines.
+	Run(ctx context.Context, req *agentpb.RunRequest, events func(*agentpb.RunEvent)) *agentpb.RunResult
+}
+
+// Server is the AgentService implementation.
+type Server struct {
+	agentpb.UnimplementedAgentServiceServer
+
+	runner Runner
+	slots  chan struct{}
+	token  string
+
+	mu      sync.Mutex
+	working map[string]context.CancelFunc
+}
+
+// New returns a server that starts at most cap(slots) runs at once; other
+// front ends may share slots to bound runs across all of them. A non-empty
+// token is required as "authorization: Bearer <token>" metadata.
+func New(runner Runner, slots chan struct{}, token string) *Server {
+	return &Server{runner: runner, slots: slots, token: token, working: map[string]context.CancelFunc{}}
+}
+
+// NewGRPC returns a gRPC server with s registered and its token enforced.
+func NewGRPC(s *Server, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts, grpc.UnaryInterceptor(s.authUnary), grpc.StreamInterceptor(s.authStream))
+	g := grpc.NewServer(opts...)
+	agentpb.RegisterAgentServiceServer(g, s)
+	return g
+}
+
+// Run implements AgentService.Run.
+func (s *Server) Run(ctx context.Context, req *agentpb.RunRequest) (*agentpb.RunResult, error) {
+	ctx, done, err := s.start(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return s.run(ctx, req, func(*agentpb.RunEvent) {}), nil
+}
+
+// StreamRun implements AgentService.StreamRun.
+func (s *Server) StreamRun(req *agentpb.RunRequest, stream agentpb.AgentService_StreamRunServer) error {
+	ctx, done, err := s.start(stream.Context(), req)
+	if err != nil {
+		return err
+	}
+	defer done()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// Send serially, tracking the step; a failed send cancels the run since
+	// nobody is listening
+	var mu sync.Mutex
+	var step int32
+	var sendErr error
+	send := func(ev *agentpb.RunEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		if ev.Kind == agentpb.EventKind_EVENT_KIND_STEP {
+			step = ev.Step
+		}
+		ev.Step = step
+		if sendErr == nil {
+			sendErr = stream.Send(ev)
+		}
+		if sendErr != nil {
+			cancel()
+		}
+	}
+	send(&agentpb.RunEvent{Kind: agentpb.EventKind_EVENT_KIND_STARTED, RunId: req.GetRunId()})
+	res := s.run(ctx, req, send)
+	send(&agentpb.RunEvent{Kind: agentpb.EventKind_EVENT_KIND_DONE, RunId: res.GetRunId(), Result: res})
+	return sendErr
+}
+
+// CancelRun implements AgentService.CancelRun.
+func (s *Server) CancelRun(_ context.Context, req *agentpb.CancelRunRequest) (*agentpb.CancelRunResponse, error) {
+	s.mu.Lock()
+	cancel := s.working[req.GetRunId()]
+	s.mu.Unlock()
+	if cancel == nil {
+		return nil, status.Errorf(codes.NotFound, "no working run %q", req.GetRunId())
+	}
+	cancel()
+	return &agentpb.CancelRunResponse{}, nil
+}
+
+// start validates req, names it when needed, and registers it for
+// CancelRun. done releases the run slot and forgets the run.
+func (s *Server) start(ctx context.Context, req *agentpb.RunRequest) (context.Context, func(), error) {
+	if err := s.runner.Check(req); err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		return nil, nil, status.Error(codes.ResourceExhausted, "all run slots are busy; retry later")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if req.GetRunId() == "" {
+		req.RunId = state.NewRunID()
+		for s.working[req.RunId] != nil {
+			req.RunId = state.NewRunID()
+		}
+	} else if s.working[req.GetRunId()] != nil {
+		cancel()
+		<-s.slots
+		return nil, nil, status.Errorf(codes.AlreadyExists, "run %q is already working", req.GetRunId())
+	}
+	id := req.GetRunId()
+	s.working[id] = cancel
+	return ctx, func() {
+		cancel()
+		s.mu.Lock()
+		delete(s.working, id)
+		s.mu.Unlock()
+		<-s.slots
+	}, nil
+}
+
+func (s *Server) run(ctx context.Context, req *agentpb.RunRequest, events func(*agentpb.RunEvent)) *agentpb.RunResult {
+	res := s.runner.Run(ctx, req, func(ev *agentpb.RunEvent) {
+		ev.RunId = req.GetRunId()
+		events(ev)
+	})
+	res.RunId = req.GetRunId()
+	return res
+}
+
+func (s *Server) authorize(ctx context.Context) error {
+	if s.token == "" {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		if token, ok := strings.CutPrefix(v, "Bearer "); ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "missing or wrong bearer token")
+}
+
+func (s *Server) authUnary(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) authStream(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.authorize(ss.Context()); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
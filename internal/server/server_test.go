@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/hyperifyio/goagent/internal/server/agentpb"
+)
+
+// fakeRunner echoes the prompt after one step and one tool call, or waits
+// for cancellation when the prompt is "block".
+type fakeRunner struct {
+	started chan string
+}
+
+func (f fakeRunner) Check(req *agentpb.RunRequest) error {
+	if req.GetPrompt() == "" {
+		return errors.New("prompt is required")
+	}
+	return nil
+}
+
+func (f fakeRunner) Run(ctx context.Context, req *agentpb.RunRequest, events func(*agentpb.RunEvent)) *agentpb.RunResult {
+	events(&agentpb.RunEvent{Kind: agentpb.EventKind_EVENT_KIND_STEP, Step: 1, MaxSteps: 4})
+	events(&agentpb.RunEvent{Kind: agentpb.EventKind_EVENT_KIND_TOOL_START, Tool: "t", CallId: "c1"})
+	events(&agentpb.RunEvent{Kind: agentpb.EventKind_EVENT_KIND_TOOL_END, Tool: "t", CallId: "c1"})
+	if req.GetPrompt() == "block" {
+		f.started <- req.GetRunId()
+		<-ctx.Done()
+		return &agentpb.RunResult{ExitCode: 130, Reason: "interrupted"}
+	}
+	return &agentpb.RunResult{Model: req.GetModel(), Output: "echo:" + req.GetPrompt(), Steps: 1}
+}
+
+func dial(t *testing.T, token string, maxRuns int) (agentpb.AgentServiceClient, fakeRunner) {
+	t.Helper()
+	runner := fakeRunner{started: make(chan string, 1)}
+	lis := bufconn.Listen(1 << 20)
+	g := NewGRPC(New(runner, make(chan struct{}, maxRuns), token))
+	go func() { _ = g.Serve(lis) }() //nolint:errcheck
+	t.Cleanup(g.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() }) //nolint:errcheck
+	return agentpb.NewAgentServiceClient(conn), runner
+}
+
+func TestRun(t *testing.T) {
+	client, _ := dial(t, "", 1)
+	res, err := client.Run(context.Background(), &agentpb.RunRequest{RunId: "r1", Prompt: "hi", Model: "m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.GetRunId() != "r1" || res.GetOutput() != "echo:hi" || res.GetModel() != "m" {
+		t.Fatalf("result=%v", res)
+	}
+	if _, err := client.Run(context.Background(), &agentpb.RunRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("empty prompt: err=%v", err)
+	}
+}
+
+func TestStreamRun(t *testing.T) {
+	client, _ := dial(t, "", 1)
+	stream, err := client.StreamRun(context.Background(), &agentpb.RunRequest{Prompt: "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kinds []agentpb.EventKind
+	var last *agentpb.RunEvent
+	runID := ""
+	for {
+		ev, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if runID == "" {
+			runID = ev.GetRunId()
+		}
+		if ev.GetRunId() == "" || ev.GetRunId() != runID {
+			t.Fatalf("event without the run_id %q: %v", runID, ev)
+		}
+		kinds = append(kinds, ev.GetKind())
+		last = ev
+	}
+	want := []agentpb.EventKind{
+		agentpb.EventKind_EVENT_KIND_STARTED,
+		agentpb.EventKind_EVENT_KIND_STEP,
+		agentpb.EventKind_EVENT_KIND_TOOL_START,
+		agentpb.EventKind_EVENT_KIND_TOOL_END,
+		agentpb.EventKind_EVENT_KIND_DONE,
+	}
+	if len(kinds) != len(want) {
+		t.Fatalf("kinds=%v", kinds)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Fatalf("kinds=%v", kinds)
+		}
+	}
+	if last.GetStep() != 1 || last.GetResult().GetOutput() != "echo:hi" || last.GetResult().GetRunId() != last.GetRunId() {
+		t.Fatalf("done=%v", last)
+	}
+}
+
+func TestCancelRun(t *testing.T) {
+	client, runner := dial(t, "", 1)
+	type outcome struct {
+		res *agentpb.RunResult
+		err error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		res, err := client.Run(context.Background(), &agentpb.RunRequest{RunId: "slow", Prompt: "block"})
+		done <- outcome{res, err}
+	}()
+	id := <-runner.started
+
+	// The only slot is taken
+	if _, err := client.Run(context.Background(), &agentpb.RunRequest{Prompt: "x"}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("busy: err=%v", err)
+	}
+	if _, err := client.CancelRun(context.Background(), &agentpb.CancelRunRequest{RunId: "nope"}); status.Code(err) != codes.NotFound {
+		t.Errorf("unknown run: err=%v", err)
+	}
+	if _, err := client.CancelRun(context.Background(), &agentpb.CancelRunRequest{RunId: id}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case o := <-done:
+		if o.err != nil || o.res.GetExitCode() != 130 || o.res.GetRunId() != "slow" {
+			t.Fatalf("canceled run: res=%v err=%v", o.res, o.err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run not canceled")
+	}
+	// The slot is free again
+	if _, err := client.Run(context.Background(), &agentpb.RunRequest{Prompt: "x"}); err != nil {
+		t.Fatalf("after cancel: err=%v", err)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	client, _ := dial(t, "s3cret", 1)
+	if _, err := client.Run(context.Background(), &agentpb.RunRequest{Prompt: "hi"}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("no token: err=%v", err)
+	}
+	stream, err := client.StreamRun(metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer wrong"), &agentpb.RunRequest{Prompt: "hi"})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("wrong token: err=%v", err)
+	}
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer s3cret")
+	if _, err := client.Run(ctx, &agentpb.RunRequest{Prompt: "hi"}); err != nil {
+		t.Fatalf("token: err=%v", err)
+	}
+}
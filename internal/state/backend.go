@@ -0,0 +1,220 @@
+package state
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec // content comparison with S3 ETags, not security
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Object is one stored state file. Name is slash-separated and relative to
+// the state root, e.g. "latest.json" or "runs/<id>.json".
+type Object struct {
+	Name string
+	Size int64
+	// MD5 is the hex MD5 of the content, which S3 reports as the ETag of
+	// single-part uploads; empty when the store does not know it.
+	MD5 string
+}
+
+// Backend stores the files of a state directory. The local -state-dir is a
+// DirBackend; a remote store mirrors it so runs on other machines can
+// resume from the same state.
+type Backend interface {
+	List(ctx context.Context) ([]Object, error)
+	Get(ctx context.Context, name string) ([]byte, error)
+	Put(ctx context.Context, name string, data []byte) error
+}
+
+// DirBackend stores state files under a local directory with the same
+// permissions SaveStateBundle uses.
+type DirBackend struct {
+	Dir string
+}
+
+// List returns every regular file under the directory except the lock and
+// in-flight temporary files.
+func (d DirBackend) List(ctx context.Context) ([]Object, error) {
+	if err := ensureSecureStateDir(d.Dir); err != nil {
+		return nil, err
+	}
+	var out []Object
+	if _, err := os.Stat(d.Dir); errors.Is(err, fs.ErrNotExist) {
+		return out, nil
+	}
+	err := filepath.WalkDir(d.Dir, func(p string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if e.IsDir() || !e.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(d.Dir, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if !syncedName(name) {
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		sum := md5.Sum(data) //nolint:gosec
+		out = append(out, Object{Name: name, Size: int64(len(data)), MD5: hex.EncodeToString(sum[:])})
+		return nil
+	})
+	return out, err
+}
+
+// Get reads one file; a missing file returns an error wrapping fs.ErrNotExist.
+func (d DirBackend) Get(ctx context.Context, name string) ([]byte, error) {
+	if err := validObjectName(name); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(filepath.Join(d.Dir, filepath.FromSlash(name)))
+}
+
+// Put writes one file atomically with 0600 permissions.
+func (d DirBackend) Put(ctx context.Context, name string, data []byte) error {
+	if err := validObjectName(name); err != nil {
+		return err
+	}
+	if err := ensureSecureStateDir(d.Dir); err != nil {
+		return err
+	}
+	dst := filepath.Join(d.Dir, filepath.FromSlash(name))
+	parent := filepath.Dir(dst)
+	if err := os.MkdirAll(parent, 0o700); err != nil {
+		return err
+	}
+	return writeFileAtomic(parent, dst, data)
+}
+
+// syncedName reports whether a state file is mirrored: the advisory lock,
+// temporary files from atomic writes, and quarantined files are local only.
+func syncedName(name string) bool {
+	base := path.Base(name)
+	return base != "state.lock" && !strings.HasPrefix(base, ".") && !strings.Contains(base, ".quarantined")
+}
+
+// validObjectName rejects names that would escape the state root.
+func validObjectName(name string) error {
+	if name == "" || strings.HasPrefix(name, "/") || strings.Contains(name, "\\") {
+		return fmt.Errorf("invalid state file name %q", name)
+	}
+	for _, seg := range strings.Split(name, "/") {
+		if seg == "" || seg == "." || seg == ".." {
+			return fmt.Errorf("invalid state file name %q", name)
+		}
+	}
+	return nil
+}
+
+// OpenRemote returns the backend for a -state-remote URL:
+//
+//	s3://bucket/prefix  Amazon S3 or, with AGENTCLI_STATE_S3_ENDPOINT, any
+//	                    S3-compatible store (MinIO, R2, ...)
+//	gs://bucket/prefix  Google Cloud Storage through its S3-compatible XML API
+//	                    with HMAC keys
+//
+// Credentials come from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, and
+// AWS_SESSION_TOKEN; the region from AWS_REGION (default us-east-1).
+func OpenRemote(raw string, client *http.Client) (Backend, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid state remote %q: want s3://bucket/prefix or gs://bucket/prefix", raw)
+	}
+	region := strings.TrimSpace(os.Getenv("AWS_REGION"))
+	if region == "" {
+		region = strings.TrimSpace(os.Getenv("AWS_DEFAULT_REGION"))
+	}
+	var endpoint string
+	switch u.Scheme {
+	case "s3":
+		if region == "" {
+			region = "us-east-1"
+		}
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	case "gs":
+		if region == "" {
+			region = "auto"
+		}
+		endpoint = "https://storage.googleapis.com"
+	default:
+		return nil, fmt.Errorf("unsupported state remote scheme %q: want s3 or gs", u.Scheme)
+	}
+	if v := strings.TrimSpace(os.Getenv("AGENTCLI_STATE_S3_ENDPOINT")); v != "" {
+		endpoint = v
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+	return NewS3Backend(endpoint, u.Host, strings.Trim(u.Path, "/"), region, client)
+}
+
+// SyncStats counts the files a Pull or Push copied.
+type SyncStats struct {
+	Files int
+	Bytes int64
+}
+
+// Pull copies remote files that are missing locally or differ into dir.
+// Snapshots and run logs have unique names, so state from several machines
+// merges; pointer files such as latest.json take the remote copy.
+func Pull(ctx context.Context, remote Backend, dir string) (SyncStats, error) {
+	return mirror(ctx, remote, DirBackend{Dir: dir})
+}
+
+// Push copies local files that are missing remotely or differ to remote;
+// pointer files such as latest.json overwrite the remote copy.
+func Push(ctx context.Context, dir string, remote Backend) (SyncStats, error) {
+	return mirror(ctx, DirBackend{Dir: dir}, remote)
+}
+
+func mirror(ctx context.Context, src, dst Backend) (SyncStats, error) {
+	var st SyncStats
+	have, err := dst.List(ctx)
+	if err != nil {
+		return st, err
+	}
+	known := make(map[string]Object, len(have))
+	for _, o := range have {
+		known[o.Name] = o
+	}
+	objs, err := src.List(ctx)
+	if err != nil {
+		return st, err
+	}
+	for _, o := range objs {
+		if !syncedName(o.Name) {
+			continue
+		}
+		if d, ok := known[o.Name]; ok && d.Size == o.Size && d.MD5 != "" && d.MD5 == o.MD5 {
+			continue
+		}
+		data, err := src.Get(ctx, o.Name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return st, fmt.Errorf("get %s: %w", o.Name, err)
+		}
+		if err := dst.Put(ctx, o.Name, data); err != nil {
+			return st, fmt.Errorf("put %s: %w", o.Name, err)
+		}
+		st.Files++
+		st.Bytes += int64(len(data))
+	}
+	return st, nil
+}
@@ -0,0 +1,184 @@
+package state
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/clock"
+)
+
+// fakeS3 serves path-style ListObjectsV2, GetObject, and PutObject for one
+// bucket from memory.
+type fakeS3 struct {
+	mu      sync.Mutex
+	bucket  string
+	objects map[string][]byte
+	puts    int
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=test-key/") {
+		http.Error(w, "<Error><Code>AccessDenied</Code></Error>", http.StatusForbidden)
+		return
+	}
+	// Like S3, refuse requests signed more than 15 minutes off real time
+	if at, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date")); err != nil || time.Since(at).Abs() > 15*time.Minute {
+		http.Error(w, "<Error><Code>RequestTimeTooSkewed</Code></Error>", http.StatusForbidden)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := strings.TrimPrefix(r.URL.Path, "/"+f.bucket)
+	switch {
+	case r.Method == http.MethodGet && (key == "" || key == "/"):
+		type content struct {
+			Key  string
+			ETag string
+			Size int64
+		}
+		var res struct {
+			XMLName  xml.Name `xml:"ListBucketResult"`
+			Contents []content
+		}
+		prefix := r.URL.Query().Get("prefix")
+		var keys []string
+		for k := range f.objects {
+			if strings.HasPrefix(k, prefix) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			sum := md5.Sum(f.objects[k]) //nolint:gosec
+			res.Contents = append(res.Contents, content{Key: k, ETag: `"` + hex.EncodeToString(sum[:]) + `"`, Size: int64(len(f.objects[k]))})
+		}
+		_ = xml.NewEncoder(w).Encode(res) //nolint:errcheck
+	case r.Method == http.MethodGet:
+		data, ok := f.objects[strings.TrimPrefix(key, "/")]
+		if !ok {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(data) //nolint:errcheck
+	case r.Method == http.MethodPut:
+		data, _ := io.ReadAll(r.Body) //nolint:errcheck
+		f.objects[strings.TrimPrefix(key, "/")] = data
+		f.puts++
+	default:
+		http.Error(w, "unsupported", http.StatusMethodNotAllowed)
+	}
+}
+
+func newFakeRemote(t *testing.T) (*fakeS3, Backend) {
+	t.Helper()
+	t.Setenv("AWS_ACCESS_KEY_ID", "test-key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test-secret")
+	f := &fakeS3{bucket: "team", objects: map[string][]byte{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	t.Setenv("AGENTCLI_STATE_S3_ENDPOINT", srv.URL)
+	remote, err := OpenRemote("s3://team/agents/ci", srv.Client())
+	if err != nil {
+		t.Fatalf("open remote: %v", err)
+	}
+	return f, remote
+}
+
+func TestPushPull_SharesStateThroughS3(t *testing.T) {
+	f, remote := newFakeRemote(t)
+	ctx := context.Background()
+
+	a := t.TempDir()
+	saveTestBundle(t, a, "2026-01-01T00:00:00Z", "main")
+	if err := os.WriteFile(filepath.Join(a, "state.lock"), []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	st, err := Push(ctx, a, remote)
+	if err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	if st.Files != 2 {
+		t.Fatalf("want snapshot and latest.json pushed, got %+v (objects %v)", st, f.objects)
+	}
+	for k := range f.objects {
+		if !strings.HasPrefix(k, "agents/ci/") || strings.HasSuffix(k, "state.lock") {
+			t.Fatalf("unexpected object %q", k)
+		}
+	}
+	// Unchanged files are not uploaded again
+	if st, err := Push(ctx, a, remote); err != nil || st.Files != 0 {
+		t.Fatalf("second push: %+v %v", st, err)
+	}
+
+	b := filepath.Join(t.TempDir(), "state")
+	if _, err := Pull(ctx, remote, b); err != nil {
+		t.Fatalf("pull: %v", err)
+	}
+	got, err := LoadLatestStateBundle(b)
+	if err != nil || got == nil || got.ScopeKey != "main" {
+		t.Fatalf("resumed bundle: %+v %v", got, err)
+	}
+	fi, err := os.Stat(b)
+	if err != nil || fi.Mode().Perm() != 0o700 {
+		t.Fatalf("pulled dir perms: %v %v", fi.Mode(), err)
+	}
+
+	// A snapshot saved on the second machine merges with the first one's
+	saveTestBundle(t, b, "2026-01-02T00:00:00Z", "main")
+	if _, err := Push(ctx, b, remote); err != nil {
+		t.Fatalf("push b: %v", err)
+	}
+	if _, err := Pull(ctx, remote, a); err != nil {
+		t.Fatalf("pull a: %v", err)
+	}
+	snaps, err := ListSnapshots(a)
+	if err != nil || len(snaps) != 2 || !snaps[1].Latest {
+		t.Fatalf("merged snapshots: %+v %v", snaps, err)
+	}
+}
+
+func TestS3Backend_SignsWithWallClockWhenDeterministic(t *testing.T) {
+	clock.SetDeterministic(0)
+	t.Cleanup(clock.Reset)
+	_, remote := newFakeRemote(t)
+	if err := remote.Put(context.Background(), "latest.json", []byte(`{}`)); err != nil {
+		t.Fatalf("put under deterministic clock: %v", err)
+	}
+}
+
+func TestOpenRemote_RejectsBadURLs(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "k")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "s")
+	for _, raw := range []string{"", "ftp://bucket/x", "s3:///nobucket"} {
+		if _, err := OpenRemote(raw, nil); err == nil {
+			t.Fatalf("%q: want error", raw)
+		}
+	}
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	if _, err := OpenRemote("gs://bucket/p", nil); err == nil || !strings.Contains(err.Error(), "AWS_ACCESS_KEY_ID") {
+		t.Fatalf("want missing credentials error, got %v", err)
+	}
+}
+
+func TestValidObjectName(t *testing.T) {
+	for _, bad := range []string{"", "/abs", "../up", "runs/../../x", "a//b", `a\b`} {
+		if validObjectName(bad) == nil {
+			t.Fatalf("%q accepted", bad)
+		}
+	}
+	if err := validObjectName("runs/20260101T000000Z-1a2b3c4d.json"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,285 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/clock"
+)
+
+// CompactToolOutputLimit matches the transcript hygiene limit: tool outputs
+// longer than this are never sent upstream again, so compaction replaces
+// them with the same marker.
+const CompactToolOutputLimit = 8 * 1024
+
+// compactedToolOutput is the marker that replaces a dropped tool output.
+const compactedToolOutput = `{"truncated":true,"reason":"large-tool-output"}`
+
+// GCPolicy selects which snapshots GCStateDir prunes. Per scope, the
+// newest Keep snapshots survive, and with MaxAge > 0 so do only those
+// younger than it; the newest snapshot of each scope and the one
+// latest.json points to are always kept.
+type GCPolicy struct {
+	Keep   int
+	MaxAge time.Duration
+	// DryRun reports what would be removed and compacted without writing.
+	DryRun bool
+}
+
+// GCResult reports what GCStateDir removed and reclaimed.
+type GCResult struct {
+	Removed        []Snapshot `json:"removed"`
+	ReclaimedBytes int64      `json:"reclaimed_bytes"`
+	// CompactedOutputs counts tool outputs dropped from the latest bundle.
+	CompactedOutputs int `json:"compacted_outputs"`
+	// PrunedCalls counts call ledger entries removed from calls/.
+	PrunedCalls int `json:"pruned_calls"`
+	// Latest is the latest snapshot after compaction.
+	Latest *Snapshot `json:"latest,omitempty"`
+}
+
+// GCStateDir compacts the latest bundle, prunes snapshots per policy, and
+// then prunes the call ledger, holding the state lock for the whole pass so a concurrent save
+// is neither compacted away nor interleaved with it. Compaction rewrites the latest snapshot with oversized tool
+// outputs in its transcript replaced by a marker; the bundle keeps its
+// created_at and prev_sha, and the uncompacted file is removed.
+func GCStateDir(dir string, policy GCPolicy) (GCResult, error) {
+	var res GCResult
+	if policy.Keep < 1 {
+		return res, errors.New("keep must be at least 1")
+	}
+	if policy.MaxAge < 0 {
+		return res, errors.New("max age must not be negative")
+	}
+	if err := ensureSecureStateDir(dir); err != nil {
+		return res, err
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return res, err
+	}
+	if unlock, lockErr := acquireStateLock(dir); lockErr == nil && unlock != nil {
+		defer unlock()
+	}
+	snaps, err := ListSnapshots(dir)
+	if err != nil {
+		return res, err
+	}
+	for i, s := range snaps {
+		if !s.Latest {
+			continue
+		}
+		compacted, n, err := compactSnapshot(dir, s, policy.DryRun)
+		if err != nil {
+			return res, fmt.Errorf("compact %s: %w", s.Path, err)
+		}
+		res.CompactedOutputs = n
+		if n > 0 && !policy.DryRun {
+			res.ReclaimedBytes += s.Size - compacted.Size
+			snaps[i] = compacted
+		}
+		latest := snaps[i]
+		res.Latest = &latest
+	}
+
+	now := clock.Now().UTC()
+	byScope := map[string][]Snapshot{}
+	for _, s := range snaps {
+		byScope[s.ScopeKey] = append(byScope[s.ScopeKey], s)
+	}
+	var doomed []Snapshot
+	for _, list := range byScope {
+		// list is oldest first; walk from the newest
+		kept := 0
+		for i := len(list) - 1; i >= 0; i-- {
+			s := list[i]
+			keep := s.Latest || i == len(list)-1
+			if !keep && kept < policy.Keep {
+				keep = policy.MaxAge == 0 || !olderThan(s.CreatedAt, now, policy.MaxAge)
+			}
+			if keep {
+				kept++
+				continue
+			}
+			doomed = append(doomed, s)
+		}
+	}
+	gone := map[string]bool{}
+	for _, s := range doomed {
+		gone[s.Path] = true
+	}
+	var kept []Snapshot
+	for _, s := range snaps {
+		if !gone[s.Path] {
+			kept = append(kept, s)
+		}
+	}
+	res.Removed = []Snapshot{}
+	for _, s := range doomed {
+		if !policy.DryRun {
+			if err := os.Remove(filepath.Join(dir, s.Path)); err != nil && !os.IsNotExist(err) {
+				return res, err
+			}
+		}
+		res.Removed = append(res.Removed, s)
+		res.ReclaimedBytes += s.Size
+	}
+	n, size, err := pruneCallLedger(dir, kept, policy.MaxAge, now, policy.DryRun)
+	if err != nil {
+		return res, fmt.Errorf("prune call ledger: %w", err)
+	}
+	res.PrunedCalls = n
+	res.ReclaimedBytes += size
+	return res, nil
+}
+
+// pruneCallLedger removes the entries of the call ledger under calls/ that
+// no resumed run can reuse. An entry survives while its call is unanswered
+// in a kept snapshot; otherwise only while it is newer than the latest
+// snapshot, as a run that crashed since may be resumed from a transcript
+// file, and, with maxAge, younger than it.
+func pruneCallLedger(dir string, kept []Snapshot, maxAge time.Duration, now time.Time, dryRun bool) (int, int64, error) {
+	ledger := filepath.Join(dir, "calls")
+	entries, err := os.ReadDir(ledger)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, 0, nil
+		}
+		return 0, 0, err
+	}
+	var latest time.Time
+	pending := map[string]bool{}
+	for _, s := range kept {
+		if t, err := time.Parse(time.RFC3339, s.CreatedAt); err == nil && s.Latest {
+			latest = t
+		}
+		data, err := os.ReadFile(filepath.Join(dir, s.Path))
+		if err != nil {
+			return 0, 0, err
+		}
+		var b StateBundle
+		if err := json.Unmarshal(data, &b); err != nil {
+			return 0, 0, fmt.Errorf("%s: %w", s.Path, err)
+		}
+		for _, id := range pendingCallIDs(b) {
+			pending[id] = true
+		}
+	}
+	n, size := 0, int64(0)
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(ledger, e.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return n, size, err
+		}
+		var entry struct {
+			CallID string `json:"call_id"`
+			TS     string `json:"ts"`
+		}
+		if json.Unmarshal(data, &entry) == nil {
+			ts, err := time.Parse(time.RFC3339Nano, entry.TS)
+			recent := err == nil && ts.After(latest) && (maxAge == 0 || now.Sub(ts) <= maxAge)
+			if pending[entry.CallID] || recent {
+				continue
+			}
+		}
+		if !dryRun {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return n, size, err
+			}
+		}
+		n++
+		size += int64(len(data))
+	}
+	return n, size, nil
+}
+
+// pendingCallIDs returns the ids of the tool calls of the bundle's last
+// assistant message that no later tool message answers.
+func pendingCallIDs(b StateBundle) []string {
+	msgs, _ := b.Context["messages"].([]any)
+	answered := map[string]bool{}
+	for i := len(msgs) - 1; i >= 0; i-- {
+		m, _ := msgs[i].(map[string]any)
+		switch m["role"] {
+		case "tool":
+			if id, ok := m["tool_call_id"].(string); ok {
+				answered[id] = true
+			}
+			continue
+		case "assistant":
+			calls, _ := m["tool_calls"].([]any)
+			var ids []string
+			for _, raw := range calls {
+				c, _ := raw.(map[string]any)
+				if id, ok := c["id"].(string); ok && !answered[id] {
+					ids = append(ids, id)
+				}
+			}
+			return ids
+		}
+		return nil
+	}
+	return nil
+}
+
+func olderThan(createdAt string, now time.Time, age time.Duration) bool {
+	t, err := time.Parse(time.RFC3339, createdAt)
+	return err == nil && now.Sub(t) > age
+}
+
+// compactSnapshot drops oversized tool outputs from the snapshot's
+// context transcript and, unless dryRun, saves the result in its place.
+// The caller holds the state lock.
+func compactSnapshot(dir string, s Snapshot, dryRun bool) (Snapshot, int, error) {
+	data, err := os.ReadFile(filepath.Join(dir, s.Path))
+	if err != nil {
+		return s, 0, err
+	}
+	var b StateBundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		return s, 0, err
+	}
+	msgs, _ := b.Context["messages"].([]any)
+	n := 0
+	for _, raw := range msgs {
+		m, ok := raw.(map[string]any)
+		if !ok || m["role"] != "tool" {
+			continue
+		}
+		if c, ok := m["content"].(string); ok && len(c) > CompactToolOutputLimit {
+			m["content"] = compactedToolOutput
+			n++
+		}
+	}
+	if n == 0 || dryRun {
+		return s, n, nil
+	}
+	if err := b.Validate(); err != nil {
+		return s, 0, err
+	}
+	if err := writeStateBundle(dir, &b); err != nil {
+		return s, 0, err
+	}
+	snaps, err := ListSnapshots(dir)
+	if err != nil {
+		return s, 0, err
+	}
+	for _, c := range snaps {
+		if c.Latest {
+			if c.Path != s.Path {
+				if err := os.Remove(filepath.Join(dir, s.Path)); err != nil && !os.IsNotExist(err) {
+					return s, 0, err
+				}
+			}
+			return c, n, nil
+		}
+	}
+	return s, 0, errors.New("compacted snapshot not found")
+}
@@ -0,0 +1,210 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/clock"
+)
+
+func TestGCStateDir_PrunesPerScopeAndKeepsLatest(t *testing.T) {
+	dir := t.TempDir()
+	for i := 1; i <= 4; i++ {
+		saveTestBundle(t, dir, time.Date(2026, 1, i, 0, 0, 0, 0, time.UTC).Format(time.RFC3339), "a")
+	}
+	saveTestBundle(t, dir, "2026-01-01T12:00:00Z", "b")
+	// latest.json now points at scope b's only snapshot
+
+	dry, err := GCStateDir(dir, GCPolicy{Keep: 2, DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if len(dry.Removed) != 2 || dry.ReclaimedBytes == 0 {
+		t.Fatalf("dry run result: %+v", dry)
+	}
+	if snaps, _ := ListSnapshots(dir); len(snaps) != 5 {
+		t.Fatalf("dry run removed files: %d left", len(snaps))
+	}
+
+	res, err := GCStateDir(dir, GCPolicy{Keep: 2})
+	if err != nil {
+		t.Fatalf("gc: %v", err)
+	}
+	if len(res.Removed) != 2 {
+		t.Fatalf("removed %+v", res.Removed)
+	}
+	for _, s := range res.Removed {
+		if s.ScopeKey != "a" || s.CreatedAt > "2026-01-02T00:00:00Z" {
+			t.Fatalf("removed the wrong snapshot: %+v", s)
+		}
+	}
+	snaps, err := ListSnapshots(dir)
+	if err != nil || len(snaps) != 3 {
+		t.Fatalf("after gc: %v %+v", err, snaps)
+	}
+}
+
+func TestGCStateDir_MaxAgeKeepsNewestPerScope(t *testing.T) {
+	clock.SetDeterministic(0)
+	t.Cleanup(clock.Reset)
+	now := clock.Now().UTC()
+	dir := t.TempDir()
+	saveTestBundle(t, dir, now.Add(-72*time.Hour).Format(time.RFC3339), "a")
+	saveTestBundle(t, dir, now.Add(-48*time.Hour).Format(time.RFC3339), "a")
+	saveTestBundle(t, dir, now.Add(-time.Hour).Format(time.RFC3339), "b")
+
+	res, err := GCStateDir(dir, GCPolicy{Keep: 10, MaxAge: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("gc: %v", err)
+	}
+	if len(res.Removed) != 1 || res.Removed[0].CreatedAt != now.Add(-72*time.Hour).Format(time.RFC3339) {
+		t.Fatalf("want only the oldest snapshot of scope a removed: %+v", res.Removed)
+	}
+}
+
+func TestGCStateDir_CompactsLatestToolOutputs(t *testing.T) {
+	dir := t.TempDir()
+	big := strings.Repeat("line\n", CompactToolOutputLimit/5+1)
+	b := &StateBundle{
+		Version:     "1",
+		CreatedAt:   "2026-01-01T00:00:00Z",
+		ToolVersion: "test-1",
+		ModelID:     "gpt-5",
+		BaseURL:     "http://example.local",
+		ScopeKey:    "a",
+		PrevSHA:     "abc123",
+		Context: map[string]any{"messages": []any{
+			map[string]any{"role": "user", "content": big},
+			map[string]any{"role": "tool", "tool_call_id": "1", "content": big},
+			map[string]any{"role": "tool", "tool_call_id": "2", "content": "small"},
+		}},
+	}
+	if err := SaveStateBundle(dir, b); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	before, _ := ListSnapshots(dir)
+
+	res, err := GCStateDir(dir, GCPolicy{Keep: 1})
+	if err != nil {
+		t.Fatalf("gc: %v", err)
+	}
+	if res.CompactedOutputs != 1 || res.ReclaimedBytes <= 0 || res.Latest == nil {
+		t.Fatalf("result %+v", res)
+	}
+	if _, err := os.Stat(filepath.Join(dir, before[0].Path)); !os.IsNotExist(err) {
+		t.Fatalf("uncompacted snapshot still present: %v", err)
+	}
+	got, snap, err := LoadSnapshot(dir, "latest")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if snap.SHA256 != res.Latest.SHA256 || got.CreatedAt != b.CreatedAt || got.PrevSHA != "abc123" {
+		t.Fatalf("compacted bundle metadata changed: %+v", snap)
+	}
+	msgs := got.Context["messages"].([]any)
+	if msgs[0].(map[string]any)["content"] != big {
+		t.Fatalf("user message was compacted")
+	}
+	if msgs[1].(map[string]any)["content"] != compactedToolOutput || msgs[2].(map[string]any)["content"] != "small" {
+		t.Fatalf("tool outputs: %v / %v", msgs[1], msgs[2])
+	}
+}
+
+func TestGCStateDir_WaitsForStateLock(t *testing.T) {
+	dir := t.TempDir()
+	saveTestBundle(t, dir, "2026-01-01T00:00:00Z", "a")
+	saveTestBundle(t, dir, "2026-01-02T00:00:00Z", "a")
+	lockPath := filepath.Join(dir, "state.lock")
+	if err := os.WriteFile(lockPath, []byte("held\n"), 0o600); err != nil {
+		t.Fatalf("hold lock: %v", err)
+	}
+
+	done := make(chan GCResult, 1)
+	go func() {
+		res, err := GCStateDir(dir, GCPolicy{Keep: 1})
+		if err != nil {
+			t.Errorf("gc: %v", err)
+		}
+		done <- res
+	}()
+	select {
+	case <-done:
+		t.Fatal("gc ran while the state lock was held")
+	case <-time.After(300 * time.Millisecond):
+	}
+	if snaps, _ := ListSnapshots(dir); len(snaps) != 2 {
+		t.Fatalf("snapshots changed under the lock: %d left", len(snaps))
+	}
+	if err := os.Remove(lockPath); err != nil {
+		t.Fatalf("release lock: %v", err)
+	}
+	res := <-done
+	if len(res.Removed) != 1 {
+		t.Fatalf("removed %+v", res.Removed)
+	}
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Fatalf("gc left its lock behind: %v", err)
+	}
+}
+
+func TestGCStateDir_PrunesCallLedger(t *testing.T) {
+	dir := t.TempDir()
+	b := &StateBundle{
+		Version:     "1",
+		CreatedAt:   "2026-01-01T00:00:00Z",
+		ToolVersion: "test-1",
+		ModelID:     "gpt-5",
+		BaseURL:     "http://example.local",
+		ScopeKey:    "a",
+		Context: map[string]any{"messages": []any{
+			map[string]any{"role": "user", "content": "write a and b"},
+			map[string]any{"role": "assistant", "tool_calls": []any{
+				map[string]any{"id": "c1", "type": "function"},
+				map[string]any{"id": "c2", "type": "function"},
+			}},
+			map[string]any{"role": "tool", "tool_call_id": "c1", "content": "{}"},
+		}},
+	}
+	if err := SaveStateBundle(dir, b); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	calls := filepath.Join(dir, "calls")
+	if err := os.MkdirAll(calls, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	for name, body := range map[string]string{
+		"k1.json":  `{"tool":"write","call_id":"c1","content":"{}"}`,
+		"k2.json":  `{"tool":"write","call_id":"c2","content":"{}"}`,
+		"k3.json":  `{"tool":"write","call_id":"c9","content":"{}","ts":"2026-01-02T00:00:00Z"}`,
+		"bad.json": `not json`,
+	} {
+		if err := os.WriteFile(filepath.Join(calls, name), []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dry, err := GCStateDir(dir, GCPolicy{Keep: 1, DryRun: true})
+	if err != nil || dry.PrunedCalls != 2 {
+		t.Fatalf("dry run: %v %+v", err, dry)
+	}
+	if left, _ := os.ReadDir(calls); len(left) != 4 {
+		t.Fatalf("dry run removed ledger entries: %d left", len(left))
+	}
+	res, err := GCStateDir(dir, GCPolicy{Keep: 1})
+	if err != nil || res.PrunedCalls != 2 || res.ReclaimedBytes == 0 {
+		t.Fatalf("gc: %v %+v", err, res)
+	}
+	left, _ := os.ReadDir(calls)
+	if len(left) != 2 || left[0].Name() != "k2.json" || left[1].Name() != "k3.json" {
+		t.Fatalf("want the pending call's and the newer entry kept: %v", left)
+	}
+
+	// -max-age ages out entries newer than the latest snapshot too
+	res, err = GCStateDir(dir, GCPolicy{Keep: 1, MaxAge: 24 * time.Hour})
+	if err != nil || res.PrunedCalls != 1 {
+		t.Fatalf("gc with max age: %v %+v", err, res)
+	}
+}
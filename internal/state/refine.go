@@ -7,6 +7,8 @@ import (
 	"errors"
 	"strings"
 	"time"
+
+	"github.com/hyperifyio/goagent/internal/clock"
 )
 
 // RefineStateBundle produces a new StateBundle derived from prev by applying a
@@ -76,7 +78,7 @@ func RefineStateBundle(prev *StateBundle, refineInput string, userPrompt string)
 	prompts["developer"] = strings.TrimSpace(strings.Join(parts, "\n\n"))
 
 	// Timestamp: ensure it advances at least by 1s if equal to previous
-	now := time.Now().UTC().Truncate(time.Second)
+	now := clock.Now().UTC().Truncate(time.Second)
 	if prev.CreatedAt == now.Format(time.RFC3339) {
 		now = now.Add(time.Second)
 	}
@@ -94,6 +96,7 @@ func RefineStateBundle(prev *StateBundle, refineInput string, userPrompt string)
 		Context:      cloneAnyMap(prev.Context),
 		ToolCaps:     cloneAnyMap(prev.ToolCaps),
 		Custom:       cloneAnyMap(prev.Custom),
+		Plan:         append([]Task(nil), prev.Plan...),
 		// Recompute based on identifying fields
 		SourceHash: ComputeSourceHash(prev.ModelID, prev.BaseURL, prev.ToolsetHash, prev.ScopeKey),
 		PrevSHA:    prevSHAHex,
@@ -0,0 +1,120 @@
+package state
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/clock"
+)
+
+// runsDir holds one RunLog per run, plus a "latest" pointer file.
+const runsDir = "runs"
+
+var reRunID = regexp.MustCompile(`^[0-9A-Za-z][0-9A-Za-z-]*$`)
+
+// RunStep records how step Step built its request: the transcript length at
+// that point and the completion cap after length backoff and clamping. A step
+// retried for finish_reason=length keeps its last attempt.
+type RunStep struct {
+	Step      int `json:"step"`
+	Messages  int `json:"messages"`
+	MaxTokens int `json:"max_tokens,omitempty"`
+}
+
+// RunLog is the per-run record used to reconstruct the request of any step.
+// Settings and Messages are opaque to this package; the CLI owns their shape.
+type RunLog struct {
+	Version   string          `json:"version"`
+	RunID     string          `json:"run_id"`
+	CreatedAt string          `json:"created_at"`
+	ScopeKey  string          `json:"scope_key,omitempty"`
+	Settings  json.RawMessage `json:"settings"`
+	Steps     []RunStep       `json:"steps"`
+	Messages  json.RawMessage `json:"messages"`
+}
+
+// NewRunID returns a sortable run identifier such as 20260102T150405Z-1a2b3c4d.
+func NewRunID() string {
+	var b [4]byte
+	clock.Read(b[:])
+	return clock.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(b[:])
+}
+
+// Validate checks the version, run id, and step numbering.
+func (l *RunLog) Validate() error {
+	if l == nil {
+		return errors.New("nil run log")
+	}
+	if l.Version != "1" {
+		return errInvalidVersion
+	}
+	if !reRunID.MatchString(l.RunID) {
+		return fmt.Errorf("invalid run id %q", l.RunID)
+	}
+	for i, s := range l.Steps {
+		if s.Step != i+1 || s.Messages < 0 || s.MaxTokens < 0 {
+			return fmt.Errorf("step %d: invalid record %+v", i+1, s)
+		}
+	}
+	return nil
+}
+
+// SaveRunLog atomically writes runs/<run_id>.json under dir with 0600
+// permissions and points runs/latest at it.
+func SaveRunLog(dir string, l *RunLog) error {
+	if l != nil && l.CreatedAt == "" {
+		cp := *l
+		cp.CreatedAt = clock.Now().UTC().Format(time.RFC3339)
+		l = &cp
+	}
+	if err := l.Validate(); err != nil {
+		return fmt.Errorf("invalid run log: %w", err)
+	}
+	if err := ensureSecureStateDir(dir); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	rd := filepath.Join(dir, runsDir)
+	if err := writeFileAtomic(rd, filepath.Join(rd, l.RunID+".json"), data); err != nil {
+		return err
+	}
+	return writeFileAtomic(rd, filepath.Join(rd, "latest"), []byte(l.RunID+"\n"))
+}
+
+// LoadRunLog reads the run saved under dir as id; "latest" resolves to the
+// most recently saved run.
+func LoadRunLog(dir, id string) (*RunLog, error) {
+	rd := filepath.Join(dir, runsDir)
+	if id == "latest" {
+		b, err := os.ReadFile(filepath.Join(rd, "latest"))
+		if err != nil {
+			return nil, fmt.Errorf("no saved runs in %s: %w", dir, err)
+		}
+		id = strings.TrimSpace(string(b))
+	}
+	if !reRunID.MatchString(id) {
+		return nil, fmt.Errorf("invalid run id %q", id)
+	}
+	data, err := os.ReadFile(filepath.Join(rd, id+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("load run %s: %w", id, err)
+	}
+	var l RunLog
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, fmt.Errorf("load run %s: %w", id, ErrStateInvalid)
+	}
+	if err := l.Validate(); err != nil {
+		return nil, fmt.Errorf("load run %s: %w", id, ErrStateInvalid)
+	}
+	return &l, nil
+}
@@ -0,0 +1,54 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunLog_SaveLoadLatest(t *testing.T) {
+	dir := t.TempDir()
+	first := &RunLog{Version: "1", RunID: "20260101T000000Z-00000001", Settings: json.RawMessage(`{}`), Messages: json.RawMessage(`[]`)}
+	second := &RunLog{Version: "1", RunID: NewRunID(), Settings: json.RawMessage(`{"model":"m"}`), Messages: json.RawMessage(`[{"role":"user"}]`),
+		Steps: []RunStep{{Step: 1, Messages: 1}, {Step: 2, Messages: 3, MaxTokens: 512}}}
+	for _, l := range []*RunLog{first, second} {
+		if err := SaveRunLog(dir, l); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+	}
+	got, err := LoadRunLog(dir, "latest")
+	if err != nil || got.RunID != second.RunID || got.CreatedAt == "" || len(got.Steps) != 2 || got.Steps[1].MaxTokens != 512 {
+		t.Fatalf("latest: %+v err=%v", got, err)
+	}
+	if got, err := LoadRunLog(dir, first.RunID); err != nil || got.RunID != first.RunID {
+		t.Fatalf("by id: %+v err=%v", got, err)
+	}
+	if fi, err := os.Stat(filepath.Join(dir, "runs", first.RunID+".json")); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Fatalf("run log perms: %v %v", fi, err)
+	}
+}
+
+func TestRunLog_RejectsBadIDsAndSteps(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := LoadRunLog(dir, "../state"); err == nil {
+		t.Fatal("path traversal id must be rejected")
+	}
+	if _, err := LoadRunLog(dir, "latest"); err == nil {
+		t.Fatal("missing latest must error")
+	}
+	bad := &RunLog{Version: "1", RunID: "r1", Steps: []RunStep{{Step: 2}}}
+	if err := SaveRunLog(dir, bad); err == nil {
+		t.Fatal("out-of-order steps must be rejected")
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "runs"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "runs", "r2.json"), []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadRunLog(dir, "r2"); !errors.Is(err, ErrStateInvalid) {
+		t.Fatalf("corrupt log: want ErrStateInvalid, got %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/sigv4"
+)
+
+// maxStateObjectBytes bounds a state file downloaded from a remote store.
+const maxStateObjectBytes = 64 << 20
+
+// S3Backend stores state files as objects under a bucket prefix of an
+// S3-compatible store, addressed path-style so custom endpoints work.
+type S3Backend struct {
+	endpoint *url.URL
+	bucket   string
+	prefix   string
+	region   string
+	creds    sigv4.Credentials
+	client   *http.Client
+}
+
+// NewS3Backend returns a backend for bucket/prefix at endpoint, signing with
+// credentials from the environment.
+func NewS3Backend(endpoint, bucket, prefix, region string, client *http.Client) (*S3Backend, error) {
+	u, err := url.Parse(strings.TrimRight(endpoint, "/"))
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", endpoint)
+	}
+	creds, err := sigv4.CredentialsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if bucket == "" {
+		return nil, fmt.Errorf("state remote has no bucket")
+	}
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &S3Backend{endpoint: u, bucket: bucket, prefix: prefix, region: region, creds: creds, client: client}, nil
+}
+
+type s3ListResult struct {
+	Contents []struct {
+		Key  string `xml:"Key"`
+		ETag string `xml:"ETag"`
+		Size int64  `xml:"Size"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List pages through ListObjectsV2 under the prefix.
+func (b *S3Backend) List(ctx context.Context) ([]Object, error) {
+	var out []Object
+	token := ""
+	for {
+		q := url.Values{"list-type": {"2"}, "prefix": {b.prefix}}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		body, err := b.do(ctx, http.MethodGet, "", q, nil)
+		if err != nil {
+			return nil, err
+		}
+		var res s3ListResult
+		if err := xml.Unmarshal(body, &res); err != nil {
+			return nil, fmt.Errorf("decode list: %w", err)
+		}
+		for _, c := range res.Contents {
+			name := strings.TrimPrefix(c.Key, b.prefix)
+			if validObjectName(name) != nil {
+				continue
+			}
+			etag := strings.Trim(c.ETag, `"`)
+			if len(etag) != 32 {
+				// Multipart uploads have no content MD5 as ETag
+				etag = ""
+			}
+			out = append(out, Object{Name: name, Size: c.Size, MD5: strings.ToLower(etag)})
+		}
+		if !res.IsTruncated || res.NextContinuationToken == "" {
+			return out, nil
+		}
+		token = res.NextContinuationToken
+	}
+}
+
+// Get downloads one object; a missing object returns an error wrapping
+// fs.ErrNotExist.
+func (b *S3Backend) Get(ctx context.Context, name string) ([]byte, error) {
+	if err := validObjectName(name); err != nil {
+		return nil, err
+	}
+	return b.do(ctx, http.MethodGet, name, nil, nil)
+}
+
+// Put uploads one object.
+func (b *S3Backend) Put(ctx context.Context, name string, data []byte) error {
+	if err := validObjectName(name); err != nil {
+		return err
+	}
+	_, err := b.do(ctx, http.MethodPut, name, nil, data)
+	return err
+}
+
+func (b *S3Backend) do(ctx context.Context, method, name string, q url.Values, body []byte) ([]byte, error) {
+	u := *b.endpoint
+	u.Path = strings.TrimRight(u.Path, "/") + "/" + b.bucket
+	if name != "" {
+		u.Path += "/" + b.prefix + name
+	}
+	u.RawQuery = q.Encode()
+	var rd io.Reader
+	if body != nil {
+		rd = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), rd)
+	if err != nil {
+		return nil, err
+	}
+	hash := sigv4.EmptyPayloadHash
+	if body != nil {
+		hash = sigv4.PayloadHash(body)
+		req.Header.Set("Content-Type", "application/json")
+	}
+	// S3 refuses signatures skewed from real time, so sign with the wall
+	// clock even when -deterministic freezes clock.Now
+	sigv4.Sign(req, hash, b.creds, b.region, "s3", time.Now())
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }() //nolint:errcheck
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxStateObjectBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound && name != "":
+		return nil, fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+	case resp.StatusCode >= 300:
+		return nil, fmt.Errorf("%s %s: http status %d: %s", method, u.Path, resp.StatusCode, s3ErrorCode(data))
+	case len(data) > maxStateObjectBytes:
+		return nil, fmt.Errorf("%s: larger than %d bytes", name, maxStateObjectBytes)
+	}
+	return data, nil
+}
+
+// s3ErrorCode extracts the <Code> of an S3 error document.
+func s3ErrorCode(body []byte) string {
+	var e struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if xml.Unmarshal(body, &e) != nil || e.Code == "" {
+		return "unexpected response"
+	}
+	if e.Message != "" {
+		return e.Code + ": " + e.Message
+	}
+	return e.Code
+}
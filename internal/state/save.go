@@ -131,7 +131,12 @@ func SaveStateBundle(dir string, bundle *StateBundle) error {
 	if unlock, lockErr := acquireStateLock(dir); lockErr == nil && unlock != nil {
 		defer unlock()
 	}
+	return writeStateBundle(dir, bundle)
+}
 
+// writeStateBundle writes the snapshot and latest.json for a validated
+// bundle; the caller holds the state lock.
+func writeStateBundle(dir string, bundle *StateBundle) error {
 	// Redact/sanitize secrets before persisting
 	sanitized, err := sanitizeBundleForSave(bundle)
 	if err != nil {
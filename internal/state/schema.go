@@ -30,6 +30,8 @@ type StateBundle struct {
 	Custom       map[string]any    `json:"custom"`
 	SourceHash   string            `json:"source_hash"`
 	PrevSHA      string            `json:"prev_sha,omitempty"`
+	// Plan is the pre-stage task breakdown with each step's progress.
+	Plan []Task `json:"plan,omitempty"`
 }
 
 var (
@@ -60,6 +62,11 @@ func (b *StateBundle) Validate() error {
 	if b.ScopeKey == "" {
 		return errMissingScope
 	}
+	if len(b.Plan) > 0 {
+		if err := validateTasks(b.Plan); err != nil {
+			return fmt.Errorf("plan: %w", err)
+		}
+	}
 	// Optional maps may be nil; normalize callers should handle nil.
 	return nil
 }
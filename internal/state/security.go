@@ -85,6 +85,11 @@ func sanitizeBundleForSave(b *StateBundle) (*StateBundle, error) {
 	out.Context = sanitizeAnyMap(b.Context)
 	out.ToolCaps = sanitizeAnyMap(b.ToolCaps)
 	out.Custom = sanitizeAnyMap(b.Custom)
+	for _, t := range b.Plan {
+		t.Title = sanitizeStringByHeuristics("title", t.Title)
+		t.Result = sanitizeStringByHeuristics("result", t.Result)
+		out.Plan = append(out.Plan, t)
+	}
 
 	// Validate round-trip JSON to ensure serializable
 	if _, err := json.Marshal(out); err != nil {
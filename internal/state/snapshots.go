@@ -0,0 +1,144 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/clock"
+)
+
+// Snapshot describes one state-*.json file in a state directory.
+type Snapshot struct {
+	Path      string `json:"path"`
+	SHA256    string `json:"sha256"`
+	CreatedAt string `json:"created_at"`
+	ScopeKey  string `json:"scope_key"`
+	ModelID   string `json:"model_id"`
+	PrevSHA   string `json:"prev_sha,omitempty"`
+	Latest    bool   `json:"latest"`
+	Size      int64  `json:"size"`
+}
+
+// ListSnapshots returns the valid snapshots in dir, oldest first. Files that
+// do not decode or validate are skipped; quarantined files never match.
+func ListSnapshots(dir string) ([]Snapshot, error) {
+	if err := ensureSecureStateDir(dir); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	latest := ""
+	if data, err := os.ReadFile(filepath.Join(dir, "latest.json")); err == nil {
+		var ptr latestPointer
+		if json.Unmarshal(data, &ptr) == nil {
+			latest = ptr.Path
+		}
+	}
+	var out []Snapshot
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, "state-") || filepath.Ext(name) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		var b StateBundle
+		if json.Unmarshal(data, &b) != nil || b.Validate() != nil {
+			continue
+		}
+		sum := sha256.Sum256(data)
+		out = append(out, Snapshot{
+			Path:      name,
+			SHA256:    hex.EncodeToString(sum[:]),
+			CreatedAt: b.CreatedAt,
+			ScopeKey:  b.ScopeKey,
+			ModelID:   b.ModelID,
+			PrevSHA:   b.PrevSHA,
+			Latest:    name == latest,
+			Size:      int64(len(data)),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CreatedAt != out[j].CreatedAt {
+			return out[i].CreatedAt < out[j].CreatedAt
+		}
+		return out[i].Path < out[j].Path
+	})
+	return out, nil
+}
+
+// LoadSnapshot loads the snapshot whose SHA-256 starts with prefix (at least
+// 4 hex characters), or the one latest.json points to for "latest".
+func LoadSnapshot(dir, prefix string) (*StateBundle, Snapshot, error) {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	snaps, err := ListSnapshots(dir)
+	if err != nil {
+		return nil, Snapshot{}, err
+	}
+	var match []Snapshot
+	for _, s := range snaps {
+		if (prefix == "latest" && s.Latest) || (len(prefix) >= 4 && strings.HasPrefix(s.SHA256, prefix)) {
+			match = append(match, s)
+		}
+	}
+	switch {
+	case len(prefix) < 4 && prefix != "latest":
+		return nil, Snapshot{}, fmt.Errorf("snapshot id %q is too short; give at least 4 hex characters", prefix)
+	case len(match) == 0:
+		return nil, Snapshot{}, fmt.Errorf("no snapshot %q in %s", prefix, dir)
+	case len(match) > 1:
+		return nil, Snapshot{}, fmt.Errorf("snapshot id %q is ambiguous (%d matches)", prefix, len(match))
+	}
+	data, err := os.ReadFile(filepath.Join(dir, match[0].Path))
+	if err != nil {
+		return nil, Snapshot{}, err
+	}
+	var b StateBundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, Snapshot{}, err
+	}
+	return &b, match[0], nil
+}
+
+// ForkLatestStateBundle copies the latest bundle into scope as a new
+// snapshot whose prev_sha is the source, and points latest.json at it. The
+// source snapshot is left untouched.
+func ForkLatestStateBundle(dir, scope string) (*StateBundle, error) {
+	scope = strings.TrimSpace(scope)
+	if scope == "" {
+		return nil, errors.New("fork scope is empty")
+	}
+	src, snap, err := LoadSnapshot(dir, "latest")
+	if err != nil {
+		return nil, err
+	}
+	if src.ScopeKey == scope {
+		return nil, fmt.Errorf("latest snapshot is already in scope %q", scope)
+	}
+	now := clock.Now().UTC().Truncate(time.Second)
+	if src.CreatedAt == now.Format(time.RFC3339) {
+		now = now.Add(time.Second)
+	}
+	// src was just decoded, so the copy may share its maps
+	fork := *src
+	fork.CreatedAt = now.Format(time.RFC3339)
+	fork.ScopeKey = scope
+	fork.SourceHash = ComputeSourceHash(src.ModelID, src.BaseURL, src.ToolsetHash, scope)
+	fork.PrevSHA = snap.SHA256
+	if err := SaveStateBundle(dir, &fork); err != nil {
+		return nil, err
+	}
+	return &fork, nil
+}
@@ -0,0 +1,89 @@
+package state
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func saveTestBundle(t *testing.T, dir, created, scope string) {
+	t.Helper()
+	b := &StateBundle{
+		Version:     "1",
+		CreatedAt:   created,
+		ToolVersion: "test-1",
+		ModelID:     "gpt-5",
+		BaseURL:     "http://example.local",
+		ToolsetHash: "abc",
+		ScopeKey:    scope,
+		Prompts:     map[string]string{"system": "hi " + scope},
+		SourceHash:  ComputeSourceHash("gpt-5", "http://example.local", "abc", scope),
+	}
+	if err := SaveStateBundle(dir, b); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+}
+
+func TestListAndLoadSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	saveTestBundle(t, dir, "2026-01-01T00:00:00Z", "a")
+	saveTestBundle(t, dir, "2026-01-02T00:00:00Z", "b")
+
+	snaps, err := ListSnapshots(dir)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(snaps) != 2 || snaps[0].ScopeKey != "a" || snaps[1].ScopeKey != "b" {
+		t.Fatalf("want snapshots a, b oldest first; got %+v", snaps)
+	}
+	if snaps[0].Latest || !snaps[1].Latest {
+		t.Fatalf("want only the second snapshot latest; got %+v", snaps)
+	}
+
+	b, s, err := LoadSnapshot(dir, strings.ToUpper(snaps[0].SHA256[:8]))
+	if err != nil {
+		t.Fatalf("load by prefix: %v", err)
+	}
+	if s.Path != snaps[0].Path || b.Prompts["system"] != "hi a" {
+		t.Fatalf("loaded wrong snapshot: %+v %+v", s, b.Prompts)
+	}
+	if _, s, err := LoadSnapshot(dir, "latest"); err != nil || s.ScopeKey != "b" {
+		t.Fatalf("load latest: %+v %v", s, err)
+	}
+	if _, _, err := LoadSnapshot(dir, "ab"); err == nil || !strings.Contains(err.Error(), "too short") {
+		t.Fatalf("want too-short error, got %v", err)
+	}
+	if _, _, err := LoadSnapshot(dir, "zzzz"); err == nil || !strings.Contains(err.Error(), "no snapshot") {
+		t.Fatalf("want not-found error, got %v", err)
+	}
+}
+
+func TestForkLatestStateBundle(t *testing.T) {
+	dir := t.TempDir()
+	saveTestBundle(t, dir, time.Now().UTC().Truncate(time.Second).Format(time.RFC3339), "main")
+	before, err := ListSnapshots(dir)
+	if err != nil || len(before) != 1 {
+		t.Fatalf("list: %v %+v", err, before)
+	}
+
+	fork, err := ForkLatestStateBundle(dir, "experiment")
+	if err != nil {
+		t.Fatalf("fork: %v", err)
+	}
+	if fork.ScopeKey != "experiment" || fork.PrevSHA != before[0].SHA256 {
+		t.Fatalf("fork scope/prev_sha: %+v", fork)
+	}
+	if fork.SourceHash != ComputeSourceHash("gpt-5", "http://example.local", "abc", "experiment") {
+		t.Fatalf("fork source hash not recomputed")
+	}
+	after, err := ListSnapshots(dir)
+	if err != nil || len(after) != 2 {
+		t.Fatalf("list after fork: %v %+v", err, after)
+	}
+	if after[0].SHA256 != before[0].SHA256 || !after[1].Latest || after[1].ScopeKey != "experiment" {
+		t.Fatalf("want source untouched and fork latest; got %+v", after)
+	}
+	if _, err := ForkLatestStateBundle(dir, "experiment"); err == nil {
+		t.Fatalf("want error forking into the same scope")
+	}
+}
@@ -0,0 +1,173 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/clock"
+)
+
+// TaskStatus is the lifecycle state of one plan task.
+type TaskStatus string
+
+const (
+	TaskPending    TaskStatus = "pending"
+	TaskInProgress TaskStatus = "in_progress"
+	TaskDone       TaskStatus = "done"
+	TaskFailed     TaskStatus = "failed"
+)
+
+// Task is one numbered step of a plan-and-execute run.
+type Task struct {
+	ID     int        `json:"id"`
+	Title  string     `json:"title"`
+	Status TaskStatus `json:"status"`
+	Result string     `json:"result,omitempty"`
+}
+
+// TaskBoard is the persisted plan for a goal. It is stored per scope as
+// plan-<scope>.json next to the state bundles so an interrupted run can
+// resume at the first unfinished task.
+type TaskBoard struct {
+	Version   string `json:"version"`
+	GoalHash  string `json:"goal_hash"`
+	ScopeKey  string `json:"scope_key"`
+	UpdatedAt string `json:"updated_at"`
+	Tasks     []Task `json:"tasks"`
+}
+
+// GoalHash returns the identifier used to match a saved board to a prompt.
+func GoalHash(goal string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(goal)))
+	return hex.EncodeToString(sum[:])
+}
+
+// Current returns the first task that is neither done nor failed, or nil
+// when every task is finished.
+func (b *TaskBoard) Current() *Task {
+	if b == nil {
+		return nil
+	}
+	for i := range b.Tasks {
+		if b.Tasks[i].Status != TaskDone && b.Tasks[i].Status != TaskFailed {
+			return &b.Tasks[i]
+		}
+	}
+	return nil
+}
+
+// Complete reports whether every task is finished.
+func (b *TaskBoard) Complete() bool { return b != nil && b.Current() == nil }
+
+// Render formats the board as one line per task, e.g. "[x] 1. Read file".
+func (b *TaskBoard) Render() string {
+	if b == nil {
+		return ""
+	}
+	var sb strings.Builder
+	for _, t := range b.Tasks {
+		mark := " "
+		switch t.Status {
+		case TaskDone:
+			mark = "x"
+		case TaskFailed:
+			mark = "!"
+		case TaskInProgress:
+			mark = ">"
+		}
+		fmt.Fprintf(&sb, "[%s] %d. %s", mark, t.ID, t.Title)
+		if t.Result != "" {
+			fmt.Fprintf(&sb, " — %s", t.Result)
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// Validate checks ids are 1..n in order, titles are non-empty, and statuses
+// are known.
+func (b *TaskBoard) Validate() error {
+	if b == nil {
+		return errors.New("nil task board")
+	}
+	if b.Version != "1" {
+		return errInvalidVersion
+	}
+	if len(b.Tasks) == 0 {
+		return errors.New("task board has no tasks")
+	}
+	return validateTasks(b.Tasks)
+}
+
+// validateTasks checks ids are 1..n in order, titles are non-empty, and
+// statuses are known.
+func validateTasks(tasks []Task) error {
+	for i, t := range tasks {
+		if t.ID != i+1 {
+			return fmt.Errorf("task %d: id must be %d", i, i+1)
+		}
+		if strings.TrimSpace(t.Title) == "" {
+			return fmt.Errorf("task %d: empty title", t.ID)
+		}
+		switch t.Status {
+		case TaskPending, TaskInProgress, TaskDone, TaskFailed:
+		default:
+			return fmt.Errorf("task %d: invalid status %q", t.ID, t.Status)
+		}
+	}
+	return nil
+}
+
+func taskBoardPath(dir, scope string) string {
+	name := "plan.json"
+	if s := strings.TrimSpace(scope); s != "" {
+		sum := sha256.Sum256([]byte(s))
+		name = "plan-" + hex.EncodeToString(sum[:4]) + ".json"
+	}
+	return filepath.Join(dir, name)
+}
+
+// SaveTaskBoard atomically writes the board for its scope under dir with
+// 0600 permissions.
+func SaveTaskBoard(dir string, board *TaskBoard) error {
+	if err := board.Validate(); err != nil {
+		return fmt.Errorf("invalid task board: %w", err)
+	}
+	if err := ensureSecureStateDir(dir); err != nil {
+		return err
+	}
+	cp := *board
+	cp.UpdatedAt = clock.Now().UTC().Format(time.RFC3339)
+	data, err := json.MarshalIndent(&cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(dir, taskBoardPath(dir, board.ScopeKey), data)
+}
+
+// LoadTaskBoard reads the board saved for scope. It returns (nil, nil) when
+// no board exists and ErrStateInvalid when the file is unreadable or invalid.
+func LoadTaskBoard(dir, scope string) (*TaskBoard, error) {
+	data, err := os.ReadFile(taskBoardPath(dir, scope))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, ErrStateInvalid
+	}
+	var b TaskBoard
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, ErrStateInvalid
+	}
+	if err := b.Validate(); err != nil {
+		return nil, ErrStateInvalid
+	}
+	return &b, nil
+}
@@ -0,0 +1,53 @@
+package state
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTaskBoard_SaveLoadRoundTripAndCurrent(t *testing.T) {
+	dir := t.TempDir()
+	b := &TaskBoard{Version: "1", GoalHash: GoalHash("ship it"), ScopeKey: "scope-1", Tasks: []Task{
+		{ID: 1, Title: "Read", Status: TaskDone, Result: "ok"},
+		{ID: 2, Title: "Write", Status: TaskPending},
+	}}
+	if err := SaveTaskBoard(dir, b); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if _, err := LoadTaskBoard(dir, "other-scope"); err != nil {
+		t.Fatalf("other scope should be absent without error: %v", err)
+	}
+	got, err := LoadTaskBoard(dir, "scope-1")
+	if err != nil || got == nil {
+		t.Fatalf("load: %v %v", got, err)
+	}
+	if got.UpdatedAt == "" || got.GoalHash != b.GoalHash || got.Current().ID != 2 || got.Complete() {
+		t.Fatalf("unexpected board: %+v", got)
+	}
+	if want := "[x] 1. Read — ok\n[ ] 2. Write"; got.Render() != want {
+		t.Fatalf("render=%q want %q", got.Render(), want)
+	}
+	got.Tasks[1].Status = TaskFailed
+	if !got.Complete() {
+		t.Fatalf("board with only done/failed tasks should be complete")
+	}
+}
+
+func TestTaskBoard_ValidateAndCorruptFile(t *testing.T) {
+	bad := &TaskBoard{Version: "1", Tasks: []Task{{ID: 2, Title: "x", Status: TaskPending}}}
+	if err := bad.Validate(); err == nil {
+		t.Fatalf("expected id validation error")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(taskBoardPath(dir, "s"), []byte("{"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := LoadTaskBoard(dir, "s"); !errors.Is(err, ErrStateInvalid) {
+		t.Fatalf("expected ErrStateInvalid, got %v", err)
+	}
+	if filepath.Dir(taskBoardPath(dir, "s")) != dir {
+		t.Fatalf("board must live in the state dir")
+	}
+}
@@ -0,0 +1,282 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// fuzzMaxDepth bounds nesting of generated objects and arrays; below it only
+// required properties and minimum-length arrays are produced.
+const fuzzMaxDepth = 4
+
+// fuzzStrings are tricky but harmless string values. Path traversal and shell
+// metacharacter payloads are deliberately absent so fuzzing cannot be turned
+// into an attack on the host.
+var fuzzStrings = []string{"", " ", "a", "hello world", "0", "-1", "true", "null", "päivää 🌍", "line1\nline2", "tab\tand \"quotes\" \\", "{}", "[]"}
+
+// GenerateArgs returns a random JSON value conforming to schema (a JSON
+// Schema subset: type, properties, required, items, enum, const, bounds,
+// min/max length and items, oneOf/anyOf/allOf, and local $ref). Values favor
+// edge cases such as empty strings, bounds, and unicode. Regex patterns and
+// formats are not enforced.
+func GenerateArgs(schema json.RawMessage, r *rand.Rand) ([]byte, error) {
+	root := map[string]any{"type": "object"}
+	if len(schema) > 0 {
+		if err := json.Unmarshal(schema, &root); err != nil {
+			return nil, fmt.Errorf("parse schema: %w", err)
+		}
+	}
+	g := &argGenerator{s: &simplifier{root: root}, r: r}
+	return json.Marshal(g.value(root, 0))
+}
+
+type argGenerator struct {
+	s *simplifier
+	r *rand.Rand
+}
+
+func (g *argGenerator) value(n map[string]any, depth int) any {
+	n = g.s.resolveRef(n, 0)
+	if v, ok := n["const"]; ok {
+		return v
+	}
+	if enum, ok := n["enum"].([]any); ok && len(enum) > 0 {
+		return enum[g.r.Intn(len(enum))]
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if alts, ok := n[key].([]any); ok && len(alts) > 0 {
+			if alt, ok := alts[g.r.Intn(len(alts))].(map[string]any); ok {
+				return g.value(alt, depth)
+			}
+		}
+	}
+	if all, ok := n["allOf"].([]any); ok && len(all) > 0 {
+		merged := map[string]any{}
+		for k, v := range n {
+			if k != "allOf" {
+				merged[k] = v
+			}
+		}
+		for _, part := range all {
+			if pm, ok := part.(map[string]any); ok {
+				mergeObjectSchema(merged, g.s.resolveRef(pm, 0), true)
+			}
+		}
+		return g.value(merged, depth)
+	}
+	typ := g.pickType(n)
+	switch typ {
+	case "object":
+		return g.object(n, depth)
+	case "array":
+		return g.array(n, depth)
+	case "integer":
+		return int64(g.number(n, true))
+	case "number":
+		return g.number(n, false)
+	case "boolean":
+		return g.r.Intn(2) == 0
+	case "null":
+		return nil
+	default:
+		return g.str(n)
+	}
+}
+
+// pickType chooses one of the declared types, defaulting to object when
+// properties are present and to string otherwise.
+func (g *argGenerator) pickType(n map[string]any) string {
+	switch t := n["type"].(type) {
+	case string:
+		return t
+	case []any:
+		if len(t) > 0 {
+			if s, ok := t[g.r.Intn(len(t))].(string); ok {
+				return s
+			}
+		}
+	}
+	if isObjectSchema(n) {
+		return "object"
+	}
+	return "string"
+}
+
+func (g *argGenerator) object(n map[string]any, depth int) map[string]any {
+	out := map[string]any{}
+	props, _ := n["properties"].(map[string]any)
+	required := map[string]bool{}
+	for _, k := range stringList(n["required"]) {
+		required[k] = true
+	}
+	// Iterate in a stable order so a seed reproduces the same arguments
+	names := make([]string, 0, len(props))
+	for k := range props {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		if !required[k] && (depth >= fuzzMaxDepth || g.r.Intn(2) == 0) {
+			continue
+		}
+		pm, _ := props[k].(map[string]any)
+		if pm == nil {
+			pm = map[string]any{}
+		}
+		out[k] = g.value(pm, depth+1)
+	}
+	return out
+}
+
+func (g *argGenerator) array(n map[string]any, depth int) []any {
+	lo, hi := intKeyword(n, "minItems", 0), intKeyword(n, "maxItems", 3)
+	if hi < lo {
+		hi = lo
+	}
+	count := lo
+	if depth < fuzzMaxDepth {
+		count += g.r.Intn(hi - lo + 1)
+	}
+	items, _ := n["items"].(map[string]any)
+	if items == nil {
+		items = map[string]any{}
+	}
+	out := make([]any, 0, count)
+	for i := 0; i < count; i++ {
+		out = append(out, g.value(items, depth+1))
+	}
+	return out
+}
+
+// number picks a boundary or random value within minimum/maximum (and the
+// exclusive variants).
+func (g *argGenerator) number(n map[string]any, integer bool) float64 {
+	lo, hi := -1e6, 1e6
+	if v, ok := n["minimum"].(float64); ok {
+		lo = v
+	}
+	if v, ok := n["exclusiveMinimum"].(float64); ok {
+		lo = v + 1e-9
+		if integer {
+			lo = math.Floor(v) + 1
+		}
+	}
+	if v, ok := n["maximum"].(float64); ok {
+		hi = v
+	}
+	if v, ok := n["exclusiveMaximum"].(float64); ok {
+		hi = v - 1e-9
+		if integer {
+			hi = math.Ceil(v) - 1
+		}
+	}
+	if integer {
+		lo, hi = math.Ceil(lo), math.Floor(hi)
+	}
+	if hi < lo {
+		return lo
+	}
+	candidates := []float64{lo, hi}
+	for _, v := range []float64{0, 1, -1} {
+		if v >= lo && v <= hi {
+			candidates = append(candidates, v)
+		}
+	}
+	if g.r.Intn(2) == 0 {
+		return candidates[g.r.Intn(len(candidates))]
+	}
+	v := lo + g.r.Float64()*(hi-lo)
+	if integer {
+		v = math.Floor(v)
+	}
+	return v
+}
+
+// str picks a tricky string and pads or trims it to minLength/maxLength.
+func (g *argGenerator) str(n map[string]any) string {
+	s := fuzzStrings[g.r.Intn(len(fuzzStrings))]
+	if g.r.Intn(8) == 0 {
+		s = strings.Repeat("x", 1+g.r.Intn(4096))
+	}
+	rs := []rune(s)
+	if max := intKeyword(n, "maxLength", -1); max >= 0 && len(rs) > max {
+		rs = rs[:max]
+	}
+	for min := intKeyword(n, "minLength", 0); len(rs) < min; {
+		rs = append(rs, 'a')
+	}
+	return string(rs)
+}
+
+func intKeyword(n map[string]any, key string, def int) int {
+	if v, ok := n[key].(float64); ok && v >= 0 {
+		return int(v)
+	}
+	return def
+}
+
+// FuzzFailure describes one fuzz iteration that broke the tool contract.
+type FuzzFailure struct {
+	Iteration int    `json:"iteration"`
+	Args      string `json:"args"`
+	Problem   string `json:"problem"`
+}
+
+// FuzzReport summarizes fuzzing one tool. ControlledErrors counts runs that
+// failed cleanly with a JSON error on stderr, which the tool contract allows.
+type FuzzReport struct {
+	Tool             string        `json:"tool"`
+	Iterations       int           `json:"iterations"`
+	Passed           int           `json:"passed"`
+	ControlledErrors int           `json:"controlled_errors"`
+	Failures         []FuzzFailure `json:"failures,omitempty"`
+}
+
+// FuzzTool runs spec with n generated argument sets and checks the tool
+// contract: finish within the timeout, and either print valid JSON on stdout
+// or fail with a JSON error on stderr. Timeouts, crashes (non-JSON stderr such
+// as a panic trace), and invalid stdout are reported as failures.
+func FuzzTool(ctx context.Context, spec ToolSpec, n int, r *rand.Rand, defaultTimeout time.Duration) FuzzReport {
+	rep := FuzzReport{Tool: spec.Name}
+	for i := 1; i <= n && ctx.Err() == nil; i++ {
+		rep.Iterations++
+		args, err := GenerateArgs(spec.Schema, r)
+		if err != nil {
+			rep.Failures = append(rep.Failures, FuzzFailure{Iteration: i, Problem: err.Error()})
+			break
+		}
+		out, runErr := RunToolWithJSON(ctx, spec, args, defaultTimeout)
+		problem := ""
+		switch {
+		case runErr == nil && !json.Valid(out):
+			problem = "stdout is not valid JSON: " + truncateFuzz(string(out))
+		case runErr == nil:
+		case runErr.Error() == "tool timed out":
+			problem = "timed out"
+		case json.Valid([]byte(strings.TrimSpace(runErr.Error()))):
+			rep.ControlledErrors++
+		default:
+			problem = "crashed or wrote non-JSON stderr: " + truncateFuzz(runErr.Error())
+		}
+		if problem != "" {
+			rep.Failures = append(rep.Failures, FuzzFailure{Iteration: i, Args: truncateFuzz(string(args)), Problem: problem})
+			continue
+		}
+		rep.Passed++
+	}
+	return rep
+}
+
+func truncateFuzz(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) > 300 {
+		return s[:300] + "…"
+	}
+	return s
+}
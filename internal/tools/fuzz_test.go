@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestGenerateArgs_ConformsToSchema(t *testing.T) {
+	schema := json.RawMessage(`{
+		"type": "object",
+		"required": ["path", "mode", "count", "tags", "opts"],
+		"properties": {
+			"path": {"type": "string", "minLength": 2, "maxLength": 5},
+			"mode": {"enum": ["r", "w"]},
+			"count": {"type": "integer", "minimum": 1, "maximum": 3},
+			"tags": {"type": "array", "minItems": 1, "maxItems": 2, "items": {"$ref": "#/$defs/tag"}},
+			"opts": {"oneOf": [{"type": "boolean"}, {"const": "auto"}]}
+		},
+		"$defs": {"tag": {"type": "string", "maxLength": 1}}
+	}`)
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		b, err := GenerateArgs(schema, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got struct {
+			Path  string   `json:"path"`
+			Mode  string   `json:"mode"`
+			Count float64  `json:"count"`
+			Tags  []string `json:"tags"`
+			Opts  any      `json:"opts"`
+		}
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Fatalf("args %s: %v", b, err)
+		}
+		if n := len([]rune(got.Path)); n < 2 || n > 5 {
+			t.Fatalf("path length %d out of bounds: %s", n, b)
+		}
+		if got.Mode != "r" && got.Mode != "w" {
+			t.Fatalf("mode not from enum: %s", b)
+		}
+		if got.Count < 1 || got.Count > 3 || got.Count != float64(int(got.Count)) {
+			t.Fatalf("count out of bounds: %s", b)
+		}
+		if len(got.Tags) < 1 || len(got.Tags) > 2 || len([]rune(got.Tags[0])) > 1 {
+			t.Fatalf("tags violate items/bounds: %s", b)
+		}
+		if _, isBool := got.Opts.(bool); !isBool && got.Opts != "auto" {
+			t.Fatalf("opts matches no alternative: %s", b)
+		}
+	}
+	a, _ := GenerateArgs(schema, rand.New(rand.NewSource(7))) //nolint:errcheck
+	b, _ := GenerateArgs(schema, rand.New(rand.NewSource(7))) //nolint:errcheck
+	if string(a) != string(b) {
+		t.Fatalf("same seed produced different args: %s vs %s", a, b)
+	}
+}
+
+func TestFuzzTool_ClassifiesOutcomes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell tool fixtures require a POSIX shell")
+	}
+	dir := t.TempDir()
+	write := func(name, body string) ToolSpec {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte("#!/bin/sh\ncat >/dev/null\n"+body), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		return ToolSpec{Name: name, Command: []string{p}, Schema: json.RawMessage(`{"type":"object","properties":{"q":{"type":"string"}}}`), WorkDir: dir}
+	}
+	ctx := context.Background()
+	r := rand.New(rand.NewSource(1))
+
+	if rep := FuzzTool(ctx, write("ok", "echo '{\"ok\":true}'\n"), 5, r, 5*time.Second); rep.Passed != 5 || len(rep.Failures) != 0 {
+		t.Fatalf("ok tool: %+v", rep)
+	}
+	if rep := FuzzTool(ctx, write("erring", "echo '{\"error\":\"bad\"}' >&2\nexit 1\n"), 3, r, 5*time.Second); rep.Passed != 3 || rep.ControlledErrors != 3 {
+		t.Fatalf("controlled errors must pass: %+v", rep)
+	}
+	if rep := FuzzTool(ctx, write("panicky", "echo 'panic: boom' >&2\nexit 2\n"), 2, r, 5*time.Second); len(rep.Failures) != 2 {
+		t.Fatalf("crash not reported: %+v", rep)
+	}
+	if rep := FuzzTool(ctx, write("garbage", "echo 'not json'\n"), 1, r, 5*time.Second); len(rep.Failures) != 1 || rep.Failures[0].Args == "" {
+		t.Fatalf("invalid stdout not reported: %+v", rep)
+	}
+}
@@ -0,0 +1,235 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Call is one tool call as hooks see it.
+type Call struct {
+	Tool   string          `json:"tool"`
+	CallID string          `json:"call_id,omitempty"`
+	Args   json.RawMessage `json:"args"`
+}
+
+// Hook observes or changes tool calls. Calls run concurrently, so a hook
+// must be safe for concurrent use.
+type Hook interface {
+	// BeforeToolCall runs before the tool starts. It may rewrite call.Args;
+	// an error blocks the call and is reported to the model instead.
+	BeforeToolCall(ctx context.Context, call *Call) error
+	// AfterToolCall runs after the tool succeeded and returns the output the
+	// model sees; an error turns the call into a failure.
+	AfterToolCall(ctx context.Context, call Call, output []byte) ([]byte, error)
+	// OnToolError runs after the tool failed.
+	OnToolError(ctx context.Context, call Call, err error)
+}
+
+// HookFuncs adapts functions to Hook; nil fields do nothing.
+type HookFuncs struct {
+	Before  func(ctx context.Context, call *Call) error
+	After   func(ctx context.Context, call Call, output []byte) ([]byte, error)
+	OnError func(ctx context.Context, call Call, err error)
+}
+
+func (h HookFuncs) BeforeToolCall(ctx context.Context, call *Call) error {
+	if h.Before == nil {
+		return nil
+	}
+	return h.Before(ctx, call)
+}
+
+func (h HookFuncs) AfterToolCall(ctx context.Context, call Call, output []byte) ([]byte, error) {
+	if h.After == nil {
+		return output, nil
+	}
+	return h.After(ctx, call, output)
+}
+
+func (h HookFuncs) OnToolError(ctx context.Context, call Call, err error) {
+	if h.OnError != nil {
+		h.OnError(ctx, call, err)
+	}
+}
+
+// Hooks applies hooks in order around tool calls.
+type Hooks []Hook
+
+// Run runs call through the hooks with run executing the tool on the
+// (possibly rewritten) arguments.
+func (hs Hooks) Run(ctx context.Context, call Call, run func(ctx context.Context, args []byte) ([]byte, error)) ([]byte, error) {
+	for _, h := range hs {
+		if err := h.BeforeToolCall(ctx, &call); err != nil {
+			return nil, fmt.Errorf("tool call blocked by hook: %w", err)
+		}
+	}
+	out, err := run(ctx, call.Args)
+	if err != nil {
+		for _, h := range hs {
+			h.OnToolError(ctx, call, err)
+		}
+		return nil, err
+	}
+	for _, h := range hs {
+		if out, err = h.AfterToolCall(ctx, call, out); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+var (
+	hooksMu    sync.RWMutex
+	hooks      []registeredHook
+	nextHookID int
+)
+
+type registeredHook struct {
+	id   int
+	hook Hook
+}
+
+// RegisterHook adds h after the hooks already registered, for every later
+// agent tool call in this process. unregister removes it again.
+func RegisterHook(h Hook) (unregister func()) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	nextHookID++
+	id := nextHookID
+	hooks = append(hooks, registeredHook{id: id, hook: h})
+	return func() {
+		hooksMu.Lock()
+		defer hooksMu.Unlock()
+		for i, r := range hooks {
+			if r.id == id {
+				hooks = append(hooks[:i:i], hooks[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// RegisteredHooks returns the registered hooks in registration order.
+func RegisteredHooks() Hooks {
+	hooksMu.RLock()
+	defer hooksMu.RUnlock()
+	out := make(Hooks, 0, len(hooks))
+	for _, r := range hooks {
+		out = append(out, r.hook)
+	}
+	return out
+}
+
+// Hook events sent to a CommandHook.
+const (
+	HookBeforeToolCall = "before_tool_call"
+	HookAfterToolCall  = "after_tool_call"
+	HookOnToolError    = "on_tool_error"
+)
+
+// CommandHook runs an external command through sh for every hook event. The
+// command reads one JSON object on stdin: the Call fields plus "event" and,
+// after the call, "output" or "error". On before_tool_call it may print
+// {"args": {...}} to replace the arguments or {"deny": "reason"} to block the
+// call; on after_tool_call {"output": "..."} replaces the output. Empty
+// stdout changes nothing. A command that fails or times out blocks the call
+// before it runs and fails it afterwards; on_tool_error ignores the command's
+// outcome.
+type CommandHook struct {
+	Command string
+	Timeout time.Duration
+}
+
+type hookRequest struct {
+	Event string `json:"event"`
+	Call
+	Output *string `json:"output,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+type hookResponse struct {
+	Args   json.RawMessage `json:"args"`
+	Deny   string          `json:"deny"`
+	Output *string         `json:"output"`
+}
+
+func (h CommandHook) BeforeToolCall(ctx context.Context, call *Call) error {
+	resp, err := h.run(ctx, hookRequest{Event: HookBeforeToolCall, Call: *call})
+	switch {
+	case err != nil:
+		return err
+	case resp.Deny != "":
+		return errors.New(resp.Deny)
+	case len(resp.Args) > 0:
+		var obj map[string]any
+		if json.Unmarshal(resp.Args, &obj) != nil || obj == nil {
+			return fmt.Errorf("tool hook: args must be a JSON object")
+		}
+		call.Args = resp.Args
+	}
+	return nil
+}
+
+func (h CommandHook) AfterToolCall(ctx context.Context, call Call, output []byte) ([]byte, error) {
+	s := string(output)
+	resp, err := h.run(ctx, hookRequest{Event: HookAfterToolCall, Call: call, Output: &s})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Output != nil {
+		return []byte(*resp.Output), nil
+	}
+	return output, nil
+}
+
+func (h CommandHook) OnToolError(ctx context.Context, call Call, err error) {
+	_, _ = h.run(ctx, hookRequest{Event: HookOnToolError, Call: call, Error: err.Error()}) //nolint:errcheck // observers cannot change a failure
+}
+
+func (h CommandHook) run(ctx context.Context, req hookRequest) (hookResponse, error) {
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+	if len(req.Args) == 0 {
+		req.Args = json.RawMessage("{}")
+	} else if !json.Valid(req.Args) {
+		// Malformed model arguments reach the hook as a JSON string
+		quoted, _ := json.Marshal(string(req.Args)) //nolint:errcheck
+		req.Args = quoted
+	}
+	input, err := json.Marshal(req)
+	if err != nil {
+		return hookResponse{}, err
+	}
+	cmd := exec.CommandContext(ctx, "sh", "-c", h.Command)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.WaitDelay = toolKillGrace
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return hookResponse{}, fmt.Errorf("tool hook: %s: %w", req.Event, ctx.Err())
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return hookResponse{}, fmt.Errorf("tool hook: %s: %s", req.Event, msg)
+		}
+		return hookResponse{}, fmt.Errorf("tool hook: %s: %w", req.Event, err)
+	}
+	var resp hookResponse
+	if out := bytes.TrimSpace(stdout.Bytes()); len(out) > 0 {
+		if err := json.Unmarshal(out, &resp); err != nil {
+			return hookResponse{}, fmt.Errorf("tool hook: %s: invalid response: %w", req.Event, err)
+		}
+	}
+	return resp, nil
+}
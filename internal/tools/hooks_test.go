@@ -0,0 +1,162 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func echoRun(_ context.Context, args []byte) ([]byte, error) { return args, nil }
+
+func TestHooks_RunOrderAndRewrites(t *testing.T) {
+	var order []string
+	hs := Hooks{
+		HookFuncs{
+			Before: func(_ context.Context, call *Call) error {
+				order = append(order, "before1")
+				call.Args = json.RawMessage(`{"n":2}`)
+				return nil
+			},
+			After: func(_ context.Context, _ Call, out []byte) ([]byte, error) {
+				order = append(order, "after1")
+				return append(out, '!'), nil
+			},
+		},
+		HookFuncs{
+			Before: func(_ context.Context, call *Call) error {
+				order = append(order, "before2:"+string(call.Args))
+				return nil
+			},
+			After: func(_ context.Context, _ Call, out []byte) ([]byte, error) {
+				order = append(order, "after2:"+string(out))
+				return out, nil
+			},
+		},
+	}
+	out, err := hs.Run(context.Background(), Call{Tool: "t", Args: json.RawMessage(`{"n":1}`)}, echoRun)
+	if err != nil || string(out) != `{"n":2}!` {
+		t.Fatalf("out=%q err=%v", out, err)
+	}
+	if got := strings.Join(order, ","); got != `before1,before2:{"n":2},after1,after2:{"n":2}!` {
+		t.Fatalf("order=%s", got)
+	}
+}
+
+func TestHooks_BlockAndError(t *testing.T) {
+	ran := false
+	blocked := Hooks{HookFuncs{Before: func(context.Context, *Call) error { return errors.New("policy") }}}
+	_, err := blocked.Run(context.Background(), Call{Tool: "t"}, func(context.Context, []byte) ([]byte, error) {
+		ran = true
+		return nil, nil
+	})
+	if ran || err == nil || err.Error() != "tool call blocked by hook: policy" {
+		t.Fatalf("ran=%v err=%v", ran, err)
+	}
+
+	var seen error
+	failing := Hooks{HookFuncs{
+		After: func(context.Context, Call, []byte) ([]byte, error) {
+			t.Fatal("after ran for a failed call")
+			return nil, nil
+		},
+		OnError: func(_ context.Context, _ Call, err error) { seen = err },
+	}}
+	boom := errors.New("boom")
+	if _, err := failing.Run(context.Background(), Call{Tool: "t"}, func(context.Context, []byte) ([]byte, error) { return nil, boom }); !errors.Is(err, boom) || !errors.Is(seen, boom) {
+		t.Fatalf("err=%v seen=%v", err, seen)
+	}
+}
+
+func TestRegisterHook(t *testing.T) {
+	a := HookFuncs{}
+	unregisterA := RegisterHook(a)
+	unregisterB := RegisterHook(HookFuncs{OnError: func(context.Context, Call, error) {}})
+	if got := len(RegisteredHooks()); got != 2 {
+		t.Fatalf("hooks=%d", got)
+	}
+	unregisterA()
+	unregisterA()
+	hs := RegisteredHooks()
+	if len(hs) != 1 || hs[0].(HookFuncs).OnError == nil {
+		t.Fatalf("hooks=%v", hs)
+	}
+	unregisterB()
+	if got := len(RegisteredHooks()); got != 0 {
+		t.Fatalf("hooks=%d", got)
+	}
+}
+
+func writeHookScript(t *testing.T, body string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("hook scripts require a POSIX shell")
+	}
+	path := filepath.Join(t.TempDir(), "hook.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCommandHook(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "events.jsonl")
+	// Logs every event; rewrites args, denies "rm", and wraps output
+	h := CommandHook{Command: writeHookScript(t, `in=$(cat)
+printf '%s\n' "$in" >> `+log+`
+case "$in" in
+*'"event":"before_tool_call","tool":"rm"'*) echo '{"deny":"rm is not allowed"}' ;;
+*'"event":"before_tool_call"'*) echo '{"args":{"path":"safe.txt"}}' ;;
+*'"event":"after_tool_call"'*) echo '{"output":"wrapped"}' ;;
+esac
+`), Timeout: 5 * time.Second}
+	hs := Hooks{h}
+	out, err := hs.Run(context.Background(), Call{Tool: "cat", CallID: "c1", Args: json.RawMessage(`{"path":"x"}`)}, echoRun)
+	if err != nil || string(out) != "wrapped" {
+		t.Fatalf("out=%q err=%v", out, err)
+	}
+	if _, err := hs.Run(context.Background(), Call{Tool: "rm", Args: json.RawMessage(`{}`)}, echoRun); err == nil || err.Error() != "tool call blocked by hook: rm is not allowed" {
+		t.Fatalf("deny: err=%v", err)
+	}
+	_, err = hs.Run(context.Background(), Call{Tool: "cat", Args: json.RawMessage(`not json`)}, func(context.Context, []byte) ([]byte, error) { return nil, errors.New("boom") })
+	if err == nil || err.Error() != "boom" {
+		t.Fatalf("failure: err=%v", err)
+	}
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	want := []string{
+		`{"event":"before_tool_call","tool":"cat","call_id":"c1","args":{"path":"x"}}`,
+		`{"event":"after_tool_call","tool":"cat","call_id":"c1","args":{"path":"safe.txt"},"output":"{\"path\":\"safe.txt\"}"}`,
+		`{"event":"before_tool_call","tool":"rm","args":{}}`,
+		`{"event":"before_tool_call","tool":"cat","args":"not json"}`,
+		`{"event":"on_tool_error","tool":"cat","args":{"path":"safe.txt"},"error":"boom"}`,
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("events:\n%s", data)
+	}
+}
+
+func TestCommandHook_Failures(t *testing.T) {
+	for body, want := range map[string]string{
+		"echo 'not allowed today' >&2; exit 3\n": "tool hook: before_tool_call: not allowed today",
+		"exit 3\n":                               "tool hook: before_tool_call: exit status 3",
+		"echo nope\n":                            "tool hook: before_tool_call: invalid response",
+		"echo '{\"args\":[1]}'\n":                "tool hook: args must be a JSON object",
+		"sleep 5\n":                              "context deadline exceeded",
+	} {
+		h := CommandHook{Command: writeHookScript(t, "cat >/dev/null\n"+body), Timeout: 200 * time.Millisecond}
+		call := Call{Tool: "t", Args: json.RawMessage(`{}`)}
+		if err := h.BeforeToolCall(context.Background(), &call); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: err=%v want %q", body, err, want)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package tools
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// IdempotencyKeyEnv names the environment variable carrying a call's
+// idempotency key to the tool process.
+const IdempotencyKeyEnv = "GOAGENT_IDEMPOTENCY_KEY"
+
+// IdempotencyKey identifies one tool call: the SHA-256 of its call id and
+// its arguments, compacted so whitespace does not change the key. A call
+// replayed from a saved transcript keeps its key, so a tool can recognize a
+// request it has already carried out.
+func IdempotencyKey(callID, args string) string {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, []byte(args)); err != nil {
+		compact.Reset()
+		compact.WriteString(args)
+	}
+	sum := sha256.Sum256(append([]byte(callID+"\x00"), compact.Bytes()...))
+	return hex.EncodeToString(sum[:])
+}
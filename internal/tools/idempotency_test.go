@@ -0,0 +1,26 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	k := IdempotencyKey("call_1", `{"path":"a","content":"x"}`)
+	if len(k) != 64 || k != IdempotencyKey("call_1", "{ \"path\": \"a\",\n \"content\": \"x\" }") {
+		t.Fatalf("key %q must ignore whitespace", k)
+	}
+	if k == IdempotencyKey("call_2", `{"path":"a","content":"x"}`) || k == IdempotencyKey("call_1", `{"path":"b","content":"x"}`) {
+		t.Fatal("key must depend on the call id and arguments")
+	}
+}
+
+func TestRunToolWithJSON_PassesIdempotencyKey(t *testing.T) {
+	spec := ToolSpec{Name: "key", Command: []string{"/bin/sh", "-c", `printf %s "$GOAGENT_IDEMPOTENCY_KEY"`}, IdempotencyKey: "abc123"}
+	out, err := RunToolWithJSON(context.Background(), spec, []byte(`{}`), 5*time.Second)
+	if err != nil || strings.TrimSpace(string(out)) != "abc123" {
+		t.Fatalf("out=%q err=%v", out, err)
+	}
+}
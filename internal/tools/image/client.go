@@ -3,6 +3,8 @@ package image
 import (
 	"net/http"
 	"time"
+
+	"github.com/hyperifyio/goagent/internal/oai"
 )
 
 // RetryPolicy controls retry behavior for image HTTP calls.
@@ -35,7 +37,7 @@ func NewClient(baseURL, apiKey string, httpTimeout time.Duration, retries int, b
 	return &Client {
 		baseURL: baseURL,
 		apiKey: apiKey,
-		httpClient: &http.Client{ Timeout: httpTimeout },
+		httpClient: &http.Client{ Timeout: httpTimeout, Transport: oai.SharedTransport() },
 		retry: RetryPolicy{ MaxRetries: retries, Backoff: backoff },
 	}
 }
@@ -0,0 +1,33 @@
+package tools
+
+import "sync"
+
+// Interceptor runs in place of every RunToolWithJSON call; run starts the
+// tool process. It lets a recorder capture tool runs or a replayer answer
+// them without running anything.
+type Interceptor func(name string, input []byte, run func() ([]byte, error)) ([]byte, error)
+
+var (
+	interceptMu sync.RWMutex
+	interceptor Interceptor
+)
+
+// SetInterceptor installs fn for all later tool runs; nil removes it.
+// restore puts the previous interceptor back.
+func SetInterceptor(fn Interceptor) (restore func()) {
+	interceptMu.Lock()
+	defer interceptMu.Unlock()
+	prev := interceptor
+	interceptor = fn
+	return func() {
+		interceptMu.Lock()
+		defer interceptMu.Unlock()
+		interceptor = prev
+	}
+}
+
+func currentInterceptor() Interceptor {
+	interceptMu.RLock()
+	defer interceptMu.RUnlock()
+	return interceptor
+}
@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/tools/jsrun"
+	"github.com/hyperifyio/goagent/internal/tracing"
+)
+
+// defaultJSOutputKB caps the output of a "js" tool without js.outputKB,
+// matching code.sandbox.js.run.
+const defaultJSOutputKB = 64
+
+type cachedScript struct {
+	size    int64
+	modTime time.Time
+	script  *jsrun.Script
+}
+
+var (
+	jsScriptsMu sync.Mutex
+	jsScripts   = map[string]cachedScript{}
+)
+
+// loadJSScript returns the compiled script at path, recompiling it when the
+// file changed since it was last compiled.
+func loadJSScript(path string) (*jsrun.Script, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	jsScriptsMu.Lock()
+	defer jsScriptsMu.Unlock()
+	if c, ok := jsScripts[path]; ok && c.size == info.Size() && c.modTime.Equal(info.ModTime()) {
+		return c.script, nil
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	script, err := jsrun.Compile(path, string(src))
+	if err != nil {
+		return nil, err
+	}
+	jsScripts[path] = cachedScript{size: info.Size(), modTime: info.ModTime(), script: script}
+	return script, nil
+}
+
+// runJSTool runs a "js" runtime tool in a fresh embedded VM: read_input()
+// returns the JSON arguments and whatever the script passes to emit() is the
+// result. A thrown exception fails the call with its message; the tool
+// timeout bounds wall time and js.outputKB the output.
+func runJSTool(ctx context.Context, spec ToolSpec, jsonInput []byte, start time.Time, span *tracing.Span) ([]byte, error) {
+	if len(jsonInput) == 0 {
+		jsonInput = []byte("{}")
+	}
+	outputKB := defaultJSOutputKB
+	if spec.JS != nil && spec.JS.OutputKB > 0 {
+		outputKB = spec.JS.OutputKB
+	}
+	var out string
+	script, err := loadJSScript(spec.Source)
+	if err != nil {
+		err = fmt.Errorf("load script: %w", err)
+	} else {
+		out, err = script.Exec(ctx, string(jsonInput), outputKB*1024)
+	}
+	exitCode := 0
+	switch {
+	case errors.Is(err, jsrun.ErrOutputLimit):
+		err = fmt.Errorf("tool output exceeded %d KB", outputKB)
+		exitCode = 1
+	case ctx.Err() != nil:
+		exitCode = -1
+	case err != nil:
+		exitCode = 1
+	}
+	writeAudit(spec, start, exitCode, len(out), 0, nil)
+	span.SetAttributes(tracing.Int("tool.exit_code", exitCode), tracing.Int("tool.stdout_bytes", len(out)))
+
+	normErr := normalizeWaitError(ctx, err, "")
+	recordToolMetrics(spec.Name, timeNow().Sub(start), len(out), ctx.Err(), normErr)
+	if normErr != nil {
+		return nil, normErr
+	}
+	return []byte(out), nil
+}
@@ -0,0 +1,85 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunToolWithJSON_JS(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "tools"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, src := range map[string]string{
+		"add.js":  `var a = JSON.parse(read_input()); emit(JSON.stringify({sum: a.x + a.y}))`,
+		"fail.js": `throw new Error("x must be a number")`,
+		"spin.js": `for (;;) {}`,
+		"big.js":  `emit("0123456789".repeat(200))`,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, "tools", name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	man := filepath.Join(dir, "tools.json")
+	if err := os.WriteFile(man, []byte(`{"tools":[
+		{"name":"add","runtime":"js","source":"tools/add.js"},
+		{"name":"fail","runtime":"js","source":"tools/fail.js"},
+		{"name":"spin","runtime":"js","source":"tools/spin.js","timeoutSec":1},
+		{"name":"big","runtime":"js","source":"tools/big.js","js":{"outputKB":1}}
+	]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reg, _, err := LoadManifest(man)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ProgramPath(reg["add"]); got != filepath.Join(dir, "tools", "add.js") {
+		t.Fatalf("source=%q", got)
+	}
+	if err := CheckProgram(reg["add"]); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	out, err := RunToolWithJSON(context.Background(), reg["add"], []byte(`{"x":2,"y":3}`), 5*time.Second)
+	if err != nil || string(out) != `{"sum":5}` {
+		t.Fatalf("add: out=%q err=%v", out, err)
+	}
+	for name, want := range map[string]string{
+		"fail": "x must be a number",
+		"spin": "tool timed out",
+		"big":  "tool output exceeded 1 KB",
+	} {
+		if _, err := RunToolWithJSON(context.Background(), reg[name], nil, 5*time.Second); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err=%v want %q", name, err, want)
+		}
+	}
+
+	// An edited script is recompiled on the next call
+	if err := os.WriteFile(filepath.Join(dir, "tools", "add.js"), []byte(`emit("edited version")`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := RunToolWithJSON(context.Background(), reg["add"], nil, 5*time.Second); err != nil || string(out) != "edited version" {
+		t.Fatalf("edited: out=%q err=%v", out, err)
+	}
+}
+
+func TestLoadManifest_JSRuntimeValidation(t *testing.T) {
+	for body, want := range map[string]string{
+		`"runtime":"js"`: `runtime "js" requires source`,
+		`"runtime":"js","source":"a.js","command":["/x"]`: `runtime "js" takes source, not command`,
+		`"runtime":"js","source":"../a.js"`:               "source must not escape the manifest directory",
+		`"command":["/x"],"source":"a.js"`:                `source requires runtime "js"`,
+		`"command":["/x"],"js":{"outputKB":1}`:            `js limits require runtime "js"`,
+	} {
+		man := filepath.Join(t.TempDir(), "tools.json")
+		if err := os.WriteFile(man, []byte(`{"tools":[{"name":"t",`+body+`}]}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := LoadManifest(man); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err=%v want %q", body, err, want)
+		}
+	}
+}
@@ -6,11 +6,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/dop251/goja"
+
+	"github.com/hyperifyio/goagent/internal/audit"
 )
 
 // Input models the expected stdin JSON for code.sandbox.js.run
@@ -215,43 +215,5 @@ func writeBounded(buf *bytes.Buffer, s string, capBytes int) {
 
 // appendAudit writes an NDJSON line under .goagent/audit/YYYYMMDD.log at the repo root.
 func appendAudit(entry any) error {
-	b, err := json.Marshal(entry)
-	if err != nil {
-		return err
-	}
-	root := moduleRoot()
-	dir := filepath.Join(root, ".goagent", "audit")
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return err
-	}
-	fname := time.Now().UTC().Format("20060102") + ".log"
-	path := filepath.Join(dir, fname)
-	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
-	if err != nil {
-		return err
-	}
-	defer func() { _ = f.Close() }() //nolint:errcheck // best-effort close
-	if _, err := f.Write(append(b, '\n')); err != nil {
-		return err
-	}
-	return nil
-}
-
-// moduleRoot walks upward from CWD to the directory containing go.mod; falls back to CWD.
-func moduleRoot() string {
-	cwd, err := os.Getwd()
-	if err != nil || cwd == "" {
-		return "."
-	}
-	dir := cwd
-	for {
-		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
-			return dir
-		}
-		parent := filepath.Dir(dir)
-		if parent == dir {
-			return cwd
-		}
-		dir = parent
-	}
+	return audit.Append(entry)
 }
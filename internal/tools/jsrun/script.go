@@ -0,0 +1,79 @@
+package jsrun
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dop251/goja"
+)
+
+// ErrOutputLimit is returned by Script.Exec when the script emits more than
+// its output cap.
+var ErrOutputLimit = errOutputLimit
+
+// maxCallStackSize bounds script recursion so a runaway script fails with a
+// RangeError instead of growing the agent's memory.
+const maxCallStackSize = 4096
+
+// Script is a compiled JavaScript tool. Each Exec runs it in a fresh VM, so
+// runs share no state and a Script is safe for concurrent use.
+type Script struct {
+	prog *goja.Program
+}
+
+// Compile parses src; name appears in error positions.
+func Compile(name, src string) (*Script, error) {
+	prog, err := goja.Compile(name, src, false)
+	if err != nil {
+		return nil, err
+	}
+	return &Script{prog: prog}, nil
+}
+
+// Exec runs the script with the same deny-by-default bindings as
+// code.sandbox.js.run: read_input() returns input and emit(s) appends to the
+// output; there is no module loader, filesystem, network, or timer. It stops
+// the script when ctx ends and returns ctx.Err(). Emitting more than
+// outputCap bytes returns the truncated output with ErrOutputLimit.
+func (s *Script) Exec(ctx context.Context, input string, outputCap int) (output string, runErr error) {
+	var out bytes.Buffer
+	vm := goja.New()
+	vm.SetMaxCallStackSize(maxCallStackSize)
+	if err := vm.Set("read_input", func() string { return input }); err != nil {
+		return "", err
+	}
+	if err := vm.Set("emit", func(call goja.FunctionCall) goja.Value {
+		if len(call.Arguments) > 0 {
+			arg := call.Arguments[0].String()
+			if out.Len()+len(arg) > outputCap {
+				writeBounded(&out, arg, outputCap)
+				panic(errOutputLimit)
+			}
+			out.WriteString(arg)
+		}
+		return goja.Undefined()
+	}); err != nil {
+		return "", err
+	}
+
+	stop := context.AfterFunc(ctx, func() { vm.Interrupt(ctx.Err()) })
+	defer stop()
+	defer func() {
+		if r := recover(); r != nil {
+			output = out.String()
+			if err, ok := r.(error); ok {
+				runErr = err
+			} else {
+				runErr = fmt.Errorf("panic: %v", r)
+			}
+		}
+	}()
+	_, err := vm.RunProgram(s.prog)
+	var interrupted *goja.InterruptedError
+	if errors.As(err, &interrupted) && ctx.Err() != nil {
+		return out.String(), ctx.Err()
+	}
+	return out.String(), err
+}
@@ -0,0 +1,58 @@
+package jsrun
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScriptExec(t *testing.T) {
+	s, err := Compile("tool.js", `
+var calls = (typeof calls === "undefined") ? 1 : calls + 1;
+var args = JSON.parse(read_input());
+emit(JSON.stringify({greeting: "hi " + args.name, calls: calls}));
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each run gets a fresh VM, so globals never carry over
+	for i := 0; i < 2; i++ {
+		out, err := s.Exec(context.Background(), `{"name":"ada"}`, 1024)
+		if err != nil || out != `{"greeting":"hi ada","calls":1}` {
+			t.Fatalf("run %d: out=%q err=%v", i, out, err)
+		}
+	}
+}
+
+func TestScriptExec_Failures(t *testing.T) {
+	run := func(src string, ctx context.Context, outputCap int) (string, error) {
+		t.Helper()
+		s, err := Compile("tool.js", src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s.Exec(ctx, "{}", outputCap)
+	}
+	if _, err := run(`throw new Error("bad args")`, context.Background(), 64); err == nil || !strings.Contains(err.Error(), "bad args") {
+		t.Errorf("throw: err=%v", err)
+	}
+	if _, err := run(`require("fs")`, context.Background(), 64); err == nil || !strings.Contains(err.Error(), "require is not defined") {
+		t.Errorf("require: err=%v", err)
+	}
+	if _, err := run(`function f() { return f() }; f()`, context.Background(), 64); err == nil {
+		t.Error("unbounded recursion succeeded")
+	}
+	if out, err := run(`emit("abc"); emit("defgh")`, context.Background(), 5); !errors.Is(err, ErrOutputLimit) || out != "abcde" {
+		t.Errorf("output limit: out=%q err=%v", out, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := run(`for (;;) {}`, ctx, 64); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("timeout: err=%v", err)
+	}
+	if _, err := Compile("tool.js", `function (`); err == nil {
+		t.Error("syntax error compiled")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/hyperifyio/goagent/internal/oai"
+	"github.com/hyperifyio/goagent/internal/tools/wasmrun"
 )
 
 type ToolSpec struct {
@@ -22,51 +23,185 @@ type ToolSpec struct {
 	// normalized to upper case, trimmed, validated against [A-Z_][A-Z0-9_]*,
 	// and de-duplicated while preserving order.
 	EnvPassthrough []string `json:"envPassthrough,omitempty"`
+	// DescriptionVariants holds alternate descriptions keyed by model family
+	// (a case-insensitive model ID prefix such as "gpt-5" or "llama"). The
+	// variant whose key is the longest prefix of -model is advertised instead
+	// of Description; the optional "default" key applies when none match.
+	DescriptionVariants map[string]string `json:"descriptionVariants,omitempty"`
+	// Examples are few-shot argument samples appended to the advertised
+	// description (bounded by ExamplesTokenCap) to improve argument quality.
+	Examples []ToolExample `json:"examples,omitempty"`
+	// OutputSchema is an optional JSON Schema for the tool's stdout. Output
+	// that does not conform reaches the model as a structured error listing
+	// the violations instead of the output itself (see ValidateOutput).
+	OutputSchema json.RawMessage `json:"outputSchema,omitempty"`
+	// Mutates declares whether the tool changes files in the workspace. When
+	// omitted, the bundled tools are classified by name (see MutatesWorkspace).
+	Mutates *bool `json:"mutating,omitempty"`
+	// MutatesAlias is the older "mutates" spelling of "mutating"; the loader
+	// folds it into Mutates and rejects a manifest that sets both differently.
+	MutatesAlias *bool `json:"mutates,omitempty"`
+	// SupportsDryRun declares that the tool accepts {"dryRun": true} and then
+	// reports what it would do without changing anything. It is advertised
+	// in the description, and -read-only runs such calls as dry runs.
+	SupportsDryRun bool `json:"supportsDryRun,omitempty"`
+	// RateLimit caps how often the tool runs; calls over the limit fail with
+	// a RateLimitError instead of running (see takeRateToken).
+	RateLimit *RateLimit `json:"rateLimit,omitempty"`
+	// Retries is how many times a failed call is run again, with backoff,
+	// before the error reaches the model; RetryOn selects the failures
+	// retried (nonzero exits and timeouts when omitted).
+	Retries int      `json:"retries,omitempty"`
+	RetryOn *RetryOn `json:"retryOn,omitempty"`
+	// Runtime selects how the tool runs: "process" (the default) executes
+	// Command as a child process; "wasm" loads Command[0] as a WASI module
+	// and runs it in-process with Command[1:] as its arguments; "js" and
+	// "starlark" run Source in the embedded JavaScript or Starlark engine.
+	Runtime string `json:"runtime,omitempty"`
+	// Wasm holds the limits of a "wasm" runtime tool.
+	Wasm *WasmLimits `json:"wasm,omitempty"`
+	// Source is the script of a "js" or "starlark" runtime tool, which has
+	// no Command.
+	// A relative path is resolved against the manifest directory and must
+	// stay inside it.
+	Source string `json:"source,omitempty"`
+	// JS holds the limits of a "js" runtime tool.
+	JS *JSLimits `json:"js,omitempty"`
+	// WorkDir is a runtime-only working directory for the tool process (not
+	// read from the manifest); empty inherits the agent's working directory.
+	WorkDir string `json:"-"`
+	// IdempotencyKey is a runtime-only key of the call being run (see
+	// IdempotencyKey), passed to the tool in IdempotencyKeyEnv.
+	IdempotencyKey string `json:"-"`
+	// Namespace is the namespace of the manifest that declared the tool,
+	// which prefixes Name (not read from the tool entry).
+	Namespace string `json:"-"`
+}
+
+// Tool runtimes.
+const (
+	RuntimeProcess  = "process"
+	RuntimeWasm     = "wasm"
+	RuntimeJS       = "js"
+	RuntimeStarlark = "starlark"
+)
+
+// WasmLimits bounds a "wasm" runtime tool.
+type WasmLimits struct {
+	// MemoryPages caps linear memory in 64 KiB pages (at most 65536); 0
+	// uses the default of 4096 (256 MiB).
+	MemoryPages uint32 `json:"memoryPages,omitempty"`
+	// Fuel caps the guest function calls per tool call; 0 is unlimited.
+	Fuel uint64 `json:"fuel,omitempty"`
+}
+
+// JSLimits bounds a "js" runtime tool; its wall time is the tool timeout.
+type JSLimits struct {
+	// OutputKB caps the emitted output; 0 uses the default of 64 KiB.
+	OutputKB int `json:"outputKB,omitempty"`
 }
 
 type Manifest struct {
-	Tools []ToolSpec `json:"tools"`
+	// Namespace, when set, prefixes the name of every tool this file
+	// declares, including starlarkDirs tools, as "<namespace>.<name>", so
+	// separately maintained manifests merge without name collisions.
+	Namespace string     `json:"namespace,omitempty"`
+	Tools     []ToolSpec `json:"tools"`
+	// StarlarkDirs lists directories whose .star files are registered as
+	// "starlark" runtime tools named after the file (see starlarkrun). A
+	// relative directory is resolved against the manifest directory and must
+	// stay inside it.
+	StarlarkDirs []string `json:"starlarkDirs,omitempty"`
+	// Include lists further manifests whose tools are merged into this one.
+	// A relative path is resolved against the manifest directory and must
+	// stay inside it.
+	Include []string `json:"include,omitempty"`
+	// Aliases maps extra tool names to the tools they stand for. Alias names
+	// are not namespaced; calls to an alias run the canonical tool.
+	Aliases map[string]string `json:"aliases,omitempty"`
 }
 
 // LoadManifest reads tools.json and returns a name->spec registry and an OpenAI-compatible tools array.
 // Relative command paths in the manifest are validated and then resolved relative to the manifest's directory,
 // so they do not depend on the process working directory.
 func LoadManifest(manifestPath string) (map[string]ToolSpec, []oai.Tool, error) {
+	l := &manifestLoader{registry: make(map[string]ToolSpec), nameSeen: make(map[string]struct{}), loading: make(map[string]bool)}
+	if err := l.load(manifestPath); err != nil {
+		return nil, nil, err
+	}
+	if err := l.resolveAliases(); err != nil {
+		return nil, nil, err
+	}
+	return l.registry, l.tools, nil
+}
+
+// load adds the tools of one manifest file, then those of its includes.
+func (l *manifestLoader) load(manifestPath string) error {
 	data, err := os.ReadFile(manifestPath)
 	if err != nil {
-		return nil, nil, fmt.Errorf("read manifest: %w", err)
+		return fmt.Errorf("read manifest: %w", err)
 	}
 	var man Manifest
 	if err := json.Unmarshal(data, &man); err != nil {
-		return nil, nil, fmt.Errorf("parse manifest: %w", err)
+		return fmt.Errorf("parse manifest: %w", err)
+	}
+	if err := validateNamespace(man.Namespace); err != nil {
+		return err
 	}
-	registry := make(map[string]ToolSpec)
-	var oaiTools []oai.Tool
-	nameSeen := make(map[string]struct{})
 	manifestDir := filepath.Dir(manifestPath)
 	for i, t := range man.Tools {
 		if t.Name == "" {
-			return nil, nil, fmt.Errorf("tool[%d]: name is required", i)
+			return fmt.Errorf("tool[%d]: name is required", i)
 		}
-		if _, ok := nameSeen[t.Name]; ok {
-			return nil, nil, fmt.Errorf("tool[%d] %q: duplicate name", i, t.Name)
+		t.Name, t.Namespace = qualifiedName(man.Namespace, t.Name), man.Namespace
+		if _, ok := l.nameSeen[t.Name]; ok {
+			return fmt.Errorf("tool[%d] %q: duplicate name", i, t.Name)
 		}
-		nameSeen[t.Name] = struct{}{}
-		if len(t.Command) < 1 {
-			return nil, nil, fmt.Errorf("tool[%d] %q: command must have at least program name", i, t.Name)
+		l.nameSeen[t.Name] = struct{}{}
+		if len(t.Command) < 1 && !usesSource(t.Runtime) {
+			return fmt.Errorf("tool[%d] %q: command must have at least program name", i, t.Name)
 		}
 		// Validate and normalize envPassthrough early so callers can rely on it
 		if len(t.EnvPassthrough) > 0 {
 			norm, err := normalizeEnvAllowlist(t.EnvPassthrough)
 			if err != nil {
-				return nil, nil, fmt.Errorf("tool[%d] %q: %v", i, t.Name, err)
+				return fmt.Errorf("tool[%d] %q: %v", i, t.Name, err)
 			}
 			t.EnvPassthrough = norm
 		}
+		t.DescriptionVariants = normalizeDescriptionVariants(t.DescriptionVariants)
+		examples, err := validateExamples(t.Examples)
+		if err != nil {
+			return fmt.Errorf("tool[%d] %q: %v", i, t.Name, err)
+		}
+		t.Examples = examples
+		if err := validateRuntime(t); err != nil {
+			return fmt.Errorf("tool[%d] %q: %v", i, t.Name, err)
+		}
+		if err := validateOutputSchema(t.OutputSchema); err != nil {
+			return fmt.Errorf("tool[%d] %q: %v", i, t.Name, err)
+		}
+		if t.MutatesAlias != nil {
+			if t.Mutates != nil && *t.Mutates != *t.MutatesAlias {
+				return fmt.Errorf("tool[%d] %q: mutating and mutates disagree", i, t.Name)
+			}
+			t.Mutates, t.MutatesAlias = t.MutatesAlias, nil
+		}
+		if err := validateRateLimit(t.RateLimit); err != nil {
+			return fmt.Errorf("tool[%d] %q: %v", i, t.Name, err)
+		}
+		if err := validateRetries(t); err != nil {
+			return fmt.Errorf("tool[%d] %q: %v", i, t.Name, err)
+		}
 		// S52/S30: Harden command[0] validation. For any relative program path,
 		// enforce the canonical tools bin prefix and prevent path escapes.
-		cmd0 := t.Command[0]
-		if !filepath.IsAbs(cmd0) {
+		if usesSource(t.Runtime) {
+			src, err := resolveSourcePath(manifestDir, t.Source)
+			if err != nil {
+				return fmt.Errorf("tool[%d] %q: %v", i, t.Name, err)
+			}
+			t.Source = src
+		} else if cmd0 := t.Command[0]; !filepath.IsAbs(cmd0) {
 			// Normalize separators: convert backslashes to slashes (works cross‑platform)
 			// and then perform a platform-agnostic clean. Finally, ensure forward slashes.
 			raw := strings.ReplaceAll(cmd0, "\\", "/")
@@ -77,17 +212,17 @@ func LoadManifest(manifestPath string) (map[string]ToolSpec, []oai.Tool, error)
 			}
 			// Reject leading parent traversal
 			if strings.HasPrefix(norm, "../") || norm == ".." {
-				return nil, nil, fmt.Errorf("tool[%d] %q: command[0] must not start with '..' or escape tools/bin (got %q)", i, t.Name, cmd0)
+				return fmt.Errorf("tool[%d] %q: command[0] must not start with '..' or escape tools/bin (got %q)", i, t.Name, cmd0)
 			}
 			// If original referenced ./tools/bin, ensure cleaned still stays within ./tools/bin
 			if strings.HasPrefix(raw, "./tools/bin/") || raw == "./tools/bin" {
 				if !(strings.HasPrefix(norm, "./tools/bin/")) {
-					return nil, nil, fmt.Errorf("tool[%d] %q: command[0] escapes ./tools/bin after normalization (got %q -> %q)", i, t.Name, cmd0, norm)
+					return fmt.Errorf("tool[%d] %q: command[0] escapes ./tools/bin after normalization (got %q -> %q)", i, t.Name, cmd0, norm)
 				}
 			} else {
 				// Enforce canonical prefix for all other relative commands
 				if !strings.HasPrefix(norm, "./tools/bin/") {
-					return nil, nil, fmt.Errorf("tool[%d] %q: relative command[0] must start with ./tools/bin/", i, t.Name)
+					return fmt.Errorf("tool[%d] %q: relative command[0] must start with ./tools/bin/", i, t.Name)
 				}
 			}
 			// Resolve relative program path against the manifest directory to avoid dependence on process CWD
@@ -98,23 +233,101 @@ func LoadManifest(manifestPath string) (map[string]ToolSpec, []oai.Tool, error)
 			resolved := filepath.Join(manifestDir, filepath.FromSlash(trimmed))
 			absResolved, errAbs := filepath.Abs(resolved)
 			if errAbs != nil {
-				return nil, nil, fmt.Errorf("tool[%d] %q: resolve command[0]: %v", i, t.Name, errAbs)
+				return fmt.Errorf("tool[%d] %q: resolve command[0]: %v", i, t.Name, errAbs)
 			}
 			t.Command[0] = absResolved
 		}
-		registry[t.Name] = t
+		l.registry[t.Name] = t
 		// Build OpenAI tools entry
 		entry := oai.Tool{
 			Type: "function",
 			Function: oai.ToolFunction{
 				Name:        t.Name,
-				Description: t.Description,
+				Description: appendExamples(describeDryRun(t.Description, t), t.Examples, ExamplesTokenCap),
 				Parameters:  t.Schema,
 			},
 		}
-		oaiTools = append(oaiTools, entry)
+		l.tools = append(l.tools, entry)
+	}
+	for i, dir := range man.StarlarkDirs {
+		resolved, err := resolveSourcePath(manifestDir, dir)
+		if err != nil {
+			return fmt.Errorf("starlarkDirs[%d]: %v", i, err)
+		}
+		specs, err := discoverStarlarkTools(resolved)
+		if err != nil {
+			return fmt.Errorf("starlarkDirs[%d]: %v", i, err)
+		}
+		for _, t := range specs {
+			t.Name, t.Namespace = qualifiedName(man.Namespace, t.Name), man.Namespace
+			if _, ok := l.nameSeen[t.Name]; ok {
+				return fmt.Errorf("starlarkDirs[%d] %q: duplicate name", i, t.Name)
+			}
+			l.nameSeen[t.Name] = struct{}{}
+			l.registry[t.Name] = t
+			l.tools = append(l.tools, oai.Tool{
+				Type:     "function",
+				Function: oai.ToolFunction{Name: t.Name, Description: t.Description, Parameters: t.Schema},
+			})
+		}
+	}
+	return l.merge(manifestPath, man)
+}
+
+// usesSource reports whether runtime runs a Source script instead of a
+// Command.
+func usesSource(runtime string) bool {
+	return runtime == RuntimeJS || runtime == RuntimeStarlark
+}
+
+// validateRuntime checks the runtime and its limits.
+func validateRuntime(t ToolSpec) error {
+	if t.Wasm != nil && t.Runtime != RuntimeWasm {
+		return fmt.Errorf("wasm limits require runtime %q", RuntimeWasm)
+	}
+	if t.JS != nil && t.Runtime != RuntimeJS {
+		return fmt.Errorf("js limits require runtime %q", RuntimeJS)
+	}
+	if t.Source != "" && !usesSource(t.Runtime) {
+		return fmt.Errorf("source requires runtime %q or %q", RuntimeJS, RuntimeStarlark)
+	}
+	switch t.Runtime {
+	case "", RuntimeProcess:
+	case RuntimeWasm:
+		if t.Wasm != nil && t.Wasm.MemoryPages > wasmrun.MaxMemoryPages {
+			return fmt.Errorf("wasm.memoryPages must be at most %d", wasmrun.MaxMemoryPages)
+		}
+	case RuntimeJS, RuntimeStarlark:
+		if t.Source == "" {
+			return fmt.Errorf("runtime %q requires source", t.Runtime)
+		}
+		if len(t.Command) > 0 {
+			return fmt.Errorf("runtime %q takes source, not command", t.Runtime)
+		}
+		if t.JS != nil && t.JS.OutputKB < 0 {
+			return fmt.Errorf("js.outputKB must not be negative")
+		}
+	default:
+		return fmt.Errorf("unknown runtime %q (want %q, %q, %q, or %q)", t.Runtime, RuntimeProcess, RuntimeWasm, RuntimeJS, RuntimeStarlark)
+	}
+	return nil
+}
+
+// resolveSourcePath returns the absolute path of a script source. Relative
+// paths are resolved against the manifest directory and may not leave it.
+func resolveSourcePath(manifestDir, src string) (string, error) {
+	if !filepath.IsAbs(src) {
+		norm := path.Clean(strings.ReplaceAll(src, "\\", "/"))
+		if norm == ".." || strings.HasPrefix(norm, "../") {
+			return "", fmt.Errorf("source must not escape the manifest directory (got %q)", src)
+		}
+		src = filepath.Join(manifestDir, filepath.FromSlash(norm))
+	}
+	abs, err := filepath.Abs(src)
+	if err != nil {
+		return "", fmt.Errorf("resolve source: %v", err)
 	}
-	return registry, oaiTools, nil
+	return abs, nil
 }
 
 // normalizeEnvAllowlist normalizes, validates, and de-duplicates environment
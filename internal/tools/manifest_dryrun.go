@@ -0,0 +1,35 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// dryRunNote is appended to the advertised description of tools that
+// declare supportsDryRun.
+const dryRunNote = `Supports dry runs: pass "dryRun": true to see what the call would do without changing anything.`
+
+// describeDryRun appends dryRunNote to desc when spec supports dry runs.
+func describeDryRun(desc string, spec ToolSpec) string {
+	if !spec.SupportsDryRun {
+		return desc
+	}
+	if strings.TrimSpace(desc) == "" {
+		return dryRunNote
+	}
+	return desc + "\n\n" + dryRunNote
+}
+
+// WithDryRun returns the call arguments with "dryRun" set to true. The
+// arguments must be a JSON object; empty arguments count as {}.
+func WithDryRun(args []byte) ([]byte, error) {
+	obj := map[string]json.RawMessage{}
+	if trimmed := strings.TrimSpace(string(args)); trimmed != "" {
+		if err := json.Unmarshal([]byte(trimmed), &obj); err != nil || obj == nil {
+			return nil, errors.New("arguments must be a JSON object")
+		}
+	}
+	obj["dryRun"] = json.RawMessage("true")
+	return json.Marshal(obj)
+}
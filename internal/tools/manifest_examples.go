@@ -0,0 +1,74 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ExamplesTokenCap bounds the estimated tokens that rendered examples may add
+// to a single tool description. Examples beyond the cap are dropped in
+// manifest order so the first (most representative) ones survive.
+const ExamplesTokenCap = 256
+
+// ToolExample is a few-shot illustration of a well-formed call. Arguments must
+// be a JSON object matching the tool schema; Description is an optional short
+// note explaining when the call applies.
+type ToolExample struct {
+	Description string          `json:"description,omitempty"`
+	Arguments   json.RawMessage `json:"arguments"`
+}
+
+// validateExamples ensures each example carries a JSON object and compacts the
+// arguments so rendering is deterministic.
+func validateExamples(in []ToolExample) ([]ToolExample, error) {
+	if len(in) == 0 {
+		return nil, nil
+	}
+	out := make([]ToolExample, 0, len(in))
+	for i, ex := range in {
+		trimmed := bytes.TrimSpace(ex.Arguments)
+		if len(trimmed) == 0 || trimmed[0] != '{' {
+			return nil, fmt.Errorf("examples[%d]: arguments must be a JSON object", i)
+		}
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, trimmed); err != nil {
+			return nil, fmt.Errorf("examples[%d]: invalid arguments JSON: %v", i, err)
+		}
+		out = append(out, ToolExample{Description: strings.TrimSpace(ex.Description), Arguments: buf.Bytes()})
+	}
+	return out, nil
+}
+
+// appendExamples renders examples as an "Examples:" block after desc, adding
+// entries while the estimated token cost (~4 chars/token) stays within
+// tokenCap. It returns desc unchanged when no example fits.
+func appendExamples(desc string, examples []ToolExample, tokenCap int) string {
+	if len(examples) == 0 || tokenCap <= 0 {
+		return desc
+	}
+	const header = "Examples:"
+	budget := tokenCap * 4
+	used := len(header)
+	var lines []string
+	for _, ex := range examples {
+		line := "- " + string(ex.Arguments)
+		if ex.Description != "" {
+			line = "- " + ex.Description + ": " + string(ex.Arguments)
+		}
+		if used+len(line)+1 > budget {
+			break
+		}
+		used += len(line) + 1
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return desc
+	}
+	block := header + "\n" + strings.Join(lines, "\n")
+	if strings.TrimSpace(desc) == "" {
+		return block
+	}
+	return desc + "\n\n" + block
+}
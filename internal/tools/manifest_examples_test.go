@@ -0,0 +1,88 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadManifest_ExamplesAppendedToDescription(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "tools.json")
+	data := `{"tools":[{"name":"fs_apply_patch","description":"Apply a unified diff","examples":[
+		{"description":"add a line","arguments":{ "unifiedDiff": "--- a/x\n+++ b/x\n" }},
+		{"arguments":{"unifiedDiff":"x","dryRun":true}}
+	],"command":["/bin/echo"]}]}`
+	if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	_, oaiTools, err := LoadManifest(file)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	desc := oaiTools[0].Function.Description
+	want := "Apply a unified diff\n\nExamples:\n- add a line: {\"unifiedDiff\":\"--- a/x\\n+++ b/x\\n\"}\n- {\"unifiedDiff\":\"x\",\"dryRun\":true}"
+	if desc != want {
+		t.Fatalf("description mismatch:\n got %q\nwant %q", desc, want)
+	}
+}
+
+func TestLoadManifest_ExamplesRejectNonObject(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "tools.json")
+	data := `{"tools":[{"name":"x","examples":[{"arguments":[1,2]}],"command":["/bin/echo"]}]}`
+	if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	_, _, err := LoadManifest(file)
+	if err == nil || !strings.Contains(err.Error(), "examples[0]") {
+		t.Fatalf("expected examples[0] error, got %v", err)
+	}
+}
+
+func TestAppendExamples_TokenCap(t *testing.T) {
+	examples := []ToolExample{
+		{Arguments: []byte(`{"a":"` + strings.Repeat("x", 20) + `"}`)},
+		{Arguments: []byte(`{"b":"` + strings.Repeat("y", 200) + `"}`)},
+	}
+	got := appendExamples("d", examples, 10)
+	if !strings.Contains(got, `"a"`) || strings.Contains(got, `"b"`) {
+		t.Fatalf("expected only the first example within cap, got %q", got)
+	}
+	if got := appendExamples("d", examples[1:], 10); got != "d" {
+		t.Fatalf("expected description unchanged when nothing fits, got %q", got)
+	}
+}
+
+func TestLoadManifest_SupportsDryRunIsAdvertised(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "tools.json")
+	data := `{"tools":[{"name":"fs_apply_patch","description":"Apply a unified diff","supportsDryRun":true,"examples":[{"arguments":{"unifiedDiff":"x"}}],"command":["/bin/echo"]}]}`
+	if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	registry, oaiTools, err := LoadManifest(file)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	want := "Apply a unified diff\n\n" + dryRunNote + "\n\nExamples:\n- {\"unifiedDiff\":\"x\"}"
+	if got := oaiTools[0].Function.Description; got != want {
+		t.Fatalf("description mismatch:\n got %q\nwant %q", got, want)
+	}
+	if got := ApplyModelDescriptions(oaiTools, registry, "m")[0].Function.Description; got != want {
+		t.Fatalf("model description mismatch:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestWithDryRun(t *testing.T) {
+	for in, want := range map[string]string{"": `{"dryRun":true}`, `{"dryRun":false,"a":1}`: `{"a":1,"dryRun":true}`} {
+		got, err := WithDryRun([]byte(in))
+		if err != nil || string(got) != want {
+			t.Errorf("WithDryRun(%q) = %s, %v; want %s", in, got, err, want)
+		}
+	}
+	if _, err := WithDryRun([]byte(`[1]`)); err == nil {
+		t.Fatal("non-object arguments accepted")
+	}
+}
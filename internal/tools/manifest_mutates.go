@@ -0,0 +1,35 @@
+package tools
+
+// mutatingBuiltins are the bundled tools that write to the workspace.
+var mutatingBuiltins = map[string]bool{
+	"exec":           true,
+	"fs_append_file": true,
+	"fs_apply_patch": true,
+	"fs_edit_range":  true,
+	"fs_mkdirp":      true,
+	"fs_move":        true,
+	"fs_rm":          true,
+	"fs_write_file":  true,
+	"img_create":     true,
+	"text_replace":   true,
+}
+
+// MutatesWorkspace reports whether running spec may change workspace files.
+// An explicit "mutating" manifest field wins; otherwise bundled tools are
+// classified by name, without any manifest namespace, and unknown tools are assumed read-only.
+func MutatesWorkspace(spec ToolSpec) bool {
+	if spec.Mutates != nil {
+		return *spec.Mutates
+	}
+	return mutatingBuiltins[baseName(spec)]
+}
+
+// AnyMutates reports whether any tool in the registry mutates the workspace.
+func AnyMutates(registry map[string]ToolSpec) bool {
+	for _, spec := range registry {
+		if MutatesWorkspace(spec) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMutatesWorkspace(t *testing.T) {
+	yes, no := true, false
+	cases := []struct {
+		spec ToolSpec
+		want bool
+	}{
+		{ToolSpec{Name: "fs_write_file"}, true},
+		{ToolSpec{Name: "exec"}, true},
+		{ToolSpec{Name: "fs_read_file"}, false},
+		{ToolSpec{Name: "custom"}, false},
+		{ToolSpec{Name: "custom", Mutates: &yes}, true},
+		{ToolSpec{Name: "fs_rm", Mutates: &no}, false},
+	}
+	for _, c := range cases {
+		if got := MutatesWorkspace(c.spec); got != c.want {
+			t.Errorf("%s mutates=%v: got %v want %v", c.spec.Name, c.spec.Mutates, got, c.want)
+		}
+	}
+	if AnyMutates(map[string]ToolSpec{"a": {Name: "fs_stat"}}) || !AnyMutates(map[string]ToolSpec{"a": {Name: "fs_stat"}, "b": {Name: "fs_move"}}) {
+		t.Fatal("AnyMutates classification wrong")
+	}
+}
+
+func TestLoadManifest_MutatesField(t *testing.T) {
+	for _, field := range []string{`"mutating":true`, `"mutates":true`, `"mutating":true,"mutates":true`} {
+		path := filepath.Join(t.TempDir(), "tools.json")
+		if err := os.WriteFile(path, []byte(`{"tools":[{"name":"writer","command":["/bin/true"],`+field+`}]}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		reg, _, err := LoadManifest(path)
+		if err != nil {
+			t.Fatalf("%s: %v", field, err)
+		}
+		if !MutatesWorkspace(reg["writer"]) {
+			t.Fatalf("manifest %s not honored", field)
+		}
+	}
+}
+
+func TestLoadManifest_MutatingConflict(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tools.json")
+	if err := os.WriteFile(path, []byte(`{"tools":[{"name":"writer","command":["/bin/true"],"mutating":true,"mutates":false}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := LoadManifest(path); err == nil || !strings.Contains(err.Error(), "mutating and mutates disagree") {
+		t.Fatalf("err=%v", err)
+	}
+}
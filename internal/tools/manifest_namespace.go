@@ -0,0 +1,119 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// namespacePattern is a dot-separated sequence of tool-name-safe segments.
+var namespacePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)*$`)
+
+// manifestLoader accumulates the tools of a manifest and the manifests it
+// includes into one registry. Aliases are collected while loading and
+// resolved once every tool name is known.
+type manifestLoader struct {
+	registry map[string]ToolSpec
+	tools    []oai.Tool
+	nameSeen map[string]struct{}
+	aliases  []pendingAlias
+	// loading holds the absolute paths of the manifests being loaded, to
+	// reject include cycles
+	loading map[string]bool
+}
+
+type pendingAlias struct {
+	manifest  string
+	namespace string
+	name      string
+	target    string
+}
+
+// qualifiedName returns name prefixed with namespace, if any.
+func qualifiedName(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+// baseName returns spec's name without its manifest namespace.
+func baseName(spec ToolSpec) string {
+	if spec.Namespace == "" {
+		return spec.Name
+	}
+	return strings.TrimPrefix(spec.Name, spec.Namespace+".")
+}
+
+func validateNamespace(ns string) error {
+	if ns != "" && !namespacePattern.MatchString(ns) {
+		return fmt.Errorf("namespace %q must be dot-separated segments of letters, digits, '_' or '-'", ns)
+	}
+	return nil
+}
+
+// merge records the aliases of the manifest at manifestPath and loads its
+// includes. An include path resolves against the manifest directory and
+// must stay inside it; each included manifest applies its own namespace.
+func (l *manifestLoader) merge(manifestPath string, man Manifest) error {
+	names := make([]string, 0, len(man.Aliases))
+	for name := range man.Aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		l.aliases = append(l.aliases, pendingAlias{manifest: manifestPath, namespace: man.Namespace, name: name, target: man.Aliases[name]})
+	}
+	abs, err := filepath.Abs(manifestPath)
+	if err != nil {
+		return fmt.Errorf("resolve manifest: %v", err)
+	}
+	l.loading[abs] = true
+	defer delete(l.loading, abs)
+	for i, inc := range man.Include {
+		resolved, err := resolveSourcePath(filepath.Dir(manifestPath), inc)
+		if err != nil {
+			return fmt.Errorf("include[%d]: %v", i, err)
+		}
+		if l.loading[resolved] {
+			return fmt.Errorf("include[%d] %q: include cycle", i, inc)
+		}
+		if err := l.load(resolved); err != nil {
+			return fmt.Errorf("include[%d] %q: %w", i, inc, err)
+		}
+	}
+	return nil
+}
+
+// resolveAliases registers each alias under its own name with the spec of
+// the tool it targets, so a call to the alias runs the canonical tool.
+// A target names a tool of the alias's own manifest without its namespace,
+// or any merged tool by its full name. Aliases are not advertised.
+func (l *manifestLoader) resolveAliases() error {
+	tools := make(map[string]struct{}, len(l.nameSeen))
+	for name := range l.nameSeen {
+		tools[name] = struct{}{}
+	}
+	for _, a := range l.aliases {
+		if strings.TrimSpace(a.name) == "" {
+			return fmt.Errorf("%s: alias name is required", a.manifest)
+		}
+		if _, ok := l.nameSeen[a.name]; ok {
+			return fmt.Errorf("%s: alias %q: duplicate name", a.manifest, a.name)
+		}
+		target := qualifiedName(a.namespace, a.target)
+		if _, ok := tools[target]; !ok {
+			target = a.target
+		}
+		if _, ok := tools[target]; !ok {
+			return fmt.Errorf("%s: alias %q: unknown tool %q", a.manifest, a.name, a.target)
+		}
+		l.nameSeen[a.name] = struct{}{}
+		l.registry[a.name] = l.registry[target]
+	}
+	return nil
+}
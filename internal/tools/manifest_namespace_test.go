@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeManifest(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+func TestLoadManifest_NamespacesIncludesAndAliases(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "tools.json")
+	writeManifest(t, root, `{
+		"namespace": "core",
+		"tools": [{"name": "fs_write_file", "command": ["/bin/true"]}],
+		"include": ["vendor/tools.json"],
+		"aliases": {"write": "fs_write_file", "search": "web.search"}
+	}`)
+	writeManifest(t, filepath.Join(dir, "vendor", "tools.json"), `{
+		"namespace": "web",
+		"tools": [{"name": "search", "command": ["./tools/bin/search"]}, {"name": "fs_write_file", "command": ["/bin/true"]}]
+	}`)
+
+	reg, oaiTools, err := LoadManifest(root)
+	if err != nil {
+		t.Fatalf("LoadManifest: %v", err)
+	}
+	var advertised []string
+	for _, tool := range oaiTools {
+		advertised = append(advertised, tool.Function.Name)
+	}
+	if got := strings.Join(advertised, ","); got != "core.fs_write_file,web.search,web.fs_write_file" {
+		t.Fatalf("advertised=%s", got)
+	}
+	if len(reg) != 5 {
+		t.Fatalf("registry has %d entries, want 5", len(reg))
+	}
+	if spec := reg["write"]; spec.Name != "core.fs_write_file" || !MutatesWorkspace(spec) {
+		t.Fatalf("alias write -> %+v", spec)
+	}
+	// Included command paths resolve against the included manifest
+	if spec := reg["search"]; spec.Name != "web.search" || spec.Command[0] != filepath.Join(dir, "vendor", "tools", "bin", "search") {
+		t.Fatalf("alias search -> %+v", spec)
+	}
+}
+
+func TestLoadManifest_NamespaceErrors(t *testing.T) {
+	cases := []struct {
+		name, manifest, want string
+	}{
+		{"bad namespace", `{"namespace":"a b","tools":[]}`, "namespace"},
+		{"unknown alias target", `{"tools":[{"name":"x","command":["/bin/true"]}],"aliases":{"y":"z"}}`, `unknown tool "z"`},
+		{"alias clashes with tool", `{"tools":[{"name":"x","command":["/bin/true"]},{"name":"y","command":["/bin/true"]}],"aliases":{"y":"x"}}`, "duplicate name"},
+		{"include escapes", `{"tools":[],"include":["../other.json"]}`, "escape"},
+		{"include cycle", `{"tools":[],"include":["tools.json"]}`, "include cycle"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "tools.json")
+			writeManifest(t, file, tc.manifest)
+			if _, _, err := LoadManifest(file); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("err=%v, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadManifest_MergedDuplicateNames(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "tools.json")
+	writeManifest(t, root, `{"tools":[{"name":"x","command":["/bin/true"]}],"include":["b.json"]}`)
+	writeManifest(t, filepath.Join(dir, "b.json"), `{"tools":[{"name":"x","command":["/bin/true"]}]}`)
+	if _, _, err := LoadManifest(root); err == nil || !strings.Contains(err.Error(), `include[0] "b.json"`) {
+		t.Fatalf("err=%v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// DescriptionForModel returns the tool description to advertise to the given
+// model. When the spec declares descriptionVariants, the key that is the
+// longest case-insensitive prefix of the model ID wins; a "default" key acts
+// as a fallback before the plain description. Model IDs such as "gpt-5-mini"
+// therefore match a "gpt-5" variant, while "oss-gpt-20b" matches "oss-gpt".
+func DescriptionForModel(spec ToolSpec, model string) string {
+	if len(spec.DescriptionVariants) == 0 {
+		return spec.Description
+	}
+	id := strings.ToLower(strings.TrimSpace(model))
+	best := ""
+	bestText := ""
+	for family, text := range spec.DescriptionVariants {
+		key := strings.ToLower(strings.TrimSpace(family))
+		if key == "" || key == "default" {
+			continue
+		}
+		if strings.HasPrefix(id, key) && len(key) > len(best) {
+			best = key
+			bestText = text
+		}
+	}
+	if best != "" && strings.TrimSpace(bestText) != "" {
+		return bestText
+	}
+	for family, text := range spec.DescriptionVariants {
+		if strings.EqualFold(strings.TrimSpace(family), "default") && strings.TrimSpace(text) != "" {
+			return text
+		}
+	}
+	return spec.Description
+}
+
+// ApplyModelDescriptions returns a copy of the advertised tools with each
+// description replaced by the variant selected for model, followed by the
+// dry-run note and any few-shot examples. Tools that are not present in the registry are passed
+// through unchanged.
+func ApplyModelDescriptions(in []oai.Tool, registry map[string]ToolSpec, model string) []oai.Tool {
+	out := make([]oai.Tool, len(in))
+	for i, t := range in {
+		out[i] = t
+		if spec, ok := registry[t.Function.Name]; ok {
+			out[i].Function.Description = appendExamples(describeDryRun(DescriptionForModel(spec, model), spec), spec.Examples, ExamplesTokenCap)
+		}
+	}
+	return out
+}
+
+// normalizeDescriptionVariants trims keys and drops empty entries so later
+// lookups do not need to special-case whitespace. Keys are compared
+// case-insensitively; the first occurrence (in sorted key order) wins.
+func normalizeDescriptionVariants(in map[string]string) map[string]string {
+	if len(in) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(in))
+	for k := range in {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make(map[string]string, len(in))
+	for _, k := range keys {
+		nk := strings.ToLower(strings.TrimSpace(k))
+		v := strings.TrimSpace(in[k])
+		if nk == "" || v == "" {
+			continue
+		}
+		if _, ok := out[nk]; ok {
+			continue
+		}
+		out[nk] = v
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
@@ -0,0 +1,59 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDescriptionForModel_LongestPrefixWins(t *testing.T) {
+	spec := ToolSpec{
+		Name:        "fs_apply_patch",
+		Description: "Apply a unified diff",
+		DescriptionVariants: map[string]string{
+			"gpt":     "terse",
+			"gpt-5":   "terse gpt-5",
+			"default": "with examples",
+		},
+	}
+	cases := map[string]string{
+		"gpt-5-mini":  "terse gpt-5",
+		"GPT-4o":      "terse",
+		"oss-gpt-20b": "with examples",
+	}
+	for model, want := range cases {
+		if got := DescriptionForModel(spec, model); got != want {
+			t.Fatalf("model %q: got %q want %q", model, got, want)
+		}
+	}
+	spec.DescriptionVariants = nil
+	if got := DescriptionForModel(spec, "gpt-5"); got != "Apply a unified diff" {
+		t.Fatalf("no variants: got %q", got)
+	}
+}
+
+func TestApplyModelDescriptions_FromManifest(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "tools.json")
+	data := `{"tools":[{"name":"x","description":"plain","descriptionVariants":{" Llama ":"for llama","qwen":"  "},"command":["/bin/echo"]}]}`
+	if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	reg, oaiTools, err := LoadManifest(file)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(reg["x"].DescriptionVariants) != 1 {
+		t.Fatalf("expected empty variants to be dropped, got %v", reg["x"].DescriptionVariants)
+	}
+	got := ApplyModelDescriptions(oaiTools, reg, "llama-3.1-8b")
+	if got[0].Function.Description != "for llama" {
+		t.Fatalf("variant not applied: %q", got[0].Function.Description)
+	}
+	if oaiTools[0].Function.Description != "plain" {
+		t.Fatalf("input slice mutated: %q", oaiTools[0].Function.Description)
+	}
+	if got := ApplyModelDescriptions(oaiTools, reg, "qwen2"); got[0].Function.Description != "plain" {
+		t.Fatalf("fallback: got %q", got[0].Function.Description)
+	}
+}
@@ -0,0 +1,253 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxOutputViolations bounds the violations ValidateOutput reports, so a
+// tool that returns a large wrong array does not flood the transcript.
+const maxOutputViolations = 10
+
+// ValidateOutput checks a tool's stdout against its outputSchema and returns
+// the violations as "<json path>: <problem>" strings, or nil when the output
+// conforms or schema is empty. It supports the JSON Schema subset tools
+// declare in practice: type, properties, required, additionalProperties,
+// items, enum, const, numeric bounds, min/max length and items, pattern,
+// oneOf/anyOf/allOf, and local $ref. Formats are not checked.
+func ValidateOutput(schema json.RawMessage, out []byte) []string {
+	if len(schema) == 0 {
+		return nil
+	}
+	var root map[string]any
+	if err := json.Unmarshal(schema, &root); err != nil {
+		return []string{"$: invalid outputSchema: " + err.Error()}
+	}
+	var value any
+	if err := json.Unmarshal(out, &value); err != nil {
+		return []string{"$: output is not valid JSON"}
+	}
+	v := &outputValidator{s: &simplifier{root: root}}
+	v.check(root, value, "$", 0)
+	return v.violations
+}
+
+// validateOutputSchema checks at manifest load time that outputSchema is a
+// JSON object.
+func validateOutputSchema(raw json.RawMessage) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	var m map[string]any
+	if err := json.Unmarshal(raw, &m); err != nil || m == nil {
+		return fmt.Errorf("outputSchema must be a JSON object")
+	}
+	return nil
+}
+
+type outputValidator struct {
+	s          *simplifier
+	violations []string
+}
+
+func (v *outputValidator) fail(path, format string, args ...any) {
+	if len(v.violations) < maxOutputViolations {
+		v.violations = append(v.violations, path+": "+fmt.Sprintf(format, args...))
+	}
+}
+
+// check records the violations of value against schema n. A nested
+// alternative is checked with a fresh validator so only its verdict counts.
+func (v *outputValidator) check(n map[string]any, value any, path string, refDepth int) {
+	if _, ok := n["$ref"]; ok {
+		if refDepth >= maxRefDepth {
+			return
+		}
+		n = v.s.resolveRef(n, refDepth)
+		refDepth++
+	}
+	if c, ok := n["const"]; ok && !reflect.DeepEqual(c, value) {
+		v.fail(path, "must equal %s", compactJSON(c))
+	}
+	if enum, ok := n["enum"].([]any); ok && !containsValue(enum, value) {
+		v.fail(path, "must be one of %s", compactJSON(enum))
+	}
+	for _, part := range anyMaps(n["allOf"]) {
+		v.check(part, value, path, refDepth)
+	}
+	for _, key := range []string{"anyOf", "oneOf"} {
+		alts := anyMaps(n[key])
+		if len(alts) == 0 {
+			continue
+		}
+		matched := 0
+		for _, alt := range alts {
+			sub := &outputValidator{s: v.s}
+			sub.check(alt, value, path, refDepth)
+			if len(sub.violations) == 0 {
+				matched++
+			}
+		}
+		switch {
+		case key == "anyOf" && matched == 0:
+			v.fail(path, "must match at least one schema in anyOf")
+		case key == "oneOf" && matched != 1:
+			v.fail(path, "must match exactly one schema in oneOf, matched %d", matched)
+		}
+	}
+	if types := typeList(n["type"]); len(types) > 0 && !typeMatches(types, value) {
+		v.fail(path, "expected %s, got %s", strings.Join(types, " or "), jsonType(value))
+		return
+	}
+	switch x := value.(type) {
+	case map[string]any:
+		v.object(n, x, path, refDepth)
+	case []any:
+		v.array(n, x, path, refDepth)
+	case string:
+		if min := intKeyword(n, "minLength", -1); min >= 0 && utf8.RuneCountInString(x) < min {
+			v.fail(path, "must be at least %d characters", min)
+		}
+		if max := intKeyword(n, "maxLength", -1); max >= 0 && utf8.RuneCountInString(x) > max {
+			v.fail(path, "must be at most %d characters", max)
+		}
+		if p, ok := n["pattern"].(string); ok {
+			if re, err := regexp.Compile(p); err == nil && !re.MatchString(x) {
+				v.fail(path, "must match pattern %q", p)
+			}
+		}
+	case float64:
+		v.number(n, x, path)
+	}
+}
+
+func (v *outputValidator) object(n map[string]any, obj map[string]any, path string, refDepth int) {
+	for _, k := range stringList(n["required"]) {
+		if _, ok := obj[k]; !ok {
+			v.fail(path, "missing required property %q", k)
+		}
+	}
+	props, _ := n["properties"].(map[string]any)
+	names := make([]string, 0, len(obj))
+	for k := range obj {
+		names = append(names, k)
+	}
+	// Report in a stable order
+	sort.Strings(names)
+	for _, k := range names {
+		if pm, ok := props[k].(map[string]any); ok {
+			v.check(pm, obj[k], path+"."+k, refDepth)
+			continue
+		}
+		switch extra := n["additionalProperties"].(type) {
+		case bool:
+			if !extra {
+				v.fail(path, "unexpected property %q", k)
+			}
+		case map[string]any:
+			v.check(extra, obj[k], path+"."+k, refDepth)
+		}
+	}
+}
+
+func (v *outputValidator) array(n map[string]any, arr []any, path string, refDepth int) {
+	if min := intKeyword(n, "minItems", -1); min >= 0 && len(arr) < min {
+		v.fail(path, "must have at least %d items", min)
+	}
+	if max := intKeyword(n, "maxItems", -1); max >= 0 && len(arr) > max {
+		v.fail(path, "must have at most %d items", max)
+	}
+	if items, ok := n["items"].(map[string]any); ok {
+		for i, el := range arr {
+			v.check(items, el, fmt.Sprintf("%s[%d]", path, i), refDepth)
+		}
+	}
+}
+
+func (v *outputValidator) number(n map[string]any, x float64, path string) {
+	if min, ok := n["minimum"].(float64); ok && x < min {
+		v.fail(path, "must be >= %v", min)
+	}
+	if max, ok := n["maximum"].(float64); ok && x > max {
+		v.fail(path, "must be <= %v", max)
+	}
+	if min, ok := n["exclusiveMinimum"].(float64); ok && x <= min {
+		v.fail(path, "must be > %v", min)
+	}
+	if max, ok := n["exclusiveMaximum"].(float64); ok && x >= max {
+		v.fail(path, "must be < %v", max)
+	}
+}
+
+func anyMaps(v any) []map[string]any {
+	list, _ := v.([]any)
+	out := make([]map[string]any, 0, len(list))
+	for _, x := range list {
+		if m, ok := x.(map[string]any); ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func typeList(v any) []string {
+	if s, ok := v.(string); ok {
+		return []string{s}
+	}
+	return stringList(v)
+}
+
+func typeMatches(types []string, value any) bool {
+	got := jsonType(value)
+	for _, t := range types {
+		if t == got || (t == "number" && got == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType names the JSON Schema type of a decoded value; whole numbers are
+// "integer".
+func jsonType(value any) string {
+	switch x := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if x == math.Trunc(x) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func containsValue(list []any, value any) bool {
+	for _, x := range list {
+		if reflect.DeepEqual(x, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func compactJSON(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
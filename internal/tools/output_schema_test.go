@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateOutput(t *testing.T) {
+	schema := json.RawMessage(`{
+		"type": "object",
+		"required": ["count", "items"],
+		"additionalProperties": false,
+		"properties": {
+			"count": {"type": "integer", "minimum": 0},
+			"items": {"type": "array", "maxItems": 2, "items": {"$ref": "#/$defs/item"}},
+			"status": {"enum": ["ok", "partial"]}
+		},
+		"$defs": {"item": {"type": "object", "required": ["name"], "properties": {"name": {"type": "string", "pattern": "^[a-z]+$"}}}}
+	}`)
+	cases := []struct {
+		out  string
+		want []string
+	}{
+		{`{"count":1,"items":[{"name":"a"}],"status":"ok"}`, nil},
+		{`{"count":1.5,"items":[]}`, []string{"$.count: expected integer, got number"}},
+		{`{"count":-1,"items":[{"name":"A1"},{}],"extra":true}`, []string{
+			"$.count: must be >= 0",
+			"$: unexpected property \"extra\"",
+			"$.items[0].name: must match pattern \"^[a-z]+$\"",
+			"$.items[1]: missing required property \"name\"",
+		}},
+		{`{"items":[1,2,3],"status":"bad"}`, []string{
+			"$: missing required property \"count\"",
+			"$.items: must have at most 2 items",
+			"$.items[0]: expected object, got integer",
+			"$.items[1]: expected object, got integer",
+			"$.items[2]: expected object, got integer",
+			"$.status: must be one of [\"ok\",\"partial\"]",
+		}},
+		{`not json`, []string{"$: output is not valid JSON"}},
+	}
+	for _, tc := range cases {
+		if got := ValidateOutput(schema, []byte(tc.out)); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s:\n got %q\nwant %q", tc.out, got, tc.want)
+		}
+	}
+	if got := ValidateOutput(nil, []byte("anything")); got != nil {
+		t.Fatalf("no schema: %q", got)
+	}
+	alt := json.RawMessage(`{"oneOf":[{"type":"string"},{"type":"number"}]}`)
+	if got := ValidateOutput(alt, []byte(`true`)); len(got) != 1 || !strings.Contains(got[0], "oneOf, matched 0") {
+		t.Fatalf("oneOf: %q", got)
+	}
+	if got := ValidateOutput(alt, []byte(`"x"`)); got != nil {
+		t.Fatalf("oneOf match: %q", got)
+	}
+	big := json.RawMessage(`{"type":"array","items":{"type":"string"}}`)
+	if got := ValidateOutput(big, []byte(`[1,2,3,4,5,6,7,8,9,10,11,12]`)); len(got) != maxOutputViolations {
+		t.Fatalf("violations not capped: %d", len(got))
+	}
+}
+
+func TestLoadManifest_OutputSchemaMustBeObject(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "tools.json")
+	data := `{"tools":[{"name":"x","command":["/bin/echo"],"outputSchema":["not","an","object"]}]}`
+	if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, _, err := LoadManifest(file); err == nil || !strings.Contains(err.Error(), "outputSchema must be a JSON object") {
+		t.Fatalf("err=%v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimit bounds how often a tool may run: a token bucket holding Burst
+// calls refills at PerMinute calls per minute.
+type RateLimit struct {
+	// PerMinute is the sustained call rate; it must be positive.
+	PerMinute float64 `json:"perMinute"`
+	// Burst is how many calls may run back to back; 0 means 1.
+	Burst int `json:"burst,omitempty"`
+}
+
+// RateLimitError reports a call refused by the tool's rateLimit; RetryAfter
+// is how long until the bucket holds a call again.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string { return "rate_limited" }
+
+func validateRateLimit(rl *RateLimit) error {
+	if rl == nil {
+		return nil
+	}
+	if !(rl.PerMinute > 0) || math.IsInf(rl.PerMinute, 0) {
+		return fmt.Errorf("rateLimit.perMinute must be positive")
+	}
+	if rl.Burst < 0 {
+		return fmt.Errorf("rateLimit.burst must not be negative")
+	}
+	return nil
+}
+
+// bucket is the state of one tool's rate limit.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateBuckets hold the limits of every tool by name for the whole process,
+// so aliases and subagents draw on the same bucket as the canonical tool.
+var (
+	rateMu      sync.Mutex
+	rateBuckets = map[string]*bucket{}
+)
+
+// takeRateToken spends one call from spec's bucket at now, or reports how
+// long until one is available. Callers pass the wall clock with its monotonic
+// reading, never clock.Now: a -deterministic clock stands still and would
+// never refill the bucket.
+func takeRateToken(spec ToolSpec, now time.Time) error {
+	rl := spec.RateLimit
+	if rl == nil || rl.PerMinute <= 0 {
+		return nil
+	}
+	burst := float64(max(rl.Burst, 1))
+	perNanos := rl.PerMinute / float64(time.Minute)
+
+	rateMu.Lock()
+	defer rateMu.Unlock()
+	b, ok := rateBuckets[spec.Name]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		rateBuckets[spec.Name] = b
+	}
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(burst, b.tokens+float64(elapsed)*perNanos)
+		b.last = now
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		return nil
+	}
+	wait := time.Duration(math.Ceil((1 - b.tokens) / perNanos))
+	return &RateLimitError{RetryAfter: wait}
+}
+
+// resetRateLimits forgets every bucket; tests use it to start fresh.
+func resetRateLimits() {
+	rateMu.Lock()
+	defer rateMu.Unlock()
+	rateBuckets = map[string]*bucket{}
+}
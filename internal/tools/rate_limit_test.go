@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/clock"
+)
+
+func TestTakeRateToken_BurstThenRefill(t *testing.T) {
+	resetRateLimits()
+	t.Cleanup(resetRateLimits)
+	spec := ToolSpec{Name: "img_create", RateLimit: &RateLimit{PerMinute: 6, Burst: 2}}
+	now := time.Unix(1700000000, 0)
+
+	for i := 0; i < 2; i++ {
+		if err := takeRateToken(spec, now); err != nil {
+			t.Fatalf("call %d within burst: %v", i, err)
+		}
+	}
+	var rl *RateLimitError
+	if err := takeRateToken(spec, now.Add(4*time.Second)); !errors.As(err, &rl) || rl.RetryAfter != 6*time.Second {
+		t.Fatalf("over limit: err=%v", err)
+	}
+	// One call refills every 10s
+	if err := takeRateToken(spec, now.Add(10*time.Second)); err != nil {
+		t.Fatalf("after refill: %v", err)
+	}
+	// Other tools keep their own bucket
+	if err := takeRateToken(ToolSpec{Name: "other", RateLimit: spec.RateLimit}, now); err != nil {
+		t.Fatalf("other tool: %v", err)
+	}
+}
+
+func TestRunToolWithJSON_RateLimited(t *testing.T) {
+	resetRateLimits()
+	t.Cleanup(resetRateLimits)
+	spec := ToolSpec{Name: "limited", Runtime: RuntimeJS, Source: writeRateLimitScript(t), RateLimit: &RateLimit{PerMinute: 1}}
+	if _, err := RunToolWithJSON(context.Background(), spec, []byte(`{}`), 5*time.Second); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	var rl *RateLimitError
+	if _, err := RunToolWithJSON(context.Background(), spec, []byte(`{}`), 5*time.Second); !errors.As(err, &rl) || rl.RetryAfter <= 0 {
+		t.Fatalf("second call: err=%v", err)
+	}
+}
+
+func TestRunToolWithJSON_RateLimitRefillsUnderDeterministicClock(t *testing.T) {
+	resetRateLimits()
+	t.Cleanup(resetRateLimits)
+	clock.SetDeterministic(0)
+	t.Cleanup(clock.Reset)
+	// One call every 50ms; a frozen clock would refuse the second call forever
+	spec := ToolSpec{Name: "limited", Runtime: RuntimeJS, Source: writeRateLimitScript(t), RateLimit: &RateLimit{PerMinute: 1200}}
+	if _, err := RunToolWithJSON(context.Background(), spec, []byte(`{}`), 5*time.Second); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	var rl *RateLimitError
+	_, err := RunToolWithJSON(context.Background(), spec, []byte(`{}`), 5*time.Second)
+	if errors.As(err, &rl) {
+		time.Sleep(rl.RetryAfter + 10*time.Millisecond)
+		_, err = RunToolWithJSON(context.Background(), spec, []byte(`{}`), 5*time.Second)
+	}
+	if err != nil {
+		t.Fatalf("call after refill: %v", err)
+	}
+}
+
+func writeRateLimitScript(t *testing.T) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "ok.js")
+	if err := os.WriteFile(p, []byte(`emit("ok")`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLoadManifest_RateLimitValidation(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tools.json")
+	writeManifest(t, file, `{"tools":[{"name":"x","command":["/bin/true"],"rateLimit":{"perMinute":0}}]}`)
+	if _, _, err := LoadManifest(file); err == nil || !strings.Contains(err.Error(), "rateLimit.perMinute") {
+		t.Fatalf("err=%v", err)
+	}
+}
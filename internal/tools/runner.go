@@ -1,12 +1,18 @@
 package tools
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"runtime"
+	"syscall"
 	"time"
+
+	"github.com/hyperifyio/goagent/internal/clock"
+	"github.com/hyperifyio/goagent/internal/tracing"
 )
 
 // RunToolWithJSON executes the tool command with args JSON provided on stdin.
@@ -14,7 +20,36 @@ import (
 // for mapping errors to deterministic JSON per product rules.
 // timeNow is a package-level clock to enable deterministic tests.
 // In production it defaults to time.Now.
-var timeNow = time.Now
+var timeNow = clock.Now
+
+// toolKillGrace is how long a canceled or timed-out tool has to exit after
+// SIGTERM before it is killed and its pipes are closed.
+var toolKillGrace = DefaultKillGrace
+
+// DefaultKillGrace is the grace period used until SetKillGrace is called.
+const DefaultKillGrace = 2 * time.Second
+
+// SetKillGrace sets how long a canceled or timed-out tool process has to
+// exit after SIGTERM before SIGKILL follows. Call it before running tools;
+// d <= 0 restores DefaultKillGrace.
+func SetKillGrace(d time.Duration) {
+	if d <= 0 {
+		d = DefaultKillGrace
+	}
+	toolKillGrace = d
+}
+
+// errToolTimedOut marks a run that hit its deadline; runTool replaces it with
+// a TimeoutError carrying the effective timeout.
+var errToolTimedOut = errors.New("tool timed out")
+
+// TimeoutError reports a tool run that outlived its effective timeout: the
+// manifest's timeoutSec when set, otherwise the caller's default.
+type TimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string { return "tool timed out" }
 
 // computeToolTimeout derives the timeout for a tool execution, honoring
 // spec.TimeoutSec when provided; otherwise it falls back to the default.
@@ -27,7 +62,8 @@ func computeToolTimeout(spec ToolSpec, defaultTimeout time.Duration) time.Durati
 
 // buildToolEnvironment constructs a minimal environment for the tool process
 // and returns the environment slice along with the list of env keys that were
-// passed through (for audit visibility).
+// passed through (for audit visibility). The workspace roots and the call's
+// idempotency key, when set, are always included.
 func buildToolEnvironment(spec ToolSpec) (env []string, passedKeys []string) {
 	if v := os.Getenv("PATH"); v != "" {
 		env = append(env, "PATH="+v)
@@ -43,47 +79,131 @@ func buildToolEnvironment(spec ToolSpec) (env []string, passedKeys []string) {
 			}
 		}
 	}
+	env = append(env, workspaceEnv...)
+	if spec.IdempotencyKey != "" {
+		env = append(env, IdempotencyKeyEnv+"="+spec.IdempotencyKey)
+	}
 	return env, passedKeys
 }
 
+// terminateProcess asks the tool to stop. Windows has no SIGTERM, so the
+// process is killed outright there.
+func terminateProcess(p *os.Process) error {
+	if runtime.GOOS == "windows" {
+		return p.Kill()
+	}
+	return p.Signal(syscall.SIGTERM)
+}
+
+// exitError reports a tool that ran and failed: a nonzero exit, or a thrown
+// error in a js or starlark tool. Its message is the stderr text, or the
+// failure itself when stderr is empty.
+type exitError struct {
+	msg    string
+	stderr string
+}
+
+func (e *exitError) Error() string { return e.msg }
+
 // normalizeWaitError maps timeout and process errors to deterministic errors.
 func normalizeWaitError(ctx context.Context, waitErr error, stderrText string) error {
 	if ctx.Err() == context.DeadlineExceeded {
-		return errors.New("tool timed out")
+		return errToolTimedOut
+	}
+	if ctx.Err() == context.Canceled {
+		return errors.New("tool canceled")
 	}
 	if waitErr != nil {
 		msg := stderrText
 		if msg == "" {
 			msg = waitErr.Error()
 		}
-		return errors.New(msg)
+		return &exitError{msg: msg, stderr: stderrText}
 	}
 	return nil
 }
 
+// ProgramPath returns the file a tool runs: the script of a js or starlark
+// tool, otherwise command[0]. It is empty for a tool without either.
+func ProgramPath(spec ToolSpec) string {
+	if usesSource(spec.Runtime) {
+		return spec.Source
+	}
+	if len(spec.Command) == 0 {
+		return ""
+	}
+	return spec.Command[0]
+}
+
+// CheckProgram reports whether the tool can start: the program of a process
+// tool must resolve like exec.LookPath, and the module of a wasm tool or the
+// script of a js or starlark tool must be a regular file.
+func CheckProgram(spec ToolSpec) error {
+	program := ProgramPath(spec)
+	if spec.Runtime == RuntimeWasm || usesSource(spec.Runtime) {
+		info, err := os.Stat(program)
+		if err == nil && !info.Mode().IsRegular() {
+			err = fmt.Errorf("%s is not a regular file", program)
+		}
+		return err
+	}
+	_, err := exec.LookPath(program)
+	return err
+}
+
 func RunToolWithJSON(parentCtx context.Context, spec ToolSpec, jsonInput []byte, defaultTimeout time.Duration) ([]byte, error) {
-	start := time.Now()
+	run := func() ([]byte, error) { return runWithRetries(parentCtx, spec, jsonInput, defaultTimeout) }
+	if ic := currentInterceptor(); ic != nil {
+		return ic(spec.Name, jsonInput, run)
+	}
+	return run()
+}
+
+func runTool(parentCtx context.Context, spec ToolSpec, jsonInput []byte, defaultTimeout time.Duration) (_ []byte, runErr error) {
+	start := timeNow()
+	// Trace the run as a tool.exec span under the caller's step
+	parentCtx, span := tracing.Start(parentCtx, "tool.exec", tracing.String("tool.name", spec.Name))
+	defer func() { span.End(runErr) }()
+	// Refuse calls over the tool's rateLimit before starting anything
+	if err := takeRateToken(spec, time.Now()); err != nil {
+		return nil, err
+	}
 	// Derive timeout, honoring per-tool override when provided.
 	to := computeToolTimeout(spec, defaultTimeout)
 	ctx, cancel := context.WithTimeout(parentCtx, to)
 	defer cancel()
+	defer func() {
+		if errors.Is(runErr, errToolTimedOut) {
+			runErr = &TimeoutError{Timeout: to}
+		}
+	}()
+	switch spec.Runtime {
+	case RuntimeWasm:
+		return runWasmTool(ctx, spec, jsonInput, start, span)
+	case RuntimeJS:
+		return runJSTool(ctx, spec, jsonInput, start, span)
+	case RuntimeStarlark:
+		return runStarlarkTool(ctx, spec, jsonInput, start, span)
+	}
 
 	cmd := exec.CommandContext(ctx, spec.Command[0], spec.Command[1:]...)
+	// On cancel or timeout send SIGTERM first; SIGKILL follows after the grace
+	// period so the tool is never left running after the agent gives up on it.
+	cmd.Cancel = func() error { return terminateProcess(cmd.Process) }
+	cmd.WaitDelay = toolKillGrace
 	// Build minimal environment and record passed-through keys for audit.
 	env, passedKeys := buildToolEnvironment(spec)
 	cmd.Env = env
+	cmd.Dir = spec.WorkDir
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
 		return nil, fmt.Errorf("stdin pipe: %w", err)
 	}
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return nil, fmt.Errorf("stdout pipe: %w", err)
-	}
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return nil, fmt.Errorf("stderr pipe: %w", err)
-	}
+	// Collect output into buffers rather than pipes: Wait then returns only
+	// after both streams are fully copied, so fast tools cannot lose output.
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
 
 	if err := cmd.Start(); err != nil {
 		return nil, fmt.Errorf("start: %w", err)
@@ -92,11 +212,12 @@ func RunToolWithJSON(parentCtx context.Context, spec ToolSpec, jsonInput []byte,
 	if len(jsonInput) == 0 {
 		jsonInput = []byte("{}")
 	}
-	if _, err := stdin.Write(jsonInput); err != nil {
-		return nil, fmt.Errorf("write stdin: %w", err)
-	}
+	// A tool that exits without reading all of its input breaks the pipe;
+	// its exit status, not the write error, decides the outcome, so a
+	// transient failure is still classified for retries.
+	_, writeErr := stdin.Write(jsonInput)
 	// Best-effort close; log failure to audit but do not fail run
-	if err := stdin.Close(); err != nil {
+	if err := stdin.Close(); err != nil && writeErr == nil {
 		// Capture the close error as a best-effort audit line
 		if err2 := appendAuditLog(map[string]any{
 			"ts":    timeNow().UTC().Format(time.RFC3339Nano),
@@ -108,15 +229,9 @@ func RunToolWithJSON(parentCtx context.Context, spec ToolSpec, jsonInput []byte,
 		}
 	}
 
-	// Read stdout and stderr fully
-	outCh := make(chan []byte, 1)
-	errCh := make(chan []byte, 1)
-	go func() { outCh <- safeReadAll(stdout) }()
-	go func() { errCh <- safeReadAll(stderr) }()
-
 	err = cmd.Wait()
-	out := <-outCh
-	serr := <-errCh
+	out := stdout.Bytes()
+	serr := stderr.Bytes()
 
 	exitCode := 0
 	if err != nil {
@@ -130,8 +245,11 @@ func RunToolWithJSON(parentCtx context.Context, spec ToolSpec, jsonInput []byte,
 	}
 	// Best-effort audit (failures do not affect tool result)
 	writeAudit(spec, start, exitCode, len(out), len(serr), passedKeys)
+	span.SetAttributes(tracing.Int("tool.exit_code", exitCode), tracing.Int("tool.stdout_bytes", len(out)), tracing.Int("tool.stderr_bytes", len(serr)))
 
-	if normErr := normalizeWaitError(ctx, err, string(serr)); normErr != nil {
+	normErr := normalizeWaitError(ctx, err, string(serr))
+	recordToolMetrics(spec.Name, timeNow().Sub(start), len(out), ctx.Err(), normErr)
+	if normErr != nil {
 		return nil, normErr
 	}
 	return out, nil
@@ -1,10 +1,14 @@
 package tools
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"os"
-	"path/filepath"
 	"time"
+
+	"github.com/hyperifyio/goagent/internal/audit"
+	"github.com/hyperifyio/goagent/internal/metrics"
+	"github.com/hyperifyio/goagent/internal/redact"
 )
 
 // writeAudit emits an NDJSON line capturing tool execution metadata.
@@ -29,10 +33,10 @@ func writeAudit(spec ToolSpec, start time.Time, exitCode, stdoutBytes, stderrByt
 	entry := auditEntry{
 		TS:          timeNow().UTC().Format(time.RFC3339Nano),
 		Tool:        spec.Name,
-		Argv:        redactSensitiveStrings(append([]string(nil), spec.Command...)),
-		CWD:         redactSensitiveString(cwd),
+		Argv:        redact.Strings(append([]string(nil), spec.Command...)),
+		CWD:         redact.String(cwd),
 		Exit:        exitCode,
-		MS:          time.Since(start).Milliseconds(),
+		MS:          timeNow().Sub(start).Milliseconds(),
 		StdoutBytes: stdoutBytes,
 		StderrBytes: stderrBytes,
 		Truncated:   false,
@@ -43,53 +47,25 @@ func writeAudit(spec ToolSpec, start time.Time, exitCode, stdoutBytes, stderrByt
 	}
 }
 
-// appendAuditLog writes an NDJSON audit line to .goagent/audit/YYYYMMDD.log under the repository root.
-// The repository root is determined by walking upward from the current working directory
-// until a directory containing go.mod is found. If no go.mod is found, falls back to CWD.
-func appendAuditLog(entry any) error {
-	b, err := json.Marshal(entry)
-	if err != nil {
-		return err
-	}
-	root := moduleRoot()
-	dir := filepath.Join(root, ".goagent", "audit")
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return err
-	}
-	fname := timeNow().UTC().Format("20060102") + ".log"
-	path := filepath.Join(dir, fname)
-	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
-	if err != nil {
-		return err
+// recordToolMetrics records latency, outcome (ok|error|timeout|canceled), and
+// stdout size for one tool run.
+func recordToolMetrics(tool string, elapsed time.Duration, stdoutBytes int, ctxErr, runErr error) {
+	outcome := "ok"
+	switch {
+	case errors.Is(ctxErr, context.DeadlineExceeded):
+		outcome = "timeout"
+	case errors.Is(ctxErr, context.Canceled):
+		outcome = "canceled"
+	case runErr != nil:
+		outcome = "error"
 	}
-	defer func() {
-		if err := f.Close(); err != nil {
-			_ = err
-		}
-	}()
-	if _, err := f.Write(append(b, '\n')); err != nil {
-		return err
-	}
-	return nil
+	metrics.ToolDuration.Observe(elapsed.Seconds(), tool, outcome)
+	metrics.ToolOutputBytes.Add(float64(stdoutBytes), tool)
 }
 
-// moduleRoot walks upward from the current working directory to locate the directory
-// containing go.mod. If none is found, it returns the current working directory.
-func moduleRoot() string {
-	cwd, err := os.Getwd()
-	if err != nil || cwd == "" {
-		return "."
-	}
-	dir := cwd
-	for {
-		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
-			return dir
-		}
-		parent := filepath.Dir(dir)
-		if parent == dir {
-			// Reached filesystem root; fallback to original cwd
-			return cwd
-		}
-		dir = parent
-	}
+// appendAuditLog writes an NDJSON audit line to .goagent/audit/YYYYMMDD.log under the repository root
+// using the shared audit writer, which applies size/day rotation and retention.
+// The file date follows timeNow so tests can pin the day deterministically.
+func appendAuditLog(entry any) error {
+	return audit.AppendAt(audit.ModuleRoot(), timeNow(), entry)
 }
@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
@@ -42,6 +43,26 @@ func main(){_,_ = io.ReadAll(os.Stdin); time.Sleep(2*time.Second)}
 	if err.Error() != "tool timed out" {
 		t.Fatalf("expected 'tool timed out', got: %v", err)
 	}
+	// The manifest's timeoutSec wins over the default, shorter or longer
+	for _, def := range []time.Duration{3 * time.Second, 200 * time.Millisecond} {
+		_, err := RunToolWithJSON(context.Background(), spec, []byte(`{}`), def)
+		var te *TimeoutError
+		if !errors.As(err, &te) || te.Timeout != time.Second {
+			t.Fatalf("default %s: want TimeoutError after 1s, got %#v", def, err)
+		}
+	}
+}
+
+func TestSetKillGrace(t *testing.T) {
+	defer SetKillGrace(0)
+	SetKillGrace(500 * time.Millisecond)
+	if toolKillGrace != 500*time.Millisecond {
+		t.Fatalf("grace=%s", toolKillGrace)
+	}
+	SetKillGrace(0)
+	if toolKillGrace != DefaultKillGrace {
+		t.Fatalf("grace=%s want default", toolKillGrace)
+	}
 }
 
 func TestRunToolWithJSON_SuccessEcho(t *testing.T) {
@@ -239,7 +260,7 @@ func main(){b,_:=io.ReadAll(os.Stdin); fmt.Print(string(b))}
 func TestRunToolWithJSON_AuditLog_Redaction(t *testing.T) {
 	// Arrange: set env secrets and GOAGENT_REDACT patterns
 	t.Setenv("OAI_API_KEY", "sk-test-1234567890")
-	t.Setenv("GOAGENT_REDACT", "secret,sk-[a-z0-9]+")
+	t.Setenv("GOAGENT_REDACT", "secret\nsk-[a-z0-9]+")
 
 	dir := t.TempDir()
 	helper := filepath.Join(dir, "echo.go")
@@ -447,3 +468,35 @@ func waitForAuditFile(t *testing.T, auditDir string, timeout time.Duration) stri
 
 // containsFind is a tiny helper to avoid importing strings in this test's top-level import list diff
 func containsFind(s, sub string) bool { return strings.Contains(s, sub) }
+
+func TestRunToolWithJSON_Cancel_TerminatesThenKills(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SIGTERM semantics are POSIX-only")
+	}
+	dir := t.TempDir()
+	helper := filepath.Join(dir, "stubborn.go")
+	if err := os.WriteFile(helper, []byte(`package main
+import ("io"; "os"; "os/signal"; "syscall"; "time")
+func main(){signal.Ignore(syscall.SIGTERM); _,_ = io.ReadAll(os.Stdin); time.Sleep(30*time.Second)}
+`), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	bin := filepath.Join(dir, "stubborn")
+	if out, err := exec.Command("go", "build", "-o", bin, helper).CombinedOutput(); err != nil {
+		t.Fatalf("build helper: %v: %s", err, string(out))
+	}
+	oldGrace := toolKillGrace
+	toolKillGrace = 200 * time.Millisecond
+	defer func() { toolKillGrace = oldGrace }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	start := time.Now()
+	_, err := RunToolWithJSON(ctx, ToolSpec{Name: "stubborn", Command: []string{bin}}, []byte(`{}`), 30*time.Second)
+	if err == nil || err.Error() != "tool canceled" {
+		t.Fatalf("expected 'tool canceled', got: %v", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Fatalf("tool ignoring SIGTERM was not killed after the grace period (took %s)", d)
+	}
+}
@@ -0,0 +1,385 @@
+package tools
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+// simplifyMaxDepth is the deepest object nesting kept as structured
+// properties. The top-level parameters object is depth 0, so its direct
+// object-valued properties are kept and anything below them is collapsed.
+const simplifyMaxDepth = 1
+
+// SimplifyToolSchemas returns a copy of tools whose parameter schemas are
+// rewritten by SimplifySchema. Tools with empty or unparsable schemas are
+// passed through unchanged.
+func SimplifyToolSchemas(in []oai.Tool) []oai.Tool {
+	out := make([]oai.Tool, len(in))
+	for i, t := range in {
+		out[i] = t
+		out[i].Function.Parameters = SimplifySchema(t.Function.Parameters)
+	}
+	return out
+}
+
+// SimplifySchema flattens a JSON Schema so small models can follow it:
+// local $ref pointers are inlined, allOf is merged, oneOf/anyOf collapse into
+// a single object (union of properties, intersection of required) or the
+// first primitive alternative, and objects nested deeper than one level are
+// replaced by a plain object annotated with an example value. Keywords that
+// small models tend to mangle ($schema, $id, $defs/definitions) are dropped.
+func SimplifySchema(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return raw
+	}
+	var root map[string]any
+	if err := json.Unmarshal(raw, &root); err != nil {
+		return raw
+	}
+	s := &simplifier{root: root}
+	out := s.node(root, 0, 0)
+	b, err := json.Marshal(out)
+	if err != nil {
+		return raw
+	}
+	return b
+}
+
+type simplifier struct {
+	root map[string]any
+}
+
+// maxRefDepth bounds $ref expansion to survive recursive definitions.
+const maxRefDepth = 8
+
+func (s *simplifier) node(n map[string]any, depth, refDepth int) map[string]any {
+	n = s.resolveRef(n, refDepth)
+	out := make(map[string]any, len(n))
+	for k, v := range n {
+		switch k {
+		case "$schema", "$id", "$defs", "definitions", "$ref":
+			continue
+		}
+		out[k] = v
+	}
+	if all, ok := out["allOf"].([]any); ok {
+		delete(out, "allOf")
+		for _, alt := range all {
+			if m, ok := alt.(map[string]any); ok {
+				mergeObjectSchema(out, s.resolveRef(m, refDepth), true)
+			}
+		}
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		alts, ok := out[key].([]any)
+		if !ok {
+			continue
+		}
+		delete(out, key)
+		s.collapseAlternatives(out, alts, refDepth)
+	}
+	if props, ok := out["properties"].(map[string]any); ok {
+		simplified := make(map[string]any, len(props))
+		for name, p := range props {
+			pm, ok := p.(map[string]any)
+			if !ok {
+				simplified[name] = p
+				continue
+			}
+			child := s.node(pm, depth+1, refDepth)
+			if depth+1 > simplifyMaxDepth && isObjectSchema(child) {
+				child = collapseObject(child)
+			}
+			simplified[name] = child
+		}
+		out["properties"] = simplified
+	}
+	if items, ok := out["items"].(map[string]any); ok {
+		child := s.node(items, depth+1, refDepth)
+		if depth+1 > simplifyMaxDepth && isObjectSchema(child) {
+			child = collapseObject(child)
+		}
+		out["items"] = child
+	}
+	return out
+}
+
+// resolveRef inlines a local "#/$defs/X" or "#/definitions/X" reference,
+// keeping sibling keywords from the referencing node.
+func (s *simplifier) resolveRef(n map[string]any, refDepth int) map[string]any {
+	ref, ok := n["$ref"].(string)
+	if !ok || refDepth >= maxRefDepth {
+		return n
+	}
+	var target map[string]any
+	for _, prefix := range []string{"#/$defs/", "#/definitions/"} {
+		if strings.HasPrefix(ref, prefix) {
+			container, _ := s.root[strings.TrimSuffix(strings.TrimPrefix(prefix, "#/"), "/")].(map[string]any)
+			target, _ = container[strings.TrimPrefix(ref, prefix)].(map[string]any)
+		}
+	}
+	if target == nil {
+		return n
+	}
+	merged := make(map[string]any, len(target)+len(n))
+	for k, v := range s.resolveRef(target, refDepth+1) {
+		merged[k] = v
+	}
+	for k, v := range n {
+		if k != "$ref" {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// collapseAlternatives folds oneOf/anyOf alternatives into out and records a
+// short "Accepts one of" note in the description.
+func (s *simplifier) collapseAlternatives(out map[string]any, alts []any, refDepth int) {
+	var summaries []string
+	objectSeen := false
+	var requiredSets [][]string
+	for _, alt := range alts {
+		m, ok := alt.(map[string]any)
+		if !ok {
+			continue
+		}
+		m = s.resolveRef(m, refDepth)
+		summaries = append(summaries, summarizeAlternative(m))
+		if isObjectSchema(m) {
+			objectSeen = true
+			mergeObjectSchema(out, m, false)
+			requiredSets = append(requiredSets, stringList(m["required"]))
+			continue
+		}
+		if _, has := out["type"]; !has && !objectSeen {
+			for k, v := range m {
+				if _, exists := out[k]; !exists && k != "description" {
+					out[k] = v
+				}
+			}
+		}
+	}
+	if objectSeen {
+		out["type"] = "object"
+		req := stringList(out["required"])
+		if len(requiredSets) > 0 {
+			common := intersect(requiredSets)
+			req = unionStrings(req, common)
+		}
+		if len(req) > 0 {
+			out["required"] = toAnyList(req)
+		} else {
+			delete(out, "required")
+		}
+	}
+	if len(summaries) > 0 {
+		appendDescription(out, "Accepts one of: "+strings.Join(summaries, "; ")+".")
+	}
+}
+
+// mergeObjectSchema copies properties (and, when withRequired, required
+// names) from src into dst.
+func mergeObjectSchema(dst, src map[string]any, withRequired bool) {
+	if props, ok := src["properties"].(map[string]any); ok {
+		dp, _ := dst["properties"].(map[string]any)
+		if dp == nil {
+			dp = make(map[string]any, len(props))
+		}
+		for k, v := range props {
+			if _, exists := dp[k]; !exists {
+				dp[k] = v
+			}
+		}
+		dst["properties"] = dp
+		if _, has := dst["type"]; !has {
+			dst["type"] = "object"
+		}
+	}
+	if withRequired {
+		if req := unionStrings(stringList(dst["required"]), stringList(src["required"])); len(req) > 0 {
+			dst["required"] = toAnyList(req)
+		}
+	}
+	if d, ok := src["description"].(string); ok && strings.TrimSpace(d) != "" {
+		appendDescription(dst, d)
+	}
+}
+
+// collapseObject replaces a nested object schema with a plain object whose
+// description carries an example value generated from the original.
+func collapseObject(n map[string]any) map[string]any {
+	out := map[string]any{"type": "object"}
+	desc, _ := n["description"].(string)
+	ex, err := json.Marshal(exampleValue(n, 0))
+	if err == nil {
+		if strings.TrimSpace(desc) != "" {
+			desc = strings.TrimSpace(desc) + " "
+		}
+		desc += "Example: " + string(ex)
+	}
+	if desc != "" {
+		out["description"] = desc
+	}
+	return out
+}
+
+// exampleValue builds a representative value from default, examples, enum,
+// or type placeholders.
+func exampleValue(n map[string]any, depth int) any {
+	if v, ok := n["default"]; ok {
+		return v
+	}
+	if exs, ok := n["examples"].([]any); ok && len(exs) > 0 {
+		return exs[0]
+	}
+	if enum, ok := n["enum"].([]any); ok && len(enum) > 0 {
+		return enum[0]
+	}
+	switch schemaType(n) {
+	case "object":
+		obj := map[string]any{}
+		if depth >= maxRefDepth {
+			return obj
+		}
+		props, _ := n["properties"].(map[string]any)
+		for k, p := range props {
+			if pm, ok := p.(map[string]any); ok {
+				obj[k] = exampleValue(pm, depth+1)
+			}
+		}
+		return obj
+	case "array":
+		if items, ok := n["items"].(map[string]any); ok && depth < maxRefDepth {
+			return []any{exampleValue(items, depth+1)}
+		}
+		return []any{}
+	case "integer", "number":
+		if v, ok := n["minimum"]; ok {
+			return v
+		}
+		return 0
+	case "boolean":
+		return false
+	default:
+		return "string"
+	}
+}
+
+func summarizeAlternative(m map[string]any) string {
+	if t, ok := m["title"].(string); ok && strings.TrimSpace(t) != "" {
+		return strings.TrimSpace(t)
+	}
+	if isObjectSchema(m) {
+		props, _ := m["properties"].(map[string]any)
+		names := make([]string, 0, len(props))
+		for k := range props {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		return "object with " + strings.Join(names, ", ")
+	}
+	if t := schemaType(m); t != "" {
+		return t
+	}
+	return "value"
+}
+
+func isObjectSchema(n map[string]any) bool {
+	if schemaType(n) == "object" {
+		return true
+	}
+	_, hasProps := n["properties"].(map[string]any)
+	return hasProps
+}
+
+func schemaType(n map[string]any) string {
+	switch t := n["type"].(type) {
+	case string:
+		return t
+	case []any:
+		for _, v := range t {
+			if s, ok := v.(string); ok && s != "null" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+func appendDescription(n map[string]any, text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	if d, ok := n["description"].(string); ok && strings.TrimSpace(d) != "" {
+		if strings.Contains(d, text) {
+			return
+		}
+		n["description"] = strings.TrimSpace(d) + " " + text
+		return
+	}
+	n["description"] = text
+}
+
+func stringList(v any) []string {
+	list, _ := v.([]any)
+	out := make([]string, 0, len(list))
+	for _, x := range list {
+		if s, ok := x.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func toAnyList(in []string) []any {
+	out := make([]any, len(in))
+	for i, s := range in {
+		out[i] = s
+	}
+	return out
+}
+
+func unionStrings(a, b []string) []string {
+	seen := make(map[string]struct{}, len(a)+len(b))
+	var out []string
+	for _, s := range append(append([]string{}, a...), b...) {
+		if _, ok := seen[s]; ok {
+			continue
+		}
+		seen[s] = struct{}{}
+		out = append(out, s)
+	}
+	return out
+}
+
+func intersect(sets [][]string) []string {
+	if len(sets) == 0 {
+		return nil
+	}
+	var out []string
+	for _, s := range sets[0] {
+		inAll := true
+		for _, other := range sets[1:] {
+			found := false
+			for _, o := range other {
+				if o == s {
+					found = true
+					break
+				}
+			}
+			if !found {
+				inAll = false
+				break
+			}
+		}
+		if inAll {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperifyio/goagent/internal/oai"
+)
+
+func decodeSchema(t *testing.T, raw json.RawMessage) map[string]any {
+	t.Helper()
+	var m map[string]any
+	if err := json.Unmarshal(raw, &m); err != nil {
+		t.Fatalf("unmarshal: %v; raw=%s", err, raw)
+	}
+	return m
+}
+
+func TestSimplifySchema_CollapsesOneOfObjects(t *testing.T) {
+	raw := json.RawMessage(`{
+		"$schema":"https://json-schema.org/draft/2020-12/schema",
+		"type":"object",
+		"oneOf":[
+			{"properties":{"path":{"type":"string"},"mode":{"type":"string"}},"required":["path","mode"]},
+			{"properties":{"path":{"type":"string"},"glob":{"type":"string"}},"required":["path"]}
+		]
+	}`)
+	got := decodeSchema(t, SimplifySchema(raw))
+	if _, ok := got["oneOf"]; ok {
+		t.Fatalf("oneOf should be removed: %v", got)
+	}
+	if _, ok := got["$schema"]; ok {
+		t.Fatalf("$schema should be dropped")
+	}
+	props := got["properties"].(map[string]any)
+	for _, k := range []string{"path", "mode", "glob"} {
+		if _, ok := props[k]; !ok {
+			t.Fatalf("missing merged property %q: %v", k, props)
+		}
+	}
+	req := got["required"].([]any)
+	if len(req) != 1 || req[0] != "path" {
+		t.Fatalf("required should be the intersection [path], got %v", req)
+	}
+	if d, _ := got["description"].(string); !strings.Contains(d, "Accepts one of:") {
+		t.Fatalf("expected alternatives note in description, got %q", d)
+	}
+}
+
+func TestSimplifySchema_FlattensDeepNestingWithExample(t *testing.T) {
+	raw := json.RawMessage(`{
+		"type":"object",
+		"$defs":{"save":{"type":"object","properties":{"dir":{"type":"string"},"ext":{"type":"string","enum":["png"]}}}},
+		"properties":{
+			"opts":{"type":"object","properties":{
+				"save":{"$ref":"#/$defs/save","description":"Where to save"}
+			}}
+		}
+	}`)
+	got := decodeSchema(t, SimplifySchema(raw))
+	if _, ok := got["$defs"]; ok {
+		t.Fatalf("$defs should be dropped")
+	}
+	opts := got["properties"].(map[string]any)["opts"].(map[string]any)
+	save := opts["properties"].(map[string]any)["save"].(map[string]any)
+	if _, ok := save["properties"]; ok {
+		t.Fatalf("depth-2 object should be collapsed: %v", save)
+	}
+	d, _ := save["description"].(string)
+	if !strings.HasPrefix(d, "Where to save Example: ") || !strings.Contains(d, `"ext":"png"`) {
+		t.Fatalf("unexpected collapsed description %q", d)
+	}
+}
+
+func TestSimplifyToolSchemas_PassesThroughInvalid(t *testing.T) {
+	in := []oai.Tool{{Type: "function", Function: oai.ToolFunction{Name: "x", Parameters: json.RawMessage(`not json`)}}}
+	out := SimplifyToolSchemas(in)
+	if string(out[0].Function.Parameters) != "not json" {
+		t.Fatalf("invalid schema should pass through, got %s", out[0].Function.Parameters)
+	}
+}
@@ -0,0 +1,118 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/tools/starlarkrun"
+	"github.com/hyperifyio/goagent/internal/tracing"
+)
+
+// starlarkOutputKB caps the output of a "starlark" tool.
+const starlarkOutputKB = 64
+
+type cachedStarlarkTool struct {
+	size    int64
+	modTime time.Time
+	tool    *starlarkrun.Tool
+}
+
+var (
+	starlarkToolsMu sync.Mutex
+	starlarkTools   = map[string]cachedStarlarkTool{}
+)
+
+// loadStarlarkTool returns the compiled tool at path, recompiling it when
+// the file changed since it was last compiled.
+func loadStarlarkTool(path string) (*starlarkrun.Tool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	starlarkToolsMu.Lock()
+	defer starlarkToolsMu.Unlock()
+	if c, ok := starlarkTools[path]; ok && c.size == info.Size() && c.modTime.Equal(info.ModTime()) {
+		return c.tool, nil
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tool, err := starlarkrun.Compile(path, src)
+	if err != nil {
+		return nil, err
+	}
+	starlarkTools[path] = cachedStarlarkTool{size: info.Size(), modTime: info.ModTime(), tool: tool}
+	return tool, nil
+}
+
+// discoverStarlarkTools compiles every .star file directly inside dir, in
+// name order, into a "starlark" runtime spec named after the file.
+func discoverStarlarkTools(dir string) ([]ToolSpec, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	var specs []ToolSpec
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), starlarkrun.Ext) {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		tool, err := loadStarlarkTool(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.Name(), err)
+		}
+		specs = append(specs, ToolSpec{
+			Name:        tool.Name,
+			Description: tool.Description,
+			Schema:      tool.Schema,
+			Runtime:     RuntimeStarlark,
+			Source:      path,
+		})
+	}
+	return specs, nil
+}
+
+// runStarlarkTool runs a "starlark" runtime tool: main() reads the JSON
+// arguments with read_input() and returns its result through emit(). A
+// failure reports the Starlark backtrace; the tool timeout bounds wall time.
+func runStarlarkTool(ctx context.Context, spec ToolSpec, jsonInput []byte, start time.Time, span *tracing.Span) ([]byte, error) {
+	if len(jsonInput) == 0 {
+		jsonInput = []byte("{}")
+	}
+	var out string
+	tool, err := loadStarlarkTool(spec.Source)
+	if err != nil {
+		err = fmt.Errorf("load script: %w", err)
+	} else {
+		out, err = tool.Exec(ctx, string(jsonInput), starlarkOutputKB*1024)
+	}
+	exitCode := 0
+	switch {
+	case errors.Is(err, starlarkrun.ErrOutputLimit):
+		err = fmt.Errorf("tool output exceeded %d KB", starlarkOutputKB)
+		exitCode = 1
+	case ctx.Err() != nil:
+		exitCode = -1
+	case err != nil:
+		exitCode = 1
+	}
+	writeAudit(spec, start, exitCode, len(out), 0, nil)
+	span.SetAttributes(tracing.Int("tool.exit_code", exitCode), tracing.Int("tool.stdout_bytes", len(out)))
+
+	normErr := normalizeWaitError(ctx, err, "")
+	recordToolMetrics(spec.Name, timeNow().Sub(start), len(out), ctx.Err(), normErr)
+	if normErr != nil {
+		return nil, normErr
+	}
+	return []byte(out), nil
+}
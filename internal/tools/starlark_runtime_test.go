@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadManifest_StarlarkDirs(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "tools", "star"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, src := range map[string]string{
+		"add.star": `description = "Add two numbers"
+schema = {"type": "object", "properties": {"x": {"type": "number"}, "y": {"type": "number"}}}
+
+def main():
+    a = json.decode(read_input())
+    emit(json.encode({"sum": a["x"] + a["y"]}))
+`,
+		"fail.star":  "def main():\n    fail('x must be a number')\n",
+		"spin.star":  "def main():\n    while True:\n        pass\n",
+		"notes.txt":  "not a tool",
+		"clock.star": "def main():\n    emit(str(time.now()))\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, "tools", "star", name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	man := filepath.Join(dir, "tools.json")
+	write := func(body string) {
+		t.Helper()
+		if err := os.WriteFile(man, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A tool that reaches for an undeclared clock fails discovery
+	write(`{"tools":[],"starlarkDirs":["tools/star"]}`)
+	if _, _, err := LoadManifest(man); err == nil || !strings.Contains(err.Error(), "clock.star") || !strings.Contains(err.Error(), "undefined: time") {
+		t.Fatalf("clock: err=%v", err)
+	}
+	if err := os.Remove(filepath.Join(dir, "tools", "star", "clock.star")); err != nil {
+		t.Fatal(err)
+	}
+
+	write(`{"tools":[{"name":"spin","runtime":"starlark","source":"tools/star/spin.star","timeoutSec":1}],"starlarkDirs":["tools/star"]}`)
+	if _, _, err := LoadManifest(man); err == nil || !strings.Contains(err.Error(), `"spin": duplicate name`) {
+		t.Fatalf("duplicate: err=%v", err)
+	}
+
+	write(`{"tools":[],"starlarkDirs":["tools/star"]}`)
+	reg, oaiTools, err := LoadManifest(man)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, tool := range oaiTools {
+		names = append(names, tool.Function.Name)
+	}
+	if strings.Join(names, ",") != "add,fail,spin" {
+		t.Fatalf("names=%v", names)
+	}
+	if oaiTools[0].Function.Description != "Add two numbers" || !strings.Contains(string(oaiTools[0].Function.Parameters), `"x"`) {
+		t.Fatalf("add tool=%+v", oaiTools[0].Function)
+	}
+	if err := CheckProgram(reg["add"]); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	out, err := RunToolWithJSON(context.Background(), reg["add"], []byte(`{"x":2,"y":3}`), 5*time.Second)
+	if err != nil || string(out) != `{"sum":5}` {
+		t.Fatalf("add: out=%q err=%v", out, err)
+	}
+	if _, err := RunToolWithJSON(context.Background(), reg["fail"], nil, 5*time.Second); err == nil || !strings.Contains(err.Error(), "x must be a number") {
+		t.Errorf("fail: err=%v", err)
+	}
+	if _, err := RunToolWithJSON(context.Background(), reg["spin"], nil, 500*time.Millisecond); err == nil || !strings.Contains(err.Error(), "tool timed out") {
+		t.Errorf("spin: err=%v", err)
+	}
+
+	write(`{"tools":[],"starlarkDirs":["../elsewhere"]}`)
+	if _, _, err := LoadManifest(man); err == nil || !strings.Contains(err.Error(), "starlarkDirs[0]") {
+		t.Fatalf("escape: err=%v", err)
+	}
+}
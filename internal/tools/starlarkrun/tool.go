@@ -0,0 +1,192 @@
+// Package starlarkrun runs manifest tools written in Starlark. A tool file
+// declares an optional `description` string and `schema` dict at top level
+// and a `main()` function that reads its arguments with read_input() and
+// returns its result through emit(). Tools are deny-by-default: besides the
+// Starlark builtins they see only the predeclared json and re modules,
+// read_input, and emit; there is no load(), filesystem, network, or clock.
+package starlarkrun
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	starjson "go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+)
+
+// Ext is the file extension of Starlark tools.
+const Ext = ".star"
+
+// ErrOutputLimit is returned by Exec when main emits more than the output
+// cap.
+var ErrOutputLimit = errors.New("OUTPUT_LIMIT")
+
+// fileOptions enables while loops, sets, and top-level statements but keeps
+// recursion off, so every program terminates or hits the tool timeout
+// without growing the Go stack.
+var fileOptions = &syntax.FileOptions{Set: true, While: true, TopLevelControl: true, GlobalReassign: true}
+
+// discoverySteps bounds the top-level evaluation in Compile, which runs
+// without a deadline.
+const discoverySteps = 1_000_000
+
+var toolNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Tool is a compiled Starlark tool. Each Exec runs the program in a fresh
+// thread with fresh globals, so a Tool is safe for concurrent use.
+type Tool struct {
+	// Name is the file name without Ext.
+	Name        string
+	Description string
+	// Schema is the JSON encoding of the schema global, or nil.
+	Schema json.RawMessage
+
+	prog *starlark.Program
+}
+
+// Compile compiles the tool at path from src and evaluates its top level
+// once to read description and schema and to check main.
+func Compile(path string, src []byte) (*Tool, error) {
+	name := strings.TrimSuffix(filepath.Base(path), Ext)
+	if !toolNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("tool name %q must match %s", name, toolNamePattern)
+	}
+	_, prog, err := starlark.SourceProgramOptions(fileOptions, path, src, predeclaredNames.Has)
+	if err != nil {
+		return nil, err
+	}
+	t := &Tool{Name: name, prog: prog}
+	thread := newThread(name)
+	thread.SetMaxExecutionSteps(discoverySteps)
+	globals, err := prog.Init(thread, predeclared("{}", nil, 0))
+	if err != nil {
+		return nil, err
+	}
+	if fn, ok := globals["main"].(*starlark.Function); !ok || fn.NumParams() != 0 {
+		return nil, errors.New("must define main() without parameters")
+	}
+	if v, ok := globals["description"]; ok {
+		s, ok := starlark.AsString(v)
+		if !ok {
+			return nil, fmt.Errorf("description must be a string, got %s", v.Type())
+		}
+		t.Description = s
+	}
+	if v, ok := globals["schema"]; ok {
+		if _, ok := v.(*starlark.Dict); !ok {
+			return nil, fmt.Errorf("schema must be a dict, got %s", v.Type())
+		}
+		enc, err := starlark.Call(thread, starjson.Module.Members["encode"], starlark.Tuple{v}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("schema: %w", err)
+		}
+		s, _ := starlark.AsString(enc)
+		t.Schema = json.RawMessage(s)
+	}
+	return t, nil
+}
+
+// Exec runs main with read_input() returning input. It stops the program
+// when ctx ends and returns ctx.Err(). Emitting more than outputCap bytes
+// returns the truncated output with ErrOutputLimit; other failures carry
+// the Starlark backtrace.
+func (t *Tool) Exec(ctx context.Context, input string, outputCap int) (string, error) {
+	var out bytes.Buffer
+	thread := newThread(t.Name)
+	stop := context.AfterFunc(ctx, func() { thread.Cancel(ctx.Err().Error()) })
+	defer stop()
+	globals, err := t.prog.Init(thread, predeclared(input, &out, outputCap))
+	if err == nil {
+		_, err = starlark.Call(thread, globals["main"], nil, nil)
+	}
+	if ctx.Err() != nil {
+		return out.String(), ctx.Err()
+	}
+	if errors.Is(err, ErrOutputLimit) {
+		return out.String(), ErrOutputLimit
+	}
+	var evalErr *starlark.EvalError
+	if errors.As(err, &evalErr) {
+		return out.String(), errors.New(evalErr.Backtrace())
+	}
+	return out.String(), err
+}
+
+func newThread(name string) *starlark.Thread {
+	return &starlark.Thread{
+		Name:  name,
+		Print: func(*starlark.Thread, string) {},
+		Load: func(*starlark.Thread, string) (starlark.StringDict, error) {
+			return nil, errors.New("load is not available to tools")
+		},
+	}
+}
+
+var predeclaredNames = predeclared("", nil, 0)
+
+// predeclared returns the tool stdlib. With a nil out, emit fails, which
+// keeps the top level of a tool free of output.
+func predeclared(input string, out *bytes.Buffer, outputCap int) starlark.StringDict {
+	return starlark.StringDict{
+		"json": starjson.Module,
+		"re": &starlarkstruct.Module{Name: "re", Members: starlark.StringDict{
+			"match": starlark.NewBuiltin("re.match", reMatch),
+		}},
+		"read_input": starlark.NewBuiltin("read_input", func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+				return nil, err
+			}
+			return starlark.String(input), nil
+		}),
+		"emit": starlark.NewBuiltin("emit", func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var s string
+			if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &s); err != nil {
+				return nil, err
+			}
+			if out == nil {
+				return nil, errors.New("emit: only available inside main()")
+			}
+			if out.Len()+len(s) > outputCap {
+				out.WriteString(s[:outputCap-out.Len()])
+				return nil, ErrOutputLimit
+			}
+			out.WriteString(s)
+			return starlark.None, nil
+		}),
+	}
+}
+
+// reMatch implements re.match(pattern, s): like Python it anchors at the
+// start of s, and it returns a tuple of the whole match and its groups, or
+// None. Patterns use Go RE2 syntax.
+func reMatch(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var pattern, s string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 2, &pattern, &s); err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(`^(?:` + pattern + `)`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", b.Name(), err)
+	}
+	m := re.FindStringSubmatchIndex(s)
+	if m == nil {
+		return starlark.None, nil
+	}
+	groups := make(starlark.Tuple, 0, len(m)/2)
+	for i := 0; i < len(m); i += 2 {
+		if m[i] < 0 {
+			groups = append(groups, starlark.None)
+			continue
+		}
+		groups = append(groups, starlark.String(s[m[i]:m[i+1]]))
+	}
+	return groups, nil
+}
@@ -0,0 +1,81 @@
+package starlarkrun
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompileAndExec(t *testing.T) {
+	tool, err := Compile("/tools/greet.star", []byte(`
+description = "Greet someone"
+schema = {"type": "object", "properties": {"name": {"type": "string"}}}
+
+def main():
+    args = json.decode(read_input())
+    m = re.match(r"(\w+)@(\w+)", args["name"])
+    emit(json.encode({"greeting": "hi " + m[1], "host": m[2]}))
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tool.Name != "greet" || tool.Description != "Greet someone" {
+		t.Fatalf("name=%q description=%q", tool.Name, tool.Description)
+	}
+	if string(tool.Schema) != `{"properties":{"name":{"type":"string"}},"type":"object"}` {
+		t.Fatalf("schema=%s", tool.Schema)
+	}
+	for i := 0; i < 2; i++ {
+		out, err := tool.Exec(context.Background(), `{"name":"ada@lab"}`, 1024)
+		if err != nil || out != `{"greeting":"hi ada","host":"lab"}` {
+			t.Fatalf("run %d: out=%q err=%v", i, out, err)
+		}
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	for src, want := range map[string]string{
+		`x = 1`:                              "must define main()",
+		`def main(a): pass`:                  "must define main()",
+		"description = 1\ndef main(): pass":  "description must be a string",
+		"schema = []\ndef main(): pass":      "schema must be a dict",
+		"emit('x')\ndef main(): pass":        "only available inside main()",
+		`load("x.star", "y")`:                "load is not available",
+		"open('/etc/passwd')\ndef main(): 1": "undefined: open",
+		`def main(`:                          "got end of file",
+	} {
+		if _, err := Compile("t.star", []byte(src)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: err=%v want %q", src, err, want)
+		}
+	}
+	if _, err := Compile("bad name.star", []byte("def main(): pass")); err == nil || !strings.Contains(err.Error(), "tool name") {
+		t.Errorf("bad name: err=%v", err)
+	}
+}
+
+func TestExec_Failures(t *testing.T) {
+	run := func(src string, ctx context.Context, outputCap int) (string, error) {
+		t.Helper()
+		tool, err := Compile("t.star", []byte(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tool.Exec(ctx, "{}", outputCap)
+	}
+	if _, err := run("def main():\n    fail('bad args')", context.Background(), 64); err == nil || !strings.Contains(err.Error(), "bad args") || !strings.Contains(err.Error(), "Traceback") {
+		t.Errorf("fail: err=%v", err)
+	}
+	if _, err := run("def main():\n    main()", context.Background(), 64); err == nil || !strings.Contains(err.Error(), "called recursively") {
+		t.Errorf("recursion: err=%v", err)
+	}
+	if out, err := run("def main():\n    emit('abc')\n    emit('defgh')", context.Background(), 5); !errors.Is(err, ErrOutputLimit) || out != "abcde" {
+		t.Errorf("output limit: out=%q err=%v", out, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := run("def main():\n    while True:\n        pass", ctx, 64); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("timeout: err=%v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// MaxToolRetries bounds a manifest's retries.
+const MaxToolRetries = 10
+
+// RetryOn selects which failures of a tool with retries are retried.
+type RetryOn struct {
+	// NonzeroExit retries a process that exited unsuccessfully, or a js,
+	// starlark, or wasm tool that failed.
+	NonzeroExit bool `json:"nonzeroExit,omitempty"`
+	// Timeout retries a run that hit its timeout.
+	Timeout bool `json:"timeout,omitempty"`
+	// StderrPattern retries a failure whose stderr matches this regular
+	// expression (Go RE2 syntax), whether or not NonzeroExit is set.
+	StderrPattern string `json:"stderrPattern,omitempty"`
+}
+
+// toolRetryBackoff is the wait before the first retry; it doubles for each
+// further retry up to toolRetryMaxBackoff. Tests shorten it.
+var (
+	toolRetryBackoff    = 500 * time.Millisecond
+	toolRetryMaxBackoff = 10 * time.Second
+)
+
+func validateRetries(t ToolSpec) error {
+	if t.Retries < 0 || t.Retries > MaxToolRetries {
+		return fmt.Errorf("retries must be between 0 and %d", MaxToolRetries)
+	}
+	if t.RetryOn == nil {
+		return nil
+	}
+	if t.Retries == 0 {
+		return fmt.Errorf("retryOn requires retries")
+	}
+	if t.RetryOn.StderrPattern != "" {
+		if _, err := regexp.Compile(t.RetryOn.StderrPattern); err != nil {
+			return fmt.Errorf("retryOn.stderrPattern: %v", err)
+		}
+	}
+	return nil
+}
+
+// runWithRetries runs the tool, retrying failures spec.RetryOn selects up
+// to spec.Retries times with exponential backoff. Only the last attempt's
+// result is returned; each retry is noted in the audit log.
+func runWithRetries(ctx context.Context, spec ToolSpec, jsonInput []byte, defaultTimeout time.Duration) ([]byte, error) {
+	wait := toolRetryBackoff
+	for attempt := 0; ; attempt++ {
+		out, err := runTool(ctx, spec, jsonInput, defaultTimeout)
+		if err == nil || attempt >= spec.Retries || !retryable(spec, err) || ctx.Err() != nil {
+			return out, err
+		}
+		if err2 := appendAuditLog(map[string]any{
+			"ts":        timeNow().UTC().Format(time.RFC3339Nano),
+			"event":     "tool_retry",
+			"tool":      spec.Name,
+			"attempt":   attempt + 1,
+			"error":     err.Error(),
+			"backoffMs": wait.Milliseconds(),
+		}); err2 != nil {
+			_ = err2
+		}
+		select {
+		case <-ctx.Done():
+			return out, err
+		case <-time.After(wait):
+		}
+		wait = min(wait*2, toolRetryMaxBackoff)
+	}
+}
+
+// retryable reports whether spec retries err. Without retryOn, nonzero
+// exits and timeouts are retried; rate-limited calls never are.
+func retryable(spec ToolSpec, err error) bool {
+	on := RetryOn{NonzeroExit: true, Timeout: true}
+	if spec.RetryOn != nil {
+		on = *spec.RetryOn
+	}
+	var timeout *TimeoutError
+	if errors.As(err, &timeout) {
+		return on.Timeout
+	}
+	var exit *exitError
+	if !errors.As(err, &exit) {
+		return false
+	}
+	if on.NonzeroExit {
+		return true
+	}
+	if on.StderrPattern != "" {
+		matched, _ := regexp.MatchString(on.StderrPattern, exit.stderr) //nolint:errcheck
+		return matched
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func shortRetryBackoff(t *testing.T) {
+	t.Helper()
+	prev := toolRetryBackoff
+	toolRetryBackoff = time.Millisecond
+	t.Cleanup(func() { toolRetryBackoff = prev })
+}
+
+// flakyTool fails with stderr msg until it has run fails times.
+func flakyTool(t *testing.T, fails int, msg string) (ToolSpec, string) {
+	t.Helper()
+	counter := filepath.Join(t.TempDir(), "runs")
+	script := `n=$(cat "$1" 2>/dev/null || echo 0); n=$((n+1)); echo $n > "$1"; if [ $n -le ` + strconv.Itoa(fails) + ` ]; then echo "` + msg + `" >&2; exit 1; fi; echo '{"ok":true}'`
+	return ToolSpec{Name: "flaky", Command: []string{"/bin/sh", "-c", script, "flaky", counter}}, counter
+}
+
+func runs(t *testing.T, counter string) string {
+	t.Helper()
+	b, err := os.ReadFile(counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func TestRunToolWithJSON_RetriesNonzeroExit(t *testing.T) {
+	shortRetryBackoff(t)
+	spec, counter := flakyTool(t, 2, "connection reset")
+	spec.Retries = 2
+	out, err := RunToolWithJSON(context.Background(), spec, []byte(`{}`), 5*time.Second)
+	if err != nil || strings.TrimSpace(string(out)) != `{"ok":true}` {
+		t.Fatalf("out=%q err=%v", out, err)
+	}
+	if got := runs(t, counter); got != "3" {
+		t.Fatalf("runs=%s, want 3", got)
+	}
+}
+
+func TestRunToolWithJSON_RetriesToolThatIgnoresLargeInput(t *testing.T) {
+	shortRetryBackoff(t)
+	spec, counter := flakyTool(t, 1, "connection reset")
+	spec.Retries = 1
+	// Larger than a pipe buffer, so the write breaks when the tool exits
+	input := []byte(`{"pad":"` + strings.Repeat("x", 1<<20) + `"}`)
+	out, err := RunToolWithJSON(context.Background(), spec, input, 5*time.Second)
+	if err != nil || strings.TrimSpace(string(out)) != `{"ok":true}` {
+		t.Fatalf("out=%q err=%v", out, err)
+	}
+	if got := runs(t, counter); got != "2" {
+		t.Fatalf("runs=%s, want 2", got)
+	}
+}
+
+func TestRunToolWithJSON_RetriesExhausted(t *testing.T) {
+	shortRetryBackoff(t)
+	spec, counter := flakyTool(t, 5, "connection reset")
+	spec.Retries = 1
+	if _, err := RunToolWithJSON(context.Background(), spec, []byte(`{}`), 5*time.Second); err == nil || !strings.Contains(err.Error(), "connection reset") {
+		t.Fatalf("err=%v", err)
+	}
+	if got := runs(t, counter); got != "2" {
+		t.Fatalf("runs=%s, want 2", got)
+	}
+}
+
+func TestRunToolWithJSON_RetryOnStderrPattern(t *testing.T) {
+	shortRetryBackoff(t)
+	spec, counter := flakyTool(t, 1, "invalid argument")
+	spec.Retries = 3
+	spec.RetryOn = &RetryOn{StderrPattern: `(?i)reset|unavailable`}
+	if _, err := RunToolWithJSON(context.Background(), spec, []byte(`{}`), 5*time.Second); err == nil {
+		t.Fatal("want the non-matching failure surfaced")
+	}
+	if got := runs(t, counter); got != "1" {
+		t.Fatalf("runs=%s, want 1", got)
+	}
+
+	spec, counter = flakyTool(t, 1, "503 Service Unavailable")
+	spec.Retries = 3
+	spec.RetryOn = &RetryOn{StderrPattern: `(?i)reset|unavailable`}
+	if _, err := RunToolWithJSON(context.Background(), spec, []byte(`{}`), 5*time.Second); err != nil {
+		t.Fatalf("err=%v", err)
+	}
+	if got := runs(t, counter); got != "2" {
+		t.Fatalf("runs=%s, want 2", got)
+	}
+}
+
+func TestLoadManifest_RetryValidation(t *testing.T) {
+	cases := map[string]string{
+		`"retries":11`:                                "retries must be between",
+		`"retryOn":{"timeout":true}`:                  "retryOn requires retries",
+		`"retries":1,"retryOn":{"stderrPattern":"("}`: "stderrPattern",
+	}
+	for fields, want := range cases {
+		file := filepath.Join(t.TempDir(), "tools.json")
+		writeManifest(t, file, `{"tools":[{"name":"x","command":["/bin/true"],`+fields+`}]}`)
+		if _, _, err := LoadManifest(file); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%s: err=%v, want %q", fields, err, want)
+		}
+	}
+}
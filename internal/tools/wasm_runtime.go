@@ -0,0 +1,49 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/tools/wasmrun"
+	"github.com/hyperifyio/goagent/internal/tracing"
+)
+
+// runWasmTool runs a "wasm" runtime tool in-process: Command[0] is loaded as
+// a WASI module (compiled once and cached), the JSON arguments are its stdin,
+// and its stdout is the result. Audit, metrics, and error mapping match
+// process tools; a non-zero exit reports stderr like a failed process.
+func runWasmTool(ctx context.Context, spec ToolSpec, jsonInput []byte, start time.Time, span *tracing.Span) ([]byte, error) {
+	env, passedKeys := buildToolEnvironment(spec)
+	if len(jsonInput) == 0 {
+		jsonInput = []byte("{}")
+	}
+	cfg := wasmrun.Config{Args: spec.Command, Env: env, Stdin: jsonInput}
+	if spec.Wasm != nil {
+		cfg.MemoryPages = spec.Wasm.MemoryPages
+		cfg.Fuel = spec.Wasm.Fuel
+	}
+	res, err := wasmrun.DefaultEngine.Run(ctx, spec.Command[0], cfg)
+	exitCode := int(res.ExitCode)
+	stderrText := string(res.Stderr)
+	switch {
+	case errors.Is(err, wasmrun.ErrFuelExhausted):
+		err = fmt.Errorf("tool ran out of fuel (%d calls)", cfg.Fuel)
+		exitCode = -1
+		stderrText = ""
+	case err != nil:
+		exitCode = -1
+	case res.ExitCode != 0:
+		err = fmt.Errorf("exit status %d", res.ExitCode)
+	}
+	writeAudit(spec, start, exitCode, len(res.Stdout), len(res.Stderr), passedKeys)
+	span.SetAttributes(tracing.Int("tool.exit_code", exitCode), tracing.Int("tool.stdout_bytes", len(res.Stdout)), tracing.Int("tool.stderr_bytes", len(res.Stderr)))
+
+	normErr := normalizeWaitError(ctx, err, stderrText)
+	recordToolMetrics(spec.Name, timeNow().Sub(start), len(res.Stdout), ctx.Err(), normErr)
+	if normErr != nil {
+		return nil, normErr
+	}
+	return res.Stdout, nil
+}
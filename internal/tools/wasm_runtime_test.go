@@ -0,0 +1,94 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func buildWasmTool(t *testing.T, src string) string {
+	t.Helper()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(file, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	wasm := filepath.Join(dir, "tool.wasm")
+	cmd := exec.Command("go", "build", "-o", wasm, file)
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("build wasm tool: %v: %s", err, out)
+	}
+	return wasm
+}
+
+func TestRunToolWithJSON_Wasm(t *testing.T) {
+	wasm := buildWasmTool(t, `package main
+import ("fmt"; "io"; "os")
+func depth(n int) int { if n == 0 { return 0 }; return depth(n-1) + 1 }
+func main() {
+	b, _ := io.ReadAll(os.Stdin)
+	switch os.Args[1] {
+	case "fail":
+		fmt.Fprint(os.Stderr, "{\"error\":\"bad\"}")
+		os.Exit(1)
+	case "spin":
+		for { depth(10) }
+	}
+	fmt.Printf("%s:%s", os.Args[1], b)
+}
+`)
+	man := filepath.Join(t.TempDir(), "tools.json")
+	if err := os.WriteFile(man, []byte(`{"tools":[
+		{"name":"echo","command":["`+wasm+`","echo"],"runtime":"wasm","wasm":{"memoryPages":1024}},
+		{"name":"fail","command":["`+wasm+`","fail"],"runtime":"wasm"},
+		{"name":"spin","command":["`+wasm+`","spin"],"runtime":"wasm","timeoutSec":1}
+	]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reg, _, err := LoadManifest(man)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckProgram(reg["echo"]); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	out, err := RunToolWithJSON(context.Background(), reg["echo"], []byte(`{"a":1}`), 5*time.Second)
+	if err != nil || string(out) != `echo:{"a":1}` {
+		t.Fatalf("echo: out=%q err=%v", out, err)
+	}
+	if _, err := RunToolWithJSON(context.Background(), reg["fail"], nil, 5*time.Second); err == nil || err.Error() != `{"error":"bad"}` {
+		t.Fatalf("fail: err=%v", err)
+	}
+	if _, err := RunToolWithJSON(context.Background(), reg["spin"], nil, 5*time.Second); err == nil || err.Error() != "tool timed out" {
+		t.Fatalf("spin: err=%v", err)
+	}
+	// The timeout is far above the time the fuel lasts, so only the fuel
+	// error can end the run
+	fueled := reg["spin"]
+	fueled.Wasm = &WasmLimits{Fuel: 100000}
+	fueled.TimeoutSec = 60
+	if _, err := RunToolWithJSON(context.Background(), fueled, nil, 5*time.Second); err == nil || err.Error() != "tool ran out of fuel (100000 calls)" {
+		t.Fatalf("fuel: err=%v", err)
+	}
+}
+
+func TestLoadManifest_RuntimeValidation(t *testing.T) {
+	for body, want := range map[string]string{
+		`"runtime":"jvm"`:    `unknown runtime "jvm"`,
+		`"wasm":{"fuel":10}`: `wasm limits require runtime "wasm"`,
+		`"runtime":"wasm","wasm":{"memoryPages":65537}`: "wasm.memoryPages must be at most 65536",
+	} {
+		man := filepath.Join(t.TempDir(), "tools.json")
+		if err := os.WriteFile(man, []byte(`{"tools":[{"name":"t","command":["/bin/t.wasm"],`+body+`}]}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := LoadManifest(man); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err=%v want %q", body, err, want)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package wasmrun
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// MaxMemoryPages is the largest linear memory a module may use: 65536 pages
+// of 64 KiB, the 4 GiB wasm32 address space.
+const MaxMemoryPages = 65536
+
+// DefaultMemoryPages caps linear memory when a tool sets no limit (256 MiB).
+const DefaultMemoryPages = 4096
+
+// ErrFuelExhausted is returned when a module makes more function calls than
+// its fuel allows.
+var ErrFuelExhausted = errors.New("fuel exhausted")
+
+// Config describes one run of a WASI command module.
+type Config struct {
+	// Args is the argv the module sees, including argv[0].
+	Args []string
+	// Env holds KEY=VALUE entries; nothing else of the host environment is
+	// visible.
+	Env   []string
+	Stdin []byte
+	// MemoryPages caps linear memory in 64 KiB pages; 0 uses
+	// DefaultMemoryPages.
+	MemoryPages uint32
+	// Fuel is the number of guest function calls the run may make; 0 is
+	// unlimited. Metered modules are compiled with call listeners, so fuel
+	// costs some speed.
+	Fuel uint64
+}
+
+// Result is the outcome of a run that reached the end of the module or
+// called proc_exit.
+type Result struct {
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode uint32
+}
+
+// Engine runs WASI command modules in-process with wazero. Each module file
+// is compiled once per limit set and instantiated afresh for every run, so a
+// call costs neither a process spawn nor a compile. Modules get stdin,
+// stdout, stderr, args, the given environment, clocks, and random bytes, but
+// no filesystem or network. An Engine is safe for concurrent use.
+type Engine struct {
+	// Metered modules are compiled with the fuel listener, so they keep a
+	// cache of their own: code compiled without it would skip metering.
+	cache, meteredCache wazero.CompilationCache
+
+	mu      sync.Mutex
+	modules map[moduleKey]*compiledModule
+}
+
+type moduleKey struct {
+	path    string
+	pages   uint32
+	metered bool
+}
+
+type compiledModule struct {
+	size    int64
+	modTime time.Time
+	rt      wazero.Runtime
+	mod     wazero.CompiledModule
+}
+
+// NewEngine returns an empty engine.
+func NewEngine() *Engine {
+	return &Engine{cache: wazero.NewCompilationCache(), meteredCache: wazero.NewCompilationCache(), modules: map[moduleKey]*compiledModule{}}
+}
+
+// DefaultEngine is shared by the tool runner.
+var DefaultEngine = NewEngine()
+
+// Run instantiates the module at path and runs its _start export. A non-zero
+// exit code is reported in Result, not as an error. When ctx ends first the
+// error is ctx.Err(); running out of fuel returns ErrFuelExhausted.
+func (e *Engine) Run(ctx context.Context, path string, cfg Config) (Result, error) {
+	pages := cfg.MemoryPages
+	if pages == 0 {
+		pages = DefaultMemoryPages
+	}
+	if pages > MaxMemoryPages {
+		return Result{}, fmt.Errorf("memory limit %d pages exceeds %d", pages, MaxMemoryPages)
+	}
+	cm, err := e.compiled(ctx, moduleKey{path: path, pages: pages, metered: cfg.Fuel > 0})
+	if err != nil {
+		return Result{}, err
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var meter *fuelMeter
+	if cfg.Fuel > 0 {
+		meter = &fuelMeter{cancel: cancel}
+		meter.left.Store(cfg.Fuel)
+		runCtx = context.WithValue(runCtx, fuelKey{}, meter)
+	}
+	var stdout, stderr bytes.Buffer
+	mc := wazero.NewModuleConfig().
+		// Anonymous, so concurrent runs of one module do not clash
+		WithName("").
+		WithArgs(cfg.Args...).
+		WithStdin(bytes.NewReader(cfg.Stdin)).
+		WithStdout(&stdout).
+		WithStderr(&stderr).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithSysNanosleep().
+		WithRandSource(rand.Reader)
+	for _, kv := range cfg.Env {
+		if k, v, ok := strings.Cut(kv, "="); ok && k != "" {
+			mc = mc.WithEnv(k, v)
+		}
+	}
+	mod, err := cm.rt.InstantiateModule(runCtx, cm.mod, mc)
+	if mod != nil {
+		_ = mod.Close(context.Background()) //nolint:errcheck // the run is over
+	}
+	res := Result{Stdout: stdout.Bytes(), Stderr: stderr.Bytes()}
+	if err == nil {
+		return res, nil
+	}
+	var exitErr *sys.ExitError
+	if !errors.As(err, &exitErr) {
+		return res, err
+	}
+	switch exitErr.ExitCode() {
+	case sys.ExitCodeContextCanceled, sys.ExitCodeDeadlineExceeded:
+		if meter != nil && meter.exhausted.Load() {
+			return res, ErrFuelExhausted
+		}
+		if ctx.Err() != nil {
+			return res, ctx.Err()
+		}
+		return res, err
+	}
+	res.ExitCode = exitErr.ExitCode()
+	return res, nil
+}
+
+// compiled returns the compiled module for key, recompiling when the file
+// changed since it was last compiled.
+func (e *Engine) compiled(ctx context.Context, key moduleKey) (*compiledModule, error) {
+	info, err := os.Stat(key.path)
+	if err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if cm := e.modules[key]; cm != nil {
+		if cm.size == info.Size() && cm.modTime.Equal(info.ModTime()) {
+			return cm, nil
+		}
+		_ = cm.rt.Close(ctx) //nolint:errcheck // replaced below
+		delete(e.modules, key)
+	}
+	code, err := os.ReadFile(key.path)
+	if err != nil {
+		return nil, err
+	}
+	cache := e.cache
+	if key.metered {
+		cache = e.meteredCache
+	}
+	rt := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(key.pages).
+		WithCloseOnContextDone(true).
+		WithCompilationCache(cache))
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, rt); err != nil {
+		_ = rt.Close(ctx) //nolint:errcheck
+		return nil, fmt.Errorf("wasi: %w", err)
+	}
+	compileCtx := ctx
+	if key.metered {
+		compileCtx = experimental.WithFunctionListenerFactory(ctx, fuelListenerFactory{})
+	}
+	mod, err := rt.CompileModule(compileCtx, code)
+	if err != nil {
+		_ = rt.Close(ctx) //nolint:errcheck
+		return nil, fmt.Errorf("compile %s: %w", key.path, err)
+	}
+	cm := &compiledModule{size: info.Size(), modTime: info.ModTime(), rt: rt, mod: mod}
+	e.modules[key] = cm
+	return cm, nil
+}
+
+// Close releases every compiled module.
+func (e *Engine) Close(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var errs []error
+	for key, cm := range e.modules {
+		errs = append(errs, cm.rt.Close(ctx))
+		delete(e.modules, key)
+	}
+	return errors.Join(errs...)
+}
+
+type fuelKey struct{}
+
+// fuelMeter counts down guest function calls and cancels the run when none
+// are left; the runtime then stops the module at its next call or loop.
+type fuelMeter struct {
+	left      atomic.Uint64
+	exhausted atomic.Bool
+	cancel    context.CancelFunc
+}
+
+func (m *fuelMeter) burn() {
+	for {
+		left := m.left.Load()
+		if left == 0 {
+			if !m.exhausted.Swap(true) {
+				m.cancel()
+			}
+			return
+		}
+		if m.left.CompareAndSwap(left, left-1) {
+			return
+		}
+	}
+}
+
+type fuelListenerFactory struct{}
+
+func (fuelListenerFactory) NewFunctionListener(def api.FunctionDefinition) experimental.FunctionListener {
+	if def.GoFunction() != nil {
+		// Host (WASI) functions are not guest work
+		return nil
+	}
+	return experimental.FunctionListenerFunc(func(ctx context.Context, _ api.Module, _ api.FunctionDefinition, _ []uint64, _ experimental.StackIterator) {
+		if m, ok := ctx.Value(fuelKey{}).(*fuelMeter); ok {
+			m.burn()
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package wasmrun
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// buildGuest compiles a WASI command that echoes stdin and $GREETING, exits
+// 3, spins, or allocates, depending on argv[1].
+func buildGuest(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	src := filepath.Join(dir, "guest.go")
+	if err := os.WriteFile(src, []byte(`package main
+import ("fmt"; "io"; "os")
+var sink [][]byte
+func spin(n int) int { if n == 0 { return 0 }; return spin(n-1) + 1 }
+func main() {
+	switch os.Args[1] {
+	case "echo":
+		b, _ := io.ReadAll(os.Stdin)
+		fmt.Printf("%s %s", os.Getenv("GREETING"), b)
+	case "exit":
+		fmt.Fprint(os.Stderr, "bad input")
+		os.Exit(3)
+	case "spin":
+		for { spin(100) }
+	case "alloc":
+		for i := 0; i < 64; i++ { sink = append(sink, make([]byte, 16<<20)) }
+	}
+}
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	wasm := filepath.Join(dir, "guest.wasm")
+	cmd := exec.Command("go", "build", "-o", wasm, src)
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("build guest: %v: %s", err, out)
+	}
+	return wasm
+}
+
+func TestEngineRun(t *testing.T) {
+	wasm := buildGuest(t)
+	e := NewEngine()
+	t.Cleanup(func() { _ = e.Close(context.Background()) }) //nolint:errcheck
+
+	t.Run("echo", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			res, err := e.Run(context.Background(), wasm, Config{Args: []string{"guest", "echo"}, Env: []string{"GREETING=hi"}, Stdin: []byte(`{"x":1}`)})
+			if err != nil || res.ExitCode != 0 || string(res.Stdout) != `hi {"x":1}` {
+				t.Fatalf("run %d: res=%+v err=%v", i, res, err)
+			}
+		}
+	})
+
+	t.Run("exit code", func(t *testing.T) {
+		res, err := e.Run(context.Background(), wasm, Config{Args: []string{"guest", "exit"}})
+		if err != nil || res.ExitCode != 3 || string(res.Stderr) != "bad input" {
+			t.Fatalf("res=%+v err=%v", res, err)
+		}
+	})
+
+	t.Run("memory limit", func(t *testing.T) {
+		res, err := e.Run(context.Background(), wasm, Config{Args: []string{"guest", "alloc"}, MemoryPages: 2048})
+		if err == nil && res.ExitCode == 0 {
+			t.Fatal("allocating 1 GiB under a 128 MiB limit succeeded")
+		}
+		if _, err := e.Run(context.Background(), wasm, Config{Args: []string{"guest", "alloc"}, MemoryPages: MaxMemoryPages + 1}); err == nil {
+			t.Fatal("limit above 4 GiB accepted")
+		}
+	})
+
+	t.Run("fuel", func(t *testing.T) {
+		if _, err := e.Run(context.Background(), wasm, Config{Args: []string{"guest", "spin"}, Fuel: 1_000_000}); !errors.Is(err, ErrFuelExhausted) {
+			t.Fatalf("err=%v", err)
+		}
+		res, err := e.Run(context.Background(), wasm, Config{Args: []string{"guest", "echo"}, Stdin: []byte("ok"), Fuel: 10_000_000})
+		if err != nil || !strings.HasSuffix(string(res.Stdout), "ok") {
+			t.Fatalf("metered echo: res=%+v err=%v", res, err)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		if _, err := e.Run(ctx, wasm, Config{Args: []string{"guest", "spin"}}); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("err=%v", err)
+		}
+	})
+}
@@ -0,0 +1,33 @@
+package tools
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/hyperifyio/goagent/tools/workspace"
+)
+
+// workspaceEnv holds the workspace root variables given to every tool
+// process; see SetWorkspace.
+var workspaceEnv []string
+
+// SetWorkspace sets the workspace roots passed to tool processes: root is
+// the default root of the fs_* tools (empty keeps the working directory)
+// and roots the named roots they may address as "name:path". Call it
+// before running tools.
+func SetWorkspace(root string, roots map[string]string) {
+	var env []string
+	if root != "" {
+		env = append(env, workspace.RootEnv+"="+root)
+	}
+	if len(roots) > 0 {
+		entries := make([]string, 0, len(roots))
+		for name, dir := range roots {
+			entries = append(entries, name+"="+dir)
+		}
+		sort.Strings(entries)
+		env = append(env, workspace.RootsEnv+"="+strings.Join(entries, string(os.PathListSeparator)))
+	}
+	workspaceEnv = env
+}
@@ -0,0 +1,166 @@
+// Package tracing records OpenTelemetry-compatible spans for agent steps,
+// chat requests, and tool runs, and exports them as OTLP/HTTP JSON. It uses
+// only the standard library; when no exporter is installed every call is a
+// cheap no-op and Start returns a nil span whose methods do nothing.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/clock"
+	"github.com/hyperifyio/goagent/internal/redact"
+)
+
+// Span kinds as defined by OTLP.
+const (
+	KindInternal = 1
+	KindClient   = 3
+)
+
+// Attr is a span attribute. Value is a string, int64, float64, or bool.
+type Attr struct {
+	Key   string
+	Value any
+}
+
+// String returns a string attribute.
+func String(k, v string) Attr { return Attr{Key: k, Value: v} }
+
+// Int returns an integer attribute.
+func Int(k string, v int) Attr { return Attr{Key: k, Value: int64(v)} }
+
+// Bool returns a boolean attribute.
+func Bool(k string, v bool) Attr { return Attr{Key: k, Value: v} }
+
+// Span is one timed operation. All methods are safe on a nil span.
+type Span struct {
+	mu       sync.Mutex
+	exp      *Exporter
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	kind     int
+	start    time.Time
+	end      time.Time
+	attrs    []Attr
+	errMsg   string
+	ended    bool
+}
+
+type spanKey struct{}
+
+var (
+	mu      sync.Mutex
+	current *Exporter
+)
+
+// SetExporter installs e as the destination for ended spans; nil disables
+// tracing.
+func SetExporter(e *Exporter) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = e
+}
+
+func exporter() *Exporter {
+	mu.Lock()
+	defer mu.Unlock()
+	return current
+}
+
+// Start begins an internal span named name as a child of the span in ctx (or
+// as the root of a new trace) and returns a context carrying it.
+func Start(ctx context.Context, name string, attrs ...Attr) (context.Context, *Span) {
+	return StartKind(ctx, name, KindInternal, attrs...)
+}
+
+// StartKind is Start with an explicit span kind, e.g. KindClient for
+// outgoing requests.
+func StartKind(ctx context.Context, name string, kind int, attrs ...Attr) (context.Context, *Span) {
+	e := exporter()
+	if e == nil {
+		return ctx, nil
+	}
+	s := &Span{exp: e, name: name, kind: kind, start: clock.Now(), attrs: append([]Attr(nil), attrs...)}
+	if parent := FromContext(ctx); parent != nil {
+		s.traceID = parent.traceID
+		s.parentID = parent.spanID
+	} else {
+		randomID(s.traceID[:])
+	}
+	randomID(s.spanID[:])
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// FromContext returns the span carried by ctx, or nil.
+func FromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// SetAttributes adds or replaces attributes.
+func (s *Span) SetAttributes(attrs ...Attr) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range attrs {
+		replaced := false
+		for i := range s.attrs {
+			if s.attrs[i].Key == a.Key {
+				s.attrs[i] = a
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			s.attrs = append(s.attrs, a)
+		}
+	}
+}
+
+// End finishes the span, marking it as an error when err is non-nil, and
+// hands it to the exporter. Only the first call has an effect.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = clock.Now()
+	if err != nil {
+		s.errMsg = redact.String(err.Error())
+	}
+	s.mu.Unlock()
+	s.exp.enqueue(s)
+}
+
+// TraceParent returns the W3C traceparent header value for s, or "".
+func (s *Span) TraceParent() string {
+	if s == nil {
+		return ""
+	}
+	return "00-" + hex.EncodeToString(s.traceID[:]) + "-" + hex.EncodeToString(s.spanID[:]) + "-01"
+}
+
+// randomID fills b from crypto/rand, or from the seeded generator in
+// deterministic mode so span ids repeat across runs.
+func randomID(b []byte) {
+	if clock.Deterministic() {
+		clock.Read(b)
+		return
+	}
+	if _, err := rand.Read(b); err != nil {
+		clock.Read(b)
+	}
+}
@@ -0,0 +1,134 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// collector records OTLP/JSON export requests.
+type collector struct {
+	mu      sync.Mutex
+	reqs    []otlpRequest
+	headers []http.Header
+}
+
+func (c *collector) handler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body) //nolint:errcheck
+		var req otlpRequest
+		if err := json.Unmarshal(b, &req); err != nil {
+			t.Errorf("decode export: %v", err)
+		}
+		c.mu.Lock()
+		c.reqs = append(c.reqs, req)
+		c.headers = append(c.headers, r.Header.Clone())
+		c.mu.Unlock()
+	})
+}
+
+func TestStart_NoExporterIsNoop(t *testing.T) {
+	SetExporter(nil)
+	ctx, span := Start(context.Background(), "x", String("k", "v"))
+	if span != nil || FromContext(ctx) != nil {
+		t.Fatal("expected nil span without an exporter")
+	}
+	span.SetAttributes(Int("n", 1))
+	span.End(errors.New("ignored"))
+	if span.TraceParent() != "" {
+		t.Fatal("nil span must have no traceparent")
+	}
+}
+
+func TestExporter_ExportsNestedSpans(t *testing.T) {
+	var c collector
+	srv := httptest.NewServer(c.handler(t))
+	defer srv.Close()
+	exp := NewExporter(srv.URL+"/v1/traces", map[string]string{"X-Key": "k"}, "")
+	SetExporter(exp)
+	defer SetExporter(nil)
+
+	ctx, root := Start(context.Background(), "agent.run")
+	_, child := StartKind(ctx, "chat.request", KindClient, String("gen_ai.request.model", "m"))
+	child.SetAttributes(Int("gen_ai.usage.input_tokens", 5), String("gen_ai.request.model", "m2"))
+	child.End(errors.New("boom"))
+	child.End(nil) // second End is ignored
+	root.End(nil)
+	if err := exp.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	if len(c.reqs) != 1 || c.headers[0].Get("X-Key") != "k" {
+		t.Fatalf("exports=%d headers=%v", len(c.reqs), c.headers)
+	}
+	rs := c.reqs[0].ResourceSpans[0]
+	if v := rs.Resource.Attributes[0].Value["stringValue"]; v != "agentcli" {
+		t.Fatalf("service.name=%v", v)
+	}
+	spans := rs.ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("want 2 spans, got %d", len(spans))
+	}
+	ch, rt := spans[0], spans[1]
+	if ch.Name != "chat.request" || ch.Kind != KindClient || ch.TraceID != rt.TraceID || ch.ParentSpanID != rt.SpanID || rt.ParentSpanID != "" {
+		t.Fatalf("bad nesting: child=%+v root=%+v", ch, rt)
+	}
+	if ch.Status.Code != 2 || ch.Status.Message != "boom" || rt.Status.Code != 0 {
+		t.Fatalf("status: child=%+v root=%+v", ch.Status, rt.Status)
+	}
+	if len(ch.Attributes) != 2 || ch.Attributes[0].Value["stringValue"] != "m2" || ch.Attributes[1].Value["intValue"] != "5" {
+		t.Fatalf("attributes: %+v", ch.Attributes)
+	}
+	if tp := root.TraceParent(); !strings.HasPrefix(tp, "00-"+rt.TraceID+"-"+rt.SpanID) {
+		t.Fatalf("traceparent=%s", tp)
+	}
+}
+
+func TestExporter_ReportsCollectorErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	exp := NewExporter(srv.URL, nil, "svc")
+	SetExporter(exp)
+	defer SetExporter(nil)
+	_, s := Start(context.Background(), "x")
+	s.End(nil)
+	if err := exp.Shutdown(context.Background()); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Fatalf("want collector error, got %v", err)
+	}
+}
+
+func TestNewExporterFromEnv(t *testing.T) {
+	for _, k := range []string{"OTEL_SDK_DISABLED", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_PROTOCOL", "OTEL_EXPORTER_OTLP_HEADERS", "OTEL_SERVICE_NAME"} {
+		t.Setenv(k, "")
+	}
+	if e, err := NewExporterFromEnv(); e != nil || err != nil {
+		t.Fatalf("unset: %v %v", e, err)
+	}
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://collector:4318/")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "authorization=Bearer%20x, x-tenant=a")
+	t.Setenv("OTEL_SERVICE_NAME", "batch")
+	e, err := NewExporterFromEnv()
+	if err != nil || e.endpoint != "http://collector:4318/v1/traces" || e.headers["authorization"] != "Bearer x" || e.headers["x-tenant"] != "a" || e.service != "batch" {
+		t.Fatalf("env exporter: %+v err=%v", e, err)
+	}
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "https://traces.example/custom")
+	if e, _ := NewExporterFromEnv(); e.endpoint != "https://traces.example/custom" {
+		t.Fatalf("traces endpoint not preferred: %s", e.endpoint)
+	}
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "grpc")
+	if _, err := NewExporterFromEnv(); err == nil {
+		t.Fatal("expected error for grpc protocol")
+	}
+	t.Setenv("OTEL_SDK_DISABLED", "true")
+	if e, err := NewExporterFromEnv(); e != nil || err != nil {
+		t.Fatalf("disabled: %v %v", e, err)
+	}
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	readability "github.com/go-shiori/go-readability"
+	"github.com/hyperifyio/goagent/internal/audit"
+)
+
+type input struct {
+	URL          string `json:"url"`
+	WaitSelector string `json:"wait_selector"`
+	WaitMs       int    `json:"wait_ms"`
+	TimeoutMs    int    `json:"timeout_ms"`
+	MaxChars     int    `json:"max_chars"`
+	Screenshot   bool   `json:"screenshot"`
+	FullPage     bool   `json:"full_page"`
+}
+
+type artifactRef struct {
+	Path string `json:"path"`
+	MIME string `json:"mime"`
+}
+
+type output struct {
+	URL         string       `json:"url"`
+	FinalURL    string       `json:"final_url"`
+	Title       string       `json:"title"`
+	Text        string       `json:"text"`
+	Length      int          `json:"length"`
+	Truncated   bool         `json:"truncated"`
+	NetworkIdle bool         `json:"network_idle"`
+	Artifact    *artifactRef `json:"artifact,omitempty"`
+}
+
+const (
+	defaultTimeout  = 30 * time.Second
+	maxTimeout      = 110 * time.Second
+	defaultMaxChars = 20000
+	// idleWait bounds how long to wait for network idle after the load
+	// event; pages that keep polling never reach it.
+	idleWait    = 10 * time.Second
+	maxWaitMs   = 10000
+	maxHTMLSize = 5 << 20 // 5 MiB, as readability_extract
+)
+
+func main() {
+	if err := run(); err != nil {
+		msg := strings.ReplaceAll(err.Error(), "\n", " ")
+		var he *hintedError
+		if errors.As(err, &he) && he.hint != "" {
+			fmt.Fprintf(os.Stderr, "{\"error\":%q,\"hint\":%q}\n", he.err.Error(), he.hint)
+		} else {
+			fmt.Fprintf(os.Stderr, "{\"error\":%q}\n", msg)
+		}
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	in, err := decodeInput()
+	if err != nil {
+		return err
+	}
+	target, err := checkURL(in.URL)
+	if err != nil {
+		return err
+	}
+	timeout := defaultTimeout
+	if in.TimeoutMs > 0 {
+		timeout = time.Duration(in.TimeoutMs) * time.Millisecond
+	}
+	start := time.Now()
+	out, err := render(target, in, timeout)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(out); err != nil {
+		return fmt.Errorf("encode json: %w", err)
+	}
+	_ = audit.Append(map[string]any{ //nolint:errcheck
+		"ts":           time.Now().UTC().Format(time.RFC3339Nano),
+		"tool":         "browser_render",
+		"url_host":     target.Hostname(),
+		"final_host":   hostOf(out.FinalURL),
+		"network_idle": out.NetworkIdle,
+		"length":       out.Length,
+		"screenshot":   out.Artifact != nil,
+		"ms":           time.Since(start).Milliseconds(),
+	})
+	return nil
+}
+
+func decodeInput() (input, error) {
+	var in input
+	if err := json.NewDecoder(bufio.NewReader(os.Stdin)).Decode(&in); err != nil {
+		return in, fmt.Errorf("parse json: %w", err)
+	}
+	if strings.TrimSpace(in.URL) == "" {
+		return in, errors.New("url is required")
+	}
+	if in.TimeoutMs < 0 || time.Duration(in.TimeoutMs)*time.Millisecond > maxTimeout {
+		return in, fmt.Errorf("timeout_ms must be between 1 and %d", maxTimeout.Milliseconds())
+	}
+	if in.WaitMs < 0 || in.WaitMs > maxWaitMs {
+		return in, fmt.Errorf("wait_ms must be between 0 and %d", maxWaitMs)
+	}
+	if in.MaxChars < 0 {
+		return in, errors.New("max_chars must be >= 0")
+	}
+	if in.MaxChars == 0 {
+		in.MaxChars = defaultMaxChars
+	}
+	return in, nil
+}
+
+// checkURL requires an http(s) URL whose host is on the
+// BROWSER_RENDER_ALLOW list and does not resolve to a private address.
+func checkURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return nil, errors.New("url must be an absolute http/https URL")
+	}
+	if err := checkAllowed(u); err != nil {
+		return nil, err
+	}
+	if err := ssrfGuard(u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// checkAllowed matches the host against BROWSER_RENDER_ALLOW, a comma
+// separated list of hosts where "*.example.com" also matches subdomains.
+func checkAllowed(u *url.URL) error {
+	list := strings.TrimSpace(os.Getenv("BROWSER_RENDER_ALLOW"))
+	if list == "" {
+		return hinted(errors.New("BROWSER_RENDER_ALLOW is required"), "export BROWSER_RENDER_ALLOW=example.com,*.example.org and add it to envPassthrough")
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, pat := range strings.Split(list, ",") {
+		pat = strings.ToLower(strings.TrimSpace(pat))
+		switch {
+		case pat == "":
+			continue
+		case pat == host:
+			return nil
+		case strings.HasPrefix(pat, "*.") && (host == pat[2:] || strings.HasSuffix(host, pat[1:])):
+			return nil
+		}
+	}
+	return fmt.Errorf("host %s is not in BROWSER_RENDER_ALLOW", host)
+}
+
+// idleWatcher reports the first networkIdle lifecycle event of the document
+// loaded after arm is called, ignoring the initial blank page.
+type idleWatcher struct {
+	mu      sync.Mutex
+	armed   bool
+	sawInit bool
+	idle    chan struct{}
+}
+
+func (w *idleWatcher) arm() {
+	w.mu.Lock()
+	w.armed = true
+	w.mu.Unlock()
+}
+
+func (w *idleWatcher) event(ev any) {
+	e, ok := ev.(*page.EventLifecycleEvent)
+	if !ok {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	switch {
+	case !w.armed:
+	case e.Name == "init":
+		w.sawInit = true
+	case e.Name == "networkIdle" && w.sawInit:
+		select {
+		case w.idle <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// nolint:gocyclo // Sequencing browser steps and their fallbacks; covered by tests.
+func render(target *url.URL, in input, timeout time.Duration) (output, error) {
+	opts := append(chromedp.DefaultExecAllocatorOptions[:], chromedp.WindowSize(1280, 800))
+	if p := strings.TrimSpace(os.Getenv("BROWSER_RENDER_CHROME")); p != "" {
+		opts = append(opts, chromedp.ExecPath(p))
+	}
+	// Chromium refuses to start its sandbox as root, e.g. in containers
+	if os.Geteuid() == 0 {
+		opts = append(opts, chromedp.NoSandbox)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(ctx, opts...)
+	defer cancelAlloc()
+	bctx, cancelBrowser := chromedp.NewContext(allocCtx)
+	defer cancelBrowser()
+
+	w := &idleWatcher{idle: make(chan struct{}, 1)}
+	chromedp.ListenTarget(bctx, w.event)
+	out := output{URL: target.String()}
+	err := chromedp.Run(bctx,
+		page.SetLifecycleEventsEnabled(true),
+		chromedp.ActionFunc(func(context.Context) error { w.arm(); return nil }),
+		chromedp.Navigate(target.String()),
+	)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return output{}, fmt.Errorf("timeout after %s loading %s", timeout, target)
+		}
+		if strings.Contains(err.Error(), "executable file not found") {
+			return output{}, hinted(fmt.Errorf("start browser: %w", err), "install Chromium or set BROWSER_RENDER_CHROME to its path")
+		}
+		return output{}, fmt.Errorf("navigate: %w", err)
+	}
+	select {
+	case <-w.idle:
+		out.NetworkIdle = true
+	case <-time.After(idleWait):
+	case <-ctx.Done():
+	}
+	var actions []chromedp.Action
+	if in.WaitSelector != "" {
+		actions = append(actions, chromedp.WaitVisible(in.WaitSelector, chromedp.ByQuery))
+	}
+	if in.WaitMs > 0 {
+		actions = append(actions, chromedp.Sleep(time.Duration(in.WaitMs)*time.Millisecond))
+	}
+	var html, innerText string
+	actions = append(actions,
+		chromedp.Location(&out.FinalURL),
+		chromedp.Title(&out.Title),
+		chromedp.OuterHTML("html", &html, chromedp.ByQuery),
+		chromedp.Evaluate(`document.body ? document.body.innerText : ""`, &innerText),
+	)
+	if err := chromedp.Run(bctx, actions...); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return output{}, fmt.Errorf("timeout after %s rendering %s", timeout, target)
+		}
+		return output{}, fmt.Errorf("render: %w", err)
+	}
+	// A redirect may leave the allowlist; report nothing from such a page
+	final, err := url.Parse(out.FinalURL)
+	if err != nil || checkAllowed(final) != nil {
+		return output{}, fmt.Errorf("final URL %s is not in BROWSER_RENDER_ALLOW", out.FinalURL)
+	}
+	out.Text = strings.TrimSpace(innerText)
+	if len(html) <= maxHTMLSize {
+		if art, err := readability.FromReader(strings.NewReader(html), final); err == nil && strings.TrimSpace(art.TextContent) != "" {
+			out.Text = strings.TrimSpace(art.TextContent)
+			if art.Title != "" {
+				out.Title = art.Title
+			}
+		}
+	}
+	out.Length = utf8.RuneCountInString(out.Text)
+	if out.Length > in.MaxChars {
+		out.Text = string([]rune(out.Text)[:in.MaxChars])
+		out.Truncated = true
+	}
+	if in.Screenshot {
+		var shot []byte
+		action := chromedp.CaptureScreenshot(&shot)
+		if in.FullPage {
+			action = chromedp.FullScreenshot(&shot, 100)
+		}
+		if err := chromedp.Run(bctx, action); err != nil {
+			return output{}, fmt.Errorf("screenshot: %w", err)
+		}
+		path, err := writeScreenshot(shot)
+		if err != nil {
+			return output{}, err
+		}
+		out.Artifact = &artifactRef{Path: path, MIME: "image/png"}
+	}
+	return out, nil
+}
+
+// writeScreenshot saves the PNG to a temporary file; the agent moves it into
+// its artifact store and keeps only a reference in the transcript.
+func writeScreenshot(png []byte) (string, error) {
+	f, err := os.CreateTemp("", "browser_render-*.png")
+	if err != nil {
+		return "", fmt.Errorf("screenshot: %w", err)
+	}
+	if _, err := f.Write(png); err != nil {
+		_ = f.Close() //nolint:errcheck
+		return "", fmt.Errorf("screenshot: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("screenshot: %w", err)
+	}
+	return f.Name(), nil
+}
+
+func hostOf(raw string) string {
+	if u, err := url.Parse(raw); err == nil {
+		return u.Hostname()
+	}
+	return ""
+}
+
+// ssrfGuard blocks private, loopback, and .onion hosts; BROWSER_RENDER_ALLOW_LOCAL=1
+// permits them for local testing.
+func ssrfGuard(u *url.URL) error {
+	host := u.Hostname()
+	if strings.HasSuffix(strings.ToLower(host), ".onion") {
+		return errors.New("SSRF blocked: onion domains are not allowed")
+	}
+	if os.Getenv("BROWSER_RENDER_ALLOW_LOCAL") == "1" {
+		return nil
+	}
+	ips, err := net.LookupIP(host)
+	if err != nil || len(ips) == 0 {
+		return errors.New("SSRF blocked: cannot resolve host")
+	}
+	for _, ip := range ips {
+		if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+			return errors.New("SSRF blocked: private or loopback address")
+		}
+	}
+	return nil
+}
+
+type hintedError struct {
+	err  error
+	hint string
+}
+
+func (h *hintedError) Error() string { return h.err.Error() }
+
+func hinted(err error, hint string) error { return &hintedError{err: err, hint: hint} }
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	testutil "github.com/hyperifyio/goagent/tools/testutil"
+)
+
+func runBrowserRender(t *testing.T, bin string, env []string, input any) (output, string, error) {
+	t.Helper()
+	data, err := json.Marshal(input)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	cmd := exec.Command(bin)
+	cmd.Dir = t.TempDir()
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Env = append([]string{"PATH=" + os.Getenv("PATH"), "HOME=" + os.Getenv("HOME")}, env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	runErr := cmd.Run()
+	var out output
+	if runErr == nil {
+		if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+			t.Fatalf("unmarshal stdout: %v; raw=%q", err, stdout.String())
+		}
+	}
+	return out, strings.TrimSpace(stderr.String()), runErr
+}
+
+func TestBrowserRender_RejectsBeforeLaunchingBrowser(t *testing.T) {
+	bin := testutil.BuildTool(t, "browser_render")
+	cases := []struct {
+		name  string
+		env   []string
+		input map[string]any
+		want  string
+	}{
+		{"missing url", []string{"BROWSER_RENDER_ALLOW=example.com"}, map[string]any{}, "url is required"},
+		{"bad scheme", []string{"BROWSER_RENDER_ALLOW=example.com"}, map[string]any{"url": "file:///etc/passwd"}, "absolute http/https URL"},
+		{"no allowlist", nil, map[string]any{"url": "https://example.com/"}, "BROWSER_RENDER_ALLOW is required"},
+		{"not allowed", []string{"BROWSER_RENDER_ALLOW=*.example.org,example.net"}, map[string]any{"url": "https://example.com/"}, "not in BROWSER_RENDER_ALLOW"},
+		{"private address", []string{"BROWSER_RENDER_ALLOW=127.0.0.1"}, map[string]any{"url": "http://127.0.0.1:9/"}, "SSRF blocked"},
+		{"timeout bound", []string{"BROWSER_RENDER_ALLOW=example.com"}, map[string]any{"url": "https://example.com/", "timeout_ms": 999999}, "timeout_ms"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, stderr, err := runBrowserRender(t, bin, tc.env, tc.input)
+			if err == nil || !strings.Contains(stderr, tc.want) {
+				t.Fatalf("want error containing %q, got err=%v stderr=%s", tc.want, err, stderr)
+			}
+		})
+	}
+}
+
+func TestCheckAllowed_WildcardMatchesSubdomains(t *testing.T) {
+	t.Setenv("BROWSER_RENDER_ALLOW", " *.Example.org , docs.test ")
+	for host, ok := range map[string]bool{
+		"example.org": true, "a.example.org": true, "a.b.example.org": true, "docs.test": true,
+		"badexample.org": false, "www.docs.test": false, "example.com": false,
+	} {
+		u := &url.URL{Scheme: "https", Host: host}
+		if err := checkAllowed(u); (err == nil) != ok {
+			t.Errorf("%s: allowed=%v, want %v", host, err == nil, ok)
+		}
+	}
+}
+
+// TestBrowserRender_RendersScriptContent needs Chromium; set
+// BROWSER_RENDER_CHROME or put chromium on PATH to run it.
+func TestBrowserRender_RendersScriptContent(t *testing.T) {
+	chrome := os.Getenv("BROWSER_RENDER_CHROME")
+	if chrome == "" {
+		for _, name := range []string{"chromium", "chromium-browser", "google-chrome", "headless-shell"} {
+			if p, err := exec.LookPath(name); err == nil {
+				chrome = p
+				break
+			}
+		}
+	}
+	if chrome == "" {
+		t.Skip("no Chromium found")
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><head><title>Shell</title></head><body><div id="app"></div>
+<script>document.getElementById("app").innerHTML = "<article><h1>Rendered</h1><p>Text added by script.</p></article>";</script></body></html>`)) //nolint:errcheck
+	}))
+	defer srv.Close()
+	bin := testutil.BuildTool(t, "browser_render")
+	env := []string{"BROWSER_RENDER_ALLOW=127.0.0.1", "BROWSER_RENDER_ALLOW_LOCAL=1", "BROWSER_RENDER_CHROME=" + chrome}
+	out, stderr, err := runBrowserRender(t, bin, env, map[string]any{"url": srv.URL, "screenshot": true})
+	if err != nil {
+		t.Fatalf("run: %v stderr=%s", err, stderr)
+	}
+	if !strings.Contains(out.Text, "Text added by script.") {
+		t.Fatalf("script content missing: %+v", out)
+	}
+	if out.Artifact == nil || out.Artifact.MIME != "image/png" {
+		t.Fatalf("missing screenshot artifact: %+v", out)
+	}
+	if _, err := os.Stat(out.Artifact.Path); err != nil {
+		t.Fatalf("screenshot file: %v", err)
+	}
+	_ = os.Remove(out.Artifact.Path) //nolint:errcheck
+}
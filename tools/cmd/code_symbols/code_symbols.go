@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hyperifyio/goagent/tools/workspace"
+)
+
+type symbolsInput struct {
+	Path       string   `json:"path"`
+	Language   string   `json:"language,omitempty"`
+	Kinds      []string `json:"kinds,omitempty"`
+	MaxSymbols int      `json:"maxSymbols,omitempty"`
+}
+
+// symbol is one outline entry. Lines are 1-based and inclusive; StartLine
+// covers leading decorators, EndLine the closing brace or last body line.
+type symbol struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	StartLine int    `json:"startLine"`
+	EndLine   int    `json:"endLine"`
+	Container string `json:"container,omitempty"`
+	Signature string `json:"signature"`
+	// line is where the name is declared, used for the signature.
+	line int
+}
+
+type symbolsOutput struct {
+	Language  string   `json:"language"`
+	Symbols   []symbol `json:"symbols"`
+	Truncated bool     `json:"truncated"`
+	// Partial is set when the file has syntax errors and the outline
+	// covers only what could be parsed.
+	Partial bool `json:"partial,omitempty"`
+}
+
+const (
+	defaultMaxSymbols = 500
+	maxFileBytes      = 4 << 20 // 4 MiB
+	maxSignatureChars = 200
+)
+
+// languages maps file extensions to the outliner that handles them.
+var languages = map[string]string{
+	".go":  "go",
+	".py":  "python",
+	".pyi": "python",
+	".js":  "javascript",
+	".jsx": "javascript",
+	".mjs": "javascript",
+	".cjs": "javascript",
+	".ts":  "typescript",
+	".tsx": "typescript",
+	".mts": "typescript",
+	".cts": "typescript",
+}
+
+func main() {
+	in, err := readInput(os.Stdin)
+	if err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	if _, in.Path, err = workspace.Enter(in.Path); err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	if err := validatePath(in.Path); err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	out, err := outline(in)
+	if err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(out); err != nil {
+		stderrJSON(fmt.Errorf("encode json: %w", err))
+		os.Exit(1)
+	}
+}
+
+func readInput(r io.Reader) (symbolsInput, error) {
+	var in symbolsInput
+	b, err := io.ReadAll(bufio.NewReader(r))
+	if err != nil {
+		return in, fmt.Errorf("read stdin: %w", err)
+	}
+	if err := json.Unmarshal(b, &in); err != nil {
+		return in, fmt.Errorf("parse json: %w", err)
+	}
+	if strings.TrimSpace(in.Path) == "" {
+		return in, errors.New("path is required")
+	}
+	if in.MaxSymbols < 0 {
+		return in, errors.New("maxSymbols must be >= 0")
+	}
+	return in, nil
+}
+
+func validatePath(p string) error {
+	if filepath.IsAbs(p) {
+		return fmt.Errorf("ABSOLUTE_PATH: %s", p)
+	}
+	clean := filepath.ToSlash(filepath.Clean(p))
+	if clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(clean, "/../") {
+		return fmt.Errorf("PATH_ESCAPE: %s", p)
+	}
+	return nil
+}
+
+func outline(in symbolsInput) (symbolsOutput, error) {
+	lang := strings.ToLower(strings.TrimSpace(in.Language))
+	if lang == "" {
+		lang = languages[strings.ToLower(filepath.Ext(in.Path))]
+		if lang == "" {
+			return symbolsOutput{}, fmt.Errorf("UNSUPPORTED_LANGUAGE: cannot tell the language of %s; pass language (go, python, javascript, typescript)", in.Path)
+		}
+	}
+	fi, err := os.Stat(in.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return symbolsOutput{}, fmt.Errorf("NOT_FOUND: %s", in.Path)
+		}
+		return symbolsOutput{}, err
+	}
+	if fi.Size() > maxFileBytes {
+		return symbolsOutput{}, fmt.Errorf("FILE_TOO_LARGE: %s (%d bytes) exceeds limit %d bytes", in.Path, fi.Size(), maxFileBytes)
+	}
+	src, err := os.ReadFile(in.Path)
+	if err != nil {
+		return symbolsOutput{}, err
+	}
+	out := symbolsOutput{Language: lang}
+	var syms []symbol
+	switch lang {
+	case "go":
+		syms, out.Partial = goSymbols(in.Path, src)
+	case "python":
+		syms = pythonSymbols(src)
+	case "javascript", "typescript":
+		syms = jsSymbols(src)
+	default:
+		return symbolsOutput{}, fmt.Errorf("UNSUPPORTED_LANGUAGE: %s (want go, python, javascript, or typescript)", lang)
+	}
+	lines := strings.Split(string(src), "\n")
+	keep := map[string]bool{}
+	for _, k := range in.Kinds {
+		keep[k] = true
+	}
+	max := in.MaxSymbols
+	if max == 0 {
+		max = defaultMaxSymbols
+	}
+	sort.SliceStable(syms, func(i, j int) bool { return syms[i].StartLine < syms[j].StartLine })
+	out.Symbols = []symbol{}
+	for _, s := range syms {
+		if len(keep) > 0 && !keep[s.Kind] {
+			continue
+		}
+		if len(out.Symbols) >= max {
+			out.Truncated = true
+			break
+		}
+		if s.line == 0 {
+			s.line = s.StartLine
+		}
+		if s.line-1 < len(lines) {
+			s.Signature = signature(lines[s.line-1])
+		}
+		out.Symbols = append(out.Symbols, s)
+	}
+	return out, nil
+}
+
+// signature is the trimmed declaration line without a trailing opening
+// brace, cut to maxSignatureChars.
+func signature(line string) string {
+	s := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(line), "{"))
+	if utf8.RuneCountInString(s) > maxSignatureChars {
+		s = string([]rune(s)[:maxSignatureChars]) + "…"
+	}
+	return s
+}
+
+func stderrJSON(err error) {
+	msg := err.Error()
+	msg = strings.ReplaceAll(msg, "\n", " ")
+	fmt.Fprintf(os.Stderr, "{\"error\":%q}\n", msg)
+}
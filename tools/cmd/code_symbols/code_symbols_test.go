@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	testutil "github.com/hyperifyio/goagent/tools/testutil"
+)
+
+type codeSymbolsOutput struct {
+	Language string `json:"language"`
+	Symbols  []struct {
+		Name      string `json:"name"`
+		Kind      string `json:"kind"`
+		StartLine int    `json:"startLine"`
+		EndLine   int    `json:"endLine"`
+		Container string `json:"container"`
+		Signature string `json:"signature"`
+	} `json:"symbols"`
+	Truncated bool `json:"truncated"`
+	Partial   bool `json:"partial"`
+}
+
+func runCodeSymbols(t *testing.T, bin, dir string, input any) (codeSymbolsOutput, string, int) {
+	t.Helper()
+	data, err := json.Marshal(input)
+	if err != nil {
+		t.Fatalf("marshal input: %v", err)
+	}
+	cmd := exec.Command(bin)
+	cmd.Dir = dir
+	cmd.Stdin = bytes.NewReader(data)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	code := 0
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok {
+			code = ee.ExitCode()
+		} else {
+			code = 1
+		}
+	}
+	var out codeSymbolsOutput
+	if code == 0 {
+		if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+			t.Fatalf("unmarshal stdout: %v; raw=%q", err, stdout.String())
+		}
+	}
+	return out, stderr.String(), code
+}
+
+// outlineOf writes src to name and returns "name kind start-end container"
+// per symbol.
+func outlineOf(t *testing.T, name, src string) []string {
+	t.Helper()
+	bin := testutil.BuildTool(t, "code_symbols")
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	out, stderr, code := runCodeSymbols(t, bin, dir, map[string]any{"path": name})
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	var got []string
+	for _, s := range out.Symbols {
+		got = append(got, strings.TrimSpace(fmt.Sprintf("%s %s %d-%d %s", s.Name, s.Kind, s.StartLine, s.EndLine, s.Container)))
+	}
+	return got
+}
+
+func assertOutline(t *testing.T, got, want []string) {
+	t.Helper()
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("outline mismatch\ngot:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCodeSymbols_Go(t *testing.T) {
+	src := `package demo
+
+// Store keeps items.
+type Store[T any] struct {
+	items []T
+}
+
+type (
+	Getter interface {
+		Get(key string) string
+	}
+	ID int
+)
+
+func (s *Store[T]) Add(v T) {
+	s.items = append(s.items, v)
+}
+
+func New() *Store[int] { return &Store[int]{} }
+`
+	assertOutline(t, outlineOf(t, "demo.go", src), []string{
+		"Store struct 4-6",
+		"Getter interface 9-11",
+		"Get method 10-10 Getter",
+		"ID type 12-12",
+		"Add method 15-17 Store",
+		"New function 19-19",
+	})
+}
+
+func TestCodeSymbols_Python(t *testing.T) {
+	src := `import os
+
+
+@dataclass
+class Point:
+    """Doc with
+def fake():
+    """
+    x: int
+
+    def norm(self,
+             other):
+        s = (1 +
+    2)
+        return s
+
+    # trailing comment
+
+async def fetch(url):
+    def helper():
+        pass
+    return helper
+`
+	assertOutline(t, outlineOf(t, "geo.py", src), []string{
+		"Point class 4-15",
+		"norm method 11-15 Point",
+		"fetch function 19-22",
+		"helper function 20-21 fetch",
+	})
+}
+
+func TestCodeSymbols_TypeScript(t *testing.T) {
+	src := "import { x } from \"./x\";\n" +
+		"\n" +
+		"export interface Shape {\n" +
+		"  area(): number;\n" +
+		"}\n" +
+		"\n" +
+		"export type Id = string | number;\n" +
+		"\n" +
+		"const re = /\\/\\*not a comment/g;\n" +
+		"\n" +
+		"export default class Circle implements Shape {\n" +
+		"  private r = 1;\n" +
+		"  static from(r: number): Circle {\n" +
+		"    return new Circle();\n" +
+		"  }\n" +
+		"  @memo()\n" +
+		"  area(): number {\n" +
+		"    const s = `area ${this.r * 2} {`;\n" +
+		"    return Math.PI * this.r ** 2;\n" +
+		"  }\n" +
+		"  handle = async (e: Event) => {\n" +
+		"    console.log(e);\n" +
+		"  };\n" +
+		"}\n" +
+		"\n" +
+		"export async function load<T>(url: string): Promise<{ data: T }> {\n" +
+		"  function inner() {\n" +
+		"    return 1;\n" +
+		"  }\n" +
+		"  return fetch(url);\n" +
+		"}\n" +
+		"\n" +
+		"const add = (a, b) => a + b;\n" +
+		"\n" +
+		"enum Color {\n" +
+		"  Red,\n" +
+		"}\n"
+	assertOutline(t, outlineOf(t, "shapes.ts", src), []string{
+		"Shape interface 3-5",
+		"Id type 7-7",
+		"Circle class 11-24",
+		"from method 13-15 Circle",
+		"area method 16-20 Circle",
+		"handle method 21-23 Circle",
+		"load function 26-31",
+		"inner function 27-29 load",
+		"add function 33-33",
+		"Color enum 35-37",
+	})
+}
+
+func TestCodeSymbols_KindsFilterAndLimits(t *testing.T) {
+	bin := testutil.BuildTool(t, "code_symbols")
+	dir := t.TempDir()
+	src := "function a() {}\nfunction b() {}\nclass C {\n  m() {}\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "x.js"), []byte(src), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	out, stderr, code := runCodeSymbols(t, bin, dir, map[string]any{"path": "x.js", "kinds": []string{"function"}, "maxSymbols": 1})
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if out.Language != "javascript" || len(out.Symbols) != 1 || out.Symbols[0].Name != "a" || !out.Truncated {
+		t.Fatalf("unexpected output: %+v", out)
+	}
+	if out.Symbols[0].Signature != "function a() {}" {
+		t.Fatalf("signature = %q", out.Symbols[0].Signature)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("hi"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	_, stderr, code = runCodeSymbols(t, bin, dir, map[string]any{"path": "notes.txt"})
+	if code == 0 || !strings.Contains(stderr, "UNSUPPORTED_LANGUAGE") {
+		t.Fatalf("want UNSUPPORTED_LANGUAGE, got code=%d stderr=%s", code, stderr)
+	}
+}
+
+func TestCodeSymbols_GoSyntaxErrorIsPartial(t *testing.T) {
+	bin := testutil.BuildTool(t, "code_symbols")
+	dir := t.TempDir()
+	src := "package p\n\nfunc ok() {}\n\nfunc broken( {\n"
+	if err := os.WriteFile(filepath.Join(dir, "p.go"), []byte(src), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	out, stderr, code := runCodeSymbols(t, bin, dir, map[string]any{"path": "p.go"})
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if !out.Partial || len(out.Symbols) == 0 || out.Symbols[0].Name != "ok" {
+		t.Fatalf("unexpected output: %+v", out)
+	}
+}
+
+// TestCodeSymbols_PythonNestingAndDecorators covers multi-line decorators,
+// classes nested in methods and classes, and def lines inside strings.
+func TestCodeSymbols_PythonNestingAndDecorators(t *testing.T) {
+	src := `class Outer:
+    @decorator(
+        arg=1,
+    )
+    @other
+    def method(self):
+        class Inner:
+            def deep(self):
+                return f"{x!r:>{width}} }}"
+        return Inner
+
+    class Nested:
+        x = """
+def not_a_def():
+"""
+
+        def m(self): pass
+@property
+def tail(): ...
+`
+	assertOutline(t, outlineOf(t, "nested.py", src), []string{
+		"Outer class 1-17",
+		"method method 2-10 Outer",
+		"Inner class 7-9 method",
+		"deep method 8-9 Inner",
+		"Nested class 12-17 Outer",
+		"m method 17-17 Nested",
+		"tail function 18-19",
+	})
+}
+
+// TestCodeSymbols_TypeScriptTemplatesDecoratorsAndNestedClasses covers
+// nested template literals with braces in their holes, decorators with
+// arguments on classes and members, and classes declared in methods or
+// assigned to fields.
+func TestCodeSymbols_TypeScriptTemplatesDecoratorsAndNestedClasses(t *testing.T) {
+	src := "@Component({\n" +
+		"  selector: `app-${name}`,\n" +
+		"})\n" +
+		"export class Outer {\n" +
+		"  @Input() value = 1;\n" +
+		"  @HostListener(\"click\", [\"$event\"])\n" +
+		"  onClick(e) {\n" +
+		"    const tpl = `a ${ `nested ${ {a: 1}.a }` } b`;\n" +
+		"    class Local {\n" +
+		"      run() { return `}`; }\n" +
+		"    }\n" +
+		"    return tpl;\n" +
+		"  }\n" +
+		"  static Inner = class {\n" +
+		"    go() {}\n" +
+		"  };\n" +
+		"}\n" +
+		"const f = function named() { return `${'}'}`; };\n"
+	assertOutline(t, outlineOf(t, "outer.ts", src), []string{
+		"Outer class 1-17",
+		"onClick method 6-13 Outer",
+		"Local class 9-11 onClick",
+		"run method 10-10 Local",
+		"Inner class 14-16 Outer",
+		"go method 15-15 Inner",
+		"f function 18-18",
+	})
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+)
+
+// goSymbols outlines Go source with the standard parser. On a syntax error
+// the declarations parsed so far are returned with partial set.
+func goSymbols(path string, src []byte) (syms []symbol, partial bool) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, src, parser.SkipObjectResolution)
+	if f == nil {
+		return nil, true
+	}
+	line := func(p token.Pos) int { return fset.Position(p).Line }
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			s := symbol{Name: d.Name.Name, Kind: "function", StartLine: line(d.Pos()), EndLine: line(d.End())}
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				s.Kind = "method"
+				s.Container = receiverName(d.Recv.List[0].Type)
+			}
+			syms = append(syms, s)
+		case *ast.GenDecl:
+			if d.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range d.Specs {
+				ts, ok := spec.(*ast.TypeSpec)
+				if !ok {
+					continue
+				}
+				start := line(ts.Pos())
+				if !d.Lparen.IsValid() {
+					start = line(d.Pos())
+				}
+				s := symbol{Name: ts.Name.Name, Kind: "type", StartLine: start, EndLine: line(ts.End()), line: line(ts.Pos())}
+				switch t := ts.Type.(type) {
+				case *ast.StructType:
+					s.Kind = "struct"
+				case *ast.InterfaceType:
+					s.Kind = "interface"
+					syms = append(syms, interfaceMethods(t, ts.Name.Name, line)...)
+				}
+				syms = append(syms, s)
+			}
+		}
+	}
+	return syms, err != nil
+}
+
+func interfaceMethods(t *ast.InterfaceType, container string, line func(token.Pos) int) []symbol {
+	var syms []symbol
+	for _, field := range t.Methods.List {
+		if _, ok := field.Type.(*ast.FuncType); !ok {
+			continue
+		}
+		for _, name := range field.Names {
+			syms = append(syms, symbol{Name: name.Name, Kind: "method", StartLine: line(field.Pos()), EndLine: line(field.End()), Container: container})
+		}
+	}
+	return syms
+}
+
+// receiverName returns the type name of a method receiver without pointer
+// or type parameters.
+func receiverName(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
@@ -0,0 +1,559 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// jsToken is a lexical token of JavaScript or TypeScript. Comments are
+// dropped and each string, template, regex, or number literal is a single
+// token of kind 'l'; identifiers are 'i' and punctuation 'p'.
+type jsToken struct {
+	kind byte
+	text string
+	line int
+}
+
+// jsRegexAfter are the keywords after which a slash starts a regex literal.
+var jsRegexAfter = map[string]bool{
+	"return": true, "typeof": true, "instanceof": true, "in": true, "of": true, "new": true,
+	"delete": true, "void": true, "throw": true, "case": true, "do": true, "else": true,
+	"yield": true, "await": true,
+}
+
+// jsStatementStart are keywords that begin a new declaration; an
+// unterminated statement ends before one of them on a later line.
+var jsStatementStart = map[string]bool{
+	"export": true, "import": true, "function": true, "class": true, "const": true, "let": true,
+	"var": true, "interface": true, "type": true, "enum": true, "namespace": true, "declare": true,
+}
+
+// jsModifiers may precede a class member name.
+var jsModifiers = map[string]bool{
+	"static": true, "public": true, "private": true, "protected": true, "readonly": true,
+	"abstract": true, "override": true, "declare": true, "async": true, "get": true, "set": true,
+	"accessor": true,
+}
+
+// jsLex tokenizes src. Template literal holes are lexed as code so the
+// brackets inside them balance; the ${ and } delimiters are not emitted.
+func jsLex(src []byte) []jsToken {
+	var toks []jsToken
+	s := string(src)
+	line := 1
+	// braces holds 't' for a template hole and 'b' for a code brace
+	var braces []byte
+	inTemplate := false
+	emit := func(kind byte, text string) { toks = append(toks, jsToken{kind: kind, text: text, line: line}) }
+	for i := 0; i < len(s); {
+		if inTemplate {
+			start, startLine := i, line
+			for i < len(s) {
+				if s[i] == '\\' {
+					if i+1 < len(s) && s[i+1] == '\n' {
+						line++
+					}
+					i += 2
+					continue
+				}
+				if s[i] == '\n' {
+					line++
+				}
+				if s[i] == '`' {
+					i++
+					inTemplate = false
+					break
+				}
+				if strings.HasPrefix(s[i:], "${") {
+					braces = append(braces, 't')
+					i += 2
+					inTemplate = false
+					break
+				}
+				i++
+			}
+			toks = append(toks, jsToken{kind: 'l', text: s[start:min(i, len(s))], line: startLine})
+			continue
+		}
+		c := s[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.HasPrefix(s[i:], "//"):
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(s[i:], "/*"):
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				end = len(s) - i - 2
+			}
+			line += strings.Count(s[i:i+2+end], "\n")
+			i += end + 4
+		case c == '\'' || c == '"':
+			start := i
+			for i++; i < len(s) && s[i] != c && s[i] != '\n'; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+			i++
+			emit('l', s[start:min(i, len(s))])
+		case c == '`':
+			i++
+			inTemplate = true
+		case c == '/' && jsRegexAllowed(toks):
+			start := i
+			inClass := false
+			for i++; i < len(s) && s[i] != '\n'; i++ {
+				if s[i] == '\\' {
+					i++
+					continue
+				}
+				if s[i] == '[' {
+					inClass = true
+				} else if s[i] == ']' {
+					inClass = false
+				} else if s[i] == '/' && !inClass {
+					break
+				}
+			}
+			if i < len(s) && s[i] == '/' {
+				for i++; i < len(s) && isJSIdent(rune(s[i])); i++ {
+				}
+			}
+			emit('l', s[start:min(i, len(s))])
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(s) && (isJSIdent(rune(s[i])) || s[i] == '.') {
+				i++
+			}
+			emit('l', s[start:i])
+		default:
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if isJSIdent(r) || r == '#' {
+				start := i
+				for i += size; i < len(s); i += size {
+					r, size = utf8.DecodeRuneInString(s[i:])
+					if !isJSIdent(r) {
+						break
+					}
+				}
+				emit('i', s[start:i])
+				continue
+			}
+			if strings.HasPrefix(s[i:], "=>") {
+				emit('p', "=>")
+				i += 2
+				continue
+			}
+			switch c {
+			case '{':
+				braces = append(braces, 'b')
+			case '}':
+				if n := len(braces); n > 0 {
+					top := braces[n-1]
+					braces = braces[:n-1]
+					if top == 't' {
+						i++
+						inTemplate = true
+						continue
+					}
+				}
+			}
+			emit('p', s[i:i+size])
+			i += size
+		}
+	}
+	return toks
+}
+
+func isJSIdent(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// jsRegexAllowed reports whether a slash after toks starts a regex rather
+// than a division.
+func jsRegexAllowed(toks []jsToken) bool {
+	if len(toks) == 0 {
+		return true
+	}
+	t := toks[len(toks)-1]
+	switch t.kind {
+	case 'i':
+		return jsRegexAfter[t.text]
+	case 'l':
+		return false
+	}
+	return t.text != ")" && t.text != "]" && t.text != "}"
+}
+
+// jsOutliner finds declarations in a token stream; match pairs each
+// bracket with its partner.
+type jsOutliner struct {
+	toks  []jsToken
+	match []int
+	syms  []symbol
+}
+
+// jsSymbols outlines JavaScript or TypeScript source. It tokenizes and
+// matches brackets instead of using a tree-sitter grammar: tree-sitter
+// needs cgo, and the tool binaries are built with CGO_ENABLED=0 so they
+// cross-compile. Outlining only needs declaration heads and bracket spans,
+// which the tokenizer gets right once strings, templates, regexes, and
+// comments are lexed.
+func jsSymbols(src []byte) []symbol {
+	o := &jsOutliner{toks: jsLex(src)}
+	o.match = make([]int, len(o.toks))
+	var stack []int
+	for i, t := range o.toks {
+		o.match[i] = -1
+		if t.kind != 'p' {
+			continue
+		}
+		switch t.text {
+		case "(", "[", "{":
+			stack = append(stack, i)
+		case ")", "]", "}":
+			if n := len(stack); n > 0 {
+				o.match[stack[n-1]] = i
+				o.match[i] = stack[n-1]
+				stack = stack[:n-1]
+			}
+		}
+	}
+	o.block(0, len(o.toks), "", false)
+	return o.syms
+}
+
+func (o *jsOutliner) is(i int, text string) bool {
+	return i >= 0 && i < len(o.toks) && o.toks[i].text == text && o.toks[i].kind != 'l'
+}
+
+func (o *jsOutliner) ident(i int) bool {
+	return i >= 0 && i < len(o.toks) && o.toks[i].kind == 'i'
+}
+
+// next steps over token i, skipping a whole bracketed group at once.
+func (o *jsOutliner) next(i int) int {
+	if m := o.match[i]; m > i {
+		return m + 1
+	}
+	return i + 1
+}
+
+// startsStatement reports whether token i can begin a declaration given
+// the previous token at the same level.
+func (o *jsOutliner) startsStatement(i, prev int) bool {
+	if prev < 0 {
+		return true
+	}
+	p := o.toks[prev]
+	if p.kind == 'p' && (p.text == ";" || p.text == "}" || p.text == "{") {
+		return true
+	}
+	return o.toks[i].line > p.line && !jsOperator(p)
+}
+
+func jsOperator(t jsToken) bool {
+	return t.kind == 'p' && t.text != ")" && t.text != "]" && t.text != "}"
+}
+
+// block outlines the tokens in [from, to) at one nesting level.
+// nolint:gocyclo // One case per declaration form; covered by tests.
+func (o *jsOutliner) block(from, to int, container string, inClass bool) {
+	prev := -1
+	for i := from; i < to; {
+		if !o.ident(i) && !o.is(i, "@") || !o.startsStatement(i, prev) {
+			prev, i = o.advance(i)
+			continue
+		}
+		if inClass {
+			if end := o.member(i, to, container); end > i {
+				prev, i = end-1, end
+				continue
+			}
+			prev, i = o.advance(i)
+			continue
+		}
+		start := i
+		i = o.decorators(i)
+		for o.is(i, "export") || o.is(i, "default") || o.is(i, "declare") || o.is(i, "abstract") ||
+			(o.is(i, "async") && o.is(i+1, "function")) {
+			i++
+		}
+		end := -1
+		switch {
+		case o.is(i, "function"):
+			end = o.function(start, i, to, container)
+		case o.is(i, "class"):
+			end = o.class(start, i, to, container)
+		case (o.is(i, "interface") || o.is(i, "enum")) && o.ident(i+1):
+			end = o.braced(start, i+1, to, o.toks[i].text, container)
+		case o.is(i, "const") && o.is(i+1, "enum") && o.ident(i+2):
+			end = o.braced(start, i+2, to, "enum", container)
+		case (o.is(i, "namespace") || o.is(i, "module")) && o.ident(i+1):
+			if b := o.find(i+2, to, "{"); b >= 0 {
+				o.add(o.toks[i+1].text, "namespace", start, i+1, o.match[b], container)
+				o.block(b+1, o.match[b], o.toks[i+1].text, false)
+				end = o.next(b)
+			}
+		case o.is(i, "type") && o.ident(i+1) && (o.is(i+2, "=") || o.is(i+2, "<")):
+			e := o.statementEnd(i, to)
+			o.add(o.toks[i+1].text, "type", start, i+1, e, container)
+			end = e + 1
+		case (o.is(i, "const") || o.is(i, "let") || o.is(i, "var")) && o.ident(i+1):
+			end = o.variable(start, i+1, to, "function", container)
+		}
+		if end > start {
+			prev, i = end-1, end
+			continue
+		}
+		prev, i = o.advance(start)
+	}
+}
+
+func (o *jsOutliner) advance(i int) (prev, next int) {
+	n := o.next(i)
+	return n - 1, n
+}
+
+// add records a symbol spanning tokens start..end named at token name.
+func (o *jsOutliner) add(name, kind string, start, nameTok, end int, container string) {
+	if end < start {
+		end = start
+	}
+	end = min(end, len(o.toks)-1)
+	o.syms = append(o.syms, symbol{Name: name, Kind: kind, StartLine: o.toks[start].line, EndLine: o.toks[end].line, Container: container, line: o.toks[nameTok].line})
+}
+
+// find returns the first token with text at the same level in [i, to) or
+// -1, giving up at a statement terminator.
+func (o *jsOutliner) find(i, to int, text string) int {
+	for ; i < to; i = o.next(i) {
+		if o.is(i, text) {
+			return i
+		}
+		if o.is(i, ";") || o.is(i, "}") {
+			return -1
+		}
+	}
+	return -1
+}
+
+// body finds the function body after the parameter list ending at token
+// i-1, skipping a TypeScript return type. It returns the body's opening
+// brace, or -1 and the index of a terminating ';' for a signature.
+func (o *jsOutliner) body(i, to int) (open, end int) {
+	typed := o.is(i, ":")
+	for ; i < to; i = o.next(i) {
+		switch {
+		case o.is(i, "{"):
+			p := o.toks[i-1]
+			// In a return type, a brace after an operator is an object type
+			if typed && p.kind == 'p' && p.text != ")" && p.text != "]" && p.text != "}" && p.text != ">" {
+				continue
+			}
+			return i, o.match[i]
+		case o.is(i, ";"), o.is(i, "}"), o.is(i, "=>"):
+			return -1, i - 1
+		}
+	}
+	return -1, to - 1
+}
+
+func (o *jsOutliner) function(start, i, to int, container string) int {
+	i++
+	if o.is(i, "*") {
+		i++
+	}
+	name, nameTok := "default", i-1
+	if o.ident(i) {
+		name, nameTok = o.toks[i].text, i
+	}
+	paren := o.find(i, to, "(")
+	if paren < 0 || o.match[paren] < 0 {
+		return -1
+	}
+	open, end := o.body(o.match[paren]+1, to)
+	o.add(name, "function", start, nameTok, end, container)
+	if open < 0 {
+		return end + 1
+	}
+	o.block(open+1, end, name, false)
+	return end + 1
+}
+
+func (o *jsOutliner) class(start, i, to int, container string) int {
+	name, nameTok := "default", i
+	if o.ident(i+1) && !o.is(i+1, "extends") && !o.is(i+1, "implements") {
+		name, nameTok = o.toks[i+1].text, i+1
+	}
+	open := o.find(i+1, to, "{")
+	if open < 0 || o.match[open] < 0 {
+		return -1
+	}
+	o.add(name, "class", start, nameTok, o.match[open], container)
+	o.block(open+1, o.match[open], name, true)
+	return o.match[open] + 1
+}
+
+func (o *jsOutliner) braced(start, nameTok, to int, kind, container string) int {
+	open := o.find(nameTok+1, to, "{")
+	if open < 0 || o.match[open] < 0 {
+		return -1
+	}
+	o.add(o.toks[nameTok].text, kind, start, nameTok, o.match[open], container)
+	return o.match[open] + 1
+}
+
+// variable records `name = function ...`, `name = class ...`, or an arrow
+// function assigned to the identifier at nameTok; other assignments are
+// not symbols.
+func (o *jsOutliner) variable(start, nameTok, to int, kind, container string) int {
+	i := nameTok + 1
+	if o.is(i, "?") || o.is(i, "!") {
+		i++
+	}
+	if o.is(i, ":") {
+		for i < to && !o.is(i, "=") && !o.is(i, ";") {
+			i = o.next(i)
+		}
+	}
+	if !o.is(i, "=") {
+		return -1
+	}
+	i++
+	if o.is(i, "async") {
+		i++
+	}
+	name := o.toks[nameTok].text
+	switch {
+	case o.is(i, "class"):
+		// A class expression takes the name it is assigned to
+		open := o.find(i+1, to, "{")
+		if open < 0 || o.match[open] < 0 {
+			return -1
+		}
+		o.add(name, "class", start, nameTok, o.match[open], container)
+		o.block(open+1, o.match[open], name, true)
+		return o.statementEnd(o.match[open], to) + 1
+	case o.is(i, "function"):
+		paren := o.find(i, to, "(")
+		if paren < 0 || o.match[paren] < 0 {
+			return -1
+		}
+		open, end := o.body(o.match[paren]+1, to)
+		o.add(name, kind, start, nameTok, end, container)
+		if open >= 0 {
+			o.block(open+1, end, name, false)
+		}
+		return end + 1
+	case o.is(i, "<") || o.is(i, "("):
+		paren := o.find(i, to, "(")
+		if paren < 0 || o.match[paren] < 0 {
+			return -1
+		}
+		i = o.match[paren] + 1
+		if o.is(i, ":") {
+			for i < to && !o.is(i, "=>") && !o.is(i, ";") {
+				i = o.next(i)
+			}
+		}
+	case o.ident(i) && o.is(i+1, "=>"):
+		i++
+	default:
+		return -1
+	}
+	if !o.is(i, "=>") {
+		return -1
+	}
+	if o.is(i+1, "{") && o.match[i+1] > 0 {
+		end := o.match[i+1]
+		o.add(name, kind, start, nameTok, end, container)
+		o.block(i+2, end, name, false)
+		return end + 1
+	}
+	end := o.statementEnd(i, to)
+	o.add(name, kind, start, nameTok, end, container)
+	return end + 1
+}
+
+// member records a class member starting at token i and returns the index
+// after it, or -1 when i does not start a method.
+func (o *jsOutliner) member(i, to int, class string) int {
+	start := i
+	i = o.decorators(i)
+	for o.ident(i) && jsModifiers[o.toks[i].text] && (o.ident(i+1) || o.is(i+1, "*") || o.is(i+1, "[")) {
+		i++
+	}
+	if o.is(i, "*") {
+		i++
+	}
+	nameTok := i
+	name := ""
+	switch {
+	case o.ident(i):
+		name = o.toks[i].text
+	case o.is(i, "[") && o.match[i] > i:
+		name = "[computed]"
+		i = o.match[i]
+	default:
+		return -1
+	}
+	i++
+	if o.is(i, "?") || o.is(i, "!") {
+		i++
+	}
+	if o.is(i, "<") {
+		i = o.find(i, to, "(")
+	}
+	if o.is(i, "(") && o.match[i] > i {
+		open, end := o.body(o.match[i]+1, to)
+		o.add(name, "method", start, nameTok, end, class)
+		if open >= 0 {
+			o.block(open+1, end, name, false)
+		}
+		return end + 1
+	}
+	if o.is(i, "=") || o.is(i, ":") {
+		return o.variable(start, nameTok, to, "method", class)
+	}
+	return -1
+}
+
+// decorators skips the decorators starting at token i, such as
+// @Component({...}) or @a.b, and returns the token after them.
+func (o *jsOutliner) decorators(i int) int {
+	for o.is(i, "@") && o.ident(i+1) {
+		i += 2
+		for o.is(i, ".") && o.ident(i+1) {
+			i += 2
+		}
+		if o.is(i, "(") {
+			i = o.next(i)
+		}
+	}
+	return i
+}
+
+// statementEnd returns the last token of the statement containing token i:
+// its ';', or the token before a declaration keyword on a later line.
+func (o *jsOutliner) statementEnd(i, to int) int {
+	last := i
+	for j := o.next(i); j < to; j = o.next(j) {
+		t := o.toks[j]
+		if o.is(j, ";") {
+			return j
+		}
+		if t.kind == 'i' && jsStatementStart[t.text] && t.line > o.toks[last].line && !jsOperator(o.toks[last]) {
+			return last
+		}
+		last = o.next(j) - 1
+	}
+	return last
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+var pyDefRe = regexp.MustCompile(`^(?:async\s+)?(def|class)\s+([A-Za-z_]\w*)`)
+
+// pyScope is an open def or class; it ends before the next logical line
+// indented at or left of it.
+type pyScope struct {
+	indent int
+	idx    int
+}
+
+// pythonSymbols outlines Python source by indentation. Strings, bracketed
+// continuations, and backslash continuations are tracked so only the first
+// physical line of a logical line can open or close a scope. Indentation
+// bounds every Python scope, so this needs no grammar; a tree-sitter one
+// would also bring in cgo, which the tool builds avoid.
+func pythonSymbols(src []byte) []symbol {
+	var syms []symbol
+	var stack []pyScope
+	var quote string // open string delimiter carried across lines
+	depth, decorator, lastCode := 0, 0, 0
+	continued := false
+	closeTo := func(indent int) {
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			syms[stack[len(stack)-1].idx].EndLine = lastCode
+			stack = stack[:len(stack)-1]
+		}
+	}
+	for i, raw := range strings.Split(string(src), "\n") {
+		n := i + 1
+		text := strings.TrimRight(raw, "\r")
+		stripped := strings.TrimSpace(text)
+		logical := quote == "" && depth == 0 && !continued
+		if logical {
+			if stripped == "" || strings.HasPrefix(stripped, "#") {
+				continue
+			}
+			indent := pyIndent(text)
+			closeTo(indent)
+			switch m := pyDefRe.FindStringSubmatch(stripped); {
+			case strings.HasPrefix(stripped, "@"):
+				if decorator == 0 {
+					decorator = n
+				}
+			case m != nil:
+				s := symbol{Name: m[2], Kind: "function", StartLine: n, EndLine: n, line: n}
+				if m[1] == "class" {
+					s.Kind = "class"
+				}
+				if decorator > 0 {
+					s.StartLine = decorator
+				}
+				if len(stack) > 0 {
+					parent := syms[stack[len(stack)-1].idx]
+					s.Container = parent.Name
+					if parent.Kind == "class" && s.Kind == "function" {
+						s.Kind = "method"
+					}
+				}
+				stack = append(stack, pyScope{indent: indent, idx: len(syms)})
+				syms = append(syms, s)
+				decorator = 0
+			default:
+				decorator = 0
+			}
+		}
+		if stripped != "" {
+			lastCode = n
+		}
+		quote, depth, continued = pyScan(text, quote, depth)
+	}
+	closeTo(0)
+	return syms
+}
+
+// pyIndent measures leading whitespace with tabs at multiples of eight.
+func pyIndent(line string) int {
+	col := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			col++
+		case '\t':
+			col += 8 - col%8
+		default:
+			return col
+		}
+	}
+	return col
+}
+
+// pyScan advances the string and bracket state over one physical line and
+// reports whether the line ends with a backslash continuation.
+func pyScan(line, quote string, depth int) (string, int, bool) {
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if quote != "" {
+			switch {
+			case c == '\\':
+				i++
+			case strings.HasPrefix(line[i:], quote):
+				i += len(quote) - 1
+				quote = ""
+			}
+			continue
+		}
+		switch c {
+		case '#':
+			return "", depth, false
+		case '\'', '"':
+			q := string(c)
+			if strings.HasPrefix(line[i:], q+q+q) {
+				q = q + q + q
+			}
+			quote = q
+			i += len(q) - 1
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			if depth > 0 {
+				depth--
+			}
+		}
+	}
+	// A single-quoted string cannot span lines
+	if len(quote) == 1 {
+		quote = ""
+	}
+	return quote, depth, quote == "" && strings.HasSuffix(line, "\\")
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hyperifyio/goagent/tools/workspace"
+)
+
+type statsInput struct {
+	Path      string `json:"path"`
+	Delimiter string `json:"delimiter,omitempty"`
+	// HasHeader defaults to true.
+	HasHeader  *bool `json:"hasHeader,omitempty"`
+	SampleRows int   `json:"sampleRows,omitempty"`
+	MaxRows    int   `json:"maxRows,omitempty"`
+}
+
+type columnStats struct {
+	Name string `json:"name"`
+	// Type is integer, number, boolean, string, or empty when every value
+	// is null.
+	Type      string   `json:"type"`
+	NullCount int      `json:"nullCount"`
+	Min       *float64 `json:"min,omitempty"`
+	Max       *float64 `json:"max,omitempty"`
+	Mean      *float64 `json:"mean,omitempty"`
+	Stddev    *float64 `json:"stddev,omitempty"`
+	// MaxLength is the longest value in characters.
+	MaxLength int `json:"maxLength"`
+
+	ints, floats, bools, others int
+	n                          int
+	mean, m2                   float64
+}
+
+type statsOutput struct {
+	Delimiter string         `json:"delimiter"`
+	Columns   []*columnStats `json:"columns"`
+	RowCount  int            `json:"rowCount"`
+	Head      [][]string     `json:"head"`
+	Tail      [][]string     `json:"tail"`
+	Truncated bool           `json:"truncated"`
+}
+
+const (
+	defaultSampleRows = 5
+	maxSampleRows     = 100
+	defaultMaxRows    = 1000000
+	// maxCellChars bounds each sampled value so one huge cell cannot flood
+	// the output.
+	maxCellChars = 200
+)
+
+// nullValues are the spellings counted as missing values.
+var nullValues = map[string]bool{"": true, "na": true, "n/a": true, "null": true, "none": true, "nan": true}
+
+func main() {
+	in, err := readInput(os.Stdin)
+	if err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	if _, in.Path, err = workspace.Enter(in.Path); err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	if err := validatePath(in.Path); err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	out, err := summarize(in)
+	if err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(out); err != nil {
+		stderrJSON(fmt.Errorf("encode json: %w", err))
+		os.Exit(1)
+	}
+}
+
+func readInput(r io.Reader) (statsInput, error) {
+	var in statsInput
+	b, err := io.ReadAll(bufio.NewReader(r))
+	if err != nil {
+		return in, fmt.Errorf("read stdin: %w", err)
+	}
+	if err := json.Unmarshal(b, &in); err != nil {
+		return in, fmt.Errorf("parse json: %w", err)
+	}
+	if strings.TrimSpace(in.Path) == "" {
+		return in, errors.New("path is required")
+	}
+	if in.Delimiter != "" && (utf8.RuneCountInString(in.Delimiter) != 1 || in.Delimiter == "\"" || in.Delimiter == "\n") {
+		return in, fmt.Errorf("delimiter must be a single character other than quote or newline")
+	}
+	if in.SampleRows < 0 || in.SampleRows > maxSampleRows {
+		return in, fmt.Errorf("sampleRows must be between 0 and %d", maxSampleRows)
+	}
+	if in.MaxRows < 0 {
+		return in, errors.New("maxRows must be >= 0")
+	}
+	return in, nil
+}
+
+func validatePath(p string) error {
+	if filepath.IsAbs(p) {
+		return fmt.Errorf("ABSOLUTE_PATH: %s", p)
+	}
+	clean := filepath.ToSlash(filepath.Clean(p))
+	if clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(clean, "/../") {
+		return fmt.Errorf("PATH_ESCAPE: %s", p)
+	}
+	return nil
+}
+
+// nolint:gocyclo // Reading, sampling, and per-column accounting in one pass; covered by tests.
+func summarize(in statsInput) (statsOutput, error) {
+	f, err := os.Open(in.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return statsOutput{}, fmt.Errorf("NOT_FOUND: %s", in.Path)
+		}
+		return statsOutput{}, err
+	}
+	defer f.Close() //nolint:errcheck // read-only
+	br := bufio.NewReaderSize(f, 64*1024)
+	delim := []rune(in.Delimiter)
+	if len(delim) == 0 {
+		head, _ := br.Peek(64 * 1024) //nolint:errcheck // a short file is fine
+		delim = []rune{sniffDelimiter(in.Path, head)}
+	}
+	r := csv.NewReader(br)
+	r.Comma = delim[0]
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	r.ReuseRecord = true
+
+	samples := in.SampleRows
+	if samples == 0 {
+		samples = defaultSampleRows
+	}
+	maxRows := in.MaxRows
+	if maxRows == 0 {
+		maxRows = defaultMaxRows
+	}
+	out := statsOutput{Delimiter: string(delim), Columns: []*columnStats{}, Head: [][]string{}, Tail: [][]string{}}
+	header := in.HasHeader == nil || *in.HasHeader
+	var tail [][]string
+	for {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return statsOutput{}, fmt.Errorf("PARSE_ERROR: %s: %w", in.Path, err)
+		}
+		if header {
+			for _, name := range rec {
+				out.Columns = append(out.Columns, &columnStats{Name: strings.TrimPrefix(name, "\ufeff")})
+			}
+			header = false
+			continue
+		}
+		if out.RowCount >= maxRows {
+			out.Truncated = true
+			break
+		}
+		out.RowCount++
+		for len(out.Columns) < len(rec) {
+			out.Columns = append(out.Columns, &columnStats{Name: fmt.Sprintf("column_%d", len(out.Columns)+1)})
+		}
+		for i, c := range out.Columns {
+			v := ""
+			if i < len(rec) {
+				v = rec[i]
+			}
+			c.add(v)
+		}
+		row := sampleRow(rec)
+		if len(out.Head) < samples {
+			out.Head = append(out.Head, row)
+			continue
+		}
+		tail = append(tail, row)
+		if len(tail) > samples {
+			tail = tail[1:]
+		}
+	}
+	if tail != nil {
+		out.Tail = tail
+	}
+	for _, c := range out.Columns {
+		c.finish()
+	}
+	return out, nil
+}
+
+// sniffDelimiter picks tab for .tsv files and otherwise the candidate that
+// occurs most often in the first line.
+func sniffDelimiter(path string, head []byte) rune {
+	if strings.EqualFold(filepath.Ext(path), ".tsv") {
+		return '\t'
+	}
+	if i := bytes.IndexByte(head, '\n'); i >= 0 {
+		head = head[:i]
+	}
+	best, bestCount := ',', 0
+	for _, d := range []rune{',', '\t', ';', '|'} {
+		if n := bytes.Count(head, []byte(string(d))); n > bestCount {
+			best, bestCount = d, n
+		}
+	}
+	return best
+}
+
+func sampleRow(rec []string) []string {
+	row := make([]string, len(rec))
+	for i, v := range rec {
+		if utf8.RuneCountInString(v) > maxCellChars {
+			v = string([]rune(v)[:maxCellChars]) + "…"
+		}
+		row[i] = v
+	}
+	return row
+}
+
+// add accounts one value; numeric values update a running mean and
+// variance (Welford) so memory stays constant.
+func (c *columnStats) add(v string) {
+	if n := utf8.RuneCountInString(v); n > c.MaxLength {
+		c.MaxLength = n
+	}
+	t := strings.TrimSpace(v)
+	if nullValues[strings.ToLower(t)] {
+		c.NullCount++
+		return
+	}
+	if _, err := strconv.ParseInt(t, 10, 64); err == nil {
+		c.ints++
+	} else if x, err := strconv.ParseFloat(t, 64); err == nil && !math.IsInf(x, 0) {
+		c.floats++
+	} else {
+		if _, err := strconv.ParseBool(t); err == nil {
+			c.bools++
+		} else {
+			c.others++
+		}
+		return
+	}
+	x, _ := strconv.ParseFloat(t, 64) //nolint:errcheck // parsed above
+	if c.n == 0 || x < *c.Min {
+		c.Min = &x
+	}
+	if c.n == 0 || x > *c.Max {
+		c.Max = &x
+	}
+	c.n++
+	d := x - c.mean
+	c.mean += d / float64(c.n)
+	c.m2 += d * (x - c.mean)
+}
+
+// finish derives the column type; numeric statistics are reported only for
+// integer and number columns.
+func (c *columnStats) finish() {
+	switch {
+	case c.others > 0 || (c.bools > 0 && c.ints+c.floats > 0):
+		c.Type = "string"
+	case c.bools > 0:
+		c.Type = "boolean"
+	case c.floats > 0:
+		c.Type = "number"
+	case c.ints > 0:
+		c.Type = "integer"
+	default:
+		c.Type = "empty"
+	}
+	if c.Type != "integer" && c.Type != "number" {
+		c.Min, c.Max = nil, nil
+		return
+	}
+	mean := c.mean
+	c.Mean = &mean
+	stddev := 0.0
+	if c.n > 1 {
+		stddev = math.Sqrt(c.m2 / float64(c.n-1))
+	}
+	c.Stddev = &stddev
+}
+
+func stderrJSON(err error) {
+	msg := err.Error()
+	msg = strings.ReplaceAll(msg, "\n", " ")
+	fmt.Fprintf(os.Stderr, "{\"error\":%q}\n", msg)
+}
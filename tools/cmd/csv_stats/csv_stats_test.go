@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	testutil "github.com/hyperifyio/goagent/tools/testutil"
+)
+
+type csvColumn struct {
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	NullCount int      `json:"nullCount"`
+	Min       *float64 `json:"min"`
+	Max       *float64 `json:"max"`
+	Mean      *float64 `json:"mean"`
+	Stddev    *float64 `json:"stddev"`
+	MaxLength int      `json:"maxLength"`
+}
+
+type csvStatsOutput struct {
+	Delimiter string      `json:"delimiter"`
+	Columns   []csvColumn `json:"columns"`
+	RowCount  int         `json:"rowCount"`
+	Head      [][]string  `json:"head"`
+	Tail      [][]string  `json:"tail"`
+	Truncated bool        `json:"truncated"`
+}
+
+func runCSVStats(t *testing.T, bin, dir string, input any) (csvStatsOutput, string, int) {
+	t.Helper()
+	data, err := json.Marshal(input)
+	if err != nil {
+		t.Fatalf("marshal input: %v", err)
+	}
+	cmd := exec.Command(bin)
+	cmd.Dir = dir
+	cmd.Stdin = bytes.NewReader(data)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	code := 0
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok {
+			code = ee.ExitCode()
+		} else {
+			code = 1
+		}
+	}
+	var out csvStatsOutput
+	if code == 0 {
+		if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+			t.Fatalf("unmarshal stdout: %v; raw=%q", err, stdout.String())
+		}
+	}
+	return out, stderr.String(), code
+}
+
+func TestCSVStats_TypesAndNumericStats(t *testing.T) {
+	bin := testutil.BuildTool(t, "csv_stats")
+	dir := t.TempDir()
+	data := "id,price,active,name,note\n1,2.5,true,apple,\n2,3.5,false,\"pear, green\",NA\n3,,true,plum,\n4,6,false,fig,\n"
+	if err := os.WriteFile(filepath.Join(dir, "fruit.csv"), []byte(data), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	out, stderr, code := runCSVStats(t, bin, dir, map[string]any{"path": "fruit.csv", "sampleRows": 1})
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if out.Delimiter != "," || out.RowCount != 4 || out.Truncated {
+		t.Fatalf("unexpected summary: %+v", out)
+	}
+	want := map[string]string{"id": "integer", "price": "number", "active": "boolean", "name": "string", "note": "empty"}
+	for _, c := range out.Columns {
+		if c.Type != want[c.Name] {
+			t.Fatalf("column %s type %s, want %s", c.Name, c.Type, want[c.Name])
+		}
+	}
+	price := out.Columns[1]
+	if price.NullCount != 1 || price.Min == nil || *price.Min != 2.5 || *price.Max != 6 || *price.Mean != 4 {
+		t.Fatalf("unexpected price stats: %+v", price)
+	}
+	if id := out.Columns[0]; id.Stddev == nil || *id.Stddev < 1.29 || *id.Stddev > 1.30 {
+		t.Fatalf("unexpected id stddev: %+v", id)
+	}
+	if out.Columns[4].NullCount != 4 || out.Columns[2].Min != nil {
+		t.Fatalf("unexpected null or boolean stats: %+v", out.Columns)
+	}
+	if len(out.Head) != 1 || out.Head[0][0] != "1" || len(out.Tail) != 1 || out.Tail[0][3] != "fig" {
+		t.Fatalf("unexpected samples: head=%v tail=%v", out.Head, out.Tail)
+	}
+}
+
+func TestCSVStats_TSVWithoutHeaderAndMaxRows(t *testing.T) {
+	bin := testutil.BuildTool(t, "csv_stats")
+	dir := t.TempDir()
+	var b strings.Builder
+	for i := 0; i < 50; i++ {
+		b.WriteString("a\t1\n")
+	}
+	if err := os.WriteFile(filepath.Join(dir, "rows.tsv"), []byte(b.String()), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	out, stderr, code := runCSVStats(t, bin, dir, map[string]any{"path": "rows.tsv", "hasHeader": false, "maxRows": 10})
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if out.Delimiter != "\t" || out.RowCount != 10 || !out.Truncated {
+		t.Fatalf("unexpected summary: %+v", out)
+	}
+	if len(out.Columns) != 2 || out.Columns[0].Name != "column_1" || out.Columns[1].Type != "integer" {
+		t.Fatalf("unexpected columns: %+v", out.Columns)
+	}
+	if len(out.Head) != 5 || len(out.Tail) != 5 {
+		t.Fatalf("unexpected samples: head=%d tail=%d", len(out.Head), len(out.Tail))
+	}
+}
+
+func TestCSVStats_SniffsSemicolonAndCapsCells(t *testing.T) {
+	bin := testutil.BuildTool(t, "csv_stats")
+	dir := t.TempDir()
+	data := "a;b\n" + strings.Repeat("x", 500) + ";2\n"
+	if err := os.WriteFile(filepath.Join(dir, "semi.csv"), []byte(data), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	out, stderr, code := runCSVStats(t, bin, dir, map[string]any{"path": "semi.csv"})
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if out.Delimiter != ";" || len(out.Columns) != 2 || out.Columns[0].MaxLength != 500 {
+		t.Fatalf("unexpected summary: %+v", out)
+	}
+	if n := len([]rune(out.Head[0][0])); n != 201 {
+		t.Fatalf("sample cell has %d characters, want 201", n)
+	}
+}
+
+func TestCSVStats_Errors(t *testing.T) {
+	bin := testutil.BuildTool(t, "csv_stats")
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		input map[string]any
+		want  string
+	}{
+		{map[string]any{"path": "/tmp/x.csv"}, "ABSOLUTE_PATH"},
+		{map[string]any{"path": "../x.csv"}, "PATH_ESCAPE"},
+		{map[string]any{"path": "missing.csv"}, "NOT_FOUND"},
+		{map[string]any{"path": "x.csv", "delimiter": ",,"}, "delimiter"},
+	} {
+		_, stderr, code := runCSVStats(t, bin, dir, tc.input)
+		if code == 0 || !strings.Contains(stderr, tc.want) {
+			t.Fatalf("%v: want %s, got code=%d stderr=%s", tc.input, tc.want, code, stderr)
+		}
+	}
+}
@@ -20,15 +20,35 @@ type execInput struct {
 	Env        map[string]string `json:"env,omitempty"`
 	Stdin      string            `json:"stdin,omitempty"`
 	TimeoutSec int               `json:"timeoutSec,omitempty"`
+	// MaxOutputBytes caps how much of each stream is returned inline;
+	// defaults to defaultMaxOutputBytes
+	MaxOutputBytes int `json:"maxOutputBytes,omitempty"`
 }
 
 type execOutput struct {
-	ExitCode   int    `json:"exitCode"`
-	Stdout     string `json:"stdout"`
-	Stderr     string `json:"stderr"`
+	ExitCode        int    `json:"exitCode"`
+	Stdout          string `json:"stdout"`
+	Stderr          string `json:"stderr"`
+	StdoutTruncated bool   `json:"stdoutTruncated"`
+	StderrTruncated bool   `json:"stderrTruncated"`
+	// Full output of a truncated stream, kept in a temp file
+	StdoutFile string `json:"stdoutFile,omitempty"`
+	StderrFile string `json:"stderrFile,omitempty"`
+	// Killed is true when timeoutSec fired and the command's process group
+	// was killed with Signal
+	Killed     bool   `json:"killed"`
+	Signal     string `json:"signal,omitempty"`
 	DurationMs int64  `json:"durationMs"`
 }
 
+// pipeWaitDelay bounds how long output is still read after the command
+// exits or is killed, for processes that escaped its group.
+const pipeWaitDelay = 2 * time.Second
+
+// defaultMaxOutputBytes is the per-stream inline cap when maxOutputBytes is
+// omitted.
+const defaultMaxOutputBytes = 64 * 1024
+
 func main() {
 	in, err := readInput(os.Stdin)
 	if err != nil {
@@ -37,9 +57,14 @@ func main() {
 		fmt.Fprintf(os.Stderr, "{\"error\":%q}\n", msg)
 		os.Exit(1)
 	}
+	if perr := checkPolicy(in); perr != nil {
+		// Refused by the operator policy: same contract, with policy details
+		b, _ := json.Marshal(perr) //nolint:errcheck // strings always encode
+		fmt.Fprintln(os.Stderr, string(b))
+		os.Exit(1)
+	}
 
-	stdout, stderr, exitCode, dur := runCommand(in)
-	writeOutput(execOutput{ExitCode: exitCode, Stdout: stdout, Stderr: stderr, DurationMs: dur})
+	writeOutput(runCommand(in))
 }
 
 func readInput(r io.Reader) (execInput, error) {
@@ -55,10 +80,16 @@ func readInput(r io.Reader) (execInput, error) {
 	if strings.TrimSpace(in.Cmd) == "" {
 		return in, fmt.Errorf("cmd is required")
 	}
+	if in.MaxOutputBytes < 0 {
+		return in, fmt.Errorf("maxOutputBytes must be positive")
+	}
+	if in.MaxOutputBytes == 0 {
+		in.MaxOutputBytes = defaultMaxOutputBytes
+	}
 	return in, nil
 }
 
-func runCommand(in execInput) (stdoutStr, stderrStr string, exitCode int, durationMs int64) {
+func runCommand(in execInput) (out execOutput) {
 	start := time.Now()
 	ctx := context.Background()
 	var cancel context.CancelFunc
@@ -95,16 +126,29 @@ func runCommand(in execInput) (stdoutStr, stderrStr string, exitCode int, durati
 	if in.Stdin != "" {
 		cmd.Stdin = strings.NewReader(in.Stdin)
 	}
-	var stdoutBuf, stderrBuf strings.Builder
-	cmd.Stdout = &stdoutBuf
-	cmd.Stderr = &stderrBuf
+	stdoutBuf := &cappedWriter{max: in.MaxOutputBytes, pattern: "exec-stdout-*"}
+	stderrBuf := &cappedWriter{max: in.MaxOutputBytes, pattern: "exec-stderr-*"}
+	defer stdoutBuf.close()
+	defer stderrBuf.close()
+	cmd.Stdout = stdoutBuf
+	cmd.Stderr = stderrBuf
+	// On timeout kill the whole process group, not just the direct child, so
+	// grandchildren do not outlive the call or hold the output pipes open
+	startInProcessGroup(cmd)
+	cmd.Cancel = func() error { return killProcessGroup(cmd) }
+	cmd.WaitDelay = pipeWaitDelay
 
-	exitCode = 0
 	err := cmd.Run()
-	durationMs = time.Since(start).Milliseconds()
+	if ctx.Err() == context.DeadlineExceeded {
+		// The direct child may have exited first, leaving its group running
+		_ = killProcessGroup(cmd) //nolint:errcheck // best-effort; already exiting
+		waitProcessGroup(cmd, pipeWaitDelay)
+		out.Killed, out.Signal = true, killSignal
+	}
+	out.DurationMs = time.Since(start).Milliseconds()
 
-	stdoutStr = stdoutBuf.String()
-	stderrStr = stderrBuf.String()
+	out.Stdout, out.StdoutTruncated, out.StdoutFile = stdoutBuf.result()
+	out.Stderr, out.StderrTruncated, out.StderrFile = stderrBuf.result()
 
 	if err == nil {
 		return
@@ -113,31 +157,81 @@ func runCommand(in execInput) (stdoutStr, stderrStr string, exitCode int, durati
 	if ctxErr := ctx.Err(); ctxErr == context.DeadlineExceeded {
 		// Timed out
 		if ee, ok := err.(*exec.ExitError); ok {
-			exitCode = ee.ExitCode()
+			out.ExitCode = ee.ExitCode()
 		} else {
-			exitCode = 1
+			out.ExitCode = 1
 		}
-		if !strings.Contains(strings.ToLower(stderrStr), "timeout") {
-			if len(stderrStr) > 0 && !strings.HasSuffix(stderrStr, "\n") {
-				stderrStr += "\n"
+		if !strings.Contains(strings.ToLower(out.Stderr), "timeout") {
+			if len(out.Stderr) > 0 && !strings.HasSuffix(out.Stderr, "\n") {
+				out.Stderr += "\n"
 			}
-			stderrStr += "timeout"
+			out.Stderr += "timeout"
 		}
 		return
 	}
 	if ee, ok := err.(*exec.ExitError); ok {
-		exitCode = ee.ExitCode()
+		out.ExitCode = ee.ExitCode()
 	} else {
-		exitCode = 1
+		out.ExitCode = 1
 	}
 	return
 }
 
+// cappedWriter keeps the first max bytes of a stream in memory. Past the cap
+// it spills the whole stream to a temp file instead of growing the buffer, so
+// huge outputs stay bounded in memory but remain available on disk.
+type cappedWriter struct {
+	max       int
+	pattern   string
+	head      []byte
+	truncated bool
+	file      *os.File
+	fileErr   error
+}
+
+func (w *cappedWriter) Write(p []byte) (int, error) {
+	if !w.truncated {
+		if len(w.head)+len(p) <= w.max {
+			w.head = append(w.head, p...)
+			return len(p), nil
+		}
+		w.truncated = true
+		if w.file, w.fileErr = os.CreateTemp("", w.pattern); w.fileErr == nil {
+			_, w.fileErr = w.file.Write(w.head)
+		}
+		w.head = append(w.head, p[:w.max-len(w.head)]...)
+	}
+	if w.fileErr == nil {
+		_, w.fileErr = w.file.Write(p)
+	}
+	// Report success even when spilling fails so the command keeps running;
+	// the inline head is still returned
+	return len(p), nil
+}
+
+// result returns the inline head, whether the stream was truncated, and the
+// temp file holding the full stream ("" when not truncated or not written).
+func (w *cappedWriter) result() (string, bool, string) {
+	if w.file == nil || w.fileErr != nil {
+		return string(w.head), w.truncated, ""
+	}
+	return string(w.head), w.truncated, w.file.Name()
+}
+
+func (w *cappedWriter) close() {
+	if w.file != nil {
+		_ = w.file.Close() //nolint:errcheck // best-effort; contents already written
+		if w.fileErr != nil {
+			_ = os.Remove(w.file.Name()) //nolint:errcheck
+		}
+	}
+}
+
 func writeOutput(out execOutput) {
 	enc, err := json.Marshal(out)
 	if err != nil {
 		// Best-effort: emit minimal JSON
-		fmt.Println("{\"exitCode\":0,\"stdout\":\"\",\"stderr\":\"marshal error\",\"durationMs\":0}")
+		fmt.Println("{\"exitCode\":0,\"stdout\":\"\",\"stderr\":\"marshal error\",\"stdoutTruncated\":false,\"stderrTruncated\":false,\"killed\":false,\"durationMs\":0}")
 		return
 	}
 	// Single line JSON
@@ -3,9 +3,12 @@ package main_test
 import (
 	"bytes"
 	"encoding/json"
+	"os"
 	"os/exec"
 	"runtime"
+	"strconv"
 	"strings"
+	"syscall"
 	"testing"
 
 	testutil "github.com/hyperifyio/goagent/tools/testutil"
@@ -13,10 +16,16 @@ import (
 
 // execOutput models the expected stdout JSON contract from tools/exec.go
 type execOutput struct {
-	ExitCode   int    `json:"exitCode"`
-	Stdout     string `json:"stdout"`
-	Stderr     string `json:"stderr"`
-	DurationMs int64  `json:"durationMs"`
+	ExitCode        int    `json:"exitCode"`
+	Stdout          string `json:"stdout"`
+	Stderr          string `json:"stderr"`
+	StdoutTruncated bool   `json:"stdoutTruncated"`
+	StderrTruncated bool   `json:"stderrTruncated"`
+	StdoutFile      string `json:"stdoutFile"`
+	StderrFile      string `json:"stderrFile"`
+	Killed          bool   `json:"killed"`
+	Signal          string `json:"signal"`
+	DurationMs      int64  `json:"durationMs"`
 }
 
 // runExec runs the built exec tool with the given JSON input and decodes stdout.
@@ -175,3 +184,146 @@ func TestExec_StdinPassthrough(t *testing.T) {
 		t.Fatalf("stdin passthrough failed, got %q", out.Stdout)
 	}
 }
+
+func TestExec_MaxOutputBytes_TruncatesAndSpills(t *testing.T) {
+	bin := testutil.BuildTool(t, "exec")
+	if runtime.GOOS == "windows" {
+		t.Skip("windows not supported in this test environment")
+	}
+	out := runExec(t, bin, map[string]any{
+		"cmd":            "/bin/sh",
+		"args":           []string{"-c", "i=0; while [ $i -lt 500 ]; do printf 0123456789; i=$((i+1)); done; echo err >&2"},
+		"maxOutputBytes": 100,
+	})
+	if out.StdoutFile != "" {
+		t.Cleanup(func() { _ = os.Remove(out.StdoutFile) })
+	}
+	if !out.StdoutTruncated || out.Stdout != strings.Repeat("0123456789", 10) {
+		t.Fatalf("stdout not capped: truncated=%v len=%d", out.StdoutTruncated, len(out.Stdout))
+	}
+	full, err := os.ReadFile(out.StdoutFile)
+	if err != nil || string(full) != strings.Repeat("0123456789", 500) {
+		t.Fatalf("spill file %q: err=%v len=%d", out.StdoutFile, err, len(full))
+	}
+	if out.StderrTruncated || out.StderrFile != "" || out.Stderr != "err\n" {
+		t.Fatalf("stderr under the cap changed: %+v", out)
+	}
+}
+
+func TestExec_MaxOutputBytes_DefaultKeepsSmallOutput(t *testing.T) {
+	bin := testutil.BuildTool(t, "exec")
+	if runtime.GOOS == "windows" {
+		t.Skip("windows not supported in this test environment")
+	}
+	out := runExec(t, bin, map[string]any{"cmd": "/bin/echo", "args": []string{"hi"}})
+	if out.Stdout != "hi\n" || out.StdoutTruncated || out.StdoutFile != "" {
+		t.Fatalf("unexpected output: %+v", out)
+	}
+}
+
+func TestExec_Timeout_KillsProcessGroup(t *testing.T) {
+	bin := testutil.BuildTool(t, "exec")
+	if runtime.GOOS == "windows" {
+		t.Skip("process groups are POSIX-only")
+	}
+	// The shell reports its background child's pid, then waits on it
+	out := runExec(t, bin, map[string]any{
+		"cmd":        "/bin/sh",
+		"args":       []string{"-c", "sleep 30 & echo $!; wait"},
+		"timeoutSec": 1,
+	})
+	if !out.Killed || out.Signal != "SIGKILL" {
+		t.Fatalf("expected killed:true signal:SIGKILL, got %+v", out)
+	}
+	if out.DurationMs > 5000 {
+		t.Fatalf("tool waited for the grandchild: durationMs=%d", out.DurationMs)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(out.Stdout))
+	if err != nil {
+		t.Fatalf("grandchild pid: %v (stdout %q)", err, out.Stdout)
+	}
+	if p, err := os.FindProcess(pid); err == nil && p.Signal(syscall.Signal(0)) == nil {
+		_ = p.Kill()
+		t.Fatalf("grandchild %d still running after timeout", pid)
+	}
+}
+
+func TestExec_NoTimeout_NotKilled(t *testing.T) {
+	bin := testutil.BuildTool(t, "exec")
+	if runtime.GOOS == "windows" {
+		t.Skip("windows not supported in this test environment")
+	}
+	out := runExec(t, bin, map[string]any{"cmd": "/bin/echo", "args": []string{"x"}, "timeoutSec": 5})
+	if out.Killed || out.Signal != "" {
+		t.Fatalf("unexpected kill report: %+v", out)
+	}
+}
+
+// runExecRefused runs the exec tool with extra environment and expects the
+// structured policy error on stderr.
+func runExecRefused(t *testing.T, bin string, env []string, input any) map[string]any {
+	t.Helper()
+	data, err := json.Marshal(input)
+	if err != nil {
+		t.Fatalf("marshal input: %v", err)
+	}
+	cmd := exec.Command(bin)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = bytes.NewReader(data)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err == nil {
+		t.Fatalf("expected refusal, got stdout=%s", stdout.String())
+	}
+	if stdout.Len() != 0 {
+		t.Fatalf("refused command produced stdout: %q", stdout.String())
+	}
+	var perr map[string]any
+	if err := json.Unmarshal(bytes.TrimSpace(stderr.Bytes()), &perr); err != nil {
+		t.Fatalf("stderr is not JSON: %v; raw=%q", err, stderr.String())
+	}
+	return perr
+}
+
+func TestExec_Policy_Allowlist(t *testing.T) {
+	bin := testutil.BuildTool(t, "exec")
+	if runtime.GOOS == "windows" {
+		t.Skip("windows not supported in this test environment")
+	}
+	t.Setenv("GOAGENT_EXEC_ALLOW", "echo, /bin/t*")
+	if out := runExec(t, bin, map[string]any{"cmd": "echo", "args": []string{"ok"}}); out.Stdout != "ok\n" {
+		t.Fatalf("allowed name refused: %+v", out)
+	}
+	if out := runExec(t, bin, map[string]any{"cmd": "/bin/true"}); out.ExitCode != 0 {
+		t.Fatalf("allowed path glob refused: %+v", out)
+	}
+	// A bare name does not cover the same binary given by path
+	perr := runExecRefused(t, bin, nil, map[string]any{"cmd": "/bin/echo", "args": []string{"x"}})
+	if perr["policy"] != "allow" || perr["cmd"] != "/bin/echo" || !strings.Contains(perr["error"].(string), "GOAGENT_EXEC_ALLOW") {
+		t.Fatalf("unexpected error: %v", perr)
+	}
+	perr = runExecRefused(t, bin, nil, map[string]any{"cmd": "sh", "args": []string{"-c", "echo hi"}})
+	if perr["policy"] != "allow" {
+		t.Fatalf("unexpected error: %v", perr)
+	}
+}
+
+func TestExec_Policy_Deny(t *testing.T) {
+	bin := testutil.BuildTool(t, "exec")
+	if runtime.GOOS == "windows" {
+		t.Skip("windows not supported in this test environment")
+	}
+	perr := runExecRefused(t, bin, []string{`GOAGENT_EXEC_DENY=rm\s+-(rf|fr)`}, map[string]any{"cmd": "/bin/echo", "args": []string{"rm", "-rf", "/"}})
+	if perr["policy"] != "deny" || !strings.Contains(perr["error"].(string), "rm -rf") {
+		t.Fatalf("unexpected error: %v", perr)
+	}
+	perr = runExecRefused(t, bin, []string{"GOAGENT_EXEC_DENY=("}, map[string]any{"cmd": "/bin/echo"})
+	if !strings.Contains(perr["error"].(string), "invalid GOAGENT_EXEC_DENY") {
+		t.Fatalf("invalid pattern must refuse: %v", perr)
+	}
+	t.Setenv("GOAGENT_EXEC_DENY", `rm\s+-rf`)
+	if out := runExec(t, bin, map[string]any{"cmd": "/bin/echo", "args": []string{"fine"}}); out.Stdout != "fine\n" {
+		t.Fatalf("non-matching command refused: %+v", out)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Operator policy environment variables. Both are optional; when neither is
+// set any command may run.
+const (
+	// envAllow is a comma-separated allowlist. An entry without a path
+	// separator is a binary name that matches commands resolved through PATH;
+	// an entry with one is an absolute path that matches the resolved
+	// executable. Entries may use filepath.Match wildcards.
+	envAllow = "GOAGENT_EXEC_ALLOW"
+	// envDeny is an RE2 pattern; a command line (cmd and args joined by
+	// spaces) that matches it is refused.
+	envDeny = "GOAGENT_EXEC_DENY"
+)
+
+// policyError is the structured stderr JSON for a refused command.
+type policyError struct {
+	Error  string   `json:"error"`
+	Policy string   `json:"policy"` // "allow" | "deny"
+	Cmd    string   `json:"cmd"`
+	Args   []string `json:"args,omitempty"`
+}
+
+// checkPolicy applies GOAGENT_EXEC_ALLOW and GOAGENT_EXEC_DENY to in and
+// returns nil when the command may run. Misconfigured policies refuse every
+// command rather than failing open.
+func checkPolicy(in execInput) *policyError {
+	refuse := func(policy, format string, args ...any) *policyError {
+		return &policyError{Error: fmt.Sprintf(format, args...), Policy: policy, Cmd: in.Cmd, Args: in.Args}
+	}
+	if raw := strings.TrimSpace(os.Getenv(envAllow)); raw != "" {
+		ok, err := allowed(in.Cmd, strings.Split(raw, ","))
+		if err != nil {
+			return refuse("allow", "invalid %s: %v", envAllow, err)
+		}
+		if !ok {
+			return refuse("allow", "command %q is not in %s", in.Cmd, envAllow)
+		}
+	}
+	if raw := strings.TrimSpace(os.Getenv(envDeny)); raw != "" {
+		re, err := regexp.Compile(raw)
+		if err != nil {
+			return refuse("deny", "invalid %s: %v", envDeny, err)
+		}
+		line := strings.Join(append([]string{in.Cmd}, in.Args...), " ")
+		if loc := re.FindStringIndex(line); loc != nil {
+			return refuse("deny", "command line matches %s (%q)", envDeny, line[loc[0]:loc[1]])
+		}
+	}
+	return nil
+}
+
+// allowed reports whether cmd matches an allowlist entry.
+func allowed(cmd string, entries []string) (bool, error) {
+	byPath := strings.ContainsRune(cmd, '/') || strings.ContainsRune(cmd, filepath.Separator)
+	resolved := ""
+	if byPath {
+		if abs, err := filepath.Abs(cmd); err == nil {
+			resolved = abs
+		}
+	} else if p, err := exec.LookPath(cmd); err == nil {
+		if abs, err := filepath.Abs(p); err == nil {
+			resolved = abs
+		}
+	}
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		if strings.ContainsRune(e, '/') || strings.ContainsRune(e, filepath.Separator) {
+			if resolved == "" {
+				continue
+			}
+			ok, err := filepath.Match(filepath.Clean(e), resolved)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				return true, nil
+			}
+			continue
+		}
+		// Bare names only cover commands looked up through PATH, so a
+		// same-named binary elsewhere cannot slip through
+		if byPath {
+			continue
+		}
+		ok, err := filepath.Match(e, cmd)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
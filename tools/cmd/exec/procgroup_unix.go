@@ -0,0 +1,45 @@
+//go:build !windows
+
+package main
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// killSignal is reported as "signal" when a timed-out command is killed.
+const killSignal = "SIGKILL"
+
+// startInProcessGroup makes the command lead a new process group so a
+// timeout can kill everything it spawned.
+func startInProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup sends SIGKILL to the command's whole process group.
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	if err == syscall.ESRCH {
+		return nil
+	}
+	return err
+}
+
+// waitProcessGroup waits up to limit for every member of the killed group to
+// be gone. Orphaned members are reaped by init once they die.
+func waitProcessGroup(cmd *exec.Cmd, limit time.Duration) {
+	if cmd.Process == nil {
+		return
+	}
+	deadline := time.Now().Add(limit)
+	for time.Now().Before(deadline) {
+		if syscall.Kill(-cmd.Process.Pid, 0) == syscall.ESRCH {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
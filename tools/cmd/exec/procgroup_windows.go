@@ -0,0 +1,25 @@
+//go:build windows
+
+package main
+
+import (
+	"os/exec"
+	"time"
+)
+
+// killSignal is reported as "signal" when a timed-out command is killed.
+const killSignal = "kill"
+
+// startInProcessGroup is a no-op on Windows, which has no process groups in
+// the POSIX sense; only the direct child is killed on timeout.
+func startInProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills the direct child.
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
+
+func waitProcessGroup(cmd *exec.Cmd, limit time.Duration) {}
@@ -11,6 +11,8 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+
+	"github.com/hyperifyio/goagent/tools/workspace"
 )
 
 type appendInput struct {
@@ -30,6 +32,10 @@ func main() {
 		stderrJSON(err)
 		os.Exit(1)
 	}
+	if _, in.Path, err = workspace.Enter(in.Path); err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
 	if err := validatePath(in.Path); err != nil {
 		stderrJSON(err)
 		os.Exit(1)
@@ -93,7 +99,7 @@ func validatePath(p string) error {
 		return fmt.Errorf("path must be relative to repository root: %s", p)
 	}
 	clean := filepath.ToSlash(filepath.Clean(p))
-	if strings.HasPrefix(clean, "../") || strings.Contains(clean, "/../") {
+	if clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(clean, "/../") {
 		return fmt.Errorf("path escapes repository root: %s", p)
 	}
 	return nil
@@ -9,11 +9,16 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/hyperifyio/goagent/tools/workspace"
 )
 
 type applyInput struct {
 	UnifiedDiff string `json:"unifiedDiff"`
 	DryRun      bool   `json:"dryRun,omitempty"`
+	// Root names the workspace root the diff applies to; the default
+	// root when empty
+	Root string `json:"root,omitempty"`
 }
 
 type applyOutput struct {
@@ -30,6 +35,10 @@ func main() {
 		stderrJSON(errors.New("unifiedDiff is required"))
 		os.Exit(1)
 	}
+	if err := workspace.Chdir(in.Root); err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
 	// Minimal implementation to create a new file per S02 for clean new-file apply
 	changed, err := applyNewFileOnly(in.UnifiedDiff, in.DryRun)
 	if err != nil {
@@ -136,7 +145,7 @@ func validateRelPath(p string) error {
 		return fmt.Errorf("ABSOLUTE_PATH: %s", p)
 	}
 	clean := filepath.ToSlash(filepath.Clean(p))
-	if strings.HasPrefix(clean, "../") || strings.Contains(clean, "/../") {
+	if clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(clean, "/../") {
 		return fmt.Errorf("PATH_ESCAPE: %s", p)
 	}
 	return nil
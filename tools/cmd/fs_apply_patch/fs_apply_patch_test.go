@@ -227,3 +227,36 @@ func TestFsApplyPatch_DryRun_NewFile_NoWrite(t *testing.T) {
 		t.Fatalf("expected file to NOT exist after dryRun, stat err=%v", err)
 	}
 }
+
+// TestFsApplyPatch_NamedRoot verifies "root" applies the diff inside the
+// named workspace root and that escaping paths and unknown roots are
+// refused.
+func TestFsApplyPatch_NamedRoot(t *testing.T) {
+	bin := buildFsApplyPatch(t)
+	dir := t.TempDir()
+	t.Setenv("GOAGENT_WORKSPACE_ROOTS", "api="+dir)
+	newFile := func(path string) string {
+		return "--- /dev/null\n+++ b/" + path + "\n@@ -0,0 +1,1 @@\n+hello\n"
+	}
+	work := t.TempDir()
+
+	out, stderr, code := runFsApplyPatchInDir(t, bin, work, map[string]any{"root": "api", "unifiedDiff": newFile("sub/new.txt")})
+	if code != 0 || out.FilesChanged != 1 {
+		t.Fatalf("expected success, got exit=%d stderr=%q", code, stderr)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sub", "new.txt")); err != nil {
+		t.Fatalf("expected file in root: %v", err)
+	}
+	cases := []struct{ root, path, want string }{
+		{"api", "../../escape.txt", "PATH_ESCAPE"},
+		{"web", "new2.txt", "UNKNOWN_ROOT"},
+	}
+	for _, tc := range cases {
+		if _, stderr, code := runFsApplyPatchInDir(t, bin, work, map[string]any{"root": tc.root, "unifiedDiff": newFile(tc.path)}); code == 0 || !strings.Contains(stderr, tc.want) {
+			t.Fatalf("%s:%s: expected %s, got exit=%d stderr=%q", tc.root, tc.path, tc.want, code, stderr)
+		}
+	}
+	if entries, _ := os.ReadDir(work); len(entries) != 0 {
+		t.Fatalf("diffs leaked into the working directory: %v", entries)
+	}
+}
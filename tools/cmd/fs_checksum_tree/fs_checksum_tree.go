@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/hyperifyio/goagent/tools/workspace"
+)
+
+type treeInput struct {
+	Path          string   `json:"path"`
+	IncludeHidden bool     `json:"includeHidden,omitempty"`
+	Exclude       []string `json:"exclude,omitempty"`
+	MaxFiles      int      `json:"maxFiles,omitempty"`
+	ExpectRoot    string   `json:"expectRoot,omitempty"`
+}
+
+type fileSum struct {
+	Path      string `json:"path"`
+	Sha256    string `json:"sha256"`
+	SizeBytes int64  `json:"sizeBytes"`
+}
+
+type treeOutput struct {
+	Root       string    `json:"root"`
+	FileCount  int       `json:"fileCount"`
+	TotalBytes int64     `json:"totalBytes"`
+	Files      []fileSum `json:"files"`
+	Truncated  bool      `json:"truncated"`
+	Matches    *bool     `json:"matches,omitempty"`
+}
+
+// defaultMaxFiles bounds the per-file list when maxFiles is unset; the root
+// digest always covers every file.
+const defaultMaxFiles = 1000
+
+func main() {
+	in, err := readInput(os.Stdin)
+	if err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	if _, in.Path, err = workspace.Enter(in.Path); err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	if err := validatePath(in.Path); err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	out, err := checksumTree(in)
+	if err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(out); err != nil {
+		stderrJSON(fmt.Errorf("encode json: %w", err))
+		os.Exit(1)
+	}
+}
+
+func readInput(r io.Reader) (treeInput, error) {
+	var in treeInput
+	b, err := io.ReadAll(bufio.NewReader(r))
+	if err != nil {
+		return in, fmt.Errorf("read stdin: %w", err)
+	}
+	if err := json.Unmarshal(b, &in); err != nil {
+		return in, fmt.Errorf("parse json: %w", err)
+	}
+	if strings.TrimSpace(in.Path) == "" {
+		return in, fmt.Errorf("path is required")
+	}
+	if in.MaxFiles < 0 {
+		return in, fmt.Errorf("maxFiles must be >= 0")
+	}
+	for _, p := range in.Exclude {
+		if _, err := path.Match(p, ""); err != nil {
+			return in, fmt.Errorf("bad exclude pattern %q: %w", p, err)
+		}
+	}
+	return in, nil
+}
+
+func validatePath(p string) error {
+	if filepath.IsAbs(p) {
+		return fmt.Errorf("ABSOLUTE_PATH: %s", p)
+	}
+	clean := filepath.ToSlash(filepath.Clean(p))
+	if clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(clean, "/../") {
+		return fmt.Errorf("PATH_ESCAPE: %s", p)
+	}
+	return nil
+}
+
+// walker computes the Merkle digest of a tree. A file's digest is the
+// SHA-256 of its content and a symlink's the SHA-256 of "symlink:" plus its
+// target. A directory's digest is the SHA-256 of one line per entry in name
+// order, "<f|l|d> <name>\x00<hex digest>\n", so renaming, adding, or
+// removing anything changes every digest up to the root.
+type walker struct {
+	in    treeInput
+	max   int
+	out   treeOutput
+	files []fileSum
+}
+
+func checksumTree(in treeInput) (treeOutput, error) {
+	fi, err := os.Stat(in.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return treeOutput{}, fmt.Errorf("NOT_FOUND: %s", in.Path)
+		}
+		return treeOutput{}, err
+	}
+	if !fi.IsDir() {
+		return treeOutput{}, fmt.Errorf("NOT_A_DIRECTORY: %s", in.Path)
+	}
+	w := &walker{in: in, max: in.MaxFiles}
+	if w.max == 0 {
+		w.max = defaultMaxFiles
+	}
+	root, err := w.dir(filepath.Clean(in.Path), "")
+	if err != nil {
+		return treeOutput{}, err
+	}
+	w.out.Root = hex.EncodeToString(root)
+	w.out.Files = w.files
+	if w.out.Files == nil {
+		w.out.Files = []fileSum{}
+	}
+	w.out.Truncated = w.out.FileCount > len(w.files)
+	if in.ExpectRoot != "" {
+		matches := strings.EqualFold(strings.TrimSpace(in.ExpectRoot), w.out.Root)
+		w.out.Matches = &matches
+	}
+	return w.out, nil
+}
+
+// excluded reports whether the tree-relative path rel is skipped.
+func (w *walker) excluded(rel, name string) bool {
+	if name == ".git" || (!w.in.IncludeHidden && strings.HasPrefix(name, ".")) {
+		return true
+	}
+	for _, p := range w.in.Exclude {
+		if ok, _ := path.Match(p, rel); ok {
+			return true
+		}
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *walker) dir(abs, rel string) ([]byte, error) {
+	entries, err := os.ReadDir(abs)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	for _, e := range entries {
+		name := e.Name()
+		childRel := path.Join(rel, name)
+		if w.excluded(childRel, name) {
+			continue
+		}
+		childAbs := filepath.Join(abs, name)
+		var kind string
+		var sum []byte
+		switch {
+		case e.Type()&os.ModeSymlink != 0:
+			target, err := os.Readlink(childAbs)
+			if err != nil {
+				return nil, err
+			}
+			s := sha256.Sum256([]byte("symlink:" + target))
+			kind, sum = "l", s[:]
+		case e.IsDir():
+			if sum, err = w.dir(childAbs, childRel); err != nil {
+				return nil, err
+			}
+			kind = "d"
+		case e.Type().IsRegular():
+			size, s, err := hashFile(childAbs)
+			if err != nil {
+				return nil, err
+			}
+			kind, sum = "f", s
+			w.out.FileCount++
+			w.out.TotalBytes += size
+			if len(w.files) < w.max {
+				w.files = append(w.files, fileSum{Path: childRel, Sha256: hex.EncodeToString(s), SizeBytes: size})
+			}
+		default:
+			// Sockets, devices, and pipes have no stable content
+			continue
+		}
+		fmt.Fprintf(h, "%s %s\x00%s\n", kind, name, hex.EncodeToString(sum))
+	}
+	return h.Sum(nil), nil
+}
+
+func hashFile(p string) (int64, []byte, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer f.Close() //nolint:errcheck // read-only
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return 0, nil, fmt.Errorf("read %s: %w", p, err)
+	}
+	return n, h.Sum(nil), nil
+}
+
+func stderrJSON(err error) {
+	msg := err.Error()
+	msg = strings.ReplaceAll(msg, "\n", " ")
+	fmt.Fprintf(os.Stderr, "{\"error\":%q}\n", msg)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperifyio/goagent/tools/testutil"
+)
+
+// runChecksumTree runs the tool in dir and decodes stdout.
+func runChecksumTree(t *testing.T, bin, dir string, input any) (treeOutput, string, int) {
+	t.Helper()
+	data, err := json.Marshal(input)
+	if err != nil {
+		t.Fatalf("marshal input: %v", err)
+	}
+	cmd := exec.Command(bin)
+	cmd.Dir = dir
+	cmd.Stdin = bytes.NewReader(data)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	code := 0
+	if err := cmd.Run(); err != nil {
+		if ee, ok := err.(*exec.ExitError); ok {
+			code = ee.ExitCode()
+		} else {
+			t.Fatalf("run: %v", err)
+		}
+	}
+	var out treeOutput
+	if code == 0 {
+		if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+			t.Fatalf("unmarshal stdout: %v; raw=%q", err, stdout.String())
+		}
+	}
+	return out, stderr.String(), code
+}
+
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, body := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestChecksumTree_DetectsDrift(t *testing.T) {
+	bin := testutil.BuildTool(t, "fs_checksum_tree")
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"tree/a.txt": "a", "tree/sub/b.txt": "bb", "tree/.hidden": "h", "tree/skip.log": "x"})
+
+	in := map[string]any{"path": "tree", "exclude": []string{"*.log"}}
+	first, stderr, code := runChecksumTree(t, bin, dir, in)
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%q", code, stderr)
+	}
+	if first.FileCount != 2 || first.TotalBytes != 3 || first.Truncated || len(first.Files) != 2 {
+		t.Fatalf("first=%+v", first)
+	}
+	if first.Files[0].Path != "a.txt" || first.Files[1].Path != "sub/b.txt" {
+		t.Fatalf("files=%+v", first.Files)
+	}
+	// ca978112... is sha256("a")
+	if !strings.HasPrefix(first.Files[0].Sha256, "ca978112") {
+		t.Fatalf("a.txt sha256=%s", first.Files[0].Sha256)
+	}
+
+	// Hidden and excluded files do not affect the digest
+	writeTree(t, dir, map[string]string{"tree/.hidden": "changed", "tree/skip.log": "changed"})
+	in["expectRoot"] = first.Root
+	same, _, _ := runChecksumTree(t, bin, dir, in)
+	if same.Root != first.Root || same.Matches == nil || !*same.Matches {
+		t.Fatalf("unrelated change altered the root: %+v", same)
+	}
+
+	// A rename with the same content does
+	if err := os.Rename(filepath.Join(dir, "tree/sub/b.txt"), filepath.Join(dir, "tree/sub/c.txt")); err != nil {
+		t.Fatal(err)
+	}
+	moved, _, _ := runChecksumTree(t, bin, dir, in)
+	if moved.Root == first.Root || moved.Matches == nil || *moved.Matches {
+		t.Fatalf("rename not detected: %+v", moved)
+	}
+}
+
+func TestChecksumTree_MaxFilesBoundsListOnly(t *testing.T) {
+	bin := testutil.BuildTool(t, "fs_checksum_tree")
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"t/1": "1", "t/2": "2", "t/3": "3"})
+	all, _, _ := runChecksumTree(t, bin, dir, map[string]any{"path": "t"})
+	capped, _, code := runChecksumTree(t, bin, dir, map[string]any{"path": "t", "maxFiles": 1})
+	if code != 0 || len(capped.Files) != 1 || !capped.Truncated || capped.FileCount != 3 || capped.Root != all.Root {
+		t.Fatalf("capped=%+v all=%+v", capped, all)
+	}
+}
+
+func TestChecksumTree_Errors(t *testing.T) {
+	bin := testutil.BuildTool(t, "fs_checksum_tree")
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"f.txt": "x"})
+	for _, tc := range []struct {
+		in   map[string]any
+		want string
+	}{
+		{map[string]any{"path": "../x"}, "PATH_ESCAPE"},
+		{map[string]any{"path": "/tmp"}, "ABSOLUTE_PATH"},
+		{map[string]any{"path": "missing"}, "NOT_FOUND"},
+		{map[string]any{"path": "f.txt"}, "NOT_A_DIRECTORY"},
+	} {
+		_, stderr, code := runChecksumTree(t, bin, dir, tc.in)
+		if code == 0 || !strings.Contains(stderr, tc.want) {
+			t.Errorf("%v: exit=%d stderr=%q", tc.in, code, stderr)
+		}
+	}
+}
@@ -13,6 +13,8 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+
+	"github.com/hyperifyio/goagent/tools/workspace"
 )
 
 type editInput struct {
@@ -36,6 +38,10 @@ func main() {
 		stderrJSON(err)
 		os.Exit(1)
 	}
+	if _, in.Path, err = workspace.Enter(in.Path); err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
 	if err := validatePath(in.Path); err != nil {
 		stderrJSON(err)
 		os.Exit(1)
@@ -77,7 +83,7 @@ func validatePath(p string) error {
 		return fmt.Errorf("ABSOLUTE_PATH: %s", p)
 	}
 	clean := filepath.ToSlash(filepath.Clean(p))
-	if strings.HasPrefix(clean, "../") || strings.Contains(clean, "/../") {
+	if clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(clean, "/../") {
 		return fmt.Errorf("PATH_ESCAPE: %s", p)
 	}
 	return nil
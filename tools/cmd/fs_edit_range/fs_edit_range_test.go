@@ -212,3 +212,35 @@ func TestFsEditRange_End_Splicing(t *testing.T) {
 		t.Fatalf("newSha256 mismatch: got %q want %q", out.NewSha256, wantHex)
 	}
 }
+
+// TestFsEditRange_NamedRoot verifies a "root:" path edits inside the named
+// workspace root and that escapes and unknown roots are refused.
+func TestFsEditRange_NamedRoot(t *testing.T) {
+	bin := buildFsEditRangeTool(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "x.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatalf("seed file: %v", err)
+	}
+	t.Setenv("GOAGENT_WORKSPACE_ROOTS", "api="+dir)
+	repl := base64.StdEncoding.EncodeToString([]byte("J"))
+
+	if _, stderr, code := runFsEditRange(t, bin, map[string]any{"path": "api:x.txt", "startByte": 0, "endByte": 1, "replacementBase64": repl}); code != 0 {
+		t.Fatalf("expected success, got exit=%d stderr=%q", code, stderr)
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "x.txt")); err != nil || string(b) != "Jello" {
+		t.Fatalf("file in root: %q %v", b, err)
+	}
+	for path, want := range map[string]string{"api:../../x.txt": "PATH_ESCAPE", "web:x.txt": "UNKNOWN_ROOT"} {
+		data, err := json.Marshal(map[string]any{"path": path, "startByte": 0, "endByte": 1, "replacementBase64": repl})
+		if err != nil {
+			t.Fatalf("marshal input: %v", err)
+		}
+		cmd := exec.Command(bin)
+		cmd.Stdin = bytes.NewReader(data)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err == nil || !strings.Contains(stderr.String(), want) {
+			t.Fatalf("%s: expected %s, got err=%v stderr=%q", path, want, err, stderr.String())
+		}
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,6 +12,8 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+
+	"github.com/hyperifyio/goagent/tools/workspace"
 )
 
 type listInput struct {
@@ -19,6 +22,10 @@ type listInput struct {
 	Globs         []string `json:"globs,omitempty"`
 	IncludeHidden bool     `json:"includeHidden,omitempty"`
 	MaxResults    int      `json:"maxResults,omitempty"`
+	// PageSize switches to paging: every match is collected and sorted, and
+	// one page of PageSize entries after Cursor is returned.
+	PageSize int    `json:"pageSize,omitempty"`
+	Cursor   string `json:"cursor,omitempty"`
 }
 
 type entry struct {
@@ -32,14 +39,26 @@ type entry struct {
 type listOutput struct {
 	Entries   []entry `json:"entries"`
 	Truncated bool    `json:"truncated"`
+	// Set only when paging
+	NextCursor   string `json:"nextCursor,omitempty"`
+	TotalMatched *int   `json:"totalMatched,omitempty"`
 }
 
+// defaultPageSize applies when a cursor is given without pageSize.
+const defaultPageSize = 1000
+
 func main() {
 	in, err := readInput(os.Stdin)
 	if err != nil {
 		stderrJSON(err)
 		os.Exit(1)
 	}
+	root, rel, err := workspace.Enter(in.Path)
+	if err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	in.Path = rel
 	if err := validatePath(in.Path); err != nil {
 		stderrJSON(err)
 		os.Exit(1)
@@ -49,6 +68,10 @@ func main() {
 		stderrJSON(err)
 		os.Exit(1)
 	}
+	// Entries in a named root carry its prefix so they can be passed back
+	for i := range out.Entries {
+		out.Entries[i].Path = workspace.Display(root, out.Entries[i].Path)
+	}
 	if err := json.NewEncoder(os.Stdout).Encode(out); err != nil {
 		stderrJSON(fmt.Errorf("encode json: %w", err))
 		os.Exit(1)
@@ -67,6 +90,9 @@ func readInput(r io.Reader) (listInput, error) {
 	if strings.TrimSpace(in.Path) == "" {
 		return in, fmt.Errorf("path is required")
 	}
+	if in.PageSize < 0 {
+		return in, fmt.Errorf("pageSize must be >= 0")
+	}
 	return in, nil
 }
 
@@ -75,7 +101,7 @@ func validatePath(p string) error {
 		return fmt.Errorf("ABSOLUTE_PATH: %s", p)
 	}
 	clean := filepath.ToSlash(filepath.Clean(p))
-	if strings.HasPrefix(clean, "../") || strings.Contains(clean, "/../") {
+	if clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(clean, "/../") {
 		return fmt.Errorf("PATH_ESCAPE: %s", p)
 	}
 	return nil
@@ -92,6 +118,11 @@ func list(in listInput) (listOutput, error) {
 	if max <= 0 {
 		max = 10000
 	}
+	paging := in.PageSize > 0 || in.Cursor != ""
+	if paging {
+		// Every match is needed for a stable order and the total
+		max = 0
+	}
 	// Normalize but avoid ineffectual assignments
 	if in.Path == "." {
 		in.Path = "."
@@ -145,7 +176,7 @@ func list(in listInput) (listOutput, error) {
 			ModeOctal: fmt.Sprintf("%04o", mode.Perm()),
 			ModTime:   info.ModTime().UTC().Format("2006-01-02T15:04:05Z07:00"),
 		})
-		if len(entries) >= max {
+		if max > 0 && len(entries) >= max {
 			return io.EOF
 		}
 		return nil
@@ -173,19 +204,66 @@ func list(in listInput) (listOutput, error) {
 		}
 	}
 	// stable ordering: dirs first, then files, lexicographic
-	sort.Slice(entries, func(i, j int) bool {
-		if entries[i].Type == entries[j].Type {
-			return entries[i].Path < entries[j].Path
+	sort.Slice(entries, func(i, j int) bool { return entryLess(entries[i], entries[j]) })
+	if paging {
+		return page(entries, in)
+	}
+	return listOutput{Entries: entries, Truncated: len(entries) >= max}, nil
+}
+
+func entryLess(a, b entry) bool {
+	if (a.Type == "dir") != (b.Type == "dir") {
+		return a.Type == "dir"
+	}
+	return a.Path < b.Path
+}
+
+// pageCursor is the position behind a nextCursor: the sort key of the last
+// entry returned. Resuming after a key rather than an index keeps pages
+// stable when entries before it are added or removed between calls.
+type pageCursor struct {
+	Dir  bool   `json:"d"`
+	Path string `json:"p"`
+}
+
+// page returns the sorted matches after in.Cursor, at most in.PageSize.
+func page(entries []entry, in listInput) (listOutput, error) {
+	size := in.PageSize
+	if size <= 0 {
+		size = defaultPageSize
+	}
+	start := 0
+	if in.Cursor != "" {
+		var c pageCursor
+		raw, err := base64.RawURLEncoding.DecodeString(in.Cursor)
+		if err != nil || json.Unmarshal(raw, &c) != nil {
+			return listOutput{}, fmt.Errorf("invalid cursor")
 		}
-		if entries[i].Type == "dir" {
-			return true
+		after := entry{Path: c.Path, Type: "file"}
+		if c.Dir {
+			after.Type = "dir"
 		}
-		if entries[j].Type == "dir" {
-			return false
+		start = sort.Search(len(entries), func(i int) bool { return entryLess(after, entries[i]) })
+	}
+	end := start + size
+	if end > len(entries) {
+		end = len(entries)
+	}
+	total := len(entries)
+	out := listOutput{Entries: entries[start:end], TotalMatched: &total}
+	if out.Entries == nil {
+		out.Entries = []entry{}
+	}
+	if end < len(entries) {
+		last := entries[end-1]
+		raw, err := json.Marshal(pageCursor{Dir: last.Type == "dir", Path: last.Path})
+		if err != nil {
+			return listOutput{}, fmt.Errorf("encode cursor: %w", err)
 		}
-		return entries[i].Path < entries[j].Path
-	})
-	return listOutput{Entries: entries, Truncated: len(entries) >= max}, nil
+		out.NextCursor = base64.RawURLEncoding.EncodeToString(raw)
+		out.Truncated = true
+	}
+	return out, nil
 }
 
 func matchSimpleGlob(path, pattern string) bool {
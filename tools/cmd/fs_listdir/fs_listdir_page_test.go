@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	testutil "github.com/hyperifyio/goagent/tools/testutil"
+)
+
+// TestFsListdir_Pagination pages through a directory and checks the pages
+// are disjoint, ordered dirs first, and report the total.
+func TestFsListdir_Pagination(t *testing.T) {
+	tmpDirAbs, err := os.MkdirTemp(".", "fslistdir-page-")
+	if err != nil {
+		t.Fatalf("mkdir temp: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := os.RemoveAll(tmpDirAbs); err != nil {
+			t.Logf("cleanup remove %s: %v", tmpDirAbs, err)
+		}
+	})
+	base := filepath.Base(tmpDirAbs)
+	for _, name := range []string{"e.txt", "a.txt", "d.txt", "c.md", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(base, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(base, "z"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	bin := testutil.BuildTool(t, "fs_listdir")
+
+	var got []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatalf("too many pages: %v", got)
+		}
+		in := map[string]any{"path": base, "pageSize": 2, "globs": []string{"**/*.txt", "**/z"}}
+		if cursor != "" {
+			in["cursor"] = cursor
+		}
+		out, stderr, code := runFsListdir(t, bin, in)
+		if code != 0 {
+			t.Fatalf("exit=%d stderr=%q", code, stderr)
+		}
+		if out.TotalMatched == nil || *out.TotalMatched != 5 {
+			t.Fatalf("totalMatched=%v", out.TotalMatched)
+		}
+		for _, e := range out.Entries {
+			got = append(got, filepath.Base(e.Path))
+		}
+		if out.NextCursor == "" {
+			if out.Truncated {
+				t.Fatal("last page marked truncated")
+			}
+			break
+		}
+		cursor = out.NextCursor
+	}
+	want := []string{"z", "a.txt", "b.txt", "d.txt", "e.txt"}
+	if len(got) != len(want) {
+		t.Fatalf("entries=%v want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("entries=%v want %v", got, want)
+		}
+	}
+
+	// A file added before the cursor position does not shift later pages
+	first, _, _ := runFsListdir(t, bin, map[string]any{"path": base, "pageSize": 3})
+	if err := os.WriteFile(filepath.Join(base, "0.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	second, _, _ := runFsListdir(t, bin, map[string]any{"path": base, "pageSize": 3, "cursor": first.NextCursor})
+	if len(second.Entries) != 3 || filepath.Base(second.Entries[0].Path) != "c.md" || *second.TotalMatched != 7 {
+		t.Fatalf("second page=%+v", second)
+	}
+}
@@ -23,8 +23,10 @@ type fsListdirEntry struct {
 }
 
 type fsListdirOutput struct {
-	Entries   []fsListdirEntry `json:"entries"`
-	Truncated bool             `json:"truncated"`
+	Entries      []fsListdirEntry `json:"entries"`
+	Truncated    bool             `json:"truncated"`
+	NextCursor   string           `json:"nextCursor"`
+	TotalMatched *int             `json:"totalMatched"`
 }
 
 // Build via shared helper in tools/testutil.
@@ -174,3 +176,34 @@ func TestFsListdir_ErrorJSON_PathRequired(t *testing.T) {
 		t.Fatalf("stderr JSON missing 'error' field: %v", payload)
 	}
 }
+
+// TestFsListdir_NamedRoot verifies a "root:" path lists the named workspace
+// root and reports entries with the same prefix.
+func TestFsListdir_NamedRoot(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "pkg"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "pkg", "x.go"), []byte("package pkg\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	t.Setenv("GOAGENT_WORKSPACE_ROOTS", "api="+dir)
+
+	bin := testutil.BuildTool(t, "fs_listdir")
+
+	out, stderr, code := runFsListdir(t, bin, map[string]any{"path": "api:pkg"})
+	if code != 0 {
+		t.Fatalf("expected success, got exit=%d stderr=%q", code, stderr)
+	}
+	if len(out.Entries) != 1 || out.Entries[0].Path != "api:pkg/x.go" {
+		t.Fatalf("entries=%+v", out.Entries)
+	}
+
+	cmd := exec.Command(bin)
+	cmd.Stdin = strings.NewReader(`{"path":"web:pkg"}`)
+	var errBuf bytes.Buffer
+	cmd.Stderr = &errBuf
+	if err := cmd.Run(); err == nil || !strings.Contains(errBuf.String(), "UNKNOWN_ROOT") {
+		t.Fatalf("expected UNKNOWN_ROOT, got err=%v stderr=%q", err, errBuf.String())
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/hyperifyio/goagent/tools/workspace"
 )
 
 type mkdirpInput struct {
@@ -25,6 +27,10 @@ func main() {
 		stderrJSON(err)
 		os.Exit(1)
 	}
+	if _, in.Path, err = workspace.Enter(in.Path); err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
 	if err := validatePath(in.Path); err != nil {
 		stderrJSON(err)
 		os.Exit(1)
@@ -69,7 +75,7 @@ func validatePath(p string) error {
 		return fmt.Errorf("ABSOLUTE_PATH: %s", p)
 	}
 	clean := filepath.ToSlash(filepath.Clean(p))
-	if strings.HasPrefix(clean, "../") || strings.Contains(clean, "/../") {
+	if clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(clean, "/../") {
 		return fmt.Errorf("PATH_ESCAPE: %s", p)
 	}
 	return nil
@@ -8,6 +8,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/hyperifyio/goagent/tools/workspace"
 )
 
 type moveInput struct {
@@ -26,6 +28,26 @@ func main() {
 		stderrJSON(err)
 		os.Exit(1)
 	}
+	// Both paths must lie in the same workspace root, which the move runs in
+	fromRoot, from, err := workspace.Split(in.From)
+	if err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	toRoot, to, err := workspace.Split(in.To)
+	if err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	if fromRoot != toRoot {
+		stderrJSON(fmt.Errorf("CROSS_ROOT: from and to must be in the same workspace root"))
+		os.Exit(1)
+	}
+	if err := workspace.Chdir(fromRoot); err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	in.From, in.To = from, to
 	if err := validatePath(in.From); err != nil {
 		stderrJSON(err)
 		os.Exit(1)
@@ -65,7 +87,7 @@ func validatePath(p string) error {
 		return fmt.Errorf("ABSOLUTE_PATH: %s", p)
 	}
 	clean := filepath.ToSlash(filepath.Clean(p))
-	if strings.HasPrefix(clean, "../") || strings.Contains(clean, "/../") {
+	if clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(clean, "/../") {
 		return fmt.Errorf("PATH_ESCAPE: %s", p)
 	}
 	return nil
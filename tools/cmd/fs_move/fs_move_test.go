@@ -177,3 +177,36 @@ func TestFsMove_ErrorJSONContract(t *testing.T) {
 		t.Fatalf("stderr JSON missing 'error' key: %v", obj)
 	}
 }
+
+// TestFsMove_NamedRoot verifies both paths resolve in their named root and
+// that escapes, unknown roots, and moves across roots are refused.
+func TestFsMove_NamedRoot(t *testing.T) {
+	bin := buildFsMoveTool(t)
+	api, web := t.TempDir(), t.TempDir()
+	t.Setenv("GOAGENT_WORKSPACE_ROOTS", "api="+api+string(os.PathListSeparator)+"web="+web)
+	if err := os.WriteFile(filepath.Join(api, "a.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatalf("seed write: %v", err)
+	}
+
+	if out, stderr, code := runFsMove(t, bin, map[string]any{"from": "api:a.txt", "to": "api:b.txt"}); code != 0 || !out.Moved {
+		t.Fatalf("expected success, got exit=%d stderr=%q", code, stderr)
+	}
+	if _, err := os.Stat(filepath.Join(api, "b.txt")); err != nil {
+		t.Fatalf("moved file in root: %v", err)
+	}
+	cases := []struct{ from, to, want string }{
+		{"api:b.txt", "api:../../b.txt", "PATH_ESCAPE"},
+		{"api:../../b.txt", "api:c.txt", "PATH_ESCAPE"},
+		{"docs:b.txt", "docs:c.txt", "UNKNOWN_ROOT"},
+		{"api:b.txt", "web:b.txt", "CROSS_ROOT"},
+		{"api:b.txt", "b.txt", "CROSS_ROOT"},
+	}
+	for _, tc := range cases {
+		if _, stderr, code := runFsMove(t, bin, map[string]any{"from": tc.from, "to": tc.to}); code == 0 || !strings.Contains(stderr, tc.want) {
+			t.Fatalf("%s -> %s: expected %s, got exit=%d stderr=%q", tc.from, tc.to, tc.want, code, stderr)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(api, "b.txt")); err != nil {
+		t.Fatalf("refused moves touched the file: %v", err)
+	}
+}
@@ -9,22 +9,31 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/hyperifyio/goagent/tools/workspace"
 )
 
 // inputSpec models the stdin JSON contract for fs_read_file.
-// {"path":"string","offsetBytes?:int,"maxBytes?:int}
+// {"path":"string","offsetBytes?:int,"maxBytes?:int,"asText?":bool,
+// "encoding?":"string","startLine?":int,"maxLines?":int,"cursor?":"string"}
 type inputSpec struct {
 	Path        string `json:"path"`
 	OffsetBytes int64  `json:"offsetBytes"`
 	MaxBytes    int64  `json:"maxBytes"`
+	AsText      bool   `json:"asText"`
+	Encoding    string `json:"encoding"`
+	StartLine   int    `json:"startLine"`
+	MaxLines    int    `json:"maxLines"`
+	Cursor      string `json:"cursor"`
 }
 
 // outputSpec is the stdout JSON contract on success.
-// {"contentBase64":"string","sizeBytes":int,"eof":bool}
+// {"contentBase64":"string","sizeBytes":int,"eof":bool,"nextCursor?":"string"}
 type outputSpec struct {
 	ContentBase64 string `json:"contentBase64"`
 	SizeBytes     int64  `json:"sizeBytes"`
 	EOF           bool   `json:"eof"`
+	NextCursor    string `json:"nextCursor,omitempty"`
 }
 
 func main() {
@@ -60,6 +69,9 @@ func run() error {
 	if strings.TrimSpace(in.Path) == "" {
 		return fmt.Errorf("path is required")
 	}
+	if _, in.Path, err = workspace.Enter(in.Path); err != nil {
+		return err
+	}
 	// Enforce repo-relative paths: disallow absolute and path escape above CWD.
 	if filepath.IsAbs(in.Path) {
 		return fmt.Errorf("path must be relative to repository root")
@@ -71,6 +83,18 @@ func run() error {
 	if in.OffsetBytes < 0 {
 		return fmt.Errorf("offsetBytes must be >= 0")
 	}
+	if in.StartLine < 0 || in.MaxLines < 0 {
+		return fmt.Errorf("startLine and maxLines must be >= 0")
+	}
+	if !in.AsText && (in.StartLine > 0 || in.MaxLines > 0 || in.Encoding != "") {
+		return fmt.Errorf("startLine, maxLines, and encoding require asText")
+	}
+	if in.StartLine > 0 && in.OffsetBytes > 0 {
+		return fmt.Errorf("startLine cannot be combined with offsetBytes")
+	}
+	if in.AsText && in.MaxBytes > 0 && in.MaxBytes < 4 {
+		return fmt.Errorf("maxBytes must be >= 4 with asText")
+	}
 	// Open and stat to determine file size.
 	f, err := os.Open(clean)
 	if err != nil {
@@ -87,6 +111,32 @@ func run() error {
 	}
 	size := info.Size()
 
+	// A continuation token resumes the read it came from
+	var cur *cursor
+	if in.Cursor != "" {
+		c, err := decodeCursor(in.Cursor, info)
+		if err != nil {
+			_ = f.Close() //nolint:errcheck // already failing
+			return err
+		}
+		if c.AsText != in.AsText {
+			_ = f.Close() //nolint:errcheck // already failing
+			return fmt.Errorf("cursor was issued for asText=%v", c.AsText)
+		}
+		cur = &c
+		in.OffsetBytes = c.Offset
+	}
+	if in.AsText {
+		out, err := readText(f, info, in, cur)
+		if cerr := f.Close(); err == nil && cerr != nil {
+			err = fmt.Errorf("close: %w", cerr)
+		}
+		if err != nil {
+			return err
+		}
+		return writeJSON(out)
+	}
+
 	// If offset beyond end, return empty content with eof=true.
 	if in.OffsetBytes >= size {
 		out := outputSpec{ContentBase64: "", SizeBytes: size, EOF: true}
@@ -127,6 +177,9 @@ func run() error {
 	}
 	eof := in.OffsetBytes+readTotal >= size
 	out := outputSpec{ContentBase64: base64.StdEncoding.EncodeToString(buf[:readTotal]), SizeBytes: size, EOF: eof}
+	if !eof {
+		out.NextCursor = encodeCursor(cursor{Offset: in.OffsetBytes + readTotal, Size: size, ModTime: info.ModTime().UnixNano()})
+	}
 	return writeJSON(out)
 }
 
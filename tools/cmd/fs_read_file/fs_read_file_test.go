@@ -181,3 +181,119 @@ func TestFsRead_ErrorJSON(t *testing.T) {
 		t.Fatalf("stderr JSON missing 'error' key: %v", obj)
 	}
 }
+
+type fsReadTextOutput struct {
+	Content    string `json:"content"`
+	Encoding   string `json:"encoding"`
+	EOF        bool   `json:"eof"`
+	StartLine  int    `json:"startLine"`
+	EndLine    int    `json:"endLine"`
+	NextCursor string `json:"nextCursor"`
+}
+
+// runFsReadText runs the tool in asText mode and decodes its output.
+func runFsReadText(t *testing.T, bin string, input map[string]any) fsReadTextOutput {
+	t.Helper()
+	input["asText"] = true
+	data, err := json.Marshal(input)
+	if err != nil {
+		t.Fatalf("marshal input: %v", err)
+	}
+	cmd := exec.Command(bin)
+	cmd.Stdin = bytes.NewReader(data)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("run: %v stderr=%q", err, stderr.String())
+	}
+	var out fsReadTextOutput
+	if err := json.Unmarshal(stdout, &out); err != nil {
+		t.Fatalf("unmarshal stdout: %v; raw=%q", err, stdout)
+	}
+	return out
+}
+
+func TestFsRead_TextPagesWithCursor(t *testing.T) {
+	bin := testutil.BuildTool(t, "fs_read_file")
+	path := makeRepoRelTempFile(t, "fsread-page-", []byte("one\ntwo\nthree\nfour\nfive"))
+	out := runFsReadText(t, bin, map[string]any{"path": path, "maxLines": 2})
+	if out.Content != "one\ntwo\n" || out.Encoding != "utf-8" || out.StartLine != 1 || out.EndLine != 2 || out.EOF || out.NextCursor == "" {
+		t.Fatalf("page 1: %+v", out)
+	}
+	out = runFsReadText(t, bin, map[string]any{"path": path, "cursor": out.NextCursor, "maxBytes": 8})
+	if out.Content != "three\nfo" || out.StartLine != 3 || out.EndLine != 4 || out.EOF {
+		t.Fatalf("page 2: %+v", out)
+	}
+	out = runFsReadText(t, bin, map[string]any{"path": path, "cursor": out.NextCursor})
+	if out.Content != "ur\nfive" || out.StartLine != 4 || out.EndLine != 5 || !out.EOF || out.NextCursor != "" {
+		t.Fatalf("page 3: %+v", out)
+	}
+	out = runFsReadText(t, bin, map[string]any{"path": path, "startLine": 4, "maxLines": 1})
+	if out.Content != "four\n" || out.StartLine != 4 || out.EndLine != 4 {
+		t.Fatalf("startLine: %+v", out)
+	}
+}
+
+func TestFsRead_TextDetectsEncoding(t *testing.T) {
+	bin := testutil.BuildTool(t, "fs_read_file")
+	utf16 := []byte{0xFF, 0xFE, 'h', 0, 0xE4, 0, '\n', 0, 0x3D, 0xD8, 0x00, 0xDE}
+	cases := []struct {
+		data []byte
+		enc  string
+		want string
+	}{
+		{[]byte("\xEF\xBB\xBFpäivää"), "utf-8", "päivää"},
+		{utf16, "utf-16le", "hä\n😀"},
+		{[]byte{'c', 'a', 'f', 0xE9}, "latin1", "café"},
+	}
+	for _, tc := range cases {
+		path := makeRepoRelTempFile(t, "fsread-enc-", tc.data)
+		out := runFsReadText(t, bin, map[string]any{"path": path})
+		if out.Encoding != tc.enc || out.Content != tc.want || !out.EOF {
+			t.Errorf("%s: %+v", tc.enc, out)
+		}
+	}
+	// A chunk boundary never splits a surrogate pair
+	path := makeRepoRelTempFile(t, "fsread-enc-", utf16)
+	out := runFsReadText(t, bin, map[string]any{"path": path, "maxLines": 1})
+	if out.Content != "hä\n" {
+		t.Fatalf("line 1: %+v", out)
+	}
+	out = runFsReadText(t, bin, map[string]any{"path": path, "cursor": out.NextCursor, "maxBytes": 4})
+	if out.Content != "😀" || !out.EOF {
+		t.Fatalf("line 2: %+v", out)
+	}
+}
+
+func TestFsRead_CursorRejectedAfterChange(t *testing.T) {
+	bin := testutil.BuildTool(t, "fs_read_file")
+	path := makeRepoRelTempFile(t, "fsread-stale-", []byte("abcdefg"))
+	// runFsRead decodes only the base64 fields, so read the cursor directly
+	cmd := exec.Command(bin)
+	cmd.Stdin = strings.NewReader(`{"path":"` + path + `","maxBytes":3}`)
+	stdout, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	var page struct {
+		NextCursor string `json:"nextCursor"`
+	}
+	if err := json.Unmarshal(stdout, &page); err != nil || page.NextCursor == "" {
+		t.Fatalf("no cursor: %s", stdout)
+	}
+	next, stderr, code := runFsRead(t, bin, map[string]any{"path": path, "cursor": page.NextCursor})
+	if code != 0 {
+		t.Fatalf("resume: exit=%d stderr=%q", code, stderr)
+	}
+	if b, _ := base64.StdEncoding.DecodeString(next.ContentBase64); string(b) != "defg" || !next.EOF {
+		t.Fatalf("resume: %q eof=%v", b, next.EOF)
+	}
+	if err := os.WriteFile(path, []byte("abcdefgh"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, stderr, code = runFsRead(t, bin, map[string]any{"path": path, "cursor": page.NextCursor})
+	if code == 0 || !strings.Contains(stderr, "STALE_CURSOR") {
+		t.Fatalf("stale cursor accepted: exit=%d stderr=%q", code, stderr)
+	}
+}
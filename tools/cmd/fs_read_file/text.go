@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// defaultTextChunk is the raw byte budget of an asText read without
+// maxBytes, so paging a huge file never holds more than this in memory.
+const defaultTextChunk = 64 * 1024
+
+// Encodings understood by asText reads.
+const (
+	encUTF8    = "utf-8"
+	encUTF16LE = "utf-16le"
+	encUTF16BE = "utf-16be"
+	encLatin1  = "latin1"
+)
+
+// textOutputSpec is the stdout JSON contract of an asText read.
+type textOutputSpec struct {
+	Content    string `json:"content"`
+	Encoding   string `json:"encoding"`
+	SizeBytes  int64  `json:"sizeBytes"`
+	EOF        bool   `json:"eof"`
+	StartLine  int    `json:"startLine,omitempty"`
+	EndLine    int    `json:"endLine,omitempty"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// cursor is the state behind a continuation token. Size and ModTime pin the
+// file version so a token is refused once the file changes.
+type cursor struct {
+	Offset   int64  `json:"o"`
+	Line     int    `json:"l,omitempty"`
+	AsText   bool   `json:"t,omitempty"`
+	Encoding string `json:"e,omitempty"`
+	Size     int64  `json:"s"`
+	ModTime  int64  `json:"m"`
+}
+
+func encodeCursor(c cursor) string {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(token string, info os.FileInfo) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || json.Unmarshal(b, &c) != nil || c.Offset < 0 {
+		return c, errors.New("invalid cursor")
+	}
+	if c.Size != info.Size() || c.ModTime != info.ModTime().UnixNano() {
+		return c, errors.New("STALE_CURSOR: file changed since the cursor was issued; start over without cursor")
+	}
+	return c, nil
+}
+
+// normalizeEncoding maps accepted spellings to the canonical names; "" and
+// "auto" ask for detection.
+func normalizeEncoding(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "auto":
+		return "", nil
+	case "utf-8", "utf8":
+		return encUTF8, nil
+	case "utf-16le", "utf16le":
+		return encUTF16LE, nil
+	case "utf-16be", "utf16be":
+		return encUTF16BE, nil
+	case "latin1", "latin-1", "iso-8859-1":
+		return encLatin1, nil
+	}
+	return "", fmt.Errorf("unsupported encoding %q (want auto, utf-8, utf-16le, utf-16be, or latin1)", s)
+}
+
+// detectEncoding sniffs the start of the file: a byte order mark wins, then
+// valid UTF-8, then NUL bytes concentrated on odd or even positions (UTF-16
+// without a BOM), and latin1 otherwise. It returns the BOM length to skip.
+func detectEncoding(f *os.File) (enc string, bom int, err error) {
+	head := make([]byte, 4096)
+	n, err := f.ReadAt(head, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", 0, fmt.Errorf("read: %w", err)
+	}
+	head = head[:n]
+	switch {
+	case bytes.HasPrefix(head, []byte{0xEF, 0xBB, 0xBF}):
+		return encUTF8, 3, nil
+	case bytes.HasPrefix(head, []byte{0xFF, 0xFE}):
+		return encUTF16LE, 2, nil
+	case bytes.HasPrefix(head, []byte{0xFE, 0xFF}):
+		return encUTF16BE, 2, nil
+	}
+	var evenNUL, oddNUL int
+	for i, b := range head {
+		if b == 0 {
+			if i%2 == 0 {
+				evenNUL++
+			} else {
+				oddNUL++
+			}
+		}
+	}
+	if pairs := len(head) / 2; pairs > 0 {
+		if oddNUL*3 > pairs && evenNUL*10 < pairs {
+			return encUTF16LE, 0, nil
+		}
+		if evenNUL*3 > pairs && oddNUL*10 < pairs {
+			return encUTF16BE, 0, nil
+		}
+	}
+	// A multi-byte character cut by the sniff window is still UTF-8
+	trimmed := head
+	for i := 0; i < utf8.UTFMax-1 && len(trimmed) > 0 && n == 4096 && !utf8.Valid(trimmed); i++ {
+		trimmed = trimmed[:len(trimmed)-1]
+	}
+	if utf8.Valid(trimmed) {
+		return encUTF8, 0, nil
+	}
+	return encLatin1, 0, nil
+}
+
+// lineReader returns raw lines in one encoding without ever buffering more
+// than the requested limit, splitting long lines on character boundaries.
+type lineReader struct {
+	r   *bufio.Reader
+	enc string
+	pos int64
+}
+
+// next returns up to limit raw bytes ending at the next line terminator
+// (included, complete true) or at limit. io.EOF is returned at end of file.
+func (lr *lineReader) next(limit int) (raw []byte, complete bool, err error) {
+	for len(raw) < limit {
+		want := limit - len(raw)
+		if want > 4096 {
+			want = 4096
+		}
+		p, perr := lr.r.Peek(want)
+		if len(p) == 0 {
+			return raw, false, io.EOF
+		}
+		if i := lr.newline(p); i >= 0 {
+			raw = append(raw, p[:i]...)
+			lr.discard(i)
+			return raw, true, nil
+		}
+		k := len(p)
+		if perr == nil {
+			k = lr.boundary(p)
+		}
+		if k == 0 {
+			break
+		}
+		raw = append(raw, p[:k]...)
+		lr.discard(k)
+		if perr != nil {
+			return raw, false, io.EOF
+		}
+	}
+	return raw, false, nil
+}
+
+func (lr *lineReader) discard(n int) {
+	_, _ = lr.r.Discard(n) //nolint:errcheck // n bytes were just peeked
+	lr.pos += int64(n)
+}
+
+// newline returns the length through the first line terminator in p, or -1.
+func (lr *lineReader) newline(p []byte) int {
+	switch lr.enc {
+	case encUTF16LE, encUTF16BE:
+		for i := 0; i+1 < len(p); i += 2 {
+			if (lr.enc == encUTF16LE && p[i] == '\n' && p[i+1] == 0) || (lr.enc == encUTF16BE && p[i] == 0 && p[i+1] == '\n') {
+				return i + 2
+			}
+		}
+		return -1
+	default:
+		if i := bytes.IndexByte(p, '\n'); i >= 0 {
+			return i + 1
+		}
+		return -1
+	}
+}
+
+// boundary returns the longest prefix of p that does not end inside a
+// character.
+func (lr *lineReader) boundary(p []byte) int {
+	switch lr.enc {
+	case encLatin1:
+		return len(p)
+	case encUTF16LE, encUTF16BE:
+		k := len(p) &^ 1
+		if k >= 2 {
+			u := lr.unit(p[k-2:])
+			if u >= 0xD800 && u <= 0xDBFF {
+				k -= 2
+			}
+		}
+		return k
+	default:
+		for k := len(p); k > 0 && k > len(p)-utf8.UTFMax; k-- {
+			if utf8.RuneStart(p[k-1]) {
+				if utf8.FullRune(p[k-1:]) {
+					return len(p)
+				}
+				return k - 1
+			}
+		}
+		return len(p)
+	}
+}
+
+func (lr *lineReader) unit(b []byte) uint16 {
+	if lr.enc == encUTF16BE {
+		return uint16(b[0])<<8 | uint16(b[1])
+	}
+	return uint16(b[1])<<8 | uint16(b[0])
+}
+
+// decode converts raw bytes in the reader's encoding to UTF-8 text; invalid
+// sequences become U+FFFD.
+func (lr *lineReader) decode(raw []byte) string {
+	switch lr.enc {
+	case encLatin1:
+		rs := make([]rune, len(raw))
+		for i, b := range raw {
+			rs[i] = rune(b)
+		}
+		return string(rs)
+	case encUTF16LE, encUTF16BE:
+		units := make([]uint16, 0, len(raw)/2)
+		for i := 0; i+1 < len(raw); i += 2 {
+			units = append(units, lr.unit(raw[i:]))
+		}
+		return string(utf16.Decode(units))
+	default:
+		return strings.ToValidUTF8(string(raw), "�")
+	}
+}
+
+// readText serves an asText read: it starts at offset (line number line,
+// or 0 when unknown), skips ahead to startLine, and returns at most
+// maxBytes raw bytes and maxLines lines.
+func readText(f *os.File, info os.FileInfo, in inputSpec, c *cursor) (textOutputSpec, error) {
+	size := info.Size()
+	enc, err := normalizeEncoding(in.Encoding)
+	if err != nil {
+		return textOutputSpec{}, err
+	}
+	offset, line := in.OffsetBytes, 0
+	if offset == 0 {
+		line = 1
+	}
+	if c != nil {
+		offset, line, enc = c.Offset, c.Line, c.Encoding
+	}
+	if enc == "" {
+		detected, bom, err := detectEncoding(f)
+		if err != nil {
+			return textOutputSpec{}, err
+		}
+		enc = detected
+		if offset == 0 {
+			offset = int64(bom)
+		}
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return textOutputSpec{}, fmt.Errorf("seek: %w", err)
+	}
+	lr := &lineReader{r: bufio.NewReaderSize(f, 64*1024), enc: enc, pos: offset}
+	budget := int(in.MaxBytes)
+	if budget <= 0 {
+		budget = defaultTextChunk
+	}
+	// Skip to the requested line without keeping the skipped text
+	for c == nil && in.StartLine > line && line > 0 {
+		_, complete, err := lr.next(budget)
+		if complete {
+			line++
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return textOutputSpec{}, fmt.Errorf("read: %w", err)
+		}
+	}
+	out := textOutputSpec{Encoding: enc, SizeBytes: size, StartLine: line}
+	var text strings.Builder
+	used, lines := 0, 0
+	for used < budget && (in.MaxLines <= 0 || lines < in.MaxLines) {
+		raw, complete, err := lr.next(budget - used)
+		used += len(raw)
+		text.WriteString(lr.decode(raw))
+		if len(raw) > 0 && line > 0 {
+			out.EndLine = line
+		}
+		if complete {
+			lines++
+			if line > 0 {
+				line++
+			}
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return textOutputSpec{}, fmt.Errorf("read: %w", err)
+		}
+		if len(raw) == 0 && !complete {
+			break
+		}
+	}
+	out.Content = text.String()
+	out.EOF = lr.pos >= size
+	if !out.EOF {
+		out.NextCursor = encodeCursor(cursor{Offset: lr.pos, Line: line, AsText: true, Encoding: enc, Size: size, ModTime: info.ModTime().UnixNano()})
+	}
+	return out, nil
+}
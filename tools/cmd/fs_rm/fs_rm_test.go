@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/hyperifyio/goagent/tools/testutil"
@@ -148,3 +149,37 @@ func TestFsRm_ForceOnMissing(t *testing.T) {
 		t.Fatalf("expected path to be absent, stat err=%v", err)
 	}
 }
+
+// TestFsRm_NamedRoot verifies a "root:" path removes inside the named
+// workspace root and that escapes and unknown roots are refused.
+func TestFsRm_NamedRoot(t *testing.T) {
+	bin := testutil.BuildTool(t, "fs_rm")
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "api")
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "x.txt"), []byte("data"), 0o644); err != nil {
+		t.Fatalf("seed file: %v", err)
+	}
+	t.Setenv("GOAGENT_WORKSPACE_ROOTS", "api="+dir)
+
+	if out, stderr, code := runFsRm(t, bin, map[string]any{"path": "api:sub/x.txt"}); code != 0 || !out.Removed {
+		t.Fatalf("expected success, got exit=%d stderr=%q", code, stderr)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sub", "x.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected file in root removed, stat err=%v", err)
+	}
+	for path, want := range map[string]string{"api:../..": "PATH_ESCAPE", "api:..": "PATH_ESCAPE", "web:sub": "UNKNOWN_ROOT"} {
+		cmd := exec.Command(bin)
+		cmd.Stdin = strings.NewReader(`{"path":"` + path + `","recursive":true}`)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err == nil || !strings.Contains(stderr.String(), want) {
+			t.Fatalf("%s: expected %s, got err=%v stderr=%q", path, want, err, stderr.String())
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sub")); err != nil {
+		t.Fatalf("refused removals touched the root: %v", err)
+	}
+}
@@ -11,6 +11,8 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+
+	"github.com/hyperifyio/goagent/tools/workspace"
 )
 
 type searchInput struct {
@@ -18,6 +20,8 @@ type searchInput struct {
 	Regex      bool     `json:"regex,omitempty"`
 	Globs      []string `json:"globs,omitempty"`
 	MaxResults int      `json:"maxResults,omitempty"`
+	// Root names the workspace root to search; the default root when empty
+	Root string `json:"root,omitempty"`
 }
 
 type match struct {
@@ -42,11 +46,19 @@ func main() {
 		stderrJSON(err)
 		os.Exit(1)
 	}
+	if err := workspace.Chdir(in.Root); err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
 	matches, truncated, err := search(in)
 	if err != nil {
 		stderrJSON(err)
 		os.Exit(1)
 	}
+	// Matches in a named root carry its prefix so they can be passed back
+	for i := range matches {
+		matches[i].Path = workspace.Display(in.Root, matches[i].Path)
+	}
 	if err := json.NewEncoder(os.Stdout).Encode(searchOutput{Matches: matches, Truncated: truncated}); err != nil {
 		stderrJSON(fmt.Errorf("encode json: %w", err))
 		os.Exit(1)
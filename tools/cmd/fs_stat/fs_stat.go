@@ -8,14 +8,20 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
+
+	"github.com/hyperifyio/goagent/tools/workspace"
 )
 
 type statInput struct {
 	Path           string `json:"path"`
 	FollowSymlinks bool   `json:"followSymlinks,omitempty"`
 	Hash           string `json:"hash,omitempty"`
+	// Git adds the path's git status; Xattrs adds extended attributes.
+	Git    bool `json:"git,omitempty"`
+	Xattrs bool `json:"xattrs,omitempty"`
 }
 
 type statOutput struct {
@@ -25,6 +31,26 @@ type statOutput struct {
 	ModeOctal string `json:"modeOctal"`
 	ModTime   string `json:"modTime"`
 	Sha256    string `json:"sha256,omitempty"`
+	UID       *int   `json:"uid,omitempty"`
+	GID       *int   `json:"gid,omitempty"`
+	Owner     string `json:"owner,omitempty"`
+	Group     string `json:"group,omitempty"`
+	// For a symlink (not followed): its target as stored, the target's
+	// path after resolving every link, and whether the target is missing.
+	SymlinkTarget   string            `json:"symlinkTarget,omitempty"`
+	SymlinkResolved string            `json:"symlinkResolved,omitempty"`
+	SymlinkBroken   bool              `json:"symlinkBroken,omitempty"`
+	Git             *gitInfo          `json:"git,omitempty"`
+	Xattrs          map[string]string `json:"xattrs,omitempty"`
+}
+
+// gitInfo is the path's state in the enclosing git work tree.
+type gitInfo struct {
+	Tracked bool `json:"tracked"`
+	// Status is clean, modified, added, deleted, renamed, conflicted,
+	// untracked, or ignored; for a directory it summarizes its contents.
+	Status string `json:"status"`
+	Staged bool   `json:"staged"`
 }
 
 func main() {
@@ -33,6 +59,10 @@ func main() {
 		stderrJSON(err)
 		os.Exit(1)
 	}
+	if _, in.Path, err = workspace.Enter(in.Path); err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
 	if err := validatePath(in.Path); err != nil {
 		stderrJSON(err)
 		os.Exit(1)
@@ -68,7 +98,7 @@ func validatePath(p string) error {
 		return fmt.Errorf("ABSOLUTE_PATH: %s", p)
 	}
 	clean := filepath.ToSlash(filepath.Clean(p))
-	if strings.HasPrefix(clean, "../") || strings.Contains(clean, "/../") {
+	if clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(clean, "/../") {
 		return fmt.Errorf("PATH_ESCAPE: %s", p)
 	}
 	return nil
@@ -105,6 +135,18 @@ func statPath(in statInput) (statOutput, error) {
 		ModeOctal: fmt.Sprintf("%04o", mode.Perm()),
 		ModTime:   fi.ModTime().UTC().Format("2006-01-02T15:04:05Z07:00"),
 	}
+	fillOwner(&out, fi)
+	if typeStr == "symlink" {
+		fillSymlink(&out, in.Path)
+	}
+	if in.Git {
+		out.Git = gitStatus(in.Path)
+	}
+	if in.Xattrs && typeStr != "symlink" {
+		if attrs, err := readXattrs(in.Path); err == nil {
+			out.Xattrs = attrs
+		}
+	}
 	if in.Hash == "sha256" && typeStr == "file" {
 		data, err := os.ReadFile(in.Path)
 		if err == nil {
@@ -115,6 +157,96 @@ func statPath(in statInput) (statOutput, error) {
 	return out, nil
 }
 
+// fillSymlink records where the link at path points. A resolved target
+// inside the working directory is reported relative to it.
+func fillSymlink(out *statOutput, path string) {
+	target, err := os.Readlink(path)
+	if err != nil {
+		return
+	}
+	out.SymlinkTarget = target
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		out.SymlinkBroken = true
+		return
+	}
+	out.SymlinkResolved = resolved
+	if abs, err := filepath.Abs(resolved); err == nil {
+		if wd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(wd, abs); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				out.SymlinkResolved = filepath.ToSlash(rel)
+			} else {
+				out.SymlinkResolved = abs
+			}
+		}
+	}
+}
+
+// gitStatus reports path's git state, or nil when git is unavailable or the
+// path is outside a work tree.
+func gitStatus(path string) *gitInfo {
+	tracked, err := exec.Command("git", "ls-files", "-z", "--", path).Output()
+	if err != nil {
+		return nil
+	}
+	status, err := exec.Command("git", "status", "--porcelain=v1", "-z", "--ignored", "--", path).Output()
+	if err != nil {
+		return nil
+	}
+	info := &gitInfo{Tracked: len(tracked) > 0, Status: "clean"}
+	var codes []string
+	recs := strings.Split(strings.TrimRight(string(status), "\x00"), "\x00")
+	for i := 0; i < len(recs); i++ {
+		if len(recs[i]) < 4 {
+			continue
+		}
+		codes = append(codes, recs[i][:2])
+		// A rename or copy record is followed by the original path
+		if recs[i][0] == 'R' || recs[i][0] == 'C' {
+			i++
+		}
+	}
+	for _, c := range codes {
+		st := gitCodeStatus(c)
+		info.Staged = info.Staged || (st != "untracked" && st != "ignored" && c[0] != ' ')
+		if len(codes) == 1 {
+			info.Status = st
+			continue
+		}
+		// A directory: changes to tracked files win over untracked ones,
+		// which win over ignored ones
+		switch {
+		case st != "untracked" && st != "ignored":
+			info.Status = "modified"
+		case st == "untracked" && info.Status != "modified":
+			info.Status = "untracked"
+		case info.Status == "clean":
+			info.Status = "ignored"
+		}
+	}
+	return info
+}
+
+// gitCodeStatus maps a porcelain XY code to a status name.
+func gitCodeStatus(c string) string {
+	switch {
+	case c == "??":
+		return "untracked"
+	case c == "!!":
+		return "ignored"
+	case strings.ContainsRune(c, 'U') || c == "AA" || c == "DD":
+		return "conflicted"
+	case c[0] == 'R':
+		return "renamed"
+	case strings.ContainsRune(c, 'D'):
+		return "deleted"
+	case c[0] == 'A':
+		return "added"
+	default:
+		return "modified"
+	}
+}
+
 func stderrJSON(err error) {
 	msg := err.Error()
 	msg = strings.ReplaceAll(msg, "\n", " ")
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/hyperifyio/goagent/tools/testutil"
+)
+
+type fsStatExtendedOutput struct {
+	Type            string `json:"type"`
+	UID             *int   `json:"uid"`
+	Owner           string `json:"owner"`
+	SymlinkTarget   string `json:"symlinkTarget"`
+	SymlinkResolved string `json:"symlinkResolved"`
+	SymlinkBroken   bool   `json:"symlinkBroken"`
+	Git             *struct {
+		Tracked bool   `json:"tracked"`
+		Status  string `json:"status"`
+		Staged  bool   `json:"staged"`
+	} `json:"git"`
+}
+
+// statIn runs the tool in dir and decodes the extended fields.
+func statIn(t *testing.T, bin, dir string, input map[string]any) fsStatExtendedOutput {
+	t.Helper()
+	data, err := json.Marshal(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(bin)
+	cmd.Dir = dir
+	cmd.Stdin = bytes.NewReader(data)
+	stdout, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	var out fsStatExtendedOutput
+	if err := json.Unmarshal(stdout, &out); err != nil {
+		t.Fatalf("unmarshal %q: %v", stdout, err)
+	}
+	return out
+}
+
+func TestFsStat_OwnerAndSymlinkTarget(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uid and symlinks are POSIX-specific")
+	}
+	bin := testutil.BuildTool(t, "fs_stat")
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "f.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("f.txt", filepath.Join(dir, "ok.lnk")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("missing.txt", filepath.Join(dir, "broken.lnk")); err != nil {
+		t.Fatal(err)
+	}
+	out := statIn(t, bin, dir, map[string]any{"path": "f.txt"})
+	if out.UID == nil || *out.UID != os.Getuid() {
+		t.Fatalf("uid=%v want %d", out.UID, os.Getuid())
+	}
+	out = statIn(t, bin, dir, map[string]any{"path": "ok.lnk"})
+	if out.Type != "symlink" || out.SymlinkTarget != "f.txt" || out.SymlinkResolved != "f.txt" || out.SymlinkBroken {
+		t.Fatalf("ok.lnk: %+v", out)
+	}
+	out = statIn(t, bin, dir, map[string]any{"path": "broken.lnk"})
+	if out.SymlinkTarget != "missing.txt" || !out.SymlinkBroken || out.SymlinkResolved != "" {
+		t.Fatalf("broken.lnk: %+v", out)
+	}
+}
+
+func TestFsStat_GitStatus(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	bin := testutil.BuildTool(t, "fs_stat")
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v %s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	for name, body := range map[string]string{"clean.txt": "a", "changed.txt": "a", ".gitignore": "*.log\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("add", ".")
+	git("commit", "-q", "-m", "init")
+	for name, body := range map[string]string{"changed.txt": "b", "new.txt": "n", "debug.log": "l"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cases := map[string]struct {
+		tracked bool
+		status  string
+	}{
+		"clean.txt":   {true, "clean"},
+		"changed.txt": {true, "modified"},
+		"new.txt":     {false, "untracked"},
+		"debug.log":   {false, "ignored"},
+		".":           {true, "modified"},
+	}
+	for path, want := range cases {
+		out := statIn(t, bin, dir, map[string]any{"path": path, "git": true})
+		if out.Git == nil || out.Git.Tracked != want.tracked || out.Git.Status != want.status || out.Git.Staged {
+			t.Errorf("%s: git=%+v want %+v", path, out.Git, want)
+		}
+	}
+	if out := statIn(t, bin, t.TempDir(), map[string]any{"path": ".", "git": true}); out.Git != nil {
+		t.Fatalf("outside a work tree: %+v", out.Git)
+	}
+}
@@ -0,0 +1,27 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// fillOwner sets the numeric owner and group of fi and their names when
+// they resolve.
+func fillOwner(out *statOutput, fi os.FileInfo) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+	uid, gid := int(st.Uid), int(st.Gid)
+	out.UID, out.GID = &uid, &gid
+	if u, err := user.LookupId(strconv.Itoa(uid)); err == nil {
+		out.Owner = u.Username
+	}
+	if g, err := user.LookupGroupId(strconv.Itoa(gid)); err == nil {
+		out.Group = g.Name
+	}
+}
@@ -0,0 +1,8 @@
+//go:build windows
+
+package main
+
+import "os"
+
+// fillOwner is a no-op: Windows file ownership is not a uid/gid pair.
+func fillOwner(*statOutput, os.FileInfo) {}
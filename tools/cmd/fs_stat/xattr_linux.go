@@ -0,0 +1,49 @@
+//go:build linux
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"syscall"
+	"unicode/utf8"
+)
+
+// maxXattrs bounds how many attributes are reported.
+const maxXattrs = 64
+
+// readXattrs returns the extended attributes of path. Values that are not
+// valid UTF-8 are reported as "base64:<data>".
+func readXattrs(path string) (map[string]string, error) {
+	size, err := syscall.Listxattr(path, nil)
+	if err != nil || size == 0 {
+		return nil, err
+	}
+	buf := make([]byte, size)
+	size, err = syscall.Listxattr(path, buf)
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]string{}
+	for _, name := range bytes.Split(bytes.TrimRight(buf[:size], "\x00"), []byte{0}) {
+		if len(out) == maxXattrs {
+			break
+		}
+		n := string(name)
+		vsize, err := syscall.Getxattr(path, n, nil)
+		if err != nil {
+			continue
+		}
+		val := make([]byte, vsize)
+		if vsize, err = syscall.Getxattr(path, n, val); err != nil {
+			continue
+		}
+		val = val[:vsize]
+		if utf8.Valid(val) {
+			out[n] = string(val)
+		} else {
+			out[n] = "base64:" + base64.StdEncoding.EncodeToString(val)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// readXattrs is only implemented on Linux.
+func readXattrs(string) (map[string]string, error) {
+	return nil, errors.New("xattrs are not supported on this platform")
+}
@@ -158,3 +158,24 @@ func TestFsWrite_ErrorJSON_PathRequired(t *testing.T) {
 		t.Fatalf("stderr should contain JSON with 'error' field, got: %q", stderr)
 	}
 }
+
+// TestFsWrite_NamedRoot verifies a "root:" path writes inside the named
+// workspace root and that escapes and unknown roots are refused.
+func TestFsWrite_NamedRoot(t *testing.T) {
+	bin := testutil.BuildTool(t, "fs_write_file")
+	dir := t.TempDir()
+	t.Setenv("GOAGENT_WORKSPACE_ROOTS", "api="+dir)
+	content := base64.StdEncoding.EncodeToString([]byte("hi\n"))
+
+	if _, stderr, code := runFsWrite(t, bin, map[string]any{"path": "api:x.txt", "contentBase64": content}); code != 0 {
+		t.Fatalf("expected success, got exit=%d stderr=%q", code, stderr)
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "x.txt")); err != nil || string(b) != "hi\n" {
+		t.Fatalf("file in root: %q %v", b, err)
+	}
+	for path, want := range map[string]string{"api:../../x.txt": "PATH_ESCAPE", "api:..": "PATH_ESCAPE", "web:x.txt": "UNKNOWN_ROOT"} {
+		if _, stderr, code := runFsWrite(t, bin, map[string]any{"path": path, "contentBase64": content}); code == 0 || !strings.Contains(stderr, want) {
+			t.Fatalf("%s: expected %s, got exit=%d stderr=%q", path, want, code, stderr)
+		}
+	}
+}
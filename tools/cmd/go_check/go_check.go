@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hyperifyio/goagent/tools/workspace"
+)
+
+const (
+	defaultMaxFailureBytes = 4096
+	// maxFailures caps how many failing tests are reported in full
+	maxFailures = 20
+)
+
+type checkInput struct {
+	Build           *bool    `json:"build,omitempty"`
+	Test            *bool    `json:"test,omitempty"`
+	Packages        []string `json:"packages,omitempty"`
+	Dir             string   `json:"dir,omitempty"`
+	Run             string   `json:"run,omitempty"`
+	MaxFailureBytes int      `json:"maxFailureBytes,omitempty"`
+}
+
+type buildError struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Col     int    `json:"col,omitempty"`
+	Message string `json:"message"`
+}
+
+type buildResult struct {
+	OK     bool         `json:"ok"`
+	Errors []buildError `json:"errors,omitempty"`
+	// Output is the raw compiler output, kept for messages that do not
+	// follow the file:line:col form
+	Output string `json:"output,omitempty"`
+}
+
+type testFailure struct {
+	Package string `json:"package"`
+	Test    string `json:"test,omitempty"`
+	Output  string `json:"output"`
+}
+
+type testResult struct {
+	OK       bool          `json:"ok"`
+	Passed   int           `json:"passed"`
+	Failed   int           `json:"failed"`
+	Skipped  int           `json:"skipped"`
+	Failures []testFailure `json:"failures,omitempty"`
+	// Omitted counts failures beyond the maxFailures cap
+	Omitted int `json:"omitted,omitempty"`
+}
+
+type checkOutput struct {
+	OK    bool         `json:"ok"`
+	Build *buildResult `json:"build,omitempty"`
+	Test  *testResult  `json:"test,omitempty"`
+}
+
+func main() {
+	in, err := readInput(os.Stdin)
+	if err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	if _, in.Dir, err = workspace.Enter(in.Dir); err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	out, err := check(in)
+	if err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(out); err != nil {
+		stderrJSON(fmt.Errorf("write stdout: %w", err))
+		os.Exit(1)
+	}
+}
+
+func readInput(r io.Reader) (checkInput, error) {
+	var in checkInput
+	b, err := io.ReadAll(bufio.NewReader(r))
+	if err != nil {
+		return in, fmt.Errorf("read stdin: %w", err)
+	}
+	if err := json.Unmarshal(b, &in); err != nil {
+		return in, fmt.Errorf("parse json: %w", err)
+	}
+	return in, nil
+}
+
+// check runs the requested steps. Build and test failures are results, not
+// errors; an error means the go tool itself could not be run.
+func check(in checkInput) (checkOutput, error) {
+	build := in.Build == nil || *in.Build
+	test := in.Test == nil || *in.Test
+	if !build && !test {
+		return checkOutput{}, errors.New("nothing to do: build and test are both false")
+	}
+	dir := "."
+	if in.Dir != "" {
+		if err := validateRelPath(in.Dir); err != nil {
+			return checkOutput{}, err
+		}
+		dir = in.Dir
+	}
+	pkgs := in.Packages
+	if len(pkgs) == 0 {
+		pkgs = []string{"./..."}
+	}
+	for _, p := range pkgs {
+		if strings.HasPrefix(p, "-") {
+			return checkOutput{}, fmt.Errorf("invalid package pattern %q", p)
+		}
+	}
+	limit := in.MaxFailureBytes
+	if limit <= 0 {
+		limit = defaultMaxFailureBytes
+	}
+
+	out := checkOutput{OK: true}
+	if build {
+		res, err := runBuild(dir, pkgs, limit)
+		if err != nil {
+			return out, err
+		}
+		out.Build = &res
+		out.OK = res.OK
+	}
+	// Tests cannot pass when the build fails; skip them rather than repeat
+	// every compile error once per package
+	if test && out.OK {
+		res, err := runTests(dir, pkgs, in.Run, limit)
+		if err != nil {
+			return out, err
+		}
+		out.Test = &res
+		out.OK = res.OK
+	}
+	return out, nil
+}
+
+// runBuild compiles pkgs, discarding any binaries so the workspace is left
+// untouched.
+func runBuild(dir string, pkgs []string, limit int) (buildResult, error) {
+	args := append([]string{"build", "-o", os.DevNull}, pkgs...)
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	var combined bytes.Buffer
+	cmd.Stdout = &combined
+	cmd.Stderr = &combined
+	if err := cmd.Run(); err != nil {
+		var ee *exec.ExitError
+		if !errors.As(err, &ee) {
+			return buildResult{}, fmt.Errorf("run go build: %w", err)
+		}
+		return buildResult{Errors: parseBuildErrors(combined.String()), Output: truncateTail(combined.String(), limit)}, nil
+	}
+	return buildResult{OK: true}, nil
+}
+
+var buildErrorRe = regexp.MustCompile(`^(\S+\.go):(\d+)(?::(\d+))?: (.+)$`)
+
+// parseBuildErrors extracts file:line[:col]: message lines from compiler output.
+func parseBuildErrors(s string) []buildError {
+	var errs []buildError
+	for _, ln := range strings.Split(s, "\n") {
+		m := buildErrorRe.FindStringSubmatch(strings.TrimSpace(ln))
+		if m == nil {
+			continue
+		}
+		line, _ := strconv.Atoi(m[2]) //nolint:errcheck // regexp guarantees digits
+		col, _ := strconv.Atoi(m[3])  //nolint:errcheck // empty when absent
+		errs = append(errs, buildError{File: m[1], Line: line, Col: col, Message: m[4]})
+	}
+	return errs
+}
+
+// testEvent is one line of `go test -json` (see `go doc test2json`). Compile
+// errors in test files arrive as build-output events keyed by ImportPath and
+// are tied to the package's fail event through FailedBuild.
+type testEvent struct {
+	Action      string `json:"Action"`
+	Package     string `json:"Package"`
+	Test        string `json:"Test"`
+	Output      string `json:"Output"`
+	ImportPath  string `json:"ImportPath"`
+	FailedBuild string `json:"FailedBuild"`
+}
+
+func runTests(dir string, pkgs []string, run string, limit int) (testResult, error) {
+	args := []string{"test", "-json"}
+	if run != "" {
+		args = append(args, "-run", run)
+	}
+	args = append(args, pkgs...)
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	var ee *exec.ExitError
+	if err != nil && !errors.As(err, &ee) {
+		return testResult{}, fmt.Errorf("run go test: %w", err)
+	}
+	res := summarizeTests(&stdout, limit)
+	// A non-zero exit with no reported failure (for example a bad -run
+	// pattern) still fails the check; stderr explains why
+	if err != nil && res.Failed == 0 && len(res.Failures) == 0 {
+		res.Failures = append(res.Failures, testFailure{Output: truncateTail(stderr.String()+stdout.String(), limit)})
+	}
+	res.OK = err == nil && res.Failed == 0 && len(res.Failures) == 0
+	return res, nil
+}
+
+// summarizeTests counts test outcomes from test2json events and collects
+// the output of failed tests, and of failed packages without a failing test
+// (compile errors, panics in TestMain, timeouts). Non-JSON lines are ignored.
+func summarizeTests(r io.Reader, limit int) testResult {
+	var res testResult
+	type key struct{ pkg, test string }
+	output := map[key]string{}
+	buildOutput := map[string]string{}
+	pkgHasFailedTest := map[string]bool{}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		var ev testEvent
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil || ev.Action == "" {
+			continue
+		}
+		k := key{ev.Package, ev.Test}
+		switch ev.Action {
+		case "build-output":
+			buildOutput[ev.ImportPath] += ev.Output
+		case "output":
+			output[k] += ev.Output
+		case "pass":
+			if ev.Test != "" {
+				res.Passed++
+			}
+			delete(output, k)
+		case "skip":
+			if ev.Test != "" {
+				res.Skipped++
+			}
+			delete(output, k)
+		case "fail":
+			switch {
+			case ev.Test != "":
+				res.Failed++
+				pkgHasFailedTest[ev.Package] = true
+				res.addFailure(ev.Package, ev.Test, output[k], limit)
+			case ev.FailedBuild != "":
+				res.addFailure(ev.Package, "", buildOutput[ev.FailedBuild]+output[k], limit)
+			case !pkgHasFailedTest[ev.Package]:
+				res.addFailure(ev.Package, "", output[k], limit)
+			}
+			delete(output, k)
+		}
+	}
+	return res
+}
+
+func (r *testResult) addFailure(pkg, test, out string, limit int) {
+	if len(r.Failures) >= maxFailures {
+		r.Omitted++
+		return
+	}
+	r.Failures = append(r.Failures, testFailure{Package: pkg, Test: test, Output: truncateTail(out, limit)})
+}
+
+// truncateTail keeps the last limit bytes, where test failures and panics
+// usually report the cause.
+func truncateTail(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	return "...[truncated]\n" + s[len(s)-limit:]
+}
+
+func validateRelPath(p string) error {
+	if filepath.IsAbs(p) {
+		return fmt.Errorf("ABSOLUTE_PATH: %s", p)
+	}
+	clean := filepath.ToSlash(filepath.Clean(p))
+	if clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(clean, "/../") {
+		return fmt.Errorf("PATH_ESCAPE: %s", p)
+	}
+	return nil
+}
+
+func stderrJSON(err error) {
+	msg := err.Error()
+	msg = strings.ReplaceAll(msg, "\n", " ")
+	fmt.Fprintf(os.Stderr, "{\"error\":%q}\n", msg)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	testutil "github.com/hyperifyio/goagent/tools/testutil"
+)
+
+// writeModule creates a throwaway module with the given files.
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/m\n\ngo 1.21\n"
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func runGoCheck(t *testing.T, dir string, input any) (checkOutput, string, int) {
+	t.Helper()
+	bin := testutil.BuildTool(t, "go_check")
+	data, err := json.Marshal(input)
+	if err != nil {
+		t.Fatalf("marshal input: %v", err)
+	}
+	cmd := exec.Command(bin)
+	cmd.Dir = dir
+	cmd.Stdin = bytes.NewReader(data)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	code := 0
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok {
+			code = ee.ExitCode()
+		} else {
+			code = 1
+		}
+	}
+	var out checkOutput
+	if code == 0 {
+		if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+			t.Fatalf("unmarshal stdout: %v; raw=%q", err, stdout.String())
+		}
+	}
+	return out, stderr.String(), code
+}
+
+func TestGoCheck_CountsAndFailures(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"a/a.go": "package a\n\nfunc Add(x, y int) int { return x + y }\n",
+		"a/a_test.go": "package a\n\nimport \"testing\"\n\n" +
+			"func TestAdd(t *testing.T) { if Add(1, 2) != 3 { t.Fatal(\"bad\") } }\n" +
+			"func TestBroken(t *testing.T) { t.Fatalf(\"want %d got %d\", 4, Add(2, 1)) }\n" +
+			"func TestLater(t *testing.T) { t.Skip(\"not yet\") }\n",
+		"b/b.go":      "package b\n",
+		"b/b_test.go": "package b\n\nimport \"testing\"\n\nfunc TestOK(t *testing.T) {}\n",
+	})
+	out, stderr, code := runGoCheck(t, dir, map[string]any{})
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, stderr)
+	}
+	if out.OK || out.Build == nil || !out.Build.OK || out.Test == nil {
+		t.Fatalf("out=%+v", out)
+	}
+	tr := out.Test
+	if tr.Passed != 2 || tr.Failed != 1 || tr.Skipped != 1 || len(tr.Failures) != 1 {
+		t.Fatalf("test=%+v", tr)
+	}
+	f := tr.Failures[0]
+	if f.Package != "example.com/m/a" || f.Test != "TestBroken" || !strings.Contains(f.Output, "want 4 got 3") {
+		t.Fatalf("failure=%+v", f)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.test")); !os.IsNotExist(err) {
+		t.Fatalf("test binary left in workspace")
+	}
+
+	out, stderr, code = runGoCheck(t, dir, map[string]any{"build": false, "packages": []string{"./b"}})
+	if code != 0 || !out.OK || out.Build != nil || out.Test.Passed != 1 {
+		t.Fatalf("exit=%d stderr=%s out=%+v", code, stderr, out)
+	}
+}
+
+func TestGoCheck_BuildErrorsSkipTests(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"main.go": "package main\n\nfunc main() {\n\tundefinedThing()\n}\n",
+	})
+	out, stderr, code := runGoCheck(t, dir, map[string]any{})
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, stderr)
+	}
+	if out.OK || out.Build.OK || out.Test != nil || len(out.Build.Errors) != 1 {
+		t.Fatalf("out=%+v", out)
+	}
+	e := out.Build.Errors[0]
+	if !strings.HasSuffix(e.File, "main.go") || e.Line != 4 || !strings.Contains(e.Message, "undefinedThing") {
+		t.Fatalf("error=%+v", e)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Fatalf("build wrote into the workspace: %v", entries)
+	}
+}
+
+func TestGoCheck_TestFileCompileError(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"a/a.go":      "package a\n",
+		"a/a_test.go": "package a\n\nimport \"testing\"\n\nfunc TestX(t *testing.T) { missing() }\n",
+	})
+	out, stderr, code := runGoCheck(t, dir, map[string]any{"test": true})
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, stderr)
+	}
+	if out.OK || !out.Build.OK || len(out.Test.Failures) != 1 {
+		t.Fatalf("out=%+v", out)
+	}
+	if f := out.Test.Failures[0]; f.Package != "example.com/m/a" || !strings.Contains(f.Output, "missing") {
+		t.Fatalf("failure=%+v", f)
+	}
+}
+
+func TestGoCheck_RejectsBadInput(t *testing.T) {
+	dir := t.TempDir()
+	for name, in := range map[string]any{
+		"escape":  map[string]any{"dir": "../x"},
+		"flag":    map[string]any{"packages": []string{"-exec=evil"}},
+		"nothing": map[string]any{"build": false, "test": false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, stderr, code := runGoCheck(t, dir, in); code == 0 || !strings.Contains(stderr, "\"error\"") {
+				t.Fatalf("exit=%d stderr=%s", code, stderr)
+			}
+		})
+	}
+}
@@ -9,16 +9,24 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 )
 
 type inputSpec struct {
+	// Mode selects the endpoint: "generate" (default), "edit", or "variation"
+	Mode string `json:"mode"`
+	// Image is the repo-relative input image for edit and variation; Mask
+	// (edit only) marks the area to change with transparent pixels
+	Image     string `json:"image"`
+	Mask      string `json:"mask"`
 	Prompt    string `json:"prompt"`
 	N         int    `json:"n"`
 	Size      string `json:"size"`
@@ -38,6 +46,17 @@ type inputSpec struct {
 
 var sizeRe = regexp.MustCompile(`^\d{3,4}x\d{3,4}$`)
 
+// maxInputImageBytes caps image and mask uploads (the largest limit of the
+// Images API models).
+const maxInputImageBytes = 50 << 20
+
+// endpoints maps each mode to its Images API path.
+var endpoints = map[string]string{
+	"generate":  "/v1/images/generations",
+	"edit":      "/v1/images/edits",
+	"variation": "/v1/images/variations",
+}
+
 func main() {
 	if err := run(); err != nil {
 		msg := strings.TrimSpace(err.Error())
@@ -55,12 +74,12 @@ func run() error {
 		return err
 	}
 	// Build request body
-	bodyBytes, err := buildRequestBody(in)
+	bodyBytes, contentType, err := buildRequestBody(in)
 	if err != nil {
 		return err
 	}
 	// Perform HTTP request with limited retries
-	respBody, model, err := doRequest(bodyBytes)
+	respBody, model, err := doRequest(endpoints[in.Mode], contentType, bodyBytes)
 	if err != nil {
 		return err
 	}
@@ -81,9 +100,34 @@ func parseInput(r io.Reader) (inputSpec, error) {
 	if err := json.Unmarshal(data, &in); err != nil {
 		return in, fmt.Errorf("bad json: %w", err)
 	}
-	if strings.TrimSpace(in.Prompt) == "" {
+	if in.Mode == "" {
+		in.Mode = "generate"
+	}
+	if _, ok := endpoints[in.Mode]; !ok {
+		return in, errors.New("mode must be one of generate, edit, variation")
+	}
+	if in.Mode != "variation" && strings.TrimSpace(in.Prompt) == "" {
 		return in, errors.New("prompt is required")
 	}
+	if in.Mode == "generate" && (in.Image != "" || in.Mask != "") {
+		return in, errors.New("image and mask require mode edit or variation")
+	}
+	if in.Mode != "generate" {
+		if strings.TrimSpace(in.Image) == "" {
+			return in, fmt.Errorf("image is required for mode %s", in.Mode)
+		}
+		if err := checkRepoRelative("image", in.Image); err != nil {
+			return in, err
+		}
+	}
+	if in.Mask != "" {
+		if in.Mode != "edit" {
+			return in, errors.New("mask requires mode edit")
+		}
+		if err := checkRepoRelative("mask", in.Mask); err != nil {
+			return in, err
+		}
+	}
 	if in.N == 0 {
 		in.N = 1
 	}
@@ -97,7 +141,11 @@ func parseInput(r io.Reader) (inputSpec, error) {
 		return in, errors.New("size must match ^\\d{3,4}x\\d{3,4}$")
 	}
 	if in.Model == "" {
+		// gpt-image-1 does not offer variations
 		in.Model = "gpt-image-1"
+		if in.Mode == "variation" {
+			in.Model = "dall-e-2"
+		}
 	}
 	if !in.ReturnB64 {
 		if in.Save == nil || strings.TrimSpace(in.Save.Dir) == "" {
@@ -123,39 +171,114 @@ func parseInput(r io.Reader) (inputSpec, error) {
 	return in, nil
 }
 
-// buildRequestBody creates the JSON body for the Images API.
-func buildRequestBody(in inputSpec) ([]byte, error) {
+// checkRepoRelative applies the save.dir path rules to an input file.
+func checkRepoRelative(field, p string) error {
+	if filepath.IsAbs(p) {
+		return fmt.Errorf("%s must be repo-relative", field)
+	}
+	if strings.HasPrefix(filepath.Clean(p), "..") {
+		return fmt.Errorf("%s escapes repository root", field)
+	}
+	return nil
+}
+
+// buildRequestBody creates the body for the Images API and its content type:
+// JSON for generate, multipart form data with the input files otherwise.
+func buildRequestBody(in inputSpec) ([]byte, string, error) {
 	reqBody := map[string]any{
 		"model":           in.Model,
-		"prompt":          in.Prompt,
 		"n":               in.N,
 		"size":            in.Size,
 		"response_format": "b64_json",
 	}
+	if in.Mode != "variation" {
+		reqBody["prompt"] = in.Prompt
+	}
 	if len(in.Extras) > 0 {
 		safe := sanitizeExtras(in.Extras)
 		for k, v := range safe {
 			switch k {
-			case "model", "prompt", "n", "size", "response_format":
+			case "model", "prompt", "n", "size", "response_format", "image", "mask":
 			default:
 				reqBody[k] = v
 			}
 		}
 	}
-	b, err := json.Marshal(reqBody)
+	if in.Mode == "generate" {
+		b, err := json.Marshal(reqBody)
+		if err != nil {
+			return nil, "", fmt.Errorf("marshal request: %w", err)
+		}
+		return b, "application/json", nil
+	}
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	keys := make([]string, 0, len(reqBody))
+	for k := range reqBody {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if reqBody[k] == nil {
+			continue
+		}
+		if err := w.WriteField(k, fmt.Sprint(reqBody[k])); err != nil {
+			return nil, "", fmt.Errorf("build form: %w", err)
+		}
+	}
+	files := [][2]string{{"image", in.Image}}
+	if in.Mask != "" {
+		files = append(files, [2]string{"mask", in.Mask})
+	}
+	for _, f := range files {
+		if err := addFormFile(w, f[0], f[1]); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", fmt.Errorf("build form: %w", err)
+	}
+	return buf.Bytes(), w.FormDataContentType(), nil
+}
+
+// addFormFile attaches the image at path as form field name.
+func addFormFile(w *multipart.Writer, name, path string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if !fi.Mode().IsRegular() {
+		return fmt.Errorf("%s: %s is not a regular file", name, path)
+	}
+	if fi.Size() > maxInputImageBytes {
+		return fmt.Errorf("%s: %s is %d bytes; the limit is %d", name, path, fi.Size(), maxInputImageBytes)
+	}
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("marshal request: %w", err)
+		return fmt.Errorf("%s: %w", name, err)
 	}
-	return b, nil
+	if ct := http.DetectContentType(data); !strings.HasPrefix(ct, "image/") {
+		return fmt.Errorf("%s: %s is not an image (detected %s)", name, path, ct)
+	}
+	part, err := w.CreateFormFile(name, filepath.Base(path))
+	if err != nil {
+		return fmt.Errorf("build form: %w", err)
+	}
+	if _, err := part.Write(data); err != nil {
+		return fmt.Errorf("build form: %w", err)
+	}
+	return nil
 }
 
-// doRequest posts to the Images API with retries and returns body and model.
-func doRequest(bodyBytes []byte) ([]byte, string, error) {
+// doRequest posts to the Images API path with retries and returns body and
+// model.
+func doRequest(path, contentType string, bodyBytes []byte) ([]byte, string, error) {
 	baseURL := strings.TrimRight(firstNonEmpty(os.Getenv("OAI_IMAGE_BASE_URL"), os.Getenv("OAI_BASE_URL"), ""), "/")
 	if baseURL == "" {
 		return nil, "", errors.New("missing OAI_IMAGE_BASE_URL or OAI_BASE_URL")
 	}
-	url := baseURL + "/v1/images/generations"
+	url := baseURL + path
 	client := &http.Client{Timeout: httpTimeout()}
 	var lastErr error
 	var resp *http.Response
@@ -164,7 +287,7 @@ func doRequest(bodyBytes []byte) ([]byte, string, error) {
 		if err != nil {
 			return nil, "", fmt.Errorf("new request: %w", err)
 		}
-		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Type", contentType)
 		if key := strings.TrimSpace(os.Getenv("OAI_API_KEY")); key != "" {
 			req.Header.Set("Authorization", "Bearer "+key)
 		}
@@ -376,3 +376,107 @@ func TestBasename_MustNotContainPathSeparators(t *testing.T) {
 		t.Fatalf("expected basename separator error, got %q", stderr)
 	}
 }
+
+func TestEditWithMask_SendsMultipartAndSaves(t *testing.T) {
+	png1x1 := "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAQAAAC1HAwCAAAAC0lEQVR42mP8/x8AAwMCAO9cFmgAAAAASUVORK5CYII="
+	pngBytes, _ := base64.StdEncoding.DecodeString(png1x1)
+	inDir := testutil.MakeRepoRelTempDir(t, "imgcreate-in-")
+	image := filepath.Join(inDir, "base.png")
+	mask := filepath.Join(inDir, "mask.png")
+	for _, p := range []string{image, mask} {
+		if err := os.WriteFile(p, pngBytes, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/images/edits" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatalf("multipart: %v", err)
+		}
+		if r.FormValue("prompt") != "add a hat" || r.FormValue("model") != "gpt-image-1" || r.FormValue("n") != "1" || r.FormValue("background") != "transparent" {
+			t.Errorf("fields: %v", r.MultipartForm.Value)
+		}
+		for _, name := range []string{"image", "mask"} {
+			if _, hdr, err := r.FormFile(name); err != nil || hdr.Size != int64(len(pngBytes)) {
+				t.Errorf("%s: %v", name, err)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": []map[string]any{{"b64_json": png1x1}}})
+	}))
+	defer srv.Close()
+
+	bin := buildTool(t)
+	outDir := testutil.MakeRepoRelTempDir(t, "imgcreate-out-")
+	stdout, stderr, code := runTool(t, bin, map[string]any{
+		"mode":   "edit",
+		"prompt": "add a hat",
+		"image":  image,
+		"mask":   mask,
+		"extras": map[string]any{"background": "transparent", "image": "ignored"},
+		"save":   map[string]any{"dir": outDir, "basename": "edited"},
+	}, map[string]string{"OAI_IMAGE_BASE_URL": srv.URL})
+	if code != 0 {
+		t.Fatalf("unexpected failure: %s", stderr)
+	}
+	if !strings.Contains(stdout, filepath.Join(outDir, "edited_001.png")) {
+		t.Fatalf("stdout=%s", stdout)
+	}
+}
+
+func TestVariation_NoPromptAndDefaultModel(t *testing.T) {
+	png1x1 := "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAQAAAC1HAwCAAAAC0lEQVR42mP8/x8AAwMCAO9cFmgAAAAASUVORK5CYII="
+	pngBytes, _ := base64.StdEncoding.DecodeString(png1x1)
+	inDir := testutil.MakeRepoRelTempDir(t, "imgcreate-in-")
+	image := filepath.Join(inDir, "base.png")
+	if err := os.WriteFile(image, pngBytes, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatalf("multipart: %v", err)
+		}
+		if r.URL.Path != "/v1/images/variations" || r.FormValue("model") != "dall-e-2" || r.FormValue("n") != "2" {
+			t.Errorf("path=%s fields=%v", r.URL.Path, r.MultipartForm.Value)
+		}
+		if _, ok := r.MultipartForm.Value["prompt"]; ok {
+			t.Errorf("variation must not send a prompt")
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": []map[string]any{{"b64_json": png1x1}, {"b64_json": png1x1}}})
+	}))
+	defer srv.Close()
+
+	bin := buildTool(t)
+	stdout, stderr, code := runTool(t, bin, map[string]any{"mode": "variation", "image": image, "n": 2, "return_b64": true}, map[string]string{"OAI_IMAGE_BASE_URL": srv.URL})
+	if code != 0 || strings.Count(stdout, "b64 elided") != 2 {
+		t.Fatalf("code=%d stdout=%s stderr=%s", code, stdout, stderr)
+	}
+}
+
+func TestEditAndVariation_Validation(t *testing.T) {
+	bin := buildTool(t)
+	inDir := testutil.MakeRepoRelTempDir(t, "imgcreate-in-")
+	text := filepath.Join(inDir, "notes.png")
+	if err := os.WriteFile(text, []byte("not an image"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		in   map[string]any
+		want string
+	}{
+		{map[string]any{"mode": "upscale", "prompt": "p", "return_b64": true}, "mode must be one of"},
+		{map[string]any{"mode": "edit", "prompt": "p", "return_b64": true}, "image is required for mode edit"},
+		{map[string]any{"mode": "edit", "image": "a.png", "return_b64": true}, "prompt is required"},
+		{map[string]any{"prompt": "p", "image": "a.png", "return_b64": true}, "image and mask require mode edit or variation"},
+		{map[string]any{"mode": "variation", "image": "a.png", "mask": "m.png", "return_b64": true}, "mask requires mode edit"},
+		{map[string]any{"mode": "variation", "image": "../a.png", "return_b64": true}, "image escapes repository root"},
+		{map[string]any{"mode": "edit", "prompt": "p", "image": "/tmp/a.png", "return_b64": true}, "image must be repo-relative"},
+		{map[string]any{"mode": "variation", "image": text, "return_b64": true}, "is not an image"},
+	} {
+		_, stderr, code := runTool(t, bin, tc.in, map[string]string{"OAI_IMAGE_BASE_URL": "http://127.0.0.1:1"})
+		if code == 0 || !strings.Contains(stderr, tc.want) {
+			t.Errorf("%v: code=%d stderr=%q want %q", tc.in, code, stderr, tc.want)
+		}
+	}
+}
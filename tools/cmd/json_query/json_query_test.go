@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	testutil "github.com/hyperifyio/goagent/tools/testutil"
+)
+
+type jsonQueryOutput struct {
+	Results   []json.RawMessage `json:"results"`
+	Truncated bool              `json:"truncated"`
+}
+
+func runJSONQuery(t *testing.T, bin, dir string, input any) (jsonQueryOutput, string, int) {
+	t.Helper()
+	data, err := json.Marshal(input)
+	if err != nil {
+		t.Fatalf("marshal input: %v", err)
+	}
+	cmd := exec.Command(bin)
+	cmd.Dir = dir
+	cmd.Stdin = bytes.NewReader(data)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	code := 0
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok {
+			code = ee.ExitCode()
+		} else {
+			code = 1
+		}
+	}
+	var out jsonQueryOutput
+	if code == 0 {
+		if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+			t.Fatalf("unmarshal stdout: %v; raw=%q", err, stdout.String())
+		}
+	}
+	return out, stderr.String(), code
+}
+
+func results(out jsonQueryOutput) string {
+	parts := make([]string, len(out.Results))
+	for i, r := range out.Results {
+		parts[i] = string(r)
+	}
+	return strings.Join(parts, ",")
+}
+
+func TestJSONQuery_ExtractsNodes(t *testing.T) {
+	bin := testutil.BuildTool(t, "json_query")
+	dir := t.TempDir()
+	doc := `{"services":[{"name":"api","port":8080},{"name":"db","port":5432}],"id":12345678901234567890}`
+	if err := os.WriteFile(filepath.Join(dir, "cfg.json"), []byte(doc), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	out, stderr, code := runJSONQuery(t, bin, dir, map[string]any{"path": "cfg.json", "query": `.services[] | select(.port > 6000) | .name`})
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if got := results(out); got != `"api"` || out.Truncated {
+		t.Fatalf("results = %s truncated=%v", got, out.Truncated)
+	}
+	// Large integers are returned exactly
+	out, stderr, code = runJSONQuery(t, bin, dir, map[string]any{"path": "cfg.json", "query": ".id"})
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if got := results(out); got != "12345678901234567890" {
+		t.Fatalf("id = %s", got)
+	}
+}
+
+func TestJSONQuery_MaxResultsAndNDJSON(t *testing.T) {
+	bin := testutil.BuildTool(t, "json_query")
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "log.ndjson"), []byte("{\"n\":1}\n{\"n\":2}\n{\"n\":3}\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	out, stderr, code := runJSONQuery(t, bin, dir, map[string]any{"path": "log.ndjson", "query": ".n", "maxResults": 2})
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if got := results(out); got != "1,2" || !out.Truncated {
+		t.Fatalf("results = %s truncated=%v", got, out.Truncated)
+	}
+}
+
+func TestJSONQuery_Errors(t *testing.T) {
+	bin := testutil.BuildTool(t, "json_query")
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.json"), []byte(`{"a":1}`), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bad.json"), []byte(`{"a":`), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	cases := []struct {
+		name  string
+		input map[string]any
+		want  string
+	}{
+		{"absolute", map[string]any{"path": "/etc/passwd", "query": "."}, "ABSOLUTE_PATH"},
+		{"escape", map[string]any{"path": "../a.json", "query": "."}, "PATH_ESCAPE"},
+		{"missing", map[string]any{"path": "nope.json", "query": "."}, "NOT_FOUND"},
+		{"bad query", map[string]any{"path": "a.json", "query": ".a |"}, "BAD_QUERY"},
+		{"runtime", map[string]any{"path": "a.json", "query": ".a[0]"}, "QUERY_ERROR"},
+		{"parse", map[string]any{"path": "bad.json", "query": "."}, "PARSE_ERROR"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, stderr, code := runJSONQuery(t, bin, dir, tc.input)
+			if code == 0 || !strings.Contains(stderr, tc.want) {
+				t.Fatalf("want %s, got code=%d stderr=%s", tc.want, code, stderr)
+			}
+		})
+	}
+}
+
+// TestJSONQuery_NamedRoot verifies a "root:" path reads from the named
+// workspace root and that escapes and unknown roots are refused.
+func TestJSONQuery_NamedRoot(t *testing.T) {
+	bin := testutil.BuildTool(t, "json_query")
+	cwd, api := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(api, "cfg.json"), []byte(`{"name":"api"}`), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	t.Setenv("GOAGENT_WORKSPACE_ROOTS", "api="+api)
+	out, stderr, code := runJSONQuery(t, bin, cwd, map[string]any{"path": "api:cfg.json", "query": ".name"})
+	if code != 0 || results(out) != `"api"` {
+		t.Fatalf("exit %d: %s results=%s", code, stderr, results(out))
+	}
+	for path, want := range map[string]string{"api:../../cfg.json": "PATH_ESCAPE", "web:cfg.json": "UNKNOWN_ROOT"} {
+		if _, stderr, code := runJSONQuery(t, bin, cwd, map[string]any{"path": path, "query": "."}); code == 0 || !strings.Contains(stderr, want) {
+			t.Fatalf("%s: expected %s, got exit=%d stderr=%q", path, want, code, stderr)
+		}
+	}
+}
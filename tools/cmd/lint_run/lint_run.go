@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hyperifyio/goagent/tools/workspace"
+)
+
+const (
+	defaultMaxFindings = 200
+	linterGolangci     = "golangci-lint"
+	linterVet          = "go vet"
+)
+
+type lintInput struct {
+	Paths       []string `json:"paths,omitempty"`
+	Dir         string   `json:"dir,omitempty"`
+	Linter      string   `json:"linter,omitempty"` // auto | golangci-lint | go vet
+	MaxFindings int      `json:"maxFindings,omitempty"`
+}
+
+type finding struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Col     int    `json:"col,omitempty"`
+	Linter  string `json:"linter"`
+	Message string `json:"message"`
+}
+
+type lintOutput struct {
+	Linter    string    `json:"linter"`
+	Count     int       `json:"count"`
+	Findings  []finding `json:"findings"`
+	Truncated bool      `json:"truncated,omitempty"`
+}
+
+func main() {
+	in, err := readInput(os.Stdin)
+	if err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	if _, in.Dir, err = workspace.Enter(in.Dir); err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	out, err := lint(in)
+	if err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(out); err != nil {
+		stderrJSON(fmt.Errorf("write stdout: %w", err))
+		os.Exit(1)
+	}
+}
+
+func readInput(r io.Reader) (lintInput, error) {
+	var in lintInput
+	b, err := io.ReadAll(bufio.NewReader(r))
+	if err != nil {
+		return in, fmt.Errorf("read stdin: %w", err)
+	}
+	if err := json.Unmarshal(b, &in); err != nil {
+		return in, fmt.Errorf("parse json: %w", err)
+	}
+	return in, nil
+}
+
+// lint runs the selected linter over the requested paths and returns its
+// findings sorted by position. Findings are results, not errors; an error
+// means the linter itself could not run.
+func lint(in lintInput) (lintOutput, error) {
+	dir := "."
+	if in.Dir != "" {
+		if err := validateRelPath(in.Dir); err != nil {
+			return lintOutput{}, err
+		}
+		dir = in.Dir
+	}
+	paths := in.Paths
+	if len(paths) == 0 {
+		paths = []string{"./..."}
+	}
+	for _, p := range paths {
+		if strings.HasPrefix(p, "-") {
+			return lintOutput{}, fmt.Errorf("invalid path %q", p)
+		}
+		if err := validateRelPath(strings.TrimSuffix(p, "/...")); err != nil {
+			return lintOutput{}, err
+		}
+	}
+	limit := in.MaxFindings
+	if limit <= 0 {
+		limit = defaultMaxFindings
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return lintOutput{}, err
+	}
+
+	var linter string
+	switch in.Linter {
+	case "", "auto":
+		linter = linterVet
+		if _, err := exec.LookPath(linterGolangci); err == nil {
+			linter = linterGolangci
+		}
+	case linterGolangci, linterVet:
+		linter = in.Linter
+	default:
+		return lintOutput{}, fmt.Errorf("unknown linter %q (allowed: auto, %s, %s)", in.Linter, linterGolangci, linterVet)
+	}
+	var findings []finding
+	if linter == linterGolangci {
+		findings, err = runGolangci(dir, paths)
+	} else {
+		findings, err = runVet(dir, paths)
+	}
+	if err != nil {
+		return lintOutput{}, err
+	}
+
+	for i := range findings {
+		findings[i].File = relativeTo(absDir, findings[i].File)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Col < b.Col
+	})
+	out := lintOutput{Linter: linter, Count: len(findings), Findings: findings}
+	if len(out.Findings) > limit {
+		out.Findings, out.Truncated = out.Findings[:limit], true
+	}
+	if out.Findings == nil {
+		out.Findings = []finding{}
+	}
+	return out, nil
+}
+
+// runGolangci runs golangci-lint with JSON output. Version 2 replaced
+// --out-format with --output.json.path, so the major version picks the flag.
+func runGolangci(dir string, paths []string) ([]finding, error) {
+	if _, err := exec.LookPath(linterGolangci); err != nil {
+		return nil, fmt.Errorf("%s not found in PATH; use linter %q instead", linterGolangci, linterVet)
+	}
+	args := []string{"run", "--issues-exit-code=0"}
+	if golangciMajor(dir) >= 2 {
+		args = append(args, "--output.json.path=stdout", "--show-stats=false")
+	} else {
+		args = append(args, "--out-format=json")
+	}
+	cmd := exec.Command(linterGolangci, append(args, paths...)...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s failed: %v: %s", linterGolangci, err, tail(stderr.String()+stdout.String(), 2000))
+	}
+	return parseGolangci(stdout.Bytes())
+}
+
+func golangciMajor(dir string) int {
+	cmd := exec.Command(linterGolangci, "--version")
+	cmd.Dir = dir
+	b, err := cmd.Output()
+	if err != nil {
+		return 1
+	}
+	m := regexp.MustCompile(`version v?(\d+)\.`).FindSubmatch(b)
+	if m == nil {
+		return 1
+	}
+	n, _ := strconv.Atoi(string(m[1])) //nolint:errcheck // regexp guarantees digits
+	return n
+}
+
+// parseGolangci decodes the JSON report, skipping any text printed before it.
+func parseGolangci(b []byte) ([]finding, error) {
+	start := bytes.IndexByte(b, '{')
+	if start < 0 {
+		return nil, fmt.Errorf("%s printed no JSON report", linterGolangci)
+	}
+	var report struct {
+		Issues []struct {
+			FromLinter string `json:"FromLinter"`
+			Text       string `json:"Text"`
+			Pos        struct {
+				Filename string `json:"Filename"`
+				Line     int    `json:"Line"`
+				Column   int    `json:"Column"`
+			} `json:"Pos"`
+		} `json:"Issues"`
+	}
+	if err := json.NewDecoder(bytes.NewReader(b[start:])).Decode(&report); err != nil {
+		return nil, fmt.Errorf("decode %s report: %w", linterGolangci, err)
+	}
+	out := make([]finding, 0, len(report.Issues))
+	for _, is := range report.Issues {
+		out = append(out, finding{File: is.Pos.Filename, Line: is.Pos.Line, Col: is.Pos.Column, Linter: is.FromLinter, Message: is.Text})
+	}
+	return out, nil
+}
+
+// runVet runs go vet -json. Analyzer findings arrive as JSON blocks keyed by
+// package and analyzer; packages that fail to type-check are reported as
+// plain "vet: file:line:col: message" lines, recorded as linter "typecheck".
+func runVet(dir string, paths []string) ([]finding, error) {
+	cmd := exec.Command("go", append([]string{"vet", "-json"}, paths...)...)
+	cmd.Dir = dir
+	var combined bytes.Buffer
+	cmd.Stdout = &combined
+	cmd.Stderr = &combined
+	err := cmd.Run()
+	var ee *exec.ExitError
+	if err != nil && !errors.As(err, &ee) {
+		return nil, fmt.Errorf("run go vet: %w", err)
+	}
+	findings, parseErr := parseVet(combined.String())
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	// A failed run that explains nothing (for example a bad package path)
+	// is an error rather than a clean result
+	if err != nil && len(findings) == 0 {
+		return nil, fmt.Errorf("go vet failed: %s", tail(combined.String(), 2000))
+	}
+	return findings, nil
+}
+
+var positionRe = regexp.MustCompile(`^(?:vet: )?(\S+\.go):(\d+)(?::(\d+))?: (.+)$`)
+
+func parseVet(s string) ([]finding, error) {
+	var out []finding
+	lines := strings.Split(s, "\n")
+	for i := 0; i < len(lines); i++ {
+		ln := strings.TrimRight(lines[i], "\r")
+		if ln == "{" {
+			// A JSON block ends at the first unindented closing brace
+			end := i
+			for end < len(lines) && strings.TrimRight(lines[end], "\r") != "}" {
+				end++
+			}
+			block := strings.Join(lines[i:min(end+1, len(lines))], "\n")
+			fs, err := parseVetBlock(block)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, fs...)
+			i = end
+			continue
+		}
+		if m := positionRe.FindStringSubmatch(strings.TrimSpace(ln)); m != nil {
+			out = append(out, newFinding(m[1], m[2], m[3], "typecheck", m[4]))
+		}
+	}
+	return out, nil
+}
+
+func parseVetBlock(block string) ([]finding, error) {
+	var byPkg map[string]map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(block), &byPkg); err != nil {
+		return nil, fmt.Errorf("decode go vet report: %w", err)
+	}
+	var out []finding
+	for _, byAnalyzer := range byPkg {
+		for analyzer, raw := range byAnalyzer {
+			var diags []struct {
+				Posn    string `json:"posn"`
+				Message string `json:"message"`
+			}
+			if json.Unmarshal(raw, &diags) != nil {
+				// Analyzer failures are objects such as {"error": "..."}
+				var failure struct {
+					Error string `json:"error"`
+				}
+				if json.Unmarshal(raw, &failure) == nil && failure.Error != "" {
+					out = append(out, finding{Linter: analyzer, Message: failure.Error})
+				}
+				continue
+			}
+			for _, d := range diags {
+				m := positionRe.FindStringSubmatch(d.Posn + ": " + d.Message)
+				if m == nil {
+					out = append(out, finding{File: d.Posn, Linter: analyzer, Message: d.Message})
+					continue
+				}
+				out = append(out, newFinding(m[1], m[2], m[3], analyzer, d.Message))
+			}
+		}
+	}
+	return out, nil
+}
+
+func newFinding(file, line, col, linter, msg string) finding {
+	l, _ := strconv.Atoi(line) //nolint:errcheck // regexp guarantees digits
+	c, _ := strconv.Atoi(col)  //nolint:errcheck // empty when absent
+	return finding{File: file, Line: l, Col: c, Linter: linter, Message: msg}
+}
+
+// relativeTo reports file relative to dir when it lies inside it, so
+// findings name paths the agent can pass straight to the fs tools.
+func relativeTo(dir, file string) string {
+	if file == "" || !filepath.IsAbs(file) {
+		return filepath.ToSlash(file)
+	}
+	if rel, err := filepath.Rel(dir, file); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return file
+}
+
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return "..." + s[len(s)-n:]
+}
+
+func validateRelPath(p string) error {
+	if filepath.IsAbs(p) {
+		return fmt.Errorf("ABSOLUTE_PATH: %s", p)
+	}
+	clean := filepath.ToSlash(filepath.Clean(p))
+	if clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(clean, "/../") {
+		return fmt.Errorf("PATH_ESCAPE: %s", p)
+	}
+	return nil
+}
+
+func stderrJSON(err error) {
+	msg := err.Error()
+	msg = strings.ReplaceAll(msg, "\n", " ")
+	fmt.Fprintf(os.Stderr, "{\"error\":%q}\n", msg)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	testutil "github.com/hyperifyio/goagent/tools/testutil"
+)
+
+// writeModule creates a throwaway module with the given files.
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/m\n\ngo 1.21\n"
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func runLint(t *testing.T, dir string, input any) (lintOutput, string, int) {
+	t.Helper()
+	bin := testutil.BuildTool(t, "lint_run")
+	data, err := json.Marshal(input)
+	if err != nil {
+		t.Fatalf("marshal input: %v", err)
+	}
+	cmd := exec.Command(bin)
+	cmd.Dir = dir
+	cmd.Stdin = bytes.NewReader(data)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	code := 0
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok {
+			code = ee.ExitCode()
+		} else {
+			code = 1
+		}
+	}
+	var out lintOutput
+	if code == 0 {
+		if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+			t.Fatalf("unmarshal stdout: %v; raw=%q", err, stdout.String())
+		}
+	}
+	return out, stderr.String(), code
+}
+
+var lintModule = map[string]string{
+	"a/a.go": "package a\n\nimport \"fmt\"\n\nfunc F() {\n\tfmt.Printf(\"%d\\n\", \"x\")\n}\n",
+	"b/b.go": "package b\n\nfunc G() int {\n\tvar x int\n\tx = x\n\treturn x\n}\n",
+	"c/c.go": "package c\n\nfunc H() {}\n",
+}
+
+func TestLintRun_VetFindings(t *testing.T) {
+	dir := writeModule(t, lintModule)
+	out, stderr, code := runLint(t, dir, map[string]any{"linter": "go vet"})
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, stderr)
+	}
+	if out.Linter != "go vet" || out.Count != 2 || len(out.Findings) != 2 || out.Truncated {
+		t.Fatalf("out=%+v", out)
+	}
+	a, b := out.Findings[0], out.Findings[1]
+	if a.File != "a/a.go" || a.Line != 6 || a.Col == 0 || a.Linter != "printf" || !strings.Contains(a.Message, "%d") {
+		t.Fatalf("first=%+v", a)
+	}
+	if b.File != "b/b.go" || b.Line != 5 || b.Linter != "assign" {
+		t.Fatalf("second=%+v", b)
+	}
+
+	out, stderr, code = runLint(t, dir, map[string]any{"paths": []string{"./c"}})
+	if code != 0 || out.Count != 0 || out.Findings == nil {
+		t.Fatalf("exit=%d stderr=%s out=%+v", code, stderr, out)
+	}
+
+	out, _, _ = runLint(t, dir, map[string]any{"linter": "go vet", "maxFindings": 1})
+	if out.Count != 2 || len(out.Findings) != 1 || !out.Truncated {
+		t.Fatalf("truncation out=%+v", out)
+	}
+}
+
+func TestLintRun_TypeErrors(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"b/b.go": "package b\n\nvar V = undefinedX\n",
+	})
+	out, stderr, code := runLint(t, dir, map[string]any{"linter": "go vet", "paths": []string{"./b"}})
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, stderr)
+	}
+	if len(out.Findings) != 1 {
+		t.Fatalf("out=%+v", out)
+	}
+	if f := out.Findings[0]; f.File != "b/b.go" || f.Line != 3 || f.Linter != "typecheck" || !strings.Contains(f.Message, "undefinedX") {
+		t.Fatalf("finding=%+v", f)
+	}
+}
+
+func TestLintRun_RejectsBadInput(t *testing.T) {
+	dir := writeModule(t, map[string]string{"a/a.go": "package a\n"})
+	for name, in := range map[string]any{
+		"escape":  map[string]any{"dir": "../x"},
+		"abs":     map[string]any{"paths": []string{"/etc/..."}},
+		"flag":    map[string]any{"paths": []string{"-vettool=evil"}},
+		"linter":  map[string]any{"linter": "staticcheck"},
+		"missing": map[string]any{"linter": "go vet", "paths": []string{"./nope"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, stderr, code := runLint(t, dir, in); code == 0 || !strings.Contains(stderr, "\"error\"") {
+				t.Fatalf("exit=%d stderr=%s", code, stderr)
+			}
+		})
+	}
+}
+
+func TestParseGolangci(t *testing.T) {
+	report := "level=warning msg=\"noise\"\n" + `{"Issues":[{"FromLinter":"errcheck","Text":"Error return value is not checked","Pos":{"Filename":"a/a.go","Line":7,"Column":2}}],"Report":{}}`
+	got, err := parseGolangci([]byte(report))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := finding{File: "a/a.go", Line: 7, Col: 2, Linter: "errcheck", Message: "Error return value is not checked"}
+	if len(got) != 1 || got[0] != want {
+		t.Fatalf("got %+v", got)
+	}
+	if _, err := parseGolangci([]byte("panic: boom")); err == nil {
+		t.Fatalf("want error without a report")
+	}
+}
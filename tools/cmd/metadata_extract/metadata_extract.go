@@ -7,9 +7,10 @@ import (
 	"fmt"
 	"net/url"
 	"os"
-	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/hyperifyio/goagent/internal/audit"
 )
 
 type input struct {
@@ -55,7 +56,7 @@ func run() error {
 	if err := enc.Encode(out); err != nil {
 		return fmt.Errorf("encode json: %w", err)
 	}
-	_ = appendAudit(map[string]any{ //nolint:errcheck
+	_ = audit.Append(map[string]any{ //nolint:errcheck
 		"ts":   time.Now().UTC().Format(time.RFC3339Nano),
 		"tool": "metadata_extract",
 		"ms":   0,
@@ -167,46 +168,3 @@ func attrValue(tag string, key string) string {
 	}
 	return tag[start : start+end]
 }
-
-// appendAudit writes an NDJSON line under .goagent/audit/YYYYMMDD.log at the repo root.
-func appendAudit(entry any) error {
-	b, err := json.Marshal(entry)
-	if err != nil {
-		return err
-	}
-	root := moduleRoot()
-	dir := filepath.Join(root, ".goagent", "audit")
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return err
-	}
-	fname := time.Now().UTC().Format("20060102") + ".log"
-	path := filepath.Join(dir, fname)
-	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
-	if err != nil {
-		return err
-	}
-	defer func() { _ = f.Close() }() //nolint:errcheck
-	if _, err := f.Write(append(b, '\n')); err != nil {
-		return err
-	}
-	return nil
-}
-
-// moduleRoot walks upward from CWD to the directory containing go.mod; falls back to CWD.
-func moduleRoot() string {
-	cwd, err := os.Getwd()
-	if err != nil || cwd == "" {
-		return "."
-	}
-	dir := cwd
-	for {
-		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
-			return dir
-		}
-		parent := filepath.Dir(dir)
-		if parent == dir {
-			return cwd
-		}
-		dir = parent
-	}
-}
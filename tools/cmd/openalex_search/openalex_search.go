@@ -9,10 +9,11 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/hyperifyio/goagent/internal/audit"
 )
 
 // input defines the expected stdin JSON for the tool.
@@ -74,7 +75,7 @@ func run() error {
 		return fmt.Errorf("encode json: %w", err)
 	}
 	// Best-effort audit; ignore errors.
-	_ = appendAudit(map[string]any{ //nolint:errcheck
+	_ = audit.Append(map[string]any{ //nolint:errcheck
 		"ts":       time.Now().UTC().Format(time.RFC3339Nano),
 		"tool":     "openalex_search",
 		"url_host": baseURL.Hostname(),
@@ -307,46 +308,3 @@ func isPrivateIP(ip net.IP) bool {
 	}
 	return false
 }
-
-// appendAudit writes an NDJSON line under .goagent/audit/YYYYMMDD.log at the repo root.
-func appendAudit(entry any) error {
-	b, err := json.Marshal(entry)
-	if err != nil {
-		return err
-	}
-	root := moduleRoot()
-	dir := filepath.Join(root, ".goagent", "audit")
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return err
-	}
-	fname := time.Now().UTC().Format("20060102") + ".log"
-	path := filepath.Join(dir, fname)
-	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
-	if err != nil {
-		return err
-	}
-	defer func() { _ = f.Close() }() //nolint:errcheck
-	if _, err := f.Write(append(b, '\n')); err != nil {
-		return err
-	}
-	return nil
-}
-
-// moduleRoot walks upward from CWD to the directory containing go.mod; falls back to CWD.
-func moduleRoot() string {
-	cwd, err := os.Getwd()
-	if err != nil || cwd == "" {
-		return "."
-	}
-	dir := cwd
-	for {
-		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
-			return dir
-		}
-		parent := filepath.Dir(dir)
-		if parent == dir {
-			return cwd
-		}
-		dir = parent
-	}
-}
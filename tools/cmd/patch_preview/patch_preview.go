@@ -0,0 +1,396 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hyperifyio/goagent/tools/workspace"
+)
+
+type previewInput struct {
+	UnifiedDiff string `json:"unifiedDiff"`
+	// Root names the workspace root the diff is checked against; the
+	// default root when empty
+	Root string `json:"root,omitempty"`
+}
+
+type hunkReport struct {
+	Index      int    `json:"index"`
+	OldStart   int    `json:"oldStart"`
+	OldLines   int    `json:"oldLines"`
+	NewStart   int    `json:"newStart"`
+	NewLines   int    `json:"newLines"`
+	Applicable bool   `json:"applicable"`
+	Offset     int    `json:"offset,omitempty"`
+	Conflict   string `json:"conflict,omitempty"`
+}
+
+type fileReport struct {
+	Path       string       `json:"path"`
+	Op         string       `json:"op"`
+	Applicable bool         `json:"applicable"`
+	Conflict   string       `json:"conflict,omitempty"`
+	OldSha256  string       `json:"oldSha256,omitempty"`
+	NewSha256  string       `json:"newSha256,omitempty"`
+	Hunks      []hunkReport `json:"hunks"`
+}
+
+type previewOutput struct {
+	Applicable bool         `json:"applicable"`
+	Files      []fileReport `json:"files"`
+}
+
+// filePatch is one file section of a unified diff. Lines keep their
+// trailing newline so comparisons are byte-exact.
+type filePatch struct {
+	oldPath string // empty for /dev/null
+	newPath string // empty for /dev/null
+	hunks   []hunk
+}
+
+type hunk struct {
+	oldStart, oldLines int
+	newStart, newLines int
+	old, new           []string
+}
+
+func main() {
+	in, err := readInput(os.Stdin)
+	if err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	if strings.TrimSpace(in.UnifiedDiff) == "" {
+		stderrJSON(errors.New("unifiedDiff is required"))
+		os.Exit(1)
+	}
+	if err := workspace.Chdir(in.Root); err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	patches, err := parseUnifiedDiff(in.UnifiedDiff)
+	if err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	out := previewOutput{Applicable: true}
+	for _, p := range patches {
+		rep, err := previewFile(p)
+		if err != nil {
+			stderrJSON(err)
+			os.Exit(1)
+		}
+		out.Applicable = out.Applicable && rep.Applicable
+		out.Files = append(out.Files, rep)
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(out); err != nil {
+		stderrJSON(fmt.Errorf("write stdout: %w", err))
+		os.Exit(1)
+	}
+}
+
+func readInput(r io.Reader) (previewInput, error) {
+	var in previewInput
+	b, err := io.ReadAll(bufio.NewReader(r))
+	if err != nil {
+		return in, fmt.Errorf("read stdin: %w", err)
+	}
+	if err := json.Unmarshal(b, &in); err != nil {
+		return in, fmt.Errorf("parse json: %w", err)
+	}
+	return in, nil
+}
+
+// parseUnifiedDiff splits a (possibly multi-file) unified diff into file
+// patches. Lines outside file sections, such as "diff --git" and "index"
+// headers, are ignored.
+func parseUnifiedDiff(diff string) ([]filePatch, error) {
+	lines := strings.SplitAfter(diff, "\n")
+	var patches []filePatch
+	for i := 0; i < len(lines); {
+		ln := lines[i]
+		if !strings.HasPrefix(ln, "--- ") {
+			i++
+			continue
+		}
+		if i+1 >= len(lines) || !strings.HasPrefix(lines[i+1], "+++ ") {
+			return nil, fmt.Errorf("BAD_DIFF: line %d: missing +++ header", i+2)
+		}
+		p := filePatch{oldPath: headerPath(ln, "--- ", "a/"), newPath: headerPath(lines[i+1], "+++ ", "b/")}
+		if p.oldPath == "" && p.newPath == "" {
+			return nil, fmt.Errorf("BAD_DIFF: line %d: both sides are /dev/null", i+1)
+		}
+		for _, path := range []string{p.oldPath, p.newPath} {
+			if path != "" {
+				if err := validateRelPath(path); err != nil {
+					return nil, err
+				}
+			}
+		}
+		i += 2
+		for i < len(lines) && strings.HasPrefix(lines[i], "@@ ") {
+			h, next, err := parseHunk(lines, i)
+			if err != nil {
+				return nil, err
+			}
+			p.hunks = append(p.hunks, h)
+			i = next
+		}
+		if len(p.hunks) == 0 {
+			return nil, fmt.Errorf("BAD_DIFF: %s: no hunks", p.displayPath())
+		}
+		patches = append(patches, p)
+	}
+	if len(patches) == 0 {
+		return nil, errors.New("BAD_DIFF: no file headers")
+	}
+	return patches, nil
+}
+
+// headerPath extracts the path from a ---/+++ header, dropping the git
+// prefix and any tab-separated timestamp. /dev/null yields "".
+func headerPath(line, marker, gitPrefix string) string {
+	p := strings.TrimRight(strings.TrimPrefix(line, marker), "\r\n")
+	if tab := strings.IndexByte(p, '\t'); tab >= 0 {
+		p = p[:tab]
+	}
+	p = strings.TrimSpace(p)
+	if p == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(p, gitPrefix)
+}
+
+// parseHunk reads the hunk starting at lines[i] and returns it with the index
+// of the first line after it. Body line counts must match the header.
+func parseHunk(lines []string, i int) (hunk, int, error) {
+	var h hunk
+	header := strings.TrimRight(lines[i], "\r\n")
+	fields := strings.Fields(header)
+	if len(fields) < 4 || fields[3] != "@@" {
+		return h, 0, fmt.Errorf("BAD_DIFF: line %d: malformed hunk header %q", i+1, header)
+	}
+	var err error
+	if h.oldStart, h.oldLines, err = parseRange(fields[1], "-"); err != nil {
+		return h, 0, fmt.Errorf("BAD_DIFF: line %d: %v", i+1, err)
+	}
+	if h.newStart, h.newLines, err = parseRange(fields[2], "+"); err != nil {
+		return h, 0, fmt.Errorf("BAD_DIFF: line %d: %v", i+1, err)
+	}
+	i++
+	for i < len(lines) && (len(h.old) < h.oldLines || len(h.new) < h.newLines) {
+		ln := lines[i]
+		if ln == "" {
+			break
+		}
+		body := ln[1:]
+		switch ln[0] {
+		case ' ':
+			h.old = append(h.old, body)
+			h.new = append(h.new, body)
+		case '-':
+			h.old = append(h.old, body)
+		case '+':
+			h.new = append(h.new, body)
+		case '\\':
+			// "\ No newline at end of file" before the hunk is complete
+			stripLastNewline(&h, lines[i-1][0])
+		case '\n':
+			// Some editors drop the leading space of empty context lines
+			h.old = append(h.old, "\n")
+			h.new = append(h.new, "\n")
+		default:
+			return h, 0, fmt.Errorf("BAD_DIFF: line %d: unexpected hunk line %q", i+1, strings.TrimRight(ln, "\n"))
+		}
+		i++
+	}
+	if len(h.old) != h.oldLines || len(h.new) != h.newLines {
+		return h, 0, fmt.Errorf("BAD_DIFF: hunk %q: body has -%d/+%d lines", header, len(h.old), len(h.new))
+	}
+	if i < len(lines) && strings.HasPrefix(lines[i], "\\") {
+		stripLastNewline(&h, lines[i-1][0])
+		i++
+	}
+	return h, i, nil
+}
+
+// stripLastNewline applies a "\ No newline at end of file" marker to the
+// side(s) the preceding line belongs to.
+func stripLastNewline(h *hunk, kind byte) {
+	trim := func(s []string) {
+		if n := len(s); n > 0 {
+			s[n-1] = strings.TrimSuffix(s[n-1], "\n")
+		}
+	}
+	if kind != '+' {
+		trim(h.old)
+	}
+	if kind != '-' {
+		trim(h.new)
+	}
+}
+
+// parseRange parses "-l,s" or "+l,s"; a missing count means 1.
+func parseRange(s, sign string) (int, int, error) {
+	if !strings.HasPrefix(s, sign) {
+		return 0, 0, fmt.Errorf("bad range %q", s)
+	}
+	start, count, found := strings.Cut(s[1:], ",")
+	l, err := strconv.Atoi(start)
+	if err != nil || l < 0 {
+		return 0, 0, fmt.Errorf("bad range %q", s)
+	}
+	n := 1
+	if found {
+		if n, err = strconv.Atoi(count); err != nil || n < 0 {
+			return 0, 0, fmt.Errorf("bad range %q", s)
+		}
+	}
+	return l, n, nil
+}
+
+func (p filePatch) displayPath() string {
+	if p.newPath != "" {
+		return p.newPath
+	}
+	return p.oldPath
+}
+
+// previewFile checks each hunk against the file's current contents in order,
+// applying applicable hunks to an in-memory copy so later hunks see earlier
+// edits. The resulting hash is reported only when every hunk applies.
+func previewFile(p filePatch) (fileReport, error) {
+	rep := fileReport{Path: p.displayPath(), Applicable: true}
+	switch {
+	case p.oldPath == "":
+		rep.Op = "create"
+	case p.newPath == "":
+		rep.Op = "delete"
+	case p.oldPath != p.newPath:
+		rep.Op = "rename"
+	default:
+		rep.Op = "modify"
+	}
+
+	var cur []string
+	if p.oldPath != "" {
+		b, err := os.ReadFile(p.oldPath)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			rep.Applicable = false
+			rep.Conflict = "file does not exist"
+		case err != nil:
+			return rep, fmt.Errorf("read %s: %w", p.oldPath, err)
+		default:
+			rep.OldSha256 = sha256Hex(b)
+			cur = strings.SplitAfter(string(b), "\n")
+			if cur[len(cur)-1] == "" {
+				cur = cur[:len(cur)-1]
+			}
+		}
+	}
+	if p.newPath != "" && p.newPath != p.oldPath {
+		if _, err := os.Lstat(p.newPath); err == nil {
+			rep.Applicable = false
+			rep.Conflict = "target already exists"
+		}
+	}
+
+	delta, floor := 0, 0
+	for i, h := range p.hunks {
+		hr := hunkReport{Index: i + 1, OldStart: h.oldStart, OldLines: h.oldLines, NewStart: h.newStart, NewLines: h.newLines}
+		if rep.Conflict != "" {
+			hr.Conflict = rep.Conflict
+			rep.Hunks = append(rep.Hunks, hr)
+			continue
+		}
+		// A zero-length old range names the line after which the hunk inserts
+		want := h.oldStart - 1 + delta
+		if h.oldLines == 0 {
+			want = h.oldStart + delta
+		}
+		at, ok := locate(cur, h.old, want, floor)
+		if ok {
+			hr.Applicable = true
+			hr.Offset = at - want
+			cur = append(cur[:at], append(append([]string(nil), h.new...), cur[at+len(h.old):]...)...)
+			delta += len(h.new) - len(h.old) + hr.Offset
+			floor = at + len(h.new)
+		} else {
+			rep.Applicable = false
+			hr.Conflict = fmt.Sprintf("context does not match at line %d", h.oldStart)
+		}
+		rep.Hunks = append(rep.Hunks, hr)
+	}
+	if rep.Applicable && rep.Op != "delete" {
+		rep.NewSha256 = sha256Hex([]byte(strings.Join(cur, "")))
+	}
+	if rep.Applicable && rep.Op == "delete" && len(cur) != 0 {
+		rep.Applicable = false
+		rep.Conflict = "file has content beyond the deleted lines"
+	}
+	return rep, nil
+}
+
+// locate finds old in cur, preferring index want and then the nearest match
+// on either side of it. Hunks must land after the previous one, so no match
+// is accepted before floor.
+func locate(cur, old []string, want, floor int) (int, bool) {
+	if want < floor {
+		want = floor
+	}
+	if want > len(cur) {
+		want = len(cur)
+	}
+	matches := func(at int) bool {
+		if at < floor || at+len(old) > len(cur) {
+			return false
+		}
+		for j, s := range old {
+			if cur[at+j] != s {
+				return false
+			}
+		}
+		return true
+	}
+	for d := 0; d <= len(cur); d++ {
+		if matches(want - d) {
+			return want - d, true
+		}
+		if d > 0 && matches(want+d) {
+			return want + d, true
+		}
+	}
+	return 0, false
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func validateRelPath(p string) error {
+	if filepath.IsAbs(p) {
+		return fmt.Errorf("ABSOLUTE_PATH: %s", p)
+	}
+	clean := filepath.ToSlash(filepath.Clean(p))
+	if clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(clean, "/../") {
+		return fmt.Errorf("PATH_ESCAPE: %s", p)
+	}
+	return nil
+}
+
+func stderrJSON(err error) {
+	msg := err.Error()
+	msg = strings.ReplaceAll(msg, "\n", " ")
+	fmt.Fprintf(os.Stderr, "{\"error\":%q}\n", msg)
+}
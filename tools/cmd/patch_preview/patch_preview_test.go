@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	testutil "github.com/hyperifyio/goagent/tools/testutil"
+)
+
+func runPatchPreviewInDir(t *testing.T, dir, diff string) (previewOutput, string, int) {
+	t.Helper()
+	bin := testutil.BuildTool(t, "patch_preview")
+	data, err := json.Marshal(map[string]any{"unifiedDiff": diff})
+	if err != nil {
+		t.Fatalf("marshal input: %v", err)
+	}
+	cmd := exec.Command(bin)
+	cmd.Dir = dir
+	cmd.Stdin = bytes.NewReader(data)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	code := 0
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok {
+			code = ee.ExitCode()
+		} else {
+			code = 1
+		}
+	}
+	var out previewOutput
+	if code == 0 {
+		if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+			t.Fatalf("unmarshal stdout: %v; raw=%q", err, stdout.String())
+		}
+	}
+	return out, stderr.String(), code
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func hashOf(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestPatchPreview_ApplicableHunksAndHashes(t *testing.T) {
+	dir := t.TempDir()
+	before := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\n"
+	writeFile(t, dir, "a.txt", before)
+	diff := "diff --git a/a.txt b/a.txt\n" +
+		"--- a/a.txt\n" +
+		"+++ b/a.txt\n" +
+		"@@ -1,2 +1,2 @@\n" +
+		"-one\n" +
+		"+ONE\n" +
+		" two\n" +
+		"@@ -7,2 +7,3 @@\n" +
+		" seven\n" +
+		" eight\n" +
+		"+nine\n"
+	out, stderr, code := runPatchPreviewInDir(t, dir, diff)
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, stderr)
+	}
+	if !out.Applicable || len(out.Files) != 1 {
+		t.Fatalf("out=%+v", out)
+	}
+	f := out.Files[0]
+	if f.Path != "a.txt" || f.Op != "modify" || len(f.Hunks) != 2 || !f.Hunks[0].Applicable || !f.Hunks[1].Applicable {
+		t.Fatalf("file=%+v", f)
+	}
+	if f.OldSha256 != hashOf(before) {
+		t.Fatalf("oldSha256=%s", f.OldSha256)
+	}
+	if want := hashOf("ONE\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\n"); f.NewSha256 != want {
+		t.Fatalf("newSha256=%s want %s", f.NewSha256, want)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "a.txt")); string(b) != before {
+		t.Fatalf("file was modified: %q", b)
+	}
+}
+
+func TestPatchPreview_ReportsConflictAndOffset(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.txt", "header\nalpha\nbeta\ngamma\n")
+	diff := "--- a/a.txt\n" +
+		"+++ b/a.txt\n" +
+		"@@ -1,2 +1,2 @@\n" +
+		" alpha\n" +
+		"-beta\n" +
+		"+BETA\n" +
+		"@@ -3,1 +3,1 @@\n" +
+		"-delta\n" +
+		"+DELTA\n"
+	out, stderr, code := runPatchPreviewInDir(t, dir, diff)
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, stderr)
+	}
+	f := out.Files[0]
+	if out.Applicable || f.Applicable || f.NewSha256 != "" {
+		t.Fatalf("want not applicable: %+v", out)
+	}
+	if h := f.Hunks[0]; !h.Applicable || h.Offset != 1 {
+		t.Fatalf("hunk 1=%+v", h)
+	}
+	if h := f.Hunks[1]; h.Applicable || !strings.Contains(h.Conflict, "line 3") {
+		t.Fatalf("hunk 2=%+v", h)
+	}
+}
+
+func TestPatchPreview_CreateDeleteAndNoNewline(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "old.txt", "bye\n")
+	writeFile(t, dir, "tail.txt", "x\ny")
+	diff := "--- /dev/null\n" +
+		"+++ b/new.txt\n" +
+		"@@ -0,0 +1,1 @@\n" +
+		"+hello\n" +
+		"--- a/old.txt\n" +
+		"+++ /dev/null\n" +
+		"@@ -1 +0,0 @@\n" +
+		"-bye\n" +
+		"--- a/tail.txt\n" +
+		"+++ b/tail.txt\n" +
+		"@@ -1,2 +1,2 @@\n" +
+		" x\n" +
+		"-y\n" +
+		"\\ No newline at end of file\n" +
+		"+z\n"
+	out, stderr, code := runPatchPreviewInDir(t, dir, diff)
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, stderr)
+	}
+	if !out.Applicable || len(out.Files) != 3 {
+		t.Fatalf("out=%+v", out)
+	}
+	if f := out.Files[0]; f.Op != "create" || f.NewSha256 != hashOf("hello\n") {
+		t.Fatalf("create=%+v", f)
+	}
+	if f := out.Files[1]; f.Op != "delete" || f.NewSha256 != "" || f.OldSha256 != hashOf("bye\n") {
+		t.Fatalf("delete=%+v", f)
+	}
+	if f := out.Files[2]; f.NewSha256 != hashOf("x\nz\n") {
+		t.Fatalf("tail=%+v", f)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "new.txt")); !os.IsNotExist(err) {
+		t.Fatalf("new.txt was created")
+	}
+}
+
+func TestPatchPreview_MissingFileAndExistingTarget(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "exists.txt", "x\n")
+	diff := "--- a/missing.txt\n" +
+		"+++ b/missing.txt\n" +
+		"@@ -1 +1 @@\n" +
+		"-a\n" +
+		"+b\n" +
+		"--- /dev/null\n" +
+		"+++ b/exists.txt\n" +
+		"@@ -0,0 +1 @@\n" +
+		"+y\n"
+	out, stderr, code := runPatchPreviewInDir(t, dir, diff)
+	if code != 0 {
+		t.Fatalf("exit=%d stderr=%s", code, stderr)
+	}
+	if out.Applicable || out.Files[0].Conflict != "file does not exist" || out.Files[1].Conflict != "target already exists" {
+		t.Fatalf("out=%+v", out)
+	}
+}
+
+func TestPatchPreview_RejectsBadInput(t *testing.T) {
+	dir := t.TempDir()
+	cases := map[string]string{
+		"escape":   "--- a/../x\n+++ b/../x\n@@ -1 +1 @@\n-a\n+b\n",
+		"absolute": "--- /etc/passwd\n+++ /etc/passwd\n@@ -1 +1 @@\n-a\n+b\n",
+		"counts":   "--- a/x\n+++ b/x\n@@ -1,2 +1,1 @@\n-a\n+b\n",
+		"noheader": "@@ -1 +1 @@\n-a\n+b\n",
+	}
+	for name, diff := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, stderr, code := runPatchPreviewInDir(t, dir, diff)
+			if code == 0 || !strings.Contains(stderr, "\"error\"") {
+				t.Fatalf("exit=%d stderr=%s", code, stderr)
+			}
+		})
+	}
+}
+
+// TestPatchPreview_ChecksWorkspaceRoot verifies the diff is checked against
+// the -workspace root that fs_apply_patch applies it to, not the working
+// directory.
+func TestPatchPreview_ChecksWorkspaceRoot(t *testing.T) {
+	cwd, ws := t.TempDir(), t.TempDir()
+	writeFile(t, ws, "notes.txt", "first\n")
+	t.Setenv("GOAGENT_WORKSPACE_ROOT", ws)
+	diff := "--- a/notes.txt\n+++ b/notes.txt\n@@ -1,1 +1,2 @@\n first\n+second\n"
+	out, stderr, code := runPatchPreviewInDir(t, cwd, diff)
+	if code != 0 || !out.Applicable || len(out.Files) != 1 || out.Files[0].NewSha256 != hashOf("first\nsecond\n") {
+		t.Fatalf("exit=%d stderr=%q out=%+v", code, stderr, out)
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hyperifyio/goagent/tools/workspace"
+)
+
+type replaceInput struct {
+	Pattern         string   `json:"pattern"`
+	Replacement     string   `json:"replacement"`
+	Regex           bool     `json:"regex,omitempty"`
+	Globs           []string `json:"globs,omitempty"`
+	MaxReplacements int      `json:"maxReplacements,omitempty"`
+	DryRun          bool     `json:"dryRun,omitempty"`
+	// Root names the workspace root to edit; the default root when empty
+	Root string `json:"root,omitempty"`
+}
+
+type fileChange struct {
+	Path         string `json:"path"`
+	Replacements int    `json:"replacements"`
+	Lines        []int  `json:"lines"`
+}
+
+type replaceOutput struct {
+	Files             []fileChange `json:"files"`
+	TotalReplacements int          `json:"totalReplacements"`
+	FilesChanged      int          `json:"filesChanged"`
+	Skipped           []string     `json:"skipped,omitempty"`
+	DryRun            bool         `json:"dryRun,omitempty"`
+}
+
+// maxFileBytes bounds the size of any single file that will be rewritten;
+// larger files are reported as skipped rather than loaded into memory.
+const maxFileBytes = 1 << 20 // 1 MiB
+
+// binarySniffBytes is how much of a file is checked for NUL bytes before it
+// is treated as binary and left alone.
+const binarySniffBytes = 8000
+
+// pending is a file whose new content has been computed but not written.
+type pending struct {
+	path    string
+	content []byte
+	mode    os.FileMode
+}
+
+func main() {
+	in, err := readInput(os.Stdin)
+	if err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	if err := workspace.Chdir(in.Root); err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	out, err := replace(in)
+	if err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	// Files in a named root carry its prefix so they can be passed back
+	for i := range out.Files {
+		out.Files[i].Path = workspace.Display(in.Root, out.Files[i].Path)
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(out); err != nil {
+		stderrJSON(fmt.Errorf("encode json: %w", err))
+		os.Exit(1)
+	}
+}
+
+func readInput(r io.Reader) (replaceInput, error) {
+	var in replaceInput
+	b, err := io.ReadAll(bufio.NewReader(r))
+	if err != nil {
+		return in, fmt.Errorf("read stdin: %w", err)
+	}
+	if err := json.Unmarshal(b, &in); err != nil {
+		return in, fmt.Errorf("parse json: %w", err)
+	}
+	if in.Pattern == "" {
+		return in, errors.New("pattern is required")
+	}
+	if in.MaxReplacements < 0 {
+		return in, errors.New("maxReplacements must be >= 0")
+	}
+	for _, g := range in.Globs {
+		if filepath.IsAbs(g) {
+			return in, fmt.Errorf("ABSOLUTE_PATH: %s", g)
+		}
+		if clean := filepath.ToSlash(filepath.Clean(g)); clean == ".." || strings.HasPrefix(clean, "../") {
+			return in, fmt.Errorf("PATH_ESCAPE: %s", g)
+		}
+	}
+	return in, nil
+}
+
+// replacer finds the matches of the input pattern in one file's content and
+// produces the rewritten content.
+type replacer struct {
+	rx  *regexp.Regexp
+	lit []byte
+	rep []byte
+}
+
+func newReplacer(in replaceInput) (*replacer, error) {
+	r := &replacer{rep: []byte(in.Replacement)}
+	if !in.Regex {
+		r.lit = []byte(in.Pattern)
+		return r, nil
+	}
+	rx, err := regexp.Compile(in.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("BAD_REGEX: %w", err)
+	}
+	// An empty match would insert the replacement between every character
+	if rx.MatchString("") {
+		return nil, fmt.Errorf("BAD_REGEX: pattern %q matches the empty string", in.Pattern)
+	}
+	r.rx = rx
+	return r, nil
+}
+
+// apply returns the rewritten content and the byte offsets of each match in
+// the original data.
+func (r *replacer) apply(data []byte) ([]byte, []int) {
+	var starts []int
+	if r.rx != nil {
+		locs := r.rx.FindAllIndex(data, -1)
+		if len(locs) == 0 {
+			return data, nil
+		}
+		for _, loc := range locs {
+			starts = append(starts, loc[0])
+		}
+		return r.rx.ReplaceAll(data, r.rep), starts
+	}
+	for i := 0; ; {
+		j := bytes.Index(data[i:], r.lit)
+		if j < 0 {
+			break
+		}
+		starts = append(starts, i+j)
+		i += j + len(r.lit)
+	}
+	if len(starts) == 0 {
+		return data, nil
+	}
+	return bytes.ReplaceAll(data, r.lit, r.rep), starts
+}
+
+// nolint:gocyclo // Coordinating walk, filter, and rewrite raises complexity; covered by tests.
+func replace(in replaceInput) (replaceOutput, error) {
+	r, err := newReplacer(in)
+	if err != nil {
+		return replaceOutput{}, err
+	}
+	files, err := collectFiles(in.Globs)
+	if err != nil {
+		return replaceOutput{}, err
+	}
+	out := replaceOutput{Files: []fileChange{}, DryRun: in.DryRun}
+	var writes []pending
+	for _, f := range files {
+		fi, err := os.Lstat(f)
+		if err != nil || !fi.Mode().IsRegular() {
+			continue
+		}
+		if fi.Size() > maxFileBytes {
+			out.Skipped = append(out.Skipped, f)
+			continue
+		}
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return replaceOutput{}, fmt.Errorf("read %s: %w", f, err)
+		}
+		head := data
+		if len(head) > binarySniffBytes {
+			head = head[:binarySniffBytes]
+		}
+		if bytes.IndexByte(head, 0) >= 0 {
+			out.Skipped = append(out.Skipped, f)
+			continue
+		}
+		updated, starts := r.apply(data)
+		if len(starts) == 0 {
+			continue
+		}
+		out.Files = append(out.Files, fileChange{Path: filepath.ToSlash(f), Replacements: len(starts), Lines: lineNumbers(data, starts)})
+		out.TotalReplacements += len(starts)
+		if in.MaxReplacements > 0 && out.TotalReplacements > in.MaxReplacements {
+			return replaceOutput{}, fmt.Errorf("TOO_MANY_REPLACEMENTS: more than %d matches; no files were changed", in.MaxReplacements)
+		}
+		if !bytes.Equal(updated, data) {
+			writes = append(writes, pending{path: f, content: updated, mode: fi.Mode().Perm()})
+		}
+	}
+	out.FilesChanged = len(writes)
+	if in.DryRun {
+		return out, nil
+	}
+	// Every file is checked before the first write so a rejected call
+	// leaves the tree untouched
+	for _, w := range writes {
+		if err := atomicWriteFile(w.path, w.content, w.mode); err != nil {
+			return replaceOutput{}, fmt.Errorf("write %s: %w", w.path, err)
+		}
+	}
+	return out, nil
+}
+
+// collectFiles walks the repository and returns the files matching any glob,
+// in lexical order. VCS metadata and build output directories are skipped.
+func collectFiles(globs []string) ([]string, error) {
+	if len(globs) == 0 {
+		globs = []string{"**/*"}
+	}
+	var files []string
+	walkErr := filepath.WalkDir(".", func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path == ".git" || path == "bin" || path == "logs" || path == filepath.Join("tools", "bin") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		for _, g := range globs {
+			if matchSimpleGlob(path, g) {
+				files = append(files, path)
+				break
+			}
+		}
+		return nil
+	})
+	if walkErr != nil {
+		return nil, walkErr
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// lineNumbers maps ascending byte offsets in data to their distinct 1-based
+// line numbers.
+func lineNumbers(data []byte, starts []int) []int {
+	var lines []int
+	line, pos := 1, 0
+	for _, s := range starts {
+		line += bytes.Count(data[pos:s], []byte{'\n'})
+		pos = s
+		if len(lines) == 0 || lines[len(lines)-1] != line {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// matchSimpleGlob performs minimal glob matching:
+// supports patterns like "**/*.ext", "*.ext", and exact filenames.
+func matchSimpleGlob(path, pattern string) bool {
+	pattern = filepath.ToSlash(pattern)
+	path = filepath.ToSlash(path)
+	if pattern == "**/*" || pattern == "**" || pattern == "*" {
+		return true
+	}
+	if strings.HasPrefix(pattern, "**/") {
+		suffix := strings.TrimPrefix(pattern, "**/")
+		if strings.HasPrefix(suffix, "*.") {
+			return strings.HasSuffix(path, strings.TrimPrefix(suffix, "*"))
+		}
+		return strings.HasSuffix(path, suffix)
+	}
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(path, strings.TrimPrefix(pattern, "*"))
+	}
+	return path == pattern
+}
+
+// atomicWriteFile replaces path via a temporary file in the same directory
+// so readers never observe a partially written file.
+func atomicWriteFile(path string, data []byte, mode os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // gone after the rename
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close() //nolint:errcheck // already failing
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func stderrJSON(err error) {
+	msg := err.Error()
+	msg = strings.ReplaceAll(msg, "\n", " ")
+	fmt.Fprintf(os.Stderr, "{\"error\":%q}\n", msg)
+}
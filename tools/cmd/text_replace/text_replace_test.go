@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	testutil "github.com/hyperifyio/goagent/tools/testutil"
+)
+
+type textReplaceOutput struct {
+	Files []struct {
+		Path         string `json:"path"`
+		Replacements int    `json:"replacements"`
+		Lines        []int  `json:"lines"`
+	} `json:"files"`
+	TotalReplacements int      `json:"totalReplacements"`
+	FilesChanged      int      `json:"filesChanged"`
+	Skipped           []string `json:"skipped"`
+	DryRun            bool     `json:"dryRun"`
+}
+
+// runTextReplace executes the text_replace tool in dir with the given input.
+func runTextReplace(t *testing.T, bin, dir string, input any) (textReplaceOutput, string, int) {
+	t.Helper()
+	data, err := json.Marshal(input)
+	if err != nil {
+		t.Fatalf("marshal input: %v", err)
+	}
+	cmd := exec.Command(bin)
+	cmd.Dir = dir
+	cmd.Stdin = bytes.NewReader(data)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	code := 0
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok {
+			code = ee.ExitCode()
+		} else {
+			code = 1
+		}
+	}
+	var out textReplaceOutput
+	if code == 0 {
+		if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+			t.Fatalf("unmarshal stdout: %v; raw=%q", err, stdout.String())
+		}
+	}
+	return out, stderr.String(), code
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+}
+
+func readFile(t *testing.T, p string) string {
+	t.Helper()
+	b, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatalf("read %s: %v", p, err)
+	}
+	return string(b)
+}
+
+func TestTextReplace_LiteralAcrossGlobs(t *testing.T) {
+	bin := testutil.BuildTool(t, "text_replace")
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.go":       "foo := 1\nbar(foo)\n",
+		"sub/b.go":   "// foo\n",
+		"notes.md":   "foo stays\n",
+		".git/HEAD":  "foo\n",
+		"sub/c.go.x": "foo\n",
+	})
+	out, stderr, code := runTextReplace(t, bin, dir, map[string]any{"pattern": "foo", "replacement": "baz", "globs": []string{"**/*.go"}})
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if out.TotalReplacements != 3 || out.FilesChanged != 2 || len(out.Files) != 2 {
+		t.Fatalf("unexpected report: %+v", out)
+	}
+	if out.Files[0].Path != "a.go" || out.Files[0].Replacements != 2 || len(out.Files[0].Lines) != 2 || out.Files[0].Lines[1] != 2 {
+		t.Fatalf("unexpected a.go change: %+v", out.Files[0])
+	}
+	if got := readFile(t, filepath.Join(dir, "a.go")); got != "baz := 1\nbar(baz)\n" {
+		t.Fatalf("a.go = %q", got)
+	}
+	if got := readFile(t, filepath.Join(dir, "notes.md")); got != "foo stays\n" {
+		t.Fatalf("notes.md changed: %q", got)
+	}
+	if got := readFile(t, filepath.Join(dir, ".git", "HEAD")); got != "foo\n" {
+		t.Fatalf(".git changed: %q", got)
+	}
+}
+
+func TestTextReplace_RegexCaptureGroups(t *testing.T) {
+	bin := testutil.BuildTool(t, "text_replace")
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"v.txt": "version=1.2\nname=x\n"})
+	out, stderr, code := runTextReplace(t, bin, dir, map[string]any{"pattern": `version=(\d+)\.(\d+)`, "replacement": "version=${1}.${2}.0", "regex": true})
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if out.TotalReplacements != 1 {
+		t.Fatalf("unexpected report: %+v", out)
+	}
+	if got := readFile(t, filepath.Join(dir, "v.txt")); got != "version=1.2.0\nname=x\n" {
+		t.Fatalf("v.txt = %q", got)
+	}
+}
+
+func TestTextReplace_DryRunLeavesFiles(t *testing.T) {
+	bin := testutil.BuildTool(t, "text_replace")
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.txt": "old\nold\n"})
+	out, stderr, code := runTextReplace(t, bin, dir, map[string]any{"pattern": "old", "replacement": "new", "dryRun": true})
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if !out.DryRun || out.TotalReplacements != 2 || out.FilesChanged != 1 {
+		t.Fatalf("unexpected report: %+v", out)
+	}
+	if got := readFile(t, filepath.Join(dir, "a.txt")); got != "old\nold\n" {
+		t.Fatalf("dry run wrote: %q", got)
+	}
+}
+
+func TestTextReplace_MaxReplacementsRejectsBeforeWriting(t *testing.T) {
+	bin := testutil.BuildTool(t, "text_replace")
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.txt": "x x\n", "b.txt": "x\n"})
+	_, stderr, code := runTextReplace(t, bin, dir, map[string]any{"pattern": "x", "replacement": "y", "maxReplacements": 2})
+	if code == 0 || !strings.Contains(stderr, "TOO_MANY_REPLACEMENTS") {
+		t.Fatalf("expected TOO_MANY_REPLACEMENTS, got code=%d stderr=%s", code, stderr)
+	}
+	if readFile(t, filepath.Join(dir, "a.txt")) != "x x\n" || readFile(t, filepath.Join(dir, "b.txt")) != "x\n" {
+		t.Fatalf("files were modified")
+	}
+}
+
+func TestTextReplace_SkipsBinaryAndKeepsMode(t *testing.T) {
+	bin := testutil.BuildTool(t, "text_replace")
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"blob.bin": "key\x00key"})
+	script := filepath.Join(dir, "run.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho key\n"), 0o755); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	out, stderr, code := runTextReplace(t, bin, dir, map[string]any{"pattern": "key", "replacement": "value"})
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if len(out.Skipped) != 1 || out.Skipped[0] != "blob.bin" {
+		t.Fatalf("expected blob.bin skipped: %+v", out)
+	}
+	if got := readFile(t, filepath.Join(dir, "blob.bin")); got != "key\x00key" {
+		t.Fatalf("binary file changed: %q", got)
+	}
+	fi, err := os.Stat(script)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if fi.Mode().Perm() != 0o755 {
+		t.Fatalf("mode = %v, want 0755", fi.Mode().Perm())
+	}
+	if got := readFile(t, script); got != "#!/bin/sh\necho value\n" {
+		t.Fatalf("run.sh = %q", got)
+	}
+}
+
+func TestTextReplace_RejectsEmptyMatchingRegex(t *testing.T) {
+	bin := testutil.BuildTool(t, "text_replace")
+	dir := t.TempDir()
+	_, stderr, code := runTextReplace(t, bin, dir, map[string]any{"pattern": "a*", "replacement": "b", "regex": true})
+	if code == 0 || !strings.Contains(stderr, "BAD_REGEX") {
+		t.Fatalf("expected BAD_REGEX, got code=%d stderr=%s", code, stderr)
+	}
+}
+
+// TestTextReplace_WorkspaceRoots verifies edits land in the -workspace root
+// and in a named root, not the working directory, and that an unknown root
+// is refused.
+func TestTextReplace_WorkspaceRoots(t *testing.T) {
+	bin := testutil.BuildTool(t, "text_replace")
+	cwd, ws, api := t.TempDir(), t.TempDir(), t.TempDir()
+	for _, dir := range []string{cwd, ws, api} {
+		writeFiles(t, dir, map[string]string{"a.go": "foo\n"})
+	}
+	t.Setenv("GOAGENT_WORKSPACE_ROOT", ws)
+	t.Setenv("GOAGENT_WORKSPACE_ROOTS", "api="+api)
+
+	if _, stderr, code := runTextReplace(t, bin, cwd, map[string]any{"pattern": "foo", "replacement": "bar"}); code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	out, stderr, code := runTextReplace(t, bin, cwd, map[string]any{"pattern": "foo", "replacement": "baz", "root": "api"})
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if len(out.Files) != 1 || out.Files[0].Path != "api:a.go" {
+		t.Fatalf("unexpected report: %+v", out)
+	}
+	for dir, want := range map[string]string{cwd: "foo\n", ws: "bar\n", api: "baz\n"} {
+		if got := readFile(t, filepath.Join(dir, "a.go")); got != want {
+			t.Fatalf("%s/a.go = %q, want %q", dir, got, want)
+		}
+	}
+	if _, stderr, code := runTextReplace(t, bin, cwd, map[string]any{"pattern": "foo", "replacement": "x", "root": "web"}); code == 0 || !strings.Contains(stderr, "UNKNOWN_ROOT") {
+		t.Fatalf("expected UNKNOWN_ROOT, got exit=%d stderr=%q", code, stderr)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// provider turns a query into one HTTP request against a search API and
+// parses its JSON response into results.
+type provider struct {
+	name string
+	// env names the variables the provider needs, for error hints.
+	env   string
+	build func(in input) (*http.Request, error)
+	parse func(body []byte) ([]result, error)
+}
+
+// providers are listed in auto-detection order.
+var providers = []provider{
+	{name: "searxng", env: "SEARXNG_BASE_URL", build: buildSearxng, parse: parseSearxng},
+	{name: "brave", env: "BRAVE_API_KEY", build: buildBrave, parse: parseBrave},
+	{name: "bing", env: "BING_API_KEY", build: buildBing, parse: parseBing},
+}
+
+// selectProvider returns the provider named by the input or
+// WEB_SEARCH_PROVIDER, or else the first one whose env is configured.
+func selectProvider(name string) (provider, error) {
+	if name == "" {
+		name = strings.ToLower(strings.TrimSpace(os.Getenv("WEB_SEARCH_PROVIDER")))
+	}
+	for _, p := range providers {
+		if name == p.name || (name == "" && strings.TrimSpace(os.Getenv(p.env)) != "") {
+			return p, nil
+		}
+	}
+	if name != "" {
+		return provider{}, fmt.Errorf("unknown provider %q (want searxng, brave, or bing)", name)
+	}
+	return provider{}, hinted(errors.New("no search provider configured"), "set SEARXNG_BASE_URL, BRAVE_API_KEY, or BING_API_KEY and list it in the tool's envPassthrough")
+}
+
+// endpoint returns the provider base URL from env, or def when unset.
+func endpoint(envName, def string) (*url.URL, error) {
+	base := strings.TrimSpace(os.Getenv(envName))
+	if base == "" {
+		base = def
+	}
+	u, err := url.Parse(base)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%s must be a valid http/https URL", envName)
+	}
+	return u, nil
+}
+
+func apiKey(envName string) (string, error) {
+	key := strings.TrimSpace(os.Getenv(envName))
+	if key == "" {
+		return "", hinted(fmt.Errorf("%s is required", envName), "export "+envName+"=... and add it to envPassthrough in tools.json")
+	}
+	return key, nil
+}
+
+func buildSearxng(in input) (*http.Request, error) {
+	base := strings.TrimSpace(os.Getenv("SEARXNG_BASE_URL"))
+	if base == "" {
+		return nil, hinted(errors.New("SEARXNG_BASE_URL is required"), "export SEARXNG_BASE_URL=http://localhost:8888")
+	}
+	u, err := endpoint("SEARXNG_BASE_URL", "")
+	if err != nil {
+		return nil, err
+	}
+	u.Path = strings.TrimRight(u.Path, "/") + "/search"
+	q := u.Query()
+	q.Set("format", "json")
+	q.Set("q", in.Q)
+	if in.TimeRange != "" {
+		q.Set("time_range", in.TimeRange)
+	}
+	if in.Language != "" {
+		q.Set("language", in.Language)
+	}
+	u.RawQuery = q.Encode()
+	return http.NewRequest(http.MethodGet, u.String(), nil)
+}
+
+func parseSearxng(body []byte) ([]result, error) {
+	var raw struct {
+		Results []struct {
+			Title         string `json:"title"`
+			URL           string `json:"url"`
+			Content       string `json:"content"`
+			Engine        string `json:"engine"`
+			PublishedDate string `json:"publishedDate"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, err
+	}
+	out := make([]result, 0, len(raw.Results))
+	for _, r := range raw.Results {
+		out = append(out, result{Title: r.Title, URL: r.URL, Snippet: r.Content, Source: r.Engine, PublishedAt: r.PublishedDate})
+	}
+	return out, nil
+}
+
+// braveFreshness maps time_range to Brave's freshness codes.
+var braveFreshness = map[string]string{"day": "pd", "week": "pw", "month": "pm", "year": "py"}
+
+func buildBrave(in input) (*http.Request, error) {
+	key, err := apiKey("BRAVE_API_KEY")
+	if err != nil {
+		return nil, err
+	}
+	u, err := endpoint("BRAVE_BASE_URL", "https://api.search.brave.com/res/v1/web/search")
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("q", in.Q)
+	q.Set("count", strconv.Itoa(min(in.Size, 20)))
+	if f := braveFreshness[in.TimeRange]; f != "" {
+		q.Set("freshness", f)
+	}
+	if in.Language != "" {
+		q.Set("search_lang", in.Language)
+	}
+	u.RawQuery = q.Encode()
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Subscription-Token", key)
+	return req, nil
+}
+
+func parseBrave(body []byte) ([]result, error) {
+	var raw struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+				PageAge     string `json:"page_age"`
+				Profile     struct {
+					Name string `json:"name"`
+				} `json:"profile"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, err
+	}
+	out := make([]result, 0, len(raw.Web.Results))
+	for _, r := range raw.Web.Results {
+		out = append(out, result{Title: r.Title, URL: r.URL, Snippet: r.Description, Source: r.Profile.Name, PublishedAt: r.PageAge})
+	}
+	return out, nil
+}
+
+// bingFreshness maps time_range to Bing's freshness values; Bing has no
+// yearly bucket, so "year" is not sent.
+var bingFreshness = map[string]string{"day": "Day", "week": "Week", "month": "Month"}
+
+func buildBing(in input) (*http.Request, error) {
+	key, err := apiKey("BING_API_KEY")
+	if err != nil {
+		return nil, err
+	}
+	u, err := endpoint("BING_BASE_URL", "https://api.bing.microsoft.com/v7.0/search")
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("q", in.Q)
+	q.Set("count", strconv.Itoa(in.Size))
+	q.Set("responseFilter", "Webpages")
+	if f := bingFreshness[in.TimeRange]; f != "" {
+		q.Set("freshness", f)
+	}
+	if in.Language != "" {
+		q.Set("setLang", in.Language)
+	}
+	u.RawQuery = q.Encode()
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Ocp-Apim-Subscription-Key", key)
+	return req, nil
+}
+
+func parseBing(body []byte) ([]result, error) {
+	var raw struct {
+		WebPages struct {
+			Value []struct {
+				Name          string `json:"name"`
+				URL           string `json:"url"`
+				Snippet       string `json:"snippet"`
+				SiteName      string `json:"siteName"`
+				DatePublished string `json:"datePublished"`
+			} `json:"value"`
+		} `json:"webPages"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, err
+	}
+	out := make([]result, 0, len(raw.WebPages.Value))
+	for _, r := range raw.WebPages.Value {
+		out = append(out, result{Title: r.Name, URL: r.URL, Snippet: r.Snippet, Source: r.SiteName, PublishedAt: r.DatePublished})
+	}
+	return out, nil
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperifyio/goagent/internal/audit"
+)
+
+type input struct {
+	Q         string `json:"q"`
+	Provider  string `json:"provider"`
+	TimeRange string `json:"time_range"`
+	Language  string `json:"language"`
+	Size      int    `json:"size"`
+}
+
+type result struct {
+	Title       string `json:"title"`
+	URL         string `json:"url"`
+	Snippet     string `json:"snippet"`
+	Source      string `json:"source,omitempty"`
+	PublishedAt string `json:"published_at,omitempty"`
+}
+
+type output struct {
+	Query    string   `json:"query"`
+	Provider string   `json:"provider"`
+	Results  []result `json:"results"`
+	// Duplicates counts results dropped because their URL was already
+	// listed.
+	Duplicates int `json:"duplicates"`
+}
+
+const (
+	defaultSize = 10
+	maxSize     = 50
+	// maxBodyBytes bounds the provider response that is read.
+	maxBodyBytes = 4 << 20
+)
+
+func main() {
+	if err := run(); err != nil {
+		msg := strings.ReplaceAll(err.Error(), "\n", " ")
+		var he *hintedError
+		if errors.As(err, &he) && he.hint != "" {
+			fmt.Fprintf(os.Stderr, "{\"error\":%q,\"hint\":%q}\n", he.err.Error(), he.hint)
+		} else {
+			fmt.Fprintf(os.Stderr, "{\"error\":%q}\n", msg)
+		}
+		os.Exit(1)
+	}
+}
+
+// run parses input, queries the selected provider with retries, dedupes
+// the results, and appends an audit line.
+func run() error {
+	in, err := decodeInput()
+	if err != nil {
+		return err
+	}
+	p, err := selectProvider(strings.ToLower(strings.TrimSpace(in.Provider)))
+	if err != nil {
+		return err
+	}
+	req, err := p.build(in)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "agentcli-web-search/0.1")
+	if err := ssrfGuard(req.URL); err != nil {
+		return err
+	}
+	client := newHTTPClient(resolveTimeout())
+	start := time.Now()
+	body, status, retries, err := fetchWithRetries(client, req)
+	if err != nil {
+		return err
+	}
+	rows, err := p.parse(body)
+	if err != nil {
+		return hinted(fmt.Errorf("decode json: %w", err), "verify the "+p.name+" endpoint returns JSON search results")
+	}
+	out := output{Query: in.Q, Provider: p.name}
+	out.Results, out.Duplicates = dedupe(rows, in.Size)
+	if err := json.NewEncoder(os.Stdout).Encode(out); err != nil {
+		return fmt.Errorf("encode json: %w", err)
+	}
+	entry := makeAudit(p.name, req.URL, in.Q, status, time.Since(start).Milliseconds(), retries)
+	_ = audit.Append(entry) //nolint:errcheck
+	return nil
+}
+
+func decodeInput() (input, error) {
+	var in input
+	if err := json.NewDecoder(bufio.NewReader(os.Stdin)).Decode(&in); err != nil {
+		return in, fmt.Errorf("parse json: %w", err)
+	}
+	if strings.TrimSpace(in.Q) == "" {
+		return in, errors.New("q is required")
+	}
+	switch in.TimeRange {
+	case "", "day", "week", "month", "year":
+	default:
+		return in, errors.New("time_range must be one of: day, week, month, year")
+	}
+	if in.Size < 0 || in.Size > maxSize {
+		return in, fmt.Errorf("size must be between 1 and %d", maxSize)
+	}
+	if in.Size == 0 {
+		in.Size = defaultSize
+	}
+	return in, nil
+}
+
+// dedupe drops results without a URL or whose normalized URL was already
+// seen, keeping provider order, and returns at most size results.
+func dedupe(rows []result, size int) ([]result, int) {
+	out := []result{}
+	seen := map[string]bool{}
+	dups := 0
+	for _, r := range rows {
+		key := normalizeURL(r.URL)
+		if key == "" {
+			continue
+		}
+		if seen[key] {
+			dups++
+			continue
+		}
+		seen[key] = true
+		if len(out) < size {
+			r.Title = strings.TrimSpace(r.Title)
+			r.Snippet = strings.TrimSpace(r.Snippet)
+			out = append(out, r)
+		}
+	}
+	return out, dups
+}
+
+// normalizeURL maps URLs that name the same page to one key: scheme and
+// host are lowercased, "www." and default ports dropped, the fragment,
+// tracking parameters, and a trailing slash removed, and the query sorted.
+func normalizeURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+	q := u.Query()
+	for k := range q {
+		lk := strings.ToLower(k)
+		if strings.HasPrefix(lk, "utm_") || lk == "gclid" || lk == "fbclid" {
+			q.Del(k)
+		}
+	}
+	path := strings.TrimRight(u.EscapedPath(), "/")
+	key := host + path
+	if enc := q.Encode(); enc != "" {
+		key += "?" + enc
+	}
+	return key
+}
+
+func fetchWithRetries(client *http.Client, req *http.Request) ([]byte, int, int, error) {
+	var retries, lastStatus int
+	for attempt := 0; attempt < 3; attempt++ {
+		if attempt > 0 {
+			retries++
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			if isTimeout(err) && attempt < 2 {
+				backoffSleep(0, attempt)
+				continue
+			}
+			return nil, 0, retries, fmt.Errorf("http: %w", err)
+		}
+		lastStatus = resp.StatusCode
+		if (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500) && attempt < 2 {
+			sleepMs := retryAfterMs(resp.Header.Get("Retry-After"))
+			_ = resp.Body.Close() //nolint:errcheck
+			backoffSleep(sleepMs, attempt)
+			continue
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes))
+		_ = resp.Body.Close() //nolint:errcheck
+		if err != nil {
+			return nil, lastStatus, retries, fmt.Errorf("read body: %w", err)
+		}
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			return nil, lastStatus, retries, hinted(fmt.Errorf("http status %d", resp.StatusCode), "check the provider API key")
+		case resp.StatusCode >= 400:
+			return nil, lastStatus, retries, fmt.Errorf("http status %d", resp.StatusCode)
+		}
+		return body, lastStatus, retries, nil
+	}
+	return nil, lastStatus, retries, fmt.Errorf("http status %d after %d retries", lastStatus, retries)
+}
+
+// makeAudit records the provider host and outcome; the query string of the
+// request URL is not logged because it can carry credentials.
+func makeAudit(providerName string, u *url.URL, q string, status int, ms int64, retries int) map[string]any {
+	entry := map[string]any{
+		"ts":       time.Now().UTC().Format(time.RFC3339Nano),
+		"tool":     "web_search",
+		"provider": providerName,
+		"url_host": u.Hostname(),
+		"status":   status,
+		"ms":       ms,
+		"retries":  retries,
+	}
+	if len(q) <= 256 {
+		entry["query"] = q
+	} else {
+		entry["query"] = q[:256]
+		entry["query_truncated"] = true
+	}
+	return entry
+}
+
+func resolveTimeout() time.Duration {
+	if v := strings.TrimSpace(os.Getenv("HTTP_TIMEOUT_MS")); v != "" {
+		if ms, err := time.ParseDuration(v + "ms"); err == nil && ms > 0 {
+			return ms
+		}
+	}
+	return 10 * time.Second
+}
+
+func newHTTPClient(timeout time.Duration) *http.Client {
+	tr := &http.Transport{}
+	return &http.Client{Timeout: timeout, Transport: tr, CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return errors.New("too many redirects")
+		}
+		return ssrfGuard(req.URL)
+	}}
+}
+
+func isTimeout(err error) bool {
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+func retryAfterMs(h string) int64 {
+	if h == "" {
+		return 0
+	}
+	if n, err := strconv.Atoi(strings.TrimSpace(h)); err == nil && n >= 0 {
+		return int64(n) * 1000
+	}
+	if t, err := http.ParseTime(h); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d.Milliseconds()
+		}
+	}
+	return 0
+}
+
+func backoffSleep(retryAfterMs int64, attempt int) {
+	d := time.Duration(100*(attempt+1)) * time.Millisecond
+	if retryAfterMs > 0 {
+		d = time.Duration(retryAfterMs) * time.Millisecond
+	}
+	time.Sleep(d)
+}
+
+// ssrfGuard blocks private, loopback, and .onion hosts; WEB_SEARCH_ALLOW_LOCAL=1
+// permits local endpoints such as a self-hosted SearXNG or a test server.
+func ssrfGuard(u *url.URL) error {
+	host := u.Hostname()
+	if host == "" {
+		return errors.New("invalid host")
+	}
+	if strings.HasSuffix(strings.ToLower(host), ".onion") {
+		return errors.New("SSRF blocked: onion domains are not allowed")
+	}
+	if os.Getenv("WEB_SEARCH_ALLOW_LOCAL") == "1" {
+		return nil
+	}
+	ips, err := net.LookupIP(host)
+	if err != nil || len(ips) == 0 {
+		return errors.New("SSRF blocked: cannot resolve host")
+	}
+	for _, ip := range ips {
+		if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+			return errors.New("SSRF blocked: private or loopback address")
+		}
+	}
+	return nil
+}
+
+type hintedError struct {
+	err  error
+	hint string
+}
+
+func (h *hintedError) Error() string { return h.err.Error() }
+
+func hinted(err error, hint string) error { return &hintedError{err: err, hint: hint} }
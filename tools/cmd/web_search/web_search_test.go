@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	testutil "github.com/hyperifyio/goagent/tools/testutil"
+)
+
+type webSearchOutput struct {
+	Query    string   `json:"query"`
+	Provider string   `json:"provider"`
+	Results  []result `json:"results"`
+	// Duplicates is the number of results dropped by URL dedupe.
+	Duplicates int `json:"duplicates"`
+}
+
+// runWebSearch runs the tool in a temp dir with only PATH, HOME, the local
+// SSRF opt-out, and the given variables in its environment.
+func runWebSearch(t *testing.T, bin string, env []string, input any) (webSearchOutput, string, error) {
+	t.Helper()
+	data, err := json.Marshal(input)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	cmd := exec.Command(bin)
+	cmd.Dir = t.TempDir()
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Env = append([]string{"PATH=" + os.Getenv("PATH"), "HOME=" + os.Getenv("HOME"), "WEB_SEARCH_ALLOW_LOCAL=1"}, env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	runErr := cmd.Run()
+	var out webSearchOutput
+	if runErr == nil {
+		if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+			t.Fatalf("unmarshal stdout: %v; raw=%q", err, stdout.String())
+		}
+	}
+	return out, strings.TrimSpace(stderr.String()), runErr
+}
+
+func TestWebSearch_SearxngDedupes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search" || r.URL.Query().Get("format") != "json" || r.URL.Query().Get("time_range") != "week" {
+			http.Error(w, "bad", http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"results":[
+			{"title":"Go","url":"https://go.dev/doc/","content":"Docs","engine":"ddg"},
+			{"title":"Go again","url":"https://www.go.dev/doc?utm_source=x#top","content":"Dup","engine":"bing"},
+			{"title":"No URL","url":"","content":"x"},
+			{"title":"Blog","url":"https://go.dev/blog","content":"Blog","engine":"ddg"}]}`)) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	bin := testutil.BuildTool(t, "web_search")
+	out, stderr, err := runWebSearch(t, bin, []string{"SEARXNG_BASE_URL=" + srv.URL}, map[string]any{"q": "golang", "time_range": "week"})
+	if err != nil {
+		t.Fatalf("run: %v stderr=%s", err, stderr)
+	}
+	if out.Provider != "searxng" || out.Duplicates != 1 || len(out.Results) != 2 {
+		t.Fatalf("unexpected output: %+v", out)
+	}
+	if out.Results[0].URL != "https://go.dev/doc/" || out.Results[0].Source != "ddg" || out.Results[1].Title != "Blog" {
+		t.Fatalf("unexpected results: %+v", out.Results)
+	}
+}
+
+func TestWebSearch_BraveSendsKeyAndMapsFields(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Subscription-Token") != "brave-key" || r.URL.Query().Get("freshness") != "pd" || r.URL.Query().Get("count") != "2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"web":{"results":[
+			{"title":"A","url":"https://a.example/","description":"first","profile":{"name":"A site"}},
+			{"title":"B","url":"https://b.example/","description":"second"},
+			{"title":"C","url":"https://c.example/","description":"third"}]}}`)) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	bin := testutil.BuildTool(t, "web_search")
+	out, stderr, err := runWebSearch(t, bin, []string{"BRAVE_API_KEY=brave-key", "BRAVE_BASE_URL=" + srv.URL}, map[string]any{"q": "x", "time_range": "day", "size": 2})
+	if err != nil {
+		t.Fatalf("run: %v stderr=%s", err, stderr)
+	}
+	if out.Provider != "brave" || len(out.Results) != 2 || out.Results[0].Snippet != "first" || out.Results[0].Source != "A site" {
+		t.Fatalf("unexpected output: %+v", out)
+	}
+}
+
+func TestWebSearch_BingExplicitProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Ocp-Apim-Subscription-Key") != "bing-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"webPages":{"value":[{"name":"Bing hit","url":"https://bing.example/x","snippet":"s","siteName":"Example"}]}}`)) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	bin := testutil.BuildTool(t, "web_search")
+	env := []string{"SEARXNG_BASE_URL=http://unused.invalid", "BING_API_KEY=bing-key", "BING_BASE_URL=" + srv.URL}
+	out, stderr, err := runWebSearch(t, bin, env, map[string]any{"q": "x", "provider": "bing"})
+	if err != nil {
+		t.Fatalf("run: %v stderr=%s", err, stderr)
+	}
+	if out.Provider != "bing" || len(out.Results) != 1 || out.Results[0].Title != "Bing hit" {
+		t.Fatalf("unexpected output: %+v", out)
+	}
+	_, stderr, err = runWebSearch(t, bin, []string{"BING_API_KEY=wrong", "BING_BASE_URL=" + srv.URL}, map[string]any{"q": "x"})
+	if err == nil || !strings.Contains(stderr, "401") || !strings.Contains(stderr, "hint") || strings.Contains(stderr, "wrong") {
+		t.Fatalf("want 401 with hint and no key echo, got err=%v stderr=%s", err, stderr)
+	}
+}
+
+func TestWebSearch_NoProviderConfigured(t *testing.T) {
+	bin := testutil.BuildTool(t, "web_search")
+	_, stderr, err := runWebSearch(t, bin, nil, map[string]any{"q": "x"})
+	if err == nil || !strings.Contains(stderr, "no search provider configured") {
+		t.Fatalf("want configuration error, got err=%v stderr=%s", err, stderr)
+	}
+	_, stderr, err = runWebSearch(t, bin, nil, map[string]any{"q": "x", "provider": "brave"})
+	if err == nil || !strings.Contains(stderr, "BRAVE_API_KEY is required") {
+		t.Fatalf("want missing key error, got err=%v stderr=%s", err, stderr)
+	}
+}
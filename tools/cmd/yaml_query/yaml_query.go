@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hyperifyio/goagent/tools/workspace"
+	"github.com/itchyny/gojq"
+	"gopkg.in/yaml.v3"
+)
+
+type queryInput struct {
+	Path       string `json:"path"`
+	Query      string `json:"query"`
+	MaxResults int    `json:"maxResults,omitempty"`
+}
+
+type queryOutput struct {
+	Results   []any `json:"results"`
+	Truncated bool  `json:"truncated"`
+}
+
+// defaultMaxResults bounds the returned nodes when maxResults is unset.
+const defaultMaxResults = 100
+
+// maxFileBytes bounds the size of the file that will be parsed.
+const maxFileBytes = 16 << 20 // 16 MiB
+
+func main() {
+	in, err := readInput(os.Stdin)
+	if err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	if _, in.Path, err = workspace.Enter(in.Path); err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	if err := validatePath(in.Path); err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	out, err := runQuery(in)
+	if err != nil {
+		stderrJSON(err)
+		os.Exit(1)
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(out); err != nil {
+		stderrJSON(fmt.Errorf("encode json: %w", err))
+		os.Exit(1)
+	}
+}
+
+func readInput(r io.Reader) (queryInput, error) {
+	var in queryInput
+	b, err := io.ReadAll(bufio.NewReader(r))
+	if err != nil {
+		return in, fmt.Errorf("read stdin: %w", err)
+	}
+	if err := json.Unmarshal(b, &in); err != nil {
+		return in, fmt.Errorf("parse json: %w", err)
+	}
+	if strings.TrimSpace(in.Path) == "" {
+		return in, errors.New("path is required")
+	}
+	if strings.TrimSpace(in.Query) == "" {
+		return in, errors.New("query is required")
+	}
+	if in.MaxResults < 0 {
+		return in, errors.New("maxResults must be >= 0")
+	}
+	return in, nil
+}
+
+func validatePath(p string) error {
+	if filepath.IsAbs(p) {
+		return fmt.Errorf("ABSOLUTE_PATH: %s", p)
+	}
+	clean := filepath.ToSlash(filepath.Clean(p))
+	if clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(clean, "/../") {
+		return fmt.Errorf("PATH_ESCAPE: %s", p)
+	}
+	return nil
+}
+
+// runQuery evaluates the query against each document of the file in turn,
+// so multi-document YAML streams work as well as a single document.
+func runQuery(in queryInput) (queryOutput, error) {
+	q, err := gojq.Parse(in.Query)
+	if err != nil {
+		return queryOutput{}, fmt.Errorf("BAD_QUERY: %w", err)
+	}
+	code, err := gojq.Compile(q)
+	if err != nil {
+		return queryOutput{}, fmt.Errorf("BAD_QUERY: %w", err)
+	}
+	docs, err := readDocuments(in.Path)
+	if err != nil {
+		return queryOutput{}, err
+	}
+	max := in.MaxResults
+	if max == 0 {
+		max = defaultMaxResults
+	}
+	out := queryOutput{Results: []any{}}
+	for _, doc := range docs {
+		iter := code.RunWithContext(context.Background(), doc)
+		for {
+			v, ok := iter.Next()
+			if !ok {
+				break
+			}
+			if err, isErr := v.(error); isErr {
+				var halt *gojq.HaltError
+				if errors.As(err, &halt) && halt.Value() == nil {
+					break
+				}
+				return queryOutput{}, fmt.Errorf("QUERY_ERROR: %w", err)
+			}
+			if len(out.Results) >= max {
+				out.Truncated = true
+				return out, nil
+			}
+			out.Results = append(out.Results, v)
+		}
+	}
+	return out, nil
+}
+
+func readDocuments(p string) ([]any, error) {
+	fi, err := os.Stat(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("NOT_FOUND: %s", p)
+		}
+		return nil, err
+	}
+	if fi.IsDir() {
+		return nil, fmt.Errorf("IS_A_DIRECTORY: %s", p)
+	}
+	if fi.Size() > maxFileBytes {
+		return nil, fmt.Errorf("FILE_TOO_LARGE: %s (%d bytes) exceeds limit %d bytes", p, fi.Size(), maxFileBytes)
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() //nolint:errcheck // read-only
+	dec := yaml.NewDecoder(bufio.NewReader(f))
+	var docs []any
+	for {
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("PARSE_ERROR: %s: %w", p, err)
+		}
+		docs = append(docs, normalize(v))
+	}
+	return docs, nil
+}
+
+// normalize converts a decoded YAML value into the JSON-like values gojq
+// accepts: mapping keys become strings, timestamps RFC 3339 strings, and
+// integers outside the int range exact numbers.
+func normalize(v any) any {
+	switch x := v.(type) {
+	case map[string]any:
+		for k, el := range x {
+			x[k] = normalize(el)
+		}
+		return x
+	case map[any]any:
+		m := make(map[string]any, len(x))
+		for k, el := range x {
+			m[fmt.Sprint(k)] = normalize(el)
+		}
+		return m
+	case []any:
+		for i, el := range x {
+			x[i] = normalize(el)
+		}
+		return x
+	case time.Time:
+		return x.Format(time.RFC3339Nano)
+	case int64:
+		return json.Number(fmt.Sprint(x))
+	case uint64:
+		return json.Number(fmt.Sprint(x))
+	default:
+		return v
+	}
+}
+
+func stderrJSON(err error) {
+	msg := err.Error()
+	msg = strings.ReplaceAll(msg, "\n", " ")
+	fmt.Fprintf(os.Stderr, "{\"error\":%q}\n", msg)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	testutil "github.com/hyperifyio/goagent/tools/testutil"
+)
+
+type yamlQueryOutput struct {
+	Results   []json.RawMessage `json:"results"`
+	Truncated bool              `json:"truncated"`
+}
+
+func runYAMLQuery(t *testing.T, bin, dir string, input any) (yamlQueryOutput, string, int) {
+	t.Helper()
+	data, err := json.Marshal(input)
+	if err != nil {
+		t.Fatalf("marshal input: %v", err)
+	}
+	cmd := exec.Command(bin)
+	cmd.Dir = dir
+	cmd.Stdin = bytes.NewReader(data)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	code := 0
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok {
+			code = ee.ExitCode()
+		} else {
+			code = 1
+		}
+	}
+	var out yamlQueryOutput
+	if code == 0 {
+		if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+			t.Fatalf("unmarshal stdout: %v; raw=%q", err, stdout.String())
+		}
+	}
+	return out, stderr.String(), code
+}
+
+func results(out yamlQueryOutput) string {
+	parts := make([]string, len(out.Results))
+	for i, r := range out.Results {
+		parts[i] = string(r)
+	}
+	return strings.Join(parts, ",")
+}
+
+func TestYAMLQuery_ExtractsNodes(t *testing.T) {
+	bin := testutil.BuildTool(t, "yaml_query")
+	dir := t.TempDir()
+	doc := "jobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - uses: actions/checkout@v4\n      - run: make test\n  lint:\n    runs-on: macos-latest\n"
+	if err := os.WriteFile(filepath.Join(dir, "ci.yml"), []byte(doc), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	out, stderr, code := runYAMLQuery(t, bin, dir, map[string]any{"path": "ci.yml", "query": `[.jobs | to_entries[] | .value["runs-on"]] | sort`})
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if got := results(out); got != `["macos-latest","ubuntu-latest"]` {
+		t.Fatalf("results = %s", got)
+	}
+}
+
+func TestYAMLQuery_MultiDocumentAndScalars(t *testing.T) {
+	bin := testutil.BuildTool(t, "yaml_query")
+	dir := t.TempDir()
+	doc := "kind: Service\n1: one\nwhen: 2024-01-02T03:04:05Z\n---\nkind: Deployment\nreplicas: 3\n"
+	if err := os.WriteFile(filepath.Join(dir, "k8s.yaml"), []byte(doc), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	out, stderr, code := runYAMLQuery(t, bin, dir, map[string]any{"path": "k8s.yaml", "query": ".kind"})
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if got := results(out); got != `"Service","Deployment"` {
+		t.Fatalf("results = %s", got)
+	}
+	out, stderr, code = runYAMLQuery(t, bin, dir, map[string]any{"path": "k8s.yaml", "query": `select(.kind == "Service") | [.["1"], .when]`})
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if got := results(out); got != `["one","2024-01-02T03:04:05Z"]` {
+		t.Fatalf("results = %s", got)
+	}
+}
+
+func TestYAMLQuery_ParseError(t *testing.T) {
+	bin := testutil.BuildTool(t, "yaml_query")
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "bad.yaml"), []byte("a: [1, 2\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	_, stderr, code := runYAMLQuery(t, bin, dir, map[string]any{"path": "bad.yaml", "query": "."})
+	if code == 0 || !strings.Contains(stderr, "PARSE_ERROR") {
+		t.Fatalf("want PARSE_ERROR, got code=%d stderr=%s", code, stderr)
+	}
+}
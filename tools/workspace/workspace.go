@@ -0,0 +1,126 @@
+// Package workspace resolves the path arguments of the fs_* tools against
+// the workspace roots the agent configured. The default root comes from
+// GOAGENT_WORKSPACE_ROOT (the working directory when unset); named roots
+// come from GOAGENT_WORKSPACE_ROOTS, an allowlist of name=dir entries
+// separated by os.PathListSeparator, and are addressed with a "name:"
+// prefix such as "api:src/main.go". A tool calls Enter once with its path
+// argument; it changes into the root, so the tool's existing checks on
+// relative paths keep confining it to that root.
+package workspace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Environment variables set by the agent for tool processes.
+const (
+	RootEnv  = "GOAGENT_WORKSPACE_ROOT"
+	RootsEnv = "GOAGENT_WORKSPACE_ROOTS"
+)
+
+// namePattern is a root name; one letter is reserved for drive letters.
+var namePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]+$`)
+
+// ValidName reports whether name can name a workspace root.
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// Roots parses GOAGENT_WORKSPACE_ROOTS into name -> directory.
+func Roots() (map[string]string, error) {
+	roots := map[string]string{}
+	for _, entry := range filepath.SplitList(os.Getenv(RootsEnv)) {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		name, dir, ok := strings.Cut(entry, "=")
+		if !ok || !ValidName(name) || strings.TrimSpace(dir) == "" {
+			return nil, fmt.Errorf("%s: bad entry %q (want name=dir)", RootsEnv, entry)
+		}
+		roots[name] = dir
+	}
+	return roots, nil
+}
+
+// Split separates a "name:" prefix from p. It returns an empty name for a
+// plain path, and an error for a prefix that looks like a root name but is
+// not in the allowlist while named roots are configured.
+func Split(p string) (name, rest string, err error) {
+	prefix, after, ok := strings.Cut(p, ":")
+	if !ok || !ValidName(prefix) {
+		return "", p, nil
+	}
+	roots, err := Roots()
+	if err != nil {
+		return "", "", err
+	}
+	if _, known := roots[prefix]; known {
+		return prefix, after, nil
+	}
+	if len(roots) > 0 {
+		return "", "", fmt.Errorf("UNKNOWN_ROOT: %q is not a workspace root (allowed: %s)", prefix, strings.Join(names(roots), ", "))
+	}
+	return "", p, nil
+}
+
+// Dir returns the directory of the named root, or of the default root for
+// an empty name ("" meaning the working directory).
+func Dir(name string) (string, error) {
+	if name == "" {
+		return strings.TrimSpace(os.Getenv(RootEnv)), nil
+	}
+	roots, err := Roots()
+	if err != nil {
+		return "", err
+	}
+	dir, ok := roots[name]
+	if !ok {
+		return "", fmt.Errorf("UNKNOWN_ROOT: %q is not a workspace root", name)
+	}
+	return dir, nil
+}
+
+// Enter resolves p, changes into its root, and returns the root name and
+// the path relative to the root for the tool to validate and use.
+func Enter(p string) (name, rel string, err error) {
+	name, rel, err = Split(p)
+	if err != nil {
+		return "", "", err
+	}
+	return name, rel, Chdir(name)
+}
+
+// Chdir changes into the named root, or the default root for "".
+func Chdir(name string) error {
+	dir, err := Dir(name)
+	if err != nil || dir == "" {
+		return err
+	}
+	if err := os.Chdir(dir); err != nil {
+		return fmt.Errorf("workspace root: %w", err)
+	}
+	return nil
+}
+
+// Display returns rel as the model should pass it back: prefixed with the
+// root name when it lies in a named root.
+func Display(name, rel string) string {
+	if name == "" {
+		return rel
+	}
+	return name + ":" + rel
+}
+
+func names(roots map[string]string) []string {
+	out := make([]string, 0, len(roots))
+	for n := range roots {
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,79 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	t.Setenv(RootsEnv, "api=/srv/api"+string(os.PathListSeparator)+"web=/srv/web")
+	cases := []struct {
+		in, name, rest, err string
+	}{
+		{in: "src/main.go", rest: "src/main.go"},
+		{in: "api:src/main.go", name: "api", rest: "src/main.go"},
+		{in: "web:", name: "web"},
+		{in: "C:/Windows", rest: "C:/Windows"},
+		{in: "docs:readme.md", err: "UNKNOWN_ROOT"},
+	}
+	for _, tc := range cases {
+		name, rest, err := Split(tc.in)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("Split(%q) err=%v, want %s", tc.in, err, tc.err)
+			}
+			continue
+		}
+		if err != nil || name != tc.name || rest != tc.rest {
+			t.Fatalf("Split(%q)=(%q,%q,%v), want (%q,%q)", tc.in, name, rest, err, tc.name, tc.rest)
+		}
+	}
+}
+
+func TestSplit_NoRootsKeepsColonPaths(t *testing.T) {
+	t.Setenv(RootsEnv, "")
+	if name, rest, err := Split("notes:today.md"); err != nil || name != "" || rest != "notes:today.md" {
+		t.Fatalf("got (%q,%q,%v)", name, rest, err)
+	}
+}
+
+func TestRoots_BadEntry(t *testing.T) {
+	t.Setenv(RootsEnv, "missing-separator")
+	if _, err := Roots(); err == nil {
+		t.Fatalf("expected error for entry without '='")
+	}
+}
+
+func TestEnter_ChangesIntoRoot(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Logf("restore cwd: %v", err)
+		}
+	})
+	dir := t.TempDir()
+	t.Setenv(RootsEnv, "api="+dir)
+	name, rel, err := Enter("api:pkg/x.go")
+	if err != nil || name != "api" || rel != "pkg/x.go" {
+		t.Fatalf("Enter=(%q,%q,%v)", name, rel, err)
+	}
+	got, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	want, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatalf("eval: %v", err)
+	}
+	if got != want {
+		t.Fatalf("cwd=%s, want %s", got, want)
+	}
+	if d := Display(name, rel); d != "api:pkg/x.go" {
+		t.Fatalf("Display=%q", d)
+	}
+}